@@ -21,6 +21,8 @@ import (
 	"github.com/unitythemaker/tracely/internal/outbox"
 	"github.com/unitythemaker/tracely/internal/rule"
 	"github.com/unitythemaker/tracely/internal/service"
+	"github.com/unitythemaker/tracely/internal/silence"
+	"github.com/unitythemaker/tracely/internal/webhook"
 )
 
 func main() {
@@ -84,10 +86,12 @@ func main() {
 	serviceRepo := service.NewRepository(queries)
 	departmentRepo := department.NewRepository(queries)
 	metricRepo := metric.NewRepository(pool, queries)
-	ruleRepo := rule.NewRepository(queries)
+	ruleRepo := rule.NewRepository(pool, queries)
 	incidentRepo := incident.NewRepository(pool, queries)
 	notificationRepo := notification.NewRepository(queries)
 	outboxRepo := outbox.NewRepository(queries)
+	webhookRepo := webhook.NewRepository(queries)
+	silenceRepo := silence.NewRepository(queries)
 
 	// Initialize handlers
 	serviceHandler := service.NewHandler(serviceRepo)
@@ -96,6 +100,8 @@ func main() {
 	ruleHandler := rule.NewHandler(ruleRepo)
 	incidentHandler := incident.NewHandler(incidentRepo)
 	notificationHandler := notification.NewHandler(notificationRepo)
+	webhookHandler := webhook.NewHandler(webhookRepo)
+	silenceHandler := silence.NewHandler(silenceRepo)
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
@@ -114,6 +120,8 @@ func main() {
 	ruleHandler.RegisterRoutes(mux)
 	incidentHandler.RegisterRoutes(mux)
 	notificationHandler.RegisterRoutes(mux)
+	webhookHandler.RegisterRoutes(mux)
+	silenceHandler.RegisterRoutes(mux)
 
 	// Middleware chain: CORS -> Body limit
 	handler := corsMiddleware(cfg.CORSAllowedOrigins, bodyLimitMiddleware(mux))
@@ -133,15 +141,31 @@ func main() {
 	// Start workers
 	workerInterval := time.Duration(cfg.WorkerPollInterval) * time.Second
 
-	ruleWorker := rule.NewWorker(outboxRepo, ruleRepo, incidentRepo, workerInterval)
+	ruleWorker := rule.NewWorker(outboxRepo, ruleRepo, incidentRepo, webhookRepo, notificationRepo, silenceRepo, workerInterval)
+	if cfg.RecordEvaluations {
+		ruleWorker.EnableEvaluations(time.Duration(cfg.EvaluationRetentionHours) * time.Hour)
+	}
+	mux.HandleFunc("GET /api/rules/stats/cache", ruleWorker.ServeCacheStats)
 	go ruleWorker.Run(workerCtx)
 
+	absenceWorker := rule.NewAbsenceWorker(ruleRepo, incidentRepo, silenceRepo, time.Duration(cfg.AbsenceCheckInterval)*time.Second)
+	go absenceWorker.Run(workerCtx)
+
 	esWorker := elasticsearch.NewWorker(outboxRepo, serviceRepo, esClient, workerInterval)
 	go esWorker.Run(workerCtx)
 
 	notificationWorker := notification.NewWorker(outboxRepo, notificationRepo, workerInterval)
 	go notificationWorker.Run(workerCtx)
 
+	// Unsigned deliveries could be forged by anyone, so without a secret webhook
+	// requests stay in the outbox until the worker runs with one
+	if cfg.WebhookSigningSecret != "" {
+		webhookWorker := webhook.NewWorker(outboxRepo, webhookRepo, cfg.WebhookSigningSecret, workerInterval)
+		go webhookWorker.Run(workerCtx)
+	} else {
+		slog.Warn("WEBHOOK_SIGNING_SECRET is not set, webhook delivery is disabled")
+	}
+
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -188,7 +212,7 @@ func corsMiddleware(allowedOrigins string, next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Actor")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_quality_rules_service_ids;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS service_ids;
@@ -0,0 +1,6 @@
+-- Scope quality rules to specific services.
+-- An empty list means the rule applies to every service.
+ALTER TABLE quality_rules
+ADD COLUMN service_ids VARCHAR(50)[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_quality_rules_service_ids ON quality_rules USING GIN (service_ids);
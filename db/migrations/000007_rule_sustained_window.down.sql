@@ -0,0 +1,3 @@
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS min_violations;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS window_samples;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS for_seconds;
@@ -0,0 +1,8 @@
+-- Sustained-violation windows for quality rules.
+-- for_seconds: the condition must hold continuously for this long before the rule fires.
+-- window_samples/min_violations: the rule fires when at least min_violations of the
+-- last window_samples samples of the series violate the condition.
+ALTER TABLE quality_rules
+ADD COLUMN for_seconds INTEGER NOT NULL DEFAULT 0 CHECK (for_seconds >= 0),
+ADD COLUMN window_samples INTEGER NOT NULL DEFAULT 0 CHECK (window_samples >= 0),
+ADD COLUMN min_violations INTEGER NOT NULL DEFAULT 0 CHECK (min_violations >= 0);
@@ -0,0 +1,3 @@
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS window_seconds;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS aggregation;
+DROP TYPE IF EXISTS rule_aggregation;
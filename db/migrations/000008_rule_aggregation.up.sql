@@ -0,0 +1,15 @@
+-- Aggregation rules compare an aggregate of the series over a trailing window
+-- (e.g. p95 LATENCY_MS over the last 5 minutes) instead of the latest sample.
+CREATE TYPE rule_aggregation AS ENUM (
+    'NONE',
+    'AVG',
+    'MIN',
+    'MAX',
+    'P50',
+    'P95',
+    'P99'
+);
+
+ALTER TABLE quality_rules
+ADD COLUMN aggregation rule_aggregation NOT NULL DEFAULT 'NONE',
+ADD COLUMN window_seconds INTEGER NOT NULL DEFAULT 0 CHECK (window_seconds >= 0);
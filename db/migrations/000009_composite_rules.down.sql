@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS incident_metrics;
+
+DROP INDEX IF EXISTS idx_quality_rules_metric_types;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS metric_types;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS freshness_seconds;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS condition;
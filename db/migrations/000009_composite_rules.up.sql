@@ -0,0 +1,23 @@
+-- Composite rules: an AND/OR tree of conditions over several metric types of the
+-- same service. condition is NULL for single-metric rules. metric_types lists every
+-- metric type the rule depends on so the rule worker can find the rules to evaluate
+-- when any of them receives a new sample.
+ALTER TABLE quality_rules
+ADD COLUMN condition JSONB,
+ADD COLUMN freshness_seconds INTEGER NOT NULL DEFAULT 0 CHECK (freshness_seconds >= 0),
+ADD COLUMN metric_types TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE quality_rules SET metric_types = ARRAY[metric_type::text];
+
+CREATE INDEX idx_quality_rules_metric_types ON quality_rules USING GIN (metric_types);
+
+-- Metrics that contributed to an incident. Single-metric rules record the
+-- triggering metric; composite rules record the latest sample of every matched condition.
+CREATE TABLE incident_metrics (
+    incident_id VARCHAR(50) NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    metric_id UUID NOT NULL REFERENCES metrics(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (incident_id, metric_id)
+);
+
+CREATE INDEX idx_incident_metrics_metric_id ON incident_metrics(metric_id);
@@ -0,0 +1,3 @@
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS recovery_samples;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS recovery_threshold;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS auto_resolve;
@@ -0,0 +1,7 @@
+-- Opt-in auto-resolve: the rule worker closes the open incident of a rule once the
+-- series has been healthy for recovery_samples consecutive samples. recovery_threshold
+-- optionally sets a separate threshold for recovery (hysteresis).
+ALTER TABLE quality_rules
+ADD COLUMN auto_resolve BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN recovery_threshold DECIMAL(10, 2),
+ADD COLUMN recovery_samples INTEGER NOT NULL DEFAULT 1 CHECK (recovery_samples >= 1);
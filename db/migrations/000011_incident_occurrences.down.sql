@@ -0,0 +1,7 @@
+-- Enum values cannot be dropped; remove the events that use it instead
+DELETE FROM incident_events WHERE event_type = 'VIOLATION_RECORDED';
+
+DROP INDEX IF EXISTS idx_incidents_rule_service_status;
+ALTER TABLE incidents DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE incidents DROP COLUMN IF EXISTS first_seen_at;
+ALTER TABLE incidents DROP COLUMN IF EXISTS occurrence_count;
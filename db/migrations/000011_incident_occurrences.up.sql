@@ -0,0 +1,13 @@
+-- Incident de-duplication: while an incident for a rule+service pair is not closed,
+-- further violations are attached to it instead of opening new incidents.
+ALTER TABLE incidents
+ADD COLUMN occurrence_count INTEGER NOT NULL DEFAULT 1,
+ADD COLUMN first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE incidents SET first_seen_at = opened_at, last_seen_at = opened_at;
+
+CREATE INDEX idx_incidents_rule_service_status ON incidents(rule_id, service_id, status);
+
+-- Timeline event for a violation attached to an open incident
+ALTER TYPE incident_event_type ADD VALUE IF NOT EXISTS 'VIOLATION_RECORDED';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rule_suppressions;
+DROP TYPE IF EXISTS suppression_reason;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS cooldown_seconds;
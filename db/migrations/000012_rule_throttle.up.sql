@@ -0,0 +1,17 @@
+-- THROTTLE rules open an incident at most once per cooldown_seconds per service
+ALTER TABLE quality_rules
+ADD COLUMN cooldown_seconds INTEGER NOT NULL DEFAULT 0 CHECK (cooldown_seconds >= 0);
+
+CREATE TYPE suppression_reason AS ENUM (
+    'THROTTLED'
+);
+
+-- Violations that matched a rule but did not open or update an incident
+CREATE TABLE rule_suppressions (
+    rule_id VARCHAR(50) NOT NULL REFERENCES quality_rules(id) ON DELETE CASCADE,
+    service_id VARCHAR(50) NOT NULL REFERENCES services(id),
+    reason suppression_reason NOT NULL,
+    suppressed_count BIGINT NOT NULL DEFAULT 0,
+    last_suppressed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (rule_id, service_id, reason)
+);
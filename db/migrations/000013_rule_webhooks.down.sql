@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TYPE IF EXISTS webhook_delivery_status;
+
+-- Enum values cannot be dropped; remove the events that use it instead
+DELETE FROM outbox WHERE event_type = 'WEBHOOK_REQUESTED';
+
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS webhook_body_template;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS webhook_headers;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS webhook_url;
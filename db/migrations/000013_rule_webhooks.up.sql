@@ -0,0 +1,52 @@
+-- WEBHOOK rules post a payload to webhook_url when they fire
+ALTER TABLE quality_rules
+ADD COLUMN webhook_url TEXT,
+ADD COLUMN webhook_headers JSONB NOT NULL DEFAULT '{}',
+ADD COLUMN webhook_body_template TEXT;
+
+ALTER TYPE event_type ADD VALUE IF NOT EXISTS 'WEBHOOK_REQUESTED';
+
+CREATE TYPE webhook_delivery_status AS ENUM (
+    'PENDING',
+    'DELIVERED',
+    'FAILED'
+);
+
+-- One delivery per WEBHOOK_REQUESTED outbox event, retried until delivered or failed
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    outbox_id UUID NOT NULL UNIQUE,
+    rule_id VARCHAR(50) NOT NULL REFERENCES quality_rules(id) ON DELETE CASCADE,
+    service_id VARCHAR(50) NOT NULL REFERENCES services(id),
+    url TEXT NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    body TEXT NOT NULL,
+    status webhook_delivery_status NOT NULL DEFAULT 'PENDING',
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX idx_webhook_deliveries_rule ON webhook_deliveries(rule_id, created_at DESC);
+
+CREATE TRIGGER update_webhook_deliveries_updated_at
+    BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Every HTTP request made for a delivery
+CREATE TABLE webhook_delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt_number INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, attempt_number);
@@ -0,0 +1,4 @@
+-- Expression rules replace operator and threshold with a condition such as
+-- "value >= 5 && value <= 10"; the expression is compiled by the API before it is stored
+ALTER TABLE quality_rules
+ADD COLUMN expression TEXT CHECK (char_length(expression) <= 1024);
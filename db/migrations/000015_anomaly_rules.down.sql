@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS series_baselines;
+
+ALTER TABLE quality_rules
+DROP COLUMN IF EXISTS anomaly_seasonal,
+DROP COLUMN IF EXISTS anomaly_direction,
+DROP COLUMN IF EXISTS anomaly_deviations;
+
+DROP TYPE IF EXISTS anomaly_direction;
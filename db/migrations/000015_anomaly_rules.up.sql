@@ -0,0 +1,26 @@
+-- Anomaly rules fire when a value is more than anomaly_deviations standard deviations
+-- away from the learned baseline of its series instead of comparing it to a threshold
+CREATE TYPE anomaly_direction AS ENUM (
+    'BOTH',
+    'ABOVE',
+    'BELOW'
+);
+
+ALTER TABLE quality_rules
+ADD COLUMN anomaly_deviations NUMERIC(10, 4) CHECK (anomaly_deviations > 0),
+ADD COLUMN anomaly_direction anomaly_direction NOT NULL DEFAULT 'BOTH',
+ADD COLUMN anomaly_seasonal BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- EWMA baselines per series. Bucket -1 learns from every sample; buckets 0-167 learn
+-- from the samples of one hour of the week (UTC, 0 = Sunday 00:00).
+CREATE TABLE series_baselines (
+    service_id VARCHAR(50) NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    metric_type metric_type NOT NULL,
+    bucket SMALLINT NOT NULL CHECK (bucket >= -1 AND bucket < 168),
+    mean DOUBLE PRECISION NOT NULL,
+    variance DOUBLE PRECISION NOT NULL,
+    sample_count BIGINT NOT NULL,
+    last_recorded_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (service_id, metric_type, bucket)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS change_seconds;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS change_mode;
+DROP TYPE IF EXISTS rule_change_mode;
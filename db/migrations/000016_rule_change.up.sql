@@ -0,0 +1,11 @@
+-- Rate-of-change rules compare how much a series moved since change_seconds ago,
+-- as an absolute delta or a percentage of the earlier value, instead of the value itself
+CREATE TYPE rule_change_mode AS ENUM (
+    'NONE',
+    'ABSOLUTE',
+    'PERCENT'
+);
+
+ALTER TABLE quality_rules
+ADD COLUMN change_mode rule_change_mode NOT NULL DEFAULT 'NONE',
+ADD COLUMN change_seconds INTEGER NOT NULL DEFAULT 0 CHECK (change_seconds >= 0);
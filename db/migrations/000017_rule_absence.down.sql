@@ -0,0 +1 @@
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS absence_seconds;
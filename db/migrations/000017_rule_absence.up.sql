@@ -0,0 +1,4 @@
+-- Absence rules open an incident when a service sends no metric of the rule's type for
+-- absence_seconds. They are checked periodically rather than when a metric arrives.
+ALTER TABLE quality_rules
+ADD COLUMN absence_seconds INTEGER NOT NULL DEFAULT 0 CHECK (absence_seconds >= 0);
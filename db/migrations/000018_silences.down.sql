@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS silenced_violations;
+
+-- Enum values cannot be dropped; remove the suppressions that use it instead
+DELETE FROM rule_suppressions WHERE reason = 'SILENCED';
+
+DROP TABLE IF EXISTS silences;
@@ -0,0 +1,43 @@
+-- Silences suppress the incidents of matching violations, e.g. during planned maintenance.
+-- Every matcher left NULL matches any value; recurring silences are only active within
+-- their weekly windows between starts_at and ends_at.
+CREATE TABLE silences (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_id VARCHAR(50) REFERENCES services(id) ON DELETE CASCADE,
+    metric_type metric_type,
+    rule_id VARCHAR(50) REFERENCES quality_rules(id) ON DELETE CASCADE,
+    severity incident_severity,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    recurrence JSONB,
+    author VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at IS NULL OR ends_at > starts_at),
+    CHECK (ends_at IS NOT NULL OR recurrence IS NOT NULL)
+);
+
+CREATE INDEX idx_silences_window ON silences(starts_at, ends_at);
+
+CREATE TRIGGER update_silences_updated_at
+    BEFORE UPDATE ON silences
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TYPE suppression_reason ADD VALUE IF NOT EXISTS 'SILENCED';
+
+-- Violations a silence suppressed, kept for auditing after the window
+CREATE TABLE silenced_violations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    silence_id UUID NOT NULL REFERENCES silences(id) ON DELETE CASCADE,
+    rule_id VARCHAR(50) NOT NULL REFERENCES quality_rules(id) ON DELETE CASCADE,
+    service_id VARCHAR(50) NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    metric_id UUID NOT NULL,
+    metric_type metric_type NOT NULL,
+    severity incident_severity NOT NULL,
+    message TEXT NOT NULL,
+    suppressed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_silenced_violations_silence ON silenced_violations(silence_id, suppressed_at DESC);
@@ -0,0 +1,6 @@
+ALTER TABLE incidents DROP COLUMN IF EXISTS rule_revision;
+ALTER TABLE quality_rules DROP COLUMN IF EXISTS revision;
+
+DROP TABLE IF EXISTS rule_revisions;
+DROP FUNCTION IF EXISTS reject_rule_revision_change();
+DROP TYPE IF EXISTS rule_revision_action;
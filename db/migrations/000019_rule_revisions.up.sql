@@ -0,0 +1,43 @@
+CREATE TYPE rule_revision_action AS ENUM (
+    'CREATED',
+    'UPDATED',
+    'ACTIVATED',
+    'DEACTIVATED',
+    'DELETED',
+    'ROLLED_BACK'
+);
+
+-- Immutable history of quality rule changes. definition is the rule after the change
+-- (before it for DELETED) and diff maps each changed field to its old and new value.
+-- Revisions have no foreign key so they outlive deleted rules.
+CREATE TABLE rule_revisions (
+    rule_id VARCHAR(50) NOT NULL,
+    revision INTEGER NOT NULL CHECK (revision >= 1),
+    action rule_revision_action NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    definition JSONB NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',
+    rolled_back_to INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (rule_id, revision)
+);
+
+CREATE OR REPLACE FUNCTION reject_rule_revision_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'rule revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER rule_revisions_immutable
+    BEFORE UPDATE OR DELETE ON rule_revisions
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_rule_revision_change();
+
+-- The latest revision of a rule; 0 for rules last changed before revisions were recorded
+ALTER TABLE quality_rules
+ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
+
+-- The revision of the rule that opened the incident
+ALTER TABLE incidents
+ADD COLUMN rule_revision INTEGER;
@@ -0,0 +1,3 @@
+-- Optional schedule limiting when a rule is evaluated: a cron expression or weekly
+-- windows, with a time zone. NULL means always.
+ALTER TABLE quality_rules ADD COLUMN schedule JSONB;
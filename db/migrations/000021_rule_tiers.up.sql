@@ -0,0 +1,4 @@
+-- Multi-tier rules: ordered thresholds with increasing severity, e.g.
+-- [{"threshold": 150, "severity": "MEDIUM"}, {"threshold": 300, "severity": "HIGH"}].
+-- threshold and severity hold the first tier.
+ALTER TABLE quality_rules ADD COLUMN tiers JSONB;
@@ -0,0 +1,3 @@
+ALTER TABLE incidents DROP COLUMN IF EXISTS evaluation_id;
+DROP TABLE IF EXISTS rule_evaluations;
+DROP TYPE IF EXISTS evaluation_outcome;
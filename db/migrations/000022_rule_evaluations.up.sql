@@ -0,0 +1,27 @@
+CREATE TYPE evaluation_outcome AS ENUM (
+    'MATCHED',
+    'NOT_MATCHED',
+    'SKIPPED',
+    'SUPPRESSED'
+);
+
+-- Explanation of how the rule worker evaluated each active rule for a metric. Recording
+-- is optional and rows older than the configured retention are pruned by the worker.
+CREATE TABLE rule_evaluations (
+    id BIGSERIAL PRIMARY KEY,
+    metric_id UUID NOT NULL REFERENCES metrics(id) ON DELETE CASCADE,
+    rule_id VARCHAR(50) NOT NULL,
+    rule_revision INTEGER,
+    outcome evaluation_outcome NOT NULL,
+    reason TEXT,
+    value DOUBLE PRECISION,
+    message TEXT,
+    evaluated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rule_evaluations_metric ON rule_evaluations(metric_id);
+CREATE INDEX idx_rule_evaluations_evaluated_at ON rule_evaluations(evaluated_at);
+
+-- The evaluation that opened the incident
+ALTER TABLE incidents
+ADD COLUMN evaluation_id BIGINT REFERENCES rule_evaluations(id) ON DELETE SET NULL;
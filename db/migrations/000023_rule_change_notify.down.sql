@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS quality_rules_changed ON quality_rules;
+DROP FUNCTION IF EXISTS notify_quality_rules_changed();
@@ -0,0 +1,14 @@
+-- Rule caches LISTEN on quality_rules_changed and reload when a transaction changing
+-- quality_rules commits, whichever server instance or client made the change
+CREATE OR REPLACE FUNCTION notify_quality_rules_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('quality_rules_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER quality_rules_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON quality_rules
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_quality_rules_changed();
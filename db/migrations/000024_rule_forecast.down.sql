@@ -0,0 +1,5 @@
+ALTER TABLE quality_rules
+DROP COLUMN IF EXISTS forecast_method,
+DROP COLUMN IF EXISTS forecast_window_seconds,
+DROP COLUMN IF EXISTS forecast_horizon_seconds;
+DROP TYPE IF EXISTS rule_forecast_method;
@@ -0,0 +1,13 @@
+-- Forecast rules fit a trend over the last forecast_window_seconds of a series and open
+-- a LOW severity incident when the forecast crosses the threshold within
+-- forecast_horizon_seconds
+CREATE TYPE rule_forecast_method AS ENUM (
+    'NONE',
+    'LINEAR',
+    'HOLT'
+);
+
+ALTER TABLE quality_rules
+ADD COLUMN forecast_method rule_forecast_method NOT NULL DEFAULT 'NONE',
+ADD COLUMN forecast_window_seconds INTEGER NOT NULL DEFAULT 0 CHECK (forecast_window_seconds >= 0),
+ADD COLUMN forecast_horizon_seconds INTEGER NOT NULL DEFAULT 0 CHECK (forecast_horizon_seconds >= 0);
@@ -0,0 +1,5 @@
+-- Enum values cannot be dropped; remove the suppressions that use it instead
+DELETE FROM rule_suppressions WHERE reason = 'SHADOWED';
+
+DROP TABLE IF EXISTS metric_evaluation_policies;
+DROP TYPE IF EXISTS evaluation_policy;
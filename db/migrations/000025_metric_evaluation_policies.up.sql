@@ -0,0 +1,27 @@
+-- How the rule worker handles several matching rules for a metric type: ALL_MATCHES
+-- fires every matching rule, FIRST_MATCH only the matching rule with the lowest
+-- priority value and records the others as shadowed
+CREATE TYPE evaluation_policy AS ENUM (
+    'ALL_MATCHES',
+    'FIRST_MATCH'
+);
+
+-- Metric types without a row use ALL_MATCHES
+CREATE TABLE metric_evaluation_policies (
+    metric_type metric_type PRIMARY KEY,
+    policy evaluation_policy NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_metric_evaluation_policies_updated_at
+    BEFORE UPDATE ON metric_evaluation_policies
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Rule caches keep the policies with the rules, so changes notify the same channel
+CREATE TRIGGER metric_evaluation_policies_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON metric_evaluation_policies
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_quality_rules_changed();
+
+ALTER TYPE suppression_reason ADD VALUE IF NOT EXISTS 'SHADOWED';
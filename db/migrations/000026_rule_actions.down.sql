@@ -0,0 +1,24 @@
+-- Notifications without an incident cannot be kept
+DELETE FROM notifications WHERE incident_id IS NULL;
+ALTER TABLE notifications DROP COLUMN IF EXISTS rule_id;
+ALTER TABLE notifications ALTER COLUMN incident_id SET NOT NULL;
+-- Enum values cannot be dropped; remove the events that use it instead
+DELETE FROM outbox WHERE event_type = 'NOTIFICATION_REQUESTED';
+
+CREATE TYPE rule_action AS ENUM (
+    'OPEN_INCIDENT',
+    'THROTTLE',
+    'WEBHOOK'
+);
+
+-- Custom actions fall back to the built-in action of their behaviour
+ALTER TABLE quality_rules DROP CONSTRAINT IF EXISTS quality_rules_action_fkey;
+DROP INDEX IF EXISTS idx_quality_rules_action;
+UPDATE quality_rules q
+SET action = CASE a.behavior WHEN 'NOTIFY_ONLY' THEN 'OPEN_INCIDENT' ELSE a.behavior::text END
+FROM rule_actions a
+WHERE q.action = a.id;
+ALTER TABLE quality_rules ALTER COLUMN action TYPE rule_action USING action::rule_action;
+
+DROP TABLE IF EXISTS rule_actions;
+DROP TYPE IF EXISTS action_behavior;
@@ -0,0 +1,49 @@
+-- Rule actions are kept in a registry so new actions can be added without a migration.
+-- Each action maps to one of the behaviours the rule worker implements.
+CREATE TYPE action_behavior AS ENUM (
+    'OPEN_INCIDENT',
+    'NOTIFY_ONLY',
+    'WEBHOOK',
+    'THROTTLE'
+);
+
+CREATE TABLE rule_actions (
+    id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    behavior action_behavior NOT NULL,
+    -- Department incidents and notifications are routed to when the rule has none
+    department_id VARCHAR(50) REFERENCES departments(id) ON DELETE SET NULL,
+    -- Template of the incident or notification message, rendered like a webhook body
+    message_template TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_rule_actions_updated_at
+    BEFORE UPDATE ON rule_actions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Rule caches keep the actions with the rules, so changes notify the same channel
+CREATE TRIGGER rule_actions_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON rule_actions
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_quality_rules_changed();
+
+-- The former enum values become built-in actions of the same behaviour
+INSERT INTO rule_actions (id, name, behavior) VALUES
+    ('OPEN_INCIDENT', 'Open incident', 'OPEN_INCIDENT'),
+    ('THROTTLE', 'Open incident at most once per cooldown', 'THROTTLE'),
+    ('WEBHOOK', 'Call webhook', 'WEBHOOK');
+
+ALTER TABLE quality_rules ALTER COLUMN action TYPE VARCHAR(50) USING action::text;
+ALTER TABLE quality_rules
+    ADD CONSTRAINT quality_rules_action_fkey FOREIGN KEY (action) REFERENCES rule_actions(id);
+CREATE INDEX idx_quality_rules_action ON quality_rules(action);
+DROP TYPE rule_action;
+
+-- NOTIFY_ONLY actions send a notification without an incident
+ALTER TYPE event_type ADD VALUE IF NOT EXISTS 'NOTIFICATION_REQUESTED';
+ALTER TABLE notifications ALTER COLUMN incident_id DROP NOT NULL;
+ALTER TABLE notifications ADD COLUMN rule_id VARCHAR(50) REFERENCES quality_rules(id) ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS draft_violations;
+-- Enum values cannot be dropped; remove the suppressions that use it instead
+DELETE FROM rule_suppressions WHERE reason = 'DRAFT';
+
+DROP TABLE IF EXISTS rule_status_changes;
+
+-- Without the workflow every active rule opens incidents; keep unpublished rules off
+UPDATE quality_rules SET is_active = FALSE WHERE status != 'PUBLISHED';
+DROP INDEX IF EXISTS idx_quality_rules_review;
+ALTER TABLE quality_rules
+DROP COLUMN IF EXISTS author,
+DROP COLUMN IF EXISTS owner,
+DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS rule_status;
@@ -0,0 +1,48 @@
+-- Rules changed through the API start as drafts, are submitted for review and are
+-- published once someone other than their author approves them. Drafts and rules
+-- pending review are evaluated in shadow mode: their violations are recorded but open
+-- no incident. Existing rules stay published.
+CREATE TYPE rule_status AS ENUM (
+    'DRAFT',
+    'PENDING_REVIEW',
+    'PUBLISHED'
+);
+
+-- owner created the rule; author made its latest change, which the reviewer approves
+ALTER TABLE quality_rules
+ADD COLUMN status rule_status NOT NULL DEFAULT 'PUBLISHED',
+ADD COLUMN owner VARCHAR(100),
+ADD COLUMN author VARCHAR(100);
+
+CREATE INDEX idx_quality_rules_review ON quality_rules(department_id) WHERE status = 'PENDING_REVIEW';
+
+-- Log of rule status changes. from_status is NULL for created rules. Like revisions,
+-- the log has no foreign key so it outlives deleted rules.
+CREATE TABLE rule_status_changes (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id VARCHAR(50) NOT NULL,
+    from_status rule_status,
+    to_status rule_status NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rule_status_changes_rule ON rule_status_changes(rule_id, id DESC);
+
+ALTER TYPE suppression_reason ADD VALUE IF NOT EXISTS 'DRAFT';
+
+-- Violations of rules in shadow mode, kept so reviewers can see what a rule would open
+CREATE TABLE draft_violations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id VARCHAR(50) NOT NULL REFERENCES quality_rules(id) ON DELETE CASCADE,
+    rule_revision INTEGER NOT NULL,
+    service_id VARCHAR(50) NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    metric_id UUID NOT NULL,
+    metric_type metric_type NOT NULL,
+    severity incident_severity NOT NULL,
+    message TEXT NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_draft_violations_rule ON draft_violations(rule_id, recorded_at DESC);
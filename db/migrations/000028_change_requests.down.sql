@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS change_requests;
+DROP TYPE IF EXISTS change_request_status;
+DROP TYPE IF EXISTS change_kind;
+DROP TYPE IF EXISTS change_target;
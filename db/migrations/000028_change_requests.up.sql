@@ -0,0 +1,41 @@
+-- Activating, deactivating or deleting a published rule, and updating an action that
+-- published rules use, changes production alerting. Such changes are requested and take
+-- effect once someone other than the requester approves them. payload holds the
+-- requested settings of an UPDATE. target_id has no foreign key so requests outlive
+-- the rules they deleted.
+CREATE TYPE change_target AS ENUM (
+    'RULE',
+    'ACTION'
+);
+
+CREATE TYPE change_kind AS ENUM (
+    'ACTIVATE',
+    'DEACTIVATE',
+    'DELETE',
+    'UPDATE'
+);
+
+CREATE TYPE change_request_status AS ENUM (
+    'PENDING',
+    'APPROVED',
+    'REJECTED'
+);
+
+CREATE TABLE change_requests (
+    id BIGSERIAL PRIMARY KEY,
+    target change_target NOT NULL,
+    target_id VARCHAR(50) NOT NULL,
+    kind change_kind NOT NULL,
+    payload JSONB,
+    requested_by VARCHAR(100) NOT NULL,
+    comment TEXT,
+    status change_request_status NOT NULL DEFAULT 'PENDING',
+    reviewed_by VARCHAR(100),
+    review_comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMPTZ
+);
+
+-- A rule or action has at most one change waiting for review
+CREATE UNIQUE INDEX idx_change_requests_pending ON change_requests(target, target_id) WHERE status = 'PENDING';
+CREATE INDEX idx_change_requests_target ON change_requests(target, target_id, id DESC);
@@ -0,0 +1,9 @@
+-- A WEBHOOK rule requests at most one delivery per service within its cooldown, so a
+-- sustained violation does not post on every sample. requested_at is the time of the
+-- last requested delivery.
+CREATE TABLE webhook_cooldowns (
+    rule_id VARCHAR(50) NOT NULL REFERENCES quality_rules(id) ON DELETE CASCADE,
+    service_id VARCHAR(50) NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (rule_id, service_id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_outbox_processing_dead_letters;
+ALTER TABLE outbox_processing DROP COLUMN IF EXISTS error;
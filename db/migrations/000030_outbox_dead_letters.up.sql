@@ -0,0 +1,6 @@
+-- Events a processor can never handle, such as payloads that do not decode, are marked
+-- processed with the reason instead of being retried on every poll. Rows with an error
+-- are the processor's dead letters.
+ALTER TABLE outbox_processing ADD COLUMN error TEXT;
+
+CREATE INDEX idx_outbox_processing_dead_letters ON outbox_processing(processor, processed_at DESC) WHERE error IS NOT NULL;
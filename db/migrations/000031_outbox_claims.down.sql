@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rule_series_leases;
+DROP TABLE IF EXISTS outbox_claims;
@@ -0,0 +1,20 @@
+-- Several server instances poll the outbox. A processor claims the events it is about to
+-- handle for a lease, so other instances skip them until they are marked processed or
+-- the lease runs out.
+CREATE TABLE outbox_claims (
+    outbox_id UUID NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+    processor VARCHAR(50) NOT NULL,
+    claimed_until TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (outbox_id, processor)
+);
+
+-- The rule worker keeps series and baselines in memory, so the metrics of a service are
+-- evaluated by one instance at a time: the owner of the service's lease.
+-- generation changes whenever the lease changes owner, telling the new owner that its
+-- in-memory state of the service may be stale.
+CREATE TABLE rule_series_leases (
+    service_id VARCHAR(50) PRIMARY KEY,
+    owner VARCHAR(64) NOT NULL,
+    generation BIGINT NOT NULL DEFAULT 1,
+    leased_until TIMESTAMPTZ NOT NULL
+);
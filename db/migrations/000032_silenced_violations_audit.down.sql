@@ -0,0 +1,12 @@
+DELETE FROM silenced_violations
+WHERE silence_id IS NULL
+   OR rule_id NOT IN (SELECT id FROM quality_rules)
+   OR service_id NOT IN (SELECT id FROM services);
+ALTER TABLE silenced_violations
+    ADD CONSTRAINT silenced_violations_service_id_fkey FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE;
+ALTER TABLE silenced_violations
+    ADD CONSTRAINT silenced_violations_rule_id_fkey FOREIGN KEY (rule_id) REFERENCES quality_rules(id) ON DELETE CASCADE;
+ALTER TABLE silenced_violations DROP CONSTRAINT silenced_violations_silence_id_fkey;
+ALTER TABLE silenced_violations
+    ADD CONSTRAINT silenced_violations_silence_id_fkey FOREIGN KEY (silence_id) REFERENCES silences(id) ON DELETE CASCADE;
+ALTER TABLE silenced_violations ALTER COLUMN silence_id SET NOT NULL;
@@ -0,0 +1,9 @@
+-- Suppressed violations are an audit trail and outlive what they refer to: deleting a
+-- silence keeps its violations without the silence, and deleting a rule or service keeps
+-- the ids they were recorded with
+ALTER TABLE silenced_violations ALTER COLUMN silence_id DROP NOT NULL;
+ALTER TABLE silenced_violations DROP CONSTRAINT silenced_violations_silence_id_fkey;
+ALTER TABLE silenced_violations
+    ADD CONSTRAINT silenced_violations_silence_id_fkey FOREIGN KEY (silence_id) REFERENCES silences(id) ON DELETE SET NULL;
+ALTER TABLE silenced_violations DROP CONSTRAINT silenced_violations_rule_id_fkey;
+ALTER TABLE silenced_violations DROP CONSTRAINT silenced_violations_service_id_fkey;
@@ -0,0 +1,7 @@
+DELETE FROM silenced_violations WHERE metric_id IS NULL;
+DELETE FROM draft_violations WHERE metric_id IS NULL;
+DELETE FROM notifications WHERE incident_id IN (SELECT id FROM incidents WHERE metric_id IS NULL);
+DELETE FROM incidents WHERE metric_id IS NULL;
+ALTER TABLE silenced_violations ALTER COLUMN metric_id SET NOT NULL;
+ALTER TABLE draft_violations ALTER COLUMN metric_id SET NOT NULL;
+ALTER TABLE incidents ALTER COLUMN metric_id SET NOT NULL;
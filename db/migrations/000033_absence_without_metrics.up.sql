@@ -0,0 +1,5 @@
+-- Absence rules open incidents for services that never reported a metric, so there may
+-- be no metric to point at
+ALTER TABLE incidents ALTER COLUMN metric_id DROP NOT NULL;
+ALTER TABLE draft_violations ALTER COLUMN metric_id DROP NOT NULL;
+ALTER TABLE silenced_violations ALTER COLUMN metric_id DROP NOT NULL;
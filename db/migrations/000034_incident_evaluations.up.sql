@@ -0,0 +1,3 @@
+-- Pruning keeps the evaluations that opened an incident, so the incident always explains
+-- itself; the index serves that check
+CREATE INDEX idx_incidents_evaluation ON incidents(evaluation_id) WHERE evaluation_id IS NOT NULL;
@@ -0,0 +1,51 @@
+-- name: CreateChangeRequest :one
+INSERT INTO change_requests (target, target_id, kind, payload, requested_by, comment)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetChangeRequest :one
+SELECT * FROM change_requests WHERE id = $1;
+
+-- name: GetChangeRequestForUpdate :one
+SELECT * FROM change_requests WHERE id = $1 FOR UPDATE;
+
+-- name: GetPendingChangeRequest :one
+SELECT * FROM change_requests
+WHERE target = $1 AND target_id = $2 AND status = 'PENDING';
+
+-- name: ReviewChangeRequest :one
+UPDATE change_requests
+SET status = $2, reviewed_by = $3, review_comment = $4, reviewed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListChangeRequests :many
+-- department_id matches the department of the rule, or of the action, a request changes
+SELECT c.* FROM change_requests c
+WHERE
+  (sqlc.narg(filter_status)::change_request_status IS NULL OR c.status = sqlc.narg(filter_status))
+  AND (sqlc.narg(filter_target)::change_target IS NULL OR c.target = sqlc.narg(filter_target))
+  AND (sqlc.narg(filter_target_id)::text IS NULL OR c.target_id = sqlc.narg(filter_target_id))
+  AND (sqlc.narg(filter_department_id)::text IS NULL
+    OR (c.target = 'RULE' AND EXISTS (
+      SELECT 1 FROM quality_rules r WHERE r.id = c.target_id AND r.department_id = sqlc.narg(filter_department_id)))
+    OR (c.target = 'ACTION' AND EXISTS (
+      SELECT 1 FROM rule_actions a WHERE a.id = c.target_id AND a.department_id = sqlc.narg(filter_department_id))))
+ORDER BY c.id DESC
+LIMIT @limit_val OFFSET @offset_val;
+
+-- name: CountChangeRequests :one
+SELECT COUNT(*)::int FROM change_requests c
+WHERE
+  (sqlc.narg(filter_status)::change_request_status IS NULL OR c.status = sqlc.narg(filter_status))
+  AND (sqlc.narg(filter_target)::change_target IS NULL OR c.target = sqlc.narg(filter_target))
+  AND (sqlc.narg(filter_target_id)::text IS NULL OR c.target_id = sqlc.narg(filter_target_id))
+  AND (sqlc.narg(filter_department_id)::text IS NULL
+    OR (c.target = 'RULE' AND EXISTS (
+      SELECT 1 FROM quality_rules r WHERE r.id = c.target_id AND r.department_id = sqlc.narg(filter_department_id)))
+    OR (c.target = 'ACTION' AND EXISTS (
+      SELECT 1 FROM rule_actions a WHERE a.id = c.target_id AND a.department_id = sqlc.narg(filter_department_id))));
+
+-- name: CountPublishedRulesUsingAction :one
+SELECT COUNT(*)::int FROM quality_rules
+WHERE action = $1 AND status = 'PUBLISHED';
@@ -0,0 +1,10 @@
+-- name: AddIncidentMetric :exec
+INSERT INTO incident_metrics (incident_id, metric_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: ListIncidentMetrics :many
+SELECT m.* FROM incident_metrics im
+JOIN metrics m ON m.id = im.metric_id
+WHERE im.incident_id = $1
+ORDER BY m.metric_type, m.recorded_at;
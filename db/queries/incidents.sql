@@ -46,6 +46,10 @@ ORDER BY
   CASE WHEN @sort_by::text = 'service_id' AND @sort_dir::text = 'desc' THEN service_id END DESC,
   CASE WHEN @sort_by::text = 'opened_at' AND @sort_dir::text = 'asc' THEN opened_at END ASC,
   CASE WHEN @sort_by::text = 'opened_at' AND @sort_dir::text = 'desc' THEN opened_at END DESC,
+  CASE WHEN @sort_by::text = 'occurrence_count' AND @sort_dir::text = 'asc' THEN occurrence_count END ASC,
+  CASE WHEN @sort_by::text = 'occurrence_count' AND @sort_dir::text = 'desc' THEN occurrence_count END DESC,
+  CASE WHEN @sort_by::text = 'last_seen_at' AND @sort_dir::text = 'asc' THEN last_seen_at END ASC,
+  CASE WHEN @sort_by::text = 'last_seen_at' AND @sort_dir::text = 'desc' THEN last_seen_at END DESC,
   opened_at DESC
 LIMIT @limit_val OFFSET @offset_val;
 
@@ -62,8 +66,8 @@ WHERE
   ));
 
 -- name: CreateIncident :one
-INSERT INTO incidents (id, service_id, rule_id, metric_id, severity, status, message, opened_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO incidents (id, service_id, rule_id, metric_id, severity, status, message, opened_at, rule_revision, evaluation_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: UpdateIncidentStatus :one
@@ -84,6 +88,50 @@ SET status = 'CLOSED', closed_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: ListUnresolvedIncidentsForRule :many
+SELECT * FROM incidents
+WHERE rule_id = $1 AND service_id = $2 AND status != 'CLOSED'
+ORDER BY opened_at;
+
+-- name: GetLastUnresolvedViolation :one
+-- Returns when the latest violation attached to an unresolved incident of a rule and
+-- service was recorded
+SELECT m.recorded_at
+FROM incidents i
+JOIN metrics m ON m.id = i.metric_id
+WHERE i.rule_id = $1 AND i.service_id = $2 AND i.status != 'CLOSED'
+ORDER BY m.recorded_at DESC
+LIMIT 1;
+
+-- name: LockIncidentKey :exec
+-- Serializes incident creation for a rule+service pair until the transaction ends
+SELECT pg_advisory_xact_lock(hashtext(@rule_id::text || '/' || @service_id::text));
+
+-- name: GetUnresolvedIncidentForRule :one
+SELECT * FROM incidents
+WHERE rule_id = $1 AND service_id = $2 AND status != 'CLOSED'
+ORDER BY opened_at DESC
+LIMIT 1
+FOR UPDATE;
+
+-- name: RecordIncidentOccurrence :one
+UPDATE incidents
+SET occurrence_count = occurrence_count + 1, last_seen_at = NOW(), metric_id = COALESCE(sqlc.narg('metric_id'), metric_id)
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateIncidentSeverity :one
+UPDATE incidents
+SET severity = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: GetLastIncidentOpenedAt :one
+SELECT opened_at FROM incidents
+WHERE rule_id = $1 AND service_id = $2
+ORDER BY opened_at DESC
+LIMIT 1;
+
 -- name: CountOpenIncidents :one
 SELECT COUNT(*) FROM incidents WHERE status != 'CLOSED';
 
@@ -93,3 +141,17 @@ WHERE service_id = $1 AND status != 'CLOSED';
 
 -- name: NextIncidentID :one
 SELECT CAST('INC-' || nextval('incident_id_seq')::TEXT AS VARCHAR) AS id;
+
+-- name: SummarizeRuleIncidentsByService :many
+-- Counts the incidents a rule opened in a time range per service
+SELECT
+  service_id,
+  COUNT(*)::int AS incident_count,
+  MIN(opened_at)::timestamptz AS first_opened_at,
+  MAX(opened_at)::timestamptz AS last_opened_at
+FROM incidents
+WHERE rule_id = @rule_id
+  AND opened_at >= @from_time
+  AND opened_at <= @to_time
+GROUP BY service_id
+ORDER BY service_id;
@@ -0,0 +1,14 @@
+-- name: ListEvaluationPolicies :many
+SELECT * FROM metric_evaluation_policies
+ORDER BY metric_type;
+
+-- name: GetEvaluationPolicy :one
+SELECT * FROM metric_evaluation_policies
+WHERE metric_type = $1;
+
+-- name: UpsertEvaluationPolicy :one
+INSERT INTO metric_evaluation_policies (metric_type, policy)
+VALUES ($1, $2)
+ON CONFLICT (metric_type) DO UPDATE
+SET policy = EXCLUDED.policy
+RETURNING *;
@@ -60,6 +60,16 @@ WHERE service_id = $1 AND metric_type = $2
 ORDER BY recorded_at DESC
 LIMIT 1;
 
+-- name: ListRecentSeriesMetrics :many
+-- Returns the newest samples of one service/metric series, newest first
+SELECT * FROM metrics
+WHERE service_id = @service_id
+  AND metric_type = @metric_type
+  AND recorded_at >= @since
+  AND recorded_at <= @until
+ORDER BY recorded_at DESC
+LIMIT @limit_val;
+
 -- name: ListMetricsInRange :many
 SELECT * FROM metrics
 WHERE
@@ -98,3 +108,14 @@ SELECT
 FROM time_buckets
 GROUP BY bucket_time, metric_type
 ORDER BY bucket_time ASC, metric_type ASC;
+
+-- name: ListBacktestMetrics :many
+-- Returns the samples a rule is replayed against, oldest first. An empty service_ids
+-- matches all services.
+SELECT * FROM metrics
+WHERE metric_type::text = ANY(@metric_types::text[])
+  AND (cardinality(@service_ids::text[]) = 0 OR service_id = ANY(@service_ids::text[]))
+  AND recorded_at >= @from_time
+  AND recorded_at <= @to_time
+ORDER BY recorded_at ASC, id ASC
+LIMIT @limit_val;
@@ -21,11 +21,89 @@ ORDER BY o.created_at
 LIMIT $2
 FOR UPDATE OF o SKIP LOCKED;
 
+-- name: ClaimUnprocessedEvents :many
+-- Claims the oldest unprocessed events of the given types for a lease. Events claimed by
+-- another instance are skipped until their lease runs out; concurrent claims of the same
+-- event serialise on its claim row, so only one of them returns it.
+WITH candidates AS (
+  SELECT o.id
+  FROM outbox o
+  LEFT JOIN outbox_processing op ON o.id = op.outbox_id AND op.processor = @processor
+  LEFT JOIN outbox_claims c ON o.id = c.outbox_id AND c.processor = @processor
+  WHERE op.outbox_id IS NULL
+    AND o.event_type::text = ANY(@event_types::text[])
+    AND (c.outbox_id IS NULL OR c.claimed_until <= NOW())
+  ORDER BY o.created_at
+  LIMIT @limit_val
+  FOR UPDATE OF o SKIP LOCKED
+), claimed AS (
+  INSERT INTO outbox_claims (outbox_id, processor, claimed_until)
+  SELECT id, @processor, NOW() + make_interval(secs => @lease_seconds::int) FROM candidates
+  ON CONFLICT (outbox_id, processor) DO UPDATE SET claimed_until = EXCLUDED.claimed_until
+  WHERE outbox_claims.claimed_until <= NOW()
+  RETURNING outbox_id
+)
+SELECT o.*
+FROM outbox o
+JOIN claimed ON claimed.outbox_id = o.id
+ORDER BY o.created_at;
+
+-- name: ClaimMetricEventsByService :many
+-- Claims the oldest unprocessed METRIC_CREATED events of services whose series lease is
+-- free, expired or already held by the owner, and renews those leases. Services leased by
+-- another instance are skipped. A lease that changes owner gets a new generation.
+WITH candidates AS (
+  SELECT o.id, o.payload->>'service_id' AS service_id
+  FROM outbox o
+  LEFT JOIN outbox_processing op ON o.id = op.outbox_id AND op.processor = @processor
+  WHERE op.outbox_id IS NULL
+    AND o.event_type = 'METRIC_CREATED'
+    AND o.payload->>'service_id' IS NOT NULL
+    AND NOT EXISTS (
+      SELECT 1 FROM rule_series_leases l
+      WHERE l.service_id = o.payload->>'service_id'
+        AND l.owner <> @owner
+        AND l.leased_until > NOW()
+    )
+  ORDER BY o.created_at
+  LIMIT @limit_val
+), leased AS (
+  INSERT INTO rule_series_leases (service_id, owner, leased_until)
+  SELECT DISTINCT candidates.service_id, @owner, NOW() + make_interval(secs => @lease_seconds::int) FROM candidates
+  ON CONFLICT (service_id) DO UPDATE
+  SET owner = EXCLUDED.owner,
+      leased_until = EXCLUDED.leased_until,
+      generation = CASE
+        WHEN rule_series_leases.owner = EXCLUDED.owner THEN rule_series_leases.generation
+        ELSE rule_series_leases.generation + 1
+      END
+  WHERE rule_series_leases.owner = EXCLUDED.owner OR rule_series_leases.leased_until <= NOW()
+  RETURNING service_id, generation
+)
+SELECT sqlc.embed(o), leased.service_id AS lease_service_id, leased.generation AS lease_generation
+FROM candidates
+JOIN outbox o ON o.id = candidates.id
+JOIN leased ON leased.service_id = candidates.service_id
+ORDER BY o.created_at;
+
+-- name: ReleaseServiceLeases :exec
+-- Expires the service leases of an owner that stops, so other instances take the services
+-- over without waiting for the leases to run out. The rows are kept for their generation.
+UPDATE rule_series_leases
+SET leased_until = NOW()
+WHERE owner = @owner AND leased_until > NOW();
+
 -- name: MarkEventProcessed :exec
 INSERT INTO outbox_processing (outbox_id, processor)
 VALUES ($1, $2)
 ON CONFLICT (outbox_id, processor) DO NOTHING;
 
+-- name: MarkEventFailed :exec
+-- Dead-letters an event: it is marked processed with the reason it cannot be handled
+INSERT INTO outbox_processing (outbox_id, processor, error)
+VALUES ($1, $2, $3)
+ON CONFLICT (outbox_id, processor) DO NOTHING;
+
 -- name: CleanupOldEvents :exec
 DELETE FROM outbox
 WHERE created_at < NOW() - INTERVAL '7 days'
@@ -0,0 +1,29 @@
+-- name: ListRuleActions :many
+SELECT * FROM rule_actions
+ORDER BY id;
+
+-- name: GetRuleAction :one
+SELECT * FROM rule_actions
+WHERE id = $1;
+
+-- name: GetRuleActionForUpdate :one
+SELECT * FROM rule_actions
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CreateRuleAction :one
+INSERT INTO rule_actions (id, name, description, behavior, department_id, message_template)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: UpdateRuleAction :one
+-- The behaviour of an action is fixed: rules using it were validated against it
+UPDATE rule_actions
+SET name = $2, description = $3, department_id = $4, message_template = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRuleAction :execrows
+DELETE FROM rule_actions
+WHERE id = $1;
+
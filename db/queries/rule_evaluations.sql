@@ -0,0 +1,16 @@
+-- name: CreateRuleEvaluation :one
+INSERT INTO rule_evaluations (metric_id, rule_id, rule_revision, outcome, reason, value, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ListMetricEvaluations :many
+SELECT e.*, i.id AS incident_id
+FROM rule_evaluations e
+LEFT JOIN incidents i ON i.evaluation_id = e.id
+WHERE e.metric_id = $1
+ORDER BY e.id;
+
+-- name: DeleteRuleEvaluationsBefore :execrows
+DELETE FROM rule_evaluations e
+WHERE e.evaluated_at < $1
+  AND NOT EXISTS (SELECT 1 FROM incidents i WHERE i.evaluation_id = e.id);
@@ -0,0 +1,34 @@
+-- name: CreateRuleStatusChange :one
+INSERT INTO rule_status_changes (rule_id, from_status, to_status, actor, comment)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListRuleStatusChanges :many
+SELECT * FROM rule_status_changes
+WHERE rule_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountRuleStatusChanges :one
+SELECT COUNT(*) FROM rule_status_changes
+WHERE rule_id = $1;
+
+-- name: CreateDraftViolation :exec
+INSERT INTO draft_violations (rule_id, rule_revision, service_id, metric_id, metric_type, severity, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListDraftViolations :many
+SELECT * FROM draft_violations
+WHERE rule_id = $1
+ORDER BY recorded_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountDraftViolations :one
+SELECT COUNT(*) FROM draft_violations
+WHERE rule_id = $1;
+
+-- name: HasDraftViolationSince :one
+SELECT EXISTS (
+  SELECT 1 FROM draft_violations
+  WHERE rule_id = $1 AND service_id = $2 AND recorded_at >= $3
+);
@@ -0,0 +1,21 @@
+-- name: CreateRuleRevision :one
+-- Revisions are numbered per rule id, continuing after the rule was deleted and recreated
+INSERT INTO rule_revisions (rule_id, revision, action, actor, definition, diff, rolled_back_to)
+SELECT @rule_id, COALESCE(MAX(revision), 0) + 1, @action, @actor, @definition, @diff, sqlc.narg(rolled_back_to)
+FROM rule_revisions
+WHERE rule_id = @rule_id
+RETURNING *;
+
+-- name: GetRuleRevision :one
+SELECT * FROM rule_revisions
+WHERE rule_id = $1 AND revision = $2;
+
+-- name: ListRuleRevisions :many
+SELECT * FROM rule_revisions
+WHERE rule_id = $1
+ORDER BY revision DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountRuleRevisions :one
+SELECT COUNT(*) FROM rule_revisions
+WHERE rule_id = $1;
@@ -0,0 +1,11 @@
+-- name: RecordRuleSuppression :one
+INSERT INTO rule_suppressions (rule_id, service_id, reason, suppressed_count, last_suppressed_at)
+VALUES ($1, $2, $3, 1, NOW())
+ON CONFLICT (rule_id, service_id, reason) DO UPDATE
+SET suppressed_count = rule_suppressions.suppressed_count + 1, last_suppressed_at = NOW()
+RETURNING *;
+
+-- name: ListRuleSuppressions :many
+SELECT * FROM rule_suppressions
+WHERE rule_id = $1
+ORDER BY reason, service_id;
@@ -14,9 +14,43 @@ SELECT * FROM quality_rules
 WHERE is_active = TRUE AND metric_type = $1
 ORDER BY priority, id;
 
+-- name: ListActiveRulesForService :many
+-- Rules with an empty service_ids list apply to every service. Composite rules
+-- match on any of the metric types their condition depends on.
+SELECT * FROM quality_rules
+WHERE is_active = TRUE
+  AND (metric_type::text = @metric_type::text OR @metric_type::text = ANY(metric_types))
+  AND (cardinality(service_ids) = 0 OR @service_id::text = ANY(service_ids))
+ORDER BY priority, id;
+
+-- name: ListAbsenceChecks :many
+-- Pairs every active absence rule with the services in its scope, their latest metric of
+-- the rule's metric type if they ever reported one, since when the rule expects data
+-- from them and whether the rule has an unresolved incident for them
+SELECT
+  sqlc.embed(r),
+  s.id AS service_id,
+  m.id AS last_metric_id,
+  m.recorded_at AS last_recorded_at,
+  GREATEST(r.created_at, s.created_at)::timestamptz AS expected_since,
+  EXISTS (
+    SELECT 1 FROM incidents i
+    WHERE i.rule_id = r.id AND i.service_id = s.id AND i.status != 'CLOSED'
+  ) AS has_open_incident
+FROM quality_rules r
+JOIN services s ON cardinality(r.service_ids) = 0 OR s.id = ANY(r.service_ids)
+LEFT JOIN metrics m ON m.id = (
+  SELECT id FROM metrics
+  WHERE metrics.service_id = s.id AND metrics.metric_type = r.metric_type
+  ORDER BY recorded_at DESC
+  LIMIT 1
+)
+WHERE r.is_active = TRUE AND r.absence_seconds > 0
+ORDER BY r.id, s.id;
+
 -- name: ListRulesFiltered :many
 SELECT
-  r.*,
+  sqlc.embed(r),
   COALESCE(tc.trigger_count, 0)::int AS trigger_count
 FROM quality_rules r
 LEFT JOIN (
@@ -28,6 +62,9 @@ WHERE
   (sqlc.narg(filter_metric_type)::metric_type IS NULL OR r.metric_type = sqlc.narg(filter_metric_type))
   AND (sqlc.narg(filter_severity)::incident_severity IS NULL OR r.severity = sqlc.narg(filter_severity))
   AND (sqlc.narg(filter_is_active)::boolean IS NULL OR r.is_active = sqlc.narg(filter_is_active))
+  AND (sqlc.narg(filter_service_id)::text IS NULL OR cardinality(r.service_ids) = 0 OR sqlc.narg(filter_service_id) = ANY(r.service_ids))
+  AND (sqlc.narg(filter_status)::rule_status IS NULL OR r.status = sqlc.narg(filter_status))
+  AND (sqlc.narg(filter_department_id)::text IS NULL OR r.department_id = sqlc.narg(filter_department_id))
   AND (sqlc.narg(filter_search)::text IS NULL OR (
     r.id ILIKE '%' || sqlc.narg(filter_search) || '%'
     OR CAST(r.threshold AS TEXT) ILIKE '%' || sqlc.narg(filter_search) || '%'
@@ -56,22 +93,62 @@ WHERE
   (sqlc.narg(filter_metric_type)::metric_type IS NULL OR metric_type = sqlc.narg(filter_metric_type))
   AND (sqlc.narg(filter_severity)::incident_severity IS NULL OR severity = sqlc.narg(filter_severity))
   AND (sqlc.narg(filter_is_active)::boolean IS NULL OR is_active = sqlc.narg(filter_is_active))
+  AND (sqlc.narg(filter_service_id)::text IS NULL OR cardinality(service_ids) = 0 OR sqlc.narg(filter_service_id) = ANY(service_ids))
+  AND (sqlc.narg(filter_status)::rule_status IS NULL OR status = sqlc.narg(filter_status))
+  AND (sqlc.narg(filter_department_id)::text IS NULL OR department_id = sqlc.narg(filter_department_id))
   AND (sqlc.narg(filter_search)::text IS NULL OR (
     id ILIKE '%' || sqlc.narg(filter_search) || '%'
     OR CAST(threshold AS TEXT) ILIKE '%' || sqlc.narg(filter_search) || '%'
   ));
 
 -- name: CreateRule :one
-INSERT INTO quality_rules (id, metric_type, threshold, operator, action, priority, severity, is_active, department_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+INSERT INTO quality_rules (id, metric_type, threshold, operator, action, priority, severity, is_active, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds,
+  condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds,
+  webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal,
+  change_mode, change_seconds, absence_seconds, schedule, tiers,
+  forecast_method, forecast_window_seconds, forecast_horizon_seconds)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34,
+  $35, $36, $37)
 RETURNING *;
 
 -- name: UpdateRule :one
 UPDATE quality_rules
-SET metric_type = $2, threshold = $3, operator = $4, action = $5, priority = $6, severity = $7, is_active = $8, department_id = $9
+SET metric_type = $2, threshold = $3, operator = $4, action = $5, priority = $6, severity = $7, is_active = $8, department_id = $9, service_ids = $10,
+    for_seconds = $11, window_samples = $12, min_violations = $13, aggregation = $14, window_seconds = $15,
+    condition = $16, freshness_seconds = $17, metric_types = $18,
+    auto_resolve = $19, recovery_threshold = $20, recovery_samples = $21, cooldown_seconds = $22,
+    webhook_url = $23, webhook_headers = $24, webhook_body_template = $25, expression = $26,
+    anomaly_deviations = $27, anomaly_direction = $28, anomaly_seasonal = $29,
+    change_mode = $30, change_seconds = $31, absence_seconds = $32, schedule = $33, tiers = $34,
+    forecast_method = $35, forecast_window_seconds = $36, forecast_horizon_seconds = $37
+WHERE id = $1
+RETURNING *;
+
+-- name: ListRulesForUpdate :many
+SELECT * FROM quality_rules ORDER BY id FOR UPDATE;
+
+-- name: GetRuleForUpdate :one
+SELECT * FROM quality_rules WHERE id = $1 FOR UPDATE;
+
+-- name: SetRuleRevision :one
+UPDATE quality_rules
+SET revision = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: SetRuleStatus :one
+UPDATE quality_rules
+SET status = $2
 WHERE id = $1
 RETURNING *;
 
+-- name: DraftRule :one
+-- Returns a changed rule to draft; its author is who changed it and its owner who created it
+UPDATE quality_rules
+SET status = 'DRAFT', author = @author::varchar, owner = COALESCE(owner, @author::varchar)
+WHERE id = @id
+RETURNING *;
+
 -- name: DeleteRule :exec
 DELETE FROM quality_rules WHERE id = $1;
 
@@ -83,21 +160,25 @@ RETURNING *;
 
 -- name: GetTopTriggeredRules :many
 SELECT
-  r.id,
-  r.metric_type,
-  r.threshold,
-  r.operator,
-  r.action,
-  r.priority,
-  r.severity,
-  r.is_active,
-  r.department_id,
-  r.created_at,
-  r.updated_at,
+  sqlc.embed(r),
   COUNT(i.id)::int AS trigger_count,
-  MAX(i.opened_at) AS last_triggered_at
+  MAX(i.opened_at) AS last_triggered_at,
+  COALESCE(s.throttled_count, 0)::int AS throttled_count,
+  COALESCE(s.silenced_count, 0)::int AS silenced_count,
+  COALESCE(s.shadowed_count, 0)::int AS shadowed_count,
+  COALESCE(s.draft_count, 0)::int AS draft_count
 FROM quality_rules r
 LEFT JOIN incidents i ON r.id = i.rule_id
-GROUP BY r.id
+LEFT JOIN (
+  SELECT
+    rule_id,
+    SUM(suppressed_count) FILTER (WHERE reason = 'THROTTLED') AS throttled_count,
+    SUM(suppressed_count) FILTER (WHERE reason = 'SILENCED') AS silenced_count,
+    SUM(suppressed_count) FILTER (WHERE reason = 'SHADOWED') AS shadowed_count,
+    SUM(suppressed_count) FILTER (WHERE reason = 'DRAFT') AS draft_count
+  FROM rule_suppressions
+  GROUP BY rule_id
+) s ON r.id = s.rule_id
+GROUP BY r.id, s.throttled_count, s.silenced_count, s.shadowed_count, s.draft_count
 ORDER BY COUNT(i.id) DESC, r.priority ASC
 LIMIT $1;
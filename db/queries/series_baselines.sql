@@ -0,0 +1,16 @@
+-- name: ListSeriesBaselines :many
+SELECT * FROM series_baselines
+WHERE service_id = $1 AND metric_type = $2
+ORDER BY bucket;
+
+-- name: UpsertSeriesBaseline :exec
+-- Only moves a baseline forward, so replaying a sample does not learn from it twice
+INSERT INTO series_baselines (service_id, metric_type, bucket, mean, variance, sample_count, last_recorded_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (service_id, metric_type, bucket) DO UPDATE
+SET mean = EXCLUDED.mean,
+    variance = EXCLUDED.variance,
+    sample_count = EXCLUDED.sample_count,
+    last_recorded_at = EXCLUDED.last_recorded_at,
+    updated_at = NOW()
+WHERE series_baselines.last_recorded_at < EXCLUDED.last_recorded_at;
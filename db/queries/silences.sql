@@ -0,0 +1,55 @@
+-- name: CreateSilence :one
+INSERT INTO silences (service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetSilence :one
+SELECT * FROM silences
+WHERE id = $1 LIMIT 1;
+
+-- name: ListSilences :many
+SELECT * FROM silences
+ORDER BY starts_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: CountSilences :one
+SELECT COUNT(*) FROM silences;
+
+-- name: ListCurrentSilences :many
+-- Silences whose overall range contains the time; recurring ones still need their weekly window checked
+SELECT * FROM silences
+WHERE starts_at <= sqlc.arg(at)::timestamptz
+  AND (ends_at IS NULL OR ends_at > sqlc.arg(at)::timestamptz)
+ORDER BY starts_at;
+
+-- name: ListSilencesBetween :many
+-- Silences whose overall range overlaps the time range, for replaying rules over it
+SELECT * FROM silences
+WHERE starts_at < sqlc.arg(to_time)::timestamptz
+  AND (ends_at IS NULL OR ends_at > sqlc.arg(from_time)::timestamptz)
+ORDER BY starts_at;
+
+-- name: UpdateSilence :one
+UPDATE silences
+SET service_id = $2, metric_type = $3, rule_id = $4, severity = $5,
+    starts_at = $6, ends_at = $7, recurrence = $8, author = $9, reason = $10
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteSilence :execrows
+DELETE FROM silences
+WHERE id = $1;
+
+-- name: CreateSilencedViolation :exec
+INSERT INTO silenced_violations (silence_id, rule_id, service_id, metric_id, metric_type, severity, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListSilencedViolations :many
+SELECT * FROM silenced_violations
+WHERE silence_id = $1
+ORDER BY suppressed_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountSilencedViolations :one
+SELECT COUNT(*) FROM silenced_violations
+WHERE silence_id = $1;
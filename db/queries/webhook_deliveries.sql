@@ -0,0 +1,69 @@
+-- name: EnqueueWebhookOutsideCooldown :execrows
+-- Writes the WEBHOOK_REQUESTED outbox event only when the rule requested no delivery for
+-- the service within the cooldown. Concurrent requests serialise on the cooldown row, so
+-- exactly one of them is enqueued.
+WITH claimed AS (
+  INSERT INTO webhook_cooldowns (rule_id, service_id, requested_at)
+  VALUES (@rule_id, @service_id, NOW())
+  ON CONFLICT (rule_id, service_id) DO UPDATE SET requested_at = NOW()
+  WHERE webhook_cooldowns.requested_at <= NOW() - make_interval(secs => @cooldown_seconds::int)
+  RETURNING rule_id
+)
+INSERT INTO outbox (event_type, aggregate_type, aggregate_id, payload)
+SELECT 'WEBHOOK_REQUESTED', 'rule', claimed.rule_id, @payload FROM claimed;
+
+-- name: CreateWebhookDelivery :exec
+-- Outbox events may be seen more than once; the delivery is created only the first time.
+INSERT INTO webhook_deliveries (outbox_id, rule_id, service_id, url, headers, body)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (outbox_id) DO NOTHING;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Pushes next_attempt_at past the lease so other workers skip the claimed deliveries.
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + make_interval(secs => @lease_seconds::int)
+WHERE id IN (
+  SELECT id FROM webhook_deliveries
+  WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+  ORDER BY next_attempt_at
+  LIMIT @limit_val
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateWebhookDeliveryAttempt :one
+INSERT INTO webhook_delivery_attempts (delivery_id, attempt_number, status_code, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: MarkWebhookDelivered :one
+UPDATE webhook_deliveries
+SET status = 'DELIVERED', attempt_count = $2, last_error = NULL, delivered_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ScheduleWebhookRetry :one
+UPDATE webhook_deliveries
+SET attempt_count = $2, last_error = $3, next_attempt_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkWebhookFailed :one
+UPDATE webhook_deliveries
+SET status = 'FAILED', attempt_count = $2, last_error = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookDeliveriesForRule :many
+SELECT * FROM webhook_deliveries
+WHERE rule_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountWebhookDeliveriesForRule :one
+SELECT COUNT(*)::int FROM webhook_deliveries WHERE rule_id = $1;
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = ANY(@delivery_ids::uuid[])
+ORDER BY delivery_id, attempt_number;
@@ -12,11 +12,19 @@ INSERT INTO services (id, name) VALUES
     ('S8', 'Platinum')
 ON CONFLICT (id) DO NOTHING;
 
+-- Rule Actions (the built-in actions are registered by the migrations)
+INSERT INTO rule_actions (id, name, description, behavior, department_id, message_template) VALUES
+    ('QUALITY_ALERT', 'Quality alert', 'Opens an incident for the network team', 'OPEN_INCIDENT', 'network-team',
+        '{{.ServiceID}} quality alert: {{.Message}}'),
+    ('STREAMING_WARNING', 'Streaming warning', 'Notifies the operations team without opening an incident', 'NOTIFY_ONLY', 'ops-team',
+        '{{.ServiceID}} streaming warning: {{.Message}}')
+ON CONFLICT (id) DO NOTHING;
+
 -- Quality Rules
 INSERT INTO quality_rules (id, metric_type, threshold, operator, action, priority, severity, is_active) VALUES
     ('QR-01', 'LATENCY_MS', 150.0, '>', 'OPEN_INCIDENT', 1, 'HIGH', TRUE),
-    ('QR-02', 'PACKET_LOSS', 1.5, '>', 'OPEN_INCIDENT', 2, 'MEDIUM', TRUE),
-    ('QR-03', 'BUFFER_RATIO', 6.0, '>', 'OPEN_INCIDENT', 2, 'MEDIUM', TRUE),
+    ('QR-02', 'PACKET_LOSS', 1.5, '>', 'QUALITY_ALERT', 2, 'MEDIUM', TRUE),
+    ('QR-03', 'BUFFER_RATIO', 6.0, '>', 'STREAMING_WARNING', 2, 'MEDIUM', TRUE),
     ('QR-04', 'ERROR_RATE', 5.0, '>', 'OPEN_INCIDENT', 1, 'CRITICAL', TRUE),
     ('QR-05', 'LATENCY_MS', 300.0, '>', 'OPEN_INCIDENT', 1, 'CRITICAL', TRUE),
     ('QR-06', 'PACKET_LOSS', 5.0, '>', 'OPEN_INCIDENT', 1, 'HIGH', TRUE),
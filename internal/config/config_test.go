@@ -14,6 +14,7 @@ func TestLoad_Defaults(t *testing.T) {
 		"DATABASE_URL",
 		"ELASTICSEARCH_URL",
 		"ELASTICSEARCH_INDEX",
+		"WEBHOOK_SIGNING_SECRET",
 	}
 
 	for _, env := range envVars {
@@ -52,6 +53,14 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.WorkerPollInterval != 1 {
 		t.Errorf("Expected WorkerPollInterval=1, got %d", cfg.WorkerPollInterval)
 	}
+
+	if cfg.AbsenceCheckInterval != 10 {
+		t.Errorf("Expected AbsenceCheckInterval=10, got %d", cfg.AbsenceCheckInterval)
+	}
+
+	if cfg.WebhookSigningSecret != "" {
+		t.Errorf("Expected no default WebhookSigningSecret, got %s", cfg.WebhookSigningSecret)
+	}
 }
 
 func TestLoad_WithEnvVars(t *testing.T) {
@@ -227,3 +236,36 @@ func TestConfigStruct(t *testing.T) {
 		t.Errorf("WorkerPollInterval mismatch")
 	}
 }
+
+func TestLoad_Evaluations(t *testing.T) {
+	os.Unsetenv("RECORD_EVALUATIONS")
+	os.Unsetenv("EVALUATION_RETENTION_HOURS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if cfg.RecordEvaluations || cfg.EvaluationRetentionHours != 24 {
+		t.Errorf("Expected evaluations off with 24h retention, got %v %d", cfg.RecordEvaluations, cfg.EvaluationRetentionHours)
+	}
+
+	os.Setenv("RECORD_EVALUATIONS", "true")
+	os.Setenv("EVALUATION_RETENTION_HOURS", "6")
+	defer os.Unsetenv("RECORD_EVALUATIONS")
+	defer os.Unsetenv("EVALUATION_RETENTION_HOURS")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if !cfg.RecordEvaluations || cfg.EvaluationRetentionHours != 6 {
+		t.Errorf("Expected evaluations on with 6h retention, got %v %d", cfg.RecordEvaluations, cfg.EvaluationRetentionHours)
+	}
+
+	for _, v := range []string{"0", "-1", "day"} {
+		os.Setenv("EVALUATION_RETENTION_HOURS", v)
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected EVALUATION_RETENTION_HOURS=%q to be rejected", v)
+		}
+	}
+}
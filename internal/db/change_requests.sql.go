@@ -0,0 +1,278 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: change_requests.sql
+
+package db
+
+import (
+	"context"
+)
+
+const countChangeRequests = `-- name: CountChangeRequests :one
+SELECT COUNT(*)::int FROM change_requests c
+WHERE
+  ($1::change_request_status IS NULL OR c.status = $1)
+  AND ($2::change_target IS NULL OR c.target = $2)
+  AND ($3::text IS NULL OR c.target_id = $3)
+  AND ($4::text IS NULL
+    OR (c.target = 'RULE' AND EXISTS (
+      SELECT 1 FROM quality_rules r WHERE r.id = c.target_id AND r.department_id = $4))
+    OR (c.target = 'ACTION' AND EXISTS (
+      SELECT 1 FROM rule_actions a WHERE a.id = c.target_id AND a.department_id = $4)))
+`
+
+type CountChangeRequestsParams struct {
+	FilterStatus       NullChangeRequestStatus `json:"filter_status"`
+	FilterTarget       NullChangeTarget        `json:"filter_target"`
+	FilterTargetID     *string                 `json:"filter_target_id"`
+	FilterDepartmentID *string                 `json:"filter_department_id"`
+}
+
+func (q *Queries) CountChangeRequests(ctx context.Context, arg CountChangeRequestsParams) (int32, error) {
+	row := q.db.QueryRow(ctx, countChangeRequests,
+		arg.FilterStatus,
+		arg.FilterTarget,
+		arg.FilterTargetID,
+		arg.FilterDepartmentID,
+	)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const countPublishedRulesUsingAction = `-- name: CountPublishedRulesUsingAction :one
+SELECT COUNT(*)::int FROM quality_rules
+WHERE action = $1 AND status = 'PUBLISHED'
+`
+
+func (q *Queries) CountPublishedRulesUsingAction(ctx context.Context, action string) (int32, error) {
+	row := q.db.QueryRow(ctx, countPublishedRulesUsingAction, action)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const createChangeRequest = `-- name: CreateChangeRequest :one
+INSERT INTO change_requests (target, target_id, kind, payload, requested_by, comment)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, target, target_id, kind, payload, requested_by, comment, status, reviewed_by, review_comment, created_at, reviewed_at
+`
+
+type CreateChangeRequestParams struct {
+	Target      ChangeTarget `json:"target"`
+	TargetID    string       `json:"target_id"`
+	Kind        ChangeKind   `json:"kind"`
+	Payload     []byte       `json:"payload"`
+	RequestedBy string       `json:"requested_by"`
+	Comment     *string      `json:"comment"`
+}
+
+func (q *Queries) CreateChangeRequest(ctx context.Context, arg CreateChangeRequestParams) (ChangeRequest, error) {
+	row := q.db.QueryRow(ctx, createChangeRequest,
+		arg.Target,
+		arg.TargetID,
+		arg.Kind,
+		arg.Payload,
+		arg.RequestedBy,
+		arg.Comment,
+	)
+	var i ChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Target,
+		&i.TargetID,
+		&i.Kind,
+		&i.Payload,
+		&i.RequestedBy,
+		&i.Comment,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewComment,
+		&i.CreatedAt,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const getChangeRequest = `-- name: GetChangeRequest :one
+SELECT id, target, target_id, kind, payload, requested_by, comment, status, reviewed_by, review_comment, created_at, reviewed_at FROM change_requests WHERE id = $1
+`
+
+func (q *Queries) GetChangeRequest(ctx context.Context, id int64) (ChangeRequest, error) {
+	row := q.db.QueryRow(ctx, getChangeRequest, id)
+	var i ChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Target,
+		&i.TargetID,
+		&i.Kind,
+		&i.Payload,
+		&i.RequestedBy,
+		&i.Comment,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewComment,
+		&i.CreatedAt,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const getChangeRequestForUpdate = `-- name: GetChangeRequestForUpdate :one
+SELECT id, target, target_id, kind, payload, requested_by, comment, status, reviewed_by, review_comment, created_at, reviewed_at FROM change_requests WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetChangeRequestForUpdate(ctx context.Context, id int64) (ChangeRequest, error) {
+	row := q.db.QueryRow(ctx, getChangeRequestForUpdate, id)
+	var i ChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Target,
+		&i.TargetID,
+		&i.Kind,
+		&i.Payload,
+		&i.RequestedBy,
+		&i.Comment,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewComment,
+		&i.CreatedAt,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const getPendingChangeRequest = `-- name: GetPendingChangeRequest :one
+SELECT id, target, target_id, kind, payload, requested_by, comment, status, reviewed_by, review_comment, created_at, reviewed_at FROM change_requests
+WHERE target = $1 AND target_id = $2 AND status = 'PENDING'
+`
+
+type GetPendingChangeRequestParams struct {
+	Target   ChangeTarget `json:"target"`
+	TargetID string       `json:"target_id"`
+}
+
+func (q *Queries) GetPendingChangeRequest(ctx context.Context, arg GetPendingChangeRequestParams) (ChangeRequest, error) {
+	row := q.db.QueryRow(ctx, getPendingChangeRequest, arg.Target, arg.TargetID)
+	var i ChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Target,
+		&i.TargetID,
+		&i.Kind,
+		&i.Payload,
+		&i.RequestedBy,
+		&i.Comment,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewComment,
+		&i.CreatedAt,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const listChangeRequests = `-- name: ListChangeRequests :many
+SELECT c.id, c.target, c.target_id, c.kind, c.payload, c.requested_by, c.comment, c.status, c.reviewed_by, c.review_comment, c.created_at, c.reviewed_at FROM change_requests c
+WHERE
+  ($1::change_request_status IS NULL OR c.status = $1)
+  AND ($2::change_target IS NULL OR c.target = $2)
+  AND ($3::text IS NULL OR c.target_id = $3)
+  AND ($4::text IS NULL
+    OR (c.target = 'RULE' AND EXISTS (
+      SELECT 1 FROM quality_rules r WHERE r.id = c.target_id AND r.department_id = $4))
+    OR (c.target = 'ACTION' AND EXISTS (
+      SELECT 1 FROM rule_actions a WHERE a.id = c.target_id AND a.department_id = $4)))
+ORDER BY c.id DESC
+LIMIT $6 OFFSET $5
+`
+
+type ListChangeRequestsParams struct {
+	FilterStatus       NullChangeRequestStatus `json:"filter_status"`
+	FilterTarget       NullChangeTarget        `json:"filter_target"`
+	FilterTargetID     *string                 `json:"filter_target_id"`
+	FilterDepartmentID *string                 `json:"filter_department_id"`
+	OffsetVal          int32                   `json:"offset_val"`
+	LimitVal           int32                   `json:"limit_val"`
+}
+
+// department_id matches the department of the rule, or of the action, a request changes
+func (q *Queries) ListChangeRequests(ctx context.Context, arg ListChangeRequestsParams) ([]ChangeRequest, error) {
+	rows, err := q.db.Query(ctx, listChangeRequests,
+		arg.FilterStatus,
+		arg.FilterTarget,
+		arg.FilterTargetID,
+		arg.FilterDepartmentID,
+		arg.OffsetVal,
+		arg.LimitVal,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangeRequest{}
+	for rows.Next() {
+		var i ChangeRequest
+		if err := rows.Scan(
+			&i.ID,
+			&i.Target,
+			&i.TargetID,
+			&i.Kind,
+			&i.Payload,
+			&i.RequestedBy,
+			&i.Comment,
+			&i.Status,
+			&i.ReviewedBy,
+			&i.ReviewComment,
+			&i.CreatedAt,
+			&i.ReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reviewChangeRequest = `-- name: ReviewChangeRequest :one
+UPDATE change_requests
+SET status = $2, reviewed_by = $3, review_comment = $4, reviewed_at = NOW()
+WHERE id = $1
+RETURNING id, target, target_id, kind, payload, requested_by, comment, status, reviewed_by, review_comment, created_at, reviewed_at
+`
+
+type ReviewChangeRequestParams struct {
+	ID            int64               `json:"id"`
+	Status        ChangeRequestStatus `json:"status"`
+	ReviewedBy    *string             `json:"reviewed_by"`
+	ReviewComment *string             `json:"review_comment"`
+}
+
+func (q *Queries) ReviewChangeRequest(ctx context.Context, arg ReviewChangeRequestParams) (ChangeRequest, error) {
+	row := q.db.QueryRow(ctx, reviewChangeRequest,
+		arg.ID,
+		arg.Status,
+		arg.ReviewedBy,
+		arg.ReviewComment,
+	)
+	var i ChangeRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Target,
+		&i.TargetID,
+		&i.Kind,
+		&i.Payload,
+		&i.RequestedBy,
+		&i.Comment,
+		&i.Status,
+		&i.ReviewedBy,
+		&i.ReviewComment,
+		&i.CreatedAt,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
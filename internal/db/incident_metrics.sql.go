@@ -0,0 +1,62 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: incident_metrics.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const addIncidentMetric = `-- name: AddIncidentMetric :exec
+INSERT INTO incident_metrics (incident_id, metric_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddIncidentMetricParams struct {
+	IncidentID string    `json:"incident_id"`
+	MetricID   uuid.UUID `json:"metric_id"`
+}
+
+func (q *Queries) AddIncidentMetric(ctx context.Context, arg AddIncidentMetricParams) error {
+	_, err := q.db.Exec(ctx, addIncidentMetric, arg.IncidentID, arg.MetricID)
+	return err
+}
+
+const listIncidentMetrics = `-- name: ListIncidentMetrics :many
+SELECT m.id, m.service_id, m.metric_type, m.value, m.recorded_at, m.created_at FROM incident_metrics im
+JOIN metrics m ON m.id = im.metric_id
+WHERE im.incident_id = $1
+ORDER BY m.metric_type, m.recorded_at
+`
+
+func (q *Queries) ListIncidentMetrics(ctx context.Context, incidentID string) ([]Metric, error) {
+	rows, err := q.db.Query(ctx, listIncidentMetrics, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Metric{}
+	for rows.Next() {
+		var i Metric
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.MetricType,
+			&i.Value,
+			&i.RecordedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
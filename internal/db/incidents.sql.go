@@ -16,7 +16,7 @@ const closeIncident = `-- name: CloseIncident :one
 UPDATE incidents
 SET status = 'CLOSED', closed_at = NOW()
 WHERE id = $1
-RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at
+RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id
 `
 
 func (q *Queries) CloseIncident(ctx context.Context, id string) (Incident, error) {
@@ -35,6 +35,11 @@ func (q *Queries) CloseIncident(ctx context.Context, id string) (Incident, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
 	)
 	return i, err
 }
@@ -95,20 +100,22 @@ func (q *Queries) CountOpenIncidentsByService(ctx context.Context, serviceID str
 }
 
 const createIncident = `-- name: CreateIncident :one
-INSERT INTO incidents (id, service_id, rule_id, metric_id, severity, status, message, opened_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at
+INSERT INTO incidents (id, service_id, rule_id, metric_id, severity, status, message, opened_at, rule_revision, evaluation_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id
 `
 
 type CreateIncidentParams struct {
-	ID        string           `json:"id"`
-	ServiceID string           `json:"service_id"`
-	RuleID    string           `json:"rule_id"`
-	MetricID  uuid.UUID        `json:"metric_id"`
-	Severity  IncidentSeverity `json:"severity"`
-	Status    IncidentStatus   `json:"status"`
-	Message   *string          `json:"message"`
-	OpenedAt  time.Time        `json:"opened_at"`
+	ID           string           `json:"id"`
+	ServiceID    string           `json:"service_id"`
+	RuleID       string           `json:"rule_id"`
+	MetricID     *uuid.UUID       `json:"metric_id"`
+	Severity     IncidentSeverity `json:"severity"`
+	Status       IncidentStatus   `json:"status"`
+	Message      *string          `json:"message"`
+	OpenedAt     time.Time        `json:"opened_at"`
+	RuleRevision *int32           `json:"rule_revision"`
+	EvaluationID *int64           `json:"evaluation_id"`
 }
 
 func (q *Queries) CreateIncident(ctx context.Context, arg CreateIncidentParams) (Incident, error) {
@@ -121,6 +128,8 @@ func (q *Queries) CreateIncident(ctx context.Context, arg CreateIncidentParams)
 		arg.Status,
 		arg.Message,
 		arg.OpenedAt,
+		arg.RuleRevision,
+		arg.EvaluationID,
 	)
 	var i Incident
 	err := row.Scan(
@@ -136,12 +145,17 @@ func (q *Queries) CreateIncident(ctx context.Context, arg CreateIncidentParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
 	)
 	return i, err
 }
 
 const getIncident = `-- name: GetIncident :one
-SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at FROM incidents WHERE id = $1
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents WHERE id = $1
 `
 
 func (q *Queries) GetIncident(ctx context.Context, id string) (Incident, error) {
@@ -160,12 +174,97 @@ func (q *Queries) GetIncident(ctx context.Context, id string) (Incident, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
+	)
+	return i, err
+}
+
+const getLastIncidentOpenedAt = `-- name: GetLastIncidentOpenedAt :one
+SELECT opened_at FROM incidents
+WHERE rule_id = $1 AND service_id = $2
+ORDER BY opened_at DESC
+LIMIT 1
+`
+
+type GetLastIncidentOpenedAtParams struct {
+	RuleID    string `json:"rule_id"`
+	ServiceID string `json:"service_id"`
+}
+
+func (q *Queries) GetLastIncidentOpenedAt(ctx context.Context, arg GetLastIncidentOpenedAtParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, getLastIncidentOpenedAt, arg.RuleID, arg.ServiceID)
+	var opened_at time.Time
+	err := row.Scan(&opened_at)
+	return opened_at, err
+}
+
+const getLastUnresolvedViolation = `-- name: GetLastUnresolvedViolation :one
+SELECT m.recorded_at
+FROM incidents i
+JOIN metrics m ON m.id = i.metric_id
+WHERE i.rule_id = $1 AND i.service_id = $2 AND i.status != 'CLOSED'
+ORDER BY m.recorded_at DESC
+LIMIT 1
+`
+
+type GetLastUnresolvedViolationParams struct {
+	RuleID    string `json:"rule_id"`
+	ServiceID string `json:"service_id"`
+}
+
+// Returns when the latest violation attached to an unresolved incident of a rule and
+// service was recorded
+func (q *Queries) GetLastUnresolvedViolation(ctx context.Context, arg GetLastUnresolvedViolationParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, getLastUnresolvedViolation, arg.RuleID, arg.ServiceID)
+	var recorded_at time.Time
+	err := row.Scan(&recorded_at)
+	return recorded_at, err
+}
+
+const getUnresolvedIncidentForRule = `-- name: GetUnresolvedIncidentForRule :one
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
+WHERE rule_id = $1 AND service_id = $2 AND status != 'CLOSED'
+ORDER BY opened_at DESC
+LIMIT 1
+FOR UPDATE
+`
+
+type GetUnresolvedIncidentForRuleParams struct {
+	RuleID    string `json:"rule_id"`
+	ServiceID string `json:"service_id"`
+}
+
+func (q *Queries) GetUnresolvedIncidentForRule(ctx context.Context, arg GetUnresolvedIncidentForRuleParams) (Incident, error) {
+	row := q.db.QueryRow(ctx, getUnresolvedIncidentForRule, arg.RuleID, arg.ServiceID)
+	var i Incident
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.RuleID,
+		&i.MetricID,
+		&i.Severity,
+		&i.Status,
+		&i.Message,
+		&i.OpenedAt,
+		&i.ClosedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
 	)
 	return i, err
 }
 
 const listIncidents = `-- name: ListIncidents :many
-SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at FROM incidents
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
 ORDER BY opened_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -197,6 +296,11 @@ func (q *Queries) ListIncidents(ctx context.Context, arg ListIncidentsParams) ([
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.InProgressAt,
+			&i.OccurrenceCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.RuleRevision,
+			&i.EvaluationID,
 		); err != nil {
 			return nil, err
 		}
@@ -209,7 +313,7 @@ func (q *Queries) ListIncidents(ctx context.Context, arg ListIncidentsParams) ([
 }
 
 const listIncidentsByService = `-- name: ListIncidentsByService :many
-SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at FROM incidents
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
 WHERE service_id = $1
 ORDER BY opened_at DESC
 LIMIT $2 OFFSET $3
@@ -243,6 +347,11 @@ func (q *Queries) ListIncidentsByService(ctx context.Context, arg ListIncidentsB
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.InProgressAt,
+			&i.OccurrenceCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.RuleRevision,
+			&i.EvaluationID,
 		); err != nil {
 			return nil, err
 		}
@@ -255,7 +364,7 @@ func (q *Queries) ListIncidentsByService(ctx context.Context, arg ListIncidentsB
 }
 
 const listIncidentsByStatus = `-- name: ListIncidentsByStatus :many
-SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at FROM incidents
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
 WHERE status = $1
 ORDER BY opened_at DESC
 LIMIT $2 OFFSET $3
@@ -289,6 +398,11 @@ func (q *Queries) ListIncidentsByStatus(ctx context.Context, arg ListIncidentsBy
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.InProgressAt,
+			&i.OccurrenceCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.RuleRevision,
+			&i.EvaluationID,
 		); err != nil {
 			return nil, err
 		}
@@ -301,7 +415,7 @@ func (q *Queries) ListIncidentsByStatus(ctx context.Context, arg ListIncidentsBy
 }
 
 const listIncidentsFiltered = `-- name: ListIncidentsFiltered :many
-SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at FROM incidents
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
 WHERE
   ($1::incident_status IS NULL OR status = $1)
   AND ($2::incident_severity IS NULL OR severity = $2)
@@ -322,6 +436,10 @@ ORDER BY
   CASE WHEN $5::text = 'service_id' AND $6::text = 'desc' THEN service_id END DESC,
   CASE WHEN $5::text = 'opened_at' AND $6::text = 'asc' THEN opened_at END ASC,
   CASE WHEN $5::text = 'opened_at' AND $6::text = 'desc' THEN opened_at END DESC,
+  CASE WHEN $5::text = 'occurrence_count' AND $6::text = 'asc' THEN occurrence_count END ASC,
+  CASE WHEN $5::text = 'occurrence_count' AND $6::text = 'desc' THEN occurrence_count END DESC,
+  CASE WHEN $5::text = 'last_seen_at' AND $6::text = 'asc' THEN last_seen_at END ASC,
+  CASE WHEN $5::text = 'last_seen_at' AND $6::text = 'desc' THEN last_seen_at END DESC,
   opened_at DESC
 LIMIT $8 OFFSET $7
 `
@@ -368,6 +486,11 @@ func (q *Queries) ListIncidentsFiltered(ctx context.Context, arg ListIncidentsFi
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.InProgressAt,
+			&i.OccurrenceCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.RuleRevision,
+			&i.EvaluationID,
 		); err != nil {
 			return nil, err
 		}
@@ -380,7 +503,7 @@ func (q *Queries) ListIncidentsFiltered(ctx context.Context, arg ListIncidentsFi
 }
 
 const listOpenIncidents = `-- name: ListOpenIncidents :many
-SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at FROM incidents
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
 WHERE status != 'CLOSED'
 ORDER BY severity, opened_at DESC
 LIMIT $1 OFFSET $2
@@ -413,6 +536,11 @@ func (q *Queries) ListOpenIncidents(ctx context.Context, arg ListOpenIncidentsPa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.InProgressAt,
+			&i.OccurrenceCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.RuleRevision,
+			&i.EvaluationID,
 		); err != nil {
 			return nil, err
 		}
@@ -424,6 +552,70 @@ func (q *Queries) ListOpenIncidents(ctx context.Context, arg ListOpenIncidentsPa
 	return items, nil
 }
 
+const listUnresolvedIncidentsForRule = `-- name: ListUnresolvedIncidentsForRule :many
+SELECT id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id FROM incidents
+WHERE rule_id = $1 AND service_id = $2 AND status != 'CLOSED'
+ORDER BY opened_at
+`
+
+type ListUnresolvedIncidentsForRuleParams struct {
+	RuleID    string `json:"rule_id"`
+	ServiceID string `json:"service_id"`
+}
+
+func (q *Queries) ListUnresolvedIncidentsForRule(ctx context.Context, arg ListUnresolvedIncidentsForRuleParams) ([]Incident, error) {
+	rows, err := q.db.Query(ctx, listUnresolvedIncidentsForRule, arg.RuleID, arg.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Incident{}
+	for rows.Next() {
+		var i Incident
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.RuleID,
+			&i.MetricID,
+			&i.Severity,
+			&i.Status,
+			&i.Message,
+			&i.OpenedAt,
+			&i.ClosedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InProgressAt,
+			&i.OccurrenceCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.RuleRevision,
+			&i.EvaluationID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockIncidentKey = `-- name: LockIncidentKey :exec
+SELECT pg_advisory_xact_lock(hashtext($1::text || '/' || $2::text))
+`
+
+type LockIncidentKeyParams struct {
+	RuleID    string `json:"rule_id"`
+	ServiceID string `json:"service_id"`
+}
+
+// Serializes incident creation for a rule+service pair until the transaction ends
+func (q *Queries) LockIncidentKey(ctx context.Context, arg LockIncidentKeyParams) error {
+	_, err := q.db.Exec(ctx, lockIncidentKey, arg.RuleID, arg.ServiceID)
+	return err
+}
+
 const nextIncidentID = `-- name: NextIncidentID :one
 SELECT CAST('INC-' || nextval('incident_id_seq')::TEXT AS VARCHAR) AS id
 `
@@ -435,11 +627,48 @@ func (q *Queries) NextIncidentID(ctx context.Context) (string, error) {
 	return id, err
 }
 
+const recordIncidentOccurrence = `-- name: RecordIncidentOccurrence :one
+UPDATE incidents
+SET occurrence_count = occurrence_count + 1, last_seen_at = NOW(), metric_id = COALESCE($2, metric_id)
+WHERE id = $1
+RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id
+`
+
+type RecordIncidentOccurrenceParams struct {
+	ID       string     `json:"id"`
+	MetricID *uuid.UUID `json:"metric_id"`
+}
+
+func (q *Queries) RecordIncidentOccurrence(ctx context.Context, arg RecordIncidentOccurrenceParams) (Incident, error) {
+	row := q.db.QueryRow(ctx, recordIncidentOccurrence, arg.ID, arg.MetricID)
+	var i Incident
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.RuleID,
+		&i.MetricID,
+		&i.Severity,
+		&i.Status,
+		&i.Message,
+		&i.OpenedAt,
+		&i.ClosedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
+	)
+	return i, err
+}
+
 const setIncidentInProgress = `-- name: SetIncidentInProgress :one
 UPDATE incidents
 SET status = 'IN_PROGRESS', in_progress_at = NOW()
 WHERE id = $1
-RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at
+RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id
 `
 
 func (q *Queries) SetIncidentInProgress(ctx context.Context, id string) (Incident, error) {
@@ -458,6 +687,101 @@ func (q *Queries) SetIncidentInProgress(ctx context.Context, id string) (Inciden
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
+	)
+	return i, err
+}
+
+const summarizeRuleIncidentsByService = `-- name: SummarizeRuleIncidentsByService :many
+SELECT
+  service_id,
+  COUNT(*)::int AS incident_count,
+  MIN(opened_at)::timestamptz AS first_opened_at,
+  MAX(opened_at)::timestamptz AS last_opened_at
+FROM incidents
+WHERE rule_id = $1
+  AND opened_at >= $2
+  AND opened_at <= $3
+GROUP BY service_id
+ORDER BY service_id
+`
+
+type SummarizeRuleIncidentsByServiceParams struct {
+	RuleID   string    `json:"rule_id"`
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+type SummarizeRuleIncidentsByServiceRow struct {
+	ServiceID     string    `json:"service_id"`
+	IncidentCount int32     `json:"incident_count"`
+	FirstOpenedAt time.Time `json:"first_opened_at"`
+	LastOpenedAt  time.Time `json:"last_opened_at"`
+}
+
+// Counts the incidents a rule opened in a time range per service
+func (q *Queries) SummarizeRuleIncidentsByService(ctx context.Context, arg SummarizeRuleIncidentsByServiceParams) ([]SummarizeRuleIncidentsByServiceRow, error) {
+	rows, err := q.db.Query(ctx, summarizeRuleIncidentsByService, arg.RuleID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SummarizeRuleIncidentsByServiceRow{}
+	for rows.Next() {
+		var i SummarizeRuleIncidentsByServiceRow
+		if err := rows.Scan(
+			&i.ServiceID,
+			&i.IncidentCount,
+			&i.FirstOpenedAt,
+			&i.LastOpenedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateIncidentSeverity = `-- name: UpdateIncidentSeverity :one
+UPDATE incidents
+SET severity = $2
+WHERE id = $1
+RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id
+`
+
+type UpdateIncidentSeverityParams struct {
+	ID       string           `json:"id"`
+	Severity IncidentSeverity `json:"severity"`
+}
+
+func (q *Queries) UpdateIncidentSeverity(ctx context.Context, arg UpdateIncidentSeverityParams) (Incident, error) {
+	row := q.db.QueryRow(ctx, updateIncidentSeverity, arg.ID, arg.Severity)
+	var i Incident
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.RuleID,
+		&i.MetricID,
+		&i.Severity,
+		&i.Status,
+		&i.Message,
+		&i.OpenedAt,
+		&i.ClosedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
 	)
 	return i, err
 }
@@ -466,7 +790,7 @@ const updateIncidentStatus = `-- name: UpdateIncidentStatus :one
 UPDATE incidents
 SET status = $2
 WHERE id = $1
-RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at
+RETURNING id, service_id, rule_id, metric_id, severity, status, message, opened_at, closed_at, created_at, updated_at, in_progress_at, occurrence_count, first_seen_at, last_seen_at, rule_revision, evaluation_id
 `
 
 type UpdateIncidentStatusParams struct {
@@ -490,6 +814,11 @@ func (q *Queries) UpdateIncidentStatus(ctx context.Context, arg UpdateIncidentSt
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InProgressAt,
+		&i.OccurrenceCount,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+		&i.RuleRevision,
+		&i.EvaluationID,
 	)
 	return i, err
 }
@@ -0,0 +1,67 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: metric_evaluation_policies.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getEvaluationPolicy = `-- name: GetEvaluationPolicy :one
+SELECT metric_type, policy, updated_at FROM metric_evaluation_policies
+WHERE metric_type = $1
+`
+
+func (q *Queries) GetEvaluationPolicy(ctx context.Context, metricType MetricType) (MetricEvaluationPolicy, error) {
+	row := q.db.QueryRow(ctx, getEvaluationPolicy, metricType)
+	var i MetricEvaluationPolicy
+	err := row.Scan(&i.MetricType, &i.Policy, &i.UpdatedAt)
+	return i, err
+}
+
+const listEvaluationPolicies = `-- name: ListEvaluationPolicies :many
+SELECT metric_type, policy, updated_at FROM metric_evaluation_policies
+ORDER BY metric_type
+`
+
+func (q *Queries) ListEvaluationPolicies(ctx context.Context) ([]MetricEvaluationPolicy, error) {
+	rows, err := q.db.Query(ctx, listEvaluationPolicies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MetricEvaluationPolicy{}
+	for rows.Next() {
+		var i MetricEvaluationPolicy
+		if err := rows.Scan(&i.MetricType, &i.Policy, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertEvaluationPolicy = `-- name: UpsertEvaluationPolicy :one
+INSERT INTO metric_evaluation_policies (metric_type, policy)
+VALUES ($1, $2)
+ON CONFLICT (metric_type) DO UPDATE
+SET policy = EXCLUDED.policy
+RETURNING metric_type, policy, updated_at
+`
+
+type UpsertEvaluationPolicyParams struct {
+	MetricType MetricType       `json:"metric_type"`
+	Policy     EvaluationPolicy `json:"policy"`
+}
+
+func (q *Queries) UpsertEvaluationPolicy(ctx context.Context, arg UpsertEvaluationPolicyParams) (MetricEvaluationPolicy, error) {
+	row := q.db.QueryRow(ctx, upsertEvaluationPolicy, arg.MetricType, arg.Policy)
+	var i MetricEvaluationPolicy
+	err := row.Scan(&i.MetricType, &i.Policy, &i.UpdatedAt)
+	return i, err
+}
@@ -200,6 +200,59 @@ func (q *Queries) GetMetricsAggregated(ctx context.Context, arg GetMetricsAggreg
 	return items, nil
 }
 
+const listBacktestMetrics = `-- name: ListBacktestMetrics :many
+SELECT id, service_id, metric_type, value, recorded_at, created_at FROM metrics
+WHERE metric_type::text = ANY($1::text[])
+  AND (cardinality($2::text[]) = 0 OR service_id = ANY($2::text[]))
+  AND recorded_at >= $3
+  AND recorded_at <= $4
+ORDER BY recorded_at ASC, id ASC
+LIMIT $5
+`
+
+type ListBacktestMetricsParams struct {
+	MetricTypes []string  `json:"metric_types"`
+	ServiceIds  []string  `json:"service_ids"`
+	FromTime    time.Time `json:"from_time"`
+	ToTime      time.Time `json:"to_time"`
+	LimitVal    int32     `json:"limit_val"`
+}
+
+// Returns the samples a rule is replayed against, oldest first. An empty service_ids
+// matches all services.
+func (q *Queries) ListBacktestMetrics(ctx context.Context, arg ListBacktestMetricsParams) ([]Metric, error) {
+	rows, err := q.db.Query(ctx, listBacktestMetrics,
+		arg.MetricTypes,
+		arg.ServiceIds,
+		arg.FromTime,
+		arg.ToTime,
+		arg.LimitVal,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Metric{}
+	for rows.Next() {
+		var i Metric
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.MetricType,
+			&i.Value,
+			&i.RecordedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMetrics = `-- name: ListMetrics :many
 SELECT id, service_id, metric_type, value, recorded_at, created_at FROM metrics
 ORDER BY recorded_at DESC
@@ -439,3 +492,55 @@ func (q *Queries) ListMetricsInRange(ctx context.Context, arg ListMetricsInRange
 	}
 	return items, nil
 }
+
+const listRecentSeriesMetrics = `-- name: ListRecentSeriesMetrics :many
+SELECT id, service_id, metric_type, value, recorded_at, created_at FROM metrics
+WHERE service_id = $1
+  AND metric_type = $2
+  AND recorded_at >= $3
+  AND recorded_at <= $4
+ORDER BY recorded_at DESC
+LIMIT $5
+`
+
+type ListRecentSeriesMetricsParams struct {
+	ServiceID  string     `json:"service_id"`
+	MetricType MetricType `json:"metric_type"`
+	Since      time.Time  `json:"since"`
+	Until      time.Time  `json:"until"`
+	LimitVal   int32      `json:"limit_val"`
+}
+
+// Returns the newest samples of one service/metric series, newest first
+func (q *Queries) ListRecentSeriesMetrics(ctx context.Context, arg ListRecentSeriesMetricsParams) ([]Metric, error) {
+	rows, err := q.db.Query(ctx, listRecentSeriesMetrics,
+		arg.ServiceID,
+		arg.MetricType,
+		arg.Since,
+		arg.Until,
+		arg.LimitVal,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Metric{}
+	for rows.Next() {
+		var i Metric
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.MetricType,
+			&i.Value,
+			&i.RecordedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -13,12 +13,316 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ActionBehavior string
+
+const (
+	ActionBehaviorOPENINCIDENT ActionBehavior = "OPEN_INCIDENT"
+	ActionBehaviorNOTIFYONLY   ActionBehavior = "NOTIFY_ONLY"
+	ActionBehaviorWEBHOOK      ActionBehavior = "WEBHOOK"
+	ActionBehaviorTHROTTLE     ActionBehavior = "THROTTLE"
+)
+
+func (e *ActionBehavior) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ActionBehavior(s)
+	case string:
+		*e = ActionBehavior(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ActionBehavior: %T", src)
+	}
+	return nil
+}
+
+type NullActionBehavior struct {
+	ActionBehavior ActionBehavior `json:"action_behavior"`
+	Valid          bool           `json:"valid"` // Valid is true if ActionBehavior is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullActionBehavior) Scan(value interface{}) error {
+	if value == nil {
+		ns.ActionBehavior, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ActionBehavior.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullActionBehavior) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ActionBehavior), nil
+}
+
+type AnomalyDirection string
+
+const (
+	AnomalyDirectionBOTH  AnomalyDirection = "BOTH"
+	AnomalyDirectionABOVE AnomalyDirection = "ABOVE"
+	AnomalyDirectionBELOW AnomalyDirection = "BELOW"
+)
+
+func (e *AnomalyDirection) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AnomalyDirection(s)
+	case string:
+		*e = AnomalyDirection(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AnomalyDirection: %T", src)
+	}
+	return nil
+}
+
+type NullAnomalyDirection struct {
+	AnomalyDirection AnomalyDirection `json:"anomaly_direction"`
+	Valid            bool             `json:"valid"` // Valid is true if AnomalyDirection is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAnomalyDirection) Scan(value interface{}) error {
+	if value == nil {
+		ns.AnomalyDirection, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AnomalyDirection.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAnomalyDirection) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AnomalyDirection), nil
+}
+
+type ChangeKind string
+
+const (
+	ChangeKindACTIVATE   ChangeKind = "ACTIVATE"
+	ChangeKindDEACTIVATE ChangeKind = "DEACTIVATE"
+	ChangeKindDELETE     ChangeKind = "DELETE"
+	ChangeKindUPDATE     ChangeKind = "UPDATE"
+)
+
+func (e *ChangeKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChangeKind(s)
+	case string:
+		*e = ChangeKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChangeKind: %T", src)
+	}
+	return nil
+}
+
+type NullChangeKind struct {
+	ChangeKind ChangeKind `json:"change_kind"`
+	Valid      bool       `json:"valid"` // Valid is true if ChangeKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChangeKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChangeKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChangeKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChangeKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChangeKind), nil
+}
+
+type ChangeRequestStatus string
+
+const (
+	ChangeRequestStatusPENDING  ChangeRequestStatus = "PENDING"
+	ChangeRequestStatusAPPROVED ChangeRequestStatus = "APPROVED"
+	ChangeRequestStatusREJECTED ChangeRequestStatus = "REJECTED"
+)
+
+func (e *ChangeRequestStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChangeRequestStatus(s)
+	case string:
+		*e = ChangeRequestStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChangeRequestStatus: %T", src)
+	}
+	return nil
+}
+
+type NullChangeRequestStatus struct {
+	ChangeRequestStatus ChangeRequestStatus `json:"change_request_status"`
+	Valid               bool                `json:"valid"` // Valid is true if ChangeRequestStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChangeRequestStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChangeRequestStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChangeRequestStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChangeRequestStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChangeRequestStatus), nil
+}
+
+type ChangeTarget string
+
+const (
+	ChangeTargetRULE   ChangeTarget = "RULE"
+	ChangeTargetACTION ChangeTarget = "ACTION"
+)
+
+func (e *ChangeTarget) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChangeTarget(s)
+	case string:
+		*e = ChangeTarget(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChangeTarget: %T", src)
+	}
+	return nil
+}
+
+type NullChangeTarget struct {
+	ChangeTarget ChangeTarget `json:"change_target"`
+	Valid        bool         `json:"valid"` // Valid is true if ChangeTarget is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChangeTarget) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChangeTarget, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChangeTarget.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChangeTarget) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChangeTarget), nil
+}
+
+type EvaluationOutcome string
+
+const (
+	EvaluationOutcomeMATCHED    EvaluationOutcome = "MATCHED"
+	EvaluationOutcomeNOTMATCHED EvaluationOutcome = "NOT_MATCHED"
+	EvaluationOutcomeSKIPPED    EvaluationOutcome = "SKIPPED"
+	EvaluationOutcomeSUPPRESSED EvaluationOutcome = "SUPPRESSED"
+)
+
+func (e *EvaluationOutcome) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EvaluationOutcome(s)
+	case string:
+		*e = EvaluationOutcome(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EvaluationOutcome: %T", src)
+	}
+	return nil
+}
+
+type NullEvaluationOutcome struct {
+	EvaluationOutcome EvaluationOutcome `json:"evaluation_outcome"`
+	Valid             bool              `json:"valid"` // Valid is true if EvaluationOutcome is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEvaluationOutcome) Scan(value interface{}) error {
+	if value == nil {
+		ns.EvaluationOutcome, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EvaluationOutcome.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEvaluationOutcome) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EvaluationOutcome), nil
+}
+
+type EvaluationPolicy string
+
+const (
+	EvaluationPolicyALLMATCHES EvaluationPolicy = "ALL_MATCHES"
+	EvaluationPolicyFIRSTMATCH EvaluationPolicy = "FIRST_MATCH"
+)
+
+func (e *EvaluationPolicy) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EvaluationPolicy(s)
+	case string:
+		*e = EvaluationPolicy(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EvaluationPolicy: %T", src)
+	}
+	return nil
+}
+
+type NullEvaluationPolicy struct {
+	EvaluationPolicy EvaluationPolicy `json:"evaluation_policy"`
+	Valid            bool             `json:"valid"` // Valid is true if EvaluationPolicy is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEvaluationPolicy) Scan(value interface{}) error {
+	if value == nil {
+		ns.EvaluationPolicy, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EvaluationPolicy.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEvaluationPolicy) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EvaluationPolicy), nil
+}
+
 type EventType string
 
 const (
-	EventTypeMETRICCREATED   EventType = "METRIC_CREATED"
-	EventTypeINCIDENTCREATED EventType = "INCIDENT_CREATED"
-	EventTypeINCIDENTUPDATED EventType = "INCIDENT_UPDATED"
+	EventTypeMETRICCREATED         EventType = "METRIC_CREATED"
+	EventTypeINCIDENTCREATED       EventType = "INCIDENT_CREATED"
+	EventTypeINCIDENTUPDATED       EventType = "INCIDENT_UPDATED"
+	EventTypeWEBHOOKREQUESTED      EventType = "WEBHOOK_REQUESTED"
+	EventTypeNOTIFICATIONREQUESTED EventType = "NOTIFICATION_REQUESTED"
 )
 
 func (e *EventType) Scan(src interface{}) error {
@@ -59,11 +363,12 @@ func (ns NullEventType) Value() (driver.Value, error) {
 type IncidentEventType string
 
 const (
-	IncidentEventTypeCREATED         IncidentEventType = "CREATED"
-	IncidentEventTypeSTATUSCHANGED   IncidentEventType = "STATUS_CHANGED"
-	IncidentEventTypeCOMMENTADDED    IncidentEventType = "COMMENT_ADDED"
-	IncidentEventTypeASSIGNED        IncidentEventType = "ASSIGNED"
-	IncidentEventTypeSEVERITYCHANGED IncidentEventType = "SEVERITY_CHANGED"
+	IncidentEventTypeCREATED           IncidentEventType = "CREATED"
+	IncidentEventTypeSTATUSCHANGED     IncidentEventType = "STATUS_CHANGED"
+	IncidentEventTypeCOMMENTADDED      IncidentEventType = "COMMENT_ADDED"
+	IncidentEventTypeASSIGNED          IncidentEventType = "ASSIGNED"
+	IncidentEventTypeSEVERITYCHANGED   IncidentEventType = "SEVERITY_CHANGED"
+	IncidentEventTypeVIOLATIONRECORDED IncidentEventType = "VIOLATION_RECORDED"
 )
 
 func (e *IncidentEventType) Scan(src interface{}) error {
@@ -232,47 +537,137 @@ func (ns NullMetricType) Value() (driver.Value, error) {
 	return string(ns.MetricType), nil
 }
 
-type RuleAction string
+type RuleAggregation string
 
 const (
-	RuleActionOPENINCIDENT RuleAction = "OPEN_INCIDENT"
-	RuleActionTHROTTLE     RuleAction = "THROTTLE"
-	RuleActionWEBHOOK      RuleAction = "WEBHOOK"
+	RuleAggregationNONE RuleAggregation = "NONE"
+	RuleAggregationAVG  RuleAggregation = "AVG"
+	RuleAggregationMIN  RuleAggregation = "MIN"
+	RuleAggregationMAX  RuleAggregation = "MAX"
+	RuleAggregationP50  RuleAggregation = "P50"
+	RuleAggregationP95  RuleAggregation = "P95"
+	RuleAggregationP99  RuleAggregation = "P99"
 )
 
-func (e *RuleAction) Scan(src interface{}) error {
+func (e *RuleAggregation) Scan(src interface{}) error {
 	switch s := src.(type) {
 	case []byte:
-		*e = RuleAction(s)
+		*e = RuleAggregation(s)
 	case string:
-		*e = RuleAction(s)
+		*e = RuleAggregation(s)
 	default:
-		return fmt.Errorf("unsupported scan type for RuleAction: %T", src)
+		return fmt.Errorf("unsupported scan type for RuleAggregation: %T", src)
 	}
 	return nil
 }
 
-type NullRuleAction struct {
-	RuleAction RuleAction `json:"rule_action"`
-	Valid      bool       `json:"valid"` // Valid is true if RuleAction is not NULL
+type NullRuleAggregation struct {
+	RuleAggregation RuleAggregation `json:"rule_aggregation"`
+	Valid           bool            `json:"valid"` // Valid is true if RuleAggregation is not NULL
 }
 
 // Scan implements the Scanner interface.
-func (ns *NullRuleAction) Scan(value interface{}) error {
+func (ns *NullRuleAggregation) Scan(value interface{}) error {
 	if value == nil {
-		ns.RuleAction, ns.Valid = "", false
+		ns.RuleAggregation, ns.Valid = "", false
 		return nil
 	}
 	ns.Valid = true
-	return ns.RuleAction.Scan(value)
+	return ns.RuleAggregation.Scan(value)
 }
 
 // Value implements the driver Valuer interface.
-func (ns NullRuleAction) Value() (driver.Value, error) {
+func (ns NullRuleAggregation) Value() (driver.Value, error) {
 	if !ns.Valid {
 		return nil, nil
 	}
-	return string(ns.RuleAction), nil
+	return string(ns.RuleAggregation), nil
+}
+
+type RuleChangeMode string
+
+const (
+	RuleChangeModeNONE     RuleChangeMode = "NONE"
+	RuleChangeModeABSOLUTE RuleChangeMode = "ABSOLUTE"
+	RuleChangeModePERCENT  RuleChangeMode = "PERCENT"
+)
+
+func (e *RuleChangeMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RuleChangeMode(s)
+	case string:
+		*e = RuleChangeMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RuleChangeMode: %T", src)
+	}
+	return nil
+}
+
+type NullRuleChangeMode struct {
+	RuleChangeMode RuleChangeMode `json:"rule_change_mode"`
+	Valid          bool           `json:"valid"` // Valid is true if RuleChangeMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRuleChangeMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.RuleChangeMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RuleChangeMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRuleChangeMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RuleChangeMode), nil
+}
+
+type RuleForecastMethod string
+
+const (
+	RuleForecastMethodNONE   RuleForecastMethod = "NONE"
+	RuleForecastMethodLINEAR RuleForecastMethod = "LINEAR"
+	RuleForecastMethodHOLT   RuleForecastMethod = "HOLT"
+)
+
+func (e *RuleForecastMethod) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RuleForecastMethod(s)
+	case string:
+		*e = RuleForecastMethod(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RuleForecastMethod: %T", src)
+	}
+	return nil
+}
+
+type NullRuleForecastMethod struct {
+	RuleForecastMethod RuleForecastMethod `json:"rule_forecast_method"`
+	Valid              bool               `json:"valid"` // Valid is true if RuleForecastMethod is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRuleForecastMethod) Scan(value interface{}) error {
+	if value == nil {
+		ns.RuleForecastMethod, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RuleForecastMethod.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRuleForecastMethod) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RuleForecastMethod), nil
 }
 
 type RuleOperator string
@@ -321,6 +716,197 @@ func (ns NullRuleOperator) Value() (driver.Value, error) {
 	return string(ns.RuleOperator), nil
 }
 
+type RuleRevisionAction string
+
+const (
+	RuleRevisionActionCREATED     RuleRevisionAction = "CREATED"
+	RuleRevisionActionUPDATED     RuleRevisionAction = "UPDATED"
+	RuleRevisionActionACTIVATED   RuleRevisionAction = "ACTIVATED"
+	RuleRevisionActionDEACTIVATED RuleRevisionAction = "DEACTIVATED"
+	RuleRevisionActionDELETED     RuleRevisionAction = "DELETED"
+	RuleRevisionActionROLLEDBACK  RuleRevisionAction = "ROLLED_BACK"
+)
+
+func (e *RuleRevisionAction) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RuleRevisionAction(s)
+	case string:
+		*e = RuleRevisionAction(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RuleRevisionAction: %T", src)
+	}
+	return nil
+}
+
+type NullRuleRevisionAction struct {
+	RuleRevisionAction RuleRevisionAction `json:"rule_revision_action"`
+	Valid              bool               `json:"valid"` // Valid is true if RuleRevisionAction is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRuleRevisionAction) Scan(value interface{}) error {
+	if value == nil {
+		ns.RuleRevisionAction, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RuleRevisionAction.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRuleRevisionAction) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RuleRevisionAction), nil
+}
+
+type RuleStatus string
+
+const (
+	RuleStatusDRAFT         RuleStatus = "DRAFT"
+	RuleStatusPENDINGREVIEW RuleStatus = "PENDING_REVIEW"
+	RuleStatusPUBLISHED     RuleStatus = "PUBLISHED"
+)
+
+func (e *RuleStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RuleStatus(s)
+	case string:
+		*e = RuleStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RuleStatus: %T", src)
+	}
+	return nil
+}
+
+type NullRuleStatus struct {
+	RuleStatus RuleStatus `json:"rule_status"`
+	Valid      bool       `json:"valid"` // Valid is true if RuleStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRuleStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.RuleStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RuleStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRuleStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RuleStatus), nil
+}
+
+type SuppressionReason string
+
+const (
+	SuppressionReasonTHROTTLED SuppressionReason = "THROTTLED"
+	SuppressionReasonSILENCED  SuppressionReason = "SILENCED"
+	SuppressionReasonSHADOWED  SuppressionReason = "SHADOWED"
+	SuppressionReasonDRAFT     SuppressionReason = "DRAFT"
+)
+
+func (e *SuppressionReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SuppressionReason(s)
+	case string:
+		*e = SuppressionReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SuppressionReason: %T", src)
+	}
+	return nil
+}
+
+type NullSuppressionReason struct {
+	SuppressionReason SuppressionReason `json:"suppression_reason"`
+	Valid             bool              `json:"valid"` // Valid is true if SuppressionReason is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSuppressionReason) Scan(value interface{}) error {
+	if value == nil {
+		ns.SuppressionReason, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SuppressionReason.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSuppressionReason) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SuppressionReason), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPENDING   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusDELIVERED WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryStatusFAILED    WebhookDeliveryStatus = "FAILED"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhook_delivery_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
+type ChangeRequest struct {
+	ID            int64               `json:"id"`
+	Target        ChangeTarget        `json:"target"`
+	TargetID      string              `json:"target_id"`
+	Kind          ChangeKind          `json:"kind"`
+	Payload       []byte              `json:"payload"`
+	RequestedBy   string              `json:"requested_by"`
+	Comment       *string             `json:"comment"`
+	Status        ChangeRequestStatus `json:"status"`
+	ReviewedBy    *string             `json:"reviewed_by"`
+	ReviewComment *string             `json:"review_comment"`
+	CreatedAt     time.Time           `json:"created_at"`
+	ReviewedAt    pgtype.Timestamptz  `json:"reviewed_at"`
+}
+
 type Department struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
@@ -329,19 +915,36 @@ type Department struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+type DraftViolation struct {
+	ID           uuid.UUID        `json:"id"`
+	RuleID       string           `json:"rule_id"`
+	RuleRevision int32            `json:"rule_revision"`
+	ServiceID    string           `json:"service_id"`
+	MetricID     *uuid.UUID       `json:"metric_id"`
+	MetricType   MetricType       `json:"metric_type"`
+	Severity     IncidentSeverity `json:"severity"`
+	Message      string           `json:"message"`
+	RecordedAt   time.Time        `json:"recorded_at"`
+}
+
 type Incident struct {
-	ID           string             `json:"id"`
-	ServiceID    string             `json:"service_id"`
-	RuleID       string             `json:"rule_id"`
-	MetricID     uuid.UUID          `json:"metric_id"`
-	Severity     IncidentSeverity   `json:"severity"`
-	Status       IncidentStatus     `json:"status"`
-	Message      *string            `json:"message"`
-	OpenedAt     time.Time          `json:"opened_at"`
-	ClosedAt     pgtype.Timestamptz `json:"closed_at"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
-	InProgressAt pgtype.Timestamptz `json:"in_progress_at"`
+	ID              string             `json:"id"`
+	ServiceID       string             `json:"service_id"`
+	RuleID          string             `json:"rule_id"`
+	MetricID        *uuid.UUID         `json:"metric_id"`
+	Severity        IncidentSeverity   `json:"severity"`
+	Status          IncidentStatus     `json:"status"`
+	Message         *string            `json:"message"`
+	OpenedAt        time.Time          `json:"opened_at"`
+	ClosedAt        pgtype.Timestamptz `json:"closed_at"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	InProgressAt    pgtype.Timestamptz `json:"in_progress_at"`
+	OccurrenceCount int32              `json:"occurrence_count"`
+	FirstSeenAt     time.Time          `json:"first_seen_at"`
+	LastSeenAt      time.Time          `json:"last_seen_at"`
+	RuleRevision    *int32             `json:"rule_revision"`
+	EvaluationID    *int64             `json:"evaluation_id"`
 }
 
 type IncidentComment struct {
@@ -364,6 +967,12 @@ type IncidentEvent struct {
 	CreatedAt  time.Time         `json:"created_at"`
 }
 
+type IncidentMetric struct {
+	IncidentID string    `json:"incident_id"`
+	MetricID   uuid.UUID `json:"metric_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 type Metric struct {
 	ID         uuid.UUID      `json:"id"`
 	ServiceID  string         `json:"service_id"`
@@ -373,9 +982,15 @@ type Metric struct {
 	CreatedAt  time.Time      `json:"created_at"`
 }
 
+type MetricEvaluationPolicy struct {
+	MetricType MetricType       `json:"metric_type"`
+	Policy     EvaluationPolicy `json:"policy"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
 type Notification struct {
 	ID           string    `json:"id"`
-	IncidentID   string    `json:"incident_id"`
+	IncidentID   *string   `json:"incident_id"`
 	Target       string    `json:"target"`
 	Message      string    `json:"message"`
 	SentAt       time.Time `json:"sent_at"`
@@ -383,6 +998,7 @@ type Notification struct {
 	IsRead       bool      `json:"is_read"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	DepartmentID *string   `json:"department_id"`
+	RuleID       *string   `json:"rule_id"`
 }
 
 type Outbox struct {
@@ -394,24 +1010,133 @@ type Outbox struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+type OutboxClaim struct {
+	OutboxID     uuid.UUID `json:"outbox_id"`
+	Processor    string    `json:"processor"`
+	ClaimedUntil time.Time `json:"claimed_until"`
+}
+
 type OutboxProcessing struct {
 	OutboxID    uuid.UUID `json:"outbox_id"`
 	Processor   string    `json:"processor"`
 	ProcessedAt time.Time `json:"processed_at"`
+	Error       *string   `json:"error"`
 }
 
 type QualityRule struct {
-	ID           string           `json:"id"`
-	MetricType   MetricType       `json:"metric_type"`
-	Threshold    pgtype.Numeric   `json:"threshold"`
-	Operator     RuleOperator     `json:"operator"`
-	Action       RuleAction       `json:"action"`
-	Priority     int32            `json:"priority"`
-	Severity     IncidentSeverity `json:"severity"`
-	IsActive     bool             `json:"is_active"`
-	CreatedAt    time.Time        `json:"created_at"`
-	UpdatedAt    time.Time        `json:"updated_at"`
-	DepartmentID *string          `json:"department_id"`
+	ID                     string             `json:"id"`
+	MetricType             MetricType         `json:"metric_type"`
+	Threshold              pgtype.Numeric     `json:"threshold"`
+	Operator               RuleOperator       `json:"operator"`
+	Action                 string             `json:"action"`
+	Priority               int32              `json:"priority"`
+	Severity               IncidentSeverity   `json:"severity"`
+	IsActive               bool               `json:"is_active"`
+	CreatedAt              time.Time          `json:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at"`
+	DepartmentID           *string            `json:"department_id"`
+	ServiceIds             []string           `json:"service_ids"`
+	ForSeconds             int32              `json:"for_seconds"`
+	WindowSamples          int32              `json:"window_samples"`
+	MinViolations          int32              `json:"min_violations"`
+	Aggregation            RuleAggregation    `json:"aggregation"`
+	WindowSeconds          int32              `json:"window_seconds"`
+	Condition              []byte             `json:"condition"`
+	FreshnessSeconds       int32              `json:"freshness_seconds"`
+	MetricTypes            []string           `json:"metric_types"`
+	AutoResolve            bool               `json:"auto_resolve"`
+	RecoveryThreshold      pgtype.Numeric     `json:"recovery_threshold"`
+	RecoverySamples        int32              `json:"recovery_samples"`
+	CooldownSeconds        int32              `json:"cooldown_seconds"`
+	WebhookUrl             *string            `json:"webhook_url"`
+	WebhookHeaders         []byte             `json:"webhook_headers"`
+	WebhookBodyTemplate    *string            `json:"webhook_body_template"`
+	Expression             *string            `json:"expression"`
+	AnomalyDeviations      pgtype.Numeric     `json:"anomaly_deviations"`
+	AnomalyDirection       AnomalyDirection   `json:"anomaly_direction"`
+	AnomalySeasonal        bool               `json:"anomaly_seasonal"`
+	ChangeMode             RuleChangeMode     `json:"change_mode"`
+	ChangeSeconds          int32              `json:"change_seconds"`
+	AbsenceSeconds         int32              `json:"absence_seconds"`
+	Revision               int32              `json:"revision"`
+	Schedule               []byte             `json:"schedule"`
+	Tiers                  []byte             `json:"tiers"`
+	ForecastMethod         RuleForecastMethod `json:"forecast_method"`
+	ForecastWindowSeconds  int32              `json:"forecast_window_seconds"`
+	ForecastHorizonSeconds int32              `json:"forecast_horizon_seconds"`
+	Status                 RuleStatus         `json:"status"`
+	Owner                  *string            `json:"owner"`
+	Author                 *string            `json:"author"`
+}
+
+type RuleAction struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Description     *string        `json:"description"`
+	Behavior        ActionBehavior `json:"behavior"`
+	DepartmentID    *string        `json:"department_id"`
+	MessageTemplate *string        `json:"message_template"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+type RuleEvaluation struct {
+	ID           int64             `json:"id"`
+	MetricID     uuid.UUID         `json:"metric_id"`
+	RuleID       string            `json:"rule_id"`
+	RuleRevision *int32            `json:"rule_revision"`
+	Outcome      EvaluationOutcome `json:"outcome"`
+	Reason       *string           `json:"reason"`
+	Value        *float64          `json:"value"`
+	Message      *string           `json:"message"`
+	EvaluatedAt  time.Time         `json:"evaluated_at"`
+}
+
+type RuleRevision struct {
+	RuleID       string             `json:"rule_id"`
+	Revision     int32              `json:"revision"`
+	Action       RuleRevisionAction `json:"action"`
+	Actor        string             `json:"actor"`
+	Definition   []byte             `json:"definition"`
+	Diff         []byte             `json:"diff"`
+	RolledBackTo *int32             `json:"rolled_back_to"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+type RuleSeriesLease struct {
+	ServiceID   string    `json:"service_id"`
+	Owner       string    `json:"owner"`
+	Generation  int64     `json:"generation"`
+	LeasedUntil time.Time `json:"leased_until"`
+}
+
+type RuleStatusChange struct {
+	ID         int64          `json:"id"`
+	RuleID     string         `json:"rule_id"`
+	FromStatus NullRuleStatus `json:"from_status"`
+	ToStatus   RuleStatus     `json:"to_status"`
+	Actor      string         `json:"actor"`
+	Comment    *string        `json:"comment"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type RuleSuppression struct {
+	RuleID           string            `json:"rule_id"`
+	ServiceID        string            `json:"service_id"`
+	Reason           SuppressionReason `json:"reason"`
+	SuppressedCount  int64             `json:"suppressed_count"`
+	LastSuppressedAt time.Time         `json:"last_suppressed_at"`
+}
+
+type SeriesBaseline struct {
+	ServiceID      string     `json:"service_id"`
+	MetricType     MetricType `json:"metric_type"`
+	Bucket         int16      `json:"bucket"`
+	Mean           float64    `json:"mean"`
+	Variance       float64    `json:"variance"`
+	SampleCount    int64      `json:"sample_count"`
+	LastRecordedAt time.Time  `json:"last_recorded_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 type Service struct {
@@ -419,3 +1144,63 @@ type Service struct {
 	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type Silence struct {
+	ID         uuid.UUID            `json:"id"`
+	ServiceID  *string              `json:"service_id"`
+	MetricType NullMetricType       `json:"metric_type"`
+	RuleID     *string              `json:"rule_id"`
+	Severity   NullIncidentSeverity `json:"severity"`
+	StartsAt   time.Time            `json:"starts_at"`
+	EndsAt     pgtype.Timestamptz   `json:"ends_at"`
+	Recurrence []byte               `json:"recurrence"`
+	Author     string               `json:"author"`
+	Reason     string               `json:"reason"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+type SilencedViolation struct {
+	ID           uuid.UUID        `json:"id"`
+	SilenceID    *uuid.UUID       `json:"silence_id"`
+	RuleID       string           `json:"rule_id"`
+	ServiceID    string           `json:"service_id"`
+	MetricID     *uuid.UUID       `json:"metric_id"`
+	MetricType   MetricType       `json:"metric_type"`
+	Severity     IncidentSeverity `json:"severity"`
+	Message      string           `json:"message"`
+	SuppressedAt time.Time        `json:"suppressed_at"`
+}
+
+type WebhookCooldown struct {
+	RuleID      string    `json:"rule_id"`
+	ServiceID   string    `json:"service_id"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID             `json:"id"`
+	OutboxID      uuid.UUID             `json:"outbox_id"`
+	RuleID        string                `json:"rule_id"`
+	ServiceID     string                `json:"service_id"`
+	Url           string                `json:"url"`
+	Headers       []byte                `json:"headers"`
+	Body          string                `json:"body"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	AttemptCount  int32                 `json:"attempt_count"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	LastError     *string               `json:"last_error"`
+	DeliveredAt   pgtype.Timestamptz    `json:"delivered_at"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID            uuid.UUID `json:"id"`
+	DeliveryID    uuid.UUID `json:"delivery_id"`
+	AttemptNumber int32     `json:"attempt_number"`
+	StatusCode    *int32    `json:"status_code"`
+	Error         *string   `json:"error"`
+	DurationMs    int32     `json:"duration_ms"`
+	AttemptedAt   time.Time `json:"attempted_at"`
+}
@@ -47,17 +47,18 @@ func (q *Queries) CountUnreadNotifications(ctx context.Context) (int64, error) {
 }
 
 const createNotification = `-- name: CreateNotification :one
-INSERT INTO notifications (id, incident_id, target, message, department_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id
+INSERT INTO notifications (id, incident_id, target, message, department_id, rule_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id
 `
 
 type CreateNotificationParams struct {
 	ID           string  `json:"id"`
-	IncidentID   string  `json:"incident_id"`
+	IncidentID   *string `json:"incident_id"`
 	Target       string  `json:"target"`
 	Message      string  `json:"message"`
 	DepartmentID *string `json:"department_id"`
+	RuleID       *string `json:"rule_id"`
 }
 
 func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error) {
@@ -67,6 +68,7 @@ func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotification
 		arg.Target,
 		arg.Message,
 		arg.DepartmentID,
+		arg.RuleID,
 	)
 	var i Notification
 	err := row.Scan(
@@ -79,12 +81,13 @@ func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotification
 		&i.IsRead,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.RuleID,
 	)
 	return i, err
 }
 
 const getNotification = `-- name: GetNotification :one
-SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id FROM notifications WHERE id = $1
+SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id FROM notifications WHERE id = $1
 `
 
 func (q *Queries) GetNotification(ctx context.Context, id string) (Notification, error) {
@@ -100,12 +103,13 @@ func (q *Queries) GetNotification(ctx context.Context, id string) (Notification,
 		&i.IsRead,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.RuleID,
 	)
 	return i, err
 }
 
 const listNotifications = `-- name: ListNotifications :many
-SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id FROM notifications
+SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id FROM notifications
 ORDER BY sent_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -134,6 +138,7 @@ func (q *Queries) ListNotifications(ctx context.Context, arg ListNotificationsPa
 			&i.IsRead,
 			&i.UpdatedAt,
 			&i.DepartmentID,
+			&i.RuleID,
 		); err != nil {
 			return nil, err
 		}
@@ -146,12 +151,12 @@ func (q *Queries) ListNotifications(ctx context.Context, arg ListNotificationsPa
 }
 
 const listNotificationsByIncident = `-- name: ListNotificationsByIncident :many
-SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id FROM notifications
+SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id FROM notifications
 WHERE incident_id = $1
 ORDER BY sent_at DESC
 `
 
-func (q *Queries) ListNotificationsByIncident(ctx context.Context, incidentID string) ([]Notification, error) {
+func (q *Queries) ListNotificationsByIncident(ctx context.Context, incidentID *string) ([]Notification, error) {
 	rows, err := q.db.Query(ctx, listNotificationsByIncident, incidentID)
 	if err != nil {
 		return nil, err
@@ -170,6 +175,7 @@ func (q *Queries) ListNotificationsByIncident(ctx context.Context, incidentID st
 			&i.IsRead,
 			&i.UpdatedAt,
 			&i.DepartmentID,
+			&i.RuleID,
 		); err != nil {
 			return nil, err
 		}
@@ -182,7 +188,7 @@ func (q *Queries) ListNotificationsByIncident(ctx context.Context, incidentID st
 }
 
 const listNotificationsFiltered = `-- name: ListNotificationsFiltered :many
-SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id FROM notifications
+SELECT id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id FROM notifications
 WHERE
   ($1::boolean IS NULL OR is_read = $1)
   AND ($2::text IS NULL OR incident_id = $2)
@@ -242,6 +248,7 @@ func (q *Queries) ListNotificationsFiltered(ctx context.Context, arg ListNotific
 			&i.IsRead,
 			&i.UpdatedAt,
 			&i.DepartmentID,
+			&i.RuleID,
 		); err != nil {
 			return nil, err
 		}
@@ -268,7 +275,7 @@ const markNotificationAsRead = `-- name: MarkNotificationAsRead :one
 UPDATE notifications
 SET is_read = TRUE
 WHERE id = $1
-RETURNING id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id
+RETURNING id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id
 `
 
 func (q *Queries) MarkNotificationAsRead(ctx context.Context, id string) (Notification, error) {
@@ -284,6 +291,7 @@ func (q *Queries) MarkNotificationAsRead(ctx context.Context, id string) (Notifi
 		&i.IsRead,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.RuleID,
 	)
 	return i, err
 }
@@ -292,7 +300,7 @@ const markNotificationAsUnread = `-- name: MarkNotificationAsUnread :one
 UPDATE notifications
 SET is_read = FALSE
 WHERE id = $1
-RETURNING id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id
+RETURNING id, incident_id, target, message, sent_at, created_at, is_read, updated_at, department_id, rule_id
 `
 
 func (q *Queries) MarkNotificationAsUnread(ctx context.Context, id string) (Notification, error) {
@@ -308,6 +316,7 @@ func (q *Queries) MarkNotificationAsUnread(ctx context.Context, id string) (Noti
 		&i.IsRead,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.RuleID,
 	)
 	return i, err
 }
@@ -11,6 +11,159 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimMetricEventsByService = `-- name: ClaimMetricEventsByService :many
+WITH candidates AS (
+  SELECT o.id, o.payload->>'service_id' AS service_id
+  FROM outbox o
+  LEFT JOIN outbox_processing op ON o.id = op.outbox_id AND op.processor = $1
+  WHERE op.outbox_id IS NULL
+    AND o.event_type = 'METRIC_CREATED'
+    AND o.payload->>'service_id' IS NOT NULL
+    AND NOT EXISTS (
+      SELECT 1 FROM rule_series_leases l
+      WHERE l.service_id = o.payload->>'service_id'
+        AND l.owner <> $2
+        AND l.leased_until > NOW()
+    )
+  ORDER BY o.created_at
+  LIMIT $3
+), leased AS (
+  INSERT INTO rule_series_leases (service_id, owner, leased_until)
+  SELECT DISTINCT candidates.service_id, $2, NOW() + make_interval(secs => $4::int) FROM candidates
+  ON CONFLICT (service_id) DO UPDATE
+  SET owner = EXCLUDED.owner,
+      leased_until = EXCLUDED.leased_until,
+      generation = CASE
+        WHEN rule_series_leases.owner = EXCLUDED.owner THEN rule_series_leases.generation
+        ELSE rule_series_leases.generation + 1
+      END
+  WHERE rule_series_leases.owner = EXCLUDED.owner OR rule_series_leases.leased_until <= NOW()
+  RETURNING service_id, generation
+)
+SELECT o.id, o.event_type, o.aggregate_type, o.aggregate_id, o.payload, o.created_at, leased.service_id AS lease_service_id, leased.generation AS lease_generation
+FROM candidates
+JOIN outbox o ON o.id = candidates.id
+JOIN leased ON leased.service_id = candidates.service_id
+ORDER BY o.created_at
+`
+
+type ClaimMetricEventsByServiceParams struct {
+	Processor    string `json:"processor"`
+	Owner        string `json:"owner"`
+	LimitVal     int32  `json:"limit_val"`
+	LeaseSeconds int32  `json:"lease_seconds"`
+}
+
+type ClaimMetricEventsByServiceRow struct {
+	Outbox          Outbox `json:"outbox"`
+	LeaseServiceID  string `json:"lease_service_id"`
+	LeaseGeneration int64  `json:"lease_generation"`
+}
+
+// Claims the oldest unprocessed METRIC_CREATED events of services whose series lease is
+// free, expired or already held by the owner, and renews those leases. Services leased by
+// another instance are skipped. A lease that changes owner gets a new generation.
+func (q *Queries) ClaimMetricEventsByService(ctx context.Context, arg ClaimMetricEventsByServiceParams) ([]ClaimMetricEventsByServiceRow, error) {
+	rows, err := q.db.Query(ctx, claimMetricEventsByService,
+		arg.Processor,
+		arg.Owner,
+		arg.LimitVal,
+		arg.LeaseSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ClaimMetricEventsByServiceRow{}
+	for rows.Next() {
+		var i ClaimMetricEventsByServiceRow
+		if err := rows.Scan(
+			&i.Outbox.ID,
+			&i.Outbox.EventType,
+			&i.Outbox.AggregateType,
+			&i.Outbox.AggregateID,
+			&i.Outbox.Payload,
+			&i.Outbox.CreatedAt,
+			&i.LeaseServiceID,
+			&i.LeaseGeneration,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimUnprocessedEvents = `-- name: ClaimUnprocessedEvents :many
+WITH candidates AS (
+  SELECT o.id
+  FROM outbox o
+  LEFT JOIN outbox_processing op ON o.id = op.outbox_id AND op.processor = $1
+  LEFT JOIN outbox_claims c ON o.id = c.outbox_id AND c.processor = $1
+  WHERE op.outbox_id IS NULL
+    AND o.event_type::text = ANY($2::text[])
+    AND (c.outbox_id IS NULL OR c.claimed_until <= NOW())
+  ORDER BY o.created_at
+  LIMIT $3
+  FOR UPDATE OF o SKIP LOCKED
+), claimed AS (
+  INSERT INTO outbox_claims (outbox_id, processor, claimed_until)
+  SELECT id, $1, NOW() + make_interval(secs => $4::int) FROM candidates
+  ON CONFLICT (outbox_id, processor) DO UPDATE SET claimed_until = EXCLUDED.claimed_until
+  WHERE outbox_claims.claimed_until <= NOW()
+  RETURNING outbox_id
+)
+SELECT o.id, o.event_type, o.aggregate_type, o.aggregate_id, o.payload, o.created_at
+FROM outbox o
+JOIN claimed ON claimed.outbox_id = o.id
+ORDER BY o.created_at
+`
+
+type ClaimUnprocessedEventsParams struct {
+	Processor    string   `json:"processor"`
+	EventTypes   []string `json:"event_types"`
+	LimitVal     int32    `json:"limit_val"`
+	LeaseSeconds int32    `json:"lease_seconds"`
+}
+
+// Claims the oldest unprocessed events of the given types for a lease. Events claimed by
+// another instance are skipped until their lease runs out; concurrent claims of the same
+// event serialise on its claim row, so only one of them returns it.
+func (q *Queries) ClaimUnprocessedEvents(ctx context.Context, arg ClaimUnprocessedEventsParams) ([]Outbox, error) {
+	rows, err := q.db.Query(ctx, claimUnprocessedEvents,
+		arg.Processor,
+		arg.EventTypes,
+		arg.LimitVal,
+		arg.LeaseSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.Payload,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const cleanupOldEvents = `-- name: CleanupOldEvents :exec
 DELETE FROM outbox
 WHERE created_at < NOW() - INTERVAL '7 days'
@@ -143,6 +296,24 @@ func (q *Queries) GetUnprocessedIncidentEvents(ctx context.Context, arg GetUnpro
 	return items, nil
 }
 
+const markEventFailed = `-- name: MarkEventFailed :exec
+INSERT INTO outbox_processing (outbox_id, processor, error)
+VALUES ($1, $2, $3)
+ON CONFLICT (outbox_id, processor) DO NOTHING
+`
+
+type MarkEventFailedParams struct {
+	OutboxID  uuid.UUID `json:"outbox_id"`
+	Processor string    `json:"processor"`
+	Error     *string   `json:"error"`
+}
+
+// Dead-letters an event: it is marked processed with the reason it cannot be handled
+func (q *Queries) MarkEventFailed(ctx context.Context, arg MarkEventFailedParams) error {
+	_, err := q.db.Exec(ctx, markEventFailed, arg.OutboxID, arg.Processor, arg.Error)
+	return err
+}
+
 const markEventProcessed = `-- name: MarkEventProcessed :exec
 INSERT INTO outbox_processing (outbox_id, processor)
 VALUES ($1, $2)
@@ -158,3 +329,16 @@ func (q *Queries) MarkEventProcessed(ctx context.Context, arg MarkEventProcessed
 	_, err := q.db.Exec(ctx, markEventProcessed, arg.OutboxID, arg.Processor)
 	return err
 }
+
+const releaseServiceLeases = `-- name: ReleaseServiceLeases :exec
+UPDATE rule_series_leases
+SET leased_until = NOW()
+WHERE owner = $1 AND leased_until > NOW()
+`
+
+// Expires the service leases of an owner that stops, so other instances take the services
+// over without waiting for the leases to run out. The rows are kept for their generation.
+func (q *Queries) ReleaseServiceLeases(ctx context.Context, owner string) error {
+	_, err := q.db.Exec(ctx, releaseServiceLeases, owner)
+	return err
+}
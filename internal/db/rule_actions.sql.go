@@ -0,0 +1,176 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rule_actions.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createRuleAction = `-- name: CreateRuleAction :one
+INSERT INTO rule_actions (id, name, description, behavior, department_id, message_template)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, name, description, behavior, department_id, message_template, created_at, updated_at
+`
+
+type CreateRuleActionParams struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Description     *string        `json:"description"`
+	Behavior        ActionBehavior `json:"behavior"`
+	DepartmentID    *string        `json:"department_id"`
+	MessageTemplate *string        `json:"message_template"`
+}
+
+func (q *Queries) CreateRuleAction(ctx context.Context, arg CreateRuleActionParams) (RuleAction, error) {
+	row := q.db.QueryRow(ctx, createRuleAction,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.Behavior,
+		arg.DepartmentID,
+		arg.MessageTemplate,
+	)
+	var i RuleAction
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Behavior,
+		&i.DepartmentID,
+		&i.MessageTemplate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteRuleAction = `-- name: DeleteRuleAction :execrows
+DELETE FROM rule_actions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteRuleAction(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRuleAction, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getRuleAction = `-- name: GetRuleAction :one
+SELECT id, name, description, behavior, department_id, message_template, created_at, updated_at FROM rule_actions
+WHERE id = $1
+`
+
+func (q *Queries) GetRuleAction(ctx context.Context, id string) (RuleAction, error) {
+	row := q.db.QueryRow(ctx, getRuleAction, id)
+	var i RuleAction
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Behavior,
+		&i.DepartmentID,
+		&i.MessageTemplate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getRuleActionForUpdate = `-- name: GetRuleActionForUpdate :one
+SELECT id, name, description, behavior, department_id, message_template, created_at, updated_at FROM rule_actions
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRuleActionForUpdate(ctx context.Context, id string) (RuleAction, error) {
+	row := q.db.QueryRow(ctx, getRuleActionForUpdate, id)
+	var i RuleAction
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Behavior,
+		&i.DepartmentID,
+		&i.MessageTemplate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listRuleActions = `-- name: ListRuleActions :many
+SELECT id, name, description, behavior, department_id, message_template, created_at, updated_at FROM rule_actions
+ORDER BY id
+`
+
+func (q *Queries) ListRuleActions(ctx context.Context) ([]RuleAction, error) {
+	rows, err := q.db.Query(ctx, listRuleActions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RuleAction{}
+	for rows.Next() {
+		var i RuleAction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Behavior,
+			&i.DepartmentID,
+			&i.MessageTemplate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateRuleAction = `-- name: UpdateRuleAction :one
+UPDATE rule_actions
+SET name = $2, description = $3, department_id = $4, message_template = $5
+WHERE id = $1
+RETURNING id, name, description, behavior, department_id, message_template, created_at, updated_at
+`
+
+type UpdateRuleActionParams struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Description     *string `json:"description"`
+	DepartmentID    *string `json:"department_id"`
+	MessageTemplate *string `json:"message_template"`
+}
+
+// The behaviour of an action is fixed: rules using it were validated against it
+func (q *Queries) UpdateRuleAction(ctx context.Context, arg UpdateRuleActionParams) (RuleAction, error) {
+	row := q.db.QueryRow(ctx, updateRuleAction,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.DepartmentID,
+		arg.MessageTemplate,
+	)
+	var i RuleAction
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Behavior,
+		&i.DepartmentID,
+		&i.MessageTemplate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,120 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rule_evaluations.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRuleEvaluation = `-- name: CreateRuleEvaluation :one
+INSERT INTO rule_evaluations (metric_id, rule_id, rule_revision, outcome, reason, value, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, metric_id, rule_id, rule_revision, outcome, reason, value, message, evaluated_at
+`
+
+type CreateRuleEvaluationParams struct {
+	MetricID     uuid.UUID         `json:"metric_id"`
+	RuleID       string            `json:"rule_id"`
+	RuleRevision *int32            `json:"rule_revision"`
+	Outcome      EvaluationOutcome `json:"outcome"`
+	Reason       *string           `json:"reason"`
+	Value        *float64          `json:"value"`
+	Message      *string           `json:"message"`
+}
+
+func (q *Queries) CreateRuleEvaluation(ctx context.Context, arg CreateRuleEvaluationParams) (RuleEvaluation, error) {
+	row := q.db.QueryRow(ctx, createRuleEvaluation,
+		arg.MetricID,
+		arg.RuleID,
+		arg.RuleRevision,
+		arg.Outcome,
+		arg.Reason,
+		arg.Value,
+		arg.Message,
+	)
+	var i RuleEvaluation
+	err := row.Scan(
+		&i.ID,
+		&i.MetricID,
+		&i.RuleID,
+		&i.RuleRevision,
+		&i.Outcome,
+		&i.Reason,
+		&i.Value,
+		&i.Message,
+		&i.EvaluatedAt,
+	)
+	return i, err
+}
+
+const deleteRuleEvaluationsBefore = `-- name: DeleteRuleEvaluationsBefore :execrows
+DELETE FROM rule_evaluations e
+WHERE e.evaluated_at < $1
+  AND NOT EXISTS (SELECT 1 FROM incidents i WHERE i.evaluation_id = e.id)
+`
+
+func (q *Queries) DeleteRuleEvaluationsBefore(ctx context.Context, evaluatedAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRuleEvaluationsBefore, evaluatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listMetricEvaluations = `-- name: ListMetricEvaluations :many
+SELECT e.id, e.metric_id, e.rule_id, e.rule_revision, e.outcome, e.reason, e.value, e.message, e.evaluated_at, i.id AS incident_id
+FROM rule_evaluations e
+LEFT JOIN incidents i ON i.evaluation_id = e.id
+WHERE e.metric_id = $1
+ORDER BY e.id
+`
+
+type ListMetricEvaluationsRow struct {
+	ID           int64             `json:"id"`
+	MetricID     uuid.UUID         `json:"metric_id"`
+	RuleID       string            `json:"rule_id"`
+	RuleRevision *int32            `json:"rule_revision"`
+	Outcome      EvaluationOutcome `json:"outcome"`
+	Reason       *string           `json:"reason"`
+	Value        *float64          `json:"value"`
+	Message      *string           `json:"message"`
+	EvaluatedAt  time.Time         `json:"evaluated_at"`
+	IncidentID   *string           `json:"incident_id"`
+}
+
+func (q *Queries) ListMetricEvaluations(ctx context.Context, metricID uuid.UUID) ([]ListMetricEvaluationsRow, error) {
+	rows, err := q.db.Query(ctx, listMetricEvaluations, metricID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMetricEvaluationsRow{}
+	for rows.Next() {
+		var i ListMetricEvaluationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.MetricID,
+			&i.RuleID,
+			&i.RuleRevision,
+			&i.Outcome,
+			&i.Reason,
+			&i.Value,
+			&i.Message,
+			&i.EvaluatedAt,
+			&i.IncidentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
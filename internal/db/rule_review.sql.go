@@ -0,0 +1,204 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rule_review.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countDraftViolations = `-- name: CountDraftViolations :one
+SELECT COUNT(*) FROM draft_violations
+WHERE rule_id = $1
+`
+
+func (q *Queries) CountDraftViolations(ctx context.Context, ruleID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countDraftViolations, ruleID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRuleStatusChanges = `-- name: CountRuleStatusChanges :one
+SELECT COUNT(*) FROM rule_status_changes
+WHERE rule_id = $1
+`
+
+func (q *Queries) CountRuleStatusChanges(ctx context.Context, ruleID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countRuleStatusChanges, ruleID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createDraftViolation = `-- name: CreateDraftViolation :exec
+INSERT INTO draft_violations (rule_id, rule_revision, service_id, metric_id, metric_type, severity, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateDraftViolationParams struct {
+	RuleID       string           `json:"rule_id"`
+	RuleRevision int32            `json:"rule_revision"`
+	ServiceID    string           `json:"service_id"`
+	MetricID     *uuid.UUID       `json:"metric_id"`
+	MetricType   MetricType       `json:"metric_type"`
+	Severity     IncidentSeverity `json:"severity"`
+	Message      string           `json:"message"`
+}
+
+func (q *Queries) CreateDraftViolation(ctx context.Context, arg CreateDraftViolationParams) error {
+	_, err := q.db.Exec(ctx, createDraftViolation,
+		arg.RuleID,
+		arg.RuleRevision,
+		arg.ServiceID,
+		arg.MetricID,
+		arg.MetricType,
+		arg.Severity,
+		arg.Message,
+	)
+	return err
+}
+
+const createRuleStatusChange = `-- name: CreateRuleStatusChange :one
+INSERT INTO rule_status_changes (rule_id, from_status, to_status, actor, comment)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, rule_id, from_status, to_status, actor, comment, created_at
+`
+
+type CreateRuleStatusChangeParams struct {
+	RuleID     string         `json:"rule_id"`
+	FromStatus NullRuleStatus `json:"from_status"`
+	ToStatus   RuleStatus     `json:"to_status"`
+	Actor      string         `json:"actor"`
+	Comment    *string        `json:"comment"`
+}
+
+func (q *Queries) CreateRuleStatusChange(ctx context.Context, arg CreateRuleStatusChangeParams) (RuleStatusChange, error) {
+	row := q.db.QueryRow(ctx, createRuleStatusChange,
+		arg.RuleID,
+		arg.FromStatus,
+		arg.ToStatus,
+		arg.Actor,
+		arg.Comment,
+	)
+	var i RuleStatusChange
+	err := row.Scan(
+		&i.ID,
+		&i.RuleID,
+		&i.FromStatus,
+		&i.ToStatus,
+		&i.Actor,
+		&i.Comment,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const hasDraftViolationSince = `-- name: HasDraftViolationSince :one
+SELECT EXISTS (
+  SELECT 1 FROM draft_violations
+  WHERE rule_id = $1 AND service_id = $2 AND recorded_at >= $3
+)
+`
+
+type HasDraftViolationSinceParams struct {
+	RuleID     string    `json:"rule_id"`
+	ServiceID  string    `json:"service_id"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func (q *Queries) HasDraftViolationSince(ctx context.Context, arg HasDraftViolationSinceParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasDraftViolationSince, arg.RuleID, arg.ServiceID, arg.RecordedAt)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listDraftViolations = `-- name: ListDraftViolations :many
+SELECT id, rule_id, rule_revision, service_id, metric_id, metric_type, severity, message, recorded_at FROM draft_violations
+WHERE rule_id = $1
+ORDER BY recorded_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListDraftViolationsParams struct {
+	RuleID string `json:"rule_id"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListDraftViolations(ctx context.Context, arg ListDraftViolationsParams) ([]DraftViolation, error) {
+	rows, err := q.db.Query(ctx, listDraftViolations, arg.RuleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DraftViolation{}
+	for rows.Next() {
+		var i DraftViolation
+		if err := rows.Scan(
+			&i.ID,
+			&i.RuleID,
+			&i.RuleRevision,
+			&i.ServiceID,
+			&i.MetricID,
+			&i.MetricType,
+			&i.Severity,
+			&i.Message,
+			&i.RecordedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRuleStatusChanges = `-- name: ListRuleStatusChanges :many
+SELECT id, rule_id, from_status, to_status, actor, comment, created_at FROM rule_status_changes
+WHERE rule_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListRuleStatusChangesParams struct {
+	RuleID string `json:"rule_id"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListRuleStatusChanges(ctx context.Context, arg ListRuleStatusChangesParams) ([]RuleStatusChange, error) {
+	rows, err := q.db.Query(ctx, listRuleStatusChanges, arg.RuleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RuleStatusChange{}
+	for rows.Next() {
+		var i RuleStatusChange
+		if err := rows.Scan(
+			&i.ID,
+			&i.RuleID,
+			&i.FromStatus,
+			&i.ToStatus,
+			&i.Actor,
+			&i.Comment,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,131 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rule_revisions.sql
+
+package db
+
+import (
+	"context"
+)
+
+const countRuleRevisions = `-- name: CountRuleRevisions :one
+SELECT COUNT(*) FROM rule_revisions
+WHERE rule_id = $1
+`
+
+func (q *Queries) CountRuleRevisions(ctx context.Context, ruleID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countRuleRevisions, ruleID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createRuleRevision = `-- name: CreateRuleRevision :one
+INSERT INTO rule_revisions (rule_id, revision, action, actor, definition, diff, rolled_back_to)
+SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6
+FROM rule_revisions
+WHERE rule_id = $1
+RETURNING rule_id, revision, action, actor, definition, diff, rolled_back_to, created_at
+`
+
+type CreateRuleRevisionParams struct {
+	RuleID       string             `json:"rule_id"`
+	Action       RuleRevisionAction `json:"action"`
+	Actor        string             `json:"actor"`
+	Definition   []byte             `json:"definition"`
+	Diff         []byte             `json:"diff"`
+	RolledBackTo *int32             `json:"rolled_back_to"`
+}
+
+// Revisions are numbered per rule id, continuing after the rule was deleted and recreated
+func (q *Queries) CreateRuleRevision(ctx context.Context, arg CreateRuleRevisionParams) (RuleRevision, error) {
+	row := q.db.QueryRow(ctx, createRuleRevision,
+		arg.RuleID,
+		arg.Action,
+		arg.Actor,
+		arg.Definition,
+		arg.Diff,
+		arg.RolledBackTo,
+	)
+	var i RuleRevision
+	err := row.Scan(
+		&i.RuleID,
+		&i.Revision,
+		&i.Action,
+		&i.Actor,
+		&i.Definition,
+		&i.Diff,
+		&i.RolledBackTo,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRuleRevision = `-- name: GetRuleRevision :one
+SELECT rule_id, revision, action, actor, definition, diff, rolled_back_to, created_at FROM rule_revisions
+WHERE rule_id = $1 AND revision = $2
+`
+
+type GetRuleRevisionParams struct {
+	RuleID   string `json:"rule_id"`
+	Revision int32  `json:"revision"`
+}
+
+func (q *Queries) GetRuleRevision(ctx context.Context, arg GetRuleRevisionParams) (RuleRevision, error) {
+	row := q.db.QueryRow(ctx, getRuleRevision, arg.RuleID, arg.Revision)
+	var i RuleRevision
+	err := row.Scan(
+		&i.RuleID,
+		&i.Revision,
+		&i.Action,
+		&i.Actor,
+		&i.Definition,
+		&i.Diff,
+		&i.RolledBackTo,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listRuleRevisions = `-- name: ListRuleRevisions :many
+SELECT rule_id, revision, action, actor, definition, diff, rolled_back_to, created_at FROM rule_revisions
+WHERE rule_id = $1
+ORDER BY revision DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListRuleRevisionsParams struct {
+	RuleID string `json:"rule_id"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListRuleRevisions(ctx context.Context, arg ListRuleRevisionsParams) ([]RuleRevision, error) {
+	rows, err := q.db.Query(ctx, listRuleRevisions, arg.RuleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RuleRevision{}
+	for rows.Next() {
+		var i RuleRevision
+		if err := rows.Scan(
+			&i.RuleID,
+			&i.Revision,
+			&i.Action,
+			&i.Actor,
+			&i.Definition,
+			&i.Diff,
+			&i.RolledBackTo,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,69 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rule_suppressions.sql
+
+package db
+
+import (
+	"context"
+)
+
+const listRuleSuppressions = `-- name: ListRuleSuppressions :many
+SELECT rule_id, service_id, reason, suppressed_count, last_suppressed_at FROM rule_suppressions
+WHERE rule_id = $1
+ORDER BY reason, service_id
+`
+
+func (q *Queries) ListRuleSuppressions(ctx context.Context, ruleID string) ([]RuleSuppression, error) {
+	rows, err := q.db.Query(ctx, listRuleSuppressions, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RuleSuppression{}
+	for rows.Next() {
+		var i RuleSuppression
+		if err := rows.Scan(
+			&i.RuleID,
+			&i.ServiceID,
+			&i.Reason,
+			&i.SuppressedCount,
+			&i.LastSuppressedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordRuleSuppression = `-- name: RecordRuleSuppression :one
+INSERT INTO rule_suppressions (rule_id, service_id, reason, suppressed_count, last_suppressed_at)
+VALUES ($1, $2, $3, 1, NOW())
+ON CONFLICT (rule_id, service_id, reason) DO UPDATE
+SET suppressed_count = rule_suppressions.suppressed_count + 1, last_suppressed_at = NOW()
+RETURNING rule_id, service_id, reason, suppressed_count, last_suppressed_at
+`
+
+type RecordRuleSuppressionParams struct {
+	RuleID    string            `json:"rule_id"`
+	ServiceID string            `json:"service_id"`
+	Reason    SuppressionReason `json:"reason"`
+}
+
+func (q *Queries) RecordRuleSuppression(ctx context.Context, arg RecordRuleSuppressionParams) (RuleSuppression, error) {
+	row := q.db.QueryRow(ctx, recordRuleSuppression, arg.RuleID, arg.ServiceID, arg.Reason)
+	var i RuleSuppression
+	err := row.Scan(
+		&i.RuleID,
+		&i.ServiceID,
+		&i.Reason,
+		&i.SuppressedCount,
+		&i.LastSuppressedAt,
+	)
+	return i, err
+}
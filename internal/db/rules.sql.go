@@ -9,6 +9,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -18,17 +19,23 @@ WHERE
   ($1::metric_type IS NULL OR metric_type = $1)
   AND ($2::incident_severity IS NULL OR severity = $2)
   AND ($3::boolean IS NULL OR is_active = $3)
-  AND ($4::text IS NULL OR (
-    id ILIKE '%' || $4 || '%'
-    OR CAST(threshold AS TEXT) ILIKE '%' || $4 || '%'
+  AND ($4::text IS NULL OR cardinality(service_ids) = 0 OR $4 = ANY(service_ids))
+  AND ($5::rule_status IS NULL OR status = $5)
+  AND ($6::text IS NULL OR department_id = $6)
+  AND ($7::text IS NULL OR (
+    id ILIKE '%' || $7 || '%'
+    OR CAST(threshold AS TEXT) ILIKE '%' || $7 || '%'
   ))
 `
 
 type CountRulesFilteredParams struct {
-	FilterMetricType NullMetricType       `json:"filter_metric_type"`
-	FilterSeverity   NullIncidentSeverity `json:"filter_severity"`
-	FilterIsActive   *bool                `json:"filter_is_active"`
-	FilterSearch     *string              `json:"filter_search"`
+	FilterMetricType   NullMetricType       `json:"filter_metric_type"`
+	FilterSeverity     NullIncidentSeverity `json:"filter_severity"`
+	FilterIsActive     *bool                `json:"filter_is_active"`
+	FilterServiceID    *string              `json:"filter_service_id"`
+	FilterStatus       NullRuleStatus       `json:"filter_status"`
+	FilterDepartmentID *string              `json:"filter_department_id"`
+	FilterSearch       *string              `json:"filter_search"`
 }
 
 func (q *Queries) CountRulesFiltered(ctx context.Context, arg CountRulesFilteredParams) (int32, error) {
@@ -36,6 +43,9 @@ func (q *Queries) CountRulesFiltered(ctx context.Context, arg CountRulesFiltered
 		arg.FilterMetricType,
 		arg.FilterSeverity,
 		arg.FilterIsActive,
+		arg.FilterServiceID,
+		arg.FilterStatus,
+		arg.FilterDepartmentID,
 		arg.FilterSearch,
 	)
 	var column_1 int32
@@ -44,21 +54,54 @@ func (q *Queries) CountRulesFiltered(ctx context.Context, arg CountRulesFiltered
 }
 
 const createRule = `-- name: CreateRule :one
-INSERT INTO quality_rules (id, metric_type, threshold, operator, action, priority, severity, is_active, department_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id
+INSERT INTO quality_rules (id, metric_type, threshold, operator, action, priority, severity, is_active, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds,
+  condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds,
+  webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal,
+  change_mode, change_seconds, absence_seconds, schedule, tiers,
+  forecast_method, forecast_window_seconds, forecast_horizon_seconds)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34,
+  $35, $36, $37)
+RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author
 `
 
 type CreateRuleParams struct {
-	ID           string           `json:"id"`
-	MetricType   MetricType       `json:"metric_type"`
-	Threshold    pgtype.Numeric   `json:"threshold"`
-	Operator     RuleOperator     `json:"operator"`
-	Action       RuleAction       `json:"action"`
-	Priority     int32            `json:"priority"`
-	Severity     IncidentSeverity `json:"severity"`
-	IsActive     bool             `json:"is_active"`
-	DepartmentID *string          `json:"department_id"`
+	ID                     string             `json:"id"`
+	MetricType             MetricType         `json:"metric_type"`
+	Threshold              pgtype.Numeric     `json:"threshold"`
+	Operator               RuleOperator       `json:"operator"`
+	Action                 string             `json:"action"`
+	Priority               int32              `json:"priority"`
+	Severity               IncidentSeverity   `json:"severity"`
+	IsActive               bool               `json:"is_active"`
+	DepartmentID           *string            `json:"department_id"`
+	ServiceIds             []string           `json:"service_ids"`
+	ForSeconds             int32              `json:"for_seconds"`
+	WindowSamples          int32              `json:"window_samples"`
+	MinViolations          int32              `json:"min_violations"`
+	Aggregation            RuleAggregation    `json:"aggregation"`
+	WindowSeconds          int32              `json:"window_seconds"`
+	Condition              []byte             `json:"condition"`
+	FreshnessSeconds       int32              `json:"freshness_seconds"`
+	MetricTypes            []string           `json:"metric_types"`
+	AutoResolve            bool               `json:"auto_resolve"`
+	RecoveryThreshold      pgtype.Numeric     `json:"recovery_threshold"`
+	RecoverySamples        int32              `json:"recovery_samples"`
+	CooldownSeconds        int32              `json:"cooldown_seconds"`
+	WebhookUrl             *string            `json:"webhook_url"`
+	WebhookHeaders         []byte             `json:"webhook_headers"`
+	WebhookBodyTemplate    *string            `json:"webhook_body_template"`
+	Expression             *string            `json:"expression"`
+	AnomalyDeviations      pgtype.Numeric     `json:"anomaly_deviations"`
+	AnomalyDirection       AnomalyDirection   `json:"anomaly_direction"`
+	AnomalySeasonal        bool               `json:"anomaly_seasonal"`
+	ChangeMode             RuleChangeMode     `json:"change_mode"`
+	ChangeSeconds          int32              `json:"change_seconds"`
+	AbsenceSeconds         int32              `json:"absence_seconds"`
+	Schedule               []byte             `json:"schedule"`
+	Tiers                  []byte             `json:"tiers"`
+	ForecastMethod         RuleForecastMethod `json:"forecast_method"`
+	ForecastWindowSeconds  int32              `json:"forecast_window_seconds"`
+	ForecastHorizonSeconds int32              `json:"forecast_horizon_seconds"`
 }
 
 func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (QualityRule, error) {
@@ -72,6 +115,34 @@ func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Quality
 		arg.Severity,
 		arg.IsActive,
 		arg.DepartmentID,
+		arg.ServiceIds,
+		arg.ForSeconds,
+		arg.WindowSamples,
+		arg.MinViolations,
+		arg.Aggregation,
+		arg.WindowSeconds,
+		arg.Condition,
+		arg.FreshnessSeconds,
+		arg.MetricTypes,
+		arg.AutoResolve,
+		arg.RecoveryThreshold,
+		arg.RecoverySamples,
+		arg.CooldownSeconds,
+		arg.WebhookUrl,
+		arg.WebhookHeaders,
+		arg.WebhookBodyTemplate,
+		arg.Expression,
+		arg.AnomalyDeviations,
+		arg.AnomalyDirection,
+		arg.AnomalySeasonal,
+		arg.ChangeMode,
+		arg.ChangeSeconds,
+		arg.AbsenceSeconds,
+		arg.Schedule,
+		arg.Tiers,
+		arg.ForecastMethod,
+		arg.ForecastWindowSeconds,
+		arg.ForecastHorizonSeconds,
 	)
 	var i QualityRule
 	err := row.Scan(
@@ -86,6 +157,38 @@ func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Quality
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
 	)
 	return i, err
 }
@@ -99,8 +202,72 @@ func (q *Queries) DeleteRule(ctx context.Context, id string) error {
 	return err
 }
 
+const draftRule = `-- name: DraftRule :one
+UPDATE quality_rules
+SET status = 'DRAFT', author = $1::varchar, owner = COALESCE(owner, $1::varchar)
+WHERE id = $2
+RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author
+`
+
+type DraftRuleParams struct {
+	Author string `json:"author"`
+	ID     string `json:"id"`
+}
+
+// Returns a changed rule to draft; its author is who changed it and its owner who created it
+func (q *Queries) DraftRule(ctx context.Context, arg DraftRuleParams) (QualityRule, error) {
+	row := q.db.QueryRow(ctx, draftRule, arg.Author, arg.ID)
+	var i QualityRule
+	err := row.Scan(
+		&i.ID,
+		&i.MetricType,
+		&i.Threshold,
+		&i.Operator,
+		&i.Action,
+		&i.Priority,
+		&i.Severity,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
+	)
+	return i, err
+}
+
 const getRule = `-- name: GetRule :one
-SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id FROM quality_rules WHERE id = $1
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules WHERE id = $1
 `
 
 func (q *Queries) GetRule(ctx context.Context, id string) (QualityRule, error) {
@@ -118,46 +285,131 @@ func (q *Queries) GetRule(ctx context.Context, id string) (QualityRule, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
+	)
+	return i, err
+}
+
+const getRuleForUpdate = `-- name: GetRuleForUpdate :one
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetRuleForUpdate(ctx context.Context, id string) (QualityRule, error) {
+	row := q.db.QueryRow(ctx, getRuleForUpdate, id)
+	var i QualityRule
+	err := row.Scan(
+		&i.ID,
+		&i.MetricType,
+		&i.Threshold,
+		&i.Operator,
+		&i.Action,
+		&i.Priority,
+		&i.Severity,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
 	)
 	return i, err
 }
 
 const getTopTriggeredRules = `-- name: GetTopTriggeredRules :many
 SELECT
-  r.id,
-  r.metric_type,
-  r.threshold,
-  r.operator,
-  r.action,
-  r.priority,
-  r.severity,
-  r.is_active,
-  r.department_id,
-  r.created_at,
-  r.updated_at,
+  r.id, r.metric_type, r.threshold, r.operator, r.action, r.priority, r.severity, r.is_active, r.created_at, r.updated_at, r.department_id, r.service_ids, r.for_seconds, r.window_samples, r.min_violations, r.aggregation, r.window_seconds, r.condition, r.freshness_seconds, r.metric_types, r.auto_resolve, r.recovery_threshold, r.recovery_samples, r.cooldown_seconds, r.webhook_url, r.webhook_headers, r.webhook_body_template, r.expression, r.anomaly_deviations, r.anomaly_direction, r.anomaly_seasonal, r.change_mode, r.change_seconds, r.absence_seconds, r.revision, r.schedule, r.tiers, r.forecast_method, r.forecast_window_seconds, r.forecast_horizon_seconds, r.status, r.owner, r.author,
   COUNT(i.id)::int AS trigger_count,
-  MAX(i.opened_at) AS last_triggered_at
+  MAX(i.opened_at) AS last_triggered_at,
+  COALESCE(s.throttled_count, 0)::int AS throttled_count,
+  COALESCE(s.silenced_count, 0)::int AS silenced_count,
+  COALESCE(s.shadowed_count, 0)::int AS shadowed_count,
+  COALESCE(s.draft_count, 0)::int AS draft_count
 FROM quality_rules r
 LEFT JOIN incidents i ON r.id = i.rule_id
-GROUP BY r.id
+LEFT JOIN (
+  SELECT
+    rule_id,
+    SUM(suppressed_count) FILTER (WHERE reason = 'THROTTLED') AS throttled_count,
+    SUM(suppressed_count) FILTER (WHERE reason = 'SILENCED') AS silenced_count,
+    SUM(suppressed_count) FILTER (WHERE reason = 'SHADOWED') AS shadowed_count,
+    SUM(suppressed_count) FILTER (WHERE reason = 'DRAFT') AS draft_count
+  FROM rule_suppressions
+  GROUP BY rule_id
+) s ON r.id = s.rule_id
+GROUP BY r.id, s.throttled_count, s.silenced_count, s.shadowed_count, s.draft_count
 ORDER BY COUNT(i.id) DESC, r.priority ASC
 LIMIT $1
 `
 
 type GetTopTriggeredRulesRow struct {
-	ID              string           `json:"id"`
-	MetricType      MetricType       `json:"metric_type"`
-	Threshold       pgtype.Numeric   `json:"threshold"`
-	Operator        RuleOperator     `json:"operator"`
-	Action          RuleAction       `json:"action"`
-	Priority        int32            `json:"priority"`
-	Severity        IncidentSeverity `json:"severity"`
-	IsActive        bool             `json:"is_active"`
-	DepartmentID    *string          `json:"department_id"`
-	CreatedAt       time.Time        `json:"created_at"`
-	UpdatedAt       time.Time        `json:"updated_at"`
-	TriggerCount    int32            `json:"trigger_count"`
-	LastTriggeredAt interface{}      `json:"last_triggered_at"`
+	QualityRule     QualityRule `json:"quality_rule"`
+	TriggerCount    int32       `json:"trigger_count"`
+	LastTriggeredAt interface{} `json:"last_triggered_at"`
+	ThrottledCount  int32       `json:"throttled_count"`
+	SilencedCount   int32       `json:"silenced_count"`
+	ShadowedCount   int32       `json:"shadowed_count"`
+	DraftCount      int32       `json:"draft_count"`
 }
 
 func (q *Queries) GetTopTriggeredRules(ctx context.Context, limit int32) ([]GetTopTriggeredRulesRow, error) {
@@ -170,19 +422,159 @@ func (q *Queries) GetTopTriggeredRules(ctx context.Context, limit int32) ([]GetT
 	for rows.Next() {
 		var i GetTopTriggeredRulesRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.MetricType,
-			&i.Threshold,
-			&i.Operator,
-			&i.Action,
-			&i.Priority,
-			&i.Severity,
-			&i.IsActive,
-			&i.DepartmentID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
+			&i.QualityRule.ID,
+			&i.QualityRule.MetricType,
+			&i.QualityRule.Threshold,
+			&i.QualityRule.Operator,
+			&i.QualityRule.Action,
+			&i.QualityRule.Priority,
+			&i.QualityRule.Severity,
+			&i.QualityRule.IsActive,
+			&i.QualityRule.CreatedAt,
+			&i.QualityRule.UpdatedAt,
+			&i.QualityRule.DepartmentID,
+			&i.QualityRule.ServiceIds,
+			&i.QualityRule.ForSeconds,
+			&i.QualityRule.WindowSamples,
+			&i.QualityRule.MinViolations,
+			&i.QualityRule.Aggregation,
+			&i.QualityRule.WindowSeconds,
+			&i.QualityRule.Condition,
+			&i.QualityRule.FreshnessSeconds,
+			&i.QualityRule.MetricTypes,
+			&i.QualityRule.AutoResolve,
+			&i.QualityRule.RecoveryThreshold,
+			&i.QualityRule.RecoverySamples,
+			&i.QualityRule.CooldownSeconds,
+			&i.QualityRule.WebhookUrl,
+			&i.QualityRule.WebhookHeaders,
+			&i.QualityRule.WebhookBodyTemplate,
+			&i.QualityRule.Expression,
+			&i.QualityRule.AnomalyDeviations,
+			&i.QualityRule.AnomalyDirection,
+			&i.QualityRule.AnomalySeasonal,
+			&i.QualityRule.ChangeMode,
+			&i.QualityRule.ChangeSeconds,
+			&i.QualityRule.AbsenceSeconds,
+			&i.QualityRule.Revision,
+			&i.QualityRule.Schedule,
+			&i.QualityRule.Tiers,
+			&i.QualityRule.ForecastMethod,
+			&i.QualityRule.ForecastWindowSeconds,
+			&i.QualityRule.ForecastHorizonSeconds,
+			&i.QualityRule.Status,
+			&i.QualityRule.Owner,
+			&i.QualityRule.Author,
 			&i.TriggerCount,
 			&i.LastTriggeredAt,
+			&i.ThrottledCount,
+			&i.SilencedCount,
+			&i.ShadowedCount,
+			&i.DraftCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAbsenceChecks = `-- name: ListAbsenceChecks :many
+SELECT
+  r.id, r.metric_type, r.threshold, r.operator, r.action, r.priority, r.severity, r.is_active, r.created_at, r.updated_at, r.department_id, r.service_ids, r.for_seconds, r.window_samples, r.min_violations, r.aggregation, r.window_seconds, r.condition, r.freshness_seconds, r.metric_types, r.auto_resolve, r.recovery_threshold, r.recovery_samples, r.cooldown_seconds, r.webhook_url, r.webhook_headers, r.webhook_body_template, r.expression, r.anomaly_deviations, r.anomaly_direction, r.anomaly_seasonal, r.change_mode, r.change_seconds, r.absence_seconds, r.revision, r.schedule, r.tiers, r.forecast_method, r.forecast_window_seconds, r.forecast_horizon_seconds, r.status, r.owner, r.author,
+  s.id AS service_id,
+  m.id AS last_metric_id,
+  m.recorded_at AS last_recorded_at,
+  GREATEST(r.created_at, s.created_at)::timestamptz AS expected_since,
+  EXISTS (
+    SELECT 1 FROM incidents i
+    WHERE i.rule_id = r.id AND i.service_id = s.id AND i.status != 'CLOSED'
+  ) AS has_open_incident
+FROM quality_rules r
+JOIN services s ON cardinality(r.service_ids) = 0 OR s.id = ANY(r.service_ids)
+LEFT JOIN metrics m ON m.id = (
+  SELECT id FROM metrics
+  WHERE metrics.service_id = s.id AND metrics.metric_type = r.metric_type
+  ORDER BY recorded_at DESC
+  LIMIT 1
+)
+WHERE r.is_active = TRUE AND r.absence_seconds > 0
+ORDER BY r.id, s.id
+`
+
+type ListAbsenceChecksRow struct {
+	QualityRule     QualityRule        `json:"quality_rule"`
+	ServiceID       string             `json:"service_id"`
+	LastMetricID    *uuid.UUID         `json:"last_metric_id"`
+	LastRecordedAt  pgtype.Timestamptz `json:"last_recorded_at"`
+	ExpectedSince   time.Time          `json:"expected_since"`
+	HasOpenIncident bool               `json:"has_open_incident"`
+}
+
+// Pairs every active absence rule with the services in its scope, their latest metric of
+// the rule's metric type if they ever reported one, since when the rule expects data
+// from them and whether the rule has an unresolved incident for them
+func (q *Queries) ListAbsenceChecks(ctx context.Context) ([]ListAbsenceChecksRow, error) {
+	rows, err := q.db.Query(ctx, listAbsenceChecks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAbsenceChecksRow{}
+	for rows.Next() {
+		var i ListAbsenceChecksRow
+		if err := rows.Scan(
+			&i.QualityRule.ID,
+			&i.QualityRule.MetricType,
+			&i.QualityRule.Threshold,
+			&i.QualityRule.Operator,
+			&i.QualityRule.Action,
+			&i.QualityRule.Priority,
+			&i.QualityRule.Severity,
+			&i.QualityRule.IsActive,
+			&i.QualityRule.CreatedAt,
+			&i.QualityRule.UpdatedAt,
+			&i.QualityRule.DepartmentID,
+			&i.QualityRule.ServiceIds,
+			&i.QualityRule.ForSeconds,
+			&i.QualityRule.WindowSamples,
+			&i.QualityRule.MinViolations,
+			&i.QualityRule.Aggregation,
+			&i.QualityRule.WindowSeconds,
+			&i.QualityRule.Condition,
+			&i.QualityRule.FreshnessSeconds,
+			&i.QualityRule.MetricTypes,
+			&i.QualityRule.AutoResolve,
+			&i.QualityRule.RecoveryThreshold,
+			&i.QualityRule.RecoverySamples,
+			&i.QualityRule.CooldownSeconds,
+			&i.QualityRule.WebhookUrl,
+			&i.QualityRule.WebhookHeaders,
+			&i.QualityRule.WebhookBodyTemplate,
+			&i.QualityRule.Expression,
+			&i.QualityRule.AnomalyDeviations,
+			&i.QualityRule.AnomalyDirection,
+			&i.QualityRule.AnomalySeasonal,
+			&i.QualityRule.ChangeMode,
+			&i.QualityRule.ChangeSeconds,
+			&i.QualityRule.AbsenceSeconds,
+			&i.QualityRule.Revision,
+			&i.QualityRule.Schedule,
+			&i.QualityRule.Tiers,
+			&i.QualityRule.ForecastMethod,
+			&i.QualityRule.ForecastWindowSeconds,
+			&i.QualityRule.ForecastHorizonSeconds,
+			&i.QualityRule.Status,
+			&i.QualityRule.Owner,
+			&i.QualityRule.Author,
+			&i.ServiceID,
+			&i.LastMetricID,
+			&i.LastRecordedAt,
+			&i.ExpectedSince,
+			&i.HasOpenIncident,
 		); err != nil {
 			return nil, err
 		}
@@ -195,7 +587,7 @@ func (q *Queries) GetTopTriggeredRules(ctx context.Context, limit int32) ([]GetT
 }
 
 const listActiveRules = `-- name: ListActiveRules :many
-SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id FROM quality_rules
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules
 WHERE is_active = TRUE
 ORDER BY priority, id
 `
@@ -221,6 +613,38 @@ func (q *Queries) ListActiveRules(ctx context.Context) ([]QualityRule, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DepartmentID,
+			&i.ServiceIds,
+			&i.ForSeconds,
+			&i.WindowSamples,
+			&i.MinViolations,
+			&i.Aggregation,
+			&i.WindowSeconds,
+			&i.Condition,
+			&i.FreshnessSeconds,
+			&i.MetricTypes,
+			&i.AutoResolve,
+			&i.RecoveryThreshold,
+			&i.RecoverySamples,
+			&i.CooldownSeconds,
+			&i.WebhookUrl,
+			&i.WebhookHeaders,
+			&i.WebhookBodyTemplate,
+			&i.Expression,
+			&i.AnomalyDeviations,
+			&i.AnomalyDirection,
+			&i.AnomalySeasonal,
+			&i.ChangeMode,
+			&i.ChangeSeconds,
+			&i.AbsenceSeconds,
+			&i.Revision,
+			&i.Schedule,
+			&i.Tiers,
+			&i.ForecastMethod,
+			&i.ForecastWindowSeconds,
+			&i.ForecastHorizonSeconds,
+			&i.Status,
+			&i.Owner,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -233,7 +657,7 @@ func (q *Queries) ListActiveRules(ctx context.Context) ([]QualityRule, error) {
 }
 
 const listActiveRulesByMetricType = `-- name: ListActiveRulesByMetricType :many
-SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id FROM quality_rules
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules
 WHERE is_active = TRUE AND metric_type = $1
 ORDER BY priority, id
 `
@@ -259,6 +683,117 @@ func (q *Queries) ListActiveRulesByMetricType(ctx context.Context, metricType Me
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DepartmentID,
+			&i.ServiceIds,
+			&i.ForSeconds,
+			&i.WindowSamples,
+			&i.MinViolations,
+			&i.Aggregation,
+			&i.WindowSeconds,
+			&i.Condition,
+			&i.FreshnessSeconds,
+			&i.MetricTypes,
+			&i.AutoResolve,
+			&i.RecoveryThreshold,
+			&i.RecoverySamples,
+			&i.CooldownSeconds,
+			&i.WebhookUrl,
+			&i.WebhookHeaders,
+			&i.WebhookBodyTemplate,
+			&i.Expression,
+			&i.AnomalyDeviations,
+			&i.AnomalyDirection,
+			&i.AnomalySeasonal,
+			&i.ChangeMode,
+			&i.ChangeSeconds,
+			&i.AbsenceSeconds,
+			&i.Revision,
+			&i.Schedule,
+			&i.Tiers,
+			&i.ForecastMethod,
+			&i.ForecastWindowSeconds,
+			&i.ForecastHorizonSeconds,
+			&i.Status,
+			&i.Owner,
+			&i.Author,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActiveRulesForService = `-- name: ListActiveRulesForService :many
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules
+WHERE is_active = TRUE
+  AND (metric_type::text = $1::text OR $1::text = ANY(metric_types))
+  AND (cardinality(service_ids) = 0 OR $2::text = ANY(service_ids))
+ORDER BY priority, id
+`
+
+type ListActiveRulesForServiceParams struct {
+	MetricType string `json:"metric_type"`
+	ServiceID  string `json:"service_id"`
+}
+
+// Rules with an empty service_ids list apply to every service. Composite rules
+// match on any of the metric types their condition depends on.
+func (q *Queries) ListActiveRulesForService(ctx context.Context, arg ListActiveRulesForServiceParams) ([]QualityRule, error) {
+	rows, err := q.db.Query(ctx, listActiveRulesForService, arg.MetricType, arg.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []QualityRule{}
+	for rows.Next() {
+		var i QualityRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.MetricType,
+			&i.Threshold,
+			&i.Operator,
+			&i.Action,
+			&i.Priority,
+			&i.Severity,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DepartmentID,
+			&i.ServiceIds,
+			&i.ForSeconds,
+			&i.WindowSamples,
+			&i.MinViolations,
+			&i.Aggregation,
+			&i.WindowSeconds,
+			&i.Condition,
+			&i.FreshnessSeconds,
+			&i.MetricTypes,
+			&i.AutoResolve,
+			&i.RecoveryThreshold,
+			&i.RecoverySamples,
+			&i.CooldownSeconds,
+			&i.WebhookUrl,
+			&i.WebhookHeaders,
+			&i.WebhookBodyTemplate,
+			&i.Expression,
+			&i.AnomalyDeviations,
+			&i.AnomalyDirection,
+			&i.AnomalySeasonal,
+			&i.ChangeMode,
+			&i.ChangeSeconds,
+			&i.AbsenceSeconds,
+			&i.Revision,
+			&i.Schedule,
+			&i.Tiers,
+			&i.ForecastMethod,
+			&i.ForecastWindowSeconds,
+			&i.ForecastHorizonSeconds,
+			&i.Status,
+			&i.Owner,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -271,7 +806,7 @@ func (q *Queries) ListActiveRulesByMetricType(ctx context.Context, metricType Me
 }
 
 const listRules = `-- name: ListRules :many
-SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id FROM quality_rules ORDER BY priority, id
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules ORDER BY priority, id
 `
 
 func (q *Queries) ListRules(ctx context.Context) ([]QualityRule, error) {
@@ -295,6 +830,38 @@ func (q *Queries) ListRules(ctx context.Context) ([]QualityRule, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DepartmentID,
+			&i.ServiceIds,
+			&i.ForSeconds,
+			&i.WindowSamples,
+			&i.MinViolations,
+			&i.Aggregation,
+			&i.WindowSeconds,
+			&i.Condition,
+			&i.FreshnessSeconds,
+			&i.MetricTypes,
+			&i.AutoResolve,
+			&i.RecoveryThreshold,
+			&i.RecoverySamples,
+			&i.CooldownSeconds,
+			&i.WebhookUrl,
+			&i.WebhookHeaders,
+			&i.WebhookBodyTemplate,
+			&i.Expression,
+			&i.AnomalyDeviations,
+			&i.AnomalyDirection,
+			&i.AnomalySeasonal,
+			&i.ChangeMode,
+			&i.ChangeSeconds,
+			&i.AbsenceSeconds,
+			&i.Revision,
+			&i.Schedule,
+			&i.Tiers,
+			&i.ForecastMethod,
+			&i.ForecastWindowSeconds,
+			&i.ForecastHorizonSeconds,
+			&i.Status,
+			&i.Owner,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -308,7 +875,7 @@ func (q *Queries) ListRules(ctx context.Context) ([]QualityRule, error) {
 
 const listRulesFiltered = `-- name: ListRulesFiltered :many
 SELECT
-  r.id, r.metric_type, r.threshold, r.operator, r.action, r.priority, r.severity, r.is_active, r.created_at, r.updated_at, r.department_id,
+  r.id, r.metric_type, r.threshold, r.operator, r.action, r.priority, r.severity, r.is_active, r.created_at, r.updated_at, r.department_id, r.service_ids, r.for_seconds, r.window_samples, r.min_violations, r.aggregation, r.window_seconds, r.condition, r.freshness_seconds, r.metric_types, r.auto_resolve, r.recovery_threshold, r.recovery_samples, r.cooldown_seconds, r.webhook_url, r.webhook_headers, r.webhook_body_template, r.expression, r.anomaly_deviations, r.anomaly_direction, r.anomaly_seasonal, r.change_mode, r.change_seconds, r.absence_seconds, r.revision, r.schedule, r.tiers, r.forecast_method, r.forecast_window_seconds, r.forecast_horizon_seconds, r.status, r.owner, r.author,
   COALESCE(tc.trigger_count, 0)::int AS trigger_count
 FROM quality_rules r
 LEFT JOIN (
@@ -320,53 +887,49 @@ WHERE
   ($1::metric_type IS NULL OR r.metric_type = $1)
   AND ($2::incident_severity IS NULL OR r.severity = $2)
   AND ($3::boolean IS NULL OR r.is_active = $3)
-  AND ($4::text IS NULL OR (
-    r.id ILIKE '%' || $4 || '%'
-    OR CAST(r.threshold AS TEXT) ILIKE '%' || $4 || '%'
+  AND ($4::text IS NULL OR cardinality(r.service_ids) = 0 OR $4 = ANY(r.service_ids))
+  AND ($5::rule_status IS NULL OR r.status = $5)
+  AND ($6::text IS NULL OR r.department_id = $6)
+  AND ($7::text IS NULL OR (
+    r.id ILIKE '%' || $7 || '%'
+    OR CAST(r.threshold AS TEXT) ILIKE '%' || $7 || '%'
   ))
 ORDER BY
-  CASE WHEN $5::text = 'id' AND $6::text = 'asc' THEN r.id END ASC,
-  CASE WHEN $5::text = 'id' AND $6::text = 'desc' THEN r.id END DESC,
-  CASE WHEN $5::text = 'metric_type' AND $6::text = 'asc' THEN r.metric_type END ASC,
-  CASE WHEN $5::text = 'metric_type' AND $6::text = 'desc' THEN r.metric_type END DESC,
-  CASE WHEN $5::text = 'threshold' AND $6::text = 'asc' THEN r.threshold END ASC,
-  CASE WHEN $5::text = 'threshold' AND $6::text = 'desc' THEN r.threshold END DESC,
-  CASE WHEN $5::text = 'severity' AND $6::text = 'asc' THEN r.severity END ASC,
-  CASE WHEN $5::text = 'severity' AND $6::text = 'desc' THEN r.severity END DESC,
-  CASE WHEN $5::text = 'priority' AND $6::text = 'asc' THEN r.priority END ASC,
-  CASE WHEN $5::text = 'priority' AND $6::text = 'desc' THEN r.priority END DESC,
-  CASE WHEN $5::text = 'is_active' AND $6::text = 'asc' THEN r.is_active END ASC,
-  CASE WHEN $5::text = 'is_active' AND $6::text = 'desc' THEN r.is_active END DESC,
-  CASE WHEN $5::text = 'trigger_count' AND $6::text = 'asc' THEN COALESCE(tc.trigger_count, 0) END ASC,
-  CASE WHEN $5::text = 'trigger_count' AND $6::text = 'desc' THEN COALESCE(tc.trigger_count, 0) END DESC,
+  CASE WHEN $8::text = 'id' AND $9::text = 'asc' THEN r.id END ASC,
+  CASE WHEN $8::text = 'id' AND $9::text = 'desc' THEN r.id END DESC,
+  CASE WHEN $8::text = 'metric_type' AND $9::text = 'asc' THEN r.metric_type END ASC,
+  CASE WHEN $8::text = 'metric_type' AND $9::text = 'desc' THEN r.metric_type END DESC,
+  CASE WHEN $8::text = 'threshold' AND $9::text = 'asc' THEN r.threshold END ASC,
+  CASE WHEN $8::text = 'threshold' AND $9::text = 'desc' THEN r.threshold END DESC,
+  CASE WHEN $8::text = 'severity' AND $9::text = 'asc' THEN r.severity END ASC,
+  CASE WHEN $8::text = 'severity' AND $9::text = 'desc' THEN r.severity END DESC,
+  CASE WHEN $8::text = 'priority' AND $9::text = 'asc' THEN r.priority END ASC,
+  CASE WHEN $8::text = 'priority' AND $9::text = 'desc' THEN r.priority END DESC,
+  CASE WHEN $8::text = 'is_active' AND $9::text = 'asc' THEN r.is_active END ASC,
+  CASE WHEN $8::text = 'is_active' AND $9::text = 'desc' THEN r.is_active END DESC,
+  CASE WHEN $8::text = 'trigger_count' AND $9::text = 'asc' THEN COALESCE(tc.trigger_count, 0) END ASC,
+  CASE WHEN $8::text = 'trigger_count' AND $9::text = 'desc' THEN COALESCE(tc.trigger_count, 0) END DESC,
   r.priority ASC, r.id ASC
-LIMIT $8 OFFSET $7
+LIMIT $11 OFFSET $10
 `
 
 type ListRulesFilteredParams struct {
-	FilterMetricType NullMetricType       `json:"filter_metric_type"`
-	FilterSeverity   NullIncidentSeverity `json:"filter_severity"`
-	FilterIsActive   *bool                `json:"filter_is_active"`
-	FilterSearch     *string              `json:"filter_search"`
-	SortBy           string               `json:"sort_by"`
-	SortDir          string               `json:"sort_dir"`
-	OffsetVal        int32                `json:"offset_val"`
-	LimitVal         int32                `json:"limit_val"`
+	FilterMetricType   NullMetricType       `json:"filter_metric_type"`
+	FilterSeverity     NullIncidentSeverity `json:"filter_severity"`
+	FilterIsActive     *bool                `json:"filter_is_active"`
+	FilterServiceID    *string              `json:"filter_service_id"`
+	FilterStatus       NullRuleStatus       `json:"filter_status"`
+	FilterDepartmentID *string              `json:"filter_department_id"`
+	FilterSearch       *string              `json:"filter_search"`
+	SortBy             string               `json:"sort_by"`
+	SortDir            string               `json:"sort_dir"`
+	OffsetVal          int32                `json:"offset_val"`
+	LimitVal           int32                `json:"limit_val"`
 }
 
 type ListRulesFilteredRow struct {
-	ID           string           `json:"id"`
-	MetricType   MetricType       `json:"metric_type"`
-	Threshold    pgtype.Numeric   `json:"threshold"`
-	Operator     RuleOperator     `json:"operator"`
-	Action       RuleAction       `json:"action"`
-	Priority     int32            `json:"priority"`
-	Severity     IncidentSeverity `json:"severity"`
-	IsActive     bool             `json:"is_active"`
-	CreatedAt    time.Time        `json:"created_at"`
-	UpdatedAt    time.Time        `json:"updated_at"`
-	DepartmentID *string          `json:"department_id"`
-	TriggerCount int32            `json:"trigger_count"`
+	QualityRule  QualityRule `json:"quality_rule"`
+	TriggerCount int32       `json:"trigger_count"`
 }
 
 func (q *Queries) ListRulesFiltered(ctx context.Context, arg ListRulesFilteredParams) ([]ListRulesFilteredRow, error) {
@@ -374,6 +937,9 @@ func (q *Queries) ListRulesFiltered(ctx context.Context, arg ListRulesFilteredPa
 		arg.FilterMetricType,
 		arg.FilterSeverity,
 		arg.FilterIsActive,
+		arg.FilterServiceID,
+		arg.FilterStatus,
+		arg.FilterDepartmentID,
 		arg.FilterSearch,
 		arg.SortBy,
 		arg.SortDir,
@@ -387,6 +953,75 @@ func (q *Queries) ListRulesFiltered(ctx context.Context, arg ListRulesFilteredPa
 	items := []ListRulesFilteredRow{}
 	for rows.Next() {
 		var i ListRulesFilteredRow
+		if err := rows.Scan(
+			&i.QualityRule.ID,
+			&i.QualityRule.MetricType,
+			&i.QualityRule.Threshold,
+			&i.QualityRule.Operator,
+			&i.QualityRule.Action,
+			&i.QualityRule.Priority,
+			&i.QualityRule.Severity,
+			&i.QualityRule.IsActive,
+			&i.QualityRule.CreatedAt,
+			&i.QualityRule.UpdatedAt,
+			&i.QualityRule.DepartmentID,
+			&i.QualityRule.ServiceIds,
+			&i.QualityRule.ForSeconds,
+			&i.QualityRule.WindowSamples,
+			&i.QualityRule.MinViolations,
+			&i.QualityRule.Aggregation,
+			&i.QualityRule.WindowSeconds,
+			&i.QualityRule.Condition,
+			&i.QualityRule.FreshnessSeconds,
+			&i.QualityRule.MetricTypes,
+			&i.QualityRule.AutoResolve,
+			&i.QualityRule.RecoveryThreshold,
+			&i.QualityRule.RecoverySamples,
+			&i.QualityRule.CooldownSeconds,
+			&i.QualityRule.WebhookUrl,
+			&i.QualityRule.WebhookHeaders,
+			&i.QualityRule.WebhookBodyTemplate,
+			&i.QualityRule.Expression,
+			&i.QualityRule.AnomalyDeviations,
+			&i.QualityRule.AnomalyDirection,
+			&i.QualityRule.AnomalySeasonal,
+			&i.QualityRule.ChangeMode,
+			&i.QualityRule.ChangeSeconds,
+			&i.QualityRule.AbsenceSeconds,
+			&i.QualityRule.Revision,
+			&i.QualityRule.Schedule,
+			&i.QualityRule.Tiers,
+			&i.QualityRule.ForecastMethod,
+			&i.QualityRule.ForecastWindowSeconds,
+			&i.QualityRule.ForecastHorizonSeconds,
+			&i.QualityRule.Status,
+			&i.QualityRule.Owner,
+			&i.QualityRule.Author,
+			&i.TriggerCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRulesForUpdate = `-- name: ListRulesForUpdate :many
+SELECT id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author FROM quality_rules ORDER BY id FOR UPDATE
+`
+
+func (q *Queries) ListRulesForUpdate(ctx context.Context) ([]QualityRule, error) {
+	rows, err := q.db.Query(ctx, listRulesForUpdate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []QualityRule{}
+	for rows.Next() {
+		var i QualityRule
 		if err := rows.Scan(
 			&i.ID,
 			&i.MetricType,
@@ -399,7 +1034,38 @@ func (q *Queries) ListRulesFiltered(ctx context.Context, arg ListRulesFilteredPa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DepartmentID,
-			&i.TriggerCount,
+			&i.ServiceIds,
+			&i.ForSeconds,
+			&i.WindowSamples,
+			&i.MinViolations,
+			&i.Aggregation,
+			&i.WindowSeconds,
+			&i.Condition,
+			&i.FreshnessSeconds,
+			&i.MetricTypes,
+			&i.AutoResolve,
+			&i.RecoveryThreshold,
+			&i.RecoverySamples,
+			&i.CooldownSeconds,
+			&i.WebhookUrl,
+			&i.WebhookHeaders,
+			&i.WebhookBodyTemplate,
+			&i.Expression,
+			&i.AnomalyDeviations,
+			&i.AnomalyDirection,
+			&i.AnomalySeasonal,
+			&i.ChangeMode,
+			&i.ChangeSeconds,
+			&i.AbsenceSeconds,
+			&i.Revision,
+			&i.Schedule,
+			&i.Tiers,
+			&i.ForecastMethod,
+			&i.ForecastWindowSeconds,
+			&i.ForecastHorizonSeconds,
+			&i.Status,
+			&i.Owner,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -415,7 +1081,7 @@ const setRuleActive = `-- name: SetRuleActive :one
 UPDATE quality_rules
 SET is_active = $2
 WHERE id = $1
-RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id
+RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author
 `
 
 type SetRuleActiveParams struct {
@@ -438,27 +1104,220 @@ func (q *Queries) SetRuleActive(ctx context.Context, arg SetRuleActiveParams) (Q
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
+	)
+	return i, err
+}
+
+const setRuleRevision = `-- name: SetRuleRevision :one
+UPDATE quality_rules
+SET revision = $2
+WHERE id = $1
+RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author
+`
+
+type SetRuleRevisionParams struct {
+	ID       string `json:"id"`
+	Revision int32  `json:"revision"`
+}
+
+func (q *Queries) SetRuleRevision(ctx context.Context, arg SetRuleRevisionParams) (QualityRule, error) {
+	row := q.db.QueryRow(ctx, setRuleRevision, arg.ID, arg.Revision)
+	var i QualityRule
+	err := row.Scan(
+		&i.ID,
+		&i.MetricType,
+		&i.Threshold,
+		&i.Operator,
+		&i.Action,
+		&i.Priority,
+		&i.Severity,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
+	)
+	return i, err
+}
+
+const setRuleStatus = `-- name: SetRuleStatus :one
+UPDATE quality_rules
+SET status = $2
+WHERE id = $1
+RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author
+`
+
+type SetRuleStatusParams struct {
+	ID     string     `json:"id"`
+	Status RuleStatus `json:"status"`
+}
+
+func (q *Queries) SetRuleStatus(ctx context.Context, arg SetRuleStatusParams) (QualityRule, error) {
+	row := q.db.QueryRow(ctx, setRuleStatus, arg.ID, arg.Status)
+	var i QualityRule
+	err := row.Scan(
+		&i.ID,
+		&i.MetricType,
+		&i.Threshold,
+		&i.Operator,
+		&i.Action,
+		&i.Priority,
+		&i.Severity,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
 	)
 	return i, err
 }
 
 const updateRule = `-- name: UpdateRule :one
 UPDATE quality_rules
-SET metric_type = $2, threshold = $3, operator = $4, action = $5, priority = $6, severity = $7, is_active = $8, department_id = $9
+SET metric_type = $2, threshold = $3, operator = $4, action = $5, priority = $6, severity = $7, is_active = $8, department_id = $9, service_ids = $10,
+    for_seconds = $11, window_samples = $12, min_violations = $13, aggregation = $14, window_seconds = $15,
+    condition = $16, freshness_seconds = $17, metric_types = $18,
+    auto_resolve = $19, recovery_threshold = $20, recovery_samples = $21, cooldown_seconds = $22,
+    webhook_url = $23, webhook_headers = $24, webhook_body_template = $25, expression = $26,
+    anomaly_deviations = $27, anomaly_direction = $28, anomaly_seasonal = $29,
+    change_mode = $30, change_seconds = $31, absence_seconds = $32, schedule = $33, tiers = $34,
+    forecast_method = $35, forecast_window_seconds = $36, forecast_horizon_seconds = $37
 WHERE id = $1
-RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id
+RETURNING id, metric_type, threshold, operator, action, priority, severity, is_active, created_at, updated_at, department_id, service_ids, for_seconds, window_samples, min_violations, aggregation, window_seconds, condition, freshness_seconds, metric_types, auto_resolve, recovery_threshold, recovery_samples, cooldown_seconds, webhook_url, webhook_headers, webhook_body_template, expression, anomaly_deviations, anomaly_direction, anomaly_seasonal, change_mode, change_seconds, absence_seconds, revision, schedule, tiers, forecast_method, forecast_window_seconds, forecast_horizon_seconds, status, owner, author
 `
 
 type UpdateRuleParams struct {
-	ID           string           `json:"id"`
-	MetricType   MetricType       `json:"metric_type"`
-	Threshold    pgtype.Numeric   `json:"threshold"`
-	Operator     RuleOperator     `json:"operator"`
-	Action       RuleAction       `json:"action"`
-	Priority     int32            `json:"priority"`
-	Severity     IncidentSeverity `json:"severity"`
-	IsActive     bool             `json:"is_active"`
-	DepartmentID *string          `json:"department_id"`
+	ID                     string             `json:"id"`
+	MetricType             MetricType         `json:"metric_type"`
+	Threshold              pgtype.Numeric     `json:"threshold"`
+	Operator               RuleOperator       `json:"operator"`
+	Action                 string             `json:"action"`
+	Priority               int32              `json:"priority"`
+	Severity               IncidentSeverity   `json:"severity"`
+	IsActive               bool               `json:"is_active"`
+	DepartmentID           *string            `json:"department_id"`
+	ServiceIds             []string           `json:"service_ids"`
+	ForSeconds             int32              `json:"for_seconds"`
+	WindowSamples          int32              `json:"window_samples"`
+	MinViolations          int32              `json:"min_violations"`
+	Aggregation            RuleAggregation    `json:"aggregation"`
+	WindowSeconds          int32              `json:"window_seconds"`
+	Condition              []byte             `json:"condition"`
+	FreshnessSeconds       int32              `json:"freshness_seconds"`
+	MetricTypes            []string           `json:"metric_types"`
+	AutoResolve            bool               `json:"auto_resolve"`
+	RecoveryThreshold      pgtype.Numeric     `json:"recovery_threshold"`
+	RecoverySamples        int32              `json:"recovery_samples"`
+	CooldownSeconds        int32              `json:"cooldown_seconds"`
+	WebhookUrl             *string            `json:"webhook_url"`
+	WebhookHeaders         []byte             `json:"webhook_headers"`
+	WebhookBodyTemplate    *string            `json:"webhook_body_template"`
+	Expression             *string            `json:"expression"`
+	AnomalyDeviations      pgtype.Numeric     `json:"anomaly_deviations"`
+	AnomalyDirection       AnomalyDirection   `json:"anomaly_direction"`
+	AnomalySeasonal        bool               `json:"anomaly_seasonal"`
+	ChangeMode             RuleChangeMode     `json:"change_mode"`
+	ChangeSeconds          int32              `json:"change_seconds"`
+	AbsenceSeconds         int32              `json:"absence_seconds"`
+	Schedule               []byte             `json:"schedule"`
+	Tiers                  []byte             `json:"tiers"`
+	ForecastMethod         RuleForecastMethod `json:"forecast_method"`
+	ForecastWindowSeconds  int32              `json:"forecast_window_seconds"`
+	ForecastHorizonSeconds int32              `json:"forecast_horizon_seconds"`
 }
 
 func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (QualityRule, error) {
@@ -472,6 +1331,34 @@ func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (Quality
 		arg.Severity,
 		arg.IsActive,
 		arg.DepartmentID,
+		arg.ServiceIds,
+		arg.ForSeconds,
+		arg.WindowSamples,
+		arg.MinViolations,
+		arg.Aggregation,
+		arg.WindowSeconds,
+		arg.Condition,
+		arg.FreshnessSeconds,
+		arg.MetricTypes,
+		arg.AutoResolve,
+		arg.RecoveryThreshold,
+		arg.RecoverySamples,
+		arg.CooldownSeconds,
+		arg.WebhookUrl,
+		arg.WebhookHeaders,
+		arg.WebhookBodyTemplate,
+		arg.Expression,
+		arg.AnomalyDeviations,
+		arg.AnomalyDirection,
+		arg.AnomalySeasonal,
+		arg.ChangeMode,
+		arg.ChangeSeconds,
+		arg.AbsenceSeconds,
+		arg.Schedule,
+		arg.Tiers,
+		arg.ForecastMethod,
+		arg.ForecastWindowSeconds,
+		arg.ForecastHorizonSeconds,
 	)
 	var i QualityRule
 	err := row.Scan(
@@ -486,6 +1373,38 @@ func (q *Queries) UpdateRule(ctx context.Context, arg UpdateRuleParams) (Quality
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DepartmentID,
+		&i.ServiceIds,
+		&i.ForSeconds,
+		&i.WindowSamples,
+		&i.MinViolations,
+		&i.Aggregation,
+		&i.WindowSeconds,
+		&i.Condition,
+		&i.FreshnessSeconds,
+		&i.MetricTypes,
+		&i.AutoResolve,
+		&i.RecoveryThreshold,
+		&i.RecoverySamples,
+		&i.CooldownSeconds,
+		&i.WebhookUrl,
+		&i.WebhookHeaders,
+		&i.WebhookBodyTemplate,
+		&i.Expression,
+		&i.AnomalyDeviations,
+		&i.AnomalyDirection,
+		&i.AnomalySeasonal,
+		&i.ChangeMode,
+		&i.ChangeSeconds,
+		&i.AbsenceSeconds,
+		&i.Revision,
+		&i.Schedule,
+		&i.Tiers,
+		&i.ForecastMethod,
+		&i.ForecastWindowSeconds,
+		&i.ForecastHorizonSeconds,
+		&i.Status,
+		&i.Owner,
+		&i.Author,
 	)
 	return i, err
 }
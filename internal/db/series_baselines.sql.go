@@ -0,0 +1,87 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: series_baselines.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const listSeriesBaselines = `-- name: ListSeriesBaselines :many
+SELECT service_id, metric_type, bucket, mean, variance, sample_count, last_recorded_at, updated_at FROM series_baselines
+WHERE service_id = $1 AND metric_type = $2
+ORDER BY bucket
+`
+
+type ListSeriesBaselinesParams struct {
+	ServiceID  string     `json:"service_id"`
+	MetricType MetricType `json:"metric_type"`
+}
+
+func (q *Queries) ListSeriesBaselines(ctx context.Context, arg ListSeriesBaselinesParams) ([]SeriesBaseline, error) {
+	rows, err := q.db.Query(ctx, listSeriesBaselines, arg.ServiceID, arg.MetricType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SeriesBaseline{}
+	for rows.Next() {
+		var i SeriesBaseline
+		if err := rows.Scan(
+			&i.ServiceID,
+			&i.MetricType,
+			&i.Bucket,
+			&i.Mean,
+			&i.Variance,
+			&i.SampleCount,
+			&i.LastRecordedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertSeriesBaseline = `-- name: UpsertSeriesBaseline :exec
+INSERT INTO series_baselines (service_id, metric_type, bucket, mean, variance, sample_count, last_recorded_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (service_id, metric_type, bucket) DO UPDATE
+SET mean = EXCLUDED.mean,
+    variance = EXCLUDED.variance,
+    sample_count = EXCLUDED.sample_count,
+    last_recorded_at = EXCLUDED.last_recorded_at,
+    updated_at = NOW()
+WHERE series_baselines.last_recorded_at < EXCLUDED.last_recorded_at
+`
+
+type UpsertSeriesBaselineParams struct {
+	ServiceID      string     `json:"service_id"`
+	MetricType     MetricType `json:"metric_type"`
+	Bucket         int16      `json:"bucket"`
+	Mean           float64    `json:"mean"`
+	Variance       float64    `json:"variance"`
+	SampleCount    int64      `json:"sample_count"`
+	LastRecordedAt time.Time  `json:"last_recorded_at"`
+}
+
+// Only moves a baseline forward, so replaying a sample does not learn from it twice
+func (q *Queries) UpsertSeriesBaseline(ctx context.Context, arg UpsertSeriesBaselineParams) error {
+	_, err := q.db.Exec(ctx, upsertSeriesBaseline,
+		arg.ServiceID,
+		arg.MetricType,
+		arg.Bucket,
+		arg.Mean,
+		arg.Variance,
+		arg.SampleCount,
+		arg.LastRecordedAt,
+	)
+	return err
+}
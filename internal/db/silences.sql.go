@@ -0,0 +1,377 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: silences.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countSilencedViolations = `-- name: CountSilencedViolations :one
+SELECT COUNT(*) FROM silenced_violations
+WHERE silence_id = $1
+`
+
+func (q *Queries) CountSilencedViolations(ctx context.Context, silenceID *uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countSilencedViolations, silenceID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSilences = `-- name: CountSilences :one
+SELECT COUNT(*) FROM silences
+`
+
+func (q *Queries) CountSilences(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countSilences)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createSilence = `-- name: CreateSilence :one
+INSERT INTO silences (service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason, created_at, updated_at
+`
+
+type CreateSilenceParams struct {
+	ServiceID  *string              `json:"service_id"`
+	MetricType NullMetricType       `json:"metric_type"`
+	RuleID     *string              `json:"rule_id"`
+	Severity   NullIncidentSeverity `json:"severity"`
+	StartsAt   time.Time            `json:"starts_at"`
+	EndsAt     pgtype.Timestamptz   `json:"ends_at"`
+	Recurrence []byte               `json:"recurrence"`
+	Author     string               `json:"author"`
+	Reason     string               `json:"reason"`
+}
+
+func (q *Queries) CreateSilence(ctx context.Context, arg CreateSilenceParams) (Silence, error) {
+	row := q.db.QueryRow(ctx, createSilence,
+		arg.ServiceID,
+		arg.MetricType,
+		arg.RuleID,
+		arg.Severity,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.Recurrence,
+		arg.Author,
+		arg.Reason,
+	)
+	var i Silence
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.MetricType,
+		&i.RuleID,
+		&i.Severity,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.Recurrence,
+		&i.Author,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createSilencedViolation = `-- name: CreateSilencedViolation :exec
+INSERT INTO silenced_violations (silence_id, rule_id, service_id, metric_id, metric_type, severity, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateSilencedViolationParams struct {
+	SilenceID  *uuid.UUID       `json:"silence_id"`
+	RuleID     string           `json:"rule_id"`
+	ServiceID  string           `json:"service_id"`
+	MetricID   *uuid.UUID       `json:"metric_id"`
+	MetricType MetricType       `json:"metric_type"`
+	Severity   IncidentSeverity `json:"severity"`
+	Message    string           `json:"message"`
+}
+
+func (q *Queries) CreateSilencedViolation(ctx context.Context, arg CreateSilencedViolationParams) error {
+	_, err := q.db.Exec(ctx, createSilencedViolation,
+		arg.SilenceID,
+		arg.RuleID,
+		arg.ServiceID,
+		arg.MetricID,
+		arg.MetricType,
+		arg.Severity,
+		arg.Message,
+	)
+	return err
+}
+
+const deleteSilence = `-- name: DeleteSilence :execrows
+DELETE FROM silences
+WHERE id = $1
+`
+
+func (q *Queries) DeleteSilence(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSilence, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getSilence = `-- name: GetSilence :one
+SELECT id, service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason, created_at, updated_at FROM silences
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetSilence(ctx context.Context, id uuid.UUID) (Silence, error) {
+	row := q.db.QueryRow(ctx, getSilence, id)
+	var i Silence
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.MetricType,
+		&i.RuleID,
+		&i.Severity,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.Recurrence,
+		&i.Author,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listCurrentSilences = `-- name: ListCurrentSilences :many
+SELECT id, service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason, created_at, updated_at FROM silences
+WHERE starts_at <= $1::timestamptz
+  AND (ends_at IS NULL OR ends_at > $1::timestamptz)
+ORDER BY starts_at
+`
+
+// Silences whose overall range contains the time; recurring ones still need their weekly window checked
+func (q *Queries) ListCurrentSilences(ctx context.Context, at time.Time) ([]Silence, error) {
+	rows, err := q.db.Query(ctx, listCurrentSilences, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Silence{}
+	for rows.Next() {
+		var i Silence
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.MetricType,
+			&i.RuleID,
+			&i.Severity,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.Recurrence,
+			&i.Author,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSilencedViolations = `-- name: ListSilencedViolations :many
+SELECT id, silence_id, rule_id, service_id, metric_id, metric_type, severity, message, suppressed_at FROM silenced_violations
+WHERE silence_id = $1
+ORDER BY suppressed_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListSilencedViolationsParams struct {
+	SilenceID *uuid.UUID `json:"silence_id"`
+	Limit     int32      `json:"limit"`
+	Offset    int32      `json:"offset"`
+}
+
+func (q *Queries) ListSilencedViolations(ctx context.Context, arg ListSilencedViolationsParams) ([]SilencedViolation, error) {
+	rows, err := q.db.Query(ctx, listSilencedViolations, arg.SilenceID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SilencedViolation{}
+	for rows.Next() {
+		var i SilencedViolation
+		if err := rows.Scan(
+			&i.ID,
+			&i.SilenceID,
+			&i.RuleID,
+			&i.ServiceID,
+			&i.MetricID,
+			&i.MetricType,
+			&i.Severity,
+			&i.Message,
+			&i.SuppressedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSilences = `-- name: ListSilences :many
+SELECT id, service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason, created_at, updated_at FROM silences
+ORDER BY starts_at DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListSilencesParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListSilences(ctx context.Context, arg ListSilencesParams) ([]Silence, error) {
+	rows, err := q.db.Query(ctx, listSilences, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Silence{}
+	for rows.Next() {
+		var i Silence
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.MetricType,
+			&i.RuleID,
+			&i.Severity,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.Recurrence,
+			&i.Author,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSilencesBetween = `-- name: ListSilencesBetween :many
+SELECT id, service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason, created_at, updated_at FROM silences
+WHERE starts_at < $1::timestamptz
+  AND (ends_at IS NULL OR ends_at > $2::timestamptz)
+ORDER BY starts_at
+`
+
+type ListSilencesBetweenParams struct {
+	ToTime   time.Time `json:"to_time"`
+	FromTime time.Time `json:"from_time"`
+}
+
+// Silences whose overall range overlaps the time range, for replaying rules over it
+func (q *Queries) ListSilencesBetween(ctx context.Context, arg ListSilencesBetweenParams) ([]Silence, error) {
+	rows, err := q.db.Query(ctx, listSilencesBetween, arg.ToTime, arg.FromTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Silence{}
+	for rows.Next() {
+		var i Silence
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.MetricType,
+			&i.RuleID,
+			&i.Severity,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.Recurrence,
+			&i.Author,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateSilence = `-- name: UpdateSilence :one
+UPDATE silences
+SET service_id = $2, metric_type = $3, rule_id = $4, severity = $5,
+    starts_at = $6, ends_at = $7, recurrence = $8, author = $9, reason = $10
+WHERE id = $1
+RETURNING id, service_id, metric_type, rule_id, severity, starts_at, ends_at, recurrence, author, reason, created_at, updated_at
+`
+
+type UpdateSilenceParams struct {
+	ID         uuid.UUID            `json:"id"`
+	ServiceID  *string              `json:"service_id"`
+	MetricType NullMetricType       `json:"metric_type"`
+	RuleID     *string              `json:"rule_id"`
+	Severity   NullIncidentSeverity `json:"severity"`
+	StartsAt   time.Time            `json:"starts_at"`
+	EndsAt     pgtype.Timestamptz   `json:"ends_at"`
+	Recurrence []byte               `json:"recurrence"`
+	Author     string               `json:"author"`
+	Reason     string               `json:"reason"`
+}
+
+func (q *Queries) UpdateSilence(ctx context.Context, arg UpdateSilenceParams) (Silence, error) {
+	row := q.db.QueryRow(ctx, updateSilence,
+		arg.ID,
+		arg.ServiceID,
+		arg.MetricType,
+		arg.RuleID,
+		arg.Severity,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.Recurrence,
+		arg.Author,
+		arg.Reason,
+	)
+	var i Silence
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.MetricType,
+		&i.RuleID,
+		&i.Severity,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.Recurrence,
+		&i.Author,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,368 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhook_deliveries.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + make_interval(secs => $1::int)
+WHERE id IN (
+  SELECT id FROM webhook_deliveries
+  WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+  ORDER BY next_attempt_at
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, outbox_id, rule_id, service_id, url, headers, body, status, attempt_count, next_attempt_at, last_error, delivered_at, created_at, updated_at
+`
+
+type ClaimDueWebhookDeliveriesParams struct {
+	LeaseSeconds int32 `json:"lease_seconds"`
+	LimitVal     int32 `json:"limit_val"`
+}
+
+// Pushes next_attempt_at past the lease so other workers skip the claimed deliveries.
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, claimDueWebhookDeliveries, arg.LeaseSeconds, arg.LimitVal)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.OutboxID,
+			&i.RuleID,
+			&i.ServiceID,
+			&i.Url,
+			&i.Headers,
+			&i.Body,
+			&i.Status,
+			&i.AttemptCount,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countWebhookDeliveriesForRule = `-- name: CountWebhookDeliveriesForRule :one
+SELECT COUNT(*)::int FROM webhook_deliveries WHERE rule_id = $1
+`
+
+func (q *Queries) CountWebhookDeliveriesForRule(ctx context.Context, ruleID string) (int32, error) {
+	row := q.db.QueryRow(ctx, countWebhookDeliveriesForRule, ruleID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (outbox_id, rule_id, service_id, url, headers, body)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (outbox_id) DO NOTHING
+`
+
+type CreateWebhookDeliveryParams struct {
+	OutboxID  uuid.UUID `json:"outbox_id"`
+	RuleID    string    `json:"rule_id"`
+	ServiceID string    `json:"service_id"`
+	Url       string    `json:"url"`
+	Headers   []byte    `json:"headers"`
+	Body      string    `json:"body"`
+}
+
+// Outbox events may be seen more than once; the delivery is created only the first time.
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, createWebhookDelivery,
+		arg.OutboxID,
+		arg.RuleID,
+		arg.ServiceID,
+		arg.Url,
+		arg.Headers,
+		arg.Body,
+	)
+	return err
+}
+
+const createWebhookDeliveryAttempt = `-- name: CreateWebhookDeliveryAttempt :one
+INSERT INTO webhook_delivery_attempts (delivery_id, attempt_number, status_code, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, delivery_id, attempt_number, status_code, error, duration_ms, attempted_at
+`
+
+type CreateWebhookDeliveryAttemptParams struct {
+	DeliveryID    uuid.UUID `json:"delivery_id"`
+	AttemptNumber int32     `json:"attempt_number"`
+	StatusCode    *int32    `json:"status_code"`
+	Error         *string   `json:"error"`
+	DurationMs    int32     `json:"duration_ms"`
+}
+
+func (q *Queries) CreateWebhookDeliveryAttempt(ctx context.Context, arg CreateWebhookDeliveryAttemptParams) (WebhookDeliveryAttempt, error) {
+	row := q.db.QueryRow(ctx, createWebhookDeliveryAttempt,
+		arg.DeliveryID,
+		arg.AttemptNumber,
+		arg.StatusCode,
+		arg.Error,
+		arg.DurationMs,
+	)
+	var i WebhookDeliveryAttempt
+	err := row.Scan(
+		&i.ID,
+		&i.DeliveryID,
+		&i.AttemptNumber,
+		&i.StatusCode,
+		&i.Error,
+		&i.DurationMs,
+		&i.AttemptedAt,
+	)
+	return i, err
+}
+
+const enqueueWebhookOutsideCooldown = `-- name: EnqueueWebhookOutsideCooldown :execrows
+WITH claimed AS (
+  INSERT INTO webhook_cooldowns (rule_id, service_id, requested_at)
+  VALUES ($2, $3, NOW())
+  ON CONFLICT (rule_id, service_id) DO UPDATE SET requested_at = NOW()
+  WHERE webhook_cooldowns.requested_at <= NOW() - make_interval(secs => $4::int)
+  RETURNING rule_id
+)
+INSERT INTO outbox (event_type, aggregate_type, aggregate_id, payload)
+SELECT 'WEBHOOK_REQUESTED', 'rule', claimed.rule_id, $1 FROM claimed
+`
+
+type EnqueueWebhookOutsideCooldownParams struct {
+	Payload         []byte `json:"payload"`
+	RuleID          string `json:"rule_id"`
+	ServiceID       string `json:"service_id"`
+	CooldownSeconds int32  `json:"cooldown_seconds"`
+}
+
+// Writes the WEBHOOK_REQUESTED outbox event only when the rule requested no delivery for
+// the service within the cooldown. Concurrent requests serialise on the cooldown row, so
+// exactly one of them is enqueued.
+func (q *Queries) EnqueueWebhookOutsideCooldown(ctx context.Context, arg EnqueueWebhookOutsideCooldownParams) (int64, error) {
+	result, err := q.db.Exec(ctx, enqueueWebhookOutsideCooldown,
+		arg.Payload,
+		arg.RuleID,
+		arg.ServiceID,
+		arg.CooldownSeconds,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listWebhookDeliveriesForRule = `-- name: ListWebhookDeliveriesForRule :many
+SELECT id, outbox_id, rule_id, service_id, url, headers, body, status, attempt_count, next_attempt_at, last_error, delivered_at, created_at, updated_at FROM webhook_deliveries
+WHERE rule_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListWebhookDeliveriesForRuleParams struct {
+	RuleID string `json:"rule_id"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListWebhookDeliveriesForRule(ctx context.Context, arg ListWebhookDeliveriesForRuleParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveriesForRule, arg.RuleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.OutboxID,
+			&i.RuleID,
+			&i.ServiceID,
+			&i.Url,
+			&i.Headers,
+			&i.Body,
+			&i.Status,
+			&i.AttemptCount,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveryAttempts = `-- name: ListWebhookDeliveryAttempts :many
+SELECT id, delivery_id, attempt_number, status_code, error, duration_ms, attempted_at FROM webhook_delivery_attempts
+WHERE delivery_id = ANY($1::uuid[])
+ORDER BY delivery_id, attempt_number
+`
+
+func (q *Queries) ListWebhookDeliveryAttempts(ctx context.Context, deliveryIds []uuid.UUID) ([]WebhookDeliveryAttempt, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveryAttempts, deliveryIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var i WebhookDeliveryAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeliveryID,
+			&i.AttemptNumber,
+			&i.StatusCode,
+			&i.Error,
+			&i.DurationMs,
+			&i.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookDelivered = `-- name: MarkWebhookDelivered :one
+UPDATE webhook_deliveries
+SET status = 'DELIVERED', attempt_count = $2, last_error = NULL, delivered_at = NOW()
+WHERE id = $1
+RETURNING id, outbox_id, rule_id, service_id, url, headers, body, status, attempt_count, next_attempt_at, last_error, delivered_at, created_at, updated_at
+`
+
+type MarkWebhookDeliveredParams struct {
+	ID           uuid.UUID `json:"id"`
+	AttemptCount int32     `json:"attempt_count"`
+}
+
+func (q *Queries) MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, markWebhookDelivered, arg.ID, arg.AttemptCount)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.OutboxID,
+		&i.RuleID,
+		&i.ServiceID,
+		&i.Url,
+		&i.Headers,
+		&i.Body,
+		&i.Status,
+		&i.AttemptCount,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const markWebhookFailed = `-- name: MarkWebhookFailed :one
+UPDATE webhook_deliveries
+SET status = 'FAILED', attempt_count = $2, last_error = $3
+WHERE id = $1
+RETURNING id, outbox_id, rule_id, service_id, url, headers, body, status, attempt_count, next_attempt_at, last_error, delivered_at, created_at, updated_at
+`
+
+type MarkWebhookFailedParams struct {
+	ID           uuid.UUID `json:"id"`
+	AttemptCount int32     `json:"attempt_count"`
+	LastError    *string   `json:"last_error"`
+}
+
+func (q *Queries) MarkWebhookFailed(ctx context.Context, arg MarkWebhookFailedParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, markWebhookFailed, arg.ID, arg.AttemptCount, arg.LastError)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.OutboxID,
+		&i.RuleID,
+		&i.ServiceID,
+		&i.Url,
+		&i.Headers,
+		&i.Body,
+		&i.Status,
+		&i.AttemptCount,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const scheduleWebhookRetry = `-- name: ScheduleWebhookRetry :one
+UPDATE webhook_deliveries
+SET attempt_count = $2, last_error = $3, next_attempt_at = $4
+WHERE id = $1
+RETURNING id, outbox_id, rule_id, service_id, url, headers, body, status, attempt_count, next_attempt_at, last_error, delivered_at, created_at, updated_at
+`
+
+type ScheduleWebhookRetryParams struct {
+	ID            uuid.UUID `json:"id"`
+	AttemptCount  int32     `json:"attempt_count"`
+	LastError     *string   `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+func (q *Queries) ScheduleWebhookRetry(ctx context.Context, arg ScheduleWebhookRetryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, scheduleWebhookRetry,
+		arg.ID,
+		arg.AttemptCount,
+		arg.LastError,
+		arg.NextAttemptAt,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.OutboxID,
+		&i.RuleID,
+		&i.ServiceID,
+		&i.Url,
+		&i.Headers,
+		&i.Body,
+		&i.Status,
+		&i.AttemptCount,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
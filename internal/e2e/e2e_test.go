@@ -44,7 +44,7 @@ func setupE2ETest(t *testing.T) (*TestServer, func()) {
 	serviceRepo := service.NewRepository(q)
 	metricRepo := metric.NewRepository(pool, q)
 	incidentRepo := incident.NewRepository(pool, q)
-	ruleRepo := rule.NewRepository(q)
+	ruleRepo := rule.NewRepository(pool, q)
 	outboxRepo := outbox.NewRepository(q)
 
 	// Create handlers
@@ -189,7 +189,7 @@ func TestE2E_MetricToIncidentFlow(t *testing.T) {
 		// Evaluate rules
 		for _, r := range rules {
 			violated := rule.Evaluate(&r, payload.Value)
-			if violated && r.Action == db.RuleActionOPENINCIDENT {
+			if violated && r.Action == "OPEN_INCIDENT" {
 				t.Logf("Rule %s violated, creating incident", r.ID)
 			}
 		}
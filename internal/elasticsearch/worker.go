@@ -47,7 +47,7 @@ func (w *Worker) Run(ctx context.Context) {
 }
 
 func (w *Worker) processEvents(ctx context.Context) {
-	events, err := w.outboxRepo.GetUnprocessedMetricEvents(ctx, ProcessorName, 100)
+	events, err := w.outboxRepo.ClaimMetricEvents(ctx, ProcessorName, 100)
 	if err != nil {
 		slog.Error("ESWorker: failed to get events", "error", err)
 		return
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/pkg/httputil"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
 )
 
 type Handler struct {
@@ -31,6 +32,9 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 
 	// Event endpoints (timeline)
 	mux.HandleFunc("GET /api/incidents/{id}/events", h.ListEvents)
+
+	// Metrics that contributed to the incident
+	mux.HandleFunc("GET /api/incidents/{id}/metrics", h.ListMetrics)
 }
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
@@ -314,3 +318,43 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 
 	httputil.Success(w, toEventResponseList(events))
 }
+
+// Metric handlers
+type MetricResponse struct {
+	ID         uuid.UUID `json:"id"`
+	ServiceID  string    `json:"service_id"`
+	MetricType string    `json:"metric_type"`
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func toMetricResponseList(metrics []db.Metric) []MetricResponse {
+	result := make([]MetricResponse, len(metrics))
+	for i, m := range metrics {
+		result[i] = MetricResponse{
+			ID:         m.ID,
+			ServiceID:  m.ServiceID,
+			MetricType: string(m.MetricType),
+			Value:      pgutil.NumericToFloat64(m.Value),
+			RecordedAt: m.RecordedAt,
+		}
+	}
+	return result
+}
+
+func (h *Handler) ListMetrics(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing incident id")
+		return
+	}
+
+	metrics, err := h.repo.ListMetrics(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list incident metrics", "error", err)
+		httputil.InternalError(w, "failed to list incident metrics")
+		return
+	}
+
+	httputil.Success(w, toMetricResponseList(metrics))
+}
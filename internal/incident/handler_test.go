@@ -30,7 +30,7 @@ func setupIncidentTest(t *testing.T) (*Handler, *db.Queries, *pgxpool.Pool, func
 		MetricType: db.MetricTypeLATENCYMS,
 		Threshold:  100.0,
 		Operator:   db.RuleOperatorValue0,
-		Action:     db.RuleActionOPENINCIDENT,
+		Action:     "OPEN_INCIDENT",
 		Severity:   db.IncidentSeverityHIGH,
 		IsActive:   true,
 	})
@@ -373,7 +373,13 @@ func TestIncidentRepository_CreateWithOutbox(t *testing.T) {
 	repo := NewRepository(pool, q)
 	ctx := context.Background()
 
-	inc, err := repo.CreateWithOutbox(ctx, "repo-service", "repo-rule", metric.ID, db.IncidentSeverityCRITICAL, "Test message", nil)
+	inc, err := repo.CreateWithOutbox(ctx, CreateParams{
+		ServiceID: "repo-service",
+		RuleID:    "repo-rule",
+		MetricID:  metric.ID,
+		Severity:  db.IncidentSeverityCRITICAL,
+		Message:   "Test message",
+	})
 	if err != nil {
 		t.Fatalf("Failed to create incident with outbox: %v", err)
 	}
@@ -402,6 +408,131 @@ func TestIncidentRepository_CreateWithOutbox(t *testing.T) {
 	}
 }
 
+func TestIncidentRepository_CreateWithOutbox_ContributingMetrics(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	testutil.TestService(t, q, "tv-service", "TV+")
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "composite-rule", IsActive: true})
+
+	buffer := testutil.TestMetric(t, q, testutil.TestMetricParams{
+		ServiceID:  "tv-service",
+		MetricType: db.MetricTypeBUFFERRATIO,
+		Value:      7.0,
+	})
+	loss := testutil.TestMetric(t, q, testutil.TestMetricParams{
+		ServiceID:  "tv-service",
+		MetricType: db.MetricTypePACKETLOSS,
+		Value:      2.0,
+	})
+
+	repo := NewRepository(pool, q)
+	ctx := context.Background()
+
+	inc, err := repo.CreateWithOutbox(ctx, CreateParams{
+		ServiceID:             "tv-service",
+		RuleID:                "composite-rule",
+		MetricID:              loss.ID,
+		Severity:              db.IncidentSeverityHIGH,
+		Message:               "composite condition matched",
+		ContributingMetricIDs: []uuid.UUID{buffer.ID, loss.ID},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create incident with outbox: %v", err)
+	}
+
+	metrics, err := repo.ListMetrics(ctx, inc.ID)
+	if err != nil {
+		t.Fatalf("Failed to list incident metrics: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Errorf("Expected 2 contributing metrics, got %d", len(metrics))
+	}
+}
+
+func TestIncidentRepository_RecordViolation(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	testutil.TestService(t, q, "dedup-service", "Dedup Service")
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "dedup-rule", IsActive: true})
+
+	repo := NewRepository(pool, q)
+	ctx := context.Background()
+
+	record := func() (*db.Incident, bool) {
+		t.Helper()
+		metric := testutil.TestMetric(t, q, testutil.TestMetricParams{ServiceID: "dedup-service", Value: 200})
+		inc, created, err := repo.RecordViolation(ctx, CreateParams{
+			ServiceID: "dedup-service",
+			RuleID:    "dedup-rule",
+			MetricID:  metric.ID,
+			Severity:  db.IncidentSeverityHIGH,
+			Message:   "threshold exceeded",
+		})
+		if err != nil {
+			t.Fatalf("Failed to record violation: %v", err)
+		}
+		if inc.MetricID == nil || *inc.MetricID != metric.ID {
+			t.Errorf("Expected latest metric_id %s, got %s", metric.ID, inc.MetricID)
+		}
+		return inc, created
+	}
+
+	first, created := record()
+	if !created || first.OccurrenceCount != 1 {
+		t.Fatalf("Expected a new incident with one occurrence, got created=%v count=%d", created, first.OccurrenceCount)
+	}
+
+	second, created := record()
+	third, _ := record()
+	if created || second.ID != first.ID || third.ID != first.ID {
+		t.Fatalf("Expected violations to attach to %s", first.ID)
+	}
+	if third.OccurrenceCount != 3 {
+		t.Errorf("Expected occurrence count 3, got %d", third.OccurrenceCount)
+	}
+	if third.LastSeenAt.Before(third.FirstSeenAt) {
+		t.Errorf("Expected last_seen_at after first_seen_at")
+	}
+
+	// Only the first violation notifies
+	events, err := q.GetUnprocessedIncidentEvents(ctx, db.GetUnprocessedIncidentEventsParams{
+		Processor: "test",
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get outbox events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 outbox event, got %d", len(events))
+	}
+
+	timeline, _ := repo.ListEvents(ctx, first.ID)
+	recorded := 0
+	for _, e := range timeline {
+		if e.EventType == db.IncidentEventTypeVIOLATIONRECORDED {
+			recorded++
+		}
+	}
+	if recorded != 2 {
+		t.Errorf("Expected 2 VIOLATION_RECORDED events, got %d", recorded)
+	}
+
+	// Once closed, the next violation opens a new incident
+	if _, err := repo.Close(ctx, first.ID); err != nil {
+		t.Fatalf("Failed to close incident: %v", err)
+	}
+	next, created := record()
+	if !created || next.ID == first.ID {
+		t.Errorf("Expected a new incident after the previous one was closed")
+	}
+}
+
 func TestIncidentRepository_ListOpen(t *testing.T) {
 	pool := testutil.GetTestPool(t)
 	q := db.New(pool)
@@ -478,12 +609,13 @@ func TestIncidentRepository_CountOpen(t *testing.T) {
 func TestIncidentModel_ToResponse(t *testing.T) {
 	now := time.Now()
 	message := "Test message"
+	metricID := uuid.New()
 
 	inc := &db.Incident{
 		ID:        "INC-001",
 		ServiceID: "test-svc",
 		RuleID:    "test-rule",
-		MetricID:  uuid.New(),
+		MetricID:  &metricID,
 		Severity:  db.IncidentSeverityCRITICAL,
 		Status:    db.IncidentStatusOPEN,
 		Message:   &message,
@@ -522,6 +654,7 @@ func TestIncidentHandler_RegisterRoutes(t *testing.T) {
 		{http.MethodGet, "/api/incidents"},
 		{http.MethodGet, "/api/incidents/INC-001"},
 		{http.MethodPatch, "/api/incidents/INC-001"},
+		{http.MethodGet, "/api/incidents/INC-001/metrics"},
 	}
 
 	for _, route := range routes {
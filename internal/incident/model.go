@@ -7,23 +7,45 @@ import (
 	"github.com/unitythemaker/tracely/internal/db"
 )
 
+// SeverityRank orders incident severities from LOW (1) to CRITICAL (4); unknown
+// severities rank 0
+func SeverityRank(s db.IncidentSeverity) int {
+	switch s {
+	case db.IncidentSeverityLOW:
+		return 1
+	case db.IncidentSeverityMEDIUM:
+		return 2
+	case db.IncidentSeverityHIGH:
+		return 3
+	case db.IncidentSeverityCRITICAL:
+		return 4
+	default:
+		return 0
+	}
+}
+
 type UpdateIncidentRequest struct {
 	Status string `json:"status"`
 }
 
 type IncidentResponse struct {
-	ID           string     `json:"id"`
-	ServiceID    string     `json:"service_id"`
-	RuleID       string     `json:"rule_id"`
-	MetricID     uuid.UUID  `json:"metric_id"`
-	Severity     string     `json:"severity"`
-	Status       string     `json:"status"`
-	Message      *string    `json:"message"`
-	OpenedAt     time.Time  `json:"opened_at"`
-	InProgressAt *time.Time `json:"in_progress_at"`
-	ClosedAt     *time.Time `json:"closed_at"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID              string     `json:"id"`
+	ServiceID       string     `json:"service_id"`
+	RuleID          string     `json:"rule_id"`
+	RuleRevision    *int32     `json:"rule_revision"`
+	EvaluationID    *int64     `json:"evaluation_id"`
+	MetricID        *uuid.UUID `json:"metric_id"`
+	Severity        string     `json:"severity"`
+	Status          string     `json:"status"`
+	Message         *string    `json:"message"`
+	OpenedAt        time.Time  `json:"opened_at"`
+	InProgressAt    *time.Time `json:"in_progress_at"`
+	ClosedAt        *time.Time `json:"closed_at"`
+	OccurrenceCount int32      `json:"occurrence_count"`
+	FirstSeenAt     time.Time  `json:"first_seen_at"`
+	LastSeenAt      time.Time  `json:"last_seen_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 func ToResponse(i *db.Incident) IncidentResponse {
@@ -38,18 +60,23 @@ func ToResponse(i *db.Incident) IncidentResponse {
 	}
 
 	return IncidentResponse{
-		ID:           i.ID,
-		ServiceID:    i.ServiceID,
-		RuleID:       i.RuleID,
-		MetricID:     i.MetricID,
-		Severity:     string(i.Severity),
-		Status:       string(i.Status),
-		Message:      i.Message,
-		OpenedAt:     i.OpenedAt,
-		InProgressAt: inProgressAt,
-		ClosedAt:     closedAt,
-		CreatedAt:    i.CreatedAt,
-		UpdatedAt:    i.UpdatedAt,
+		ID:              i.ID,
+		ServiceID:       i.ServiceID,
+		RuleID:          i.RuleID,
+		RuleRevision:    i.RuleRevision,
+		EvaluationID:    i.EvaluationID,
+		MetricID:        i.MetricID,
+		Severity:        string(i.Severity),
+		Status:          string(i.Status),
+		Message:         i.Message,
+		OpenedAt:        i.OpenedAt,
+		InProgressAt:    inProgressAt,
+		ClosedAt:        closedAt,
+		OccurrenceCount: i.OccurrenceCount,
+		FirstSeenAt:     i.FirstSeenAt,
+		LastSeenAt:      i.LastSeenAt,
+		CreatedAt:       i.CreatedAt,
+		UpdatedAt:       i.UpdatedAt,
 	}
 }
 
@@ -3,6 +3,8 @@ package incident
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -199,65 +201,384 @@ func (r *Repository) CreateEvent(ctx context.Context, incidentID string, eventTy
 	return &event, nil
 }
 
+// CreateParams describes an incident opened by the rule worker. MetricID is uuid.Nil
+// when an absence rule fires for a service that never reported.
+type CreateParams struct {
+	ServiceID    string
+	RuleID       string
+	MetricID     uuid.UUID
+	Severity     db.IncidentSeverity
+	Message      string
+	DepartmentID *string
+
+	// RuleRevision is the revision of the rule that fired; 0 when the rule predates revisions
+	RuleRevision int32
+
+	// EvaluationID is the recorded rule evaluation that fired, nil when evaluations are
+	// not recorded
+	EvaluationID *int64
+
+	// ContributingMetricIDs lists every metric that caused the incident. MetricID is
+	// always recorded, so this only needs to be set for composite rules.
+	ContributingMetricIDs []uuid.UUID
+}
+
 // CreateWithOutbox creates an incident and an outbox event in a single transaction
-func (r *Repository) CreateWithOutbox(ctx context.Context, serviceID, ruleID string, metricID uuid.UUID, severity db.IncidentSeverity, message string, departmentID *string) (*db.Incident, error) {
-	var incident db.Incident
+func (r *Repository) CreateWithOutbox(ctx context.Context, params CreateParams) (*db.Incident, error) {
+	var incident *db.Incident
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		inc, err := createWithOutbox(ctx, r.q.WithTx(tx), params)
+		incident = inc
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// RecordViolation opens an incident for a rule violation, or attaches the violation to
+// the incident of the same rule and service that is not closed yet. Attaching increments
+// the occurrence count and adds a timeline event but does not notify again, unless the
+// violation is more severe: then the incident's severity is raised and notified.
+// It reports whether a new incident was created.
+func (r *Repository) RecordViolation(ctx context.Context, params CreateParams) (*db.Incident, bool, error) {
+	var incident *db.Incident
+	var created bool
 
 	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
 		qtx := r.q.WithTx(tx)
 
-		// Get next ID from sequence
-		id, err := qtx.NextIncidentID(ctx)
-		if err != nil {
+		if err := qtx.LockIncidentKey(ctx, db.LockIncidentKeyParams{
+			RuleID:    params.RuleID,
+			ServiceID: params.ServiceID,
+		}); err != nil {
 			return err
 		}
 
-		// Create incident
-		inc, err := qtx.CreateIncident(ctx, db.CreateIncidentParams{
-			ID:        id,
-			ServiceID: serviceID,
-			RuleID:    ruleID,
-			MetricID:  metricID,
-			Severity:  severity,
-			Status:    db.IncidentStatusOPEN,
-			Message:   &message,
-			OpenedAt:  time.Now(),
+		open, err := qtx.GetUnresolvedIncidentForRule(ctx, db.GetUnresolvedIncidentForRuleParams{
+			RuleID:    params.RuleID,
+			ServiceID: params.ServiceID,
 		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			incident, err = createWithOutbox(ctx, qtx, params)
+			created = err == nil
+			return err
+		}
 		if err != nil {
 			return err
 		}
-		incident = inc
 
-		// Create outbox event
-		payloadMap := map[string]any{
-			"id":         inc.ID,
-			"service_id": inc.ServiceID,
-			"rule_id":    inc.RuleID,
-			"metric_id":  inc.MetricID.String(),
-			"severity":   string(inc.Severity),
-			"status":     string(inc.Status),
-			"message":    message,
+		inc, err := qtx.RecordIncidentOccurrence(ctx, db.RecordIncidentOccurrenceParams{
+			ID:       open.ID,
+			MetricID: metricIDOf(params),
+		})
+		if err != nil {
+			return err
 		}
-		if departmentID != nil {
-			payloadMap["department_id"] = *departmentID
+		incident = &inc
+
+		for _, metricID := range contributingMetricIDs(params) {
+			if err := qtx.AddIncidentMetric(ctx, db.AddIncidentMetricParams{
+				IncidentID: inc.ID,
+				MetricID:   metricID,
+			}); err != nil {
+				return err
+			}
 		}
 
-		payload, err := json.Marshal(payloadMap)
+		if escalates(&inc, params) {
+			escalated, err := escalate(ctx, qtx, &inc, params)
+			if err != nil {
+				return err
+			}
+			incident = escalated
+		}
+
+		actor := "system"
+		count := strconv.Itoa(int(inc.OccurrenceCount))
+		metadata, err := json.Marshal(map[string]any{
+			"metric_id": metricIDOf(params),
+			"message":   params.Message,
+		})
 		if err != nil {
 			return err
 		}
-
-		_, err = qtx.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
-			EventType:     db.EventTypeINCIDENTCREATED,
-			AggregateType: "incident",
-			AggregateID:   inc.ID,
-			Payload:       payload,
+		_, err = qtx.CreateIncidentEvent(ctx, db.CreateIncidentEventParams{
+			IncidentID: inc.ID,
+			EventType:  db.IncidentEventTypeVIOLATIONRECORDED,
+			Actor:      &actor,
+			NewValue:   &count,
+			Metadata:   metadata,
 		})
 		return err
 	})
 
+	if err != nil {
+		return nil, false, err
+	}
+	return incident, created, nil
+}
+
+// createWithOutbox creates an incident, its contributing metrics and the
+// INCIDENT_CREATED outbox event using the given transaction
+func createWithOutbox(ctx context.Context, qtx *db.Queries, params CreateParams) (*db.Incident, error) {
+	// Get next ID from sequence
+	id, err := qtx.NextIncidentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleRevision *int32
+	if params.RuleRevision > 0 {
+		ruleRevision = &params.RuleRevision
+	}
+
+	// Create incident
+	inc, err := qtx.CreateIncident(ctx, db.CreateIncidentParams{
+		ID:           id,
+		ServiceID:    params.ServiceID,
+		RuleID:       params.RuleID,
+		MetricID:     metricIDOf(params),
+		Severity:     params.Severity,
+		Status:       db.IncidentStatusOPEN,
+		Message:      &params.Message,
+		OpenedAt:     time.Now(),
+		RuleRevision: ruleRevision,
+		EvaluationID: params.EvaluationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Record contributing metrics
+	metricIDs := contributingMetricIDs(params)
+	for _, metricID := range metricIDs {
+		if err := qtx.AddIncidentMetric(ctx, db.AddIncidentMetricParams{
+			IncidentID: inc.ID,
+			MetricID:   metricID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create outbox event
+	metricIDStrings := make([]string, len(metricIDs))
+	for i, metricID := range metricIDs {
+		metricIDStrings[i] = metricID.String()
+	}
+	payloadMap := map[string]any{
+		"id":         inc.ID,
+		"service_id": inc.ServiceID,
+		"rule_id":    inc.RuleID,
+		"metric_id":  inc.MetricID,
+		"metric_ids": metricIDStrings,
+		"severity":   string(inc.Severity),
+		"status":     string(inc.Status),
+		"message":    params.Message,
+	}
+	if params.DepartmentID != nil {
+		payloadMap["department_id"] = *params.DepartmentID
+	}
+
+	payload, err := json.Marshal(payloadMap)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = qtx.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		EventType:     db.EventTypeINCIDENTCREATED,
+		AggregateType: "incident",
+		AggregateID:   inc.ID,
+		Payload:       payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// metricIDOf returns the metric that fired, nil when there is none
+func metricIDOf(params CreateParams) *uuid.UUID {
+	if params.MetricID == uuid.Nil {
+		return nil
+	}
+	return &params.MetricID
+}
+
+// contributingMetricIDs returns the triggering metric followed by the other contributing metrics
+func contributingMetricIDs(params CreateParams) []uuid.UUID {
+	var ids []uuid.UUID
+	if params.MetricID != uuid.Nil {
+		ids = append(ids, params.MetricID)
+	}
+	for _, id := range params.ContributingMetricIDs {
+		if id != params.MetricID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ListMetrics returns the metrics that contributed to an incident
+func (r *Repository) ListMetrics(ctx context.Context, incidentID string) ([]db.Metric, error) {
+	return r.q.ListIncidentMetrics(ctx, incidentID)
+}
+
+// ListUnresolvedForRule returns the incidents of a rule and service that are not closed
+func (r *Repository) ListUnresolvedForRule(ctx context.Context, ruleID, serviceID string) ([]db.Incident, error) {
+	return r.q.ListUnresolvedIncidentsForRule(ctx, db.ListUnresolvedIncidentsForRuleParams{
+		RuleID:    ruleID,
+		ServiceID: serviceID,
+	})
+}
+
+// LastUnresolvedViolation returns when the latest violation of the unresolved incidents
+// of a rule and service was recorded. ok is false when the rule has no unresolved
+// incident for the service.
+func (r *Repository) LastUnresolvedViolation(ctx context.Context, ruleID, serviceID string) (time.Time, bool, error) {
+	at, err := r.q.GetLastUnresolvedViolation(ctx, db.GetLastUnresolvedViolationParams{
+		RuleID:    ruleID,
+		ServiceID: serviceID,
+	})
+	if err == pgx.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return at, true, nil
+}
+
+// Resolve closes an incident on behalf of the system. It records a STATUS_CHANGED
+// timeline event and an INCIDENT_UPDATED outbox event in a single transaction.
+func (r *Repository) Resolve(ctx context.Context, id string, message string, departmentID *string) (*db.Incident, error) {
+	var incident db.Incident
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+
+		current, err := qtx.GetIncident(ctx, id)
+		if err != nil {
+			return err
+		}
+		oldStatus := string(current.Status)
+
+		inc, err := qtx.CloseIncident(ctx, id)
+		if err != nil {
+			return err
+		}
+		incident = inc
+
+		actor := "system"
+		newStatus := string(inc.Status)
+		if _, err := qtx.CreateIncidentEvent(ctx, db.CreateIncidentEventParams{
+			IncidentID: inc.ID,
+			EventType:  db.IncidentEventTypeSTATUSCHANGED,
+			Actor:      &actor,
+			OldValue:   &oldStatus,
+			NewValue:   &newStatus,
+		}); err != nil {
+			return err
+		}
+
+		return createUpdatedOutbox(ctx, qtx, &inc, message, departmentID)
+	})
+
 	if err != nil {
 		return nil, err
 	}
 	return &incident, nil
 }
+
+// escalates reports whether a violation attached to an open incident is more severe
+// than the incident
+func escalates(open *db.Incident, params CreateParams) bool {
+	return SeverityRank(params.Severity) > SeverityRank(open.Severity)
+}
+
+// escalate raises the severity of an open incident to that of a worse violation. It
+// records a SEVERITY_CHANGED timeline event and an INCIDENT_UPDATED outbox event so
+// the escalation is notified.
+func escalate(ctx context.Context, qtx *db.Queries, open *db.Incident, params CreateParams) (*db.Incident, error) {
+	inc, err := qtx.UpdateIncidentSeverity(ctx, db.UpdateIncidentSeverityParams{
+		ID:       open.ID,
+		Severity: params.Severity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actor := "system"
+	oldSeverity := string(open.Severity)
+	newSeverity := string(inc.Severity)
+	metadata, err := json.Marshal(map[string]any{
+		"metric_id": metricIDOf(params),
+		"message":   params.Message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := qtx.CreateIncidentEvent(ctx, db.CreateIncidentEventParams{
+		IncidentID: inc.ID,
+		EventType:  db.IncidentEventTypeSEVERITYCHANGED,
+		Actor:      &actor,
+		OldValue:   &oldSeverity,
+		NewValue:   &newSeverity,
+		Metadata:   metadata,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := createUpdatedOutbox(ctx, qtx, &inc, params.Message, params.DepartmentID); err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// createUpdatedOutbox queues the INCIDENT_UPDATED event of an incident using the given
+// transaction
+func createUpdatedOutbox(ctx context.Context, qtx *db.Queries, inc *db.Incident, message string, departmentID *string) error {
+	payloadMap := map[string]any{
+		"id":         inc.ID,
+		"service_id": inc.ServiceID,
+		"rule_id":    inc.RuleID,
+		"metric_id":  inc.MetricID,
+		"severity":   string(inc.Severity),
+		"status":     string(inc.Status),
+		"message":    message,
+	}
+	if departmentID != nil {
+		payloadMap["department_id"] = *departmentID
+	}
+
+	payload, err := json.Marshal(payloadMap)
+	if err != nil {
+		return err
+	}
+
+	_, err = qtx.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		EventType:     db.EventTypeINCIDENTUPDATED,
+		AggregateType: "incident",
+		AggregateID:   inc.ID,
+		Payload:       payload,
+	})
+	return err
+}
+
+// LastOpenedAt returns when the latest incident of a rule and service was opened
+func (r *Repository) LastOpenedAt(ctx context.Context, ruleID, serviceID string) (time.Time, bool, error) {
+	openedAt, err := r.q.GetLastIncidentOpenedAt(ctx, db.GetLastIncidentOpenedAtParams{
+		RuleID:    ruleID,
+		ServiceID: serviceID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return openedAt, true, nil
+}
@@ -0,0 +1,66 @@
+package incident
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestContributingMetricIDs(t *testing.T) {
+	trigger, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		params CreateParams
+		want   []uuid.UUID
+	}{
+		{"triggering metric only", CreateParams{MetricID: trigger}, []uuid.UUID{trigger}},
+		{
+			// The triggering metric is listed first and once
+			name:   "composite",
+			params: CreateParams{MetricID: trigger, ContributingMetricIDs: []uuid.UUID{other, trigger}},
+			want:   []uuid.UUID{trigger, other},
+		},
+		{"service that never reported", CreateParams{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contributingMetricIDs(tt.params); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if id := metricIDOf(CreateParams{}); id != nil {
+		t.Errorf("Expected no metric for uuid.Nil, got %s", id)
+	}
+	if id := metricIDOf(CreateParams{MetricID: trigger}); id == nil || *id != trigger {
+		t.Errorf("Expected metric %s, got %v", trigger, id)
+	}
+}
+
+func TestEscalates(t *testing.T) {
+	tests := []struct {
+		open      db.IncidentSeverity
+		violation db.IncidentSeverity
+		want      bool
+	}{
+		{db.IncidentSeverityMEDIUM, db.IncidentSeverityHIGH, true},
+		{db.IncidentSeverityLOW, db.IncidentSeverityCRITICAL, true},
+		{db.IncidentSeverityHIGH, db.IncidentSeverityHIGH, false},
+		{db.IncidentSeverityCRITICAL, db.IncidentSeverityLOW, false},
+		{db.IncidentSeverity("UNKNOWN"), db.IncidentSeverityLOW, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.open)+"/"+string(tt.violation), func(t *testing.T) {
+			got := escalates(&db.Incident{Severity: tt.open}, CreateParams{Severity: tt.violation})
+			if got != tt.want {
+				t.Errorf("Expected escalates=%v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/pkg/httputil"
 )
@@ -22,6 +23,7 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/metrics", h.List)
 	mux.HandleFunc("GET /api/metrics/chart", h.ChartData)
 	mux.HandleFunc("POST /api/metrics", h.Create)
+	mux.HandleFunc("GET /api/metrics/{id}/evaluations", h.ListEvaluations)
 }
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
@@ -186,3 +188,26 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	httputil.Created(w, ToResponse(metric))
 }
+
+// ListEvaluations explains how the rule worker evaluated each active rule for a metric.
+// Evaluations are only recorded when enabled and are kept for a retention period.
+func (h *Handler) ListEvaluations(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.BadRequest(w, "invalid metric id")
+		return
+	}
+
+	if _, err := h.repo.Get(r.Context(), id); err != nil {
+		httputil.NotFound(w, "metric not found")
+		return
+	}
+
+	evaluations, err := h.repo.ListEvaluations(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list evaluations", "metric_id", id, "error", err)
+		httputil.InternalError(w, "failed to list evaluations")
+		return
+	}
+	httputil.Success(w, ToEvaluationResponseList(evaluations))
+}
@@ -302,6 +302,68 @@ func TestMetricHandler_Create_AllMetricTypes(t *testing.T) {
 	}
 }
 
+func TestMetricHandler_ListEvaluations(t *testing.T) {
+	handler, q, _, cleanup := setupMetricTest(t)
+	defer cleanup()
+
+	m := testutil.TestMetric(t, q, testutil.TestMetricParams{
+		ServiceID:  "test-service",
+		MetricType: db.MetricTypeLATENCYMS,
+		Value:      100.5,
+	})
+	reason := "outside schedule"
+	for _, params := range []db.CreateRuleEvaluationParams{
+		{MetricID: m.ID, RuleID: "latency", Outcome: db.EvaluationOutcomeNOTMATCHED},
+		{MetricID: m.ID, RuleID: "night", Outcome: db.EvaluationOutcomeSKIPPED, Reason: &reason},
+	} {
+		if _, err := q.CreateRuleEvaluation(context.Background(), params); err != nil {
+			t.Fatalf("Failed to create evaluation: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/"+m.ID.String()+"/evaluations", nil)
+	req.SetPathValue("id", m.ID.String())
+	rr := httptest.NewRecorder()
+	handler.ListEvaluations(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data []EvaluationResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Data) != 2 {
+		t.Fatalf("Expected 2 evaluations, got %d", len(resp.Data))
+	}
+	if e := resp.Data[1]; e.RuleID != "night" || e.Outcome != "SKIPPED" || e.Reason == nil || *e.Reason != reason {
+		t.Errorf("Unexpected evaluation %+v", e)
+	}
+}
+
+func TestMetricHandler_ListEvaluations_NotFound(t *testing.T) {
+	handler, _, _, cleanup := setupMetricTest(t)
+	defer cleanup()
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{"not-a-uuid", http.StatusBadRequest},
+		{"6f1c1f4e-8d1b-4d51-9a4c-2b7f3f0c9a11", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics/"+tt.id+"/evaluations", nil)
+		req.SetPathValue("id", tt.id)
+		rr := httptest.NewRecorder()
+		handler.ListEvaluations(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("ListEvaluations(%s) status = %d, want %d", tt.id, rr.Code, tt.status)
+		}
+	}
+}
+
 func TestMetricHandler_RegisterRoutes(t *testing.T) {
 	handler, _, _, cleanup := setupMetricTest(t)
 	defer cleanup()
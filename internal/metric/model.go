@@ -70,6 +70,38 @@ func ToResponse(m *db.Metric) MetricResponse {
 	}
 }
 
+// EvaluationResponse explains how a rule was evaluated for a metric. Outcome is
+// MATCHED, NOT_MATCHED, SKIPPED or SUPPRESSED, with the reason when there is one.
+type EvaluationResponse struct {
+	ID           int64     `json:"id"`
+	RuleID       string    `json:"rule_id"`
+	RuleRevision *int32    `json:"rule_revision"`
+	Outcome      string    `json:"outcome"`
+	Reason       *string   `json:"reason"`
+	Value        *float64  `json:"value"`
+	Message      *string   `json:"message"`
+	IncidentID   *string   `json:"incident_id"`
+	EvaluatedAt  time.Time `json:"evaluated_at"`
+}
+
+func ToEvaluationResponseList(rows []db.ListMetricEvaluationsRow) []EvaluationResponse {
+	result := make([]EvaluationResponse, len(rows))
+	for i, e := range rows {
+		result[i] = EvaluationResponse{
+			ID:           e.ID,
+			RuleID:       e.RuleID,
+			RuleRevision: e.RuleRevision,
+			Outcome:      string(e.Outcome),
+			Reason:       e.Reason,
+			Value:        e.Value,
+			Message:      e.Message,
+			IncidentID:   e.IncidentID,
+			EvaluatedAt:  e.EvaluatedAt,
+		}
+	}
+	return result
+}
+
 func ToResponseList(metrics []db.Metric) []MetricResponse {
 	result := make([]MetricResponse, len(metrics))
 	for i, m := range metrics {
@@ -29,6 +29,12 @@ func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*db.Metric, error)
 	return &m, nil
 }
 
+// ListEvaluations returns how each rule was evaluated for a metric, with the incident
+// an evaluation opened
+func (r *Repository) ListEvaluations(ctx context.Context, metricID uuid.UUID) ([]db.ListMetricEvaluationsRow, error) {
+	return r.q.ListMetricEvaluations(ctx, metricID)
+}
+
 func (r *Repository) List(ctx context.Context, limit, offset int32) ([]db.Metric, error) {
 	return r.q.ListMetrics(ctx, db.ListMetricsParams{
 		Limit:  limit,
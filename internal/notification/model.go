@@ -6,9 +6,20 @@ import (
 	"github.com/unitythemaker/tracely/internal/db"
 )
 
+// Request is the payload of a NOTIFICATION_REQUESTED outbox event: a notification that
+// is sent for a rule without opening an incident
+type Request struct {
+	RuleID       string  `json:"rule_id"`
+	ServiceID    string  `json:"service_id"`
+	Severity     string  `json:"severity"`
+	Message      string  `json:"message"`
+	DepartmentID *string `json:"department_id,omitempty"`
+}
+
 type NotificationResponse struct {
 	ID           string    `json:"id"`
-	IncidentID   string    `json:"incident_id"`
+	IncidentID   *string   `json:"incident_id"`
+	RuleID       *string   `json:"rule_id,omitempty"`
 	Target       string    `json:"target"`
 	Message      string    `json:"message"`
 	DepartmentID *string   `json:"department_id,omitempty"`
@@ -22,6 +33,7 @@ func ToResponse(n *db.Notification) NotificationResponse {
 	return NotificationResponse{
 		ID:           n.ID,
 		IncidentID:   n.IncidentID,
+		RuleID:       n.RuleID,
 		Target:       n.Target,
 		Message:      n.Message,
 		DepartmentID: n.DepartmentID,
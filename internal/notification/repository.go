@@ -2,6 +2,8 @@ package notification
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/unitythemaker/tracely/internal/db"
 )
@@ -30,7 +32,7 @@ func (r *Repository) List(ctx context.Context, limit, offset int32) ([]db.Notifi
 }
 
 func (r *Repository) ListByIncident(ctx context.Context, incidentID string) ([]db.Notification, error) {
-	return r.q.ListNotificationsByIncident(ctx, incidentID)
+	return r.q.ListNotificationsByIncident(ctx, &incidentID)
 }
 
 func (r *Repository) ListFiltered(ctx context.Context, params ListFilteredParams) ([]db.Notification, int, error) {
@@ -70,25 +72,55 @@ func (r *Repository) ListFiltered(ctx context.Context, params ListFilteredParams
 }
 
 func (r *Repository) Create(ctx context.Context, incidentID, target, message string, departmentID *string) (*db.Notification, error) {
+	return r.create(ctx, db.CreateNotificationParams{
+		IncidentID:   &incidentID,
+		Target:       target,
+		Message:      message,
+		DepartmentID: departmentID,
+	})
+}
+
+// CreateForRule stores a notification a rule sent without opening an incident
+func (r *Repository) CreateForRule(ctx context.Context, ruleID, target, message string, departmentID *string) (*db.Notification, error) {
+	return r.create(ctx, db.CreateNotificationParams{
+		RuleID:       &ruleID,
+		Target:       target,
+		Message:      message,
+		DepartmentID: departmentID,
+	})
+}
+
+func (r *Repository) create(ctx context.Context, params db.CreateNotificationParams) (*db.Notification, error) {
 	// Get next ID from sequence
 	id, err := r.q.NextNotificationID(ctx)
 	if err != nil {
 		return nil, err
 	}
+	params.ID = id
 
-	n, err := r.q.CreateNotification(ctx, db.CreateNotificationParams{
-		ID:           id,
-		IncidentID:   incidentID,
-		Target:       target,
-		Message:      message,
-		DepartmentID: departmentID,
-	})
+	n, err := r.q.CreateNotification(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 	return &n, nil
 }
 
+// Enqueue queues a notification of a rule for the notification worker
+func (r *Repository) Enqueue(ctx context.Context, req Request) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification request: %w", err)
+	}
+
+	_, err = r.q.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		EventType:     db.EventTypeNOTIFICATIONREQUESTED,
+		AggregateType: "rule",
+		AggregateID:   req.RuleID,
+		Payload:       payload,
+	})
+	return err
+}
+
 func (r *Repository) MarkAsRead(ctx context.Context, id string) (*db.Notification, error) {
 	n, err := r.q.MarkNotificationAsRead(ctx, id)
 	if err != nil {
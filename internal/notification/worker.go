@@ -44,21 +44,35 @@ func (w *Worker) Run(ctx context.Context) {
 }
 
 func (w *Worker) processEvents(ctx context.Context) {
-	events, err := w.outboxRepo.GetUnprocessedIncidentEvents(ctx, ProcessorName, 100)
+	events, err := w.outboxRepo.ClaimIncidentEvents(ctx, ProcessorName, 100)
 	if err != nil {
 		slog.Error("NotificationWorker: failed to get events", "error", err)
 		return
 	}
 
 	for _, event := range events {
-		if err := w.processEvent(ctx, event); err != nil {
-			slog.Error("NotificationWorker: failed to process event", "event_id", event.ID, "error", err)
-			continue
-		}
+		w.handle(ctx, event, w.processEvent)
+	}
 
-		if err := w.outboxRepo.MarkProcessed(ctx, event.ID, ProcessorName); err != nil {
-			slog.Error("NotificationWorker: failed to mark event processed", "event_id", event.ID, "error", err)
-		}
+	requests, err := w.outboxRepo.ClaimNotificationEvents(ctx, ProcessorName, 100)
+	if err != nil {
+		slog.Error("NotificationWorker: failed to get notification requests", "error", err)
+		return
+	}
+	for _, event := range requests {
+		w.handle(ctx, event, w.processRequest)
+	}
+}
+
+// handle processes an event and marks it processed once it succeeded
+func (w *Worker) handle(ctx context.Context, event db.Outbox, process func(context.Context, db.Outbox) error) {
+	if err := process(ctx, event); err != nil {
+		slog.Error("NotificationWorker: failed to process event", "event_id", event.ID, "error", err)
+		return
+	}
+
+	if err := w.outboxRepo.MarkProcessed(ctx, event.ID, ProcessorName); err != nil {
+		slog.Error("NotificationWorker: failed to mark event processed", "event_id", event.ID, "error", err)
 	}
 }
 
@@ -99,6 +113,29 @@ func (w *Worker) processEvent(ctx context.Context, event db.Outbox) error {
 	return nil
 }
 
+// processRequest sends the notification of a rule whose action only notifies
+func (w *Worker) processRequest(ctx context.Context, event db.Outbox) error {
+	var req Request
+	if err := json.Unmarshal(event.Payload, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	target := "OPS_TEAM"
+	if req.DepartmentID != nil {
+		target = *req.DepartmentID
+	}
+
+	message := fmt.Sprintf("[%s] Rule %s: %s (Service: %s)",
+		req.Severity, req.RuleID, req.Message, req.ServiceID)
+
+	sendMockNotification(target, message, "", req.Severity)
+
+	if _, err := w.notifRepo.CreateForRule(ctx, req.RuleID, target, message, req.DepartmentID); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
 // sendMockNotification is a mock function that simulates sending notifications
 // In a real system, this could send to email, Slack, SMS, etc.
 func sendMockNotification(target, message, incidentID, severity string) {
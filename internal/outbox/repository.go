@@ -2,11 +2,16 @@ package outbox
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 )
 
+// ClaimLease is how long claimed events are hidden from other instances. Processors must
+// mark a claimed batch processed well within it.
+const ClaimLease = 5 * time.Minute
+
 type Repository struct {
 	q *db.Queries
 }
@@ -30,6 +35,90 @@ func (r *Repository) GetUnprocessedIncidentEvents(ctx context.Context, processor
 	})
 }
 
+func (r *Repository) GetUnprocessedWebhookEvents(ctx context.Context, processor string, limit int32) ([]db.Outbox, error) {
+	return r.q.GetUnprocessedEvents(ctx, db.GetUnprocessedEventsParams{
+		Processor: processor,
+		EventType: db.EventTypeWEBHOOKREQUESTED,
+		Limit:     limit,
+	})
+}
+
+func (r *Repository) GetUnprocessedNotificationEvents(ctx context.Context, processor string, limit int32) ([]db.Outbox, error) {
+	return r.q.GetUnprocessedEvents(ctx, db.GetUnprocessedEventsParams{
+		Processor: processor,
+		EventType: db.EventTypeNOTIFICATIONREQUESTED,
+		Limit:     limit,
+	})
+}
+
+// ClaimMetricEvents claims unprocessed METRIC_CREATED events for ClaimLease, so other
+// instances running the processor skip them
+func (r *Repository) ClaimMetricEvents(ctx context.Context, processor string, limit int32) ([]db.Outbox, error) {
+	return r.claim(ctx, processor, limit, db.EventTypeMETRICCREATED)
+}
+
+// ClaimIncidentEvents claims unprocessed incident events like ClaimMetricEvents
+func (r *Repository) ClaimIncidentEvents(ctx context.Context, processor string, limit int32) ([]db.Outbox, error) {
+	return r.claim(ctx, processor, limit, db.EventTypeINCIDENTCREATED, db.EventTypeINCIDENTUPDATED)
+}
+
+// ClaimWebhookEvents claims unprocessed WEBHOOK_REQUESTED events like ClaimMetricEvents
+func (r *Repository) ClaimWebhookEvents(ctx context.Context, processor string, limit int32) ([]db.Outbox, error) {
+	return r.claim(ctx, processor, limit, db.EventTypeWEBHOOKREQUESTED)
+}
+
+// ClaimNotificationEvents claims unprocessed NOTIFICATION_REQUESTED events like ClaimMetricEvents
+func (r *Repository) ClaimNotificationEvents(ctx context.Context, processor string, limit int32) ([]db.Outbox, error) {
+	return r.claim(ctx, processor, limit, db.EventTypeNOTIFICATIONREQUESTED)
+}
+
+func (r *Repository) claim(ctx context.Context, processor string, limit int32, eventTypes ...db.EventType) ([]db.Outbox, error) {
+	types := make([]string, len(eventTypes))
+	for i, t := range eventTypes {
+		types[i] = string(t)
+	}
+	return r.q.ClaimUnprocessedEvents(ctx, db.ClaimUnprocessedEventsParams{
+		Processor:    processor,
+		EventTypes:   types,
+		LimitVal:     limit,
+		LeaseSeconds: int32(ClaimLease.Seconds()),
+	})
+}
+
+// ServiceEvent is a METRIC_CREATED event claimed with the lease of its service
+type ServiceEvent struct {
+	db.Outbox
+	ServiceID string
+	// Generation changes whenever the service's lease changes owner
+	Generation int64
+}
+
+// ClaimMetricEventsByService claims unprocessed METRIC_CREATED events together with
+// leases on their services, so the metrics of a service are processed by one owner at a
+// time. Events of services leased by another owner are skipped; leases the owner already
+// holds are renewed.
+func (r *Repository) ClaimMetricEventsByService(ctx context.Context, processor, owner string, limit int32, lease time.Duration) ([]ServiceEvent, error) {
+	rows, err := r.q.ClaimMetricEventsByService(ctx, db.ClaimMetricEventsByServiceParams{
+		Processor:    processor,
+		Owner:        owner,
+		LimitVal:     limit,
+		LeaseSeconds: int32(lease.Seconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]ServiceEvent, len(rows))
+	for i, row := range rows {
+		events[i] = ServiceEvent{Outbox: row.Outbox, ServiceID: row.LeaseServiceID, Generation: row.LeaseGeneration}
+	}
+	return events, nil
+}
+
+// ReleaseServiceLeases gives up the service leases of an owner
+func (r *Repository) ReleaseServiceLeases(ctx context.Context, owner string) error {
+	return r.q.ReleaseServiceLeases(ctx, owner)
+}
+
 func (r *Repository) MarkProcessed(ctx context.Context, outboxID uuid.UUID, processor string) error {
 	return r.q.MarkEventProcessed(ctx, db.MarkEventProcessedParams{
 		OutboxID:  outboxID,
@@ -37,6 +126,16 @@ func (r *Repository) MarkProcessed(ctx context.Context, outboxID uuid.UUID, proc
 	})
 }
 
+// MarkFailed dead-letters an event the processor can never handle, so it is not retried.
+// The reason is kept on the processing record.
+func (r *Repository) MarkFailed(ctx context.Context, outboxID uuid.UUID, processor, reason string) error {
+	return r.q.MarkEventFailed(ctx, db.MarkEventFailedParams{
+		OutboxID:  outboxID,
+		Processor: processor,
+		Error:     &reason,
+	})
+}
+
 func (r *Repository) Cleanup(ctx context.Context) error {
 	return r.q.CleanupOldEvents(ctx)
 }
@@ -469,3 +469,115 @@ func TestOutboxRepository_EventOrdering(t *testing.T) {
 		}
 	}
 }
+
+func TestOutboxRepository_ClaimEvents(t *testing.T) {
+	repo, q, cleanup := setupOutboxTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		testutil.TestOutboxEvent(t, q, testutil.TestOutboxEventParams{
+			EventType: db.EventTypeMETRICCREATED,
+			Payload:   []byte("{}"),
+		})
+	}
+	testutil.TestOutboxEvent(t, q, testutil.TestOutboxEventParams{
+		EventType:     db.EventTypeINCIDENTCREATED,
+		AggregateType: "incident",
+		Payload:       []byte("{}"),
+	})
+
+	first, err := repo.ClaimMetricEvents(ctx, "processor", 2)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(first) != 2 {
+		t.Fatalf("Expected 2 claimed events, got %d", len(first))
+	}
+
+	// Another instance of the processor only gets the unclaimed event
+	second, err := repo.ClaimMetricEvents(ctx, "processor", 10)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(second) != 1 || second[0].ID == first[0].ID || second[0].ID == first[1].ID {
+		t.Errorf("Expected the remaining event, got %+v", second)
+	}
+
+	// Claims are per processor
+	other, err := repo.ClaimMetricEvents(ctx, "other_processor", 10)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(other) != 3 {
+		t.Errorf("Expected 3 events for another processor, got %d", len(other))
+	}
+	incidents, err := repo.ClaimIncidentEvents(ctx, "processor", 10)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(incidents) != 1 {
+		t.Errorf("Expected 1 incident event, got %d", len(incidents))
+	}
+
+	// An expired claim is handed out again
+	if _, err := testutil.GetTestPool(t).Exec(ctx, "UPDATE outbox_claims SET claimed_until = NOW() WHERE outbox_id = $1", first[0].ID); err != nil {
+		t.Fatalf("Failed to expire claim: %v", err)
+	}
+	again, err := repo.ClaimMetricEvents(ctx, "processor", 10)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(again) != 1 || again[0].ID != first[0].ID {
+		t.Errorf("Expected the expired claim to be reclaimed, got %+v", again)
+	}
+}
+
+func TestOutboxRepository_ClaimMetricEventsByService(t *testing.T) {
+	repo, q, cleanup := setupOutboxTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, serviceID := range []string{"S1", "S2", "S1"} {
+		payload, _ := json.Marshal(map[string]any{"service_id": serviceID})
+		testutil.TestOutboxEvent(t, q, testutil.TestOutboxEventParams{
+			EventType: db.EventTypeMETRICCREATED,
+			Payload:   payload,
+		})
+	}
+
+	// The first owner leases S1 with the first event; the batch limit leaves S2 free
+	a, err := repo.ClaimMetricEventsByService(ctx, "processor", "a", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(a) != 1 || a[0].ServiceID != "S1" {
+		t.Fatalf("Expected an S1 event, got %+v", a)
+	}
+
+	// Another owner skips S1 while it is leased
+	b, err := repo.ClaimMetricEventsByService(ctx, "processor", "b", 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(b) != 1 || b[0].ServiceID != "S2" {
+		t.Fatalf("Expected only the S2 event, got %+v", b)
+	}
+
+	// Released leases are taken over with a new generation
+	if err := repo.ReleaseServiceLeases(ctx, "a"); err != nil {
+		t.Fatalf("Failed to release leases: %v", err)
+	}
+	b, err = repo.ClaimMetricEventsByService(ctx, "processor", "b", 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to claim events: %v", err)
+	}
+	if len(b) != 3 {
+		t.Fatalf("Expected all 3 events, got %+v", b)
+	}
+	for _, e := range b {
+		if e.ServiceID == "S1" && e.Generation != a[0].Generation+1 {
+			t.Errorf("Expected S1 to get generation %d, got %d", a[0].Generation+1, e.Generation)
+		}
+	}
+}
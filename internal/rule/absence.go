@@ -0,0 +1,193 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/incident"
+	"github.com/unitythemaker/tracely/internal/silence"
+	"github.com/unitythemaker/tracely/internal/webhook"
+)
+
+const (
+	// MinAbsenceSeconds is the shortest silence an absence rule can wait for; shorter
+	// windows would be dominated by the check interval
+	MinAbsenceSeconds = 60
+	// MaxAbsenceSeconds is the longest silence an absence rule can wait for
+	MaxAbsenceSeconds = 7 * 24 * 60 * 60
+)
+
+// AbsenceWorker periodically checks absence rules. The rule worker only runs when a
+// metric arrives, so it cannot notice a service that stopped reporting.
+type AbsenceWorker struct {
+	ruleRepo     *Repository
+	incidentRepo *incident.Repository
+	silenceRepo  *silence.Repository
+	interval     time.Duration
+}
+
+func NewAbsenceWorker(ruleRepo *Repository, incidentRepo *incident.Repository, silenceRepo *silence.Repository, interval time.Duration) *AbsenceWorker {
+	return &AbsenceWorker{
+		ruleRepo:     ruleRepo,
+		incidentRepo: incidentRepo,
+		silenceRepo:  silenceRepo,
+		interval:     interval,
+	}
+}
+
+func (w *AbsenceWorker) Run(ctx context.Context) {
+	slog.Info("AbsenceWorker started", "interval", w.interval)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("AbsenceWorker stopped")
+			return
+		case <-ticker.C:
+			w.check(ctx, time.Now())
+		}
+	}
+}
+
+// check opens an incident for every service that went silent and resolves the incidents
+// of services that report again. It keeps no state, so restarts and several instances
+// do not open duplicate incidents.
+func (w *AbsenceWorker) check(ctx context.Context, now time.Time) {
+	rows, err := w.ruleRepo.ListAbsenceChecks(ctx)
+	if err != nil {
+		slog.Error("AbsenceWorker: failed to list absence checks", "error", err)
+		return
+	}
+	actions, err := w.ruleRepo.Actions(ctx)
+	if err != nil {
+		slog.Error("AbsenceWorker: failed to list rule actions", "error", err)
+		return
+	}
+
+	for _, row := range rows {
+		rule := &row.QualityRule
+		violation := CheckAbsence(rule, row.LastRecordedAt, row.ExpectedSince, now)
+		silentSince := row.ExpectedSince
+		if row.LastRecordedAt.Valid {
+			silentSince = row.LastRecordedAt.Time
+		}
+		var action *db.RuleAction
+		if a, ok := actions[rule.Action]; ok {
+			action = &a
+		}
+
+		switch {
+		case violation != nil && !isPublished(rule) && InSchedule(rule, now):
+			// Record each gap in the data once, like the incident of a published rule
+			recorded, err := w.ruleRepo.HasDraftViolationSince(ctx, rule.ID, row.ServiceID, silentSince)
+			if err == nil && !recorded {
+				err = recordDraft(ctx, w.ruleRepo, rule, row.ServiceID, row.LastMetricID, violation)
+			}
+			if err != nil {
+				slog.Error("AbsenceWorker: failed to record draft violation", "rule_id", rule.ID, "error", err)
+			}
+
+		case violation != nil && !row.HasOpenIncident && InSchedule(rule, now):
+			silenced, err := recordIfSilenced(ctx, w.silenceRepo, w.ruleRepo, rule, row.ServiceID, row.LastMetricID, violation)
+			if err != nil {
+				slog.Error("AbsenceWorker: failed to check silences", "rule_id", rule.ID, "error", err)
+				continue
+			}
+			if silenced {
+				continue
+			}
+
+			// A service that never reported has no metric to point at
+			var metricID uuid.UUID
+			event := webhook.Event{
+				RuleID:     rule.ID,
+				ServiceID:  row.ServiceID,
+				MetricType: string(rule.MetricType),
+				Severity:   string(rule.Severity),
+				Message:    violation.Message,
+				RecordedAt: silentSince,
+			}
+			if row.LastMetricID != nil {
+				metricID = *row.LastMetricID
+				event.MetricID = metricID.String()
+			}
+			inc, _, err := w.incidentRepo.RecordViolation(ctx, incident.CreateParams{
+				ServiceID:    row.ServiceID,
+				RuleID:       rule.ID,
+				MetricID:     metricID,
+				Severity:     rule.Severity,
+				Message:      messageOf(action, event),
+				DepartmentID: routeOf(rule, action),
+				RuleRevision: rule.Revision,
+			})
+			if err != nil {
+				slog.Error("AbsenceWorker: failed to record violation", "rule_id", rule.ID, "error", err)
+				continue
+			}
+			slog.Info("AbsenceWorker: incident created",
+				"incident_id", inc.ID,
+				"rule_id", rule.ID,
+				"service_id", row.ServiceID,
+				"silent_since", silentSince,
+			)
+
+		case violation == nil && row.HasOpenIncident && row.LastRecordedAt.Valid:
+			w.resolve(ctx, rule, routeOf(rule, action), row.ServiceID, row.LastRecordedAt.Time)
+		}
+	}
+}
+
+// resolve closes the incidents of an absence rule once its service reports again
+func (w *AbsenceWorker) resolve(ctx context.Context, rule *db.QualityRule, departmentID *string, serviceID string, resumedAt time.Time) {
+	incidents, err := w.incidentRepo.ListUnresolvedForRule(ctx, rule.ID, serviceID)
+	if err != nil {
+		slog.Error("AbsenceWorker: failed to list unresolved incidents", "rule_id", rule.ID, "error", err)
+		return
+	}
+
+	message := fmt.Sprintf("Auto-resolved, %s data resumed at %s", rule.MetricType, resumedAt.UTC().Format(time.RFC3339))
+	for _, inc := range incidents {
+		if _, err := w.incidentRepo.Resolve(ctx, inc.ID, message, departmentID); err != nil {
+			slog.Error("AbsenceWorker: failed to resolve incident", "incident_id", inc.ID, "error", err)
+			return
+		}
+		slog.Info("AbsenceWorker: incident auto-resolved",
+			"incident_id", inc.ID,
+			"rule_id", rule.ID,
+			"service_id", serviceID,
+		)
+	}
+}
+
+// CheckAbsence reports whether a series has been silent for longer than the absence
+// window of a rule, given when its latest metric was recorded. A series that never
+// reported is silent since expectedSince, when the rule or its service was created.
+func CheckAbsence(rule *db.QualityRule, lastRecordedAt pgtype.Timestamptz, expectedSince, now time.Time) *Violation {
+	window := time.Duration(rule.AbsenceSeconds) * time.Second
+	if !lastRecordedAt.Valid {
+		silence := now.Sub(expectedSince)
+		if window <= 0 || silence < window {
+			return nil
+		}
+		return &Violation{
+			Message: fmt.Sprintf("%s: no data for %s (expected within %s, never reported)",
+				rule.MetricType, silence.Truncate(time.Second), window),
+		}
+	}
+
+	silence := now.Sub(lastRecordedAt.Time)
+	if window <= 0 || silence < window {
+		return nil
+	}
+	return &Violation{
+		Message: fmt.Sprintf("%s: no data for %s (expected within %s, last sample at %s)",
+			rule.MetricType, silence.Truncate(time.Second), window, lastRecordedAt.Time.UTC().Format(time.RFC3339)),
+	}
+}
@@ -0,0 +1,158 @@
+package rule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestCheckAbsence(t *testing.T) {
+	last := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	lastRecordedAt := pgtype.Timestamptz{Time: last, Valid: true}
+	created := last.Add(-24 * time.Hour)
+	rule := &db.QualityRule{MetricType: db.MetricTypeLATENCYMS, AbsenceSeconds: 300}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		fired bool
+	}{
+		{"within window", last.Add(4 * time.Minute), false},
+		{"at window", last.Add(5 * time.Minute), true},
+		{"long silence", last.Add(2 * time.Hour), true},
+		{"sample from the future", last.Add(-time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := CheckAbsence(rule, lastRecordedAt, created, tt.now)
+			if (v != nil) != tt.fired {
+				t.Fatalf("Expected fired=%v, got %v", tt.fired, v)
+			}
+		})
+	}
+
+	v := CheckAbsence(rule, lastRecordedAt, created, last.Add(7*time.Minute+500*time.Millisecond))
+	want := "LATENCY_MS: no data for 7m0s (expected within 5m0s, last sample at 2026-03-12T09:00:00Z)"
+	if v == nil || v.Message != want {
+		t.Errorf("Expected message %q, got %v", want, v)
+	}
+
+	if v := CheckAbsence(&db.QualityRule{MetricType: db.MetricTypeLATENCYMS}, lastRecordedAt, created, last.Add(time.Hour)); v != nil {
+		t.Errorf("Expected rules without absence_seconds to never fire, got %v", v)
+	}
+}
+
+func TestCheckAbsence_NeverReported(t *testing.T) {
+	created := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	rule := &db.QualityRule{MetricType: db.MetricTypeLATENCYMS, AbsenceSeconds: 300}
+
+	// A series that never reported is silent since the rule or service was created
+	if v := CheckAbsence(rule, pgtype.Timestamptz{}, created, created.Add(4*time.Minute)); v != nil {
+		t.Errorf("Expected no violation within the window after creation, got %v", v)
+	}
+	v := CheckAbsence(rule, pgtype.Timestamptz{}, created, created.Add(7*time.Minute))
+	want := "LATENCY_MS: no data for 7m0s (expected within 5m0s, never reported)"
+	if v == nil || v.Message != want {
+		t.Errorf("Expected message %q, got %v", want, v)
+	}
+}
+
+func TestAbsenceWorker(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ruleRepo := wt.worker.ruleRepo
+	_, err := ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency-silent",
+		RuleDefinition: RuleDefinition{
+			MetricType:     "LATENCY_MS",
+			AbsenceSeconds: 300,
+			Action:         "OPEN_INCIDENT",
+			Severity:       "HIGH",
+			IsActive:       true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, ruleRepo, "latency-silent")
+	absence := NewAbsenceWorker(ruleRepo, wt.incidentRepo, wt.worker.silenceRepo, time.Second)
+
+	// The event-driven worker ignores absence rules
+	wt.record(t, 100)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected no incidents from the rule worker, got %d", len(incidents))
+	}
+
+	absence.check(ctx, time.Now())
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	id := incidents[0].ID
+	if incidents[0].Message == nil || !strings.Contains(*incidents[0].Message, "LATENCY_MS: no data for") {
+		t.Errorf("Unexpected message: %v", incidents[0].Message)
+	}
+
+	// Later checks keep the one incident open
+	absence.check(ctx, time.Now())
+	if incidents := wt.incidents(t); len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident after a second check, got %d", len(incidents))
+	}
+
+	wt.start = time.Now().Truncate(time.Second)
+	wt.record(t, 100)
+	absence.check(ctx, time.Now())
+	inc, _ := wt.incidentRepo.Get(ctx, id)
+	if inc.Status != db.IncidentStatusCLOSED {
+		t.Fatalf("Expected incident to be resolved once data resumed, got %s", inc.Status)
+	}
+}
+
+func TestAbsenceWorker_NeverReported(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ruleRepo := wt.worker.ruleRepo
+	_, err := ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency-silent",
+		RuleDefinition: RuleDefinition{
+			MetricType:     "LATENCY_MS",
+			ServiceIDs:     []string{"S1"},
+			AbsenceSeconds: 300,
+			Action:         "OPEN_INCIDENT",
+			Severity:       "HIGH",
+			IsActive:       true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, ruleRepo, "latency-silent")
+	absence := NewAbsenceWorker(ruleRepo, wt.incidentRepo, wt.worker.silenceRepo, time.Second)
+
+	// S1 never reported, so the window runs from when the rule was created
+	absence.check(ctx, time.Now())
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected no incidents within the window, got %d", len(incidents))
+	}
+
+	absence.check(ctx, time.Now().Add(10*time.Minute))
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if incidents[0].MetricID != nil {
+		t.Errorf("Expected no metric on the incident, got %s", incidents[0].MetricID)
+	}
+	if incidents[0].Message == nil || !strings.Contains(*incidents[0].Message, "never reported") {
+		t.Errorf("Unexpected message: %v", incidents[0].Message)
+	}
+}
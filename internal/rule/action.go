@@ -0,0 +1,71 @@
+package rule
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/webhook"
+)
+
+// The built-in actions, registered by the migration that replaced the action enum
+const (
+	ActionOpenIncident = "OPEN_INCIDENT"
+	ActionThrottle     = "THROTTLE"
+	ActionWebhook      = "WEBHOOK"
+)
+
+// Actions indexes the registered rule actions by id
+type Actions map[string]db.RuleAction
+
+// indexActions maps the registered actions by id
+func indexActions(actions []db.RuleAction) Actions {
+	byID := make(Actions, len(actions))
+	for _, a := range actions {
+		byID[a.ID] = a
+	}
+	return byID
+}
+
+// behavior returns the behaviour of the action a rule references
+func (a Actions) behavior(id string) (db.ActionBehavior, error) {
+	action, ok := a[id]
+	if !ok {
+		return "", fmt.Errorf("unknown action: %s", id)
+	}
+	return action.Behavior, nil
+}
+
+// IsValidActionBehavior reports whether b is a behaviour the rule worker implements
+func IsValidActionBehavior(b db.ActionBehavior) bool {
+	switch b {
+	case db.ActionBehaviorOPENINCIDENT, db.ActionBehaviorNOTIFYONLY, db.ActionBehaviorWEBHOOK, db.ActionBehaviorTHROTTLE:
+		return true
+	default:
+		return false
+	}
+}
+
+// routeOf returns the department a violation is routed to: the rule's own, or the
+// department of its action when the rule has none
+func routeOf(rule *db.QualityRule, action *db.RuleAction) *string {
+	if rule.DepartmentID != nil || action == nil {
+		return rule.DepartmentID
+	}
+	return action.DepartmentID
+}
+
+// messageOf returns the message of a violation. Actions with a message template render
+// it with the same fields as webhook bodies; a template that fails to render falls back
+// to the violation message so the violation is not lost.
+func messageOf(action *db.RuleAction, event webhook.Event) string {
+	if action == nil || action.MessageTemplate == nil || *action.MessageTemplate == "" {
+		return event.Message
+	}
+	message, err := webhook.RenderBody(action.MessageTemplate, event)
+	if err != nil {
+		slog.Error("failed to render action message template", "action", action.ID, "rule_id", event.RuleID, "error", err)
+		return event.Message
+	}
+	return message
+}
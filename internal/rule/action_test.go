@@ -0,0 +1,245 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/notification"
+	"github.com/unitythemaker/tracely/internal/testutil"
+	"github.com/unitythemaker/tracely/internal/webhook"
+)
+
+// testActions returns the built-in actions and the custom actions of the seed data
+func testActions() Actions {
+	return indexActions([]db.RuleAction{
+		{ID: ActionOpenIncident, Behavior: db.ActionBehaviorOPENINCIDENT},
+		{ID: ActionThrottle, Behavior: db.ActionBehaviorTHROTTLE},
+		{ID: ActionWebhook, Behavior: db.ActionBehaviorWEBHOOK},
+		{ID: "QUALITY_ALERT", Behavior: db.ActionBehaviorOPENINCIDENT},
+		{ID: "STREAMING_WARNING", Behavior: db.ActionBehaviorNOTIFYONLY},
+	})
+}
+
+func TestValidateDefinition_Actions(t *testing.T) {
+	base := RuleDefinition{MetricType: "LATENCY_MS", Threshold: 200, Operator: ">", Severity: "HIGH"}
+
+	tests := []struct {
+		name   string
+		modify func(r *RuleDefinition)
+		valid  bool
+	}{
+		{"built-in action", func(r *RuleDefinition) { r.Action = ActionOpenIncident }, true},
+		{"custom action", func(r *RuleDefinition) { r.Action = "STREAMING_WARNING" }, true},
+		{"unknown action", func(r *RuleDefinition) { r.Action = "PAGE_ONCALL" }, false},
+		{"cooldown with WEBHOOK behavior", func(r *RuleDefinition) {
+			r.Action = ActionWebhook
+			r.WebhookURL = &[]string{"https://example.com/hook"}[0]
+			r.CooldownSeconds = 60
+		}, true},
+		{"cooldown without THROTTLE behavior", func(r *RuleDefinition) {
+			r.Action = "STREAMING_WARNING"
+			r.CooldownSeconds = 60
+		}, false},
+		{"webhook settings without WEBHOOK behavior", func(r *RuleDefinition) {
+			r.Action = "QUALITY_ALERT"
+			r.WebhookURL = &[]string{"https://example.com/hook"}[0]
+		}, false},
+		{"absence with OPEN_INCIDENT behavior", func(r *RuleDefinition) {
+			r.Action = "QUALITY_ALERT"
+			r.Operator, r.Threshold = "", 0
+			r.AbsenceSeconds = 300
+		}, true},
+		{"absence with NOTIFY_ONLY behavior", func(r *RuleDefinition) {
+			r.Action = "STREAMING_WARNING"
+			r.Operator, r.Threshold = "", 0
+			r.AbsenceSeconds = 300
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			tt.modify(&req)
+			if err := validateDefinition(&req, testActions()); (err == nil) != tt.valid {
+				t.Errorf("validateDefinition() = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestRouteAndMessageOf(t *testing.T) {
+	ops, netops := "OPS", "NETOPS"
+	template := "{{.ServiceID}} quality alert: {{.Message}}"
+	action := &db.RuleAction{ID: "QUALITY_ALERT", DepartmentID: &netops, MessageTemplate: &template}
+
+	if got := routeOf(&db.QualityRule{DepartmentID: &ops}, action); got == nil || *got != ops {
+		t.Errorf("Expected the rule's department to win, got %v", got)
+	}
+	if got := routeOf(&db.QualityRule{}, action); got == nil || *got != netops {
+		t.Errorf("Expected the action's department, got %v", got)
+	}
+	if got := routeOf(&db.QualityRule{}, nil); got != nil {
+		t.Errorf("Expected no department, got %v", *got)
+	}
+
+	event := webhook.Event{ServiceID: "S1", Message: "LATENCY_MS exceeded"}
+	if got := messageOf(action, event); got != "S1 quality alert: LATENCY_MS exceeded" {
+		t.Errorf("Unexpected rendered message %q", got)
+	}
+	if got := messageOf(&db.RuleAction{ID: "OPEN_INCIDENT"}, event); got != event.Message {
+		t.Errorf("Expected the violation message without a template, got %q", got)
+	}
+	broken := "{{.Missing}}"
+	if got := messageOf(&db.RuleAction{ID: "BROKEN", MessageTemplate: &broken}, event); got != event.Message {
+		t.Errorf("Expected a failing template to fall back to the violation message, got %q", got)
+	}
+}
+
+func TestRuleHandler_Actions(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	testutil.TestDepartment(t, q, "NETOPS", "Network Operations")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, bytes.NewReader([]byte(body))))
+		return rr
+	}
+
+	creates := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"id": "QUALITY_ALERT", "name": "Quality alert", "behavior": "OPEN_INCIDENT", "department_id": "NETOPS", "message_template": "{{.ServiceID}}: {{.Message}}"}`, http.StatusCreated},
+		{"duplicate", `{"id": "QUALITY_ALERT", "name": "Quality alert", "behavior": "OPEN_INCIDENT"}`, http.StatusConflict},
+		{"unknown behavior", `{"id": "PAGE", "name": "Page", "behavior": "PAGE"}`, http.StatusBadRequest},
+		{"without name", `{"id": "PAGE", "behavior": "NOTIFY_ONLY"}`, http.StatusBadRequest},
+		{"unknown template field", `{"id": "PAGE", "name": "Page", "behavior": "NOTIFY_ONLY", "message_template": "{{.Unknown}}"}`, http.StatusBadRequest},
+		{"unknown department", `{"id": "PAGE", "name": "Page", "behavior": "NOTIFY_ONLY", "department_id": "NOPE"}`, http.StatusBadRequest},
+	}
+	for _, tt := range creates {
+		if rr := do(http.MethodPost, "/api/rule-actions", tt.body); rr.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d. Body: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	// Rules reference the custom action by id
+	rr := do(http.MethodPost, "/api/rules", `{"id": "QR-02", "metric_type": "PACKET_LOSS", "threshold": 2, "operator": ">", "action": "QUALITY_ALERT", "severity": "HIGH"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected rule with a custom action to be created, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	rr = do(http.MethodPost, "/api/rules", `{"id": "QR-99", "metric_type": "PACKET_LOSS", "threshold": 2, "operator": ">", "action": "PAGE_ONCALL", "severity": "HIGH"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown action to be rejected, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodPatch, "/api/rule-actions/QUALITY_ALERT", `{"name": "Quality alert (network)", "department_id": "NETOPS"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var updated struct {
+		Data ActionResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &updated)
+	if updated.Data.Name != "Quality alert (network)" || updated.Data.Behavior != "OPEN_INCIDENT" || updated.Data.MessageTemplate != nil {
+		t.Errorf("Unexpected updated action %+v", updated.Data)
+	}
+	if rr := do(http.MethodPatch, "/api/rule-actions/MISSING", `{"name": "Missing"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing action, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	rr = do(http.MethodGet, "/api/rule-actions", "")
+	var list struct {
+		Data []ActionResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Data) != 4 {
+		t.Errorf("Expected the 3 built-in actions and QUALITY_ALERT, got %+v", list.Data)
+	}
+
+	deletes := []struct {
+		id         string
+		wantStatus int
+	}{
+		{"OPEN_INCIDENT", http.StatusConflict},
+		{"QUALITY_ALERT", http.StatusConflict},
+		{"MISSING", http.StatusNotFound},
+	}
+	for _, tt := range deletes {
+		if rr := do(http.MethodDelete, "/api/rule-actions/"+tt.id, ""); rr.Code != tt.wantStatus {
+			t.Errorf("DELETE %s: expected status %d, got %d. Body: %s", tt.id, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	if _, err := handler.repo.Delete(context.Background(), "QR-02", "test"); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if rr := do(http.MethodDelete, "/api/rule-actions/QUALITY_ALERT", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected an unused action to be deleted, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWorker_NotifyOnlyAction(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	testutil.TestDepartment(t, wt.queries, "VIDEO", "Video Delivery")
+	template := "{{.ServiceID}} streaming warning: {{.Message}}"
+	department := "VIDEO"
+	if _, err := wt.worker.ruleRepo.CreateAction(ctx, CreateActionRequest{
+		ID:              "STREAMING_WARNING",
+		Name:            "Streaming warning",
+		Behavior:        string(db.ActionBehaviorNOTIFYONLY),
+		DepartmentID:    &department,
+		MessageTemplate: &template,
+	}); err != nil {
+		t.Fatalf("Failed to create action: %v", err)
+	}
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "QR-03", Threshold: 150, Action: "STREAMING_WARNING", IsActive: true})
+	wt.worker.EnableEvaluations(time.Hour)
+
+	// The violation requests a notification routed to the action's department and opens no incident
+	wt.record(t, 200)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected NOTIFY_ONLY actions not to open incidents, got %d", len(incidents))
+	}
+	events, err := wt.worker.outboxRepo.GetUnprocessedNotificationEvents(ctx, "test", 10)
+	if err != nil {
+		t.Fatalf("Failed to list notification requests: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 notification request, got %d", len(events))
+	}
+	var req notification.Request
+	if err := json.Unmarshal(events[0].Payload, &req); err != nil {
+		t.Fatalf("Failed to decode notification request: %v", err)
+	}
+	if req.RuleID != "QR-03" || req.DepartmentID == nil || *req.DepartmentID != "VIDEO" || !strings.HasPrefix(req.Message, "S1 streaming warning: ") {
+		t.Errorf("Unexpected notification request %+v", req)
+	}
+
+	metrics, err := wt.metricRepo.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list metrics: %v", err)
+	}
+	evaluations, err := wt.queries.ListMetricEvaluations(ctx, metrics[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list evaluations: %v", err)
+	}
+	if len(evaluations) != 1 || evaluations[0].Outcome != db.EvaluationOutcomeMATCHED || evaluations[0].Reason == nil || *evaluations[0].Reason != "notification requested" {
+		t.Errorf("Expected a MATCHED evaluation with a notification request, got %+v", evaluations)
+	}
+}
@@ -0,0 +1,85 @@
+package rule
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// IsValidAggregation reports whether a is a known aggregation function
+func IsValidAggregation(a db.RuleAggregation) bool {
+	switch a {
+	case db.RuleAggregationNONE, db.RuleAggregationAVG, db.RuleAggregationMIN, db.RuleAggregationMAX,
+		db.RuleAggregationP50, db.RuleAggregationP95, db.RuleAggregationP99:
+		return true
+	default:
+		return false
+	}
+}
+
+// samplesInWindow returns the samples recorded within window of the last sample
+func samplesInWindow(samples []Sample, window time.Duration) []Sample {
+	if len(samples) == 0 {
+		return samples
+	}
+	cutoff := samples[len(samples)-1].RecordedAt.Add(-window)
+	start := sort.Search(len(samples), func(i int) bool {
+		return !samples[i].RecordedAt.Before(cutoff)
+	})
+	return samples[start:]
+}
+
+// Aggregate computes an aggregation function over sample values.
+// Percentiles use linear interpolation, matching PERCENTILE_CONT in GetMetricsAggregated.
+func Aggregate(agg db.RuleAggregation, samples []Sample) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	switch agg {
+	case db.RuleAggregationAVG:
+		var sum float64
+		for _, s := range samples {
+			sum += s.Value
+		}
+		return sum / float64(len(samples)), true
+	case db.RuleAggregationMIN:
+		min := samples[0].Value
+		for _, s := range samples[1:] {
+			min = math.Min(min, s.Value)
+		}
+		return min, true
+	case db.RuleAggregationMAX:
+		max := samples[0].Value
+		for _, s := range samples[1:] {
+			max = math.Max(max, s.Value)
+		}
+		return max, true
+	case db.RuleAggregationP50:
+		return percentile(samples, 0.5), true
+	case db.RuleAggregationP95:
+		return percentile(samples, 0.95), true
+	case db.RuleAggregationP99:
+		return percentile(samples, 0.99), true
+	default:
+		return 0, false
+	}
+}
+
+func percentile(samples []Sample, p float64) float64 {
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = s.Value
+	}
+	sort.Float64s(values)
+
+	pos := p * float64(len(values)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return values[lower]
+	}
+	return values[lower] + (values[upper]-values[lower])*(pos-float64(lower))
+}
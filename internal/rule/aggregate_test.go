@@ -0,0 +1,75 @@
+package rule
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func TestAggregate(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	samples := series(start, 40, 10, 30, 20, 50)
+
+	tests := []struct {
+		agg      db.RuleAggregation
+		expected float64
+	}{
+		{db.RuleAggregationAVG, 30},
+		{db.RuleAggregationMIN, 10},
+		{db.RuleAggregationMAX, 50},
+		{db.RuleAggregationP50, 30},
+		{db.RuleAggregationP95, 48},
+		{db.RuleAggregationP99, 49.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.agg), func(t *testing.T) {
+			value, ok := Aggregate(tt.agg, samples)
+			if !ok {
+				t.Fatalf("Aggregate() returned ok = false")
+			}
+			if math.Abs(value-tt.expected) > 1e-9 {
+				t.Errorf("Aggregate() = %v, want %v", value, tt.expected)
+			}
+		})
+	}
+
+	if _, ok := Aggregate(db.RuleAggregationAVG, nil); ok {
+		t.Errorf("Expected no aggregate for an empty series")
+	}
+	if _, ok := Aggregate(db.RuleAggregationNONE, samples); ok {
+		t.Errorf("Expected no aggregate for NONE")
+	}
+}
+
+func TestCheck_Aggregation(t *testing.T) {
+	rule := &db.QualityRule{
+		MetricType:    db.MetricTypeLATENCYMS,
+		Operator:      db.RuleOperatorValue0,
+		Threshold:     pgutil.Float64ToNumeric(150.0),
+		Aggregation:   db.RuleAggregationAVG,
+		WindowSeconds: 180,
+	}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	// The window covers the last four samples (three minutes back from the latest)
+	v := Check(rule, series(start, 500, 100, 200, 200, 200))
+	if v == nil {
+		t.Fatalf("Expected violation")
+	}
+	if v.Value != 175 {
+		t.Errorf("Expected aggregate value 175, got %v", v.Value)
+	}
+	if !strings.Contains(v.Message, "AVG") || !strings.Contains(v.Message, "3m0s") {
+		t.Errorf("Expected message to report aggregation and window, got %q", v.Message)
+	}
+
+	// A single spike does not move the average over the threshold
+	if v := Check(rule, series(start, 100, 100, 100, 300)); v != nil {
+		t.Errorf("Expected no violation, got %+v", v)
+	}
+}
@@ -0,0 +1,414 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/incident"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+const (
+	// MaxBacktestRange is the longest time range a backtest can replay
+	MaxBacktestRange = 31 * 24 * time.Hour
+	// MaxBacktestSamples caps the number of metrics a backtest loads
+	MaxBacktestSamples = 200000
+)
+
+// ErrBacktestTooLarge is returned when the time range holds more than MaxBacktestSamples metrics
+var ErrBacktestTooLarge = fmt.Errorf("time range contains more than %d metrics", MaxBacktestSamples)
+
+// BacktestRequest replays either a saved rule (rule_id) or an unsaved definition (rule)
+// over the metrics recorded between from and to
+type BacktestRequest struct {
+	RuleID *string            `json:"rule_id,omitempty"`
+	Rule   *CreateRuleRequest `json:"rule,omitempty"`
+	From   time.Time          `json:"from"`
+	To     time.Time          `json:"to"`
+}
+
+type BacktestResponse struct {
+	RuleID           string                  `json:"rule_id"`
+	From             time.Time               `json:"from"`
+	To               time.Time               `json:"to"`
+	EvaluatedSamples int                     `json:"evaluated_samples"`
+	Violations       int                     `json:"violations"`
+	WouldOpen        int                     `json:"would_open_incidents"`
+	ActualOpened     int                     `json:"actual_incidents"`
+	Services         []BacktestServiceResult `json:"services"`
+}
+
+// BacktestServiceResult is what a rule would have done for one service
+type BacktestServiceResult struct {
+	ServiceID        string             `json:"service_id"`
+	Violations       int                `json:"violations"`
+	WouldOpen        int                `json:"would_open_incidents"`
+	Throttled        int                `json:"throttled"`
+	Shadowed         int                `json:"shadowed"`
+	Silenced         int                `json:"silenced"`
+	Webhooks         int                `json:"webhooks"`
+	Notifications    int                `json:"notifications"`
+	EvaluationErrors int                `json:"evaluation_errors"`
+	FirstTriggeredAt *time.Time         `json:"first_triggered_at"`
+	LastTriggeredAt  *time.Time         `json:"last_triggered_at"`
+	Incidents        []BacktestIncident `json:"incidents"`
+	Actual           ActualIncidents    `json:"actual"`
+}
+
+// BacktestIncident is an incident the rule would have opened
+type BacktestIncident struct {
+	MetricID    uuid.UUID  `json:"metric_id"`
+	Severity    string     `json:"severity"`
+	Message     string     `json:"message"`
+	OpenedAt    time.Time  `json:"opened_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	Occurrences int        `json:"occurrence_count"`
+}
+
+// ActualIncidents summarises the incidents the saved rule opened in the time range
+type ActualIncidents struct {
+	IncidentCount int        `json:"incident_count"`
+	FirstOpenedAt *time.Time `json:"first_opened_at"`
+	LastOpenedAt  *time.Time `json:"last_opened_at"`
+}
+
+// ReplayEnv is what a replayed rule is evaluated alongside: the other active rules, which
+// may shadow it under FIRST_MATCH, the evaluation policies and the silences of the
+// replayed time range. The zero value replays the rule on its own.
+type ReplayEnv struct {
+	Rules    []db.QualityRule
+	Policies []db.MetricEvaluationPolicy
+	Silences []db.Silence
+}
+
+// backtestState mirrors what the worker keeps in the database for a rule and service:
+// the open incident, the last time one was opened and the last violation attached to it
+type backtestState struct {
+	result *BacktestServiceResult
+	// open is the index of the open incident in result.Incidents, or -1
+	open            int
+	lastOpenedAt    *time.Time
+	lastViolationAt time.Time
+	lastWebhookAt   *time.Time
+}
+
+// Replay evaluates a rule against metrics ordered by recorded_at, the way the worker
+// would have evaluated them as they arrived, without writing anything. The rule is
+// decided together with env like the worker decides it, so silences and FIRST_MATCH
+// shadowing apply; the rule itself is replayed as if it was published. Incidents are
+// deduplicated, throttled and auto-resolved like live ones, using the replayed
+// sample times. behavior is the behaviour of the rule's action. No incident is assumed to
+// be open when the replay starts, and anomaly baselines are learned from the replayed
+// metrics only.
+func Replay(ctx context.Context, rule *db.QualityRule, behavior db.ActionBehavior, env ReplayEnv, metrics []db.Metric, load HistoryLoader) ([]BacktestServiceResult, error) {
+	series := NewSeriesStore(load)
+	baselines := NewBaselineStore(nil, nil)
+	eval := newEvaluator(series, baselines)
+
+	target := CompileRule(*rule)
+	target.Status = db.RuleStatusPUBLISHED
+	others := make([]db.QualityRule, 0, len(env.Rules))
+	for _, r := range env.Rules {
+		if r.ID != rule.ID {
+			others = append(others, r)
+		}
+	}
+	byType := indexRules(compileRules(others))
+	policies := indexPolicies(env.Policies)
+	states := make(map[string]*backtestState)
+
+	for _, m := range metrics {
+		key := SeriesKey{ServiceID: m.ServiceID, MetricType: m.MetricType}
+		sample := Sample{
+			MetricID:   m.ID,
+			Value:      pgutil.NumericToFloat64(m.Value),
+			RecordedAt: m.RecordedAt,
+		}
+		// Metrics of other types read by expressions are observed but do not trigger the rule
+		samples, err := series.Observe(ctx, key, sample)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load series history: %w", err)
+		}
+		if !triggeredBy(rule, m.MetricType) {
+			continue
+		}
+
+		policy := policyOf(policies, m.MetricType)
+		rules := replayRules(target, inScope(byType[m.MetricType], m.ServiceID), policy)
+		decisions := decide(ctx, eval, rules, policy, m.ServiceID, samples, m.RecordedAt, matchSilences(env.Silences, m.RecordedAt))
+		if err := baselines.Learn(ctx, key, sample); err != nil {
+			return nil, err
+		}
+		d := decisions[len(decisions)-1]
+		if d.outcome == outcomeOutsideSchedule {
+			continue
+		}
+
+		state, ok := states[m.ServiceID]
+		if !ok {
+			state = &backtestState{
+				result: &BacktestServiceResult{ServiceID: m.ServiceID, Incidents: []BacktestIncident{}},
+				open:   -1,
+			}
+			states[m.ServiceID] = state
+		}
+		switch d.outcome {
+		case outcomeFailed:
+			state.result.EvaluationErrors++
+		case outcomeNotMatched:
+			if rule.AutoResolve {
+				state.recover(rule, samples, sample.RecordedAt)
+			}
+		case outcomeShadowed:
+			state.trigger(sample.RecordedAt)
+			state.result.Shadowed++
+		case outcomeSilenced:
+			state.trigger(sample.RecordedAt)
+			state.result.Silenced++
+		case outcomeViolated:
+			state.trigger(sample.RecordedAt)
+			state.violate(rule, behavior, sample, d.violation)
+		}
+	}
+
+	results := make([]BacktestServiceResult, 0, len(states))
+	for _, state := range states {
+		results = append(results, *state.result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ServiceID < results[j].ServiceID
+	})
+	return results, nil
+}
+
+// replayRules returns the rules a replayed rule is decided with. Under FIRST_MATCH the
+// rules ordered before it by priority and id may shadow it; otherwise it is decided alone.
+func replayRules(target *CompiledRule, others []*CompiledRule, policy db.EvaluationPolicy) []*CompiledRule {
+	if policy != db.EvaluationPolicyFIRSTMATCH {
+		return []*CompiledRule{target}
+	}
+	rules := make([]*CompiledRule, 0, len(others)+1)
+	for _, r := range others {
+		if r.Priority < target.Priority || (r.Priority == target.Priority && r.ID < target.ID) {
+			rules = append(rules, r)
+		}
+	}
+	return append(rules, target)
+}
+
+// trigger counts a violation of the rule, whether or not its action applies
+func (s *backtestState) trigger(at time.Time) {
+	s.result.Violations++
+	if s.result.FirstTriggeredAt == nil {
+		s.result.FirstTriggeredAt = &at
+	}
+	s.result.LastTriggeredAt = &at
+}
+
+// violate applies the behaviour of the rule's action to a violation, like the worker does
+func (s *backtestState) violate(rule *db.QualityRule, behavior db.ActionBehavior, sample Sample, violation *Violation) {
+	at := sample.RecordedAt
+	switch behavior {
+	case db.ActionBehaviorOPENINCIDENT:
+	case db.ActionBehaviorTHROTTLE:
+		cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+		if s.lastOpenedAt != nil && at.Sub(*s.lastOpenedAt) < cooldown {
+			s.result.Throttled++
+			return
+		}
+	case db.ActionBehaviorWEBHOOK:
+		if s.lastWebhookAt != nil && at.Sub(*s.lastWebhookAt) < webhookCooldown(rule) {
+			s.result.Throttled++
+			return
+		}
+		s.lastWebhookAt = &at
+		s.result.Webhooks++
+		return
+	case db.ActionBehaviorNOTIFYONLY:
+		s.result.Notifications++
+		return
+	default:
+		return
+	}
+
+	severity := severityOf(rule, violation)
+	s.lastViolationAt = at
+	if s.open >= 0 {
+		open := &s.result.Incidents[s.open]
+		open.Occurrences++
+		// Like the worker, a worse tier escalates the open incident
+		if incident.SeverityRank(severity) > incident.SeverityRank(db.IncidentSeverity(open.Severity)) {
+			open.Severity = string(severity)
+		}
+		return
+	}
+	s.result.Incidents = append(s.result.Incidents, BacktestIncident{
+		MetricID:    sample.MetricID,
+		Severity:    string(severity),
+		Message:     violation.Message,
+		OpenedAt:    at,
+		Occurrences: 1,
+	})
+	s.result.WouldOpen++
+	s.open = len(s.result.Incidents) - 1
+	s.lastOpenedAt = &at
+}
+
+// recover resolves the open incident of an auto-resolving rule once the rule has
+// recovered for recovery_samples samples since the last violation attached to it, like
+// the worker's trackRecovery
+func (s *backtestState) recover(rule *db.QualityRule, samples []Sample, at time.Time) {
+	if s.open < 0 {
+		return
+	}
+	if _, healthy := Recovered(rule, samples); !healthy {
+		return
+	}
+	if recoveryStreak(rule, samples, s.lastViolationAt) < rule.RecoverySamples {
+		return
+	}
+	s.result.Incidents[s.open].ResolvedAt = &at
+	s.open = -1
+}
+
+// triggeredBy reports whether a metric of the given type triggers evaluation of the
+// rule, matching ListActiveForService
+func triggeredBy(rule *db.QualityRule, metricType db.MetricType) bool {
+	return rule.MetricType == metricType || slices.Contains(rule.MetricTypes, string(metricType))
+}
+
+// replayMetricTypes returns the metric types a backtest needs: those that trigger the
+// rule and those its expression reads
+func replayMetricTypes(rule *db.QualityRule) ([]string, error) {
+	types := []string{string(rule.MetricType)}
+	for _, mt := range rule.MetricTypes {
+		if !slices.Contains(types, mt) {
+			types = append(types, mt)
+		}
+	}
+	if rule.Expression != nil {
+		expr, err := CompileExpression(*rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression: %w", err)
+		}
+		for _, mt := range expr.MetricTypes() {
+			if !slices.Contains(types, string(mt)) {
+				types = append(types, string(mt))
+			}
+		}
+	}
+	return types, nil
+}
+
+// runBacktest replays a rule over a time range and compares the result with the
+// incidents the saved rule compareID opened in that range. compareID may be empty
+// for a rule that was never saved.
+func runBacktest(ctx context.Context, repo *Repository, rule *db.QualityRule, behavior db.ActionBehavior, compareID string, from, to time.Time) (*BacktestResponse, error) {
+	metricTypes, err := replayMetricTypes(rule)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := repo.ListBacktestMetrics(ctx, metricTypes, rule.ServiceIds, from, to, MaxBacktestSamples+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) > MaxBacktestSamples {
+		return nil, ErrBacktestTooLarge
+	}
+
+	env, err := replayEnv(ctx, repo, from, to)
+	if err != nil {
+		return nil, err
+	}
+	services, err := Replay(ctx, rule, behavior, env, metrics, repo.LoadSeriesHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	var actual []db.SummarizeRuleIncidentsByServiceRow
+	if compareID != "" {
+		if actual, err = repo.SummarizeIncidents(ctx, compareID, from, to); err != nil {
+			return nil, err
+		}
+	}
+	services = mergeActualIncidents(services, actual)
+
+	resp := &BacktestResponse{
+		RuleID:           compareID,
+		From:             from,
+		To:               to,
+		EvaluatedSamples: len(metrics),
+		Services:         services,
+	}
+	for _, s := range services {
+		resp.Violations += s.Violations
+		resp.WouldOpen += s.WouldOpen
+		resp.ActualOpened += s.Actual.IncidentCount
+	}
+	return resp, nil
+}
+
+// replayEnv loads the active rules, evaluation policies and silences a backtest over
+// from and to is decided with
+func replayEnv(ctx context.Context, repo *Repository, from, to time.Time) (ReplayEnv, error) {
+	rules, err := repo.ListActive(ctx)
+	if err != nil {
+		return ReplayEnv{}, err
+	}
+	policies, err := repo.ListEvaluationPolicies(ctx)
+	if err != nil {
+		return ReplayEnv{}, err
+	}
+	silences, err := repo.ListSilencesBetween(ctx, from, to)
+	if err != nil {
+		return ReplayEnv{}, err
+	}
+	return ReplayEnv{Rules: rules, Policies: policies, Silences: silences}, nil
+}
+
+// mergeActualIncidents adds the actual incident counts to the replay results. Services
+// that had incidents but no replayed violations are included so nothing is hidden.
+func mergeActualIncidents(services []BacktestServiceResult, actual []db.SummarizeRuleIncidentsByServiceRow) []BacktestServiceResult {
+	for _, row := range actual {
+		summary := ActualIncidents{
+			IncidentCount: int(row.IncidentCount),
+			FirstOpenedAt: &row.FirstOpenedAt,
+			LastOpenedAt:  &row.LastOpenedAt,
+		}
+		i := slices.IndexFunc(services, func(s BacktestServiceResult) bool { return s.ServiceID == row.ServiceID })
+		if i >= 0 {
+			services[i].Actual = summary
+			continue
+		}
+		services = append(services, BacktestServiceResult{
+			ServiceID: row.ServiceID,
+			Incidents: []BacktestIncident{},
+			Actual:    summary,
+		})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ServiceID < services[j].ServiceID
+	})
+	return services
+}
+
+// validateBacktest checks the time range and rule selection of a backtest request
+func validateBacktest(req *BacktestRequest) error {
+	switch {
+	case req.From.IsZero() || req.To.IsZero():
+		return errors.New("from and to are required")
+	case !req.To.After(req.From):
+		return errors.New("to must be after from")
+	case req.To.Sub(req.From) > MaxBacktestRange:
+		return fmt.Errorf("time range must not exceed %d days", int(MaxBacktestRange.Hours()/24))
+	case (req.RuleID == nil) == (req.Rule == nil):
+		return errors.New("exactly one of rule_id and rule is required")
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func backtestMetrics(serviceID string, start time.Time, values ...float64) []db.Metric {
+	metrics := make([]db.Metric, len(values))
+	for i, v := range values {
+		metrics[i] = db.Metric{
+			ID:         uuid.New(),
+			ServiceID:  serviceID,
+			MetricType: db.MetricTypeLATENCYMS,
+			Value:      pgutil.Float64ToNumeric(v),
+			RecordedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+	}
+	return metrics
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	base := db.QualityRule{
+		ID:              "latency",
+		MetricType:      db.MetricTypeLATENCYMS,
+		MetricTypes:     []string{string(db.MetricTypeLATENCYMS)},
+		Operator:        db.RuleOperatorValue0,
+		Threshold:       pgutil.Float64ToNumeric(150),
+		Action:          ActionOpenIncident,
+		RecoverySamples: 1,
+	}
+	values := []float64{100, 200, 210, 100, 220, 230}
+
+	tests := []struct {
+		name          string
+		modify        func(r *db.QualityRule)
+		violations    int
+		wouldOpen     int
+		throttled     int
+		webhooks      int
+		notifications int
+	}{
+		{
+			name:       "deduplicates while open",
+			violations: 4,
+			wouldOpen:  1,
+		},
+		{
+			name:       "auto-resolve reopens",
+			modify:     func(r *db.QualityRule) { r.AutoResolve = true },
+			violations: 4,
+			wouldOpen:  2,
+		},
+		{
+			name: "throttled within cooldown",
+			modify: func(r *db.QualityRule) {
+				r.AutoResolve = true
+				r.Action = ActionThrottle
+				r.CooldownSeconds = 600
+			},
+			// Like the worker, the cooldown applies even while the incident is open
+			violations: 4,
+			wouldOpen:  1,
+			throttled:  3,
+		},
+		{
+			name: "webhooks do not open incidents",
+			modify: func(r *db.QualityRule) {
+				r.Action = ActionWebhook
+			},
+			// The default cooldown lets one delivery through
+			violations: 4,
+			webhooks:   1,
+			throttled:  3,
+		},
+		{
+			name: "webhooks within cooldown",
+			modify: func(r *db.QualityRule) {
+				r.Action = ActionWebhook
+				r.CooldownSeconds = 120
+			},
+			violations: 4,
+			webhooks:   2,
+			throttled:  2,
+		},
+		{
+			name: "notifications do not open incidents",
+			modify: func(r *db.QualityRule) {
+				r.Action = "STREAMING_WARNING"
+			},
+			violations:    4,
+			notifications: 4,
+		},
+		{
+			name:       "outside schedule",
+			modify:     func(r *db.QualityRule) { r.Schedule = []byte(`{"cron": "0-2 * * * *"}`) },
+			violations: 2,
+			wouldOpen:  1,
+		},
+		{
+			name:       "lower threshold",
+			modify:     func(r *db.QualityRule) { r.Threshold = pgutil.Float64ToNumeric(50) },
+			violations: 6,
+			wouldOpen:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := base
+			if tt.modify != nil {
+				tt.modify(&rule)
+			}
+
+			behavior, err := testActions().behavior(rule.Action)
+			if err != nil {
+				t.Fatalf("behavior() error = %v", err)
+			}
+			results, err := Replay(context.Background(), &rule, behavior, ReplayEnv{}, backtestMetrics("S1", start, values...), nil)
+			if err != nil {
+				t.Fatalf("Replay failed: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 service, got %d", len(results))
+			}
+			got := results[0]
+			if got.Violations != tt.violations || got.WouldOpen != tt.wouldOpen || got.Throttled != tt.throttled || got.Webhooks != tt.webhooks || got.Notifications != tt.notifications {
+				t.Errorf("Expected violations=%d would_open=%d throttled=%d webhooks=%d notifications=%d, got %d/%d/%d/%d/%d",
+					tt.violations, tt.wouldOpen, tt.throttled, tt.webhooks, tt.notifications,
+					got.Violations, got.WouldOpen, got.Throttled, got.Webhooks, got.Notifications)
+			}
+			if len(got.Incidents) != tt.wouldOpen {
+				t.Errorf("Expected %d incidents, got %d", tt.wouldOpen, len(got.Incidents))
+			}
+		})
+	}
+}
+
+func TestReplay_TriggerTimesAndResolution(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	rule := &db.QualityRule{
+		MetricType:      db.MetricTypeLATENCYMS,
+		MetricTypes:     []string{string(db.MetricTypeLATENCYMS)},
+		Operator:        db.RuleOperatorValue0,
+		Threshold:       pgutil.Float64ToNumeric(150),
+		Action:          ActionOpenIncident,
+		AutoResolve:     true,
+		RecoverySamples: 1,
+	}
+	metrics := append(backtestMetrics("S1", start, 100, 200, 210, 100), backtestMetrics("S2", start, 100)...)
+
+	results, err := Replay(context.Background(), rule, db.ActionBehaviorOPENINCIDENT, ReplayEnv{}, metrics, nil)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ServiceID != "S1" || results[1].ServiceID != "S2" {
+		t.Fatalf("Expected results for S1 and S2, got %+v", results)
+	}
+
+	s1 := results[0]
+	if !s1.FirstTriggeredAt.Equal(start.Add(time.Minute)) || !s1.LastTriggeredAt.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("Unexpected trigger times: first %v, last %v", s1.FirstTriggeredAt, s1.LastTriggeredAt)
+	}
+	inc := s1.Incidents[0]
+	if inc.Occurrences != 2 {
+		t.Errorf("Expected 2 occurrences, got %d", inc.Occurrences)
+	}
+	if inc.ResolvedAt == nil || !inc.ResolvedAt.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("Expected incident resolved at %v, got %v", start.Add(3*time.Minute), inc.ResolvedAt)
+	}
+
+	if results[1].Violations != 0 || results[1].FirstTriggeredAt != nil {
+		t.Errorf("Expected no violations for S2, got %+v", results[1])
+	}
+}
+
+func TestValidateBacktest(t *testing.T) {
+	from := time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)
+	ruleID := "latency"
+
+	tests := []struct {
+		name    string
+		req     BacktestRequest
+		wantErr bool
+	}{
+		{"saved rule", BacktestRequest{RuleID: &ruleID, From: from, To: from.Add(time.Hour)}, false},
+		{"unsaved rule", BacktestRequest{Rule: &CreateRuleRequest{}, From: from, To: from.Add(time.Hour)}, false},
+		{"missing range", BacktestRequest{RuleID: &ruleID}, true},
+		{"reversed range", BacktestRequest{RuleID: &ruleID, From: from, To: from.Add(-time.Hour)}, true},
+		{"range too long", BacktestRequest{RuleID: &ruleID, From: from, To: from.Add(MaxBacktestRange + time.Hour)}, true},
+		{"no rule", BacktestRequest{From: from, To: from.Add(time.Hour)}, true},
+		{"both rules", BacktestRequest{RuleID: &ruleID, Rule: &CreateRuleRequest{}, From: from, To: from.Add(time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBacktest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRuleHandler_Backtest(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:         "latency",
+		MetricType: db.MetricTypeLATENCYMS,
+		Threshold:  300,
+		Operator:   db.RuleOperatorValue0,
+		IsActive:   true,
+	})
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, v := range []float64{100, 200, 250, 100, 180} {
+		testutil.TestMetric(t, q, testutil.TestMetricParams{
+			ServiceID:  "S1",
+			MetricType: db.MetricTypeLATENCYMS,
+			Value:      v,
+			RecordedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	testutil.TestIncident(t, q, testutil.TestIncidentParams{
+		ServiceID: "S1",
+		RuleID:    "latency",
+		OpenedAt:  start.Add(2 * time.Minute),
+	})
+
+	// Lowering the threshold of the saved rule from 300 to 150
+	body, _ := json.Marshal(map[string]any{
+		"rule": map[string]any{
+			"id":           "latency",
+			"metric_type":  "LATENCY_MS",
+			"threshold":    150,
+			"operator":     ">",
+			"action":       "OPEN_INCIDENT",
+			"severity":     "HIGH",
+			"auto_resolve": true,
+		},
+		"from": start.Add(-time.Minute),
+		"to":   start.Add(10 * time.Minute),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/rules/backtest", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.Backtest(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data BacktestResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	got := response.Data
+	if got.EvaluatedSamples != 5 || got.Violations != 3 || got.WouldOpen != 2 || got.ActualOpened != 1 {
+		t.Errorf("Expected 5 samples, 3 violations, 2 would-be and 1 actual incident, got %+v", got)
+	}
+	if len(got.Services) != 1 || got.Services[0].Actual.IncidentCount != 1 {
+		t.Errorf("Expected S1 with 1 actual incident, got %+v", got.Services)
+	}
+}
+
+func TestRuleHandler_Backtest_NotFound(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body, _ := json.Marshal(map[string]any{
+		"rule_id": "missing",
+		"from":    time.Now().Add(-time.Hour),
+		"to":      time.Now(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/rules/backtest", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.Backtest(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+}
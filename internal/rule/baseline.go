@@ -0,0 +1,256 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+const (
+	// BaselineAlpha is the EWMA smoothing factor of baselines; each sample moves the
+	// mean and variance 5% of the way towards it
+	BaselineAlpha = 0.05
+	// BaselineWarmup is the number of samples a baseline must have seen before anomaly
+	// rules use it
+	BaselineWarmup = 20
+	// OverallBucket is the baseline that learns from every sample of a series
+	OverallBucket int16 = -1
+	// MaxAnomalyDeviations is the largest anomaly_deviations a rule can use
+	MaxAnomalyDeviations = 100
+	// minDeviationRatio keeps nearly flat series from firing on tiny changes: the
+	// deviation is at least this fraction of the mean
+	minDeviationRatio = 0.01
+)
+
+// Baseline is the learned mean and variance of a series, overall or for one hour of the week
+type Baseline struct {
+	Bucket         int16
+	Mean           float64
+	Variance       float64
+	Count          int64
+	LastRecordedAt time.Time
+}
+
+// Deviation returns the standard deviation of the baseline
+func (b Baseline) Deviation() float64 {
+	return math.Sqrt(b.Variance)
+}
+
+// Warm reports whether the baseline has seen enough samples to be used
+func (b Baseline) Warm() bool {
+	return b.Count >= BaselineWarmup
+}
+
+// Learn returns the baseline updated with a sample using an exponentially weighted
+// moving mean and variance. Samples that are not newer than the last one learned are
+// ignored so a replayed sample is not counted twice.
+func (b Baseline) Learn(value float64, at time.Time) Baseline {
+	if b.Count == 0 {
+		return Baseline{Bucket: b.Bucket, Mean: value, Count: 1, LastRecordedAt: at}
+	}
+	if !at.After(b.LastRecordedAt) {
+		return b
+	}
+	diff := value - b.Mean
+	incr := BaselineAlpha * diff
+	return Baseline{
+		Bucket:         b.Bucket,
+		Mean:           b.Mean + incr,
+		Variance:       (1 - BaselineAlpha) * (b.Variance + diff*incr),
+		Count:          b.Count + 1,
+		LastRecordedAt: at,
+	}
+}
+
+// HourOfWeek returns the hour-of-week bucket of a time: 0 for Sunday 00:00-01:00 UTC
+// through 167 for Saturday 23:00-24:00 UTC
+func HourOfWeek(t time.Time) int16 {
+	t = t.UTC()
+	return int16(int(t.Weekday())*24 + t.Hour())
+}
+
+// BaselineLoader loads the stored baselines of a series
+type BaselineLoader func(ctx context.Context, key SeriesKey) ([]Baseline, error)
+
+// BaselineSaver persists updated baselines of a series
+type BaselineSaver func(ctx context.Context, key SeriesKey, baselines []Baseline) error
+
+// BaselineStore keeps the baselines of every series the worker has seen. Baselines are
+// loaded the first time a series is seen and saved after every update, so the worker
+// can restart without relearning.
+type BaselineStore struct {
+	mu        sync.Mutex
+	baselines map[SeriesKey]map[int16]Baseline
+	load      BaselineLoader
+	save      BaselineSaver
+}
+
+// NewBaselineStore creates a store. load and save may be nil, in which case baselines
+// start empty and are only kept in memory.
+func NewBaselineStore(load BaselineLoader, save BaselineSaver) *BaselineStore {
+	return &BaselineStore{
+		baselines: make(map[SeriesKey]map[int16]Baseline),
+		load:      load,
+		save:      save,
+	}
+}
+
+// series returns the baselines of a series, loading them on first sight. s.mu must be held.
+func (s *BaselineStore) series(ctx context.Context, key SeriesKey) (map[int16]Baseline, error) {
+	buckets, ok := s.baselines[key]
+	if ok {
+		return buckets, nil
+	}
+	buckets = make(map[int16]Baseline)
+	if s.load != nil {
+		stored, err := s.load(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range stored {
+			buckets[b.Bucket] = b
+		}
+	}
+	s.baselines[key] = buckets
+	return buckets, nil
+}
+
+// Get returns the baseline of a series bucket
+func (s *BaselineStore) Get(ctx context.Context, key SeriesKey, bucket int16) (Baseline, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets, err := s.series(ctx, key)
+	if err != nil {
+		return Baseline{}, false, err
+	}
+	b, ok := buckets[bucket]
+	return b, ok, nil
+}
+
+// List returns the overall and hour-of-week baselines of a series
+func (s *BaselineStore) List(ctx context.Context, key SeriesKey) ([]Baseline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets, err := s.series(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Baseline, 0, len(buckets))
+	for bucket := OverallBucket; bucket < 168; bucket++ {
+		if b, ok := buckets[bucket]; ok {
+			result = append(result, b)
+		}
+	}
+	return result, nil
+}
+
+// Learn updates the overall and hour-of-week baselines of a series with a sample
+func (s *BaselineStore) Learn(ctx context.Context, key SeriesKey, sample Sample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets, err := s.series(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	updated := make([]Baseline, 0, 2)
+	for _, bucket := range []int16{OverallBucket, HourOfWeek(sample.RecordedAt)} {
+		prev, ok := buckets[bucket]
+		if !ok {
+			prev = Baseline{Bucket: bucket}
+		}
+		next := prev.Learn(sample.Value, sample.RecordedAt)
+		if next == prev {
+			continue
+		}
+		buckets[bucket] = next
+		updated = append(updated, next)
+	}
+
+	if s.save == nil || len(updated) == 0 {
+		return nil
+	}
+	return s.save(ctx, key, updated)
+}
+
+// Forget drops the baselines of a service; they are loaded again when next used
+func (s *BaselineStore) Forget(serviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.baselines {
+		if key.ServiceID == serviceID {
+			delete(s.baselines, key)
+		}
+	}
+}
+
+// anomalyBaseline returns the baseline an anomaly rule compares a sample against.
+// Seasonal rules use the sample's hour-of-week baseline once it is warm and fall back
+// to the overall baseline until then.
+func anomalyBaseline(ctx context.Context, store *BaselineStore, rule *db.QualityRule, key SeriesKey, at time.Time) (Baseline, bool, error) {
+	if rule.AnomalySeasonal {
+		b, ok, err := store.Get(ctx, key, HourOfWeek(at))
+		if err != nil || (ok && b.Warm()) {
+			return b, ok, err
+		}
+	}
+	return store.Get(ctx, key, OverallBucket)
+}
+
+// CheckAnomaly checks the latest sample of a series against its baseline. It returns
+// nil while the baseline is still warming up.
+func CheckAnomaly(rule *db.QualityRule, baseline Baseline, samples []Sample) *Violation {
+	if len(samples) == 0 || !baseline.Warm() {
+		return nil
+	}
+	current := samples[len(samples)-1]
+	deviations := pgutil.NumericToFloat64(rule.AnomalyDeviations)
+
+	deviation := math.Max(baseline.Deviation(), math.Abs(baseline.Mean)*minDeviationRatio)
+	if deviation == 0 {
+		// A constant zero series: any other value is an anomaly
+		deviation = math.SmallestNonzeroFloat64
+	}
+	score := (current.Value - baseline.Mean) / deviation
+
+	var fired bool
+	switch rule.AnomalyDirection {
+	case db.AnomalyDirectionABOVE:
+		fired = score > deviations
+	case db.AnomalyDirectionBELOW:
+		fired = -score > deviations
+	default:
+		fired = math.Abs(score) > deviations
+	}
+	if !fired {
+		return nil
+	}
+
+	direction := "above"
+	if score < 0 {
+		direction = "below"
+	}
+	return &Violation{
+		Value: current.Value,
+		Message: fmt.Sprintf("%s anomaly: %.2f is %.1f deviations %s baseline %.2f ± %.2f (%s)",
+			rule.MetricType, current.Value, math.Abs(score), direction, baseline.Mean, baseline.Deviation(), bucketName(baseline.Bucket)),
+	}
+}
+
+// bucketName describes a baseline bucket, e.g. "Mon 09:00 UTC"
+func bucketName(bucket int16) string {
+	if bucket == OverallBucket {
+		return "overall"
+	}
+	day := time.Weekday(bucket / 24).String()[:3]
+	return fmt.Sprintf("%s %02d:00 UTC", day, bucket%24)
+}
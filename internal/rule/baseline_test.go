@@ -0,0 +1,159 @@
+package rule
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func learned(values ...float64) Baseline {
+	b := Baseline{Bucket: OverallBucket}
+	at := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	for i, v := range values {
+		b = b.Learn(v, at.Add(time.Duration(i)*time.Minute))
+	}
+	return b
+}
+
+func repeat(v float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+func TestBaseline_Learn(t *testing.T) {
+	b := learned(10)
+	if b.Mean != 10 || b.Variance != 0 || b.Count != 1 {
+		t.Fatalf("Expected the first sample to seed the baseline, got %+v", b)
+	}
+
+	b = learned(10, 20)
+	if math.Abs(b.Mean-10.5) > 1e-9 {
+		t.Errorf("Expected mean 10.5, got %v", b.Mean)
+	}
+	if math.Abs(b.Variance-4.75) > 1e-9 {
+		t.Errorf("Expected variance 4.75, got %v", b.Variance)
+	}
+
+	// A sample that is not newer than the last one is not learned again
+	if again := b.Learn(50, b.LastRecordedAt); again != b {
+		t.Errorf("Expected a replayed sample to be ignored, got %+v", again)
+	}
+}
+
+func TestHourOfWeek(t *testing.T) {
+	tests := []struct {
+		at       time.Time
+		expected int16
+		label    string
+	}{
+		{time.Date(2026, 3, 15, 0, 30, 0, 0, time.UTC), 0, "Sun 00:00 UTC"},
+		{time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC), 33, "Mon 09:00 UTC"},
+		{time.Date(2026, 3, 21, 23, 59, 0, 0, time.UTC), 167, "Sat 23:00 UTC"},
+		// Times are bucketed in UTC
+		{time.Date(2026, 3, 16, 12, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), 33, "Mon 09:00 UTC"},
+	}
+
+	for _, tt := range tests {
+		got := HourOfWeek(tt.at)
+		if got != tt.expected {
+			t.Errorf("HourOfWeek(%v) = %d, expected %d", tt.at, got, tt.expected)
+		}
+		if label := bucketName(got); label != tt.label {
+			t.Errorf("bucketName(%d) = %q, expected %q", got, label, tt.label)
+		}
+	}
+}
+
+func TestCheckAnomaly(t *testing.T) {
+	// Alternating 90/110 settles around 100 with a deviation of about 10
+	values := make([]float64, 100)
+	for i := range values {
+		values[i] = 90 + float64(i%2)*20
+	}
+	warm := learned(values...)
+
+	tests := []struct {
+		name      string
+		baseline  Baseline
+		direction db.AnomalyDirection
+		value     float64
+		fired     bool
+	}{
+		{"within baseline", warm, db.AnomalyDirectionBOTH, 115, false},
+		{"far above", warm, db.AnomalyDirectionBOTH, 140, true},
+		{"far below", warm, db.AnomalyDirectionBOTH, 60, true},
+		{"below but only above fires", warm, db.AnomalyDirectionABOVE, 60, false},
+		{"above but only below fires", warm, db.AnomalyDirectionBELOW, 140, false},
+		{"warming up", learned(values[:BaselineWarmup-1]...), db.AnomalyDirectionBOTH, 1000, false},
+		// The deviation of a flat series is at least 1% of its mean
+		{"flat series small change", learned(repeat(100, 30)...), db.AnomalyDirectionBOTH, 102, false},
+		{"flat series large change", learned(repeat(100, 30)...), db.AnomalyDirectionBOTH, 110, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &db.QualityRule{
+				MetricType:        db.MetricTypeERRORRATE,
+				AnomalyDeviations: pgutil.Float64ToNumeric(3),
+				AnomalyDirection:  tt.direction,
+			}
+			samples := []Sample{{MetricID: uuid.New(), Value: tt.value, RecordedAt: time.Now()}}
+			v := CheckAnomaly(rule, tt.baseline, samples)
+			if (v != nil) != tt.fired {
+				t.Fatalf("Expected fired=%v, got %+v", tt.fired, v)
+			}
+			if v != nil && !strings.Contains(v.Message, "ERROR_RATE anomaly") {
+				t.Errorf("Unexpected message: %s", v.Message)
+			}
+		})
+	}
+}
+
+func TestBaselineStore(t *testing.T) {
+	ctx := context.Background()
+	key := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeERRORRATE}
+	monday9 := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+
+	// The overall baseline is stored, the hour-of-week one is not
+	stored := learned(repeat(5, 30)...)
+	var saved []Baseline
+	store := NewBaselineStore(
+		func(ctx context.Context, k SeriesKey) ([]Baseline, error) {
+			return []Baseline{stored}, nil
+		},
+		func(ctx context.Context, k SeriesKey, baselines []Baseline) error {
+			saved = append(saved, baselines...)
+			return nil
+		},
+	)
+
+	rule := &db.QualityRule{AnomalySeasonal: true}
+	b, ok, err := anomalyBaseline(ctx, store, rule, key, monday9)
+	if err != nil || !ok || b.Bucket != OverallBucket {
+		t.Fatalf("Expected seasonal rules to fall back to the loaded overall baseline, got %+v %v %v", b, ok, err)
+	}
+
+	if err := store.Learn(ctx, key, Sample{Value: 50, RecordedAt: monday9}); err != nil {
+		t.Fatalf("Learn failed: %v", err)
+	}
+	if len(saved) != 2 || saved[0].Bucket != OverallBucket || saved[1].Bucket != 33 {
+		t.Fatalf("Expected the overall and Mon 09:00 baselines to be saved, got %+v", saved)
+	}
+	if saved[0].Count != stored.Count+1 {
+		t.Errorf("Expected the stored baseline to be continued, got count %d", saved[0].Count)
+	}
+
+	list, err := store.List(ctx, key)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected 2 baselines, got %d (%v)", len(list), err)
+	}
+}
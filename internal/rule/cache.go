@@ -0,0 +1,270 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// RuleChangedChannel is the Postgres notification channel triggers on quality_rules,
+// metric_evaluation_policies and rule_actions notify when a transaction changing them commits
+const RuleChangedChannel = "quality_rules_changed"
+
+const (
+	// ruleCacheRefreshInterval is how often the cache reloads in case a notification was missed
+	ruleCacheRefreshInterval = time.Minute
+	// ruleCacheReconnectDelay is how long the listener waits before reconnecting
+	ruleCacheReconnectDelay = 5 * time.Second
+)
+
+// CacheStats counts the lookups and reloads of a RuleCache
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Refreshes     uint64 `json:"refreshes"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// ruleStore is what a RuleCache loads rules from and listens to; Repository implements it
+type ruleStore interface {
+	ListActive(ctx context.Context) ([]db.QualityRule, error)
+	ListActiveForService(ctx context.Context, metricType db.MetricType, serviceID string) ([]db.QualityRule, error)
+	ListEvaluationPolicies(ctx context.Context) ([]db.MetricEvaluationPolicy, error)
+	EvaluationPolicy(ctx context.Context, metricType db.MetricType) (db.EvaluationPolicy, error)
+	ListActions(ctx context.Context) ([]db.RuleAction, error)
+	GetAction(ctx context.Context, id string) (*db.RuleAction, error)
+	ListenRuleChanges(ctx context.Context) (*pgx.Conn, error)
+}
+
+// RuleCache keeps the active rules in memory, compiled and indexed by metric type,
+// together with the evaluation policies and actions, so the worker does not query or
+// decode them for every metric. The cache is only served while it listens for
+// quality_rules changes: without a listener it may have missed a change made by another
+// instance, so every lookup reads the database.
+type RuleCache struct {
+	repo ruleStore
+
+	mu        sync.Mutex
+	listening bool
+	loaded    bool
+	byType    map[db.MetricType][]*CompiledRule
+	policies  map[db.MetricType]db.EvaluationPolicy
+	actions   Actions
+	// generation changes on every invalidation so a load that raced with a change is
+	// not stored
+	generation uint64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	refreshes     atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func NewRuleCache(repo *Repository) *RuleCache {
+	return &RuleCache{repo: repo}
+}
+
+// ListActiveForService returns the same rules as Repository.ListActiveForService, compiled
+func (c *RuleCache) ListActiveForService(ctx context.Context, metricType db.MetricType, serviceID string) ([]*CompiledRule, error) {
+	c.mu.Lock()
+	listening, loaded, generation := c.listening, c.loaded, c.generation
+	byType := c.byType
+	c.mu.Unlock()
+
+	if listening && loaded {
+		c.hits.Add(1)
+		return inScope(byType[metricType], serviceID), nil
+	}
+	c.misses.Add(1)
+	if !listening {
+		rules, err := c.repo.ListActiveForService(ctx, metricType, serviceID)
+		if err != nil {
+			return nil, err
+		}
+		return compileRules(rules), nil
+	}
+
+	byType, err := c.load(ctx, generation)
+	if err != nil {
+		return nil, err
+	}
+	return inScope(byType[metricType], serviceID), nil
+}
+
+// EvaluationPolicy returns the same policy as Repository.EvaluationPolicy. It is served
+// from the rules loaded by ListActiveForService and not counted in the stats.
+func (c *RuleCache) EvaluationPolicy(ctx context.Context, metricType db.MetricType) (db.EvaluationPolicy, error) {
+	c.mu.Lock()
+	listening, loaded, policies := c.listening, c.loaded, c.policies
+	c.mu.Unlock()
+
+	if listening && loaded {
+		return policyOf(policies, metricType), nil
+	}
+	return c.repo.EvaluationPolicy(ctx, metricType)
+}
+
+// Action returns the same action as Repository.GetAction, served like EvaluationPolicy
+func (c *RuleCache) Action(ctx context.Context, id string) (*db.RuleAction, error) {
+	c.mu.Lock()
+	listening, loaded, actions := c.listening, c.loaded, c.actions
+	c.mu.Unlock()
+
+	if listening && loaded {
+		if a, ok := actions[id]; ok {
+			return &a, nil
+		}
+	}
+	return c.repo.GetAction(ctx, id)
+}
+
+// Stats returns the lookup and reload counts of the cache
+func (c *RuleCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Refreshes:     c.refreshes.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+}
+
+// load reads every active rule, the evaluation policies and the actions and stores them
+// unless the cache was invalidated since generation
+func (c *RuleCache) load(ctx context.Context, generation uint64) (map[db.MetricType][]*CompiledRule, error) {
+	rules, err := c.repo.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := c.repo.ListEvaluationPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := c.repo.ListActions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byType := indexRules(compileRules(rules))
+	c.refreshes.Add(1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.listening && c.generation == generation {
+		c.byType = byType
+		c.policies = indexPolicies(policies)
+		c.actions = indexActions(actions)
+		c.loaded = true
+	}
+	return byType, nil
+}
+
+// invalidate drops the cached rules after a change; the next lookup reloads them
+func (c *RuleCache) invalidate() {
+	c.drop()
+	c.invalidations.Add(1)
+}
+
+// drop clears the cached rules and returns the new generation
+func (c *RuleCache) drop() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.loaded = false
+	c.byType = nil
+	c.policies = nil
+	c.actions = nil
+	return c.generation
+}
+
+func (c *RuleCache) setListening(listening bool) {
+	c.mu.Lock()
+	c.listening = listening
+	c.mu.Unlock()
+}
+
+// Listen keeps the cache in sync with quality_rules until ctx is done. Every change
+// notification invalidates the cache and it is reloaded every ruleCacheRefreshInterval
+// in case a notification was missed. While the listener is disconnected the cache is
+// bypassed.
+func (c *RuleCache) Listen(ctx context.Context) {
+	for {
+		err := c.listen(ctx)
+		c.setListening(false)
+		c.drop()
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("RuleCache: listener disconnected, reading rules from the database", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ruleCacheReconnectDelay):
+		}
+	}
+}
+
+func (c *RuleCache) listen(ctx context.Context) error {
+	conn, err := c.repo.ListenRuleChanges(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	// Changes committed before LISTEN were not notified
+	c.drop()
+	c.setListening(true)
+	slog.Info("RuleCache: listening for rule changes")
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, ruleCacheRefreshInterval)
+		_, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			c.invalidate()
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			if _, err := c.load(ctx, c.drop()); err != nil {
+				slog.Error("RuleCache: failed to refresh rules", "error", err)
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// indexRules groups active rules by the metric types they are evaluated for, keeping
+// their order. Composite rules are listed under every metric type they depend on.
+func indexRules(rules []*CompiledRule) map[db.MetricType][]*CompiledRule {
+	byType := make(map[db.MetricType][]*CompiledRule)
+	for _, rule := range rules {
+		byType[rule.MetricType] = append(byType[rule.MetricType], rule)
+		for _, mt := range rule.MetricTypes {
+			if db.MetricType(mt) != rule.MetricType {
+				byType[db.MetricType(mt)] = append(byType[db.MetricType(mt)], rule)
+			}
+		}
+	}
+	return byType
+}
+
+// inScope returns the rules whose scope includes the service; rules without services
+// apply to every service
+func inScope(rules []*CompiledRule, serviceID string) []*CompiledRule {
+	scoped := make([]*CompiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.ServiceIds) == 0 || slices.Contains(rule.ServiceIds, serviceID) {
+			scoped = append(scoped, rule)
+		}
+	}
+	return scoped
+}
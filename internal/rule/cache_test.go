@@ -0,0 +1,322 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+)
+
+func ruleIDs(rules []*CompiledRule) []string {
+	ids := make([]string, len(rules))
+	for i, r := range rules {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestIndexRules(t *testing.T) {
+	rules := []db.QualityRule{
+		{ID: "latency", MetricType: db.MetricTypeLATENCYMS},
+		{ID: "composite", MetricType: db.MetricTypeLATENCYMS, MetricTypes: []string{"LATENCY_MS", "PACKET_LOSS"}},
+		{ID: "scoped", MetricType: db.MetricTypeLATENCYMS, ServiceIds: []string{"S2"}},
+		{ID: "loss", MetricType: db.MetricTypePACKETLOSS},
+	}
+	byType := indexRules(compileRules(rules))
+
+	tests := []struct {
+		metricType db.MetricType
+		serviceID  string
+		want       []string
+	}{
+		{db.MetricTypeLATENCYMS, "S1", []string{"latency", "composite"}},
+		{db.MetricTypeLATENCYMS, "S2", []string{"latency", "composite", "scoped"}},
+		{db.MetricTypePACKETLOSS, "S1", []string{"composite", "loss"}},
+		{db.MetricTypeERRORRATE, "S1", []string{}},
+	}
+	for _, tt := range tests {
+		got := ruleIDs(inScope(byType[tt.metricType], tt.serviceID))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s/%s = %v, want %v", tt.metricType, tt.serviceID, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s/%s = %v, want %v", tt.metricType, tt.serviceID, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestWorker_ServeCacheStats(t *testing.T) {
+	w := &Worker{rules: NewRuleCache(nil)}
+	w.rules.hits.Add(3)
+	w.rules.invalidations.Add(1)
+
+	rr := httptest.NewRecorder()
+	w.ServeCacheStats(rr, httptest.NewRequest(http.MethodGet, "/api/rules/stats/cache", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var resp struct {
+		Data CacheStats `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if resp.Data != (CacheStats{Hits: 3, Invalidations: 1}) {
+		t.Errorf("Unexpected cache stats %+v", resp.Data)
+	}
+}
+
+func TestRuleCache(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	cache := NewRuleCache(handler.repo)
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "latency", Threshold: 150, IsActive: true})
+
+	lookup := func() []string {
+		t.Helper()
+		rules, err := cache.ListActiveForService(ctx, db.MetricTypeLATENCYMS, "S1")
+		if err != nil {
+			t.Fatalf("ListActiveForService() error = %v", err)
+		}
+		return ruleIDs(rules)
+	}
+
+	// Without a listener every lookup reads the database
+	lookup()
+	lookup()
+	if s := cache.Stats(); s.Hits != 0 || s.Misses != 2 || s.Refreshes != 0 {
+		t.Fatalf("Expected 2 uncached misses, got %+v", s)
+	}
+
+	cache.setListening(true)
+	lookup()
+	if got := lookup(); len(got) != 1 {
+		t.Fatalf("Expected 1 rule, got %v", got)
+	}
+	if s := cache.Stats(); s.Hits != 1 || s.Misses != 3 || s.Refreshes != 1 {
+		t.Fatalf("Expected 1 hit after loading, got %+v", s)
+	}
+
+	// A new rule is served once the change invalidates the cache
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "outage", Threshold: 500, IsActive: true})
+	cache.invalidate()
+	if got := lookup(); len(got) != 2 {
+		t.Errorf("Expected 2 rules after invalidation, got %v", got)
+	}
+
+	// A load that raced with an invalidation is not stored
+	generation := cache.generation
+	cache.drop()
+	if _, err := cache.load(ctx, generation); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cache.loaded {
+		t.Errorf("Expected a stale load not to be stored")
+	}
+}
+
+func TestRuleCache_Listen(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := NewRuleCache(handler.repo)
+	go cache.Listen(ctx)
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("the listener", func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.listening
+	})
+
+	if _, err := cache.ListActiveForService(ctx, db.MetricTypeLATENCYMS, "S1"); err != nil {
+		t.Fatalf("ListActiveForService() error = %v", err)
+	}
+
+	// A change committed by any connection invalidates the cache
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "latency", Threshold: 150, IsActive: true})
+	waitFor("the change notification", func() bool { return cache.Stats().Invalidations > 0 })
+
+	rules, err := cache.ListActiveForService(ctx, db.MetricTypeLATENCYMS, "S1")
+	if err != nil {
+		t.Fatalf("ListActiveForService() error = %v", err)
+	}
+	if len(rules) != 1 {
+		t.Errorf("Expected the new rule to be served, got %v", ruleIDs(rules))
+	}
+}
+
+// fakeRules is an in-memory ruleStore that counts the rule loads. onLoad, when set, runs
+// while the active rules are read.
+type fakeRules struct {
+	mu       sync.Mutex
+	rules    []db.QualityRule
+	policies []db.MetricEvaluationPolicy
+	actions  []db.RuleAction
+	reads    int
+	onLoad   func()
+}
+
+func (f *fakeRules) ListActive(context.Context) ([]db.QualityRule, error) {
+	f.mu.Lock()
+	f.reads++
+	rules, onLoad := slices.Clone(f.rules), f.onLoad
+	f.mu.Unlock()
+	if onLoad != nil {
+		onLoad()
+	}
+	return rules, nil
+}
+
+func (f *fakeRules) ListActiveForService(_ context.Context, metricType db.MetricType, serviceID string) ([]db.QualityRule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+	var rules []db.QualityRule
+	for _, r := range f.rules {
+		if r.MetricType == metricType && (len(r.ServiceIds) == 0 || slices.Contains(r.ServiceIds, serviceID)) {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakeRules) ListEvaluationPolicies(context.Context) ([]db.MetricEvaluationPolicy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.policies), nil
+}
+
+func (f *fakeRules) EvaluationPolicy(_ context.Context, metricType db.MetricType) (db.EvaluationPolicy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return policyOf(indexPolicies(f.policies), metricType), nil
+}
+
+func (f *fakeRules) ListActions(context.Context) ([]db.RuleAction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.actions), nil
+}
+
+func (f *fakeRules) GetAction(_ context.Context, id string) (*db.RuleAction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, a := range f.actions {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeRules) ListenRuleChanges(context.Context) (*pgx.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeRules) set(change func(f *fakeRules)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f)
+}
+
+func TestRuleCache_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeRules{rules: []db.QualityRule{decisionRule("latency", 1, 150)}}
+	cache := &RuleCache{repo: store}
+
+	lookup := func() []string {
+		t.Helper()
+		rules, err := cache.ListActiveForService(ctx, db.MetricTypeLATENCYMS, "S1")
+		if err != nil {
+			t.Fatalf("ListActiveForService() error = %v", err)
+		}
+		return ruleIDs(rules)
+	}
+
+	// Without a listener a change may have been missed, so every lookup reads the store
+	lookup()
+	lookup()
+	if store.reads != 2 || cache.Stats().Hits != 0 {
+		t.Fatalf("Expected 2 reads without a listener, got %d (%+v)", store.reads, cache.Stats())
+	}
+
+	// While listening the rules are loaded once and served from memory
+	cache.setListening(true)
+	lookup()
+	store.set(func(f *fakeRules) {
+		f.rules = append(f.rules, decisionRule("outage", 2, 500))
+		f.policies = []db.MetricEvaluationPolicy{{MetricType: db.MetricTypeLATENCYMS, Policy: db.EvaluationPolicyFIRSTMATCH}}
+	})
+	if got := lookup(); !slices.Equal(got, []string{"latency"}) {
+		t.Errorf("Expected the cached rules until invalidated, got %v", got)
+	}
+	if s := cache.Stats(); s.Hits != 1 || s.Refreshes != 1 || store.reads != 3 {
+		t.Errorf("Expected 1 hit after 1 load, got %+v with %d reads", s, store.reads)
+	}
+
+	// A change notification reloads the rules and policies on the next lookup
+	cache.invalidate()
+	if got := lookup(); !slices.Equal(got, []string{"latency", "outage"}) {
+		t.Errorf("Expected both rules after invalidation, got %v", got)
+	}
+	if policy, _ := cache.EvaluationPolicy(ctx, db.MetricTypeLATENCYMS); policy != db.EvaluationPolicyFIRSTMATCH {
+		t.Errorf("Expected the changed policy after invalidation, got %s", policy)
+	}
+	if s := cache.Stats(); s.Invalidations != 1 || s.Refreshes != 2 {
+		t.Errorf("Expected 1 invalidation and 2 loads, got %+v", s)
+	}
+
+	// A change notified while the rules load invalidates the load, so it is not stored
+	cache.invalidate()
+	store.set(func(f *fakeRules) {
+		f.onLoad = func() {
+			cache.invalidate()
+			store.set(func(f *fakeRules) { f.onLoad = nil })
+		}
+	})
+	lookup()
+	if cache.loaded {
+		t.Fatalf("Expected a load that raced with a change not to be stored")
+	}
+	reads := store.reads
+	lookup()
+	if !cache.loaded || store.reads != reads+1 {
+		t.Errorf("Expected the next lookup to load the rules again, got loaded=%v after %d reads", cache.loaded, store.reads-reads)
+	}
+
+	// Losing the listener bypasses the cache again
+	cache.setListening(false)
+	cache.drop()
+	reads = store.reads
+	lookup()
+	if store.reads != reads+1 {
+		t.Errorf("Expected a read without a listener, got %d", store.reads-reads)
+	}
+}
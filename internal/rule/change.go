@@ -0,0 +1,91 @@
+package rule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+// IsValidChangeMode reports whether m is a known rate-of-change mode
+func IsValidChangeMode(m db.RuleChangeMode) bool {
+	switch m {
+	case db.RuleChangeModeNONE, db.RuleChangeModeABSOLUTE, db.RuleChangeModePERCENT:
+		return true
+	default:
+		return false
+	}
+}
+
+// isChangeRule reports whether a rule compares the change of its series
+func isChangeRule(rule *db.QualityRule) bool {
+	return rule.ChangeMode != "" && rule.ChangeMode != db.RuleChangeModeNONE
+}
+
+// referenceSample returns the sample a rate-of-change rule compares the latest sample
+// with: the newest one recorded at least change_seconds earlier. It must also be at most
+// twice change_seconds old, so a gap in the series is not mistaken for a sudden change.
+func referenceSample(rule *db.QualityRule, samples []Sample) (Sample, bool) {
+	if len(samples) < 2 {
+		return Sample{}, false
+	}
+	current := samples[len(samples)-1]
+	lookback := time.Duration(rule.ChangeSeconds) * time.Second
+	at := current.RecordedAt.Add(-lookback)
+
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].RecordedAt.After(at)
+	})
+	if i == 0 {
+		return Sample{}, false
+	}
+	ref := samples[i-1]
+	if ref.RecordedAt.Before(at.Add(-lookback)) {
+		return Sample{}, false
+	}
+	return ref, true
+}
+
+// changeOf returns the change of the latest sample since the reference sample: the
+// difference for ABSOLUTE rules and the difference in percent of the reference value
+// for PERCENT rules. A percentage change from zero is undefined.
+func changeOf(rule *db.QualityRule, samples []Sample) (float64, Sample, bool) {
+	ref, ok := referenceSample(rule, samples)
+	if !ok {
+		return 0, Sample{}, false
+	}
+	current := samples[len(samples)-1]
+	delta := current.Value - ref.Value
+	if rule.ChangeMode == db.RuleChangeModePERCENT {
+		if ref.Value == 0 {
+			return 0, Sample{}, false
+		}
+		delta = delta / math.Abs(ref.Value) * 100
+	}
+	return delta, ref, true
+}
+
+// checkChange evaluates a rate-of-change rule for the latest sample of a series
+func checkChange(rule *db.QualityRule, samples []Sample) *Violation {
+	delta, ref, ok := changeOf(rule, samples)
+	if !ok || !Evaluate(rule, delta) {
+		return nil
+	}
+	current := samples[len(samples)-1]
+
+	unit := ""
+	if rule.ChangeMode == db.RuleChangeModePERCENT {
+		unit = "%"
+	}
+	return &Violation{
+		Value: current.Value,
+		Message: fmt.Sprintf("%s changed by %+.2f%s in %s: %.2f -> %.2f (threshold: %.2f%s, operator: %s)",
+			rule.MetricType, delta, unit, current.RecordedAt.Sub(ref.RecordedAt), ref.Value, current.Value,
+			pgutil.NumericToFloat64(rule.Threshold), unit, rule.Operator),
+		MetricIDs: []uuid.UUID{current.MetricID, ref.MetricID},
+	}
+}
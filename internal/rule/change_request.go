@@ -0,0 +1,267 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+var (
+	// ErrOwnChangeRequest is returned when the requester of a change approves it
+	ErrOwnChangeRequest = errors.New("a change request cannot be approved by its requester")
+	// ErrChangeReviewed is returned when a change request is reviewed twice
+	ErrChangeReviewed = errors.New("change request has already been reviewed")
+	// ErrChangeTargetGone is returned when the rule or action of a change request was
+	// removed while the request waited for review
+	ErrChangeTargetGone = errors.New("the rule or action of the change request no longer exists")
+	// ErrActivationChange is returned when an update of a published rule also switches
+	// it on or off, which is requested on its own
+	ErrActivationChange = errors.New("a published rule is activated and deactivated through POST /api/rules/{id}/activate and /deactivate")
+)
+
+// ruleUpdate is the payload of an UPDATE of a rule: the requested definition and, for a
+// rollback, the revision it restores
+type ruleUpdate struct {
+	RuleDefinition
+	RolledBackTo *int32 `json:"rolled_back_to,omitempty"`
+}
+
+// IsValidChangeRequestStatus reports whether s is a change request status
+func IsValidChangeRequestStatus(s db.ChangeRequestStatus) bool {
+	switch s {
+	case db.ChangeRequestStatusPENDING, db.ChangeRequestStatusAPPROVED, db.ChangeRequestStatusREJECTED:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidChangeTarget reports whether t is a change request target
+func IsValidChangeTarget(t db.ChangeTarget) bool {
+	return t == db.ChangeTargetRULE || t == db.ChangeTargetACTION
+}
+
+// requestChange records a change waiting for review. A rule or action has at most one
+// pending request; a second one fails with a unique violation.
+func requestChange(ctx context.Context, q *db.Queries, target db.ChangeTarget, targetID string, kind db.ChangeKind, payload []byte, actor string) (*db.ChangeRequest, error) {
+	c, err := q.CreateChangeRequest(ctx, db.CreateChangeRequestParams{
+		Target:      target,
+		TargetID:    targetID,
+		Kind:        kind,
+		Payload:     payload,
+		RequestedBy: actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// requestUpdate requests a new definition for a locked published rule, which keeps
+// its current definition until the request is approved. A definition that only
+// switches the rule on or off is requested as an activation or deactivation, and one
+// the rule already has requests nothing.
+func requestUpdate(ctx context.Context, q *db.Queries, old *db.QualityRule, def RuleDefinition, rolledBackTo *int32, actor string) (*db.ChangeRequest, error) {
+	proposed, err := ruleFromDefinition(old.ID, &def)
+	if err != nil {
+		return nil, err
+	}
+	proposed.IsActive = old.IsActive
+	changed, err := definitionChanged(old, proposed)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		if def.IsActive == old.IsActive {
+			return nil, nil
+		}
+		kind := db.ChangeKindDEACTIVATE
+		if def.IsActive {
+			kind = db.ChangeKindACTIVATE
+		}
+		return requestChange(ctx, q, db.ChangeTargetRULE, old.ID, kind, nil, actor)
+	}
+
+	payload, err := json.Marshal(ruleUpdate{RuleDefinition: def, RolledBackTo: rolledBackTo})
+	if err != nil {
+		return nil, err
+	}
+	return requestChange(ctx, q, db.ChangeTargetRULE, old.ID, db.ChangeKindUPDATE, payload, actor)
+}
+
+// requestPlanned requests the change an applied ruleset makes to a published rule,
+// unless a change to the rule already waits for review. def is nil for a deletion.
+func requestPlanned(ctx context.Context, q *db.Queries, old *db.QualityRule, def *RuleDefinition, actor string) error {
+	_, err := q.GetPendingChangeRequest(ctx, db.GetPendingChangeRequestParams{Target: db.ChangeTargetRULE, TargetID: old.ID})
+	if err == nil {
+		return nil
+	}
+	if err != pgx.ErrNoRows {
+		return err
+	}
+	if def == nil {
+		_, err = requestChange(ctx, q, db.ChangeTargetRULE, old.ID, db.ChangeKindDELETE, nil, actor)
+	} else {
+		_, err = requestUpdate(ctx, q, old, *def, nil, actor)
+	}
+	return err
+}
+
+func (r *Repository) GetChangeRequest(ctx context.Context, id int64) (*db.ChangeRequest, error) {
+	c, err := r.q.GetChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ChangeRequestListParams filters change requests; empty fields match every request
+type ChangeRequestListParams struct {
+	Status       db.ChangeRequestStatus
+	Target       db.ChangeTarget
+	TargetID     string
+	DepartmentID string
+	Limit        int32
+	Offset       int32
+}
+
+// ListChangeRequests returns the matching change requests, newest first, with their
+// total count
+func (r *Repository) ListChangeRequests(ctx context.Context, params ChangeRequestListParams) ([]db.ChangeRequest, int, error) {
+	filters := db.CountChangeRequestsParams{
+		FilterStatus: db.NullChangeRequestStatus{ChangeRequestStatus: params.Status, Valid: params.Status != ""},
+		FilterTarget: db.NullChangeTarget{ChangeTarget: params.Target, Valid: params.Target != ""},
+	}
+	if params.TargetID != "" {
+		filters.FilterTargetID = &params.TargetID
+	}
+	if params.DepartmentID != "" {
+		filters.FilterDepartmentID = &params.DepartmentID
+	}
+
+	changes, err := r.q.ListChangeRequests(ctx, db.ListChangeRequestsParams{
+		FilterStatus:       filters.FilterStatus,
+		FilterTarget:       filters.FilterTarget,
+		FilterTargetID:     filters.FilterTargetID,
+		FilterDepartmentID: filters.FilterDepartmentID,
+		LimitVal:           params.Limit,
+		OffsetVal:          params.Offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.q.CountChangeRequests(ctx, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	return changes, int(total), nil
+}
+
+// ApproveChange applies a pending change request on behalf of its requester, who is
+// recorded as the actor of the resulting revision. The approver must differ from the
+// requester.
+func (r *Repository) ApproveChange(ctx context.Context, id int64, actor string, comment *string) (*db.ChangeRequest, error) {
+	return r.reviewChange(ctx, id, db.ChangeRequestStatusAPPROVED, actor, comment, func(q *db.Queries, c *db.ChangeRequest) error {
+		if c.RequestedBy == actor {
+			return ErrOwnChangeRequest
+		}
+		return applyChange(ctx, q, c)
+	})
+}
+
+// RejectChange closes a pending change request without applying it. Requesters may
+// reject their own requests to withdraw them.
+func (r *Repository) RejectChange(ctx context.Context, id int64, actor string, comment *string) (*db.ChangeRequest, error) {
+	return r.reviewChange(ctx, id, db.ChangeRequestStatusREJECTED, actor, comment, nil)
+}
+
+// reviewChange locks a pending change request, runs step, and closes the request with
+// the given status
+func (r *Repository) reviewChange(ctx context.Context, id int64, status db.ChangeRequestStatus, actor string, comment *string, step func(*db.Queries, *db.ChangeRequest) error) (*db.ChangeRequest, error) {
+	var change *db.ChangeRequest
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		c, err := qtx.GetChangeRequestForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if c.Status != db.ChangeRequestStatusPENDING {
+			return ErrChangeReviewed
+		}
+		if step != nil {
+			if err := step(qtx, &c); err != nil {
+				return err
+			}
+		}
+		reviewed, err := qtx.ReviewChangeRequest(ctx, db.ReviewChangeRequestParams{
+			ID:            id,
+			Status:        status,
+			ReviewedBy:    &actor,
+			ReviewComment: comment,
+		})
+		if err != nil {
+			return err
+		}
+		change = &reviewed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// applyChange carries out an approved change request using the given transaction
+func applyChange(ctx context.Context, q *db.Queries, c *db.ChangeRequest) error {
+	if c.Target == db.ChangeTargetACTION {
+		var req UpdateActionRequest
+		if err := json.Unmarshal(c.Payload, &req); err != nil {
+			return fmt.Errorf("failed to decode change request %d: %w", c.ID, err)
+		}
+		_, err := updateAction(ctx, q, c.TargetID, req)
+		if err == pgx.ErrNoRows {
+			return ErrChangeTargetGone
+		}
+		return err
+	}
+
+	old, err := q.GetRuleForUpdate(ctx, c.TargetID)
+	if err == pgx.ErrNoRows {
+		return ErrChangeTargetGone
+	}
+	if err != nil {
+		return err
+	}
+	switch c.Kind {
+	case db.ChangeKindACTIVATE, db.ChangeKindDEACTIVATE:
+		active := c.Kind == db.ChangeKindACTIVATE
+		if old.IsActive == active {
+			return nil
+		}
+		_, err = setActive(ctx, q, &old, active, c.RequestedBy)
+		return err
+	case db.ChangeKindUPDATE:
+		var update ruleUpdate
+		if err := json.Unmarshal(c.Payload, &update); err != nil {
+			return fmt.Errorf("failed to decode change request %d: %w", c.ID, err)
+		}
+		action := db.RuleRevisionActionUPDATED
+		if update.RolledBackTo != nil {
+			action = db.RuleRevisionActionROLLEDBACK
+		}
+		updated, err := updateRule(ctx, q, old.ID, update.RuleDefinition)
+		if err != nil {
+			return err
+		}
+		_, _, err = recordRevision(ctx, q, &old, updated, action, c.RequestedBy, update.RolledBackTo)
+		return err
+	case db.ChangeKindDELETE:
+		return deleteRule(ctx, q, &old, c.RequestedBy)
+	default:
+		return fmt.Errorf("unknown change %s of rule %s", c.Kind, c.TargetID)
+	}
+}
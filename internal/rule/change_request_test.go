@@ -0,0 +1,306 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+	"github.com/unitythemaker/tracely/pkg/httputil"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func TestIsValidChangeRequestStatus(t *testing.T) {
+	for _, s := range []db.ChangeRequestStatus{db.ChangeRequestStatusPENDING, db.ChangeRequestStatusAPPROVED, db.ChangeRequestStatusREJECTED} {
+		if !IsValidChangeRequestStatus(s) {
+			t.Errorf("Expected %s to be valid", s)
+		}
+	}
+	if IsValidChangeRequestStatus("MERGED") {
+		t.Error("Expected MERGED to be invalid")
+	}
+	if !IsValidChangeTarget(db.ChangeTargetACTION) || IsValidChangeTarget("DEPARTMENT") {
+		t.Error("Unexpected change target validation")
+	}
+}
+
+func TestRuleHandler_ChangeRequests(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	testutil.TestDepartment(t, q, "NETOPS", "Network Operations")
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "published", IsActive: true})
+	if _, err := handler.repo.pool.Exec(context.Background(), `UPDATE quality_rules SET department_id = 'NETOPS' WHERE id = 'published'`); err != nil {
+		t.Fatalf("Failed to route rule: %v", err)
+	}
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "draft", IsActive: true, Status: db.RuleStatusDRAFT})
+
+	do := func(method, path, actor, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		if actor != "" {
+			req.Header.Set(httputil.ActorHeader, actor)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	changeOf := func(rr *httptest.ResponseRecorder) ChangeRequestResponse {
+		t.Helper()
+		var resp struct {
+			Data ChangeRequestResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		return resp.Data
+	}
+	isActive := func(id string) bool {
+		t.Helper()
+		rule, err := handler.repo.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Failed to get rule %s: %v", id, err)
+		}
+		return rule.IsActive
+	}
+
+	// Drafts change immediately
+	if rr := do(http.MethodPost, "/api/rules/draft/deactivate", "alice", ""); rr.Code != http.StatusOK || isActive("draft") {
+		t.Errorf("Expected the draft to be deactivated, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	// Published rules wait for review
+	rr := do(http.MethodPost, "/api/rules/published/deactivate", "alice", "")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+	deactivate := changeOf(rr)
+	if deactivate.Kind != "DEACTIVATE" || deactivate.Status != "PENDING" || deactivate.RequestedBy != "alice" || !isActive("published") {
+		t.Fatalf("Expected a pending request leaving the rule active, got %+v", deactivate)
+	}
+	if rr := do(http.MethodDelete, "/api/rules/published", "carol", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Expected a second pending change to conflict, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodGet, "/api/change-requests?status=PENDING&department_id=NETOPS", "", "")
+	var list struct {
+		Data []ChangeRequestResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0].ID != deactivate.ID {
+		t.Errorf("Expected the department's pending request, got %+v", list.Data)
+	}
+
+	path := fmt.Sprintf("/api/change-requests/%d/", deactivate.ID)
+	steps := []struct {
+		name       string
+		path       string
+		actor      string
+		wantStatus int
+	}{
+		{"approve anonymously", "approve", "", http.StatusForbidden},
+		{"approve own request", "approve", "alice", http.StatusForbidden},
+		{"approve", "approve", "bob", http.StatusOK},
+		{"approve twice", "approve", "bob", http.StatusConflict},
+	}
+	for _, tt := range steps {
+		if rr := do(http.MethodPost, path+tt.path, tt.actor, ""); rr.Code != tt.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d. Body: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+	if isActive("published") {
+		t.Error("Expected the approved request to deactivate the rule")
+	}
+	revisions, _, err := handler.repo.ListRevisions(context.Background(), "published", 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Action != db.RuleRevisionActionDEACTIVATED || revisions[0].Actor != "alice" {
+		t.Errorf("Expected the requester's DEACTIVATED revision, got %+v", revisions)
+	}
+
+	// A rejected deletion leaves the rule in place
+	rr = do(http.MethodDelete, "/api/rules/published", "carol", "")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+	rr = do(http.MethodPost, fmt.Sprintf("/api/change-requests/%d/reject", changeOf(rr).ID), "bob", `{"comment": "still needed"}`)
+	if rejected := changeOf(rr); rr.Code != http.StatusOK || rejected.Status != "REJECTED" || rejected.ReviewComment == nil || *rejected.ReviewComment != "still needed" {
+		t.Errorf("Expected the deletion to be rejected with a comment, got %d: %+v", rr.Code, rejected)
+	}
+	if rr := do(http.MethodGet, "/api/rules/published", "", ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the rule to remain, got %d", rr.Code)
+	}
+
+	// So does changing the definition of a published rule, which is switched on and off
+	// only through its own requests
+	update := `{"metric_type": "LATENCY_MS", "threshold": 500, "operator": ">", "action": "OPEN_INCIDENT", "severity": "MEDIUM", "is_active": %v}`
+	if rr := do(http.MethodPatch, "/api/rules/published", "alice", fmt.Sprintf(update, true)); rr.Code != http.StatusConflict {
+		t.Errorf("Expected activating through an update to conflict, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	rr = do(http.MethodPatch, "/api/rules/published", "alice", fmt.Sprintf(update, false))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+	if change := changeOf(rr); change.Kind != "UPDATE" || len(change.Payload) == 0 {
+		t.Errorf("Expected a pending update with the requested definition, got %+v", change)
+	}
+	if rule, _ := handler.repo.Get(context.Background(), "published"); rule.Status != db.RuleStatusPUBLISHED || pgutil.NumericToFloat64(rule.Threshold) == 500 {
+		t.Errorf("Expected the published definition to stay live, got %+v", rule)
+	}
+
+	// Updating an action used by published rules waits for review too
+	rr = do(http.MethodPatch, "/api/rule-actions/OPEN_INCIDENT", "alice", `{"name": "Open incident (NetOps)", "department_id": "NETOPS"}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+	actionUpdate := changeOf(rr)
+	if actionUpdate.Target != "ACTION" || actionUpdate.Kind != "UPDATE" {
+		t.Errorf("Unexpected action change request %+v", actionUpdate)
+	}
+	if rr := do(http.MethodPost, fmt.Sprintf("/api/change-requests/%d/approve", actionUpdate.ID), "bob", ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the action update to be approved, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	action, err := handler.repo.GetAction(context.Background(), "OPEN_INCIDENT")
+	if err != nil {
+		t.Fatalf("Failed to get action: %v", err)
+	}
+	if action.Name != "Open incident (NetOps)" || action.DepartmentID == nil || *action.DepartmentID != "NETOPS" {
+		t.Errorf("Expected the approved settings, got %+v", action)
+	}
+
+	if rr := do(http.MethodGet, "/api/change-requests?status=MERGED", "", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown status, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := do(http.MethodGet, "/api/change-requests/999999", "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing request, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestWorker_PendingRuleChanges(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := wt.worker.ruleRepo
+	def := RuleDefinition{
+		MetricType: "LATENCY_MS", Threshold: 150, Operator: ">",
+		Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true,
+	}
+	if _, err := repo.Create(ctx, CreateRuleRequest{ID: "latency", RuleDefinition: def}, "alice"); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, repo, "latency")
+
+	// approve approves a pending change and checks how many incidents the rule has
+	// opened after a violation recorded before and one recorded after the approval
+	closeAll := func() {
+		t.Helper()
+		for _, inc := range wt.incidents(t) {
+			if inc.Status == db.IncidentStatusCLOSED {
+				continue
+			}
+			if _, err := wt.incidentRepo.Close(ctx, inc.ID); err != nil {
+				t.Fatalf("Failed to close incident: %v", err)
+			}
+		}
+	}
+	approve := func(change *db.ChangeRequest, before, after int) {
+		t.Helper()
+		closeAll()
+		wt.record(t, 200)
+		if incidents := wt.incidents(t); len(incidents) != before {
+			t.Fatalf("Expected %d incidents while %s waits for review, got %d", before, change.Kind, len(incidents))
+		}
+		closeAll()
+		if _, err := repo.ApproveChange(ctx, change.ID, "bob", nil); err != nil {
+			t.Fatalf("Failed to approve %s: %v", change.Kind, err)
+		}
+		wt.record(t, 200)
+		if incidents := wt.incidents(t); len(incidents) != after {
+			t.Fatalf("Expected %d incidents once %s is approved, got %d", after, change.Kind, len(incidents))
+		}
+	}
+	threshold := func() float64 {
+		t.Helper()
+		rule, err := repo.Get(ctx, "latency")
+		if err != nil {
+			t.Fatalf("Failed to get rule: %v", err)
+		}
+		if rule.Status != db.RuleStatusPUBLISHED {
+			t.Fatalf("Expected the rule to stay published, got %s", rule.Status)
+		}
+		return pgutil.NumericToFloat64(rule.Threshold)
+	}
+
+	// Raising the threshold is requested; the published threshold keeps alerting
+	raised := def
+	raised.Threshold = 300
+	inactive := raised
+	inactive.IsActive = false
+	if _, _, err := repo.Update(ctx, "latency", inactive, "alice"); !errors.Is(err, ErrActivationChange) {
+		t.Errorf("Expected deactivating through an update to fail, got %v", err)
+	}
+	_, change, err := repo.Update(ctx, "latency", raised, "alice")
+	if err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	if change == nil || change.Kind != db.ChangeKindUPDATE || threshold() != 150 {
+		t.Fatalf("Expected a pending update leaving threshold 150, got %+v", change)
+	}
+	approve(change, 1, 1)
+	if threshold() != 300 {
+		t.Errorf("Expected the approved threshold 300, got %v", threshold())
+	}
+
+	// Rolling back to the first revision is requested the same way
+	_, change, err = repo.Rollback(ctx, "latency", 1, "alice")
+	if err != nil {
+		t.Fatalf("Failed to roll back rule: %v", err)
+	}
+	if change == nil || change.Kind != db.ChangeKindUPDATE || threshold() != 300 {
+		t.Fatalf("Expected a pending rollback leaving threshold 300, got %+v", change)
+	}
+	approve(change, 1, 2)
+	revisions, _, err := repo.ListRevisions(ctx, "latency", 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if threshold() != 150 || revisions[0].Action != db.RuleRevisionActionROLLEDBACK || revisions[0].Actor != "alice" {
+		t.Errorf("Expected alice's rollback to threshold 150, got %v and %+v", threshold(), revisions[0])
+	}
+
+	// A ruleset deactivating the rule requests the deactivation
+	plan, err := repo.Apply(ctx, &Ruleset{Rules: []CreateRuleRequest{{ID: "latency", RuleDefinition: def}}}, false, "gitops")
+	if err != nil {
+		t.Fatalf("Failed to apply ruleset: %v", err)
+	}
+	if len(plan.Rules) != 0 {
+		t.Fatalf("Expected the ruleset to match the rule, got %+v", plan.Rules)
+	}
+	def.IsActive = false
+	plan, err = repo.Apply(ctx, &Ruleset{Rules: []CreateRuleRequest{{ID: "latency", RuleDefinition: def}}}, false, "gitops")
+	if err != nil {
+		t.Fatalf("Failed to apply ruleset: %v", err)
+	}
+	if len(plan.Rules) != 1 || plan.Rules[0].Action != PlanUpdate || !plan.Rules[0].Review {
+		t.Fatalf("Expected the update to be requested for review, got %+v", plan.Rules)
+	}
+	pending, err := repo.q.GetPendingChangeRequest(ctx, db.GetPendingChangeRequestParams{Target: db.ChangeTargetRULE, TargetID: "latency"})
+	if err != nil {
+		t.Fatalf("Failed to get pending change: %v", err)
+	}
+	change = &pending
+	if change.Kind != db.ChangeKindDEACTIVATE || change.RequestedBy != "gitops" {
+		t.Fatalf("Expected gitops to request a deactivation, got %+v", change)
+	}
+	approve(change, 3, 3)
+}
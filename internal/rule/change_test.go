@@ -0,0 +1,121 @@
+package rule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func TestCheckChange(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	// samples returns a series with one sample per offset in minutes
+	samples := func(points map[int]float64) []Sample {
+		var result []Sample
+		for minute := 0; minute <= 60; minute++ {
+			if v, ok := points[minute]; ok {
+				result = append(result, Sample{
+					MetricID:   uuid.New(),
+					Value:      v,
+					RecordedAt: start.Add(time.Duration(minute) * time.Minute),
+				})
+			}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		mode      db.RuleChangeMode
+		operator  db.RuleOperator
+		threshold float64
+		points    map[int]float64
+		fired     bool
+		message   string
+	}{
+		{
+			name: "percent doubled", mode: db.RuleChangeModePERCENT, operator: db.RuleOperatorValue1, threshold: 100,
+			points: map[int]float64{0: 100, 1: 110, 2: 120, 3: 230},
+			fired:  true, message: "LATENCY_MS changed by +109.09% in 2m0s: 110.00 -> 230.00",
+		},
+		{
+			name: "percent below threshold", mode: db.RuleChangeModePERCENT, operator: db.RuleOperatorValue1, threshold: 100,
+			points: map[int]float64{0: 100, 1: 110, 2: 120, 3: 200},
+		},
+		{
+			name: "absolute rise", mode: db.RuleChangeModeABSOLUTE, operator: db.RuleOperatorValue0, threshold: 50,
+			points: map[int]float64{0: 100, 2: 160},
+			fired:  true, message: "changed by +60.00 in 2m0s: 100.00 -> 160.00",
+		},
+		{
+			name: "absolute drop", mode: db.RuleChangeModeABSOLUTE, operator: db.RuleOperatorValue2, threshold: -50,
+			points: map[int]float64{0: 100, 3: 40},
+			fired:  true, message: "changed by -60.00 in 3m0s",
+		},
+		{
+			name: "no sample old enough", mode: db.RuleChangeModeABSOLUTE, operator: db.RuleOperatorValue0, threshold: 50,
+			points: map[int]float64{0: 100, 1: 200},
+		},
+		{
+			name: "reference too old after a gap", mode: db.RuleChangeModeABSOLUTE, operator: db.RuleOperatorValue0, threshold: 50,
+			points: map[int]float64{0: 100, 30: 200},
+		},
+		{
+			name: "percent from zero", mode: db.RuleChangeModePERCENT, operator: db.RuleOperatorValue0, threshold: 50,
+			points: map[int]float64{0: 0, 2: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &db.QualityRule{
+				MetricType:    db.MetricTypeLATENCYMS,
+				Operator:      tt.operator,
+				Threshold:     pgutil.Float64ToNumeric(tt.threshold),
+				ChangeMode:    tt.mode,
+				ChangeSeconds: 120,
+			}
+			series := samples(tt.points)
+			v := Check(rule, series)
+			if (v != nil) != tt.fired {
+				t.Fatalf("Expected fired=%v, got %+v", tt.fired, v)
+			}
+			if v == nil {
+				return
+			}
+			if !strings.Contains(v.Message, tt.message) {
+				t.Errorf("Expected message to contain %q, got %q", tt.message, v.Message)
+			}
+			if len(v.MetricIDs) != 2 || v.MetricIDs[0] != series[len(series)-1].MetricID {
+				t.Errorf("Expected the current and reference samples as contributing metrics, got %v", v.MetricIDs)
+			}
+		})
+	}
+}
+
+func TestRecovered_Change(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	rule := &db.QualityRule{
+		MetricType:    db.MetricTypeLATENCYMS,
+		Operator:      db.RuleOperatorValue1,
+		Threshold:     pgutil.Float64ToNumeric(100),
+		ChangeMode:    db.RuleChangeModePERCENT,
+		ChangeSeconds: 60,
+	}
+	series := []Sample{
+		{Value: 100, RecordedAt: start},
+		{Value: 250, RecordedAt: start.Add(time.Minute)},
+		{Value: 260, RecordedAt: start.Add(2 * time.Minute)},
+	}
+
+	if _, ok := Recovered(rule, series[:2]); ok {
+		t.Error("Expected a +150% change not to be recovered")
+	}
+	value, ok := Recovered(rule, series)
+	if !ok || value != 4 {
+		t.Errorf("Expected a +4%% change to be recovered, got %v %v", value, ok)
+	}
+}
@@ -0,0 +1,57 @@
+package rule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/schedule"
+)
+
+// CompiledRule is a rule with its condition, expression, schedule and tiers decoded
+// once, when the rule cache loads it, instead of on every evaluation. Compiled rules are
+// shared between evaluations and must not be modified.
+type CompiledRule struct {
+	db.QualityRule
+
+	condition  *Condition
+	expression *Expression
+	schedule   *schedule.Schedule
+	tiers      []Tier
+	// err is why the condition or expression does not compile; evaluating the rule
+	// fails with it
+	err error
+}
+
+// CompileRule decodes the stored definition of a rule for evaluation
+func CompileRule(rule db.QualityRule) *CompiledRule {
+	c := &CompiledRule{
+		QualityRule: rule,
+		schedule:    scheduleOf(&rule),
+		tiers:       tiersOf(&rule),
+	}
+	if rule.Expression != nil {
+		if c.expression, c.err = CompileExpression(*rule.Expression); c.err != nil {
+			c.err = fmt.Errorf("invalid expression: %w", c.err)
+		}
+		return c
+	}
+	if c.condition, c.err = ParseCondition(rule.Condition); c.err != nil {
+		c.err = fmt.Errorf("invalid condition: %w", c.err)
+	}
+	return c
+}
+
+// compileRules compiles rules, keeping their order
+func compileRules(rules []db.QualityRule) []*CompiledRule {
+	compiled := make([]*CompiledRule, len(rules))
+	for i, rule := range rules {
+		compiled[i] = CompileRule(rule)
+	}
+	return compiled
+}
+
+// InSchedule reports whether the rule is evaluated at t, like InSchedule
+func (c *CompiledRule) InSchedule(t time.Time) bool {
+	return c.schedule == nil || c.schedule.Contains(t)
+}
@@ -0,0 +1,62 @@
+package rule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestCompileRule(t *testing.T) {
+	rule, err := ruleFromDefinition("latency", &RuleDefinition{
+		MetricType: "LATENCY_MS",
+		Operator:   ">",
+		Action:     "OPEN_INCIDENT",
+		Tiers:      latencyTiers(),
+	})
+	if err != nil {
+		t.Fatalf("ruleFromDefinition() error = %v", err)
+	}
+	compiled := CompileRule(*rule)
+
+	// The tiers are decoded once; later evaluations do not read the stored JSON
+	compiled.QualityRule.Tiers = []byte("not json")
+	v, err := newEvaluator(nil, nil).evaluate(context.Background(), compiled, "S1", []Sample{{Value: 350, RecordedAt: time.Now()}})
+	if err != nil {
+		t.Fatalf("evaluate() error = %v", err)
+	}
+	if v == nil || v.Severity != db.IncidentSeverityHIGH {
+		t.Errorf("Expected a HIGH violation, got %+v", v)
+	}
+}
+
+func TestCompileRule_Invalid(t *testing.T) {
+	expression := "value >"
+	condition := []byte(`{"op": "AND", "conditions": `)
+
+	tests := []struct {
+		name string
+		rule db.QualityRule
+		want string
+	}{
+		{"expression", db.QualityRule{ID: "expr", Expression: &expression}, "invalid expression"},
+		{"condition", db.QualityRule{ID: "cond", Condition: condition}, "invalid condition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newEvaluator(nil, nil).evaluate(context.Background(), CompileRule(tt.rule), "S1", []Sample{{Value: 1, RecordedAt: time.Now()}})
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("evaluate() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompiledRule_InSchedule(t *testing.T) {
+	always := CompileRule(db.QualityRule{ID: "always"})
+	if !always.InSchedule(time.Now()) {
+		t.Error("Expected a rule without a schedule to always be evaluated")
+	}
+}
@@ -0,0 +1,188 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+const (
+	ConditionAnd = "AND"
+	ConditionOr  = "OR"
+
+	// DefaultFreshness is how old the latest sample of a composite condition may be
+	// when the rule does not set freshness_seconds
+	DefaultFreshness = 5 * time.Minute
+	// maxConditionDepth limits nesting of composite conditions
+	maxConditionDepth = 5
+)
+
+// Condition is a node of a composite rule. A node is either a group combining its
+// child conditions with AND/OR, or a leaf comparing the latest value of a metric type.
+type Condition struct {
+	Op         string      `json:"op,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	MetricType string   `json:"metric_type,omitempty"`
+	Operator   string   `json:"operator,omitempty"`
+	Threshold  *float64 `json:"threshold,omitempty"`
+}
+
+// ParseCondition decodes a stored condition. It returns nil for single-metric rules.
+func ParseCondition(raw []byte) (*Condition, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var c Condition
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *Condition) isGroup() bool {
+	return c.Op != ""
+}
+
+// Validate checks the condition tree. Errors name the offending node, e.g. condition.conditions[1].
+func (c *Condition) Validate() error {
+	return c.validate("condition", 1)
+}
+
+func (c *Condition) validate(path string, depth int) error {
+	if depth > maxConditionDepth {
+		return fmt.Errorf("%s: conditions must not be nested more than %d levels", path, maxConditionDepth)
+	}
+
+	if c.isGroup() {
+		if c.Op != ConditionAnd && c.Op != ConditionOr {
+			return fmt.Errorf("%s: op must be AND or OR", path)
+		}
+		if c.MetricType != "" || c.Operator != "" || c.Threshold != nil {
+			return fmt.Errorf("%s: a group cannot set metric_type, operator or threshold", path)
+		}
+		if len(c.Conditions) == 0 {
+			return fmt.Errorf("%s: conditions is required", path)
+		}
+		for i := range c.Conditions {
+			if err := c.Conditions[i].validate(fmt.Sprintf("%s.conditions[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case len(c.Conditions) > 0:
+		return fmt.Errorf("%s: op is required when conditions are set", path)
+	case !isValidMetricType(db.MetricType(c.MetricType)):
+		return fmt.Errorf("%s: invalid metric_type: %q", path, c.MetricType)
+	case !isValidOperator(db.RuleOperator(c.Operator)):
+		return fmt.Errorf("%s: invalid operator: %q", path, c.Operator)
+	case c.Threshold == nil:
+		return fmt.Errorf("%s: threshold is required", path)
+	}
+	return nil
+}
+
+// leaves returns the leaf conditions in tree order
+func (c *Condition) leaves() []*Condition {
+	if !c.isGroup() {
+		return []*Condition{c}
+	}
+	var result []*Condition
+	for i := range c.Conditions {
+		result = append(result, c.Conditions[i].leaves()...)
+	}
+	return result
+}
+
+// MetricTypes returns the distinct metric types used by the condition, in tree order
+func (c *Condition) MetricTypes() []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, leaf := range c.leaves() {
+		if !seen[leaf.MetricType] {
+			seen[leaf.MetricType] = true
+			result = append(result, leaf.MetricType)
+		}
+	}
+	return result
+}
+
+func (c *Condition) String() string {
+	if !c.isGroup() {
+		return fmt.Sprintf("%s %s %.2f", c.MetricType, c.Operator, *c.Threshold)
+	}
+	parts := make([]string, len(c.Conditions))
+	for i := range c.Conditions {
+		parts[i] = c.Conditions[i].String()
+	}
+	return "(" + strings.Join(parts, " "+c.Op+" ") + ")"
+}
+
+// leafMatch is a leaf condition satisfied by a sample
+type leafMatch struct {
+	cond   *Condition
+	sample Sample
+}
+
+// match evaluates the tree against the latest samples and returns the leaves
+// that made it true. Samples older than freshness relative to now are ignored.
+func (c *Condition) match(latest map[db.MetricType]Sample, now time.Time, freshness time.Duration) (bool, []leafMatch) {
+	if !c.isGroup() {
+		s, ok := latest[db.MetricType(c.MetricType)]
+		if !ok || s.RecordedAt.Before(now.Add(-freshness)) {
+			return false, nil
+		}
+		if !compare(db.RuleOperator(c.Operator), s.Value, *c.Threshold) {
+			return false, nil
+		}
+		return true, []leafMatch{{cond: c, sample: s}}
+	}
+
+	var matches []leafMatch
+	for i := range c.Conditions {
+		ok, m := c.Conditions[i].match(latest, now, freshness)
+		if !ok && c.Op == ConditionAnd {
+			return false, nil
+		}
+		matches = append(matches, m...)
+	}
+	return len(matches) > 0, matches
+}
+
+// CheckComposite evaluates a composite rule against the latest sample of each metric
+// type of the service. now is the time of the metric being processed.
+func CheckComposite(rule *db.QualityRule, cond *Condition, latest map[db.MetricType]Sample, now time.Time) *Violation {
+	freshness := ruleFreshness(rule)
+	ok, matches := cond.match(latest, now, freshness)
+	if !ok {
+		return nil
+	}
+
+	values := make([]string, len(matches))
+	metricIDs := make([]uuid.UUID, len(matches))
+	for i, m := range matches {
+		values[i] = fmt.Sprintf("%s=%.2f", m.cond.MetricType, m.sample.Value)
+		metricIDs[i] = m.sample.MetricID
+	}
+
+	return &Violation{
+		Value:     matches[len(matches)-1].sample.Value,
+		Message:   fmt.Sprintf("composite condition %s matched: %s", cond, strings.Join(values, ", ")),
+		MetricIDs: metricIDs,
+	}
+}
+
+// ruleFreshness returns the freshness window of a composite rule
+func ruleFreshness(rule *db.QualityRule) time.Duration {
+	if rule.FreshnessSeconds > 0 {
+		return time.Duration(rule.FreshnessSeconds) * time.Second
+	}
+	return DefaultFreshness
+}
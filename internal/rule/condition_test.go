@@ -0,0 +1,148 @@
+package rule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func threshold(v float64) *float64 {
+	return &v
+}
+
+// streamingCondition is BUFFER_RATIO > 6 AND (PACKET_LOSS > 1.5 OR ERROR_RATE > 5)
+func streamingCondition() *Condition {
+	return &Condition{
+		Op: ConditionAnd,
+		Conditions: []Condition{
+			{MetricType: "BUFFER_RATIO", Operator: ">", Threshold: threshold(6)},
+			{
+				Op: ConditionOr,
+				Conditions: []Condition{
+					{MetricType: "PACKET_LOSS", Operator: ">", Threshold: threshold(1.5)},
+					{MetricType: "ERROR_RATE", Operator: ">", Threshold: threshold(5)},
+				},
+			},
+		},
+	}
+}
+
+func TestCondition_Validate(t *testing.T) {
+	if err := streamingCondition().Validate(); err != nil {
+		t.Fatalf("Validate() returned error for a valid condition: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cond *Condition
+		path string
+	}{
+		{
+			name: "unknown op",
+			cond: &Condition{Op: "XOR", Conditions: []Condition{{MetricType: "LATENCY_MS", Operator: ">", Threshold: threshold(1)}}},
+			path: "condition:",
+		},
+		{
+			name: "empty group",
+			cond: &Condition{Op: ConditionAnd},
+			path: "condition:",
+		},
+		{
+			name: "invalid metric type in nested leaf",
+			cond: &Condition{Op: ConditionAnd, Conditions: []Condition{
+				{MetricType: "LATENCY_MS", Operator: ">", Threshold: threshold(1)},
+				{MetricType: "JITTER", Operator: ">", Threshold: threshold(1)},
+			}},
+			path: "condition.conditions[1]:",
+		},
+		{
+			name: "missing threshold",
+			cond: &Condition{MetricType: "LATENCY_MS", Operator: ">"},
+			path: "condition:",
+		},
+		{
+			name: "invalid operator",
+			cond: &Condition{MetricType: "LATENCY_MS", Operator: "=>", Threshold: threshold(1)},
+			path: "condition:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cond.Validate()
+			if err == nil {
+				t.Fatalf("Expected validation error")
+			}
+			if !strings.HasPrefix(err.Error(), tt.path) {
+				t.Errorf("Expected error for %s, got %q", tt.path, err.Error())
+			}
+		})
+	}
+}
+
+func TestCondition_MetricTypes(t *testing.T) {
+	got := streamingCondition().MetricTypes()
+	want := []string{"BUFFER_RATIO", "PACKET_LOSS", "ERROR_RATE"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("MetricTypes() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckComposite(t *testing.T) {
+	rule := &db.QualityRule{FreshnessSeconds: 60}
+	now := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	sample := func(value float64, age time.Duration) Sample {
+		return Sample{MetricID: uuid.New(), Value: value, RecordedAt: now.Add(-age)}
+	}
+
+	t.Run("all conditions match", func(t *testing.T) {
+		buffer := sample(7.2, 10*time.Second)
+		loss := sample(2.1, 0)
+		v := CheckComposite(rule, streamingCondition(), map[db.MetricType]Sample{
+			db.MetricTypeBUFFERRATIO: buffer,
+			db.MetricTypePACKETLOSS:  loss,
+			db.MetricTypeERRORRATE:   sample(1, 0),
+		}, now)
+		if v == nil {
+			t.Fatalf("Expected violation")
+		}
+		if len(v.MetricIDs) != 2 || v.MetricIDs[0] != buffer.MetricID || v.MetricIDs[1] != loss.MetricID {
+			t.Errorf("Expected contributing metrics [buffer loss], got %v", v.MetricIDs)
+		}
+		if !strings.Contains(v.Message, "BUFFER_RATIO=7.20") || !strings.Contains(v.Message, "PACKET_LOSS=2.10") {
+			t.Errorf("Expected message to report contributing values, got %q", v.Message)
+		}
+	})
+
+	t.Run("one side of AND does not match", func(t *testing.T) {
+		v := CheckComposite(rule, streamingCondition(), map[db.MetricType]Sample{
+			db.MetricTypeBUFFERRATIO: sample(3, 0),
+			db.MetricTypePACKETLOSS:  sample(2.1, 0),
+		}, now)
+		if v != nil {
+			t.Errorf("Expected no violation, got %+v", v)
+		}
+	})
+
+	t.Run("stale sample is ignored", func(t *testing.T) {
+		v := CheckComposite(rule, streamingCondition(), map[db.MetricType]Sample{
+			db.MetricTypeBUFFERRATIO: sample(7.2, 2*time.Minute),
+			db.MetricTypePACKETLOSS:  sample(2.1, 0),
+		}, now)
+		if v != nil {
+			t.Errorf("Expected no violation with a stale BUFFER_RATIO sample, got %+v", v)
+		}
+	})
+
+	t.Run("missing metric type", func(t *testing.T) {
+		v := CheckComposite(rule, streamingCondition(), map[db.MetricType]Sample{
+			db.MetricTypePACKETLOSS: sample(2.1, 0),
+		}, now)
+		if v != nil {
+			t.Errorf("Expected no violation without a BUFFER_RATIO sample, got %+v", v)
+		}
+	})
+}
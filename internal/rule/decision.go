@@ -0,0 +1,131 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/silence"
+)
+
+// outcome is what evaluating a rule against a sample decided, before the behaviour of
+// the rule's action is applied
+type outcome int
+
+const (
+	// outcomeOutsideSchedule: the rule is not evaluated outside its schedule
+	outcomeOutsideSchedule outcome = iota
+	// outcomeFailed: the rule could not be evaluated or its silences checked
+	outcomeFailed
+	// outcomeNotMatched: the rule did not match
+	outcomeNotMatched
+	// outcomeDraft: the rule matched but is not published, so it runs in shadow mode
+	outcomeDraft
+	// outcomeShadowed: the rule matched after a higher-priority rule under FIRST_MATCH
+	outcomeShadowed
+	// outcomeSilenced: the rule matched but a silence suppresses the violation
+	outcomeSilenced
+	// outcomeViolated: the rule matched and its action applies
+	outcomeViolated
+)
+
+// decision is the outcome of one rule for a sample
+type decision struct {
+	rule      *CompiledRule
+	outcome   outcome
+	violation *Violation
+	err       error
+	// shadowedBy is the rule that matched first when the outcome is outcomeShadowed
+	shadowedBy string
+	// silence suppresses the violation when the outcome is outcomeSilenced
+	silence *db.Silence
+}
+
+// silenceMatcher returns the silence that suppresses a violation, or nil
+type silenceMatcher func(ctx context.Context, rule *db.QualityRule, serviceID string, violation *Violation) (*db.Silence, error)
+
+// decide evaluates rules, in priority order, against the series of a service whose
+// latest sample was recorded at at. Drafts and rules pending review do not shadow other
+// rules; with FIRST_MATCH the first published rule that matches and is not silenced
+// shadows the rules after it. The worker and backtests both decide with it and only
+// differ in how they apply the decisions.
+func decide(ctx context.Context, e *evaluator, rules []*CompiledRule, policy db.EvaluationPolicy, serviceID string, samples []Sample, at time.Time, silenced silenceMatcher) []decision {
+	decisions := make([]decision, 0, len(rules))
+	var matchedRuleID string
+	for _, compiled := range rules {
+		rule := &compiled.QualityRule
+		decisions = append(decisions, decision{rule: compiled})
+		current := &decisions[len(decisions)-1]
+
+		if !compiled.InSchedule(at) {
+			current.outcome = outcomeOutsideSchedule
+			continue
+		}
+		violation, err := e.evaluate(ctx, compiled, serviceID, samples)
+		if err != nil {
+			current.outcome, current.err = outcomeFailed, err
+			continue
+		}
+		if violation == nil {
+			current.outcome = outcomeNotMatched
+			continue
+		}
+		current.violation = violation
+
+		if !isPublished(rule) {
+			current.outcome = outcomeDraft
+			continue
+		}
+		if matchedRuleID != "" {
+			current.outcome, current.shadowedBy = outcomeShadowed, matchedRuleID
+			continue
+		}
+		s, err := silenced(ctx, rule, serviceID, violation)
+		if err != nil {
+			current.outcome, current.err = outcomeFailed, fmt.Errorf("failed to check silences: %w", err)
+			continue
+		}
+		if s != nil {
+			current.outcome, current.silence = outcomeSilenced, s
+			continue
+		}
+		if policy == db.EvaluationPolicyFIRSTMATCH {
+			matchedRuleID = rule.ID
+		}
+		current.outcome = outcomeViolated
+	}
+	return decisions
+}
+
+// silenceTarget describes a violation of a rule for matching silences. A composite rule
+// is matched on every metric type its condition reads, not only its first.
+func silenceTarget(rule *db.QualityRule, serviceID string, violation *Violation) silence.Target {
+	metricTypes := []db.MetricType{rule.MetricType}
+	for _, mt := range rule.MetricTypes {
+		if !slices.Contains(metricTypes, db.MetricType(mt)) {
+			metricTypes = append(metricTypes, db.MetricType(mt))
+		}
+	}
+	return silence.Target{
+		ServiceID:   serviceID,
+		MetricTypes: metricTypes,
+		RuleID:      rule.ID,
+		Severity:    severityOf(rule, violation),
+	}
+}
+
+// matchSilences returns a silenceMatcher over a fixed set of silences, checked at the
+// given time
+func matchSilences(silences []db.Silence, at time.Time) silenceMatcher {
+	return func(_ context.Context, rule *db.QualityRule, serviceID string, violation *Violation) (*db.Silence, error) {
+		target := silenceTarget(rule, serviceID, violation)
+		for i := range silences {
+			if silence.Matches(&silences[i], target) && silence.Active(&silences[i], at) {
+				return &silences[i], nil
+			}
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,197 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func decisionRule(id string, priority int32, threshold float64) db.QualityRule {
+	return db.QualityRule{
+		ID:          id,
+		MetricType:  db.MetricTypeLATENCYMS,
+		MetricTypes: []string{string(db.MetricTypeLATENCYMS)},
+		Operator:    db.RuleOperatorValue0,
+		Threshold:   pgutil.Float64ToNumeric(threshold),
+		Action:      ActionOpenIncident,
+		Priority:    priority,
+		Status:      db.RuleStatusPUBLISHED,
+	}
+}
+
+func outcomesOf(decisions []decision) []outcome {
+	outcomes := make([]outcome, len(decisions))
+	for i, d := range decisions {
+		outcomes[i] = d.outcome
+	}
+	return outcomes
+}
+
+func TestDecide(t *testing.T) {
+	at := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	samples := []Sample{{MetricID: uuid.New(), Value: 300, RecordedAt: at}}
+
+	draft := decisionRule("draft", 1, 100)
+	draft.Status = db.RuleStatusDRAFT
+	silenced := decisionRule("silenced", 2, 100)
+	first := decisionRule("first", 3, 200)
+	second := decisionRule("second", 4, 250)
+	healthy := decisionRule("healthy", 5, 500)
+	rules := compileRules([]db.QualityRule{draft, silenced, first, second, healthy})
+
+	silenceOf := func(_ context.Context, rule *db.QualityRule, _ string, _ *Violation) (*db.Silence, error) {
+		if rule.ID == "silenced" {
+			return &db.Silence{ID: uuid.New()}, nil
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		name   string
+		policy db.EvaluationPolicy
+		want   []outcome
+	}{
+		{
+			name:   "all matches",
+			policy: db.EvaluationPolicyALLMATCHES,
+			want:   []outcome{outcomeDraft, outcomeSilenced, outcomeViolated, outcomeViolated, outcomeNotMatched},
+		},
+		{
+			// Neither the draft nor the silenced rule shadows the rules after it
+			name:   "first match",
+			policy: db.EvaluationPolicyFIRSTMATCH,
+			want:   []outcome{outcomeDraft, outcomeSilenced, outcomeViolated, outcomeShadowed, outcomeNotMatched},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := decide(context.Background(), newEvaluator(nil, nil), rules, tt.policy, "S1", samples, at, silenceOf)
+			got := outcomesOf(decisions)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expected outcomes %v, got %v", tt.want, got)
+				}
+			}
+			if tt.policy == db.EvaluationPolicyFIRSTMATCH && decisions[3].shadowedBy != "first" {
+				t.Errorf("Expected second to be shadowed by first, got %q", decisions[3].shadowedBy)
+			}
+			if decisions[1].silence == nil {
+				t.Error("Expected the silence of the silenced rule")
+			}
+		})
+	}
+}
+
+func TestDecide_ScheduleAndFailures(t *testing.T) {
+	at := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	samples := []Sample{{MetricID: uuid.New(), Value: 300, RecordedAt: at}}
+
+	scheduled := decisionRule("scheduled", 1, 100)
+	scheduled.Schedule = []byte(`{"cron": "0-2 * * * *"}`)
+	checked := decisionRule("checked", 2, 100)
+	rules := compileRules([]db.QualityRule{scheduled, checked})
+
+	failing := func(context.Context, *db.QualityRule, string, *Violation) (*db.Silence, error) {
+		return nil, errors.New("connection refused")
+	}
+	decisions := decide(context.Background(), newEvaluator(nil, nil), rules, db.EvaluationPolicyFIRSTMATCH, "S1", samples, at, failing)
+	if decisions[0].outcome != outcomeOutsideSchedule {
+		t.Errorf("Expected the scheduled rule to be skipped, got %v", decisions[0].outcome)
+	}
+	if decisions[1].outcome != outcomeFailed || decisions[1].err == nil {
+		t.Errorf("Expected a failed silence check, got %v (%v)", decisions[1].outcome, decisions[1].err)
+	}
+}
+
+func TestReplay_Environment(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	target := decisionRule("target", 5, 150)
+	target.Status = db.RuleStatusDRAFT
+	metrics := backtestMetrics("S1", start, 200, 210, 220)
+
+	higher := decisionRule("higher", 1, 150)
+	higherDraft := higher
+	higherDraft.Status = db.RuleStatusDRAFT
+	firstMatch := []db.MetricEvaluationPolicy{{MetricType: db.MetricTypeLATENCYMS, Policy: db.EvaluationPolicyFIRSTMATCH}}
+	serviceID := "S1"
+
+	tests := []struct {
+		name      string
+		env       ReplayEnv
+		wouldOpen int
+		shadowed  int
+		silenced  int
+	}{
+		{
+			// The replayed rule is evaluated as if it was published
+			name:      "alone",
+			wouldOpen: 1,
+		},
+		{
+			name:     "shadowed under first match",
+			env:      ReplayEnv{Rules: []db.QualityRule{higher}, Policies: firstMatch},
+			shadowed: 3,
+		},
+		{
+			name:      "draft rules do not shadow",
+			env:       ReplayEnv{Rules: []db.QualityRule{higherDraft}, Policies: firstMatch},
+			wouldOpen: 1,
+		},
+		{
+			name:      "all matches does not shadow",
+			env:       ReplayEnv{Rules: []db.QualityRule{higher}},
+			wouldOpen: 1,
+		},
+		{
+			name: "silenced",
+			env: ReplayEnv{Silences: []db.Silence{{
+				ServiceID: &serviceID,
+				StartsAt:  start.Add(30 * time.Second),
+				EndsAt:    pgtype.Timestamptz{Time: start.Add(90 * time.Second), Valid: true},
+			}}},
+			wouldOpen: 1,
+			silenced:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Replay(context.Background(), &target, db.ActionBehaviorOPENINCIDENT, tt.env, metrics, nil)
+			if err != nil {
+				t.Fatalf("Replay failed: %v", err)
+			}
+			got := results[0]
+			if got.Violations != 3 || got.WouldOpen != tt.wouldOpen || got.Shadowed != tt.shadowed || got.Silenced != tt.silenced {
+				t.Errorf("Expected violations=3 would_open=%d shadowed=%d silenced=%d, got %d/%d/%d/%d",
+					tt.wouldOpen, tt.shadowed, tt.silenced, got.Violations, got.WouldOpen, got.Shadowed, got.Silenced)
+			}
+		})
+	}
+}
+
+func TestMatchSilences_CompositeRule(t *testing.T) {
+	at := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	rule := decisionRule("composite", 1, 100)
+	rule.MetricTypes = []string{string(db.MetricTypeLATENCYMS), string(db.MetricTypePACKETLOSS)}
+	silences := []db.Silence{{
+		MetricType: db.NullMetricType{MetricType: db.MetricTypePACKETLOSS, Valid: true},
+		StartsAt:   at.Add(-time.Hour),
+		EndsAt:     pgtype.Timestamptz{Time: at.Add(time.Hour), Valid: true},
+	}}
+
+	// The silence targets the second metric type of the composite rule
+	s, err := matchSilences(silences, at)(context.Background(), &rule, "S1", &Violation{})
+	if err != nil || s == nil {
+		t.Errorf("Expected the PACKET_LOSS silence to match the composite rule, got %v, %v", s, err)
+	}
+
+	rule.MetricTypes = []string{string(db.MetricTypeLATENCYMS)}
+	if s, _ := matchSilences(silences, at)(context.Background(), &rule, "S1", &Violation{}); s != nil {
+		t.Errorf("Expected the PACKET_LOSS silence not to match a LATENCY_MS rule, got %v", s)
+	}
+}
@@ -1,15 +1,22 @@
 package rule
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/pkg/pgutil"
 )
 
 // Evaluate checks if a metric value violates the rule
 func Evaluate(rule *db.QualityRule, value float64) bool {
-	threshold := pgutil.NumericToFloat64(rule.Threshold)
+	return compare(rule.Operator, value, pgutil.NumericToFloat64(rule.Threshold))
+}
 
-	switch rule.Operator {
+// compare applies a rule operator to a value and a threshold
+func compare(operator db.RuleOperator, value, threshold float64) bool {
+	switch operator {
 	case db.RuleOperatorValue0: // >
 		return value > threshold
 	case db.RuleOperatorValue1: // >=
@@ -26,3 +33,153 @@ func Evaluate(rule *db.QualityRule, value float64) bool {
 		return false
 	}
 }
+
+// isValidMetricType reports whether t is a known metric type
+func isValidMetricType(t db.MetricType) bool {
+	switch t {
+	case db.MetricTypeLATENCYMS, db.MetricTypePACKETLOSS, db.MetricTypeERRORRATE, db.MetricTypeBUFFERRATIO:
+		return true
+	default:
+		return false
+	}
+}
+
+// isValidOperator reports whether op is a known rule operator
+func isValidOperator(op db.RuleOperator) bool {
+	switch op {
+	case db.RuleOperatorValue0, db.RuleOperatorValue1, db.RuleOperatorValue2,
+		db.RuleOperatorValue3, db.RuleOperatorValue4, db.RuleOperatorValue5:
+		return true
+	default:
+		return false
+	}
+}
+
+// Violation describes why a rule fired for a metric
+type Violation struct {
+	Value   float64
+	Message string
+	// Severity is set by multi-tier rules; empty means the rule's own severity
+	Severity db.IncidentSeverity
+	// MetricIDs lists the metrics that contributed, for rules that combine several series
+	MetricIDs []uuid.UUID
+}
+
+// Check evaluates a rule against a series whose last sample is the metric being processed.
+// It returns nil when the rule is not violated.
+func Check(rule *db.QualityRule, samples []Sample) *Violation {
+	return checkTiers(rule, tiersOf(rule), samples)
+}
+
+// checkTiers is Check with the rule's tiers already decoded
+func checkTiers(rule *db.QualityRule, tiers []Tier, samples []Sample) *Violation {
+	v := check(rule, samples)
+	if v != nil && len(tiers) > 0 {
+		applyTier(rule, tiers, v)
+	}
+	return v
+}
+
+func check(rule *db.QualityRule, samples []Sample) *Violation {
+	if len(samples) == 0 {
+		return nil
+	}
+	current := samples[len(samples)-1]
+	threshold := pgutil.NumericToFloat64(rule.Threshold)
+
+	switch {
+	case isForecastRule(rule):
+		return checkForecast(rule, samples)
+
+	case isChangeRule(rule):
+		return checkChange(rule, samples)
+
+	case rule.Aggregation != "" && rule.Aggregation != db.RuleAggregationNONE:
+		window := time.Duration(rule.WindowSeconds) * time.Second
+		inWindow := samplesInWindow(samples, window)
+		value, ok := Aggregate(rule.Aggregation, inWindow)
+		if !ok || !Evaluate(rule, value) {
+			return nil
+		}
+		return &Violation{
+			Value: value,
+			Message: fmt.Sprintf("%s %s over the last %s exceeded threshold: %.2f (threshold: %.2f, operator: %s, samples: %d)",
+				rule.Aggregation, rule.MetricType, window, value, threshold, rule.Operator, len(inWindow)),
+		}
+
+	case rule.ForSeconds > 0:
+		duration, ok := violatedFor(rule, samples)
+		if !ok || duration < time.Duration(rule.ForSeconds)*time.Second {
+			return nil
+		}
+		return &Violation{
+			Value: current.Value,
+			Message: fmt.Sprintf("%s threshold exceeded for %s: %.2f (threshold: %.2f, operator: %s)",
+				rule.MetricType, duration, current.Value, threshold, rule.Operator),
+		}
+
+	case rule.WindowSamples > 0:
+		violations, ok := countViolations(rule, samples)
+		if !ok || violations < requiredViolations(rule) {
+			return nil
+		}
+		return &Violation{
+			Value: current.Value,
+			Message: fmt.Sprintf("%s threshold exceeded in %d of the last %d samples: %.2f (threshold: %.2f, operator: %s)",
+				rule.MetricType, violations, rule.WindowSamples, current.Value, threshold, rule.Operator),
+		}
+
+	default:
+		if !Evaluate(rule, current.Value) {
+			return nil
+		}
+		return &Violation{
+			Value: current.Value,
+			Message: fmt.Sprintf("%s threshold exceeded: %.2f (threshold: %.2f, operator: %s)",
+				rule.MetricType, current.Value, threshold, rule.Operator),
+		}
+	}
+}
+
+// violatedFor returns how long the rule has been continuously violated, measured from the
+// first sample of the current run of violating samples to the latest sample.
+func violatedFor(rule *db.QualityRule, samples []Sample) (time.Duration, bool) {
+	last := samples[len(samples)-1]
+	if !Evaluate(rule, last.Value) {
+		return 0, false
+	}
+
+	start := last.RecordedAt
+	for i := len(samples) - 2; i >= 0; i-- {
+		if !Evaluate(rule, samples[i].Value) {
+			break
+		}
+		start = samples[i].RecordedAt
+	}
+	return last.RecordedAt.Sub(start), true
+}
+
+// countViolations counts violating samples among the last window_samples samples.
+// It reports false until the series has enough samples to fill the window.
+func countViolations(rule *db.QualityRule, samples []Sample) (int32, bool) {
+	window := int(rule.WindowSamples)
+	if len(samples) < window {
+		return 0, false
+	}
+
+	var count int32
+	for _, s := range samples[len(samples)-window:] {
+		if Evaluate(rule, s.Value) {
+			count++
+		}
+	}
+	return count, true
+}
+
+// requiredViolations returns M for an "M of the last K samples" rule; zero means all K
+func requiredViolations(rule *db.QualityRule) int32 {
+	if rule.MinViolations > 0 {
+		return rule.MinViolations
+	}
+	return rule.WindowSamples
+}
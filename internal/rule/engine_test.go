@@ -2,7 +2,9 @@ package rule
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/pkg/pgutil"
 )
@@ -239,3 +241,106 @@ func BenchmarkEvaluate(b *testing.B) {
 		Evaluate(rule, 150.0)
 	}
 }
+
+// series builds a sample series one minute apart, oldest first
+func series(start time.Time, values ...float64) []Sample {
+	samples := make([]Sample, len(values))
+	for i, v := range values {
+		samples[i] = Sample{
+			MetricID:   uuid.New(),
+			Value:      v,
+			RecordedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+	}
+	return samples
+}
+
+func TestCheck_SingleValue(t *testing.T) {
+	rule := &db.QualityRule{
+		MetricType: db.MetricTypeLATENCYMS,
+		Operator:   db.RuleOperatorValue0,
+		Threshold:  pgutil.Float64ToNumeric(150.0),
+	}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	if v := Check(rule, series(start, 100, 180)); v == nil || v.Value != 180 {
+		t.Errorf("Expected violation with value 180, got %+v", v)
+	}
+	if v := Check(rule, series(start, 180, 100)); v != nil {
+		t.Errorf("Expected no violation for a recovered sample, got %+v", v)
+	}
+	if v := Check(rule, nil); v != nil {
+		t.Errorf("Expected no violation for an empty series, got %+v", v)
+	}
+}
+
+func TestCheck_ForDuration(t *testing.T) {
+	rule := &db.QualityRule{
+		MetricType: db.MetricTypeLATENCYMS,
+		Operator:   db.RuleOperatorValue0,
+		Threshold:  pgutil.Float64ToNumeric(150.0),
+		ForSeconds: 180,
+	}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		values   []float64
+		expected bool
+	}{
+		{"single spike", []float64{100, 100, 100, 200}, false},
+		{"violated for two minutes", []float64{100, 200, 200, 200}, false},
+		{"violated for three minutes", []float64{100, 200, 200, 200, 200}, true},
+		{"run interrupted", []float64{200, 200, 100, 200, 200}, false},
+		{"latest sample recovered", []float64{200, 200, 200, 200, 100}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Check(rule, series(start, tt.values...))
+			if (v != nil) != tt.expected {
+				t.Errorf("Check() violation = %v, want %v", v != nil, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheck_MOfK(t *testing.T) {
+	rule := &db.QualityRule{
+		MetricType:    db.MetricTypePACKETLOSS,
+		Operator:      db.RuleOperatorValue0,
+		Threshold:     pgutil.Float64ToNumeric(1.5),
+		WindowSamples: 5,
+		MinViolations: 3,
+	}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		values   []float64
+		expected bool
+	}{
+		{"window not full", []float64{2, 2, 2, 2}, false},
+		{"two of five", []float64{2, 1, 1, 2, 1}, false},
+		{"three of five", []float64{2, 1, 2, 1, 2}, true},
+		{"old violations outside window", []float64{2, 2, 2, 1, 1, 1, 1, 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Check(rule, series(start, tt.values...))
+			if (v != nil) != tt.expected {
+				t.Errorf("Check() violation = %v, want %v", v != nil, tt.expected)
+			}
+		})
+	}
+
+	// Without min_violations every sample in the window must violate
+	rule.MinViolations = 0
+	if v := Check(rule, series(start, 2, 2, 2, 2, 1)); v != nil {
+		t.Errorf("Expected no violation when one of five samples is healthy")
+	}
+	if v := Check(rule, series(start, 2, 2, 2, 2, 2)); v == nil {
+		t.Errorf("Expected violation when all five samples violate")
+	}
+}
@@ -0,0 +1,70 @@
+package rule
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// evaluationPruneInterval is how often the worker deletes evaluations past their retention
+const evaluationPruneInterval = 10 * time.Minute
+
+// EnableEvaluations makes the worker record, for every metric it processes, how each
+// active rule was evaluated. Evaluations older than retention are pruned.
+func (w *Worker) EnableEvaluations(retention time.Duration) {
+	w.evaluationRetention = retention
+}
+
+// recordEvaluation stores the outcome of a rule for a metric when evaluations are enabled
+// and returns its id. value is the value the rule compared and violation is set when the
+// rule matched. Failures are logged so they never block incident handling.
+func (w *Worker) recordEvaluation(ctx context.Context, rule *db.QualityRule, metricID uuid.UUID, outcome db.EvaluationOutcome, reason string, value float64, violation *Violation) *int64 {
+	if w.evaluationRetention <= 0 {
+		return nil
+	}
+
+	params := db.CreateRuleEvaluationParams{
+		MetricID: metricID,
+		RuleID:   rule.ID,
+		Outcome:  outcome,
+		Value:    &value,
+	}
+	if rule.Revision > 0 {
+		params.RuleRevision = &rule.Revision
+	}
+	if reason != "" {
+		params.Reason = &reason
+	}
+	if violation != nil {
+		params.Value = &violation.Value
+		params.Message = &violation.Message
+	}
+
+	e, err := w.ruleRepo.RecordEvaluation(ctx, params)
+	if err != nil {
+		slog.Error("RuleWorker: failed to record evaluation", "rule_id", rule.ID, "metric_id", metricID, "error", err)
+		return nil
+	}
+	return &e.ID
+}
+
+// pruneEvaluations deletes evaluations past their retention, at most once per
+// evaluationPruneInterval
+func (w *Worker) pruneEvaluations(ctx context.Context) {
+	if w.evaluationRetention <= 0 || time.Since(w.evaluationsPrunedAt) < evaluationPruneInterval {
+		return
+	}
+	w.evaluationsPrunedAt = time.Now()
+
+	deleted, err := w.ruleRepo.PruneEvaluations(ctx, time.Now().Add(-w.evaluationRetention))
+	if err != nil {
+		slog.Error("RuleWorker: failed to prune evaluations", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Debug("RuleWorker: pruned evaluations", "deleted", deleted)
+	}
+}
@@ -0,0 +1,120 @@
+package rule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+)
+
+func TestWorker_Evaluations(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "latency", Threshold: 150, IsActive: true})
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "outage", Threshold: 500, IsActive: true})
+
+	// Nothing is recorded until evaluations are enabled
+	wt.record(t, 100)
+	metrics, err := wt.metricRepo.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list metrics: %v", err)
+	}
+	evaluations, err := wt.queries.ListMetricEvaluations(ctx, metrics[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list evaluations: %v", err)
+	}
+	if len(evaluations) != 0 {
+		t.Fatalf("Expected no evaluations while disabled, got %d", len(evaluations))
+	}
+
+	wt.worker.EnableEvaluations(time.Hour)
+	wt.record(t, 200)
+	metrics, err = wt.metricRepo.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list metrics: %v", err)
+	}
+	evaluations, err = wt.queries.ListMetricEvaluations(ctx, metrics[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list evaluations: %v", err)
+	}
+	outcomes := make(map[string]db.ListMetricEvaluationsRow)
+	for _, e := range evaluations {
+		outcomes[e.RuleID] = e
+	}
+	if len(outcomes) != 2 || outcomes["latency"].Outcome != db.EvaluationOutcomeMATCHED || outcomes["outage"].Outcome != db.EvaluationOutcomeNOTMATCHED {
+		t.Fatalf("Expected latency MATCHED and outage NOT_MATCHED, got %+v", evaluations)
+	}
+
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	matched := outcomes["latency"]
+	if incidents[0].EvaluationID == nil || *incidents[0].EvaluationID != matched.ID {
+		t.Errorf("Expected the incident to link evaluation %d, got %v", matched.ID, incidents[0].EvaluationID)
+	}
+	if matched.IncidentID == nil || *matched.IncidentID != incidents[0].ID {
+		t.Errorf("Expected the evaluation to list incident %s, got %v", incidents[0].ID, matched.IncidentID)
+	}
+
+	// Evaluations past their retention are pruned, except the one that opened the incident
+	wt.worker.EnableEvaluations(time.Nanosecond)
+	wt.worker.pruneEvaluations(ctx)
+	evaluations, err = wt.queries.ListMetricEvaluations(ctx, metrics[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list evaluations: %v", err)
+	}
+	if len(evaluations) != 1 || evaluations[0].ID != matched.ID {
+		t.Errorf("Expected only evaluation %d to be kept, got %+v", matched.ID, evaluations)
+	}
+	if inc, _ := wt.incidentRepo.Get(ctx, incidents[0].ID); inc.EvaluationID == nil || *inc.EvaluationID != matched.ID {
+		t.Errorf("Expected the incident to keep evaluation %d, got %v", matched.ID, inc.EvaluationID)
+	}
+}
+
+func TestWorker_EvaluationOutcomes(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{
+		ID:              "latency",
+		Threshold:       150,
+		Action:          ActionThrottle,
+		CooldownSeconds: 3600,
+		IsActive:        true,
+	})
+	wt.worker.EnableEvaluations(time.Hour)
+
+	// The first violation opens an incident, the second is throttled
+	wt.record(t, 200, 210)
+	metrics, err := wt.metricRepo.List(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to list metrics: %v", err)
+	}
+
+	want := map[float64]db.EvaluationOutcome{
+		200: db.EvaluationOutcomeMATCHED,
+		210: db.EvaluationOutcomeSUPPRESSED,
+	}
+	for _, m := range metrics {
+		evaluations, err := wt.queries.ListMetricEvaluations(ctx, m.ID)
+		if err != nil {
+			t.Fatalf("Failed to list evaluations: %v", err)
+		}
+		if len(evaluations) != 1 {
+			t.Fatalf("Expected 1 evaluation for metric %s, got %d", m.ID, len(evaluations))
+		}
+		e := evaluations[0]
+		if e.Value == nil || want[*e.Value] != e.Outcome {
+			t.Errorf("Unexpected evaluation %+v", e)
+		}
+		if e.Outcome == db.EvaluationOutcomeSUPPRESSED && (e.Reason == nil || *e.Reason != "throttled") {
+			t.Errorf("Expected throttled reason, got %v", e.Reason)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// evaluator checks rules against the series in a SeriesStore. The worker and backtests
+// share it so that a replayed rule fires exactly when the live rule would.
+type evaluator struct {
+	series    *SeriesStore
+	baselines *BaselineStore
+}
+
+func newEvaluator(series *SeriesStore, baselines *BaselineStore) *evaluator {
+	return &evaluator{
+		series:    series,
+		baselines: baselines,
+	}
+}
+
+// evaluate checks a rule against the series of the metric being processed. Composite
+// rules also look up the latest sample of every other metric type they depend on.
+func (e *evaluator) evaluate(ctx context.Context, compiled *CompiledRule, serviceID string, samples []Sample) (*Violation, error) {
+	rule := &compiled.QualityRule
+	if rule.AbsenceSeconds > 0 {
+		// Absence rules fire when samples stop arriving; the AbsenceWorker checks them
+		return nil, nil
+	}
+	if compiled.err != nil {
+		return nil, compiled.err
+	}
+	if compiled.expression != nil {
+		return e.evaluateExpression(ctx, rule, compiled.expression, serviceID, samples)
+	}
+	if rule.AnomalyDeviations.Valid {
+		current := samples[len(samples)-1]
+		baseline, _, err := anomalyBaseline(ctx, e.baselines, rule, SeriesKey{ServiceID: serviceID, MetricType: rule.MetricType}, current.RecordedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load baseline: %w", err)
+		}
+		return CheckAnomaly(rule, baseline, samples), nil
+	}
+
+	cond := compiled.condition
+	if cond == nil {
+		return checkTiers(rule, compiled.tiers, samples), nil
+	}
+
+	current := samples[len(samples)-1]
+	latest := make(map[db.MetricType]Sample, len(rule.MetricTypes))
+	for _, mt := range rule.MetricTypes {
+		sample, ok, err := e.series.Latest(ctx, SeriesKey{ServiceID: serviceID, MetricType: db.MetricType(mt)}, current.RecordedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load series history: %w", err)
+		}
+		if ok {
+			latest[db.MetricType(mt)] = sample
+		}
+	}
+	return CheckComposite(rule, cond, latest, current.RecordedAt), nil
+}
+
+// evaluateExpression checks an expression rule. Other metric types the expression reads
+// must have a sample within the rule's freshness; otherwise the rule does not fire.
+func (e *evaluator) evaluateExpression(ctx context.Context, rule *db.QualityRule, expr *Expression, serviceID string, samples []Sample) (*Violation, error) {
+	current := samples[len(samples)-1]
+	cutoff := current.RecordedAt.Add(-ruleFreshness(rule))
+	latest := make(map[db.MetricType]Sample)
+	for _, mt := range expr.MetricTypes() {
+		if mt == rule.MetricType {
+			continue
+		}
+		sample, ok, err := e.series.Latest(ctx, SeriesKey{ServiceID: serviceID, MetricType: mt}, current.RecordedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load series history: %w", err)
+		}
+		if ok && !sample.RecordedAt.Before(cutoff) {
+			latest[mt] = sample
+		}
+	}
+
+	violation, err := CheckExpression(rule, expr, serviceID, samples, latest)
+	if errors.Is(err, ErrExpressionNoData) {
+		slog.Debug("rule expression skipped", "rule_id", rule.ID, "service_id", serviceID, "reason", err)
+		return nil, nil
+	}
+	return violation, err
+}
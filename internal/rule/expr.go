@@ -0,0 +1,349 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// Expression rules replace operator and threshold with a condition written in a small
+// expression language, e.g.
+//
+//	value >= 5 && value <= 10
+//	value > 1.5 * avg_over(3600)
+//	LATENCY_MS > 150 && PACKET_LOSS > 1 && service != "S3"
+//	between(hour, 8, 20) && weekday != 0
+//
+// Expressions can only read the evaluation environment and call the built-in
+// functions below, so evaluating one has no side effects and always terminates.
+type Expression struct {
+	source      string
+	root        exprNode
+	metricTypes []db.MetricType
+}
+
+// ExpressionEnv is what an expression is evaluated against
+type ExpressionEnv struct {
+	Value      float64
+	ServiceID  string
+	MetricType db.MetricType
+	Time       time.Time
+	// Samples is the series of the rule's metric type, oldest first
+	Samples []Sample
+	// Latest holds the latest fresh sample of the other metric types the expression uses
+	Latest map[db.MetricType]Sample
+}
+
+// ErrExpressionNoData is returned when an expression uses a metric type without a recent sample
+var ErrExpressionNoData = errors.New("no recent sample")
+
+type exprType int
+
+const (
+	typeNumber exprType = iota
+	typeBool
+	typeString
+)
+
+func (t exprType) String() string {
+	switch t {
+	case typeBool:
+		return "condition"
+	case typeString:
+		return "string"
+	default:
+		return "number"
+	}
+}
+
+type exprVariable struct {
+	t   exprType
+	get func(env *ExpressionEnv) any
+}
+
+// exprVariables are the identifiers available to expressions. Time values are UTC.
+var exprVariables = map[string]exprVariable{
+	"value":       {typeNumber, func(env *ExpressionEnv) any { return env.Value }},
+	"service":     {typeString, func(env *ExpressionEnv) any { return env.ServiceID }},
+	"metric_type": {typeString, func(env *ExpressionEnv) any { return string(env.MetricType) }},
+	"hour":        {typeNumber, func(env *ExpressionEnv) any { return float64(env.Time.UTC().Hour()) }},
+	"minute":      {typeNumber, func(env *ExpressionEnv) any { return float64(env.Time.UTC().Minute()) }},
+	// weekday is 0 for Sunday through 6 for Saturday
+	"weekday": {typeNumber, func(env *ExpressionEnv) any { return float64(env.Time.UTC().Weekday()) }},
+}
+
+type exprFunction struct {
+	args   []exprType
+	result exprType
+	// window functions aggregate the rule's series over their argument in seconds
+	window db.RuleAggregation
+	call   func(args []float64) any
+}
+
+var (
+	numberArg   = []exprType{typeNumber}
+	numberArgs2 = []exprType{typeNumber, typeNumber}
+	numberArgs3 = []exprType{typeNumber, typeNumber, typeNumber}
+)
+
+var exprFunctions = map[string]*exprFunction{
+	"abs": {args: numberArg, result: typeNumber, call: func(a []float64) any { return math.Abs(a[0]) }},
+	"min": {args: numberArgs2, result: typeNumber, call: func(a []float64) any { return math.Min(a[0], a[1]) }},
+	"max": {args: numberArgs2, result: typeNumber, call: func(a []float64) any { return math.Max(a[0], a[1]) }},
+	// between is inclusive on both ends
+	"between":  {args: numberArgs3, result: typeBool, call: func(a []float64) any { return a[0] >= a[1] && a[0] <= a[2] }},
+	"avg_over": {args: numberArg, result: typeNumber, window: db.RuleAggregationAVG},
+	"min_over": {args: numberArg, result: typeNumber, window: db.RuleAggregationMIN},
+	"max_over": {args: numberArg, result: typeNumber, window: db.RuleAggregationMAX},
+	"p50_over": {args: numberArg, result: typeNumber, window: db.RuleAggregationP50},
+	"p95_over": {args: numberArg, result: typeNumber, window: db.RuleAggregationP95},
+	"p99_over": {args: numberArg, result: typeNumber, window: db.RuleAggregationP99},
+}
+
+type exprNode interface {
+	typ() exprType
+	pos() int
+	eval(env *ExpressionEnv) (any, error)
+}
+
+type numberLit struct {
+	at int
+	v  float64
+}
+
+type stringLit struct {
+	at int
+	v  string
+}
+
+type boolLit struct {
+	at int
+	v  bool
+}
+
+type varNode struct {
+	at   int
+	name string
+	v    exprVariable
+}
+
+// metricNode reads the latest value of another metric type of the service
+type metricNode struct {
+	at         int
+	metricType db.MetricType
+}
+
+type unaryNode struct {
+	at int
+	op string
+	x  exprNode
+}
+
+type binaryNode struct {
+	at          int
+	op          string
+	t           exprType
+	left, right exprNode
+}
+
+type callNode struct {
+	at     int
+	name   string
+	fn     *exprFunction
+	args   []exprNode
+	window time.Duration
+}
+
+func (n *numberLit) typ() exprType  { return typeNumber }
+func (n *stringLit) typ() exprType  { return typeString }
+func (n *boolLit) typ() exprType    { return typeBool }
+func (n *varNode) typ() exprType    { return n.v.t }
+func (n *metricNode) typ() exprType { return typeNumber }
+func (n *unaryNode) typ() exprType  { return n.x.typ() }
+func (n *binaryNode) typ() exprType { return n.t }
+func (n *callNode) typ() exprType   { return n.fn.result }
+
+func (n *numberLit) pos() int  { return n.at }
+func (n *stringLit) pos() int  { return n.at }
+func (n *boolLit) pos() int    { return n.at }
+func (n *varNode) pos() int    { return n.at }
+func (n *metricNode) pos() int { return n.at }
+func (n *unaryNode) pos() int  { return n.at }
+func (n *binaryNode) pos() int { return n.left.pos() }
+func (n *callNode) pos() int   { return n.at }
+
+func (n *numberLit) eval(*ExpressionEnv) (any, error) { return n.v, nil }
+func (n *stringLit) eval(*ExpressionEnv) (any, error) { return n.v, nil }
+func (n *boolLit) eval(*ExpressionEnv) (any, error)   { return n.v, nil }
+
+func (n *varNode) eval(env *ExpressionEnv) (any, error) {
+	return n.v.get(env), nil
+}
+
+func (n *metricNode) eval(env *ExpressionEnv) (any, error) {
+	if n.metricType == env.MetricType {
+		return env.Value, nil
+	}
+	s, ok := env.Latest[n.metricType]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", n.metricType, ErrExpressionNoData)
+	}
+	return s.Value, nil
+}
+
+func (n *unaryNode) eval(env *ExpressionEnv) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !x.(bool), nil
+	}
+	return -x.(float64), nil
+}
+
+func (n *binaryNode) eval(env *ExpressionEnv) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || short-circuit, so "service == \"S1\" && PACKET_LOSS > 1" does not need PACKET_LOSS for other services
+	switch n.op {
+	case "&&":
+		if !left.(bool) {
+			return false, nil
+		}
+		return n.right.eval(env)
+	case "||":
+		if left.(bool) {
+			return true, nil
+		}
+		return n.right.eval(env)
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+
+	l, r := left.(float64), right.(float64)
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, exprErrorf(n.at, "division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, exprErrorf(n.at, "division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+	return nil, exprErrorf(n.at, "unknown operator %s", n.op)
+}
+
+func (n *callNode) eval(env *ExpressionEnv) (any, error) {
+	if n.fn.window != "" {
+		value, ok := Aggregate(n.fn.window, samplesInWindow(env.Samples, n.window))
+		if !ok {
+			return nil, fmt.Errorf("%s(): %w", n.name, ErrExpressionNoData)
+		}
+		return value, nil
+	}
+
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v.(float64)
+	}
+	return n.fn.call(args), nil
+}
+
+func (e *Expression) addMetricType(mt db.MetricType) {
+	for _, existing := range e.metricTypes {
+		if existing == mt {
+			return
+		}
+	}
+	e.metricTypes = append(e.metricTypes, mt)
+}
+
+// MetricTypes returns the metric types the expression reads by name, in order of appearance
+func (e *Expression) MetricTypes() []db.MetricType {
+	return e.metricTypes
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression. Errors wrap ErrExpressionNoData when a metric type or
+// window the expression reads has no samples.
+func (e *Expression) Eval(env ExpressionEnv) (bool, error) {
+	v, err := e.root.eval(&env)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// CheckExpression evaluates an expression rule for the latest sample of a series.
+// latest holds the latest fresh samples of the other metric types the expression reads.
+func CheckExpression(rule *db.QualityRule, expr *Expression, serviceID string, samples []Sample, latest map[db.MetricType]Sample) (*Violation, error) {
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	current := samples[len(samples)-1]
+
+	ok, err := expr.Eval(ExpressionEnv{
+		Value:      current.Value,
+		ServiceID:  serviceID,
+		MetricType: rule.MetricType,
+		Time:       current.RecordedAt,
+		Samples:    samples,
+		Latest:     latest,
+	})
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	metricIDs := []uuid.UUID{current.MetricID}
+	for _, mt := range expr.MetricTypes() {
+		if s, ok := latest[mt]; ok && mt != rule.MetricType {
+			metricIDs = append(metricIDs, s.MetricID)
+		}
+	}
+	return &Violation{
+		Value:     current.Value,
+		Message:   fmt.Sprintf("%s expression matched: %s (value: %.2f)", rule.MetricType, expr, current.Value),
+		MetricIDs: metricIDs,
+	}, nil
+}
@@ -0,0 +1,432 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// maxExpressionLength bounds the source of an expression
+const maxExpressionLength = 1024
+
+// ExpressionError points at the offending part of an expression. Pos is the 1-based
+// character position in the source.
+type ExpressionError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("expression: position %d: %s", e.Pos, e.Msg)
+}
+
+func exprErrorf(pos int, format string, args ...any) error {
+	return &ExpressionError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators are matched longest first
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ","}
+
+func tokenize(src string) ([]token, error) {
+	runes := []rune(src)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: pos})
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: pos})
+
+		case r == '"':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, exprErrorf(pos, "unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[start:i]), pos: pos})
+
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: pos})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, exprErrorf(pos, "unexpected character %q", r)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// exprParser is a recursive descent parser that type checks while it builds the tree.
+// Precedence from lowest: ||, &&, comparisons, + -, * / %, unary ! -.
+type exprParser struct {
+	tokens []token
+	i      int
+	expr   *Expression
+}
+
+// CompileExpression parses and type checks an expression. The expression must evaluate to a boolean.
+func CompileExpression(src string) (*Expression, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, exprErrorf(1, "expression is empty")
+	}
+	if len(src) > maxExpressionLength {
+		return nil, exprErrorf(maxExpressionLength+1, "expression must not be longer than %d characters", maxExpressionLength)
+	}
+
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &Expression{source: src}
+	p := &exprParser{tokens: tokens, expr: expr}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, exprErrorf(tok.pos, "unexpected %q", tok.text)
+	}
+	if root.typ() != typeBool {
+		return nil, exprErrorf(root.pos(), "expression must be a condition, got %s", root.typ())
+	}
+	expr.root = root
+	return expr, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *exprParser) acceptOp(ops ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) expectOp(op string) (token, error) {
+	tok, ok := p.acceptOp(op)
+	if !ok {
+		return tok, exprErrorf(tok.pos, "expected %q, got %s", op, describeToken(tok))
+	}
+	return tok, nil
+}
+
+func describeToken(tok token) string {
+	if tok.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(tok.text)
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newBinary(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newBinary(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseComparison does not chain: "1 < value < 5" is rejected, use between() instead
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if next, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">="); ok {
+		return nil, exprErrorf(next.pos, "comparisons cannot be chained, use between() or &&")
+	}
+	return newBinary(tok, left, right)
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newBinary(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newBinary(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	tok, ok := p.acceptOp("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	want := typeNumber
+	if tok.text == "!" {
+		want = typeBool
+	}
+	if x.typ() != want {
+		return nil, exprErrorf(tok.pos, "operator %s needs a %s, got %s", tok.text, want, x.typ())
+	}
+	return &unaryNode{at: tok.pos, op: tok.text, x: x}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, exprErrorf(tok.pos, "invalid number %q", tok.text)
+		}
+		return &numberLit{at: tok.pos, v: v}, nil
+
+	case tokenString:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return nil, exprErrorf(tok.pos, "invalid string %s", tok.text)
+		}
+		return &stringLit{at: tok.pos, v: s}, nil
+
+	case tokenIdent:
+		if _, ok := p.acceptOp("("); ok {
+			return p.parseCall(tok)
+		}
+		return p.ident(tok)
+
+	case tokenOp:
+		if tok.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, exprErrorf(tok.pos, "unexpected %s", describeToken(tok))
+}
+
+func (p *exprParser) ident(tok token) (exprNode, error) {
+	switch tok.text {
+	case "true", "false":
+		return &boolLit{at: tok.pos, v: tok.text == "true"}, nil
+	}
+	if v, ok := exprVariables[tok.text]; ok {
+		return &varNode{at: tok.pos, name: tok.text, v: v}, nil
+	}
+	if mt := db.MetricType(tok.text); isValidMetricType(mt) {
+		p.expr.addMetricType(mt)
+		return &metricNode{at: tok.pos, metricType: mt}, nil
+	}
+	return nil, exprErrorf(tok.pos, "unknown identifier %q", tok.text)
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	fn, ok := exprFunctions[name.text]
+	if !ok {
+		return nil, exprErrorf(name.pos, "unknown function %q", name.text)
+	}
+
+	var args []exprNode
+	if _, ok := p.acceptOp(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.acceptOp(","); !ok {
+				break
+			}
+		}
+		if _, err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(args) != len(fn.args) {
+		return nil, exprErrorf(name.pos, "%s() takes %d argument(s), got %d", name.text, len(fn.args), len(args))
+	}
+	for i, arg := range args {
+		if arg.typ() != fn.args[i] {
+			return nil, exprErrorf(arg.pos(), "argument %d of %s() must be a %s, got %s", i+1, name.text, fn.args[i], arg.typ())
+		}
+	}
+
+	call := &callNode{at: name.pos, name: name.text, fn: fn, args: args}
+	if fn.window != "" {
+		// Window functions take a constant number of seconds so the rule's history needs are known up front
+		lit, ok := args[0].(*numberLit)
+		if !ok {
+			return nil, exprErrorf(args[0].pos(), "the window of %s() must be a number of seconds", name.text)
+		}
+		window := time.Duration(lit.v * float64(time.Second))
+		if window <= 0 || window > SeriesRetention {
+			return nil, exprErrorf(args[0].pos(), "the window of %s() must be between 1 and %d seconds", name.text, int(SeriesRetention.Seconds()))
+		}
+		call.window = window
+	}
+	return call, nil
+}
+
+func newBinary(tok token, left, right exprNode) (exprNode, error) {
+	node := &binaryNode{at: tok.pos, op: tok.text, left: left, right: right}
+	switch tok.text {
+	case "&&", "||":
+		if left.typ() != typeBool || right.typ() != typeBool {
+			return nil, exprErrorf(tok.pos, "operator %s needs conditions on both sides, got %s and %s", tok.text, left.typ(), right.typ())
+		}
+		node.t = typeBool
+	case "==", "!=":
+		if left.typ() != right.typ() {
+			return nil, exprErrorf(tok.pos, "cannot compare %s with %s", left.typ(), right.typ())
+		}
+		node.t = typeBool
+	case "<", "<=", ">", ">=":
+		if left.typ() != typeNumber || right.typ() != typeNumber {
+			return nil, exprErrorf(tok.pos, "operator %s needs numbers on both sides, got %s and %s", tok.text, left.typ(), right.typ())
+		}
+		node.t = typeBool
+	default:
+		if left.typ() != typeNumber || right.typ() != typeNumber {
+			return nil, exprErrorf(tok.pos, "operator %s needs numbers on both sides, got %s and %s", tok.text, left.typ(), right.typ())
+		}
+		node.t = typeNumber
+	}
+	return node, nil
+}
@@ -0,0 +1,151 @@
+package rule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestCompileExpression_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		pos  int
+	}{
+		{"empty", "   ", 1},
+		{"unknown identifier", "valu > 5", 1},
+		{"unknown function", "value > median(60)", 9},
+		{"not a condition", "value + 1", 1},
+		{"unexpected character", "value > 5 $", 11},
+		{"unterminated string", `service == "S1`, 12},
+		{"missing operand", "value >", 8},
+		{"unbalanced parenthesis", "(value > 5", 11},
+		{"string compared with number", `service == 5`, 9},
+		{"ordering strings", `service > "S1"`, 9},
+		{"and with number", "value && true", 7},
+		{"chained comparison", "1 < value < 5", 11},
+		{"wrong argument count", "between(value, 5)", 1},
+		{"wrong argument type", `abs(service) > 1`, 5},
+		{"non-constant window", "value > avg_over(value)", 18},
+		{"window too long", "value > avg_over(86400)", 18},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileExpression(tt.src)
+			var exprErr *ExpressionError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("Expected ExpressionError, got %v", err)
+			}
+			if exprErr.Pos != tt.pos {
+				t.Errorf("Expected position %d, got %d (%v)", tt.pos, exprErr.Pos, err)
+			}
+		})
+	}
+}
+
+func TestExpression_Eval(t *testing.T) {
+	// Thursday 2026-03-12 09:30 UTC
+	at := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	env := ExpressionEnv{
+		Value:      7,
+		ServiceID:  "S1",
+		MetricType: db.MetricTypeBUFFERRATIO,
+		Time:       at,
+		Samples:    series(at.Add(-4*time.Minute), 2, 2, 4, 4, 7),
+		Latest: map[db.MetricType]Sample{
+			db.MetricTypeLATENCYMS: {Value: 180, RecordedAt: at},
+		},
+	}
+
+	tests := []struct {
+		src      string
+		expected bool
+	}{
+		{"value >= 5 && value <= 10", true},
+		{"between(value, 5, 10)", true},
+		{"between(value, 8, 10)", false},
+		{"!(value > 5)", false},
+		{"value > 1.5 * avg_over(300)", true},
+		{"value > 2 * avg_over(300)", false},
+		{"max_over(120) - min_over(120) > 2", true},
+		{"LATENCY_MS > 150 && BUFFER_RATIO > 5", true},
+		{"LATENCY_MS / 10 + value > 30", false},
+		{`service == "S1" && metric_type == "BUFFER_RATIO"`, true},
+		{`service != "S1" || value < 0`, false},
+		{"hour == 9 && minute >= 30 && weekday == 4", true},
+		{"abs(-value) == 7 && max(value, 10) == 10 && min(value, 10) == 7", true},
+		{"value % 2 == 1", true},
+		{"true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			expr, err := CompileExpression(tt.src)
+			if err != nil {
+				t.Fatalf("CompileExpression() error: %v", err)
+			}
+			got, err := expr.Eval(env)
+			if err != nil {
+				t.Fatalf("Eval() error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Eval() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpression_MissingData(t *testing.T) {
+	expr, err := CompileExpression(`service == "S1" && PACKET_LOSS > 1`)
+	if err != nil {
+		t.Fatalf("CompileExpression() error: %v", err)
+	}
+	if got := expr.MetricTypes(); len(got) != 1 || got[0] != db.MetricTypePACKETLOSS {
+		t.Errorf("Expected metric types [PACKET_LOSS], got %v", got)
+	}
+
+	if _, err := expr.Eval(ExpressionEnv{ServiceID: "S1"}); !errors.Is(err, ErrExpressionNoData) {
+		t.Errorf("Expected ErrExpressionNoData, got %v", err)
+	}
+	// && short-circuits, so other services do not need PACKET_LOSS
+	if ok, err := expr.Eval(ExpressionEnv{ServiceID: "S2"}); err != nil || ok {
+		t.Errorf("Expected false without error, got %v, %v", ok, err)
+	}
+
+	expr, _ = CompileExpression("value > 10 / (value - value)")
+	var exprErr *ExpressionError
+	if _, err := expr.Eval(ExpressionEnv{Value: 1}); !errors.As(err, &exprErr) {
+		t.Errorf("Expected division by zero error, got %v", err)
+	}
+}
+
+func TestCheckExpression(t *testing.T) {
+	rule := &db.QualityRule{MetricType: db.MetricTypeBUFFERRATIO}
+	expr, err := CompileExpression("value > 5 && LATENCY_MS > 150")
+	if err != nil {
+		t.Fatalf("CompileExpression() error: %v", err)
+	}
+
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	samples := series(start, 3, 7)
+	latency := series(start, 180)[0]
+	latest := map[db.MetricType]Sample{db.MetricTypeLATENCYMS: latency}
+
+	v, err := CheckExpression(rule, expr, "S1", samples, latest)
+	if err != nil || v == nil {
+		t.Fatalf("Expected violation, got %v (err: %v)", v, err)
+	}
+	if v.Value != 7 {
+		t.Errorf("Expected value 7, got %v", v.Value)
+	}
+	if len(v.MetricIDs) != 2 || v.MetricIDs[0] != samples[1].MetricID || v.MetricIDs[1] != latency.MetricID {
+		t.Errorf("Expected the current and LATENCY_MS samples as contributing metrics, got %v", v.MetricIDs)
+	}
+
+	if v, err := CheckExpression(rule, expr, "S1", series(start, 7, 3), latest); v != nil || err != nil {
+		t.Errorf("Expected no violation, got %v (err: %v)", v, err)
+	}
+}
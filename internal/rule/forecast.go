@@ -0,0 +1,245 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+const (
+	// MinForecastSamples is how many samples a forecast rule needs in its window
+	MinForecastSamples = 5
+	// MaxForecastHorizonSeconds is the furthest a forecast rule looks ahead
+	MaxForecastHorizonSeconds = 24 * 60 * 60
+
+	// holtAlpha and holtBeta smooth the level and the trend of Holt forecasts
+	holtAlpha = 0.5
+	holtBeta  = 0.3
+	// forecastZ is the normal quantile of the 95% prediction interval
+	forecastZ = 1.96
+)
+
+// IsValidForecastMethod reports whether m is a known forecast method
+func IsValidForecastMethod(m db.RuleForecastMethod) bool {
+	switch m {
+	case db.RuleForecastMethodNONE, db.RuleForecastMethodLINEAR, db.RuleForecastMethodHOLT:
+		return true
+	default:
+		return false
+	}
+}
+
+// isForecastRule reports whether a rule fires on the forecast of its series
+func isForecastRule(rule *db.QualityRule) bool {
+	return rule.ForecastMethod != "" && rule.ForecastMethod != db.RuleForecastMethodNONE
+}
+
+// forecastSeverity returns the severity stored for a rule: forecast rules warn of a
+// breach that has not happened yet, so they always open LOW incidents
+func forecastSeverity(method, severity string) string {
+	if forecastMethodOrDefault(method) != db.RuleForecastMethodNONE {
+		return string(db.IncidentSeverityLOW)
+	}
+	return severity
+}
+
+// validateForecast checks the forecast settings of a rule. Forecast rules compare the
+// forecast of a single series with operator and threshold, so they cannot be combined
+// with the other rule shapes.
+func validateForecast(d *RuleDefinition) error {
+	m := forecastMethodOrDefault(d.ForecastMethod)
+	switch {
+	case !IsValidForecastMethod(m):
+		return fmt.Errorf("invalid forecast_method: %s", d.ForecastMethod)
+	case m == db.RuleForecastMethodNONE && (d.ForecastWindowSeconds != 0 || d.ForecastHorizonSeconds != 0):
+		return errors.New("forecast_window_seconds and forecast_horizon_seconds require a forecast_method")
+	case m == db.RuleForecastMethodNONE:
+		return nil
+	case d.ForecastWindowSeconds <= 0 || time.Duration(d.ForecastWindowSeconds)*time.Second > SeriesRetention:
+		return fmt.Errorf("forecast_window_seconds must be greater than 0 and at most %d", int(SeriesRetention.Seconds()))
+	case d.ForecastHorizonSeconds <= 0 || d.ForecastHorizonSeconds > MaxForecastHorizonSeconds:
+		return fmt.Errorf("forecast_horizon_seconds must be greater than 0 and at most %d", MaxForecastHorizonSeconds)
+	case d.Severity != "" && db.IncidentSeverity(d.Severity) != db.IncidentSeverityLOW:
+		return errors.New("forecast rules open LOW severity incidents")
+	case len(d.Tiers) > 0 || d.Condition != nil || d.Expression != nil || d.AnomalyDeviations != nil || d.AbsenceSeconds > 0 || changeModeOrDefault(d.ChangeMode) != db.RuleChangeModeNONE:
+		return errors.New("forecast rules cannot have tiers, a condition, an expression, anomaly, absence or rate-of-change settings")
+	case d.windowed():
+		return errors.New("forecast rules cannot use aggregation, for_seconds or window_samples")
+	case d.RecoveryThreshold != nil:
+		return errors.New("forecast rules cannot use recovery_threshold")
+	}
+
+	switch db.RuleOperator(d.Operator) {
+	case db.RuleOperatorValue0, db.RuleOperatorValue1, db.RuleOperatorValue2, db.RuleOperatorValue3:
+		return nil
+	default:
+		return errors.New("forecast rules require one of the operators >, >=, < and <=")
+	}
+}
+
+// Forecast is the value a series is expected to reach at the end of a rule's horizon
+type Forecast struct {
+	Value float64
+	// Lower and Upper bound the 95% prediction interval of Value
+	Lower float64
+	Upper float64
+	At    time.Time
+	// Level is the fitted value of the latest sample and Slope the trend per second
+	Level   float64
+	Slope   float64
+	Samples int
+}
+
+// forecastOf fits the trend of the samples in a forecast rule's window and projects it
+// to the end of the horizon. It reports false until the window has MinForecastSamples
+// samples spread over time.
+func forecastOf(rule *db.QualityRule, samples []Sample) (Forecast, bool) {
+	window := samplesInWindow(samples, time.Duration(rule.ForecastWindowSeconds)*time.Second)
+	if len(window) < MinForecastSamples {
+		return Forecast{}, false
+	}
+	horizon := time.Duration(rule.ForecastHorizonSeconds) * time.Second
+
+	var f Forecast
+	var ok bool
+	if rule.ForecastMethod == db.RuleForecastMethodHOLT {
+		f, ok = holtForecast(window, horizon.Seconds())
+	} else {
+		f, ok = linearForecast(window, horizon.Seconds())
+	}
+	if !ok {
+		return Forecast{}, false
+	}
+	f.At = window[len(window)-1].RecordedAt.Add(horizon)
+	f.Samples = len(window)
+	return f, true
+}
+
+// linearForecast fits a least-squares line through the samples. The interval is the
+// prediction interval of the regression at the horizon.
+func linearForecast(samples []Sample, horizon float64) (Forecast, bool) {
+	n := float64(len(samples))
+	start := samples[0].RecordedAt
+	var meanX, meanY float64
+	for _, s := range samples {
+		meanX += s.RecordedAt.Sub(start).Seconds()
+		meanY += s.Value
+	}
+	meanX /= n
+	meanY /= n
+
+	var sxx, sxy float64
+	for _, s := range samples {
+		dx := s.RecordedAt.Sub(start).Seconds() - meanX
+		sxx += dx * dx
+		sxy += dx * (s.Value - meanY)
+	}
+	if sxx == 0 {
+		return Forecast{}, false
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for _, s := range samples {
+		r := s.Value - (intercept + slope*s.RecordedAt.Sub(start).Seconds())
+		sse += r * r
+	}
+	stddev := math.Sqrt(sse / (n - 2))
+
+	last := samples[len(samples)-1].RecordedAt.Sub(start).Seconds()
+	x := last + horizon
+	value := intercept + slope*x
+	margin := forecastZ * stddev * math.Sqrt(1+1/n+(x-meanX)*(x-meanX)/sxx)
+	return Forecast{
+		Value: value,
+		Lower: value - margin,
+		Upper: value + margin,
+		Level: intercept + slope*last,
+		Slope: slope,
+	}, true
+}
+
+// holtForecast applies Holt's linear exponential smoothing with the trend per second, so
+// irregular sampling is handled. The interval widens with the number of average sample
+// intervals in the horizon, from the deviation of the one-step-ahead errors.
+func holtForecast(samples []Sample, horizon float64) (Forecast, bool) {
+	span := samples[len(samples)-1].RecordedAt.Sub(samples[0].RecordedAt).Seconds()
+	if span <= 0 {
+		return Forecast{}, false
+	}
+
+	level := samples[0].Value
+	var trend float64
+	if dt := samples[1].RecordedAt.Sub(samples[0].RecordedAt).Seconds(); dt > 0 {
+		trend = (samples[1].Value - samples[0].Value) / dt
+	}
+
+	var sse float64
+	var errs int
+	for i := 1; i < len(samples); i++ {
+		dt := samples[i].RecordedAt.Sub(samples[i-1].RecordedAt).Seconds()
+		predicted := level + trend*dt
+		if i > 1 {
+			sse += (samples[i].Value - predicted) * (samples[i].Value - predicted)
+			errs++
+		}
+		prev := level
+		level = holtAlpha*samples[i].Value + (1-holtAlpha)*predicted
+		if dt > 0 {
+			trend = holtBeta*(level-prev)/dt + (1-holtBeta)*trend
+		}
+	}
+	stddev := math.Sqrt(sse / float64(errs))
+
+	// Var(h) = σ²(1 + Σ_{j=1}^{h-1} α²(1+jβ)²) for h steps of the average interval
+	steps := math.Max(1, math.Ceil(horizon/(span/float64(len(samples)-1))))
+	m := steps - 1
+	sum := m + holtBeta*m*(m+1) + holtBeta*holtBeta*m*(m+1)*(2*m+1)/6
+	margin := forecastZ * stddev * math.Sqrt(1+holtAlpha*holtAlpha*sum)
+
+	value := level + trend*horizon
+	return Forecast{
+		Value: value,
+		Lower: value - margin,
+		Upper: value + margin,
+		Level: level,
+		Slope: trend,
+	}, true
+}
+
+// checkForecast evaluates a forecast rule: it fires when the forecast at the end of the
+// horizon crosses the threshold
+func checkForecast(rule *db.QualityRule, samples []Sample) *Violation {
+	f, ok := forecastOf(rule, samples)
+	if !ok || !Evaluate(rule, f.Value) {
+		return nil
+	}
+	threshold := pgutil.NumericToFloat64(rule.Threshold)
+
+	return &Violation{
+		Value: f.Value,
+		Message: fmt.Sprintf("%s predicted breach%s: forecast %.2f at %s (95%% interval %.2f to %.2f, threshold: %.2f, operator: %s, %s trend %+.4f/min over %d samples)",
+			rule.MetricType, breachETA(rule, f, threshold), f.Value, f.At.UTC().Format(time.RFC3339),
+			f.Lower, f.Upper, threshold, rule.Operator, rule.ForecastMethod, f.Slope*60, f.Samples),
+	}
+}
+
+// breachETA describes when the trend reaches the threshold
+func breachETA(rule *db.QualityRule, f Forecast, threshold float64) string {
+	if Evaluate(rule, f.Level) {
+		return " (threshold already reached)"
+	}
+	if f.Slope == 0 {
+		return ""
+	}
+	eta := time.Duration((threshold - f.Level) / f.Slope * float64(time.Second))
+	if eta < 0 {
+		return ""
+	}
+	return " in " + eta.Round(time.Second).String()
+}
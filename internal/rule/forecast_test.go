@@ -0,0 +1,221 @@
+package rule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func TestCheckForecast(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	// series returns one sample per minute with the value at each minute
+	series := func(minutes int, value func(minute int) float64) []Sample {
+		result := make([]Sample, minutes)
+		for m := range result {
+			result[m] = Sample{Value: value(m), RecordedAt: start.Add(time.Duration(m) * time.Minute)}
+		}
+		return result
+	}
+	rising := func(m int) float64 { return 100 + 2*float64(m) + float64(m%3) }
+
+	tests := []struct {
+		name      string
+		method    db.RuleForecastMethod
+		operator  db.RuleOperator
+		threshold float64
+		samples   []Sample
+		fired     bool
+		message   string
+	}{
+		{
+			name: "linear crosses within horizon", method: db.RuleForecastMethodLINEAR, operator: db.RuleOperatorValue0, threshold: 180,
+			samples: series(30, rising),
+			fired:   true, message: "LATENCY_MS predicted breach in ",
+		},
+		{
+			name: "holt crosses within horizon", method: db.RuleForecastMethodHOLT, operator: db.RuleOperatorValue0, threshold: 180,
+			samples: series(30, rising),
+			fired:   true, message: "95% interval",
+		},
+		{
+			name: "linear beyond horizon", method: db.RuleForecastMethodLINEAR, operator: db.RuleOperatorValue0, threshold: 300,
+			samples: series(30, rising),
+		},
+		{
+			name: "falling towards a lower bound", method: db.RuleForecastMethodLINEAR, operator: db.RuleOperatorValue2, threshold: 50,
+			samples: series(30, func(m int) float64 { return 120 - 2*float64(m) }),
+			fired:   true, message: "operator: <, LINEAR trend -2.0000/min over 30 samples",
+		},
+		{
+			name: "flat series", method: db.RuleForecastMethodHOLT, operator: db.RuleOperatorValue0, threshold: 150,
+			samples: series(30, func(m int) float64 { return 100 + float64(m%2) }),
+		},
+		{
+			name: "too few samples", method: db.RuleForecastMethodLINEAR, operator: db.RuleOperatorValue0, threshold: 150,
+			samples: series(MinForecastSamples-1, func(m int) float64 { return 100 + 50*float64(m) }),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &db.QualityRule{
+				MetricType:             db.MetricTypeLATENCYMS,
+				Operator:               tt.operator,
+				Threshold:              pgutil.Float64ToNumeric(tt.threshold),
+				ForecastMethod:         tt.method,
+				ForecastWindowSeconds:  3600,
+				ForecastHorizonSeconds: 1800,
+			}
+			v := Check(rule, tt.samples)
+			if (v != nil) != tt.fired {
+				t.Fatalf("Expected fired=%v, got %+v", tt.fired, v)
+			}
+			if v == nil {
+				return
+			}
+			if !strings.Contains(v.Message, tt.message) {
+				t.Errorf("Expected message to contain %q, got %q", tt.message, v.Message)
+			}
+		})
+	}
+}
+
+func TestForecastOf(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	var samples []Sample
+	for m := range 20 {
+		samples = append(samples, Sample{Value: 100 + float64(m), RecordedAt: start.Add(time.Duration(m) * time.Minute)})
+	}
+
+	for _, method := range []db.RuleForecastMethod{db.RuleForecastMethodLINEAR, db.RuleForecastMethodHOLT} {
+		rule := &db.QualityRule{ForecastMethod: method, ForecastWindowSeconds: 3600, ForecastHorizonSeconds: 600}
+		f, ok := forecastOf(rule, samples)
+		if !ok {
+			t.Fatalf("%s: expected a forecast", method)
+		}
+		// A perfect trend of +1/min reaches 129 ten minutes after the last sample
+		if f.Value < 128.5 || f.Value > 129.5 {
+			t.Errorf("%s: expected a forecast of about 129, got %.2f", method, f.Value)
+		}
+		if f.Lower > f.Value || f.Upper < f.Value {
+			t.Errorf("%s: expected the interval %.2f to %.2f to contain the forecast", method, f.Lower, f.Upper)
+		}
+		if !f.At.Equal(start.Add(29 * time.Minute)) {
+			t.Errorf("%s: expected the forecast at the end of the horizon, got %v", method, f.At)
+		}
+	}
+
+	// Samples outside the window are ignored
+	rule := &db.QualityRule{ForecastMethod: db.RuleForecastMethodLINEAR, ForecastWindowSeconds: 120, ForecastHorizonSeconds: 600}
+	if _, ok := forecastOf(rule, samples); ok {
+		t.Error("Expected no forecast with too few samples in the window")
+	}
+}
+
+func TestRecovered_Forecast(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	rule := &db.QualityRule{
+		MetricType:             db.MetricTypeLATENCYMS,
+		Operator:               db.RuleOperatorValue0,
+		Threshold:              pgutil.Float64ToNumeric(150),
+		ForecastMethod:         db.RuleForecastMethodLINEAR,
+		ForecastWindowSeconds:  600,
+		ForecastHorizonSeconds: 600,
+	}
+	var series []Sample
+	for m := range 10 {
+		series = append(series, Sample{Value: 100 + 5*float64(m), RecordedAt: start.Add(time.Duration(m) * time.Minute)})
+	}
+
+	if _, ok := Recovered(rule, series); ok {
+		t.Error("Expected a rising forecast not to be recovered")
+	}
+	for m := 10; m < 20; m++ {
+		series = append(series, Sample{Value: 140, RecordedAt: start.Add(time.Duration(m) * time.Minute)})
+	}
+	if value, ok := Recovered(rule, series); !ok {
+		t.Errorf("Expected a flat forecast to be recovered, got %.2f", value)
+	}
+}
+
+func TestValidateForecast(t *testing.T) {
+	recovery := 100.0
+	tests := []struct {
+		name    string
+		req     RuleDefinition
+		wantErr string
+	}{
+		{name: "no forecast", req: RuleDefinition{Operator: ">"}},
+		{
+			name: "linear",
+			req:  RuleDefinition{Operator: ">", ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+		},
+		{
+			name: "low severity",
+			req:  RuleDefinition{Operator: "<=", Severity: "LOW", ForecastMethod: "HOLT", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+		},
+		{
+			name:    "unknown method",
+			req:     RuleDefinition{Operator: ">", ForecastMethod: "ARIMA", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+			wantErr: "invalid forecast_method",
+		},
+		{
+			name:    "window without method",
+			req:     RuleDefinition{Operator: ">", ForecastWindowSeconds: 1800},
+			wantErr: "require a forecast_method",
+		},
+		{
+			name:    "window beyond series retention",
+			req:     RuleDefinition{Operator: ">", ForecastMethod: "LINEAR", ForecastWindowSeconds: 7 * 3600, ForecastHorizonSeconds: 600},
+			wantErr: "forecast_window_seconds",
+		},
+		{
+			name:    "missing horizon",
+			req:     RuleDefinition{Operator: ">", ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800},
+			wantErr: "forecast_horizon_seconds",
+		},
+		{
+			name:    "higher severity",
+			req:     RuleDefinition{Operator: ">", Severity: "HIGH", ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+			wantErr: "LOW severity",
+		},
+		{
+			name:    "equality operator",
+			req:     RuleDefinition{Operator: "==", ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+			wantErr: "require one of the operators",
+		},
+		{
+			name:    "with aggregation",
+			req:     RuleDefinition{Operator: ">", Aggregation: "P95", ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+			wantErr: "cannot use aggregation",
+		},
+		{
+			name:    "with tiers",
+			req:     RuleDefinition{Operator: ">", Tiers: []Tier{{Threshold: 100, Severity: "LOW"}}, ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+			wantErr: "cannot have tiers",
+		},
+		{
+			name:    "with recovery threshold",
+			req:     RuleDefinition{Operator: ">", RecoveryThreshold: &recovery, ForecastMethod: "LINEAR", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600},
+			wantErr: "recovery_threshold",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateForecast(&tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,20 @@
 package rule
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/webhook"
 	"github.com/unitythemaker/tracely/pkg/httputil"
 	"github.com/unitythemaker/tracely/pkg/pgerror"
 )
@@ -24,8 +32,34 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/rules/stats/top-triggered", h.TopTriggered)
 	mux.HandleFunc("GET /api/rules/{id}", h.Get)
 	mux.HandleFunc("POST /api/rules", h.Create)
+	mux.HandleFunc("POST /api/rules/backtest", h.Backtest)
+	mux.HandleFunc("POST /api/rules/apply", h.Apply)
+	mux.HandleFunc("GET /api/rules/export", h.Export)
+	mux.HandleFunc("GET /api/rules/lint", h.Lint)
 	mux.HandleFunc("PATCH /api/rules/{id}", h.Update)
 	mux.HandleFunc("DELETE /api/rules/{id}", h.Delete)
+	mux.HandleFunc("POST /api/rules/{id}/activate", h.Activate)
+	mux.HandleFunc("POST /api/rules/{id}/deactivate", h.Deactivate)
+	mux.HandleFunc("GET /api/rules/{id}/revisions", h.ListRevisions)
+	mux.HandleFunc("GET /api/rules/{id}/revisions/{revision}", h.GetRevision)
+	mux.HandleFunc("POST /api/rules/{id}/revisions/{revision}/rollback", h.Rollback)
+	mux.HandleFunc("POST /api/rules/{id}/submit", h.Submit)
+	mux.HandleFunc("POST /api/rules/{id}/approve", h.Approve)
+	mux.HandleFunc("POST /api/rules/{id}/reject", h.Reject)
+	mux.HandleFunc("GET /api/rules/{id}/status-changes", h.ListStatusChanges)
+	mux.HandleFunc("GET /api/rules/{id}/draft-violations", h.ListDraftViolations)
+	mux.HandleFunc("GET /api/baselines/{service_id}/{metric_type}", h.Baseline)
+	mux.HandleFunc("GET /api/evaluation-policies", h.ListEvaluationPolicies)
+	mux.HandleFunc("PUT /api/evaluation-policies/{metric_type}", h.SetEvaluationPolicy)
+	mux.HandleFunc("GET /api/rule-actions", h.ListActions)
+	mux.HandleFunc("GET /api/rule-actions/{id}", h.GetAction)
+	mux.HandleFunc("POST /api/rule-actions", h.CreateAction)
+	mux.HandleFunc("PATCH /api/rule-actions/{id}", h.UpdateAction)
+	mux.HandleFunc("DELETE /api/rule-actions/{id}", h.DeleteAction)
+	mux.HandleFunc("GET /api/change-requests", h.ListChangeRequests)
+	mux.HandleFunc("GET /api/change-requests/{id}", h.GetChangeRequest)
+	mux.HandleFunc("POST /api/change-requests/{id}/approve", h.ApproveChange)
+	mux.HandleFunc("POST /api/change-requests/{id}/reject", h.RejectChange)
 }
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
@@ -71,6 +105,20 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		isActive := isActiveStr == "true"
 		params.IsActive = &isActive
 	}
+	if serviceID := query.Get("service_id"); serviceID != "" {
+		params.ServiceID = &serviceID
+	}
+	if status := query.Get("status"); status != "" {
+		s := db.RuleStatus(status)
+		if !IsValidRuleStatus(s) {
+			httputil.BadRequest(w, fmt.Sprintf("invalid status: %s", status))
+			return
+		}
+		params.Status = &s
+	}
+	if departmentID := query.Get("department_id"); departmentID != "" {
+		params.DepartmentID = &departmentID
+	}
 	if search := query.Get("search"); search != "" {
 		params.Search = &search
 	}
@@ -97,7 +145,22 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		httputil.NotFound(w, "rule not found")
 		return
 	}
-	httputil.Success(w, ToResponse(rule))
+
+	suppressions, err := h.repo.ListSuppressions(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list rule suppressions", "error", err)
+		httputil.InternalError(w, "failed to get rule")
+		return
+	}
+
+	resp := ToResponse(rule)
+	resp.Suppressions = ToSuppressionResponseList(suppressions)
+	byReason := make(map[db.SuppressionReason]int32)
+	for _, s := range suppressions {
+		byReason[s.Reason] += int32(s.SuppressedCount)
+	}
+	resp.setSuppressedCounts(byReason)
+	httputil.Success(w, resp)
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
@@ -111,12 +174,18 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		httputil.BadRequest(w, "id is required")
 		return
 	}
-	if req.MetricType == "" {
-		httputil.BadRequest(w, "metric_type is required")
+	actions, err := h.repo.Actions(r.Context())
+	if err != nil {
+		slog.Error("failed to list rule actions", "error", err)
+		httputil.InternalError(w, "failed to create rule")
+		return
+	}
+	if err := validateDefinition(&req.RuleDefinition, actions); err != nil {
+		httputil.BadRequest(w, err.Error())
 		return
 	}
 
-	rule, err := h.repo.Create(r.Context(), req)
+	rule, err := h.repo.Create(r.Context(), req, httputil.Actor(r))
 	if err != nil {
 		if pgerror.IsUniqueViolation(err) {
 			httputil.Conflict(w, "rule with this id already exists")
@@ -126,9 +195,14 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		httputil.InternalError(w, "failed to create rule")
 		return
 	}
-	httputil.Created(w, ToResponse(rule))
+
+	resp := ToResponse(rule)
+	resp.Warnings = h.lintWarnings(r.Context(), rule)
+	httputil.Created(w, resp)
 }
 
+// Update changes the definition of a rule. Changing a published rule responds 202 with
+// the change request that waits for review.
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -136,21 +210,89 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req UpdateRuleRequest
+	var req RuleDefinition
 	if err := httputil.Decode(r, &req); err != nil {
 		httputil.BadRequest(w, "invalid request body")
 		return
 	}
-
-	rule, err := h.repo.Update(r.Context(), id, req)
+	actions, err := h.repo.Actions(r.Context())
 	if err != nil {
-		slog.Error("failed to update rule", "error", err)
+		slog.Error("failed to list rule actions", "error", err)
 		httputil.InternalError(w, "failed to update rule")
 		return
 	}
-	httputil.Success(w, ToResponse(rule))
+	if err := validateDefinition(&req, actions); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	rule, change, err := h.repo.Update(r.Context(), id, req, httputil.Actor(r))
+	if err != nil {
+		if errors.Is(err, ErrActivationChange) {
+			httputil.Conflict(w, err.Error())
+			return
+		}
+		h.changeError(w, err, "rule not found", "failed to update rule")
+		return
+	}
+	if change != nil {
+		httputil.Accepted(w, ToChangeRequestResponse(change))
+		return
+	}
+
+	resp := ToResponse(rule)
+	resp.Warnings = h.lintWarnings(r.Context(), rule)
+	httputil.Success(w, resp)
 }
 
+// Lint analyses the active rules, optionally of one metric type, for duplicates,
+// subsumed and impossible thresholds and rules without a department
+func (h *Handler) Lint(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.repo.ListActive(r.Context())
+	if err != nil {
+		slog.Error("failed to list active rules", "error", err)
+		httputil.InternalError(w, "failed to lint rules")
+		return
+	}
+
+	actions, err := h.repo.Actions(r.Context())
+	if err != nil {
+		slog.Error("failed to list rule actions", "error", err)
+		httputil.InternalError(w, "failed to lint rules")
+		return
+	}
+
+	findings := Lint(rules, actions)
+	if metricType := r.URL.Query().Get("metric_type"); metricType != "" {
+		filtered := []LintFinding{}
+		for _, f := range findings {
+			if f.MetricType == db.MetricType(metricType) {
+				filtered = append(filtered, f)
+			}
+		}
+		findings = filtered
+	}
+	httputil.Success(w, findings)
+}
+
+// lintWarnings returns the lint findings involving a saved rule. The rule is already
+// saved, so failures are logged and no warnings are returned.
+func (h *Handler) lintWarnings(ctx context.Context, rule *db.QualityRule) []LintFinding {
+	active, err := h.repo.ListActive(ctx)
+	if err != nil {
+		slog.Error("failed to lint rule", "rule_id", rule.ID, "error", err)
+		return nil
+	}
+	actions, err := h.repo.Actions(ctx)
+	if err != nil {
+		slog.Error("failed to lint rule", "rule_id", rule.ID, "error", err)
+		return nil
+	}
+	return lintWarnings(active, actions, rule)
+}
+
+// Delete removes a rule. Deleting a published rule responds 202 with the change request
+// that waits for review.
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -158,21 +300,622 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if rule exists first
-	_, err := h.repo.Get(r.Context(), id)
+	change, err := h.repo.Delete(r.Context(), id, httputil.Actor(r))
+	if err != nil {
+		h.changeError(w, err, "rule not found", "failed to delete rule")
+		return
+	}
+	if change != nil {
+		httputil.Accepted(w, ToChangeRequestResponse(change))
+		return
+	}
+	httputil.NoContent(w)
+}
+
+// Activate turns a rule on
+func (h *Handler) Activate(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true)
+}
+
+// Deactivate turns a rule off without deleting it
+func (h *Handler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false)
+}
+
+// setActive switches a rule on or off. The change to a published rule responds 202 with
+// the change request that waits for review.
+func (h *Handler) setActive(w http.ResponseWriter, r *http.Request, active bool) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing rule id")
+		return
+	}
+
+	rule, change, err := h.repo.SetActive(r.Context(), id, active, httputil.Actor(r))
+	if err != nil {
+		h.changeError(w, err, "rule not found", "failed to update rule")
+		return
+	}
+	if change != nil {
+		httputil.Accepted(w, ToChangeRequestResponse(change))
+		return
+	}
+	httputil.Success(w, ToResponse(rule))
+}
+
+// changeError responds to an error of a change that may have been requested for review
+func (h *Handler) changeError(w http.ResponseWriter, err error, notFound, failed string) {
+	switch {
+	case err == pgx.ErrNoRows:
+		httputil.NotFound(w, notFound)
+	case pgerror.IsUniqueViolation(err):
+		httputil.Conflict(w, "another change is already waiting for review")
+	case pgerror.IsForeignKeyViolation(err):
+		httputil.BadRequest(w, "department not found")
+	default:
+		slog.Error(failed, "error", err)
+		httputil.InternalError(w, failed)
+	}
+}
+
+// ListRevisions returns the revisions of a rule, newest first. Deleted rules keep their
+// revisions.
+func (h *Handler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing rule id")
+		return
+	}
+	limit, offset := pageOf(r)
+
+	revisions, total, err := h.repo.ListRevisions(r.Context(), id, int32(limit), int32(offset))
+	if err != nil {
+		slog.Error("failed to list rule revisions", "error", err)
+		httputil.InternalError(w, "failed to list rule revisions")
+		return
+	}
+	if total == 0 {
+		httputil.NotFound(w, "rule not found")
+		return
+	}
+	httputil.SuccessPaginated(w, ToRevisionResponseList(revisions), total, limit, offset)
+}
+
+func (h *Handler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	revision, err := strconv.ParseInt(r.PathValue("revision"), 10, 32)
+	if id == "" || err != nil || revision < 1 {
+		httputil.BadRequest(w, "invalid rule id or revision")
+		return
+	}
+
+	rev, err := h.repo.GetRevision(r.Context(), id, int32(revision))
 	if err != nil {
 		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "revision not found")
+			return
+		}
+		slog.Error("failed to get rule revision", "error", err)
+		httputil.InternalError(w, "failed to get rule revision")
+		return
+	}
+	httputil.Success(w, ToRevisionResponse(rev))
+}
+
+// Rollback restores a rule to the definition of an earlier revision. A deleted rule is
+// recreated. The rollback is itself recorded as a new revision. Rolling back a published
+// rule responds 202 with the change request that waits for review.
+func (h *Handler) Rollback(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	revision, err := strconv.ParseInt(r.PathValue("revision"), 10, 32)
+	if id == "" || err != nil || revision < 1 {
+		httputil.BadRequest(w, "invalid rule id or revision")
+		return
+	}
+
+	rev, err := h.repo.GetRevision(r.Context(), id, int32(revision))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "revision not found")
+			return
+		}
+		slog.Error("failed to get rule revision", "error", err)
+		httputil.InternalError(w, "failed to roll back rule")
+		return
+	}
+
+	actions, err := h.repo.Actions(r.Context())
+	if err != nil {
+		slog.Error("failed to list rule actions", "error", err)
+		httputil.InternalError(w, "failed to roll back rule")
+		return
+	}
+
+	// Validation may have tightened, or the action been removed, since the revision was written
+	def, err := DefinitionOf(rev)
+	if err == nil {
+		err = validateDefinition(&def.RuleDefinition, actions)
+	}
+	if err != nil {
+		httputil.Conflict(w, fmt.Sprintf("revision %d cannot be restored: %v", revision, err))
+		return
+	}
+
+	rule, change, err := h.repo.Rollback(r.Context(), id, int32(revision), httputil.Actor(r))
+	if err != nil {
+		switch {
+		case pgerror.IsForeignKeyViolation(err):
+			httputil.Conflict(w, fmt.Sprintf("revision %d cannot be restored: its department no longer exists", revision))
+		case pgerror.IsUniqueViolation(err):
+			httputil.Conflict(w, "another change is already waiting for review")
+		default:
+			slog.Error("failed to roll back rule", "error", err)
+			httputil.InternalError(w, "failed to roll back rule")
+		}
+		return
+	}
+	if change != nil {
+		httputil.Accepted(w, ToChangeRequestResponse(change))
+		return
+	}
+	httputil.Success(w, ToResponse(rule))
+}
+
+// Submit sends a draft rule for review
+func (h *Handler) Submit(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.repo.Submit)
+}
+
+// Approve publishes a rule pending review. The approver must name themselves and differ
+// from the author of the rule's latest change.
+func (h *Handler) Approve(w http.ResponseWriter, r *http.Request) {
+	if httputil.Actor(r) == httputil.DefaultActor {
+		httputil.Forbidden(w, fmt.Sprintf("approving a rule requires the %s header", httputil.ActorHeader))
+		return
+	}
+	h.review(w, r, h.repo.Approve)
+}
+
+// Reject returns a rule pending review to draft, optionally with a comment for its author
+func (h *Handler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.repo.Reject)
+}
+
+// review applies a review step with the optional comment of the request body
+func (h *Handler) review(w http.ResponseWriter, r *http.Request, step func(ctx context.Context, id, actor string, comment *string) (*db.QualityRule, error)) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing rule id")
+		return
+	}
+
+	var req ReviewRequest
+	if err := httputil.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+
+	rule, err := step(r.Context(), id, httputil.Actor(r), req.Comment)
+	if err != nil {
+		var transition *TransitionError
+		switch {
+		case err == pgx.ErrNoRows:
 			httputil.NotFound(w, "rule not found")
+		case errors.As(err, &transition):
+			httputil.Conflict(w, err.Error())
+		case errors.Is(err, ErrSelfApproval):
+			httputil.Forbidden(w, err.Error())
+		default:
+			slog.Error("failed to change rule status", "rule_id", id, "error", err)
+			httputil.InternalError(w, "failed to change rule status")
+		}
+		return
+	}
+	httputil.Success(w, ToResponse(rule))
+}
+
+// ListStatusChanges returns the logged review status changes of a rule, newest first.
+// Deleted rules keep their log.
+func (h *Handler) ListStatusChanges(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing rule id")
+		return
+	}
+	limit, offset := pageOf(r)
+
+	changes, total, err := h.repo.ListStatusChanges(r.Context(), id, int32(limit), int32(offset))
+	if err != nil {
+		slog.Error("failed to list rule status changes", "error", err)
+		httputil.InternalError(w, "failed to list rule status changes")
+		return
+	}
+	if total == 0 {
+		httputil.NotFound(w, "rule not found")
+		return
+	}
+	httputil.SuccessPaginated(w, ToStatusChangeResponseList(changes), total, limit, offset)
+}
+
+// ListDraftViolations returns the violations a rule recorded in shadow mode, newest first
+func (h *Handler) ListDraftViolations(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing rule id")
+		return
+	}
+	limit, offset := pageOf(r)
+
+	violations, total, err := h.repo.ListDraftViolations(r.Context(), id, int32(limit), int32(offset))
+	if err != nil {
+		slog.Error("failed to list draft violations", "error", err)
+		httputil.InternalError(w, "failed to list draft violations")
+		return
+	}
+	httputil.SuccessPaginated(w, ToDraftViolationResponseList(violations), total, limit, offset)
+}
+
+// ListChangeRequests returns change requests, newest first, filtered by status, target,
+// target_id and the department of the changed rule or action
+func (h *Handler) ListChangeRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, offset := pageOf(r)
+	params := ChangeRequestListParams{
+		Status:       db.ChangeRequestStatus(query.Get("status")),
+		Target:       db.ChangeTarget(query.Get("target")),
+		TargetID:     query.Get("target_id"),
+		DepartmentID: query.Get("department_id"),
+		Limit:        int32(limit),
+		Offset:       int32(offset),
+	}
+	if params.Status != "" && !IsValidChangeRequestStatus(params.Status) {
+		httputil.BadRequest(w, "invalid status")
+		return
+	}
+	if params.Target != "" && !IsValidChangeTarget(params.Target) {
+		httputil.BadRequest(w, "invalid target")
+		return
+	}
+
+	changes, total, err := h.repo.ListChangeRequests(r.Context(), params)
+	if err != nil {
+		slog.Error("failed to list change requests", "error", err)
+		httputil.InternalError(w, "failed to list change requests")
+		return
+	}
+	httputil.SuccessPaginated(w, ToChangeRequestResponseList(changes), total, limit, offset)
+}
+
+func (h *Handler) GetChangeRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputil.BadRequest(w, "invalid change request id")
+		return
+	}
+
+	change, err := h.repo.GetChangeRequest(r.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "change request not found")
+			return
+		}
+		slog.Error("failed to get change request", "error", err)
+		httputil.InternalError(w, "failed to get change request")
+		return
+	}
+	httputil.Success(w, ToChangeRequestResponse(change))
+}
+
+// ApproveChange applies a pending change request. The approver must name themselves and
+// differ from the requester.
+func (h *Handler) ApproveChange(w http.ResponseWriter, r *http.Request) {
+	if httputil.Actor(r) == httputil.DefaultActor {
+		httputil.Forbidden(w, fmt.Sprintf("approving a change requires the %s header", httputil.ActorHeader))
+		return
+	}
+	h.reviewChange(w, r, h.repo.ApproveChange)
+}
+
+// RejectChange closes a pending change request without applying it
+func (h *Handler) RejectChange(w http.ResponseWriter, r *http.Request) {
+	h.reviewChange(w, r, h.repo.RejectChange)
+}
+
+// reviewChange applies a review step to a change request with the optional comment of
+// the request body
+func (h *Handler) reviewChange(w http.ResponseWriter, r *http.Request, step func(ctx context.Context, id int64, actor string, comment *string) (*db.ChangeRequest, error)) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputil.BadRequest(w, "invalid change request id")
+		return
+	}
+
+	var req ReviewRequest
+	if err := httputil.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+
+	change, err := step(r.Context(), id, httputil.Actor(r), req.Comment)
+	if err != nil {
+		switch {
+		case err == pgx.ErrNoRows:
+			httputil.NotFound(w, "change request not found")
+		case errors.Is(err, ErrOwnChangeRequest):
+			httputil.Forbidden(w, err.Error())
+		case errors.Is(err, ErrChangeReviewed), errors.Is(err, ErrChangeTargetGone):
+			httputil.Conflict(w, err.Error())
+		case pgerror.IsForeignKeyViolation(err):
+			httputil.BadRequest(w, "department not found")
+		default:
+			slog.Error("failed to review change request", "change_request_id", id, "error", err)
+			httputil.InternalError(w, "failed to review change request")
+		}
+		return
+	}
+	httputil.Success(w, ToChangeRequestResponse(change))
+}
+
+// pageOf reads the limit and offset of a paginated request, 20 items by default
+func pageOf(r *http.Request) (int, int) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// Backtest replays a saved or unsaved rule over past metrics and reports the incidents
+// it would have opened next to the ones the saved rule actually opened
+func (h *Handler) Backtest(w http.ResponseWriter, r *http.Request) {
+	var req BacktestRequest
+	if err := httputil.Decode(r, &req); err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if err := validateBacktest(&req); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	actions, err := h.repo.Actions(r.Context())
+	if err != nil {
+		slog.Error("failed to list rule actions", "error", err)
+		httputil.InternalError(w, "failed to backtest rule")
+		return
+	}
+
+	var rule *db.QualityRule
+	var compareID string
+	if req.RuleID != nil {
+		saved, err := h.repo.Get(r.Context(), *req.RuleID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				httputil.NotFound(w, "rule not found")
+				return
+			}
+			slog.Error("failed to get rule", "error", err)
+			httputil.InternalError(w, "failed to backtest rule")
+			return
+		}
+		if saved.AbsenceSeconds > 0 {
+			httputil.BadRequest(w, "absence rules cannot be backtested")
+			return
+		}
+		rule, compareID = saved, saved.ID
+	} else {
+		if err := validateDefinition(&req.Rule.RuleDefinition, actions); err != nil {
+			httputil.BadRequest(w, err.Error())
+			return
+		}
+		if req.Rule.AbsenceSeconds > 0 {
+			httputil.BadRequest(w, "absence rules cannot be backtested")
+			return
+		}
+		unsaved, err := ruleFromDefinition(req.Rule.ID, &req.Rule.RuleDefinition)
+		if err != nil {
+			httputil.BadRequest(w, err.Error())
 			return
 		}
-		slog.Error("failed to get rule", "error", err)
-		httputil.InternalError(w, "failed to delete rule")
+		rule, compareID = unsaved, req.Rule.ID
+	}
+
+	behavior, err := actions.behavior(rule.Action)
+	if err != nil {
+		httputil.BadRequest(w, err.Error())
 		return
 	}
 
-	if err := h.repo.Delete(r.Context(), id); err != nil {
-		slog.Error("failed to delete rule", "error", err)
-		httputil.InternalError(w, "failed to delete rule")
+	resp, err := runBacktest(r.Context(), h.repo, rule, behavior, compareID, req.From, req.To)
+	if err != nil {
+		if errors.Is(err, ErrBacktestTooLarge) {
+			httputil.BadRequest(w, err.Error()+"; narrow the time range")
+			return
+		}
+		slog.Error("failed to backtest rule", "error", err)
+		httputil.InternalError(w, "failed to backtest rule")
+		return
+	}
+	httputil.Success(w, resp)
+}
+
+// Baseline returns the learned baselines of a service/metric type series
+func (h *Handler) Baseline(w http.ResponseWriter, r *http.Request) {
+	key := SeriesKey{
+		ServiceID:  r.PathValue("service_id"),
+		MetricType: db.MetricType(r.PathValue("metric_type")),
+	}
+	if !isValidMetricType(key.MetricType) {
+		httputil.BadRequest(w, "invalid metric_type")
+		return
+	}
+
+	baselines, err := h.repo.LoadBaselines(r.Context(), key)
+	if err != nil {
+		slog.Error("failed to load baselines", "error", err)
+		httputil.InternalError(w, "failed to get baseline")
+		return
+	}
+	if len(baselines) == 0 {
+		httputil.NotFound(w, "no baseline for this series")
+		return
+	}
+	httputil.Success(w, ToSeriesBaselineResponse(key, baselines, time.Now()))
+}
+
+// ListEvaluationPolicies returns how every metric type handles several matching rules
+func (h *Handler) ListEvaluationPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.repo.ListEvaluationPolicies(r.Context())
+	if err != nil {
+		slog.Error("failed to list evaluation policies", "error", err)
+		httputil.InternalError(w, "failed to list evaluation policies")
+		return
+	}
+	httputil.Success(w, ToEvaluationPolicyResponseList(policies))
+}
+
+// SetEvaluationPolicy configures whether every matching rule of a metric type fires
+// (ALL_MATCHES) or only the one with the lowest priority value (FIRST_MATCH)
+func (h *Handler) SetEvaluationPolicy(w http.ResponseWriter, r *http.Request) {
+	metricType := db.MetricType(r.PathValue("metric_type"))
+	if !isValidMetricType(metricType) {
+		httputil.BadRequest(w, "invalid metric_type")
+		return
+	}
+
+	var req SetEvaluationPolicyRequest
+	if err := httputil.Decode(r, &req); err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if !IsValidEvaluationPolicy(db.EvaluationPolicy(req.Policy)) {
+		httputil.BadRequest(w, "policy must be ALL_MATCHES or FIRST_MATCH")
+		return
+	}
+
+	policy, err := h.repo.SetEvaluationPolicy(r.Context(), metricType, db.EvaluationPolicy(req.Policy))
+	if err != nil {
+		slog.Error("failed to set evaluation policy", "error", err)
+		httputil.InternalError(w, "failed to set evaluation policy")
+		return
+	}
+	httputil.Success(w, ToEvaluationPolicyResponse(policy))
+}
+
+// ListActions returns the registered rule actions, built-in and custom
+func (h *Handler) ListActions(w http.ResponseWriter, r *http.Request) {
+	actions, err := h.repo.ListActions(r.Context())
+	if err != nil {
+		slog.Error("failed to list rule actions", "error", err)
+		httputil.InternalError(w, "failed to list rule actions")
+		return
+	}
+	httputil.Success(w, ToActionResponseList(actions))
+}
+
+func (h *Handler) GetAction(w http.ResponseWriter, r *http.Request) {
+	action, err := h.repo.GetAction(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "rule action not found")
+			return
+		}
+		slog.Error("failed to get rule action", "error", err)
+		httputil.InternalError(w, "failed to get rule action")
+		return
+	}
+	httputil.Success(w, ToActionResponse(action))
+}
+
+// CreateAction registers a custom action that rules can reference by id
+func (h *Handler) CreateAction(w http.ResponseWriter, r *http.Request) {
+	var req CreateActionRequest
+	if err := httputil.Decode(r, &req); err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if req.ID == "" {
+		httputil.BadRequest(w, "id is required")
+		return
+	}
+	if !IsValidActionBehavior(db.ActionBehavior(req.Behavior)) {
+		httputil.BadRequest(w, "behavior must be OPEN_INCIDENT, NOTIFY_ONLY, WEBHOOK or THROTTLE")
+		return
+	}
+	if err := validateActionSettings(req.Name, req.MessageTemplate); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	action, err := h.repo.CreateAction(r.Context(), req)
+	if err != nil {
+		if pgerror.IsUniqueViolation(err) {
+			httputil.Conflict(w, "rule action with this id already exists")
+			return
+		}
+		if pgerror.IsForeignKeyViolation(err) {
+			httputil.BadRequest(w, "department not found")
+			return
+		}
+		slog.Error("failed to create rule action", "error", err)
+		httputil.InternalError(w, "failed to create rule action")
+		return
+	}
+	httputil.Created(w, ToActionResponse(action))
+}
+
+// UpdateAction changes the name, description, department and message template of an
+// action. Rules using it pick up the change with their next violation. While published
+// rules use the action, it responds 202 with the change request that waits for review.
+func (h *Handler) UpdateAction(w http.ResponseWriter, r *http.Request) {
+	var req UpdateActionRequest
+	if err := httputil.Decode(r, &req); err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if err := validateActionSettings(req.Name, req.MessageTemplate); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	action, change, err := h.repo.UpdateAction(r.Context(), r.PathValue("id"), req, httputil.Actor(r))
+	if err != nil {
+		h.changeError(w, err, "rule action not found", "failed to update rule action")
+		return
+	}
+	if change != nil {
+		httputil.Accepted(w, ToChangeRequestResponse(change))
+		return
+	}
+	httputil.Success(w, ToActionResponse(action))
+}
+
+// DeleteAction removes a custom action that no rule references. Built-in actions
+// cannot be deleted.
+func (h *Handler) DeleteAction(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch id {
+	case ActionOpenIncident, ActionThrottle, ActionWebhook:
+		httputil.Conflict(w, "built-in rule actions cannot be deleted")
+		return
+	}
+
+	if err := h.repo.DeleteAction(r.Context(), id); err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "rule action not found")
+			return
+		}
+		if pgerror.IsForeignKeyViolation(err) {
+			httputil.Conflict(w, "rule action is still used by rules")
+			return
+		}
+		slog.Error("failed to delete rule action", "error", err)
+		httputil.InternalError(w, "failed to delete rule action")
 		return
 	}
 	httputil.NoContent(w)
@@ -195,3 +938,398 @@ func (h *Handler) TopTriggered(w http.ResponseWriter, r *http.Request) {
 
 	httputil.Success(w, ToTopTriggeredResponseList(rows))
 }
+
+// Apply makes the stored rules and departments match a YAML or JSON ruleset. With
+// dry_run=true only the plan is returned.
+func (h *Handler) Apply(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			httputil.BadRequest(w, "invalid dry_run")
+			return
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxRulesetBytes+1))
+	if err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if len(body) > MaxRulesetBytes {
+		httputil.BadRequest(w, fmt.Sprintf("ruleset must not exceed %d bytes", MaxRulesetBytes))
+		return
+	}
+	rs, err := ParseRuleset(body)
+	if err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	plan, err := h.repo.Apply(r.Context(), rs, dryRun, httputil.Actor(r))
+	if err != nil {
+		if errors.Is(err, ErrInvalidRuleset) {
+			httputil.BadRequest(w, err.Error())
+			return
+		}
+		if pgerror.IsForeignKeyViolation(err) {
+			httputil.Conflict(w, "ruleset cannot be applied: a rule to delete is still referenced")
+			return
+		}
+		slog.Error("failed to apply ruleset", "error", err)
+		httputil.InternalError(w, "failed to apply ruleset")
+		return
+	}
+	httputil.Success(w, plan)
+}
+
+// Export returns the stored rules and departments as a ruleset that Apply accepts, as
+// YAML by default or JSON with format=json
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "json" {
+		httputil.BadRequest(w, "format must be yaml or json")
+		return
+	}
+
+	rs, err := h.repo.Export(r.Context())
+	if err != nil {
+		slog.Error("failed to export rules", "error", err)
+		httputil.InternalError(w, "failed to export rules")
+		return
+	}
+
+	var body []byte
+	contentType := "application/json"
+	if format == "yaml" {
+		body, err = MarshalRulesetYAML(rs)
+		contentType = "application/yaml"
+	} else {
+		body, err = json.MarshalIndent(rs, "", "  ")
+	}
+	if err != nil {
+		slog.Error("failed to encode ruleset", "error", err)
+		httputil.InternalError(w, "failed to export rules")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// validateDefinition checks a rule definition for Create and Update. The action must be
+// one of the registered actions.
+func validateDefinition(d *RuleDefinition, actions Actions) error {
+	if d.MetricType == "" && d.Condition == nil {
+		return errors.New("metric_type is required")
+	}
+	behavior, err := actions.behavior(d.Action)
+	if err != nil {
+		return err
+	}
+	if err := validateWindow(d); err != nil {
+		return err
+	}
+	if err := validateAggregation(d); err != nil {
+		return err
+	}
+	if err := validateChange(d); err != nil {
+		return err
+	}
+	if err := validateComposite(d); err != nil {
+		return err
+	}
+	if err := validateExpression(d); err != nil {
+		return err
+	}
+	if err := validateFreshness(d); err != nil {
+		return err
+	}
+	if err := validateAnomaly(d); err != nil {
+		return err
+	}
+	if err := validateAbsence(d, behavior); err != nil {
+		return err
+	}
+	if err := validateForecast(d); err != nil {
+		return err
+	}
+	if err := validateAutoResolve(d); err != nil {
+		return err
+	}
+	if err := validateAction(d, behavior); err != nil {
+		return err
+	}
+	if err := validateWebhook(d, behavior); err != nil {
+		return err
+	}
+	if err := validateSchedule(d.Schedule); err != nil {
+		return err
+	}
+	return validateTiers(d)
+}
+
+// windowed reports whether a rule definition aggregates or waits for a sustained
+// violation, which only threshold rules support
+func (d *RuleDefinition) windowed() bool {
+	return aggregationOrDefault(d.Aggregation) != db.RuleAggregationNONE || d.ForSeconds > 0 || d.WindowSamples > 0
+}
+
+// validateWindow checks the sustained-violation settings of a rule
+func validateWindow(d *RuleDefinition) error {
+	switch {
+	case d.ForSeconds < 0 || d.WindowSamples < 0 || d.MinViolations < 0:
+		return errors.New("for_seconds, window_samples and min_violations must not be negative")
+	case d.ForSeconds > 0 && d.WindowSamples > 0:
+		return errors.New("for_seconds and window_samples cannot be used together")
+	case time.Duration(d.ForSeconds)*time.Second > SeriesRetention:
+		return fmt.Errorf("for_seconds must not exceed %d", int(SeriesRetention.Seconds()))
+	case d.WindowSamples > MaxSeriesSamples:
+		return fmt.Errorf("window_samples must not exceed %d", MaxSeriesSamples)
+	case d.MinViolations > d.WindowSamples:
+		return errors.New("min_violations requires window_samples and must not exceed it")
+	}
+	return nil
+}
+
+// validateAggregation checks the aggregation function and window of a rule
+func validateAggregation(d *RuleDefinition) error {
+	agg := aggregationOrDefault(d.Aggregation)
+	switch {
+	case !IsValidAggregation(agg):
+		return fmt.Errorf("invalid aggregation: %s", d.Aggregation)
+	case d.WindowSeconds < 0:
+		return errors.New("window_seconds must not be negative")
+	case agg == db.RuleAggregationNONE && d.WindowSeconds > 0:
+		return errors.New("window_seconds requires an aggregation")
+	case agg == db.RuleAggregationNONE:
+		return nil
+	case d.WindowSeconds == 0:
+		return errors.New("window_seconds is required for aggregation rules")
+	case time.Duration(d.WindowSeconds)*time.Second > SeriesRetention:
+		return fmt.Errorf("window_seconds must not exceed %d", int(SeriesRetention.Seconds()))
+	case d.ForSeconds > 0 || d.WindowSamples > 0:
+		return errors.New("aggregation rules cannot use for_seconds or window_samples")
+	}
+	return nil
+}
+
+// validateChange checks the rate-of-change settings of a rule. The sample compared
+// with may be up to twice change_seconds old, which must fit in the series retention.
+func validateChange(d *RuleDefinition) error {
+	mode := changeModeOrDefault(d.ChangeMode)
+	switch {
+	case !IsValidChangeMode(mode):
+		return fmt.Errorf("invalid change_mode: %s", d.ChangeMode)
+	case d.ChangeSeconds < 0:
+		return errors.New("change_seconds must not be negative")
+	case mode == db.RuleChangeModeNONE && d.ChangeSeconds > 0:
+		return errors.New("change_seconds requires a change_mode")
+	case mode == db.RuleChangeModeNONE:
+		return nil
+	case d.ChangeSeconds == 0:
+		return errors.New("change_seconds is required for rate-of-change rules")
+	case 2*time.Duration(d.ChangeSeconds)*time.Second > SeriesRetention:
+		return fmt.Errorf("change_seconds must not exceed %d", int(SeriesRetention.Seconds()/2))
+	case d.windowed():
+		return errors.New("rate-of-change rules cannot use aggregation, for_seconds or window_samples")
+	case d.Condition != nil || d.Expression != nil || d.AnomalyDeviations != nil:
+		return errors.New("rate-of-change rules cannot have a condition, an expression or anomaly settings")
+	}
+	return nil
+}
+
+// validateComposite checks the composite settings of a rule
+func validateComposite(d *RuleDefinition) error {
+	if d.Condition == nil {
+		return nil
+	}
+	if err := d.Condition.Validate(); err != nil {
+		return err
+	}
+	if d.windowed() {
+		return errors.New("composite rules cannot use aggregation, for_seconds or window_samples")
+	}
+	return nil
+}
+
+// validateExpression compiles the expression of a rule. Compile errors carry the
+// position of the offending part. Expression rules are evaluated per sample, so they
+// use the *_over() functions rather than the window settings.
+func validateExpression(d *RuleDefinition) error {
+	if d.Expression == nil {
+		return nil
+	}
+	if _, err := CompileExpression(*d.Expression); err != nil {
+		return err
+	}
+	switch {
+	case d.Operator != "" || d.Threshold != 0:
+		return errors.New("expression rules cannot set operator or threshold")
+	case d.Condition != nil:
+		return errors.New("a rule cannot have both a condition and an expression")
+	case d.windowed():
+		return errors.New("expression rules cannot use aggregation, for_seconds or window_samples")
+	case d.RecoveryThreshold != nil:
+		return errors.New("expression rules cannot use recovery_threshold")
+	}
+	return nil
+}
+
+// validateFreshness checks how old the samples of other metric types read by composite
+// and expression rules may be
+func validateFreshness(d *RuleDefinition) error {
+	switch {
+	case d.Condition == nil && d.Expression == nil && d.FreshnessSeconds != 0:
+		return errors.New("freshness_seconds requires a condition or an expression")
+	case d.FreshnessSeconds < 0:
+		return errors.New("freshness_seconds must not be negative")
+	case time.Duration(d.FreshnessSeconds)*time.Second > SeriesRetention:
+		return fmt.Errorf("freshness_seconds must not exceed %d", int(SeriesRetention.Seconds()))
+	}
+	return nil
+}
+
+// validateAnomaly checks the anomaly settings of a rule. Anomaly rules compare each
+// sample with the baseline of its series, so they replace operator and threshold and
+// cannot be combined with the other rule shapes.
+func validateAnomaly(d *RuleDefinition) error {
+	if d.AnomalyDeviations == nil {
+		if d.AnomalyDirection != "" || d.AnomalySeasonal {
+			return errors.New("anomaly_direction and anomaly_seasonal require anomaly_deviations")
+		}
+		return nil
+	}
+	switch anomalyDirectionOrDefault(d.AnomalyDirection) {
+	case db.AnomalyDirectionBOTH, db.AnomalyDirectionABOVE, db.AnomalyDirectionBELOW:
+	default:
+		return fmt.Errorf("invalid anomaly_direction: %s", d.AnomalyDirection)
+	}
+	switch {
+	case *d.AnomalyDeviations <= 0 || *d.AnomalyDeviations > MaxAnomalyDeviations:
+		return fmt.Errorf("anomaly_deviations must be greater than 0 and at most %d", MaxAnomalyDeviations)
+	case d.Operator != "" || d.Threshold != 0:
+		return errors.New("anomaly rules cannot set operator or threshold")
+	case d.Condition != nil || d.Expression != nil:
+		return errors.New("anomaly rules cannot have a condition or an expression")
+	case d.windowed():
+		return errors.New("anomaly rules cannot use aggregation, for_seconds or window_samples")
+	case d.RecoveryThreshold != nil:
+		return errors.New("anomaly rules cannot use recovery_threshold")
+	}
+	return nil
+}
+
+// validateAbsence checks the absence settings of a rule. Absence rules fire when a
+// service stops sending a metric type rather than on the value of a sample, and their
+// incident always resolves once data resumes.
+func validateAbsence(d *RuleDefinition, behavior db.ActionBehavior) error {
+	switch {
+	case d.AbsenceSeconds < 0:
+		return errors.New("absence_seconds must not be negative")
+	case d.AbsenceSeconds == 0:
+		return nil
+	case d.AbsenceSeconds < MinAbsenceSeconds || d.AbsenceSeconds > MaxAbsenceSeconds:
+		return fmt.Errorf("absence_seconds must be between %d and %d", MinAbsenceSeconds, MaxAbsenceSeconds)
+	case behavior != db.ActionBehaviorOPENINCIDENT:
+		return errors.New("absence rules only support actions with the OPEN_INCIDENT behavior")
+	case d.Operator != "" || d.Threshold != 0:
+		return errors.New("absence rules cannot set operator or threshold")
+	case d.Condition != nil || d.Expression != nil || d.AnomalyDeviations != nil || changeModeOrDefault(d.ChangeMode) != db.RuleChangeModeNONE:
+		return errors.New("absence rules cannot have a condition, an expression, anomaly or rate-of-change settings")
+	case d.windowed():
+		return errors.New("absence rules cannot use aggregation, for_seconds or window_samples")
+	case d.AutoResolve:
+		return errors.New("absence rules resolve when data resumes and cannot use auto_resolve")
+	}
+	return nil
+}
+
+// validateAutoResolve checks the auto-resolve settings of a rule. A recovery threshold
+// must sit on the healthy side of the threshold, e.g. at or below it for a ">" rule.
+func validateAutoResolve(d *RuleDefinition) error {
+	switch {
+	case !d.AutoResolve && (d.RecoveryThreshold != nil || d.RecoverySamples != 0):
+		return errors.New("recovery_threshold and recovery_samples require auto_resolve")
+	case d.RecoverySamples < 0:
+		return errors.New("recovery_samples must not be negative")
+	case d.RecoverySamples > MaxSeriesSamples:
+		return fmt.Errorf("recovery_samples must not exceed %d", MaxSeriesSamples)
+	case d.RecoveryThreshold == nil:
+		return nil
+	case d.Condition != nil:
+		return errors.New("composite rules cannot use recovery_threshold")
+	}
+
+	threshold := tieredThreshold(d.Tiers, d.Threshold)
+	switch db.RuleOperator(d.Operator) {
+	case db.RuleOperatorValue0, db.RuleOperatorValue1: // >, >=
+		if *d.RecoveryThreshold > threshold {
+			return errors.New("recovery_threshold must not be above threshold")
+		}
+	case db.RuleOperatorValue2, db.RuleOperatorValue3: // <, <=
+		if *d.RecoveryThreshold < threshold {
+			return errors.New("recovery_threshold must not be below threshold")
+		}
+	default:
+		return fmt.Errorf("recovery_threshold is not supported for operator %s", d.Operator)
+	}
+	return nil
+}
+
+// validateAction checks the action settings of a rule against the behaviour of its action
+func validateAction(d *RuleDefinition, behavior db.ActionBehavior) error {
+	switch {
+	case d.CooldownSeconds < 0:
+		return errors.New("cooldown_seconds must not be negative")
+	case behavior == db.ActionBehaviorTHROTTLE && d.CooldownSeconds == 0:
+		return errors.New("cooldown_seconds is required for THROTTLE rules")
+	case behavior != db.ActionBehaviorTHROTTLE && behavior != db.ActionBehaviorWEBHOOK && d.CooldownSeconds > 0:
+		return errors.New("cooldown_seconds is only supported for THROTTLE and WEBHOOK rules")
+	}
+	return nil
+}
+
+// validateActionSettings checks the settings of a rule action
+func validateActionSettings(name string, messageTemplate *string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if messageTemplate != nil {
+		if err := webhook.ValidateTemplate(*messageTemplate); err != nil {
+			return fmt.Errorf("invalid message_template: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateWebhook checks the webhook settings of a rule
+func validateWebhook(d *RuleDefinition, behavior db.ActionBehavior) error {
+	if behavior != db.ActionBehaviorWEBHOOK {
+		if d.WebhookURL != nil || len(d.WebhookHeaders) > 0 || d.WebhookBodyTemplate != nil {
+			return errors.New("webhook settings are only supported for WEBHOOK rules")
+		}
+		return nil
+	}
+
+	if d.WebhookURL == nil || *d.WebhookURL == "" {
+		return errors.New("webhook_url is required for WEBHOOK rules")
+	}
+	if err := webhook.ValidateTarget(*d.WebhookURL); err != nil {
+		return err
+	}
+	for name := range d.WebhookHeaders {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid webhook header name: %q", name)
+		}
+	}
+	if d.WebhookBodyTemplate != nil {
+		if err := webhook.ValidateTemplate(*d.WebhookBodyTemplate); err != nil {
+			return fmt.Errorf("invalid webhook_body_template: %w", err)
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/internal/testutil"
@@ -20,7 +21,7 @@ func setupRuleTest(t *testing.T) (*Handler, *db.Queries, func()) {
 
 	testutil.CleanupTestData(t, pool)
 
-	repo := NewRepository(q)
+	repo := NewRepository(pool, q)
 	handler := NewHandler(repo)
 
 	cleanup := func() {
@@ -69,6 +70,49 @@ func TestRuleHandler_List(t *testing.T) {
 	}
 }
 
+func TestRuleHandler_List_FilterByService(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:       "all-services",
+		IsActive: true,
+	})
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:         "only-s1",
+		IsActive:   true,
+		ServiceIDs: []string{"S1"},
+	})
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:         "only-s2",
+		IsActive:   true,
+		ServiceIDs: []string{"S2"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rules?service_id=S1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.List(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data []RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 rules for S1, got %d", len(response.Data))
+	}
+	for _, r := range response.Data {
+		if r.ID == "only-s2" {
+			t.Errorf("Rule scoped to S2 should not be listed for S1")
+		}
+	}
+}
+
 func TestRuleHandler_Get(t *testing.T) {
 	handler, q, cleanup := setupRuleTest(t)
 	defer cleanup()
@@ -105,6 +149,97 @@ func TestRuleHandler_Get(t *testing.T) {
 	}
 }
 
+func TestRuleHandler_Get_Suppressions(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:              "throttle-rule",
+		Threshold:       150.0,
+		Action:          ActionThrottle,
+		IsActive:        true,
+		CooldownSeconds: 600,
+	})
+
+	repo := handler.repo
+	for i := 0; i < 3; i++ {
+		if err := repo.RecordSuppression(context.Background(), "throttle-rule", "S1", db.SuppressionReasonTHROTTLED); err != nil {
+			t.Fatalf("Failed to record suppression: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rules/throttle-rule", nil)
+	req.SetPathValue("id", "throttle-rule")
+	rr := httptest.NewRecorder()
+
+	handler.Get(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Data.CooldownSeconds != 600 {
+		t.Errorf("Expected cooldown 600, got %d", response.Data.CooldownSeconds)
+	}
+	if response.Data.SuppressedCount != 3 {
+		t.Errorf("Expected suppressed count 3, got %d", response.Data.SuppressedCount)
+	}
+	if len(response.Data.Suppressions) != 1 || response.Data.Suppressions[0].Reason != "THROTTLED" {
+		t.Errorf("Expected one THROTTLED suppression, got %+v", response.Data.Suppressions)
+	}
+}
+
+func TestRuleHandler_TopTriggered_Suppressions(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:              "throttle-rule",
+		Threshold:       150.0,
+		Action:          ActionThrottle,
+		IsActive:        true,
+		CooldownSeconds: 600,
+	})
+
+	repo := handler.repo
+	for _, reason := range []db.SuppressionReason{db.SuppressionReasonTHROTTLED, db.SuppressionReasonSILENCED, db.SuppressionReasonSILENCED} {
+		if err := repo.RecordSuppression(context.Background(), "throttle-rule", "S1", reason); err != nil {
+			t.Fatalf("Failed to record suppression: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rules/stats/top-triggered", nil)
+	rr := httptest.NewRecorder()
+	handler.TopTriggered(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var response struct {
+		Data []TopTriggeredRuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if len(response.Data) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(response.Data))
+	}
+	// Silenced violations are not counted as throttled
+	got := response.Data[0]
+	if got.SuppressedCount != 1 {
+		t.Errorf("Expected suppressed count 1, got %d", got.SuppressedCount)
+	}
+	if got.SuppressedByReason["THROTTLED"] != 1 || got.SuppressedByReason["SILENCED"] != 2 || len(got.SuppressedByReason) != 2 {
+		t.Errorf("Unexpected suppressed counts by reason: %v", got.SuppressedByReason)
+	}
+}
+
 func TestRuleHandler_Get_NotFound(t *testing.T) {
 	handler, _, cleanup := setupRuleTest(t)
 	defer cleanup()
@@ -139,14 +274,16 @@ func TestRuleHandler_Create(t *testing.T) {
 	defer cleanup()
 
 	body := CreateRuleRequest{
-		ID:         "new-rule",
-		MetricType: "LATENCY_MS",
-		Threshold:  200.0,
-		Operator:   ">",
-		Action:     "OPEN_INCIDENT",
-		Priority:   1,
-		Severity:   "HIGH",
-		IsActive:   true,
+		ID: "new-rule",
+		RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS",
+			Threshold:  200.0,
+			Operator:   ">",
+			Action:     "OPEN_INCIDENT",
+			Priority:   1,
+			Severity:   "HIGH",
+			IsActive:   true,
+		},
 	}
 
 	bodyBytes, _ := json.Marshal(body)
@@ -171,6 +308,330 @@ func TestRuleHandler_Create(t *testing.T) {
 	if response.Data.Threshold != 200.0 {
 		t.Errorf("Expected threshold 200.0, got %f", response.Data.Threshold)
 	}
+	if len(response.Data.ServiceIDs) != 0 {
+		t.Errorf("Expected rule to apply to all services, got %v", response.Data.ServiceIDs)
+	}
+}
+
+func TestRuleHandler_Create_WithServiceScope(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body := CreateRuleRequest{
+		ID: "scoped-rule",
+		RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS",
+			Threshold:  150.0,
+			Operator:   ">",
+			Action:     "OPEN_INCIDENT",
+			Severity:   "HIGH",
+			IsActive:   true,
+			ServiceIDs: []string{"S1", " S1 ", "S2"},
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if len(response.Data.ServiceIDs) != 2 || response.Data.ServiceIDs[0] != "S1" || response.Data.ServiceIDs[1] != "S2" {
+		t.Errorf("Expected service_ids [S1 S2], got %v", response.Data.ServiceIDs)
+	}
+}
+
+func TestRuleHandler_Create_Composite(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body := CreateRuleRequest{
+		ID: "tv-streaming",
+		RuleDefinition: RuleDefinition{
+			Action:           "OPEN_INCIDENT",
+			Severity:         "HIGH",
+			IsActive:         true,
+			Condition:        streamingCondition(),
+			FreshnessSeconds: 120,
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Data.Condition == nil || response.Data.Condition.Op != ConditionAnd || len(response.Data.Condition.Conditions) != 2 {
+		t.Errorf("Expected condition tree to round-trip, got %+v", response.Data.Condition)
+	}
+	if response.Data.MetricType != "BUFFER_RATIO" {
+		t.Errorf("Expected metric_type of the first condition, got %s", response.Data.MetricType)
+	}
+
+	// The rule is evaluated for every metric type it depends on
+	repo := handler.repo
+	for _, mt := range []db.MetricType{db.MetricTypeBUFFERRATIO, db.MetricTypePACKETLOSS, db.MetricTypeERRORRATE} {
+		rules, err := repo.ListActiveForService(context.Background(), mt, "S1")
+		if err != nil {
+			t.Fatalf("Failed to list rules: %v", err)
+		}
+		if len(rules) != 1 {
+			t.Errorf("Expected composite rule for %s, got %d rules", mt, len(rules))
+		}
+	}
+}
+
+func TestRuleHandler_Create_InvalidCondition(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body := map[string]any{
+		"id":     "bad-composite",
+		"action": "OPEN_INCIDENT",
+		"condition": map[string]any{
+			"op": "AND",
+			"conditions": []map[string]any{
+				{"metric_type": "BUFFER_RATIO", "operator": ">", "threshold": 6},
+				{"metric_type": "PACKET_LOSS", "operator": ">"},
+			},
+		},
+	}
+
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte("condition.conditions[1]")) {
+		t.Errorf("Expected error to name the invalid condition, got %s", rr.Body.String())
+	}
+}
+
+func TestRuleHandler_Create_Expression(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body := map[string]any{
+		"id":          "buffer-range",
+		"metric_type": "BUFFER_RATIO",
+		"expression":  "between(value, 5, 10) && LATENCY_MS > 150",
+		"action":      "OPEN_INCIDENT",
+		"severity":    "HIGH",
+		"is_active":   true,
+	}
+
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Data.Expression == nil || *response.Data.Expression != body["expression"] {
+		t.Errorf("Expected expression to be returned, got %v", response.Data.Expression)
+	}
+	if len(response.Data.ServiceIDs) != 0 || response.Data.MetricType != "BUFFER_RATIO" {
+		t.Errorf("Unexpected rule: %+v", response.Data)
+	}
+}
+
+func TestRuleHandler_Create_InvalidExpression(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		body     map[string]any
+		expected string
+	}{
+		{"syntax error", map[string]any{"expression": "value >"}, "position 8"},
+		{"unknown identifier", map[string]any{"expression": "value > 5 && latency > 1"}, `position 14: unknown identifier \"latency\"`},
+		{"with operator", map[string]any{"expression": "value > 5", "operator": ">"}, "cannot set operator"},
+		{"with window", map[string]any{"expression": "value > 5", "for_seconds": 60}, "cannot use aggregation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{
+				"id":          "bad-expression",
+				"metric_type": "LATENCY_MS",
+				"action":      "OPEN_INCIDENT",
+			}
+			for k, v := range tt.body {
+				body[k] = v
+			}
+
+			bodyBytes, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+			rr := httptest.NewRecorder()
+
+			handler.Create(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+			if !bytes.Contains(rr.Body.Bytes(), []byte(tt.expected)) {
+				t.Errorf("Expected error to contain %q, got %s", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRuleHandler_Create_Anomaly(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body := map[string]any{
+		"id":                 "error-rate-anomaly",
+		"metric_type":        "ERROR_RATE",
+		"anomaly_deviations": 3.5,
+		"anomaly_direction":  "ABOVE",
+		"anomaly_seasonal":   true,
+		"action":             "OPEN_INCIDENT",
+		"severity":           "HIGH",
+		"is_active":          true,
+	}
+
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	rule := response.Data
+	if rule.AnomalyDeviations == nil || *rule.AnomalyDeviations != 3.5 || rule.AnomalyDirection != "ABOVE" || !rule.AnomalySeasonal {
+		t.Errorf("Expected anomaly settings to be returned, got %+v", rule)
+	}
+}
+
+func TestRuleHandler_Create_Schedule(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	body := map[string]any{
+		"id":          "always-scheduled",
+		"metric_type": "LATENCY_MS",
+		"threshold":   150.0,
+		"operator":    ">",
+		"action":      "OPEN_INCIDENT",
+		"severity":    "HIGH",
+		"is_active":   true,
+		"schedule":    map[string]any{"cron": "* * * * *", "timezone": "Europe/Istanbul"},
+	}
+
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	rule := response.Data
+	if rule.Schedule == nil || rule.Schedule.Cron != "* * * * *" || rule.Schedule.Timezone != "Europe/Istanbul" {
+		t.Errorf("Expected schedule to be returned, got %+v", rule.Schedule)
+	}
+	if !rule.InSchedule {
+		t.Error("Expected rule to be in its schedule")
+	}
+}
+
+func TestRuleHandler_Baseline(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	repo := handler.repo
+	key := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeERRORRATE}
+	monday9 := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+	if err := repo.SaveBaselines(context.Background(), key, []Baseline{
+		{Bucket: OverallBucket, Mean: 2, Variance: 0.25, Count: 40, LastRecordedAt: monday9},
+		{Bucket: 33, Mean: 3, Variance: 1, Count: 5, LastRecordedAt: monday9},
+	}); err != nil {
+		t.Fatalf("Failed to save baselines: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/baselines/S1/ERROR_RATE", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data SeriesBaselineResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	got := response.Data
+	if got.Overall == nil || got.Overall.Mean != 2 || got.Overall.Deviation != 0.5 || !got.Overall.Warm {
+		t.Errorf("Unexpected overall baseline: %+v", got.Overall)
+	}
+	if len(got.HourOfWeek) != 1 || got.HourOfWeek[0].Label != "Mon 09:00 UTC" || got.HourOfWeek[0].Warm {
+		t.Errorf("Unexpected hour-of-week baselines: %+v", got.HourOfWeek)
+	}
+
+	for path, status := range map[string]int{
+		"/api/baselines/S2/ERROR_RATE": http.StatusNotFound,
+		"/api/baselines/S1/UNKNOWN":    http.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != status {
+			t.Errorf("GET %s: expected status %d, got %d", path, status, rr.Code)
+		}
+	}
 }
 
 func TestRuleHandler_Create_MissingID(t *testing.T) {
@@ -216,6 +677,77 @@ func TestRuleHandler_Create_MissingMetricType(t *testing.T) {
 	}
 }
 
+func TestRuleHandler_Create_InvalidWindow(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"negative for_seconds", map[string]any{"for_seconds": -1}},
+		{"for_seconds and window_samples", map[string]any{"for_seconds": 60, "window_samples": 5}},
+		{"min_violations above window", map[string]any{"window_samples": 3, "min_violations": 4}},
+		{"min_violations without window", map[string]any{"min_violations": 2}},
+		{"unknown aggregation", map[string]any{"aggregation": "MEDIAN", "window_seconds": 300}},
+		{"aggregation without window", map[string]any{"aggregation": "P95"}},
+		{"window without aggregation", map[string]any{"window_seconds": 300}},
+		{"aggregation with for_seconds", map[string]any{"aggregation": "P95", "window_seconds": 300, "for_seconds": 60}},
+		{"recovery without auto_resolve", map[string]any{"recovery_threshold": 120}},
+		{"recovery threshold above threshold", map[string]any{"auto_resolve": true, "recovery_threshold": 200}},
+		{"negative recovery_samples", map[string]any{"auto_resolve": true, "recovery_samples": -1}},
+		{"throttle without cooldown", map[string]any{"action": "THROTTLE"}},
+		{"cooldown without throttle", map[string]any{"cooldown_seconds": 600}},
+		{"webhook without url", map[string]any{"action": "WEBHOOK"}},
+		{"webhook with relative url", map[string]any{"action": "WEBHOOK", "webhook_url": "/hook"}},
+		{"webhook to a private address", map[string]any{"action": "WEBHOOK", "webhook_url": "http://169.254.169.254/latest/meta-data"}},
+		{"webhook with invalid header", map[string]any{"action": "WEBHOOK", "webhook_url": "https://example.com/hook", "webhook_headers": map[string]string{"Bad Header": "x"}}},
+		{"webhook with unknown template field", map[string]any{"action": "WEBHOOK", "webhook_url": "https://example.com/hook", "webhook_body_template": "{{.Unknown}}"}},
+		{"webhook url without webhook action", map[string]any{"webhook_url": "https://example.com/hook"}},
+		{"anomaly direction without deviations", map[string]any{"anomaly_direction": "ABOVE"}},
+		{"anomaly with threshold", map[string]any{"anomaly_deviations": 3}},
+		{"unknown change mode", map[string]any{"change_mode": "RATIO", "change_seconds": 120}},
+		{"change mode without seconds", map[string]any{"change_mode": "PERCENT"}},
+		{"change seconds without mode", map[string]any{"change_seconds": 120}},
+		{"change lookback too long", map[string]any{"change_mode": "ABSOLUTE", "change_seconds": 20000}},
+		{"change with for_seconds", map[string]any{"change_mode": "ABSOLUTE", "change_seconds": 120, "for_seconds": 60}},
+		{"absence window too short", map[string]any{"absence_seconds": 30}},
+		{"absence with threshold", map[string]any{"absence_seconds": 300}},
+		{"negative absence_seconds", map[string]any{"absence_seconds": -1}},
+		{"tiers with threshold", map[string]any{"tiers": []map[string]any{{"threshold": 300, "severity": "HIGH"}}}},
+		{"unordered tiers", map[string]any{"threshold": 0, "tiers": []map[string]any{{"threshold": 300, "severity": "MEDIUM"}, {"threshold": 150, "severity": "HIGH"}}}},
+		{"empty schedule", map[string]any{"schedule": map[string]any{}}},
+		{"invalid cron", map[string]any{"schedule": map[string]any{"cron": "* 25 * * *"}}},
+		{"invalid schedule window", map[string]any{"schedule": map[string]any{"windows": []map[string]any{{"weekdays": []string{"MON"}, "start": "09:00", "end": "09:00"}}}}},
+		{"unknown schedule timezone", map[string]any{"schedule": map[string]any{"cron": "* 9-17 * * *", "timezone": "Mars/Olympus"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{
+				"id":          "window-rule",
+				"metric_type": "LATENCY_MS",
+				"threshold":   150.0,
+				"operator":    ">",
+				"action":      "OPEN_INCIDENT",
+			}
+			for k, v := range tt.body {
+				body[k] = v
+			}
+
+			bodyBytes, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(bodyBytes))
+			rr := httptest.NewRecorder()
+
+			handler.Create(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestRuleHandler_Create_Duplicate(t *testing.T) {
 	handler, q, cleanup := setupRuleTest(t)
 	defer cleanup()
@@ -229,13 +761,15 @@ func TestRuleHandler_Create_Duplicate(t *testing.T) {
 	})
 
 	body := CreateRuleRequest{
-		ID:         "existing-rule",
-		MetricType: "LATENCY_MS",
-		Threshold:  200.0,
-		Operator:   ">",
-		Action:     "OPEN_INCIDENT",
-		Severity:   "HIGH",
-		IsActive:   true,
+		ID: "existing-rule",
+		RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS",
+			Threshold:  200.0,
+			Operator:   ">",
+			Action:     "OPEN_INCIDENT",
+			Severity:   "HIGH",
+			IsActive:   true,
+		},
 	}
 
 	bodyBytes, _ := json.Marshal(body)
@@ -272,12 +806,12 @@ func TestRuleHandler_Update(t *testing.T) {
 		MetricType: db.MetricTypeLATENCYMS,
 		Threshold:  100.0,
 		Operator:   db.RuleOperatorValue0,
-		Action:     db.RuleActionOPENINCIDENT,
+		Action:     ActionOpenIncident,
 		Severity:   db.IncidentSeverityMEDIUM,
 		IsActive:   true,
 	})
 
-	body := UpdateRuleRequest{
+	body := RuleDefinition{
 		MetricType: "LATENCY_MS",
 		Threshold:  250.0,
 		Operator:   ">=",
@@ -319,7 +853,7 @@ func TestRuleHandler_Update_MissingID(t *testing.T) {
 	handler, _, cleanup := setupRuleTest(t)
 	defer cleanup()
 
-	body := UpdateRuleRequest{Threshold: 100.0}
+	body := RuleDefinition{Threshold: 100.0}
 	bodyBytes, _ := json.Marshal(body)
 
 	req := httptest.NewRequest(http.MethodPatch, "/api/rules/", bytes.NewReader(bodyBytes))
@@ -421,7 +955,7 @@ func TestRuleRepository_ListActive(t *testing.T) {
 		IsActive: false,
 	})
 
-	repo := NewRepository(q)
+	repo := NewRepository(pool, q)
 	ctx := context.Background()
 
 	activeRules, err := repo.ListActive(ctx)
@@ -452,7 +986,7 @@ func TestRuleRepository_ListActiveByMetricType(t *testing.T) {
 		IsActive:   true,
 	})
 
-	repo := NewRepository(q)
+	repo := NewRepository(pool, q)
 	ctx := context.Background()
 
 	latencyRules, err := repo.ListActiveByMetricType(ctx, db.MetricTypeLATENCYMS)
@@ -465,6 +999,57 @@ func TestRuleRepository_ListActiveByMetricType(t *testing.T) {
 	}
 }
 
+func TestRuleRepository_ListActiveForService(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:       "global-rule",
+		IsActive: true,
+	})
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:         "superonline-rule",
+		IsActive:   true,
+		ServiceIDs: []string{"S1"},
+	})
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:         "paycell-tv-rule",
+		IsActive:   true,
+		ServiceIDs: []string{"S2", "S3"},
+	})
+
+	repo := NewRepository(pool, q)
+	ctx := context.Background()
+
+	tests := []struct {
+		serviceID string
+		expected  []string
+	}{
+		{"S1", []string{"global-rule", "superonline-rule"}},
+		{"S3", []string{"global-rule", "paycell-tv-rule"}},
+		{"S9", []string{"global-rule"}},
+	}
+
+	for _, tt := range tests {
+		rules, err := repo.ListActiveForService(ctx, db.MetricTypeLATENCYMS, tt.serviceID)
+		if err != nil {
+			t.Fatalf("Failed to list rules for service: %v", err)
+		}
+
+		if len(rules) != len(tt.expected) {
+			t.Errorf("Service %s: expected %d rules, got %d", tt.serviceID, len(tt.expected), len(rules))
+			continue
+		}
+		for i, id := range tt.expected {
+			if rules[i].ID != id {
+				t.Errorf("Service %s: expected rule %s at index %d, got %s", tt.serviceID, id, i, rules[i].ID)
+			}
+		}
+	}
+}
+
 func TestRuleRepository_SetActive(t *testing.T) {
 	pool := testutil.GetTestPool(t)
 	q := db.New(pool)
@@ -474,23 +1059,24 @@ func TestRuleRepository_SetActive(t *testing.T) {
 	testutil.TestRule(t, q, testutil.TestRuleParams{
 		ID:       "toggle-rule",
 		IsActive: true,
+		Status:   db.RuleStatusDRAFT,
 	})
 
-	repo := NewRepository(q)
+	repo := NewRepository(pool, q)
 	ctx := context.Background()
 
 	// Deactivate
-	rule, err := repo.SetActive(ctx, "toggle-rule", false)
+	rule, change, err := repo.SetActive(ctx, "toggle-rule", false, "test")
 	if err != nil {
 		t.Fatalf("Failed to set active: %v", err)
 	}
 
-	if rule.IsActive != false {
-		t.Errorf("Expected is_active false, got true")
+	if rule.IsActive != false || change != nil {
+		t.Errorf("Expected the draft to be deactivated without review, got %v and %+v", rule.IsActive, change)
 	}
 
 	// Reactivate
-	rule, err = repo.SetActive(ctx, "toggle-rule", true)
+	rule, _, err = repo.SetActive(ctx, "toggle-rule", true, "test")
 	if err != nil {
 		t.Fatalf("Failed to set active: %v", err)
 	}
@@ -514,8 +1100,19 @@ func TestRuleHandler_RegisterRoutes(t *testing.T) {
 		{http.MethodGet, "/api/rules"},
 		{http.MethodGet, "/api/rules/test"},
 		{http.MethodPost, "/api/rules"},
+		{http.MethodPost, "/api/rules/backtest"},
+		{http.MethodGet, "/api/rules/lint"},
 		{http.MethodPatch, "/api/rules/test"},
 		{http.MethodDelete, "/api/rules/test"},
+		{http.MethodPost, "/api/rules/test/submit"},
+		{http.MethodGet, "/api/rules/test/status-changes"},
+		{http.MethodGet, "/api/rules/test/draft-violations"},
+		{http.MethodGet, "/api/baselines/S1/ERROR_RATE"},
+		{http.MethodGet, "/api/evaluation-policies"},
+		{http.MethodGet, "/api/rule-actions"},
+		{http.MethodGet, "/api/rule-actions/OPEN_INCIDENT"},
+		{http.MethodGet, "/api/change-requests"},
+		{http.MethodGet, "/api/change-requests/1"},
 	}
 
 	for _, route := range routes {
@@ -534,7 +1131,7 @@ func TestRuleModel_ToResponse(t *testing.T) {
 		ID:         "test-rule",
 		MetricType: db.MetricTypeLATENCYMS,
 		Operator:   db.RuleOperatorValue0,
-		Action:     db.RuleActionOPENINCIDENT,
+		Action:     ActionOpenIncident,
 		Priority:   1,
 		Severity:   db.IncidentSeverityHIGH,
 		IsActive:   true,
@@ -0,0 +1,291 @@
+package rule
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+// LintKind names the problem a lint finding reports
+type LintKind string
+
+const (
+	// LintDuplicate reports two rules that fire on the same condition for the same services
+	LintDuplicate LintKind = "DUPLICATE"
+	// LintSubsumed reports a rule that only fires when another rule fires as well
+	LintSubsumed LintKind = "SUBSUMED"
+	// LintImpossible reports a rule whose condition cannot hold within the valid range of
+	// its metric type
+	LintImpossible LintKind = "IMPOSSIBLE"
+	// LintNoDepartment reports a rule whose incidents are not routed to a department, by
+	// the rule itself or by its action
+	LintNoDepartment LintKind = "NO_DEPARTMENT"
+)
+
+// LintFinding is a problem found in a set of rules. RelatedRuleID names the other rule
+// of duplicate and subsumed findings.
+type LintFinding struct {
+	Kind          LintKind      `json:"kind"`
+	RuleID        string        `json:"rule_id"`
+	RelatedRuleID string        `json:"related_rule_id,omitempty"`
+	MetricType    db.MetricType `json:"metric_type,omitempty"`
+	Message       string        `json:"message"`
+}
+
+// involves reports whether the finding is about the rule, directly or as the related rule
+func (f LintFinding) involves(id string) bool {
+	return f.RuleID == id || f.RelatedRuleID == id
+}
+
+// metricRange is the range of values a metric type can report
+type metricRange struct {
+	Min, Max float64
+}
+
+// metricRanges lists the valid value range of every metric type; latency has no upper
+// bound and the other metric types are percentages
+var metricRanges = map[db.MetricType]metricRange{
+	db.MetricTypeLATENCYMS:   {Min: 0, Max: math.Inf(1)},
+	db.MetricTypePACKETLOSS:  {Min: 0, Max: 100},
+	db.MetricTypeERRORRATE:   {Min: 0, Max: 100},
+	db.MetricTypeBUFFERRATIO: {Min: 0, Max: 100},
+}
+
+// Lint analyses a set of rules, usually the active ones. Threshold rules of the same
+// metric type are compared when they are evaluated the same way (aggregation, sustained
+// violation, rate-of-change, forecast and schedule settings), so a p95 rule never
+// duplicates a rule on single samples. actions are the registered actions, whose
+// department routes the rules without one.
+func Lint(rules []db.QualityRule, actions Actions) []LintFinding {
+	findings := []LintFinding{}
+	for i := range rules {
+		rule := &rules[i]
+		if f, ok := lintImpossible(rule); ok {
+			findings = append(findings, f)
+		}
+		var action *db.RuleAction
+		if a, ok := actions[rule.Action]; ok {
+			action = &a
+		}
+		if routeOf(rule, action) == nil {
+			findings = append(findings, LintFinding{
+				Kind:       LintNoDepartment,
+				RuleID:     rule.ID,
+				MetricType: rule.MetricType,
+				Message:    fmt.Sprintf("rule %q has no department, so its incidents are not routed to a department", rule.ID),
+			})
+		}
+	}
+
+	for i := range rules {
+		a := &rules[i]
+		if !isThresholdRule(a) {
+			continue
+		}
+		for j := i + 1; j < len(rules); j++ {
+			b := &rules[j]
+			if !isThresholdRule(b) || !sameEvaluation(a, b) {
+				continue
+			}
+			if f, ok := lintPair(a, b); ok {
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// isThresholdRule reports whether a rule compares a single series with operator and
+// threshold, so its condition can be compared with other rules
+func isThresholdRule(rule *db.QualityRule) bool {
+	return len(rule.Condition) == 0 && rule.Expression == nil && !rule.AnomalyDeviations.Valid && rule.AbsenceSeconds == 0
+}
+
+// sameEvaluation reports whether two threshold rules compare the same value of the same series
+func sameEvaluation(a, b *db.QualityRule) bool {
+	return a.MetricType == b.MetricType &&
+		a.Aggregation == b.Aggregation && (a.Aggregation == db.RuleAggregationNONE || a.WindowSeconds == b.WindowSeconds) &&
+		a.ForSeconds == b.ForSeconds && a.WindowSamples == b.WindowSamples && a.MinViolations == b.MinViolations &&
+		a.ChangeMode == b.ChangeMode && a.ChangeSeconds == b.ChangeSeconds &&
+		a.ForecastMethod == b.ForecastMethod && a.ForecastWindowSeconds == b.ForecastWindowSeconds && a.ForecastHorizonSeconds == b.ForecastHorizonSeconds &&
+		bytes.Equal(a.Schedule, b.Schedule)
+}
+
+// lintPair compares two threshold rules evaluated the same way
+func lintPair(a, b *db.QualityRule) (LintFinding, bool) {
+	ta, tb := pgutil.NumericToFloat64(a.Threshold), pgutil.NumericToFloat64(b.Threshold)
+	if a.Operator == b.Operator && ta == tb && sameScope(a.ServiceIds, b.ServiceIds) {
+		return LintFinding{
+			Kind:          LintDuplicate,
+			RuleID:        b.ID,
+			RelatedRuleID: a.ID,
+			MetricType:    b.MetricType,
+			Message: fmt.Sprintf("rules %q (%s) and %q (%s) both fire on %s for the same services",
+				a.ID, a.Severity, b.ID, b.Severity, describeThreshold(b)),
+		}, true
+	}
+
+	// Report the narrower rule as subsumed by the wider one
+	for _, pair := range [][2]*db.QualityRule{{a, b}, {b, a}} {
+		narrow, wide := pair[0], pair[1]
+		if !withinScope(narrow.ServiceIds, wide.ServiceIds) || !firesWithin(narrow, wide) {
+			continue
+		}
+		return LintFinding{
+			Kind:          LintSubsumed,
+			RuleID:        narrow.ID,
+			RelatedRuleID: wide.ID,
+			MetricType:    narrow.MetricType,
+			Message: fmt.Sprintf("rule %q (%s) only fires when rule %q (%s) also fires",
+				narrow.ID, describeThreshold(narrow), wide.ID, describeThreshold(wide)),
+		}, true
+	}
+	return LintFinding{}, false
+}
+
+// firesWithin reports whether every value that violates narrow also violates wide. Only
+// rules on the same side of their threshold are compared, and equality rules are
+// compared with the rule they fall into.
+func firesWithin(narrow, wide *db.QualityRule) bool {
+	tn, tw := pgutil.NumericToFloat64(narrow.Threshold), pgutil.NumericToFloat64(wide.Threshold)
+	switch {
+	case narrow.Operator == db.RuleOperatorValue4:
+		return compare(wide.Operator, tn, tw)
+	case isAbove(narrow.Operator) && isAbove(wide.Operator),
+		isBelow(narrow.Operator) && isBelow(wide.Operator):
+		// The narrow threshold itself fires the wide rule, and a strict narrow operator
+		// does not fire at the threshold
+		return compare(wide.Operator, tn, tw) ||
+			(tn == tw && (narrow.Operator == db.RuleOperatorValue0 || narrow.Operator == db.RuleOperatorValue2))
+	default:
+		return false
+	}
+}
+
+func isAbove(op db.RuleOperator) bool {
+	return op == db.RuleOperatorValue0 || op == db.RuleOperatorValue1
+}
+
+func isBelow(op db.RuleOperator) bool {
+	return op == db.RuleOperatorValue2 || op == db.RuleOperatorValue3
+}
+
+// sameScope reports whether two rules apply to the same services
+func sameScope(a, b []string) bool {
+	return withinScope(a, b) && withinScope(b, a)
+}
+
+// withinScope reports whether every service of scope also is in outer; an empty scope
+// applies to every service
+func withinScope(scope, outer []string) bool {
+	if len(outer) == 0 {
+		return true
+	}
+	if len(scope) == 0 {
+		return false
+	}
+	for _, id := range scope {
+		if !slices.Contains(outer, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// lintImpossible reports rules that can never fire because the values they compare
+// cannot reach the threshold. Rate-of-change rules compare changes, which may be
+// negative, and are not checked.
+func lintImpossible(rule *db.QualityRule) (LintFinding, bool) {
+	var reason string
+	if cond, err := ParseCondition(rule.Condition); err == nil && cond != nil {
+		if !conditionPossible(cond) {
+			reason = "its condition can never match within the valid ranges of its metric types"
+		}
+	} else if isThresholdRule(rule) && !isChangeRule(rule) {
+		if r, ok := metricRanges[rule.MetricType]; ok && !possible(rule.Operator, pgutil.NumericToFloat64(rule.Threshold), r) {
+			reason = fmt.Sprintf("%s is outside the valid range %s", describeThreshold(rule), r)
+		}
+	}
+	if reason == "" {
+		return LintFinding{}, false
+	}
+	return LintFinding{
+		Kind:       LintImpossible,
+		RuleID:     rule.ID,
+		MetricType: rule.MetricType,
+		Message:    fmt.Sprintf("rule %q can never fire: %s", rule.ID, reason),
+	}, true
+}
+
+// conditionPossible reports whether a composite condition can match: an AND group needs
+// every child to be possible and an OR group any of them
+func conditionPossible(c *Condition) bool {
+	if !c.isGroup() {
+		r, ok := metricRanges[db.MetricType(c.MetricType)]
+		return !ok || c.Threshold == nil || possible(db.RuleOperator(c.Operator), *c.Threshold, r)
+	}
+	for i := range c.Conditions {
+		ok := conditionPossible(&c.Conditions[i])
+		if c.Op == ConditionAnd && !ok {
+			return false
+		}
+		if c.Op == ConditionOr && ok {
+			return true
+		}
+	}
+	return c.Op == ConditionAnd
+}
+
+// possible reports whether some value within the range satisfies the comparison
+func possible(op db.RuleOperator, threshold float64, r metricRange) bool {
+	switch op {
+	case db.RuleOperatorValue0:
+		return threshold < r.Max
+	case db.RuleOperatorValue1:
+		return threshold <= r.Max
+	case db.RuleOperatorValue2:
+		return threshold > r.Min
+	case db.RuleOperatorValue3:
+		return threshold >= r.Min
+	case db.RuleOperatorValue4:
+		return threshold >= r.Min && threshold <= r.Max
+	default:
+		return true
+	}
+}
+
+func (r metricRange) String() string {
+	if math.IsInf(r.Max, 1) {
+		return fmt.Sprintf("%g and above", r.Min)
+	}
+	return fmt.Sprintf("%g to %g", r.Min, r.Max)
+}
+
+// describeThreshold formats the comparison of a threshold rule, e.g. "LATENCY_MS > 150"
+func describeThreshold(rule *db.QualityRule) string {
+	return fmt.Sprintf("%s %s %g", rule.MetricType, rule.Operator, pgutil.NumericToFloat64(rule.Threshold))
+}
+
+// lintWarnings lints a saved rule against the active rules and returns the findings
+// that involve it. The rule is linted even when it is inactive.
+func lintWarnings(active []db.QualityRule, actions Actions, rule *db.QualityRule) []LintFinding {
+	rules := make([]db.QualityRule, 0, len(active)+1)
+	for _, r := range active {
+		if r.ID != rule.ID {
+			rules = append(rules, r)
+		}
+	}
+	rules = append(rules, *rule)
+
+	var warnings []LintFinding
+	for _, f := range Lint(rules, actions) {
+		if f.involves(rule.ID) {
+			warnings = append(warnings, f)
+		}
+	}
+	return warnings
+}
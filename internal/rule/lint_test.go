@@ -0,0 +1,199 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func TestLint(t *testing.T) {
+	department := "D1"
+	// threshold returns an active threshold rule with a department
+	threshold := func(id string, metricType db.MetricType, op db.RuleOperator, value float64, services ...string) db.QualityRule {
+		return db.QualityRule{
+			ID:             id,
+			MetricType:     metricType,
+			Operator:       op,
+			Threshold:      pgutil.Float64ToNumeric(value),
+			Severity:       db.IncidentSeverityMEDIUM,
+			DepartmentID:   &department,
+			ServiceIds:     services,
+			Aggregation:    db.RuleAggregationNONE,
+			ChangeMode:     db.RuleChangeModeNONE,
+			ForecastMethod: db.RuleForecastMethodNONE,
+		}
+	}
+	p95 := threshold("p95", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150)
+	p95.Aggregation, p95.WindowSeconds = db.RuleAggregationP95, 300
+	drop := threshold("drop", db.MetricTypeLATENCYMS, db.RuleOperatorValue2, -50)
+	drop.ChangeMode, drop.ChangeSeconds = db.RuleChangeModeABSOLUTE, 60
+	orphan := threshold("orphan", db.MetricTypeERRORRATE, db.RuleOperatorValue0, 5)
+	orphan.DepartmentID = nil
+	// Rules without a department are routed by an action with one
+	routed := threshold("routed", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150)
+	routed.DepartmentID = nil
+	routed.Action = "QUALITY_ALERT"
+	actions := testActions()
+	alert := actions["QUALITY_ALERT"]
+	alert.DepartmentID = &department
+	actions["QUALITY_ALERT"] = alert
+	composite := threshold("composite", db.MetricTypeLATENCYMS, "", 0)
+	composite.Condition = []byte(`{"op":"AND","conditions":[` +
+		`{"metric_type":"LATENCY_MS","operator":">","threshold":150},` +
+		`{"metric_type":"BUFFER_RATIO","operator":">","threshold":100}]}`)
+
+	tests := []struct {
+		name  string
+		rules []db.QualityRule
+		want  []string
+	}{
+		{
+			name: "duplicate",
+			rules: []db.QualityRule{
+				threshold("a", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150),
+				threshold("b", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150),
+			},
+			want: []string{"DUPLICATE b a"},
+		},
+		{
+			name: "subsumed threshold",
+			rules: []db.QualityRule{
+				threshold("high", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 200),
+				threshold("low", db.MetricTypeLATENCYMS, db.RuleOperatorValue1, 150),
+			},
+			want: []string{"SUBSUMED high low"},
+		},
+		{
+			name: "subsumed scope",
+			rules: []db.QualityRule{
+				threshold("all", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150),
+				threshold("s1", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150, "S1"),
+			},
+			want: []string{"SUBSUMED s1 all"},
+		},
+		{
+			name: "narrower scope with a lower threshold",
+			rules: []db.QualityRule{
+				threshold("all", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 200),
+				threshold("s1", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150, "S1"),
+			},
+		},
+		{
+			name: "opposite directions",
+			rules: []db.QualityRule{
+				threshold("above", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150),
+				threshold("below", db.MetricTypeLATENCYMS, db.RuleOperatorValue2, 200),
+			},
+		},
+		{
+			name:  "different evaluation",
+			rules: []db.QualityRule{threshold("latency", db.MetricTypeLATENCYMS, db.RuleOperatorValue0, 150), p95},
+		},
+		{
+			name: "impossible",
+			rules: []db.QualityRule{
+				threshold("negative", db.MetricTypePACKETLOSS, db.RuleOperatorValue2, 0),
+				threshold("over", db.MetricTypeERRORRATE, db.RuleOperatorValue0, 100),
+				threshold("edge", db.MetricTypeBUFFERRATIO, db.RuleOperatorValue1, 100),
+				drop,
+				composite,
+			},
+			want: []string{"IMPOSSIBLE negative", "IMPOSSIBLE over", "IMPOSSIBLE composite"},
+		},
+		{
+			name:  "no department",
+			rules: []db.QualityRule{orphan, routed},
+			want:  []string{"NO_DEPARTMENT orphan"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range Lint(tt.rules, actions) {
+				got = append(got, strings.TrimSpace(string(f.Kind)+" "+f.RuleID+" "+f.RelatedRuleID))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLint_Messages(t *testing.T) {
+	rules := []db.QualityRule{
+		{ID: "a", MetricType: db.MetricTypePACKETLOSS, Operator: db.RuleOperatorValue2, Threshold: pgutil.Float64ToNumeric(0), Severity: db.IncidentSeverityLOW},
+		{ID: "b", MetricType: db.MetricTypePACKETLOSS, Operator: db.RuleOperatorValue2, Threshold: pgutil.Float64ToNumeric(0), Severity: db.IncidentSeverityHIGH},
+	}
+	messages := make(map[LintKind]string)
+	for _, f := range Lint(rules, testActions()) {
+		messages[f.Kind] = f.Message
+	}
+
+	want := map[LintKind]string{
+		LintImpossible:   `rule "b" can never fire: PACKET_LOSS < 0 is outside the valid range 0 to 100`,
+		LintDuplicate:    `rules "a" (LOW) and "b" (HIGH) both fire on PACKET_LOSS < 0 for the same services`,
+		LintNoDepartment: `rule "b" has no department, so its incidents are not routed to a department`,
+	}
+	for kind, message := range want {
+		if messages[kind] != message {
+			t.Errorf("Expected %s message %q, got %q", kind, message, messages[kind])
+		}
+	}
+}
+
+func TestRuleHandler_LintAndWarnings(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "latency", Threshold: 150, IsActive: true})
+
+	// Creating a duplicate succeeds with warnings
+	body, _ := json.Marshal(CreateRuleRequest{
+		ID: "latency-high", RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS", Threshold: 150, Operator: ">",
+			Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true,
+		},
+	})
+	rr := httptest.NewRecorder()
+	handler.Create(rr, httptest.NewRequest(http.MethodPost, "/api/rules", bytes.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	kinds := make(map[LintKind]bool)
+	for _, w := range created.Data.Warnings {
+		kinds[w.Kind] = true
+	}
+	if len(created.Data.Warnings) != 2 || !kinds[LintDuplicate] || !kinds[LintNoDepartment] {
+		t.Errorf("Expected duplicate and no-department warnings, got %+v", created.Data.Warnings)
+	}
+
+	// The lint endpoint reports the active rules, filtered by metric type
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	for path, want := range map[string]int{"/api/rules/lint": 3, "/api/rules/lint?metric_type=ERROR_RATE": 0} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Data []LintFinding `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if len(response.Data) != want {
+			t.Errorf("%s: expected %d findings, got %+v", path, want, response.Data)
+		}
+	}
+}
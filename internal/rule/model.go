@@ -1,64 +1,413 @@
 package rule
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/pkg/pgutil"
+	"github.com/unitythemaker/tracely/pkg/schedule"
 )
 
 type CreateRuleRequest struct {
-	ID           string  `json:"id"`
-	MetricType   string  `json:"metric_type"`
-	Threshold    float64 `json:"threshold"`
-	Operator     string  `json:"operator"`
-	Action       string  `json:"action"`
-	Priority     int32   `json:"priority"`
-	Severity     string  `json:"severity"`
-	IsActive     bool    `json:"is_active"`
-	DepartmentID *string `json:"department_id,omitempty"`
+	ID string `json:"id"`
+	RuleDefinition
 }
 
-type UpdateRuleRequest struct {
-	MetricType   string  `json:"metric_type"`
-	Threshold    float64 `json:"threshold"`
-	Operator     string  `json:"operator"`
-	Action       string  `json:"action"`
-	Priority     int32   `json:"priority"`
-	Severity     string  `json:"severity"`
-	IsActive     bool    `json:"is_active"`
-	DepartmentID *string `json:"department_id,omitempty"`
+// RuleDefinition is everything that defines a rule apart from its id. Create and
+// Update take it and validateDefinition checks it for both.
+type RuleDefinition struct {
+	MetricType   string   `json:"metric_type"`
+	Threshold    float64  `json:"threshold"`
+	Operator     string   `json:"operator"`
+	Action       string   `json:"action"`
+	Priority     int32    `json:"priority"`
+	Severity     string   `json:"severity"`
+	IsActive     bool     `json:"is_active"`
+	DepartmentID *string  `json:"department_id,omitempty"`
+	ServiceIDs   []string `json:"service_ids,omitempty"`
+
+	// Sustained-violation window; at most one of for_seconds and window_samples may be set
+	ForSeconds    int32 `json:"for_seconds,omitempty"`
+	WindowSamples int32 `json:"window_samples,omitempty"`
+	MinViolations int32 `json:"min_violations,omitempty"`
+
+	// Aggregation rules compare e.g. the p95 over the last window_seconds against the threshold
+	Aggregation   string `json:"aggregation,omitempty"`
+	WindowSeconds int32  `json:"window_seconds,omitempty"`
+
+	// Rate-of-change rules compare the change since change_seconds ago (ABSOLUTE or PERCENT)
+	// against the threshold instead of the value itself
+	ChangeMode    string `json:"change_mode,omitempty"`
+	ChangeSeconds int32  `json:"change_seconds,omitempty"`
+
+	// Composite rules set a condition tree instead of metric_type, operator and threshold
+	Condition        *Condition `json:"condition,omitempty"`
+	FreshnessSeconds int32      `json:"freshness_seconds,omitempty"`
+
+	// Expression rules set an expression such as "value >= 5 && value <= 10" instead of
+	// operator and threshold; metric_type selects the series that triggers evaluation
+	Expression *string `json:"expression,omitempty"`
+
+	// Anomaly rules set anomaly_deviations instead of operator and threshold and fire when a
+	// value is that many deviations away from the learned baseline of its series
+	AnomalyDeviations *float64 `json:"anomaly_deviations,omitempty"`
+	AnomalyDirection  string   `json:"anomaly_direction,omitempty"`
+	AnomalySeasonal   bool     `json:"anomaly_seasonal,omitempty"`
+
+	// Absence rules open an incident when the service sends no metric of metric_type for
+	// absence_seconds and resolve it when data resumes
+	AbsenceSeconds int32 `json:"absence_seconds,omitempty"`
+
+	// Forecast rules fit a LINEAR or HOLT trend over the last forecast_window_seconds and
+	// open a LOW incident when the forecast forecast_horizon_seconds ahead crosses the threshold
+	ForecastMethod         string `json:"forecast_method,omitempty"`
+	ForecastWindowSeconds  int32  `json:"forecast_window_seconds,omitempty"`
+	ForecastHorizonSeconds int32  `json:"forecast_horizon_seconds,omitempty"`
+
+	// Auto-resolve closes the incident after recovery_samples healthy samples (default 1)
+	AutoResolve       bool     `json:"auto_resolve,omitempty"`
+	RecoveryThreshold *float64 `json:"recovery_threshold,omitempty"`
+	RecoverySamples   int32    `json:"recovery_samples,omitempty"`
+
+	// THROTTLE rules open an incident, and WEBHOOK rules request a delivery, at most once
+	// per cooldown_seconds per service
+	CooldownSeconds int32 `json:"cooldown_seconds,omitempty"`
+
+	// Schedule limits evaluation to a cron expression or weekly windows; nil means always
+	Schedule *schedule.Schedule `json:"schedule,omitempty"`
+
+	// Tiers replace threshold and severity with ordered thresholds of increasing severity;
+	// an open incident is escalated when a worse tier is breached
+	Tiers []Tier `json:"tiers,omitempty"`
+
+	// WEBHOOK rules post to webhook_url; the body is rendered from webhook_body_template
+	// (a Go text/template over webhook.Event) or defaults to the event as JSON
+	WebhookURL          *string           `json:"webhook_url,omitempty"`
+	WebhookHeaders      map[string]string `json:"webhook_headers,omitempty"`
+	WebhookBodyTemplate *string           `json:"webhook_body_template,omitempty"`
 }
 
 type RuleResponse struct {
-	ID           string    `json:"id"`
-	MetricType   string    `json:"metric_type"`
-	Threshold    float64   `json:"threshold"`
-	Operator     string    `json:"operator"`
-	Action       string    `json:"action"`
-	Priority     int32     `json:"priority"`
-	Severity     string    `json:"severity"`
-	IsActive     bool      `json:"is_active"`
-	DepartmentID *string   `json:"department_id,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	TriggerCount int32     `json:"trigger_count"`
+	ID                     string             `json:"id"`
+	MetricType             string             `json:"metric_type"`
+	Threshold              float64            `json:"threshold"`
+	Operator               string             `json:"operator"`
+	Action                 string             `json:"action"`
+	Priority               int32              `json:"priority"`
+	Severity               string             `json:"severity"`
+	IsActive               bool               `json:"is_active"`
+	DepartmentID           *string            `json:"department_id,omitempty"`
+	ServiceIDs             []string           `json:"service_ids"`
+	ForSeconds             int32              `json:"for_seconds"`
+	WindowSamples          int32              `json:"window_samples"`
+	MinViolations          int32              `json:"min_violations"`
+	Aggregation            string             `json:"aggregation"`
+	WindowSeconds          int32              `json:"window_seconds"`
+	ChangeMode             string             `json:"change_mode"`
+	ChangeSeconds          int32              `json:"change_seconds"`
+	Condition              *Condition         `json:"condition,omitempty"`
+	FreshnessSeconds       int32              `json:"freshness_seconds,omitempty"`
+	Expression             *string            `json:"expression,omitempty"`
+	AnomalyDeviations      *float64           `json:"anomaly_deviations,omitempty"`
+	AnomalyDirection       string             `json:"anomaly_direction,omitempty"`
+	AnomalySeasonal        bool               `json:"anomaly_seasonal,omitempty"`
+	AbsenceSeconds         int32              `json:"absence_seconds,omitempty"`
+	ForecastMethod         string             `json:"forecast_method,omitempty"`
+	ForecastWindowSeconds  int32              `json:"forecast_window_seconds,omitempty"`
+	ForecastHorizonSeconds int32              `json:"forecast_horizon_seconds,omitempty"`
+	AutoResolve            bool               `json:"auto_resolve"`
+	RecoveryThreshold      *float64           `json:"recovery_threshold"`
+	RecoverySamples        int32              `json:"recovery_samples"`
+	CooldownSeconds        int32              `json:"cooldown_seconds"`
+	WebhookURL             *string            `json:"webhook_url,omitempty"`
+	WebhookHeaders         map[string]string  `json:"webhook_headers,omitempty"`
+	WebhookBodyTemplate    *string            `json:"webhook_body_template,omitempty"`
+	Schedule               *schedule.Schedule `json:"schedule,omitempty"`
+	InSchedule             bool               `json:"in_schedule"`
+	Tiers                  []Tier             `json:"tiers,omitempty"`
+	CreatedAt              time.Time          `json:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at"`
+	TriggerCount           int32              `json:"trigger_count"`
+	Revision               int32              `json:"revision"`
+	Status                 string             `json:"status"`
+	Owner                  *string            `json:"owner,omitempty"`
+	Author                 *string            `json:"author,omitempty"`
+
+	// Suppressed violations, returned with rule details and top-triggered stats.
+	// suppressed_count counts the violations a cooldown throttled; suppressed_by_reason
+	// counts every reason, including silences, FIRST_MATCH shadowing and drafts.
+	SuppressedCount    int32                 `json:"suppressed_count,omitempty"`
+	SuppressedByReason map[string]int32      `json:"suppressed_by_reason,omitempty"`
+	Suppressions       []SuppressionResponse `json:"suppressions,omitempty"`
+
+	// Lint findings involving the rule, returned when it is created or updated
+	Warnings []LintFinding `json:"warnings,omitempty"`
+}
+
+// SuppressionResponse counts violations of a rule that were suppressed for a service
+type SuppressionResponse struct {
+	ServiceID        string    `json:"service_id"`
+	Reason           string    `json:"reason"`
+	SuppressedCount  int64     `json:"suppressed_count"`
+	LastSuppressedAt time.Time `json:"last_suppressed_at"`
+}
+
+func ToSuppressionResponseList(rows []db.RuleSuppression) []SuppressionResponse {
+	result := make([]SuppressionResponse, len(rows))
+	for i, s := range rows {
+		result[i] = SuppressionResponse{
+			ServiceID:        s.ServiceID,
+			Reason:           string(s.Reason),
+			SuppressedCount:  s.SuppressedCount,
+			LastSuppressedAt: s.LastSuppressedAt,
+		}
+	}
+	return result
+}
+
+// setSuppressedCounts sets the suppressed counts of a rule from its totals per reason
+func (r *RuleResponse) setSuppressedCounts(byReason map[db.SuppressionReason]int32) {
+	r.SuppressedCount = byReason[db.SuppressionReasonTHROTTLED]
+	for reason, count := range byReason {
+		if count == 0 {
+			continue
+		}
+		if r.SuppressedByReason == nil {
+			r.SuppressedByReason = make(map[string]int32)
+		}
+		r.SuppressedByReason[string(reason)] = count
+	}
 }
 
 func ToResponse(r *db.QualityRule) RuleResponse {
 	return RuleResponse{
-		ID:           r.ID,
-		MetricType:   string(r.MetricType),
-		Threshold:    pgutil.NumericToFloat64(r.Threshold),
-		Operator:     string(r.Operator),
-		Action:       string(r.Action),
-		Priority:     r.Priority,
-		Severity:     string(r.Severity),
-		IsActive:     r.IsActive,
-		DepartmentID: r.DepartmentID,
-		CreatedAt:    r.CreatedAt,
-		UpdatedAt:    r.UpdatedAt,
+		ID:                     r.ID,
+		MetricType:             string(r.MetricType),
+		Threshold:              pgutil.NumericToFloat64(r.Threshold),
+		Operator:               string(r.Operator),
+		Action:                 string(r.Action),
+		Priority:               r.Priority,
+		Severity:               string(r.Severity),
+		IsActive:               r.IsActive,
+		DepartmentID:           r.DepartmentID,
+		ServiceIDs:             r.ServiceIds,
+		ForSeconds:             r.ForSeconds,
+		WindowSamples:          r.WindowSamples,
+		MinViolations:          r.MinViolations,
+		Aggregation:            string(r.Aggregation),
+		WindowSeconds:          r.WindowSeconds,
+		ChangeMode:             string(r.ChangeMode),
+		ChangeSeconds:          r.ChangeSeconds,
+		Condition:              conditionResponse(r),
+		FreshnessSeconds:       r.FreshnessSeconds,
+		Expression:             r.Expression,
+		AnomalyDeviations:      pgutil.NumericToFloat64Ptr(r.AnomalyDeviations),
+		AnomalyDirection:       anomalyDirectionResponse(r),
+		AnomalySeasonal:        r.AnomalySeasonal,
+		AbsenceSeconds:         r.AbsenceSeconds,
+		ForecastMethod:         forecastMethodResponse(r),
+		ForecastWindowSeconds:  r.ForecastWindowSeconds,
+		ForecastHorizonSeconds: r.ForecastHorizonSeconds,
+		AutoResolve:            r.AutoResolve,
+		RecoveryThreshold:      pgutil.NumericToFloat64Ptr(r.RecoveryThreshold),
+		RecoverySamples:        r.RecoverySamples,
+		CooldownSeconds:        r.CooldownSeconds,
+		WebhookURL:             r.WebhookUrl,
+		WebhookHeaders:         webhookHeadersResponse(r),
+		WebhookBodyTemplate:    r.WebhookBodyTemplate,
+		Schedule:               scheduleOf(r),
+		InSchedule:             InSchedule(r, time.Now()),
+		Tiers:                  tiersOf(r),
+		CreatedAt:              r.CreatedAt,
+		UpdatedAt:              r.UpdatedAt,
+		Revision:               r.Revision,
+		Status:                 string(r.Status),
+		Owner:                  r.Owner,
+		Author:                 r.Author,
+	}
+}
+
+// anomalyDirectionResponse only reports the direction of anomaly rules
+func anomalyDirectionResponse(rule *db.QualityRule) string {
+	if !rule.AnomalyDeviations.Valid {
+		return ""
 	}
+	return string(rule.AnomalyDirection)
+}
+
+// forecastMethodResponse omits the forecast method of rules without a forecast
+func forecastMethodResponse(rule *db.QualityRule) string {
+	if !isForecastRule(rule) {
+		return ""
+	}
+	return string(rule.ForecastMethod)
+}
+
+// conditionResponse decodes the stored condition for API responses
+func conditionResponse(rule *db.QualityRule) *Condition {
+	cond, err := ParseCondition(rule.Condition)
+	if err != nil {
+		return nil
+	}
+	return cond
+}
+
+// webhookHeadersResponse decodes the stored webhook headers for API responses
+func webhookHeadersResponse(rule *db.QualityRule) map[string]string {
+	var headers map[string]string
+	if err := json.Unmarshal(rule.WebhookHeaders, &headers); err != nil || len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// RevisionResponse is an immutable record of a rule change
+type RevisionResponse struct {
+	RuleID       string                    `json:"rule_id"`
+	Revision     int32                     `json:"revision"`
+	Action       string                    `json:"action"`
+	Actor        string                    `json:"actor"`
+	Definition   CreateRuleRequest         `json:"definition"`
+	Diff         map[string]RevisionChange `json:"diff"`
+	RolledBackTo *int32                    `json:"rolled_back_to,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at"`
+}
+
+func ToRevisionResponse(rev *db.RuleRevision) RevisionResponse {
+	resp := RevisionResponse{
+		RuleID:       rev.RuleID,
+		Revision:     rev.Revision,
+		Action:       string(rev.Action),
+		Actor:        rev.Actor,
+		RolledBackTo: rev.RolledBackTo,
+		CreatedAt:    rev.CreatedAt,
+	}
+	resp.Definition, _ = DefinitionOf(rev)
+	if err := json.Unmarshal(rev.Diff, &resp.Diff); err != nil || resp.Diff == nil {
+		resp.Diff = map[string]RevisionChange{}
+	}
+	return resp
+}
+
+func ToRevisionResponseList(revisions []db.RuleRevision) []RevisionResponse {
+	result := make([]RevisionResponse, len(revisions))
+	for i, rev := range revisions {
+		result[i] = ToRevisionResponse(&rev)
+	}
+	return result
+}
+
+// ReviewRequest carries the optional comment of a review step
+type ReviewRequest struct {
+	Comment *string `json:"comment,omitempty"`
+}
+
+// StatusChangeResponse is a logged change of a rule's review status
+type StatusChangeResponse struct {
+	ID         int64     `json:"id"`
+	RuleID     string    `json:"rule_id"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor"`
+	Comment    *string   `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func ToStatusChangeResponseList(changes []db.RuleStatusChange) []StatusChangeResponse {
+	result := make([]StatusChangeResponse, len(changes))
+	for i, c := range changes {
+		result[i] = StatusChangeResponse{
+			ID:        c.ID,
+			RuleID:    c.RuleID,
+			ToStatus:  string(c.ToStatus),
+			Actor:     c.Actor,
+			Comment:   c.Comment,
+			CreatedAt: c.CreatedAt,
+		}
+		if c.FromStatus.Valid {
+			from := string(c.FromStatus.RuleStatus)
+			result[i].FromStatus = &from
+		}
+	}
+	return result
+}
+
+// ChangeRequestResponse is a change to a published rule, or to an action published rules
+// use, that waits for review. Payload holds the requested settings of an UPDATE.
+type ChangeRequestResponse struct {
+	ID            int64           `json:"id"`
+	Target        string          `json:"target"`
+	TargetID      string          `json:"target_id"`
+	Kind          string          `json:"kind"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	RequestedBy   string          `json:"requested_by"`
+	Comment       *string         `json:"comment,omitempty"`
+	Status        string          `json:"status"`
+	ReviewedBy    *string         `json:"reviewed_by,omitempty"`
+	ReviewComment *string         `json:"review_comment,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ReviewedAt    *time.Time      `json:"reviewed_at,omitempty"`
+}
+
+func ToChangeRequestResponse(c *db.ChangeRequest) ChangeRequestResponse {
+	resp := ChangeRequestResponse{
+		ID:            c.ID,
+		Target:        string(c.Target),
+		TargetID:      c.TargetID,
+		Kind:          string(c.Kind),
+		Payload:       c.Payload,
+		RequestedBy:   c.RequestedBy,
+		Comment:       c.Comment,
+		Status:        string(c.Status),
+		ReviewedBy:    c.ReviewedBy,
+		ReviewComment: c.ReviewComment,
+		CreatedAt:     c.CreatedAt,
+	}
+	if c.ReviewedAt.Valid {
+		resp.ReviewedAt = &c.ReviewedAt.Time
+	}
+	return resp
+}
+
+func ToChangeRequestResponseList(changes []db.ChangeRequest) []ChangeRequestResponse {
+	result := make([]ChangeRequestResponse, len(changes))
+	for i := range changes {
+		result[i] = ToChangeRequestResponse(&changes[i])
+	}
+	return result
+}
+
+// DraftViolationResponse is a violation of a rule in shadow mode that opened no incident
+type DraftViolationResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	RuleID       string     `json:"rule_id"`
+	RuleRevision int32      `json:"rule_revision"`
+	ServiceID    string     `json:"service_id"`
+	MetricID     *uuid.UUID `json:"metric_id"`
+	MetricType   string     `json:"metric_type"`
+	Severity     string     `json:"severity"`
+	Message      string     `json:"message"`
+	RecordedAt   time.Time  `json:"recorded_at"`
+}
+
+func ToDraftViolationResponseList(violations []db.DraftViolation) []DraftViolationResponse {
+	result := make([]DraftViolationResponse, len(violations))
+	for i, v := range violations {
+		result[i] = DraftViolationResponse{
+			ID:           v.ID,
+			RuleID:       v.RuleID,
+			RuleRevision: v.RuleRevision,
+			ServiceID:    v.ServiceID,
+			MetricID:     v.MetricID,
+			MetricType:   string(v.MetricType),
+			Severity:     string(v.Severity),
+			Message:      v.Message,
+			RecordedAt:   v.RecordedAt,
+		}
+	}
+	return result
 }
 
 func ToResponseList(rules []db.QualityRule) []RuleResponse {
@@ -71,20 +420,9 @@ func ToResponseList(rules []db.QualityRule) []RuleResponse {
 
 // ToFilteredResponse converts a ListRulesFilteredRow (with trigger_count) to RuleResponse
 func ToFilteredResponse(r *db.ListRulesFilteredRow) RuleResponse {
-	return RuleResponse{
-		ID:           r.ID,
-		MetricType:   string(r.MetricType),
-		Threshold:    pgutil.NumericToFloat64(r.Threshold),
-		Operator:     string(r.Operator),
-		Action:       string(r.Action),
-		Priority:     r.Priority,
-		Severity:     string(r.Severity),
-		IsActive:     r.IsActive,
-		DepartmentID: r.DepartmentID,
-		CreatedAt:    r.CreatedAt,
-		UpdatedAt:    r.UpdatedAt,
-		TriggerCount: r.TriggerCount,
-	}
+	resp := ToResponse(&r.QualityRule)
+	resp.TriggerCount = r.TriggerCount
+	return resp
 }
 
 func ToFilteredResponseList(rows []db.ListRulesFilteredRow) []RuleResponse {
@@ -97,36 +435,21 @@ func ToFilteredResponseList(rows []db.ListRulesFilteredRow) []RuleResponse {
 
 // TopTriggeredRuleResponse includes trigger stats
 type TopTriggeredRuleResponse struct {
-	ID              string     `json:"id"`
-	MetricType      string     `json:"metric_type"`
-	Threshold       float64    `json:"threshold"`
-	Operator        string     `json:"operator"`
-	Action          string     `json:"action"`
-	Priority        int32      `json:"priority"`
-	Severity        string     `json:"severity"`
-	IsActive        bool       `json:"is_active"`
-	DepartmentID    *string    `json:"department_id,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
-	TriggerCount    int32      `json:"trigger_count"`
+	RuleResponse
 	LastTriggeredAt *time.Time `json:"last_triggered_at"`
 }
 
 func ToTopTriggeredResponse(r *db.GetTopTriggeredRulesRow) TopTriggeredRuleResponse {
 	resp := TopTriggeredRuleResponse{
-		ID:           r.ID,
-		MetricType:   string(r.MetricType),
-		Threshold:    pgutil.NumericToFloat64(r.Threshold),
-		Operator:     string(r.Operator),
-		Action:       string(r.Action),
-		Priority:     r.Priority,
-		Severity:     string(r.Severity),
-		IsActive:     r.IsActive,
-		DepartmentID: r.DepartmentID,
-		CreatedAt:    r.CreatedAt,
-		UpdatedAt:    r.UpdatedAt,
-		TriggerCount: r.TriggerCount,
+		RuleResponse: ToResponse(&r.QualityRule),
 	}
+	resp.TriggerCount = r.TriggerCount
+	resp.setSuppressedCounts(map[db.SuppressionReason]int32{
+		db.SuppressionReasonTHROTTLED: r.ThrottledCount,
+		db.SuppressionReasonSILENCED:  r.SilencedCount,
+		db.SuppressionReasonSHADOWED:  r.ShadowedCount,
+		db.SuppressionReasonDRAFT:     r.DraftCount,
+	})
 
 	// Handle LastTriggeredAt which is interface{} (can be nil or time.Time)
 	if r.LastTriggeredAt != nil {
@@ -145,3 +468,156 @@ func ToTopTriggeredResponseList(rows []db.GetTopTriggeredRulesRow) []TopTriggere
 	}
 	return result
 }
+
+// BaselineResponse is the learned baseline of a series bucket
+type BaselineResponse struct {
+	Bucket         int16     `json:"bucket"`
+	Label          string    `json:"label"`
+	Mean           float64   `json:"mean"`
+	Deviation      float64   `json:"deviation"`
+	SampleCount    int64     `json:"sample_count"`
+	Warm           bool      `json:"warm"`
+	LastRecordedAt time.Time `json:"last_recorded_at"`
+}
+
+// SeriesBaselineResponse lists the baselines of a series. Current is the hour-of-week
+// baseline that seasonal anomaly rules use right now.
+type SeriesBaselineResponse struct {
+	ServiceID  string             `json:"service_id"`
+	MetricType string             `json:"metric_type"`
+	Overall    *BaselineResponse  `json:"overall"`
+	Current    *BaselineResponse  `json:"current"`
+	HourOfWeek []BaselineResponse `json:"hour_of_week"`
+}
+
+func ToBaselineResponse(b Baseline) BaselineResponse {
+	return BaselineResponse{
+		Bucket:         b.Bucket,
+		Label:          bucketName(b.Bucket),
+		Mean:           b.Mean,
+		Deviation:      b.Deviation(),
+		SampleCount:    b.Count,
+		Warm:           b.Warm(),
+		LastRecordedAt: b.LastRecordedAt,
+	}
+}
+
+func ToSeriesBaselineResponse(key SeriesKey, baselines []Baseline, now time.Time) SeriesBaselineResponse {
+	resp := SeriesBaselineResponse{
+		ServiceID:  key.ServiceID,
+		MetricType: string(key.MetricType),
+		HourOfWeek: []BaselineResponse{},
+	}
+	current := HourOfWeek(now)
+	for _, b := range baselines {
+		r := ToBaselineResponse(b)
+		switch {
+		case b.Bucket == OverallBucket:
+			resp.Overall = &r
+			continue
+		case b.Bucket == current:
+			resp.Current = &r
+		}
+		resp.HourOfWeek = append(resp.HourOfWeek, r)
+	}
+	return resp
+}
+
+// SetEvaluationPolicyRequest configures how a metric type handles several matching rules
+type SetEvaluationPolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+// EvaluationPolicyResponse is the evaluation policy of a metric type. UpdatedAt is
+// omitted for metric types using the default policy.
+type EvaluationPolicyResponse struct {
+	MetricType string     `json:"metric_type"`
+	Policy     string     `json:"policy"`
+	IsDefault  bool       `json:"is_default"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+func ToEvaluationPolicyResponse(p *db.MetricEvaluationPolicy) EvaluationPolicyResponse {
+	return EvaluationPolicyResponse{
+		MetricType: string(p.MetricType),
+		Policy:     string(p.Policy),
+		UpdatedAt:  &p.UpdatedAt,
+	}
+}
+
+// ToEvaluationPolicyResponseList lists the policy of every metric type, filling in the
+// default for metric types without a configured policy
+func ToEvaluationPolicyResponseList(policies []db.MetricEvaluationPolicy) []EvaluationPolicyResponse {
+	configured := make(map[db.MetricType]*db.MetricEvaluationPolicy, len(policies))
+	for i := range policies {
+		configured[policies[i].MetricType] = &policies[i]
+	}
+
+	result := make([]EvaluationPolicyResponse, len(metricTypes))
+	for i, mt := range metricTypes {
+		if p, ok := configured[mt]; ok {
+			result[i] = ToEvaluationPolicyResponse(p)
+			continue
+		}
+		result[i] = EvaluationPolicyResponse{
+			MetricType: string(mt),
+			Policy:     string(DefaultEvaluationPolicy),
+			IsDefault:  true,
+		}
+	}
+	return result
+}
+
+// CreateActionRequest registers a rule action. Behavior selects what the rule worker
+// does on a violation: OPEN_INCIDENT, NOTIFY_ONLY, WEBHOOK or THROTTLE.
+type CreateActionRequest struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Behavior    string  `json:"behavior"`
+	// DepartmentID routes the violations of rules without a department
+	DepartmentID *string `json:"department_id,omitempty"`
+	// MessageTemplate renders the incident or notification message with the fields of
+	// a webhook body, e.g. "{{.ServiceID}} quality degraded: {{.Message}}"
+	MessageTemplate *string `json:"message_template,omitempty"`
+}
+
+// UpdateActionRequest changes an action; its behaviour is fixed once created
+type UpdateActionRequest struct {
+	Name            string  `json:"name"`
+	Description     *string `json:"description,omitempty"`
+	DepartmentID    *string `json:"department_id,omitempty"`
+	MessageTemplate *string `json:"message_template,omitempty"`
+}
+
+type ActionResponse struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Description     *string   `json:"description,omitempty"`
+	Behavior        string    `json:"behavior"`
+	DepartmentID    *string   `json:"department_id,omitempty"`
+	MessageTemplate *string   `json:"message_template,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func ToActionResponse(a *db.RuleAction) ActionResponse {
+	return ActionResponse{
+		ID:              a.ID,
+		Name:            a.Name,
+		Description:     a.Description,
+		Behavior:        string(a.Behavior),
+		DepartmentID:    a.DepartmentID,
+		MessageTemplate: a.MessageTemplate,
+		CreatedAt:       a.CreatedAt,
+		UpdatedAt:       a.UpdatedAt,
+	}
+}
+
+func ToActionResponseList(actions []db.RuleAction) []ActionResponse {
+	result := make([]ActionResponse, len(actions))
+	for i := range actions {
+		result[i] = ToActionResponse(&actions[i])
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package rule
+
+import (
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// DefaultEvaluationPolicy is the policy of metric types without a configured one: every
+// matching rule fires
+const DefaultEvaluationPolicy = db.EvaluationPolicyALLMATCHES
+
+// metricTypes lists every metric type, in the order policies are listed
+var metricTypes = []db.MetricType{
+	db.MetricTypeLATENCYMS,
+	db.MetricTypePACKETLOSS,
+	db.MetricTypeERRORRATE,
+	db.MetricTypeBUFFERRATIO,
+}
+
+// IsValidEvaluationPolicy reports whether p is a known evaluation policy
+func IsValidEvaluationPolicy(p db.EvaluationPolicy) bool {
+	switch p {
+	case db.EvaluationPolicyALLMATCHES, db.EvaluationPolicyFIRSTMATCH:
+		return true
+	default:
+		return false
+	}
+}
+
+// indexPolicies maps the configured policies by metric type
+func indexPolicies(policies []db.MetricEvaluationPolicy) map[db.MetricType]db.EvaluationPolicy {
+	byType := make(map[db.MetricType]db.EvaluationPolicy, len(policies))
+	for _, p := range policies {
+		byType[p.MetricType] = p.Policy
+	}
+	return byType
+}
+
+// policyOf returns the policy of a metric type, or the default when none is configured
+func policyOf(policies map[db.MetricType]db.EvaluationPolicy, metricType db.MetricType) db.EvaluationPolicy {
+	if p, ok := policies[metricType]; ok {
+		return p
+	}
+	return DefaultEvaluationPolicy
+}
@@ -0,0 +1,122 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+)
+
+func TestToEvaluationPolicyResponseList(t *testing.T) {
+	updatedAt := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	list := ToEvaluationPolicyResponseList([]db.MetricEvaluationPolicy{
+		{MetricType: db.MetricTypeERRORRATE, Policy: db.EvaluationPolicyFIRSTMATCH, UpdatedAt: updatedAt},
+	})
+
+	if len(list) != len(metricTypes) {
+		t.Fatalf("Expected a policy for every metric type, got %+v", list)
+	}
+	for _, p := range list {
+		configured := p.MetricType == string(db.MetricTypeERRORRATE)
+		if configured && (p.Policy != "FIRST_MATCH" || p.IsDefault || p.UpdatedAt == nil) {
+			t.Errorf("Expected the configured FIRST_MATCH policy, got %+v", p)
+		}
+		if !configured && (p.Policy != "ALL_MATCHES" || !p.IsDefault || p.UpdatedAt != nil) {
+			t.Errorf("Expected the default policy for %s, got %+v", p.MetricType, p)
+		}
+	}
+}
+
+func TestRuleHandler_EvaluationPolicies(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	tests := []struct {
+		metricType string
+		body       string
+		wantStatus int
+	}{
+		{"LATENCY_MS", `{"policy": "FIRST_MATCH"}`, http.StatusOK},
+		{"LATENCY_MS", `{"policy": "SOME_MATCHES"}`, http.StatusBadRequest},
+		{"CPU", `{"policy": "FIRST_MATCH"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/evaluation-policies/"+tt.metricType, bytes.NewReader([]byte(tt.body))))
+		if rr.Code != tt.wantStatus {
+			t.Errorf("PUT %s %s: expected status %d, got %d. Body: %s", tt.metricType, tt.body, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/evaluation-policies", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Data []EvaluationPolicyResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	policies := make(map[string]string)
+	for _, p := range response.Data {
+		policies[p.MetricType] = p.Policy
+	}
+	if policies["LATENCY_MS"] != "FIRST_MATCH" || policies["ERROR_RATE"] != "ALL_MATCHES" {
+		t.Errorf("Expected FIRST_MATCH for LATENCY_MS and the default elsewhere, got %+v", response.Data)
+	}
+}
+
+func TestWorker_FirstMatch(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "outage", Threshold: 300, Priority: 1, Severity: db.IncidentSeverityHIGH, IsActive: true})
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "latency", Threshold: 150, Priority: 2, IsActive: true})
+	if _, err := wt.worker.ruleRepo.SetEvaluationPolicy(ctx, db.MetricTypeLATENCYMS, db.EvaluationPolicyFIRSTMATCH); err != nil {
+		t.Fatalf("Failed to set evaluation policy: %v", err)
+	}
+	wt.worker.EnableEvaluations(time.Hour)
+
+	// Both rules match; only the higher-priority rule opens an incident
+	wt.record(t, 400)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 || incidents[0].RuleID != "outage" {
+		t.Fatalf("Expected 1 incident of rule outage, got %+v", incidents)
+	}
+	suppressions, err := wt.worker.ruleRepo.ListSuppressions(ctx, "latency")
+	if err != nil {
+		t.Fatalf("Failed to list suppressions: %v", err)
+	}
+	if len(suppressions) != 1 || suppressions[0].Reason != db.SuppressionReasonSHADOWED || suppressions[0].SuppressedCount != 1 {
+		t.Errorf("Expected 1 shadowed violation of rule latency, got %+v", suppressions)
+	}
+	metrics, err := wt.metricRepo.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list metrics: %v", err)
+	}
+	evaluations, err := wt.queries.ListMetricEvaluations(ctx, metrics[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list evaluations: %v", err)
+	}
+	for _, e := range evaluations {
+		if e.RuleID == "latency" && (e.Outcome != db.EvaluationOutcomeSUPPRESSED || e.Reason == nil || *e.Reason != "shadowed by rule outage") {
+			t.Errorf("Expected rule latency to be recorded as shadowed, got %+v", e)
+		}
+	}
+
+	// The lower-priority rule fires once it is the first match
+	wt.record(t, 200)
+	if incidents := wt.incidents(t); len(incidents) != 2 {
+		t.Errorf("Expected rule latency to open an incident, got %d incidents", len(incidents))
+	}
+}
@@ -0,0 +1,99 @@
+package rule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+// recoveryThreshold returns the threshold a series must get back under for the rule to recover
+func recoveryThreshold(rule *db.QualityRule) float64 {
+	if rule.RecoveryThreshold.Valid {
+		return pgutil.NumericToFloat64(rule.RecoveryThreshold)
+	}
+	return pgutil.NumericToFloat64(rule.Threshold)
+}
+
+// currentValue returns the value a rule compares for the latest sample: the aggregate
+// over the window for aggregation rules, the change for rate-of-change rules, the
+// forecast at the end of the horizon for forecast rules, otherwise the sample itself
+func currentValue(rule *db.QualityRule, samples []Sample) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	if isForecastRule(rule) {
+		f, ok := forecastOf(rule, samples)
+		return f.Value, ok
+	}
+	if isChangeRule(rule) {
+		delta, _, ok := changeOf(rule, samples)
+		return delta, ok
+	}
+	if rule.Aggregation != "" && rule.Aggregation != db.RuleAggregationNONE {
+		window := samplesInWindow(samples, time.Duration(rule.WindowSeconds)*time.Second)
+		return Aggregate(rule.Aggregation, window)
+	}
+	return samples[len(samples)-1].Value, true
+}
+
+// Recovered reports whether the latest sample of a series is back to normal. A rule
+// with a recovery threshold only recovers once the value no longer violates it, so a
+// rule firing above 150 with a recovery threshold of 120 recovers at 120 or below.
+// Composite, expression and anomaly rules have no single threshold; they recover when
+// their condition or expression no longer matches or the value is back within baseline.
+func Recovered(rule *db.QualityRule, samples []Sample) (float64, bool) {
+	value, ok := currentValue(rule, samples)
+	if !ok {
+		return 0, false
+	}
+	if len(rule.Condition) > 0 || rule.Expression != nil || rule.AnomalyDeviations.Valid {
+		return value, true
+	}
+	return value, !compare(rule.Operator, value, recoveryThreshold(rule))
+}
+
+// recoveryStreak counts the consecutive samples at the end of a series, recorded after
+// since, on which a rule has recovered. Counting stops at the rule's recovery_samples.
+// The streak is derived from the series rather than kept by the worker, so it survives
+// restarts and is the same on every instance.
+func recoveryStreak(rule *db.QualityRule, samples []Sample, since time.Time) int32 {
+	var streak int32
+	for end := len(samples); end > 0 && streak < rule.RecoverySamples; end-- {
+		if !samples[end-1].RecordedAt.After(since) {
+			break
+		}
+		if _, healthy := Recovered(rule, samples[:end]); !healthy {
+			break
+		}
+		streak++
+	}
+	return streak
+}
+
+// recoveryMessage describes why a rule resolved its incident
+func recoveryMessage(rule *db.QualityRule, value float64) string {
+	var what string
+	switch {
+	case len(rule.Condition) > 0:
+		what = "composite condition no longer matched"
+	case rule.Expression != nil:
+		what = "expression no longer matched: " + *rule.Expression
+	case rule.AnomalyDeviations.Valid:
+		what = fmt.Sprintf("%s back within baseline: %.2f", rule.MetricType, value)
+	case isForecastRule(rule):
+		what = fmt.Sprintf("%s forecast no longer breaches: %.2f (recovery threshold: %.2f, operator: %s)",
+			rule.MetricType, value, recoveryThreshold(rule), rule.Operator)
+	case isChangeRule(rule):
+		what = fmt.Sprintf("%s change recovered: %+.2f (recovery threshold: %.2f, operator: %s)",
+			rule.MetricType, value, recoveryThreshold(rule), rule.Operator)
+	default:
+		what = fmt.Sprintf("%s recovered: %.2f (recovery threshold: %.2f, operator: %s)",
+			rule.MetricType, value, recoveryThreshold(rule), rule.Operator)
+	}
+	if rule.RecoverySamples > 1 {
+		return fmt.Sprintf("Auto-resolved after %d consecutive healthy samples, %s", rule.RecoverySamples, what)
+	}
+	return "Auto-resolved, " + what
+}
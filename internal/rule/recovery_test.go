@@ -0,0 +1,121 @@
+package rule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func TestRecovered(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     *db.QualityRule
+		values   []float64
+		expected bool
+	}{
+		{
+			name:     "back under threshold",
+			rule:     &db.QualityRule{Operator: db.RuleOperatorValue0, Threshold: pgutil.Float64ToNumeric(150)},
+			values:   []float64{200, 140},
+			expected: true,
+		},
+		{
+			name: "between recovery threshold and threshold",
+			rule: &db.QualityRule{
+				Operator:          db.RuleOperatorValue0,
+				Threshold:         pgutil.Float64ToNumeric(150),
+				RecoveryThreshold: pgutil.Float64ToNumeric(120),
+			},
+			values:   []float64{200, 140},
+			expected: false,
+		},
+		{
+			name: "under recovery threshold",
+			rule: &db.QualityRule{
+				Operator:          db.RuleOperatorValue0,
+				Threshold:         pgutil.Float64ToNumeric(150),
+				RecoveryThreshold: pgutil.Float64ToNumeric(120),
+			},
+			values:   []float64{200, 120},
+			expected: true,
+		},
+		{
+			name: "aggregate still violating",
+			rule: &db.QualityRule{
+				Operator:      db.RuleOperatorValue0,
+				Threshold:     pgutil.Float64ToNumeric(150),
+				Aggregation:   db.RuleAggregationAVG,
+				WindowSeconds: 120,
+			},
+			values:   []float64{300, 300, 100},
+			expected: false,
+		},
+		{
+			name:     "less-than rule",
+			rule:     &db.QualityRule{Operator: db.RuleOperatorValue2, Threshold: pgutil.Float64ToNumeric(10)},
+			values:   []float64{5, 12},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := Recovered(tt.rule, series(start, tt.values...))
+			if ok != tt.expected {
+				t.Errorf("Recovered() = %v, want %v", ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecoveryStreak(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	rule := &db.QualityRule{
+		Operator:          db.RuleOperatorValue0,
+		Threshold:         pgutil.Float64ToNumeric(150),
+		RecoveryThreshold: pgutil.Float64ToNumeric(120),
+		RecoverySamples:   3,
+	}
+
+	tests := []struct {
+		name   string
+		values []float64
+		since  int
+		want   int32
+	}{
+		{"healthy since the violation", []float64{200, 100, 110}, 0, 2},
+		{"capped at recovery_samples", []float64{200, 100, 110, 100, 90}, 0, 3},
+		{"broken by a sample above the recovery threshold", []float64{200, 100, 130, 110}, 0, 1},
+		{"only samples after the violation count", []float64{100, 100, 200, 100}, 2, 1},
+		{"violation is the latest sample", []float64{100, 200}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := series(start, tt.values...)
+			if got := recoveryStreak(rule, samples, samples[tt.since].RecordedAt); got != tt.want {
+				t.Errorf("recoveryStreak() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoveryMessage(t *testing.T) {
+	rule := &db.QualityRule{
+		MetricType:        db.MetricTypeLATENCYMS,
+		Operator:          db.RuleOperatorValue0,
+		Threshold:         pgutil.Float64ToNumeric(150),
+		RecoveryThreshold: pgutil.Float64ToNumeric(120),
+		RecoverySamples:   3,
+	}
+
+	msg := recoveryMessage(rule, 110)
+	if !strings.Contains(msg, "3 consecutive") || !strings.Contains(msg, "120.00") {
+		t.Errorf("Expected message to report samples and recovery threshold, got %q", msg)
+	}
+}
@@ -2,17 +2,25 @@ package rule
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/pkg/pgutil"
 )
 
 type Repository struct {
-	q *db.Queries
+	pool *pgxpool.Pool
+	q    *db.Queries
 }
 
-func NewRepository(q *db.Queries) *Repository {
-	return &Repository{q: q}
+func NewRepository(pool *pgxpool.Pool, q *db.Queries) *Repository {
+	return &Repository{pool: pool, q: q}
 }
 
 func (r *Repository) Get(ctx context.Context, id string) (*db.QualityRule, error) {
@@ -31,19 +39,148 @@ func (r *Repository) ListActive(ctx context.Context) ([]db.QualityRule, error) {
 	return r.q.ListActiveRules(ctx)
 }
 
+// ListenRuleChanges opens a connection outside the pool that listens for changes to
+// quality_rules. The caller closes it.
+func (r *Repository) ListenRuleChanges(ctx context.Context) (*pgx.Conn, error) {
+	pc, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := pc.Hijack()
+	if _, err := conn.Exec(ctx, "LISTEN "+RuleChangedChannel); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (r *Repository) ListActiveByMetricType(ctx context.Context, metricType db.MetricType) ([]db.QualityRule, error) {
 	return r.q.ListActiveRulesByMetricType(ctx, metricType)
 }
 
+// ListActiveForService returns the active rules of a metric type whose scope includes the service.
+// Composite rules are included when any of their conditions uses the metric type.
+func (r *Repository) ListActiveForService(ctx context.Context, metricType db.MetricType, serviceID string) ([]db.QualityRule, error) {
+	return r.q.ListActiveRulesForService(ctx, db.ListActiveRulesForServiceParams{
+		MetricType: string(metricType),
+		ServiceID:  serviceID,
+	})
+}
+
+// ListAbsenceChecks returns the active absence rules with the latest metric of every
+// service in their scope
+func (r *Repository) ListAbsenceChecks(ctx context.Context) ([]db.ListAbsenceChecksRow, error) {
+	return r.q.ListAbsenceChecks(ctx)
+}
+
+// LoadSeriesHistory loads recent samples of a series, oldest first
+func (r *Repository) LoadSeriesHistory(ctx context.Context, key SeriesKey, since, until time.Time, limit int32) ([]Sample, error) {
+	metrics, err := r.q.ListRecentSeriesMetrics(ctx, db.ListRecentSeriesMetricsParams{
+		ServiceID:  key.ServiceID,
+		MetricType: key.MetricType,
+		Since:      since,
+		Until:      until,
+		LimitVal:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The query returns newest first
+	samples := make([]Sample, len(metrics))
+	for i, m := range metrics {
+		samples[len(metrics)-1-i] = Sample{
+			MetricID:   m.ID,
+			Value:      pgutil.NumericToFloat64(m.Value),
+			RecordedAt: m.RecordedAt,
+		}
+	}
+	return samples, nil
+}
+
+// ListBacktestMetrics returns the metrics of the given types recorded between from and
+// to, oldest first. An empty serviceIDs matches all services.
+func (r *Repository) ListBacktestMetrics(ctx context.Context, metricTypes, serviceIDs []string, from, to time.Time, limit int32) ([]db.Metric, error) {
+	return r.q.ListBacktestMetrics(ctx, db.ListBacktestMetricsParams{
+		MetricTypes: metricTypes,
+		ServiceIds:  serviceIDs,
+		FromTime:    from,
+		ToTime:      to,
+		LimitVal:    limit,
+	})
+}
+
+// ListSilencesBetween returns the silences in effect at some point between from and to
+func (r *Repository) ListSilencesBetween(ctx context.Context, from, to time.Time) ([]db.Silence, error) {
+	return r.q.ListSilencesBetween(ctx, db.ListSilencesBetweenParams{
+		FromTime: from,
+		ToTime:   to,
+	})
+}
+
+// SummarizeIncidents counts the incidents a rule opened between from and to per service
+func (r *Repository) SummarizeIncidents(ctx context.Context, ruleID string, from, to time.Time) ([]db.SummarizeRuleIncidentsByServiceRow, error) {
+	return r.q.SummarizeRuleIncidentsByService(ctx, db.SummarizeRuleIncidentsByServiceParams{
+		RuleID:   ruleID,
+		FromTime: from,
+		ToTime:   to,
+	})
+}
+
+// LoadBaselines loads the stored baselines of a series
+func (r *Repository) LoadBaselines(ctx context.Context, key SeriesKey) ([]Baseline, error) {
+	rows, err := r.q.ListSeriesBaselines(ctx, db.ListSeriesBaselinesParams{
+		ServiceID:  key.ServiceID,
+		MetricType: key.MetricType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	baselines := make([]Baseline, len(rows))
+	for i, row := range rows {
+		baselines[i] = Baseline{
+			Bucket:         row.Bucket,
+			Mean:           row.Mean,
+			Variance:       row.Variance,
+			Count:          row.SampleCount,
+			LastRecordedAt: row.LastRecordedAt,
+		}
+	}
+	return baselines, nil
+}
+
+// SaveBaselines stores updated baselines of a series
+func (r *Repository) SaveBaselines(ctx context.Context, key SeriesKey, baselines []Baseline) error {
+	for _, b := range baselines {
+		if err := r.q.UpsertSeriesBaseline(ctx, db.UpsertSeriesBaselineParams{
+			ServiceID:      key.ServiceID,
+			MetricType:     key.MetricType,
+			Bucket:         b.Bucket,
+			Mean:           b.Mean,
+			Variance:       b.Variance,
+			SampleCount:    b.Count,
+			LastRecordedAt: b.LastRecordedAt,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type RuleListFilteredParams struct {
 	MetricType *db.MetricType
 	Severity   *db.IncidentSeverity
 	IsActive   *bool
-	Search     *string
-	SortBy     string
-	SortDir    string
-	Limit      int32
-	Offset     int32
+	ServiceID  *string
+	Status     *db.RuleStatus
+	// DepartmentID lists the rules of a department, e.g. with Status the rules it has
+	// waiting for review
+	DepartmentID *string
+	Search       *string
+	SortBy       string
+	SortDir      string
+	Limit        int32
+	Offset       int32
 }
 
 func (r *Repository) ListFiltered(ctx context.Context, params RuleListFilteredParams) ([]db.ListRulesFilteredRow, int, error) {
@@ -69,6 +206,18 @@ func (r *Repository) ListFiltered(ctx context.Context, params RuleListFilteredPa
 	if params.IsActive != nil {
 		filterParams.FilterIsActive = params.IsActive
 	}
+	if params.ServiceID != nil {
+		filterParams.FilterServiceID = params.ServiceID
+	}
+	if params.Status != nil {
+		filterParams.FilterStatus = db.NullRuleStatus{
+			RuleStatus: *params.Status,
+			Valid:      true,
+		}
+	}
+	if params.DepartmentID != nil {
+		filterParams.FilterDepartmentID = params.DepartmentID
+	}
 	if params.Search != nil {
 		filterParams.FilterSearch = params.Search
 	}
@@ -79,10 +228,13 @@ func (r *Repository) ListFiltered(ctx context.Context, params RuleListFilteredPa
 	}
 
 	countParams := db.CountRulesFilteredParams{
-		FilterMetricType: filterParams.FilterMetricType,
-		FilterSeverity:   filterParams.FilterSeverity,
-		FilterIsActive:   filterParams.FilterIsActive,
-		FilterSearch:     filterParams.FilterSearch,
+		FilterMetricType:   filterParams.FilterMetricType,
+		FilterSeverity:     filterParams.FilterSeverity,
+		FilterIsActive:     filterParams.FilterIsActive,
+		FilterServiceID:    filterParams.FilterServiceID,
+		FilterStatus:       filterParams.FilterStatus,
+		FilterDepartmentID: filterParams.FilterDepartmentID,
+		FilterSearch:       filterParams.FilterSearch,
 	}
 	total, err := r.q.CountRulesFiltered(ctx, countParams)
 	if err != nil {
@@ -92,57 +244,837 @@ func (r *Repository) ListFiltered(ctx context.Context, params RuleListFilteredPa
 	return rules, int(total), nil
 }
 
-func (r *Repository) Create(ctx context.Context, req CreateRuleRequest) (*db.QualityRule, error) {
-	rule, err := r.q.CreateRule(ctx, db.CreateRuleParams{
-		ID:           req.ID,
-		MetricType:   db.MetricType(req.MetricType),
-		Threshold:    pgutil.Float64ToNumeric(req.Threshold),
-		Operator:     db.RuleOperator(req.Operator),
-		Action:       db.RuleAction(req.Action),
-		Priority:     req.Priority,
-		Severity:     db.IncidentSeverity(req.Severity),
-		IsActive:     req.IsActive,
-		DepartmentID: req.DepartmentID,
+// Create stores a new rule as a draft owned by actor and records its CREATED revision
+func (r *Repository) Create(ctx context.Context, req CreateRuleRequest, actor string) (*db.QualityRule, error) {
+	var rule *db.QualityRule
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		created, err := createRule(ctx, qtx, req)
+		if err != nil {
+			return err
+		}
+		if created, _, err = recordRevision(ctx, qtx, nil, created, db.RuleRevisionActionCREATED, actor, nil); err != nil {
+			return err
+		}
+		rule, err = draftRule(ctx, qtx, nil, created, actor)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &rule, nil
+	return rule, nil
 }
 
-func (r *Repository) Update(ctx context.Context, id string, req UpdateRuleRequest) (*db.QualityRule, error) {
-	rule, err := r.q.UpdateRule(ctx, db.UpdateRuleParams{
-		ID:           id,
-		MetricType:   db.MetricType(req.MetricType),
-		Threshold:    pgutil.Float64ToNumeric(req.Threshold),
-		Operator:     db.RuleOperator(req.Operator),
-		Action:       db.RuleAction(req.Action),
-		Priority:     req.Priority,
-		Severity:     db.IncidentSeverity(req.Severity),
-		IsActive:     req.IsActive,
-		DepartmentID: req.DepartmentID,
+// Update overwrites a rule and records its UPDATED revision. A changed rule returns to
+// draft until its change is reviewed. The change to a published rule is requested for
+// review instead, so the rule keeps alerting with its published definition: the rule is
+// returned unchanged with the pending request. Switching a published rule on or off is
+// requested through SetActive, and an update that does so fails with
+// ErrActivationChange.
+func (r *Repository) Update(ctx context.Context, id string, req RuleDefinition, actor string) (*db.QualityRule, *db.ChangeRequest, error) {
+	var (
+		rule   *db.QualityRule
+		change *db.ChangeRequest
+	)
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		old, err := qtx.GetRuleForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if isPublished(&old) {
+			if req.IsActive != old.IsActive {
+				return ErrActivationChange
+			}
+			rule = &old
+			change, err = requestUpdate(ctx, qtx, &old, req, nil, actor)
+			return err
+		}
+		updated, err := updateRule(ctx, qtx, id, req)
+		if err != nil {
+			return err
+		}
+		if updated, _, err = recordRevision(ctx, qtx, &old, updated, db.RuleRevisionActionUPDATED, actor, nil); err != nil {
+			return err
+		}
+		rule, err = draftRule(ctx, qtx, &old, updated, actor)
+		return err
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rule, change, nil
+}
+
+func createRule(ctx context.Context, q *db.Queries, req CreateRuleRequest) (*db.QualityRule, error) {
+	rule, err := ruleFromDefinition(req.ID, &req.RuleDefinition)
 	if err != nil {
 		return nil, err
 	}
-	return &rule, nil
+	created, err := q.CreateRule(ctx, ruleParams(rule))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func updateRule(ctx context.Context, q *db.Queries, id string, req RuleDefinition) (*db.QualityRule, error) {
+	rule, err := ruleFromDefinition(id, &req)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := q.UpdateRule(ctx, db.UpdateRuleParams(ruleParams(rule)))
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ruleFromDefinition builds the unsaved rule a definition describes. Create and Update
+// store exactly this rule, and the backtest replays it.
+func ruleFromDefinition(id string, req *RuleDefinition) (*db.QualityRule, error) {
+	shape, err := shapeOf(req)
+	if err != nil {
+		return nil, err
+	}
+	threshold, severity := baseTier(req.Tiers, shape.Threshold, forecastSeverity(req.ForecastMethod, req.Severity))
+	return &db.QualityRule{
+		ID:                     id,
+		MetricType:             shape.MetricType,
+		Threshold:              pgutil.Float64ToNumeric(threshold),
+		Operator:               shape.Operator,
+		Action:                 req.Action,
+		Priority:               req.Priority,
+		Severity:               db.IncidentSeverity(severity),
+		IsActive:               req.IsActive,
+		DepartmentID:           req.DepartmentID,
+		ServiceIds:             normalizeServiceIDs(req.ServiceIDs),
+		ForSeconds:             req.ForSeconds,
+		WindowSamples:          req.WindowSamples,
+		MinViolations:          req.MinViolations,
+		Aggregation:            aggregationOrDefault(req.Aggregation),
+		WindowSeconds:          req.WindowSeconds,
+		ChangeMode:             changeModeOrDefault(req.ChangeMode),
+		ChangeSeconds:          req.ChangeSeconds,
+		AbsenceSeconds:         req.AbsenceSeconds,
+		Condition:              shape.Condition,
+		FreshnessSeconds:       req.FreshnessSeconds,
+		MetricTypes:            shape.MetricTypes,
+		AutoResolve:            req.AutoResolve,
+		RecoveryThreshold:      pgutil.Float64PtrToNumeric(req.RecoveryThreshold),
+		RecoverySamples:        recoverySamplesOrDefault(req.RecoverySamples),
+		CooldownSeconds:        req.CooldownSeconds,
+		WebhookUrl:             req.WebhookURL,
+		WebhookHeaders:         webhookHeaders(req.WebhookHeaders),
+		WebhookBodyTemplate:    req.WebhookBodyTemplate,
+		Expression:             req.Expression,
+		AnomalyDeviations:      pgutil.Float64PtrToNumeric(req.AnomalyDeviations),
+		AnomalyDirection:       anomalyDirectionOrDefault(req.AnomalyDirection),
+		AnomalySeasonal:        req.AnomalySeasonal,
+		ForecastMethod:         forecastMethodOrDefault(req.ForecastMethod),
+		ForecastWindowSeconds:  req.ForecastWindowSeconds,
+		ForecastHorizonSeconds: req.ForecastHorizonSeconds,
+		Schedule:               scheduleJSON(req.Schedule),
+		Tiers:                  tiersJSON(req.Tiers),
+	}, nil
+}
+
+// ruleParams lists the stored columns of a built rule. CreateRule and UpdateRule take
+// the same columns.
+func ruleParams(rule *db.QualityRule) db.CreateRuleParams {
+	return db.CreateRuleParams{
+		ID:                     rule.ID,
+		MetricType:             rule.MetricType,
+		Threshold:              rule.Threshold,
+		Operator:               rule.Operator,
+		Action:                 rule.Action,
+		Priority:               rule.Priority,
+		Severity:               rule.Severity,
+		IsActive:               rule.IsActive,
+		DepartmentID:           rule.DepartmentID,
+		ServiceIds:             rule.ServiceIds,
+		ForSeconds:             rule.ForSeconds,
+		WindowSamples:          rule.WindowSamples,
+		MinViolations:          rule.MinViolations,
+		Aggregation:            rule.Aggregation,
+		WindowSeconds:          rule.WindowSeconds,
+		ChangeMode:             rule.ChangeMode,
+		ChangeSeconds:          rule.ChangeSeconds,
+		AbsenceSeconds:         rule.AbsenceSeconds,
+		Condition:              rule.Condition,
+		FreshnessSeconds:       rule.FreshnessSeconds,
+		MetricTypes:            rule.MetricTypes,
+		AutoResolve:            rule.AutoResolve,
+		RecoveryThreshold:      rule.RecoveryThreshold,
+		RecoverySamples:        rule.RecoverySamples,
+		CooldownSeconds:        rule.CooldownSeconds,
+		WebhookUrl:             rule.WebhookUrl,
+		WebhookHeaders:         rule.WebhookHeaders,
+		WebhookBodyTemplate:    rule.WebhookBodyTemplate,
+		Expression:             rule.Expression,
+		AnomalyDeviations:      rule.AnomalyDeviations,
+		AnomalyDirection:       rule.AnomalyDirection,
+		AnomalySeasonal:        rule.AnomalySeasonal,
+		ForecastMethod:         rule.ForecastMethod,
+		ForecastWindowSeconds:  rule.ForecastWindowSeconds,
+		ForecastHorizonSeconds: rule.ForecastHorizonSeconds,
+		Schedule:               rule.Schedule,
+		Tiers:                  rule.Tiers,
+	}
 }
 
-func (r *Repository) SetActive(ctx context.Context, id string, active bool) (*db.QualityRule, error) {
-	rule, err := r.q.SetRuleActive(ctx, db.SetRuleActiveParams{
-		ID:       id,
+// SetActive activates or deactivates a rule and records the revision. The change to a
+// published rule is requested for review instead: the rule is returned unchanged with
+// the pending request.
+func (r *Repository) SetActive(ctx context.Context, id string, active bool, actor string) (*db.QualityRule, *db.ChangeRequest, error) {
+	var (
+		rule   *db.QualityRule
+		change *db.ChangeRequest
+	)
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		old, err := qtx.GetRuleForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if old.IsActive == active {
+			rule = &old
+			return nil
+		}
+		if isPublished(&old) {
+			kind := db.ChangeKindDEACTIVATE
+			if active {
+				kind = db.ChangeKindACTIVATE
+			}
+			rule = &old
+			change, err = requestChange(ctx, qtx, db.ChangeTargetRULE, id, kind, nil, actor)
+			return err
+		}
+		rule, err = setActive(ctx, qtx, &old, active, actor)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rule, change, nil
+}
+
+// Delete removes a rule and records its DELETED revision, which keeps the definition so
+// the rule can be restored. Deleting a published rule is requested for review instead
+// and returns the pending request.
+func (r *Repository) Delete(ctx context.Context, id string, actor string) (*db.ChangeRequest, error) {
+	var change *db.ChangeRequest
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		old, err := qtx.GetRuleForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if isPublished(&old) {
+			change, err = requestChange(ctx, qtx, db.ChangeTargetRULE, id, db.ChangeKindDELETE, nil, actor)
+			return err
+		}
+		return deleteRule(ctx, qtx, &old, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// setActive switches a locked rule on or off and records the revision
+func setActive(ctx context.Context, q *db.Queries, old *db.QualityRule, active bool, actor string) (*db.QualityRule, error) {
+	action := db.RuleRevisionActionDEACTIVATED
+	if active {
+		action = db.RuleRevisionActionACTIVATED
+	}
+	updated, err := q.SetRuleActive(ctx, db.SetRuleActiveParams{
+		ID:       old.ID,
 		IsActive: active,
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &rule, nil
+	rule, _, err := recordRevision(ctx, q, old, &updated, action, actor, nil)
+	return rule, err
+}
+
+// deleteRule records the DELETED revision of a locked rule and removes it
+func deleteRule(ctx context.Context, q *db.Queries, old *db.QualityRule, actor string) error {
+	if _, _, err := recordRevision(ctx, q, old, nil, db.RuleRevisionActionDELETED, actor, nil); err != nil {
+		return err
+	}
+	return q.DeleteRule(ctx, old.ID)
+}
+
+// Rollback restores the definition of an earlier revision, recreating the rule if it
+// was deleted, and records a ROLLED_BACK revision. The restored rule is a draft. Rolling
+// back a published rule is requested for review instead, like Update: the rule is
+// returned unchanged with the pending request.
+func (r *Repository) Rollback(ctx context.Context, id string, revision int32, actor string) (*db.QualityRule, *db.ChangeRequest, error) {
+	var (
+		rule   *db.QualityRule
+		change *db.ChangeRequest
+	)
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		target, err := qtx.GetRuleRevision(ctx, db.GetRuleRevisionParams{
+			RuleID:   id,
+			Revision: revision,
+		})
+		if err != nil {
+			return err
+		}
+		def, err := DefinitionOf(&target)
+		if err != nil {
+			return err
+		}
+
+		var old *db.QualityRule
+		current, err := qtx.GetRuleForUpdate(ctx, id)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return err
+		default:
+			old = &current
+		}
+		if old != nil && isPublished(old) {
+			rule = old
+			change, err = requestUpdate(ctx, qtx, old, def.RuleDefinition, &revision, actor)
+			return err
+		}
+
+		var restored *db.QualityRule
+		if old == nil {
+			restored, err = createRule(ctx, qtx, def)
+		} else {
+			restored, err = updateRule(ctx, qtx, id, def.RuleDefinition)
+		}
+		if err != nil {
+			return err
+		}
+		if restored, _, err = recordRevision(ctx, qtx, old, restored, db.RuleRevisionActionROLLEDBACK, actor, &revision); err != nil {
+			return err
+		}
+		rule, err = draftRule(ctx, qtx, old, restored, actor)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rule, change, nil
+}
+
+// Apply makes the stored rules and departments match a ruleset in one transaction and
+// returns the plan it carried out. A dry run only computes the plan. Every rule change
+// records a revision, and created and updated rules are drafts until reviewed. Changes
+// to published rules are requested for review instead.
+func (r *Repository) Apply(ctx context.Context, rs *Ruleset, dryRun bool, actor string) (*Plan, error) {
+	actions, err := r.Actions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRuleset(rs, actions); err != nil {
+		return nil, err
+	}
+
+	var plan *Plan
+	err = pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		current, err := qtx.ListRulesForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+		departments, err := qtx.ListDepartments(ctx)
+		if err != nil {
+			return err
+		}
+		plan, err = computePlan(rs, current, departments)
+		if err != nil || dryRun {
+			return err
+		}
+		return applyPlan(ctx, qtx, rs, plan, current, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	plan.DryRun = dryRun
+	return plan, nil
+}
+
+// applyPlan carries out a plan using the given transaction
+func applyPlan(ctx context.Context, q *db.Queries, rs *Ruleset, plan *Plan, current []db.QualityRule, actor string) error {
+	departments := make(map[string]RulesetDepartment, len(rs.Departments))
+	for _, d := range rs.Departments {
+		departments[d.ID] = d
+	}
+	for _, change := range plan.Departments {
+		d := departments[change.ID]
+		var err error
+		if change.Action == PlanCreate {
+			_, err = q.CreateDepartment(ctx, db.CreateDepartmentParams{ID: d.ID, Name: d.Name, Description: d.Description})
+		} else {
+			_, err = q.UpdateDepartment(ctx, db.UpdateDepartmentParams{ID: d.ID, Name: d.Name, Description: d.Description})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	stored := make(map[string]*db.QualityRule, len(current))
+	for i := range current {
+		stored[current[i].ID] = &current[i]
+	}
+	for _, change := range plan.Rules {
+		old := stored[change.ID]
+		def := plan.definitions[change.ID]
+		var err error
+		switch change.Action {
+		case PlanCreate:
+			var created *db.QualityRule
+			if created, err = createRule(ctx, q, def); err == nil {
+				if created, _, err = recordRevision(ctx, q, nil, created, db.RuleRevisionActionCREATED, actor, nil); err == nil {
+					_, err = draftRule(ctx, q, nil, created, actor)
+				}
+			}
+		case PlanUpdate:
+			var updated *db.QualityRule
+			if change.Review {
+				err = requestPlanned(ctx, q, old, &def.RuleDefinition, actor)
+			} else if updated, err = updateRule(ctx, q, change.ID, def.RuleDefinition); err == nil {
+				if updated, _, err = recordRevision(ctx, q, old, updated, db.RuleRevisionActionUPDATED, actor, nil); err == nil {
+					_, err = draftRule(ctx, q, old, updated, actor)
+				}
+			}
+		case PlanDelete:
+			if change.Review {
+				err = requestPlanned(ctx, q, old, nil, actor)
+			} else {
+				err = deleteRule(ctx, q, old, actor)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s rule %s: %w", strings.ToLower(change.Action), change.ID, err)
+		}
+	}
+	return nil
+}
+
+// Export returns the stored rules and departments as a ruleset
+func (r *Repository) Export(ctx context.Context) (*Ruleset, error) {
+	rules, err := r.q.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	departments, err := r.q.ListDepartments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return exportRuleset(rules, departments), nil
+}
+
+// GetRevision returns a revision of a rule
+func (r *Repository) GetRevision(ctx context.Context, ruleID string, revision int32) (*db.RuleRevision, error) {
+	rev, err := r.q.GetRuleRevision(ctx, db.GetRuleRevisionParams{
+		RuleID:   ruleID,
+		Revision: revision,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// ListRevisions returns the revisions of a rule, newest first. Revisions of deleted
+// rules are kept.
+func (r *Repository) ListRevisions(ctx context.Context, ruleID string, limit, offset int32) ([]db.RuleRevision, int, error) {
+	revisions, err := r.q.ListRuleRevisions(ctx, db.ListRuleRevisionsParams{
+		RuleID: ruleID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.q.CountRuleRevisions(ctx, ruleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return revisions, int(total), nil
+}
+
+// Submit sends a draft for review
+func (r *Repository) Submit(ctx context.Context, id, actor string, comment *string) (*db.QualityRule, error) {
+	return r.transition(ctx, id, submitStep, actor, comment)
+}
+
+// Approve publishes a rule pending review. The author of the rule's latest change
+// cannot approve it.
+func (r *Repository) Approve(ctx context.Context, id, actor string, comment *string) (*db.QualityRule, error) {
+	return r.transition(ctx, id, approveStep, actor, comment)
+}
+
+// Reject returns a rule pending review to draft
+func (r *Repository) Reject(ctx context.Context, id, actor string, comment *string) (*db.QualityRule, error) {
+	return r.transition(ctx, id, rejectStep, actor, comment)
+}
+
+// transition takes a review step for the locked rule and logs the status change
+func (r *Repository) transition(ctx context.Context, id string, step reviewStep, actor string, comment *string) (*db.QualityRule, error) {
+	var rule *db.QualityRule
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		current, err := qtx.GetRuleForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := step.allows(&current, actor); err != nil {
+			return err
+		}
+		updated, err := qtx.SetRuleStatus(ctx, db.SetRuleStatusParams{ID: id, Status: step.to})
+		if err != nil {
+			return err
+		}
+		if _, err := qtx.CreateRuleStatusChange(ctx, db.CreateRuleStatusChangeParams{
+			RuleID:     id,
+			FromStatus: db.NullRuleStatus{RuleStatus: step.from, Valid: true},
+			ToStatus:   step.to,
+			Actor:      actor,
+			Comment:    comment,
+		}); err != nil {
+			return err
+		}
+		rule = &updated
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListStatusChanges returns the logged review status changes of a rule, newest first,
+// and their total count
+func (r *Repository) ListStatusChanges(ctx context.Context, ruleID string, limit, offset int32) ([]db.RuleStatusChange, int, error) {
+	changes, err := r.q.ListRuleStatusChanges(ctx, db.ListRuleStatusChangesParams{
+		RuleID: ruleID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.q.CountRuleStatusChanges(ctx, ruleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return changes, int(total), nil
+}
+
+// RecordDraftViolation stores a violation of a rule in shadow mode
+func (r *Repository) RecordDraftViolation(ctx context.Context, params db.CreateDraftViolationParams) error {
+	return r.q.CreateDraftViolation(ctx, params)
+}
+
+// HasDraftViolationSince reports whether a violation of a rule in shadow mode was
+// recorded for a service since a time
+func (r *Repository) HasDraftViolationSince(ctx context.Context, ruleID, serviceID string, since time.Time) (bool, error) {
+	return r.q.HasDraftViolationSince(ctx, db.HasDraftViolationSinceParams{
+		RuleID:     ruleID,
+		ServiceID:  serviceID,
+		RecordedAt: since,
+	})
+}
+
+// ListDraftViolations returns the violations a rule recorded in shadow mode, newest
+// first, and their total count
+func (r *Repository) ListDraftViolations(ctx context.Context, ruleID string, limit, offset int32) ([]db.DraftViolation, int, error) {
+	violations, err := r.q.ListDraftViolations(ctx, db.ListDraftViolationsParams{
+		RuleID: ruleID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.q.CountDraftViolations(ctx, ruleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return violations, int(total), nil
+}
+
+// RecordSuppression counts a violation of a rule that did not open or update an incident
+func (r *Repository) RecordSuppression(ctx context.Context, ruleID, serviceID string, reason db.SuppressionReason) error {
+	_, err := r.q.RecordRuleSuppression(ctx, db.RecordRuleSuppressionParams{
+		RuleID:    ruleID,
+		ServiceID: serviceID,
+		Reason:    reason,
+	})
+	return err
+}
+
+// RecordEvaluation stores how a rule was evaluated for a metric
+func (r *Repository) RecordEvaluation(ctx context.Context, params db.CreateRuleEvaluationParams) (*db.RuleEvaluation, error) {
+	e, err := r.q.CreateRuleEvaluation(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// PruneEvaluations deletes the evaluations recorded before a time and returns how many
+// were deleted. Evaluations that opened an incident are kept with the incident.
+func (r *Repository) PruneEvaluations(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteRuleEvaluationsBefore(ctx, before)
+}
+
+// ListEvaluationPolicies returns the configured evaluation policies; metric types
+// without one use DefaultEvaluationPolicy
+func (r *Repository) ListEvaluationPolicies(ctx context.Context) ([]db.MetricEvaluationPolicy, error) {
+	return r.q.ListEvaluationPolicies(ctx)
+}
+
+// EvaluationPolicy returns the evaluation policy of a metric type
+func (r *Repository) EvaluationPolicy(ctx context.Context, metricType db.MetricType) (db.EvaluationPolicy, error) {
+	p, err := r.q.GetEvaluationPolicy(ctx, metricType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultEvaluationPolicy, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return p.Policy, nil
+}
+
+// SetEvaluationPolicy configures the evaluation policy of a metric type
+func (r *Repository) SetEvaluationPolicy(ctx context.Context, metricType db.MetricType, policy db.EvaluationPolicy) (*db.MetricEvaluationPolicy, error) {
+	p, err := r.q.UpsertEvaluationPolicy(ctx, db.UpsertEvaluationPolicyParams{
+		MetricType: metricType,
+		Policy:     policy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
 
-func (r *Repository) Delete(ctx context.Context, id string) error {
-	return r.q.DeleteRule(ctx, id)
+// ListActions returns the registered rule actions
+func (r *Repository) ListActions(ctx context.Context) ([]db.RuleAction, error) {
+	return r.q.ListRuleActions(ctx)
+}
+
+// Actions returns the registered rule actions indexed by id
+func (r *Repository) Actions(ctx context.Context) (Actions, error) {
+	actions, err := r.q.ListRuleActions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return indexActions(actions), nil
+}
+
+func (r *Repository) GetAction(ctx context.Context, id string) (*db.RuleAction, error) {
+	a, err := r.q.GetRuleAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *Repository) CreateAction(ctx context.Context, req CreateActionRequest) (*db.RuleAction, error) {
+	a, err := r.q.CreateRuleAction(ctx, db.CreateRuleActionParams{
+		ID:              req.ID,
+		Name:            req.Name,
+		Description:     req.Description,
+		Behavior:        db.ActionBehavior(req.Behavior),
+		DepartmentID:    req.DepartmentID,
+		MessageTemplate: req.MessageTemplate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// UpdateAction changes the settings of an action; its behaviour cannot be changed. The
+// change applies to every rule using the action, so while published rules use it the
+// change is requested for review instead: the action is returned unchanged with the
+// pending request.
+func (r *Repository) UpdateAction(ctx context.Context, id string, req UpdateActionRequest, actor string) (*db.RuleAction, *db.ChangeRequest, error) {
+	var (
+		action *db.RuleAction
+		change *db.ChangeRequest
+	)
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		qtx := r.q.WithTx(tx)
+		current, err := qtx.GetRuleActionForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		published, err := qtx.CountPublishedRulesUsingAction(ctx, id)
+		if err != nil {
+			return err
+		}
+		if published > 0 {
+			payload, err := json.Marshal(req)
+			if err != nil {
+				return err
+			}
+			action = &current
+			change, err = requestChange(ctx, qtx, db.ChangeTargetACTION, id, db.ChangeKindUPDATE, payload, actor)
+			return err
+		}
+		action, err = updateAction(ctx, qtx, id, req)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return action, change, nil
+}
+
+func updateAction(ctx context.Context, q *db.Queries, id string, req UpdateActionRequest) (*db.RuleAction, error) {
+	a, err := q.UpdateRuleAction(ctx, db.UpdateRuleActionParams{
+		ID:              id,
+		Name:            req.Name,
+		Description:     req.Description,
+		DepartmentID:    req.DepartmentID,
+		MessageTemplate: req.MessageTemplate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteAction removes an action. Actions still referenced by rules cannot be deleted.
+func (r *Repository) DeleteAction(ctx context.Context, id string) error {
+	n, err := r.q.DeleteRuleAction(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListSuppressions returns the suppressed violation counts of a rule per service and reason
+func (r *Repository) ListSuppressions(ctx context.Context, ruleID string) ([]db.RuleSuppression, error) {
+	return r.q.ListRuleSuppressions(ctx, ruleID)
 }
 
 func (r *Repository) GetTopTriggered(ctx context.Context, limit int32) ([]db.GetTopTriggeredRulesRow, error) {
 	return r.q.GetTopTriggeredRules(ctx, limit)
 }
+
+// ruleShape holds the columns derived from a rule's metric type or composite condition
+type ruleShape struct {
+	MetricType  db.MetricType
+	Operator    db.RuleOperator
+	Threshold   float64
+	Condition   []byte
+	MetricTypes []string
+}
+
+// shapeOf derives the stored columns of a rule. Composite rules take metric_type,
+// operator and threshold from their first leaf condition; expression, anomaly and
+// absence rules store placeholders for operator and threshold.
+func shapeOf(d *RuleDefinition) (ruleShape, error) {
+	cond, metricType := d.Condition, d.MetricType
+	if d.Expression != nil || d.AnomalyDeviations != nil || d.AbsenceSeconds > 0 {
+		// The columns are NOT NULL but unused: the expression, baseline or absence window decides whether the rule fires
+		return ruleShape{
+			MetricType:  db.MetricType(metricType),
+			Operator:    db.RuleOperatorValue0,
+			MetricTypes: []string{metricType},
+		}, nil
+	}
+	if cond == nil {
+		return ruleShape{
+			MetricType:  db.MetricType(metricType),
+			Operator:    db.RuleOperator(d.Operator),
+			Threshold:   d.Threshold,
+			MetricTypes: []string{metricType},
+		}, nil
+	}
+
+	raw, err := json.Marshal(cond)
+	if err != nil {
+		return ruleShape{}, err
+	}
+	first := cond.leaves()[0]
+	return ruleShape{
+		MetricType:  db.MetricType(first.MetricType),
+		Operator:    db.RuleOperator(first.Operator),
+		Threshold:   *first.Threshold,
+		Condition:   raw,
+		MetricTypes: cond.MetricTypes(),
+	}, nil
+}
+
+// recoverySamplesOrDefault resolves after a single healthy sample unless configured otherwise
+func recoverySamplesOrDefault(n int32) int32 {
+	if n <= 0 {
+		return 1
+	}
+	return n
+}
+
+// aggregationOrDefault maps an omitted aggregation to NONE (single-sample rules)
+func aggregationOrDefault(a string) db.RuleAggregation {
+	if a == "" {
+		return db.RuleAggregationNONE
+	}
+	return db.RuleAggregation(a)
+}
+
+// changeModeOrDefault maps an omitted change mode to NONE (rules on the value itself)
+func changeModeOrDefault(m string) db.RuleChangeMode {
+	if m == "" {
+		return db.RuleChangeModeNONE
+	}
+	return db.RuleChangeMode(m)
+}
+
+// forecastMethodOrDefault maps an omitted forecast method to NONE (no forecast)
+func forecastMethodOrDefault(m string) db.RuleForecastMethod {
+	if m == "" {
+		return db.RuleForecastMethodNONE
+	}
+	return db.RuleForecastMethod(m)
+}
+
+// anomalyDirectionOrDefault maps an omitted direction to BOTH
+func anomalyDirectionOrDefault(d string) db.AnomalyDirection {
+	if d == "" {
+		return db.AnomalyDirectionBOTH
+	}
+	return db.AnomalyDirection(d)
+}
+
+// webhookHeaders encodes custom webhook headers for the NOT NULL JSONB column
+func webhookHeaders(headers map[string]string) []byte {
+	if len(headers) == 0 {
+		return []byte("{}")
+	}
+	b, _ := json.Marshal(headers)
+	return b
+}
+
+// normalizeServiceIDs trims and de-duplicates a rule scope. The result is never
+// nil because service_ids is NOT NULL; an empty list scopes the rule to all services.
+func normalizeServiceIDs(ids []string) []string {
+	result := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
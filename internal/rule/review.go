@@ -0,0 +1,142 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// ErrSelfApproval is returned when the author of a rule's latest change approves it
+var ErrSelfApproval = errors.New("a rule cannot be approved by the author of its latest change")
+
+// TransitionError reports a review step that does not apply to the status of a rule
+type TransitionError struct {
+	RuleID string
+	Status db.RuleStatus
+	Want   db.RuleStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("rule %s is %s, expected %s", e.RuleID, e.Status, e.Want)
+}
+
+// reviewStep moves a rule from one review status to another
+type reviewStep struct {
+	from, to db.RuleStatus
+	// check, when set, may refuse the step to the actor
+	check func(rule *db.QualityRule, actor string) error
+}
+
+var (
+	submitStep  = reviewStep{from: db.RuleStatusDRAFT, to: db.RuleStatusPENDINGREVIEW}
+	approveStep = reviewStep{from: db.RuleStatusPENDINGREVIEW, to: db.RuleStatusPUBLISHED, check: notAuthor}
+	rejectStep  = reviewStep{from: db.RuleStatusPENDINGREVIEW, to: db.RuleStatusDRAFT}
+)
+
+// allows returns why the actor cannot take the step for a rule, or nil
+func (s reviewStep) allows(rule *db.QualityRule, actor string) error {
+	if rule.Status != s.from {
+		return &TransitionError{RuleID: rule.ID, Status: rule.Status, Want: s.from}
+	}
+	if s.check != nil {
+		return s.check(rule, actor)
+	}
+	return nil
+}
+
+// notAuthor refuses the approval of a rule by the author of its latest change
+func notAuthor(rule *db.QualityRule, actor string) error {
+	if rule.Author != nil && *rule.Author == actor {
+		return ErrSelfApproval
+	}
+	return nil
+}
+
+// IsValidRuleStatus reports whether s is a review status
+func IsValidRuleStatus(s db.RuleStatus) bool {
+	switch s {
+	case db.RuleStatusDRAFT, db.RuleStatusPENDINGREVIEW, db.RuleStatusPUBLISHED:
+		return true
+	default:
+		return false
+	}
+}
+
+// isPublished reports whether a rule acts on its violations. Drafts and rules pending
+// review are evaluated in shadow mode.
+func isPublished(rule *db.QualityRule) bool {
+	return rule.Status == db.RuleStatusPUBLISHED
+}
+
+// draftRule returns a rule to draft after a change to its definition, so the change is
+// reviewed before it opens incidents, and logs the status change. old is nil for a
+// created rule. Changes that leave the definition as it was keep the status.
+func draftRule(ctx context.Context, q *db.Queries, old, current *db.QualityRule, actor string) (*db.QualityRule, error) {
+	from := db.NullRuleStatus{}
+	if old != nil {
+		changed, err := definitionChanged(old, current)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			return current, nil
+		}
+		from = db.NullRuleStatus{RuleStatus: old.Status, Valid: true}
+	}
+
+	rule, err := q.DraftRule(ctx, db.DraftRuleParams{ID: current.ID, Author: actor})
+	if err != nil {
+		return nil, err
+	}
+	if from.Valid && from.RuleStatus == db.RuleStatusDRAFT {
+		return &rule, nil
+	}
+	if _, err := q.CreateRuleStatusChange(ctx, db.CreateRuleStatusChangeParams{
+		RuleID:     rule.ID,
+		FromStatus: from,
+		ToStatus:   db.RuleStatusDRAFT,
+		Actor:      actor,
+	}); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// definitionChanged reports whether a change to a rule touched its definition
+func definitionChanged(old, current *db.QualityRule) (bool, error) {
+	before, after := requestOf(old), requestOf(current)
+	diff, err := diffDefinitions(&before, &after)
+	if err != nil {
+		return false, err
+	}
+	return len(diff) > 0, nil
+}
+
+// recordDraft records a violation of a rule in shadow mode: it is stored for reviewers
+// and counted on the rule, but opens no incident
+func recordDraft(ctx context.Context, ruleRepo *Repository, rule *db.QualityRule, serviceID string, metricID *uuid.UUID, violation *Violation) error {
+	if err := ruleRepo.RecordDraftViolation(ctx, db.CreateDraftViolationParams{
+		RuleID:       rule.ID,
+		RuleRevision: rule.Revision,
+		ServiceID:    serviceID,
+		MetricID:     metricID,
+		MetricType:   rule.MetricType,
+		Severity:     severityOf(rule, violation),
+		Message:      violation.Message,
+	}); err != nil {
+		return err
+	}
+	if err := ruleRepo.RecordSuppression(ctx, rule.ID, serviceID, db.SuppressionReasonDRAFT); err != nil {
+		return err
+	}
+	slog.Debug("violation recorded in shadow mode",
+		"rule_id", rule.ID,
+		"status", rule.Status,
+		"service_id", serviceID,
+	)
+	return nil
+}
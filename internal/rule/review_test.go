@@ -0,0 +1,269 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+	"github.com/unitythemaker/tracely/pkg/httputil"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+// publish submits a rule changed by "test" for review and approves it as another user
+func publish(t *testing.T, repo *Repository, id string) {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := repo.Submit(ctx, id, "test", nil); err != nil {
+		t.Fatalf("Failed to submit rule %s: %v", id, err)
+	}
+	if _, err := repo.Approve(ctx, id, "reviewer", nil); err != nil {
+		t.Fatalf("Failed to approve rule %s: %v", id, err)
+	}
+}
+
+func TestRuleHandler_Review(t *testing.T) {
+	handler, q, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	testutil.TestDepartment(t, q, "NETOPS", "Network Operations")
+
+	do := func(method, path, actor, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		if actor != "" {
+			req.Header.Set(httputil.ActorHeader, actor)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	status := func(rr *httptest.ResponseRecorder) RuleResponse {
+		t.Helper()
+		var resp struct {
+			Data RuleResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		return resp.Data
+	}
+
+	rr := do(http.MethodPost, "/api/rules", "alice", `{"id": "QR-01", "metric_type": "LATENCY_MS", "threshold": 150, "operator": ">", "action": "OPEN_INCIDENT", "severity": "HIGH", "is_active": true, "department_id": "NETOPS"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	if created := status(rr); created.Status != "DRAFT" || created.Owner == nil || *created.Owner != "alice" || created.Author == nil || *created.Author != "alice" {
+		t.Fatalf("Expected a draft owned and authored by alice, got %+v", created)
+	}
+
+	steps := []struct {
+		name       string
+		path       string
+		actor      string
+		body       string
+		wantStatus int
+		wantRule   string
+	}{
+		{"approve a draft", "approve", "bob", "", http.StatusConflict, ""},
+		{"submit", "submit", "alice", "", http.StatusOK, "PENDING_REVIEW"},
+		{"submit twice", "submit", "alice", "", http.StatusConflict, ""},
+		{"approve anonymously", "approve", "", "", http.StatusForbidden, ""},
+		{"approve own change", "approve", "alice", "", http.StatusForbidden, ""},
+		{"reject", "reject", "bob", `{"comment": "threshold too low"}`, http.StatusOK, "DRAFT"},
+		{"resubmit", "submit", "alice", "", http.StatusOK, "PENDING_REVIEW"},
+		{"approve", "approve", "bob", "", http.StatusOK, "PUBLISHED"},
+	}
+	for _, tt := range steps {
+		if tt.name == "approve" {
+			// The department's review queue lists the rule before it is approved
+			rr := do(http.MethodGet, "/api/rules?status=PENDING_REVIEW&department_id=NETOPS", "", "")
+			var list struct {
+				Data []RuleResponse `json:"data"`
+			}
+			json.Unmarshal(rr.Body.Bytes(), &list)
+			if len(list.Data) != 1 || list.Data[0].ID != "QR-01" {
+				t.Errorf("Expected QR-01 waiting for review, got %+v", list.Data)
+			}
+		}
+
+		rr := do(http.MethodPost, "/api/rules/QR-01/"+tt.path, tt.actor, tt.body)
+		if rr.Code != tt.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d. Body: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+		if tt.wantRule != "" {
+			if got := status(rr).Status; got != tt.wantRule {
+				t.Errorf("%s: expected rule status %s, got %s", tt.name, tt.wantRule, got)
+			}
+		}
+	}
+
+	// Changing the published rule returns it to draft for review of the change
+	rr = do(http.MethodPatch, "/api/rules/QR-01", "carol", `{"metric_type": "LATENCY_MS", "threshold": 180, "operator": ">", "action": "OPEN_INCIDENT", "severity": "HIGH", "is_active": true, "department_id": "NETOPS"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if updated := status(rr); updated.Status != "DRAFT" || *updated.Owner != "alice" || *updated.Author != "carol" {
+		t.Errorf("Expected a draft owned by alice and authored by carol, got %+v", updated)
+	}
+
+	rr = do(http.MethodGet, "/api/rules/QR-01/status-changes", "", "")
+	var changes struct {
+		Data []StatusChangeResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &changes)
+	want := []string{"DRAFT", "PUBLISHED", "PENDING_REVIEW", "DRAFT", "PENDING_REVIEW", "DRAFT"}
+	if len(changes.Data) != len(want) {
+		t.Fatalf("Expected %d status changes, got %+v", len(want), changes.Data)
+	}
+	for i, c := range changes.Data {
+		if c.ToStatus != want[i] {
+			t.Errorf("Status change %d: expected %s, got %s", i, want[i], c.ToStatus)
+		}
+	}
+	if c := changes.Data[3]; c.Actor != "bob" || c.Comment == nil || *c.Comment != "threshold too low" {
+		t.Errorf("Expected the rejection by bob with its comment, got %+v", c)
+	}
+	if c := changes.Data[5]; c.FromStatus != nil {
+		t.Errorf("Expected the creation to have no previous status, got %s", *c.FromStatus)
+	}
+
+	if rr := do(http.MethodGet, "/api/rules?status=REVIEWED", "", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown status, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/rules/MISSING/submit", "alice", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing rule, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestWorker_DraftRule(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "draft", Threshold: 150, IsActive: true, Priority: 1, Status: db.RuleStatusDRAFT})
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{ID: "published", Threshold: 180, IsActive: true, Priority: 2})
+	if _, err := wt.worker.ruleRepo.SetEvaluationPolicy(ctx, db.MetricTypeLATENCYMS, db.EvaluationPolicyFIRSTMATCH); err != nil {
+		t.Fatalf("Failed to set evaluation policy: %v", err)
+	}
+	wt.worker.EnableEvaluations(time.Hour)
+
+	// The draft records its violations without opening incidents or shadowing the
+	// published rule
+	wt.record(t, 160, 200)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 || incidents[0].RuleID != "published" {
+		t.Fatalf("Expected 1 incident of the published rule, got %+v", incidents)
+	}
+
+	violations, total, err := wt.worker.ruleRepo.ListDraftViolations(ctx, "draft", 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list draft violations: %v", err)
+	}
+	if total != 2 || len(violations) != 2 || violations[0].ServiceID != "S1" {
+		t.Errorf("Expected 2 draft violations, got %d: %+v", total, violations)
+	}
+	suppressions, err := wt.worker.ruleRepo.ListSuppressions(ctx, "draft")
+	if err != nil {
+		t.Fatalf("Failed to list suppressions: %v", err)
+	}
+	if len(suppressions) != 1 || suppressions[0].Reason != db.SuppressionReasonDRAFT || suppressions[0].SuppressedCount != 2 {
+		t.Errorf("Expected 2 DRAFT suppressions, got %+v", suppressions)
+	}
+
+	metrics, err := wt.metricRepo.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to list metrics: %v", err)
+	}
+	evaluations, err := wt.queries.ListMetricEvaluations(ctx, metrics[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list evaluations: %v", err)
+	}
+	for _, e := range evaluations {
+		if e.RuleID == "draft" && (e.Outcome != db.EvaluationOutcomeSUPPRESSED || e.Reason == nil || *e.Reason != "DRAFT rule in shadow mode") {
+			t.Errorf("Expected the draft to be SUPPRESSED in shadow mode, got %+v", e)
+		}
+	}
+}
+
+func TestReviewSteps(t *testing.T) {
+	author := "alice"
+	rule := func(status db.RuleStatus) *db.QualityRule {
+		return &db.QualityRule{ID: "latency", Status: status, Author: &author}
+	}
+
+	tests := []struct {
+		name  string
+		step  reviewStep
+		rule  *db.QualityRule
+		actor string
+		want  error
+	}{
+		{"submit draft", submitStep, rule(db.RuleStatusDRAFT), author, nil},
+		{"submit pending", submitStep, rule(db.RuleStatusPENDINGREVIEW), author, &TransitionError{}},
+		{"submit published", submitStep, rule(db.RuleStatusPUBLISHED), author, &TransitionError{}},
+		{"approve by reviewer", approveStep, rule(db.RuleStatusPENDINGREVIEW), "bob", nil},
+		{"approve by author", approveStep, rule(db.RuleStatusPENDINGREVIEW), author, ErrSelfApproval},
+		{"approve without author", approveStep, &db.QualityRule{Status: db.RuleStatusPENDINGREVIEW}, author, nil},
+		{"approve draft", approveStep, rule(db.RuleStatusDRAFT), "bob", &TransitionError{}},
+		// The author may withdraw their own change
+		{"reject by author", rejectStep, rule(db.RuleStatusPENDINGREVIEW), author, nil},
+		{"reject published", rejectStep, rule(db.RuleStatusPUBLISHED), "bob", &TransitionError{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.step.allows(tt.rule, tt.actor)
+			var transitionErr *TransitionError
+			switch want := tt.want.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Expected the step to be allowed, got %v", err)
+				}
+			case *TransitionError:
+				if !errors.As(err, &transitionErr) || transitionErr.Status != tt.rule.Status || transitionErr.Want != tt.step.from {
+					t.Errorf("Expected a transition error from %s, got %v", tt.rule.Status, err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("Expected %v, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDefinitionChanged(t *testing.T) {
+	old := decisionRule("latency", 1, 150)
+	author := "alice"
+
+	// Review fields are not part of the definition
+	reviewed := old
+	reviewed.Status = db.RuleStatusPUBLISHED
+	reviewed.Author = &author
+	reviewed.Revision = 4
+	if changed, err := definitionChanged(&old, &reviewed); err != nil || changed {
+		t.Errorf("Expected no definition change, got %v, %v", changed, err)
+	}
+
+	raised := old
+	raised.Threshold = pgutil.Float64ToNumeric(200)
+	if changed, err := definitionChanged(&old, &raised); err != nil || !changed {
+		t.Errorf("Expected a threshold change to change the definition, got %v, %v", changed, err)
+	}
+
+	deactivated := old
+	deactivated.IsActive = !old.IsActive
+	if changed, err := definitionChanged(&old, &deactivated); err != nil || !changed {
+		t.Errorf("Expected deactivating to change the definition, got %v, %v", changed, err)
+	}
+}
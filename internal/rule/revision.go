@@ -0,0 +1,198 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+// RevisionChange is the old and new value of a rule field, null where the field was unset
+type RevisionChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// requestOf returns the definition of a stored rule as a create request. The placeholder
+// columns of composite, expression, anomaly and absence rules are left out, so the
+// definition passes validation again when a rule is rolled back.
+func requestOf(r *db.QualityRule) CreateRuleRequest {
+	req := CreateRuleRequest{
+		ID: r.ID,
+		RuleDefinition: RuleDefinition{
+			MetricType:             string(r.MetricType),
+			Threshold:              pgutil.NumericToFloat64(r.Threshold),
+			Operator:               string(r.Operator),
+			Action:                 string(r.Action),
+			Priority:               r.Priority,
+			Severity:               string(r.Severity),
+			IsActive:               r.IsActive,
+			DepartmentID:           r.DepartmentID,
+			ServiceIDs:             r.ServiceIds,
+			ForSeconds:             r.ForSeconds,
+			WindowSamples:          r.WindowSamples,
+			MinViolations:          r.MinViolations,
+			WindowSeconds:          r.WindowSeconds,
+			ChangeSeconds:          r.ChangeSeconds,
+			FreshnessSeconds:       r.FreshnessSeconds,
+			Expression:             r.Expression,
+			AnomalyDeviations:      pgutil.NumericToFloat64Ptr(r.AnomalyDeviations),
+			AnomalyDirection:       anomalyDirectionResponse(r),
+			AnomalySeasonal:        r.AnomalySeasonal,
+			AbsenceSeconds:         r.AbsenceSeconds,
+			ForecastMethod:         forecastMethodResponse(r),
+			ForecastWindowSeconds:  r.ForecastWindowSeconds,
+			ForecastHorizonSeconds: r.ForecastHorizonSeconds,
+			AutoResolve:            r.AutoResolve,
+			RecoveryThreshold:      pgutil.NumericToFloat64Ptr(r.RecoveryThreshold),
+			CooldownSeconds:        r.CooldownSeconds,
+			WebhookURL:             r.WebhookUrl,
+			WebhookHeaders:         webhookHeadersResponse(r),
+			WebhookBodyTemplate:    r.WebhookBodyTemplate,
+			Schedule:               scheduleOf(r),
+			Tiers:                  tiersOf(r),
+		},
+	}
+	if r.Aggregation != db.RuleAggregationNONE {
+		req.Aggregation = string(r.Aggregation)
+	}
+	if isChangeRule(r) {
+		req.ChangeMode = string(r.ChangeMode)
+	}
+	if r.AutoResolve {
+		req.RecoverySamples = r.RecoverySamples
+	}
+	if len(req.Tiers) > 0 {
+		req.Threshold, req.Severity = 0, ""
+	}
+
+	if cond := conditionResponse(r); cond != nil {
+		req.Condition = cond
+		req.MetricType, req.Operator, req.Threshold = "", "", 0
+	} else if r.Expression != nil || r.AnomalyDeviations.Valid || r.AbsenceSeconds > 0 {
+		req.Operator, req.Threshold = "", 0
+	}
+	return req
+}
+
+// DefinitionOf decodes the rule definition stored with a revision
+func DefinitionOf(rev *db.RuleRevision) (CreateRuleRequest, error) {
+	var def CreateRuleRequest
+	if err := json.Unmarshal(rev.Definition, &def); err != nil {
+		return def, fmt.Errorf("invalid revision definition: %w", err)
+	}
+	return def, nil
+}
+
+// jsonFields returns the JSON fields of a value; none for nil
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if v == nil {
+		return fields, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// diffFields returns the fields that differ between two JSON objects
+func diffFields(before, after map[string]json.RawMessage) map[string]RevisionChange {
+	diff := make(map[string]RevisionChange)
+	for field, value := range after {
+		if prev, ok := before[field]; !ok || !bytes.Equal(prev, value) {
+			diff[field] = RevisionChange{Old: before[field], New: value}
+		}
+	}
+	for field, prev := range before {
+		if _, ok := after[field]; !ok {
+			diff[field] = RevisionChange{Old: prev}
+		}
+	}
+	return diff
+}
+
+// diffDefinitions returns the fields that differ between two rule definitions. Either
+// may be nil, for a created or deleted rule.
+func diffDefinitions(old, current *CreateRuleRequest) (map[string]RevisionChange, error) {
+	var before, after any
+	if old != nil {
+		before = old
+	}
+	if current != nil {
+		after = current
+	}
+	oldFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(oldFields, newFields), nil
+}
+
+// recordRevision stores the revision of a rule change using the given transaction and
+// returns the rule with its revision number updated. current is nil for a deleted rule
+// and old for a created one.
+func recordRevision(ctx context.Context, q *db.Queries, old, current *db.QualityRule, action db.RuleRevisionAction, actor string, rolledBackTo *int32) (*db.QualityRule, *db.RuleRevision, error) {
+	var before, after *CreateRuleRequest
+	ruleID := ""
+	if old != nil {
+		def := requestOf(old)
+		before, ruleID = &def, old.ID
+	}
+	if current != nil {
+		def := requestOf(current)
+		after, ruleID = &def, current.ID
+	}
+
+	diff, err := diffDefinitions(before, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return nil, nil, err
+	}
+	definition := after
+	if definition == nil {
+		definition = before
+	}
+	definitionJSON, err := json.Marshal(definition)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rev, err := q.CreateRuleRevision(ctx, db.CreateRuleRevisionParams{
+		RuleID:       ruleID,
+		Action:       action,
+		Actor:        actor,
+		Definition:   definitionJSON,
+		Diff:         diffJSON,
+		RolledBackTo: rolledBackTo,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		return nil, &rev, nil
+	}
+
+	rule, err := q.SetRuleRevision(ctx, db.SetRuleRevisionParams{
+		ID:       current.ID,
+		Revision: rev.Revision,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rule, &rev, nil
+}
@@ -0,0 +1,260 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestRequestOf_RoundTrip(t *testing.T) {
+	deviations, latency, loss := 3.0, 200.0, 2.0
+	expression := "value > 200"
+	tests := []struct {
+		name string
+		req  CreateRuleRequest
+	}{
+		{
+			name: "threshold",
+			req: CreateRuleRequest{
+				ID: "latency", RuleDefinition: RuleDefinition{
+					MetricType: "LATENCY_MS", Threshold: 200, Operator: ">",
+					Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true, ServiceIDs: []string{"S1"},
+				},
+			},
+		},
+		{
+			name: "tiers",
+			req: CreateRuleRequest{
+				ID: "tiered", RuleDefinition: RuleDefinition{
+					MetricType: "LATENCY_MS", Operator: ">", Action: "OPEN_INCIDENT",
+					Tiers: []Tier{{Threshold: 150, Severity: "MEDIUM"}, {Threshold: 300, Severity: "HIGH"}},
+				},
+			},
+		},
+		{
+			name: "composite",
+			req: CreateRuleRequest{
+				ID: "composite", RuleDefinition: RuleDefinition{
+					Action: "OPEN_INCIDENT", Severity: "HIGH",
+					Condition: &Condition{Op: "AND", Conditions: []Condition{
+						{MetricType: "LATENCY_MS", Operator: ">", Threshold: &latency},
+						{MetricType: "PACKET_LOSS", Operator: ">", Threshold: &loss},
+					}},
+				},
+			},
+		},
+		{
+			name: "expression",
+			req: CreateRuleRequest{
+				ID: "expr", RuleDefinition: RuleDefinition{
+					MetricType: "LATENCY_MS", Action: "OPEN_INCIDENT", Severity: "HIGH",
+					Expression: &expression,
+				},
+			},
+		},
+		{
+			name: "anomaly",
+			req: CreateRuleRequest{
+				ID: "anomaly", RuleDefinition: RuleDefinition{
+					MetricType: "LATENCY_MS", Action: "OPEN_INCIDENT", Severity: "HIGH",
+					AnomalyDeviations: &deviations, AnomalyDirection: "ABOVE",
+				},
+			},
+		},
+		{
+			name: "forecast",
+			req: CreateRuleRequest{
+				ID: "forecast", RuleDefinition: RuleDefinition{
+					MetricType: "LATENCY_MS", Threshold: 200, Operator: ">", Action: "OPEN_INCIDENT",
+					Severity: "LOW", ForecastMethod: "HOLT", ForecastWindowSeconds: 1800, ForecastHorizonSeconds: 600,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ruleFromDefinition(tt.req.ID, &tt.req.RuleDefinition)
+			if err != nil {
+				t.Fatalf("ruleFromDefinition() error = %v", err)
+			}
+			def := requestOf(rule)
+			if err := validateDefinition(&def.RuleDefinition, testActions()); err != nil {
+				t.Errorf("Expected definition to validate, got %v", err)
+			}
+			diff, err := diffDefinitions(&tt.req, &def)
+			if err != nil {
+				t.Fatalf("diffDefinitions() error = %v", err)
+			}
+			if len(diff) != 0 {
+				t.Errorf("Expected definition to match the request, got diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestDiffDefinitions(t *testing.T) {
+	old := CreateRuleRequest{ID: "r", RuleDefinition: RuleDefinition{MetricType: "LATENCY_MS", Threshold: 200, Operator: ">", Action: "OPEN_INCIDENT", Severity: "HIGH"}}
+	current := old
+	current.Threshold = 300
+	current.ServiceIDs = []string{"S1"}
+
+	diff, err := diffDefinitions(&old, &current)
+	if err != nil {
+		t.Fatalf("diffDefinitions() error = %v", err)
+	}
+	if len(diff) != 2 {
+		t.Fatalf("Expected 2 changed fields, got %v", diff)
+	}
+	if string(diff["threshold"].Old) != "200" || string(diff["threshold"].New) != "300" {
+		t.Errorf("Expected threshold 200 -> 300, got %s -> %s", diff["threshold"].Old, diff["threshold"].New)
+	}
+	if diff["service_ids"].Old != nil || string(diff["service_ids"].New) != `["S1"]` {
+		t.Errorf("Expected service_ids to be added, got %s -> %s", diff["service_ids"].Old, diff["service_ids"].New)
+	}
+
+	diff, _ = diffDefinitions(&old, nil)
+	if string(diff["id"].Old) != `"r"` || diff["id"].New != nil {
+		t.Errorf("Expected every field removed for a deleted rule, got %v", diff)
+	}
+}
+
+func TestRuleRevisions(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := handler.repo
+	_, err := repo.Create(ctx, CreateRuleRequest{
+		ID: "latency", RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS", Threshold: 200, Operator: ">",
+			Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true,
+		},
+	}, "alice")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	_, _, err = repo.Update(ctx, "latency", RuleDefinition{
+		MetricType: "LATENCY_MS", Threshold: 300, Operator: ">",
+		Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true,
+	}, "bob")
+	if err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	if _, _, err := repo.SetActive(ctx, "latency", false, "bob"); err != nil {
+		t.Fatalf("Failed to deactivate rule: %v", err)
+	}
+	if _, err := repo.Delete(ctx, "latency", "carol"); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rules/latency/revisions", nil)
+	req.SetPathValue("id", "latency")
+	rr := httptest.NewRecorder()
+	handler.ListRevisions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var list struct {
+		Data []RevisionResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+
+	want := []string{"DELETED", "DEACTIVATED", "UPDATED", "CREATED"}
+	if len(list.Data) != len(want) {
+		t.Fatalf("Expected %d revisions, got %d", len(want), len(list.Data))
+	}
+	for i, action := range want {
+		if list.Data[i].Action != action {
+			t.Errorf("Expected revision %d to be %s, got %s", i, action, list.Data[i].Action)
+		}
+	}
+	updated := list.Data[2]
+	if updated.Actor != "bob" || updated.Revision != 2 {
+		t.Errorf("Expected revision 2 by bob, got %d by %s", updated.Revision, updated.Actor)
+	}
+	if string(updated.Diff["threshold"].Old) != "200" || string(updated.Diff["threshold"].New) != "300" {
+		t.Errorf("Expected threshold diff 200 -> 300, got %+v", updated.Diff)
+	}
+
+	// Rolling back to revision 2 recreates the deleted rule
+	req = httptest.NewRequest(http.MethodPost, "/api/rules/latency/revisions/2/rollback", bytes.NewReader(nil))
+	req.SetPathValue("id", "latency")
+	req.SetPathValue("revision", "2")
+	req.Header.Set("X-Actor", "dave")
+	rr = httptest.NewRecorder()
+	handler.Rollback(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var restored struct {
+		Data RuleResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &restored)
+	if restored.Data.Threshold != 300 || !restored.Data.IsActive || restored.Data.Revision != 5 {
+		t.Errorf("Expected active rule with threshold 300 at revision 5, got %+v", restored.Data)
+	}
+
+	rev, err := repo.GetRevision(ctx, "latency", 5)
+	if err != nil {
+		t.Fatalf("Failed to get revision: %v", err)
+	}
+	if rev.Action != db.RuleRevisionActionROLLEDBACK || rev.Actor != "dave" || rev.RolledBackTo == nil || *rev.RolledBackTo != 2 {
+		t.Errorf("Expected revision 5 to roll back to 2 by dave, got %+v", rev)
+	}
+}
+
+func TestRuleHandler_Rollback_NotFound(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rules/missing/revisions/1/rollback", nil)
+	req.SetPathValue("id", "missing")
+	req.SetPathValue("revision", "1")
+	rr := httptest.NewRecorder()
+	handler.Rollback(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestWorker_IncidentRuleRevision(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := wt.worker.ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency", RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS", Threshold: 150, Operator: ">",
+			Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	_, _, err = wt.worker.ruleRepo.Update(ctx, "latency", RuleDefinition{
+		MetricType: "LATENCY_MS", Threshold: 180, Operator: ">",
+		Action: "OPEN_INCIDENT", Severity: "HIGH", IsActive: true,
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency")
+
+	wt.record(t, 200)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if incidents[0].RuleRevision == nil || *incidents[0].RuleRevision != 2 {
+		t.Errorf("Expected incident to reference revision 2, got %v", incidents[0].RuleRevision)
+	}
+}
@@ -0,0 +1,252 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxRulesetBytes limits the size of a ruleset document
+const MaxRulesetBytes = 1 << 20
+
+// ErrInvalidRuleset is returned for a ruleset that cannot be applied
+var ErrInvalidRuleset = errors.New("invalid ruleset")
+
+// Ruleset is the declarative form of the rules and departments kept in version control.
+// Rules use the fields of POST /api/rules.
+type Ruleset struct {
+	Departments []RulesetDepartment `json:"departments,omitempty"`
+	Rules       []CreateRuleRequest `json:"rules"`
+}
+
+type RulesetDepartment struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+// Plan actions
+const (
+	PlanCreate = "CREATE"
+	PlanUpdate = "UPDATE"
+	PlanDelete = "DELETE"
+)
+
+// PlanChange is a change applying a ruleset makes to a rule or department. Review marks
+// the update or deletion of a published rule, which is requested for review instead of
+// applied.
+type PlanChange struct {
+	ID     string                    `json:"id"`
+	Action string                    `json:"action"`
+	Diff   map[string]RevisionChange `json:"diff,omitempty"`
+	Review bool                      `json:"review,omitempty"`
+}
+
+// Plan lists the changes that make the stored rules and departments match a ruleset.
+// Rules missing from the ruleset are deleted; departments are never deleted because
+// incidents and notifications keep referring to them.
+type Plan struct {
+	DryRun      bool         `json:"dry_run"`
+	Departments []PlanChange `json:"departments"`
+	Rules       []PlanChange `json:"rules"`
+	Unchanged   int          `json:"unchanged"`
+
+	// definitions of the rules to create or update, by id
+	definitions map[string]CreateRuleRequest
+}
+
+// ParseRuleset decodes a YAML or JSON ruleset. Unknown fields are rejected so that a
+// misspelt setting is not silently dropped.
+func ParseRuleset(data []byte) (*Ruleset, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRuleset, err)
+	}
+	// Decode through JSON so rules use the same field names and types as the API
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRuleset, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var rs Ruleset
+	if err := dec.Decode(&rs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRuleset, err)
+	}
+	return &rs, nil
+}
+
+// validateRuleset checks a ruleset against the registered actions, before it is
+// compared with the database
+func validateRuleset(rs *Ruleset, actions Actions) error {
+	if len(rs.Rules) == 0 {
+		// An empty document would delete every rule
+		return fmt.Errorf("%w: no rules", ErrInvalidRuleset)
+	}
+
+	departments := make(map[string]bool, len(rs.Departments))
+	for i, d := range rs.Departments {
+		switch {
+		case d.ID == "" || d.Name == "":
+			return fmt.Errorf("%w: departments[%d]: id and name are required", ErrInvalidRuleset, i)
+		case departments[d.ID]:
+			return fmt.Errorf("%w: departments[%d]: duplicate id %s", ErrInvalidRuleset, i, d.ID)
+		}
+		departments[d.ID] = true
+	}
+
+	rules := make(map[string]bool, len(rs.Rules))
+	for i := range rs.Rules {
+		req := &rs.Rules[i]
+		switch {
+		case req.ID == "":
+			return fmt.Errorf("%w: rules[%d]: id is required", ErrInvalidRuleset, i)
+		case rules[req.ID]:
+			return fmt.Errorf("%w: rules[%d]: duplicate id %s", ErrInvalidRuleset, i, req.ID)
+		}
+		rules[req.ID] = true
+		if err := validateDefinition(&req.RuleDefinition, actions); err != nil {
+			return fmt.Errorf("%w: rules[%d] (%s): %v", ErrInvalidRuleset, i, req.ID, err)
+		}
+	}
+	return nil
+}
+
+// departmentOf returns the ruleset form of a stored department
+func departmentOf(d *db.Department) RulesetDepartment {
+	return RulesetDepartment{ID: d.ID, Name: d.Name, Description: d.Description}
+}
+
+// computePlan compares a validated ruleset with the stored rules and departments
+func computePlan(rs *Ruleset, current []db.QualityRule, departments []db.Department) (*Plan, error) {
+	plan := &Plan{
+		Departments: []PlanChange{},
+		Rules:       []PlanChange{},
+		definitions: make(map[string]CreateRuleRequest, len(rs.Rules)),
+	}
+
+	known := make(map[string]RulesetDepartment, len(departments))
+	for i := range departments {
+		known[departments[i].ID] = departmentOf(&departments[i])
+	}
+	for _, d := range rs.Departments {
+		existing, ok := known[d.ID]
+		known[d.ID] = d
+		if !ok {
+			plan.Departments = append(plan.Departments, PlanChange{ID: d.ID, Action: PlanCreate})
+			continue
+		}
+		before, err := jsonFields(existing)
+		if err != nil {
+			return nil, err
+		}
+		after, err := jsonFields(d)
+		if err != nil {
+			return nil, err
+		}
+		if diff := diffFields(before, after); len(diff) > 0 {
+			plan.Departments = append(plan.Departments, PlanChange{ID: d.ID, Action: PlanUpdate, Diff: diff})
+		}
+	}
+
+	stored := make(map[string]*db.QualityRule, len(current))
+	for i := range current {
+		stored[current[i].ID] = &current[i]
+	}
+	for i, req := range rs.Rules {
+		if req.DepartmentID != nil {
+			if _, ok := known[*req.DepartmentID]; !ok {
+				return nil, fmt.Errorf("%w: rules[%d] (%s): unknown department_id %s", ErrInvalidRuleset, i, req.ID, *req.DepartmentID)
+			}
+		}
+
+		// Compare normalised definitions so equivalent forms do not show up as changes
+		r, err := ruleFromDefinition(req.ID, &req.RuleDefinition)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rules[%d] (%s): %v", ErrInvalidRuleset, i, req.ID, err)
+		}
+		def := requestOf(r)
+
+		existing, ok := stored[req.ID]
+		delete(stored, req.ID)
+		if !ok {
+			plan.Rules = append(plan.Rules, PlanChange{ID: req.ID, Action: PlanCreate})
+			plan.definitions[req.ID] = req
+			continue
+		}
+		old := requestOf(existing)
+		diff, err := diffDefinitions(&old, &def)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		plan.Rules = append(plan.Rules, PlanChange{ID: req.ID, Action: PlanUpdate, Diff: diff, Review: isPublished(existing)})
+		plan.definitions[req.ID] = req
+	}
+	for id, rule := range stored {
+		plan.Rules = append(plan.Rules, PlanChange{ID: id, Action: PlanDelete, Review: isPublished(rule)})
+	}
+
+	sort.Slice(plan.Rules, func(i, j int) bool {
+		return plan.Rules[i].ID < plan.Rules[j].ID
+	})
+	return plan, nil
+}
+
+// exportRuleset returns the stored rules and departments as a ruleset
+func exportRuleset(rules []db.QualityRule, departments []db.Department) *Ruleset {
+	rs := &Ruleset{
+		Departments: make([]RulesetDepartment, len(departments)),
+		Rules:       make([]CreateRuleRequest, len(rules)),
+	}
+	for i := range departments {
+		rs.Departments[i] = departmentOf(&departments[i])
+	}
+	for i := range rules {
+		rs.Rules[i] = requestOf(&rules[i])
+	}
+	return rs
+}
+
+// MarshalRulesetYAML encodes a ruleset as YAML with the field names and order of its
+// JSON form
+func MarshalRulesetYAML(rs *Ruleset) ([]byte, error) {
+	b, err := json.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so parsing it keeps the field order; only the style needs changing
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle clears the flow and quoting styles of parsed JSON so it is written as
+// plain block YAML; strings that need quotes are still quoted
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,271 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+const testRulesetYAML = `
+departments:
+  - id: NETOPS
+    name: Network Operations
+rules:
+  - id: latency
+    metric_type: LATENCY_MS
+    threshold: 200
+    operator: ">"
+    action: OPEN_INCIDENT
+    severity: HIGH
+    is_active: true
+    department_id: NETOPS
+  - id: loss
+    metric_type: PACKET_LOSS
+    threshold: 2
+    operator: ">"
+    action: OPEN_INCIDENT
+    severity: MEDIUM
+    is_active: true
+`
+
+func TestParseRuleset(t *testing.T) {
+	rs, err := ParseRuleset([]byte(testRulesetYAML))
+	if err != nil {
+		t.Fatalf("ParseRuleset() error = %v", err)
+	}
+	if len(rs.Departments) != 1 || len(rs.Rules) != 2 {
+		t.Fatalf("Expected 1 department and 2 rules, got %+v", rs)
+	}
+	if r := rs.Rules[0]; r.ID != "latency" || r.Threshold != 200 || r.DepartmentID == nil || *r.DepartmentID != "NETOPS" {
+		t.Errorf("Unexpected rule %+v", r)
+	}
+
+	// JSON is accepted as well
+	rs, err = ParseRuleset([]byte(`{"rules": [{"id": "latency", "metric_type": "LATENCY_MS", "threshold": 200, "operator": ">", "action": "OPEN_INCIDENT", "severity": "HIGH"}]}`))
+	if err != nil {
+		t.Fatalf("ParseRuleset() JSON error = %v", err)
+	}
+	if len(rs.Rules) != 1 {
+		t.Errorf("Expected 1 rule, got %d", len(rs.Rules))
+	}
+
+	for _, doc := range []string{
+		"rules:\n  - id: latency\n    treshold: 200\n",
+		"rules: [",
+		"- latency",
+	} {
+		if _, err := ParseRuleset([]byte(doc)); !errors.Is(err, ErrInvalidRuleset) {
+			t.Errorf("ParseRuleset(%q) = %v, want ErrInvalidRuleset", doc, err)
+		}
+	}
+}
+
+func TestValidateRuleset(t *testing.T) {
+	rule := CreateRuleRequest{
+		ID: "latency", RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS", Threshold: 200, Operator: ">",
+			Action: "OPEN_INCIDENT", Severity: "HIGH",
+		},
+	}
+	invalid := rule
+	invalid.MetricType = ""
+	unnamed := rule
+	unnamed.ID = ""
+	unregistered := rule
+	unregistered.Action = "PAGE_ONCALL"
+
+	tests := []struct {
+		name  string
+		rs    Ruleset
+		valid bool
+	}{
+		{"valid", Ruleset{Rules: []CreateRuleRequest{rule}}, true},
+		{"no rules", Ruleset{}, false},
+		{"rule without id", Ruleset{Rules: []CreateRuleRequest{unnamed}}, false},
+		{"duplicate rule", Ruleset{Rules: []CreateRuleRequest{rule, rule}}, false},
+		{"invalid rule", Ruleset{Rules: []CreateRuleRequest{invalid}}, false},
+		{"unknown action", Ruleset{Rules: []CreateRuleRequest{unregistered}}, false},
+		{"department without name", Ruleset{Departments: []RulesetDepartment{{ID: "NETOPS"}}, Rules: []CreateRuleRequest{rule}}, false},
+		{"duplicate department", Ruleset{Departments: []RulesetDepartment{{ID: "NETOPS", Name: "A"}, {ID: "NETOPS", Name: "B"}}, Rules: []CreateRuleRequest{rule}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRuleset(&tt.rs, testActions())
+			if (err == nil) != tt.valid {
+				t.Errorf("validateRuleset() = %v, want valid=%v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRuleset) {
+				t.Errorf("Expected ErrInvalidRuleset, got %v", err)
+			}
+		})
+	}
+}
+
+func TestComputePlan(t *testing.T) {
+	rs, err := ParseRuleset([]byte(testRulesetYAML))
+	if err != nil {
+		t.Fatalf("ParseRuleset() error = %v", err)
+	}
+
+	stored := func(req CreateRuleRequest) db.QualityRule {
+		r, err := ruleFromDefinition(req.ID, &req.RuleDefinition)
+		if err != nil {
+			t.Fatalf("ruleFromDefinition() error = %v", err)
+		}
+		return *r
+	}
+	changed := rs.Rules[0]
+	changed.Threshold = 300
+	current := []db.QualityRule{
+		stored(changed),
+		stored(rs.Rules[1]),
+		stored(CreateRuleRequest{
+			ID: "jitter", RuleDefinition: RuleDefinition{
+				MetricType: "JITTER_MS", Threshold: 50, Operator: ">",
+				Action: "OPEN_INCIDENT", Severity: "LOW",
+			},
+		}),
+	}
+	current[2].Status = db.RuleStatusPUBLISHED
+	departments := []db.Department{{ID: "NETOPS", Name: "NetOps"}, {ID: "SUPPORT", Name: "Support"}}
+
+	plan, err := computePlan(rs, current, departments)
+	if err != nil {
+		t.Fatalf("computePlan() error = %v", err)
+	}
+
+	if len(plan.Departments) != 1 || plan.Departments[0].Action != PlanUpdate || plan.Departments[0].Diff["name"].New == nil {
+		t.Errorf("Expected NETOPS to be renamed, got %+v", plan.Departments)
+	}
+	// Deleting the published rule is requested for review
+	want := []PlanChange{{ID: "jitter", Action: PlanDelete, Review: true}, {ID: "latency", Action: PlanUpdate}}
+	if len(plan.Rules) != len(want) {
+		t.Fatalf("Expected %d rule changes, got %+v", len(want), plan.Rules)
+	}
+	for i, w := range want {
+		if plan.Rules[i].ID != w.ID || plan.Rules[i].Action != w.Action || plan.Rules[i].Review != w.Review {
+			t.Errorf("Rules[%d] = %s %s (review %v), want %s %s (review %v)", i, plan.Rules[i].Action, plan.Rules[i].ID, plan.Rules[i].Review, w.Action, w.ID, w.Review)
+		}
+	}
+	if _, ok := plan.Rules[1].Diff["threshold"]; !ok || len(plan.Rules[1].Diff) != 1 {
+		t.Errorf("Expected only the threshold to change, got %v", plan.Rules[1].Diff)
+	}
+	if plan.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged rule, got %d", plan.Unchanged)
+	}
+
+	// Against an empty database everything is created
+	plan, err = computePlan(rs, nil, nil)
+	if err != nil {
+		t.Fatalf("computePlan() error = %v", err)
+	}
+	if len(plan.Departments) != 1 || plan.Departments[0].Action != PlanCreate {
+		t.Errorf("Expected NETOPS to be created, got %+v", plan.Departments)
+	}
+	if len(plan.Rules) != 2 || plan.Rules[0].Action != PlanCreate || plan.Rules[1].Action != PlanCreate {
+		t.Errorf("Expected 2 rules to be created, got %+v", plan.Rules)
+	}
+
+	// Rules may only refer to departments that exist or are declared
+	rs.Departments = nil
+	if _, err := computePlan(rs, nil, nil); !errors.Is(err, ErrInvalidRuleset) {
+		t.Errorf("Expected unknown department to be rejected, got %v", err)
+	}
+}
+
+func TestMarshalRulesetYAML_RoundTrip(t *testing.T) {
+	rs, err := ParseRuleset([]byte(testRulesetYAML))
+	if err != nil {
+		t.Fatalf("ParseRuleset() error = %v", err)
+	}
+	out, err := MarshalRulesetYAML(rs)
+	if err != nil {
+		t.Fatalf("MarshalRulesetYAML() error = %v", err)
+	}
+	if bytes.Contains(out, []byte("{")) {
+		t.Errorf("Expected block style YAML, got:\n%s", out)
+	}
+
+	parsed, err := ParseRuleset(out)
+	if err != nil {
+		t.Fatalf("ParseRuleset() of exported YAML error = %v\n%s", err, out)
+	}
+	for i := range rs.Rules {
+		diff, err := diffDefinitions(&rs.Rules[i], &parsed.Rules[i])
+		if err != nil {
+			t.Fatalf("diffDefinitions() error = %v", err)
+		}
+		if len(diff) != 0 {
+			t.Errorf("Rule %s changed in the round trip: %v", rs.Rules[i].ID, diff)
+		}
+	}
+}
+
+func TestRuleHandler_ApplyAndExport(t *testing.T) {
+	handler, _, cleanup := setupRuleTest(t)
+	defer cleanup()
+
+	apply := func(query, body string) (int, Plan) {
+		req := httptest.NewRequest(http.MethodPost, "/api/rules/apply"+query, bytes.NewBufferString(body))
+		req.Header.Set("X-Actor", "gitops")
+		rr := httptest.NewRecorder()
+		handler.Apply(rr, req)
+
+		var resp struct {
+			Data Plan `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp.Data
+	}
+
+	code, plan := apply("?dry_run=true", testRulesetYAML)
+	if code != http.StatusOK || !plan.DryRun || len(plan.Rules) != 2 {
+		t.Fatalf("Expected a dry run plan with 2 rules, got %d %+v", code, plan)
+	}
+	rules, err := handler.repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list rules: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("Expected dry run to leave rules unchanged, got %d rules", len(rules))
+	}
+
+	code, plan = apply("", testRulesetYAML)
+	if code != http.StatusOK || plan.DryRun || len(plan.Rules) != 2 {
+		t.Fatalf("Expected 2 rules to be applied, got %d %+v", code, plan)
+	}
+	revisions, _, err := handler.repo.ListRevisions(context.Background(), "latency", 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Actor != "gitops" {
+		t.Errorf("Expected a CREATED revision by gitops, got %+v", revisions)
+	}
+
+	// Re-applying the export is a no-op
+	req := httptest.NewRequest(http.MethodGet, "/api/rules/export", nil)
+	rr := httptest.NewRecorder()
+	handler.Export(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/yaml" {
+		t.Errorf("Expected application/yaml, got %s", ct)
+	}
+	code, plan = apply("", rr.Body.String())
+	if code != http.StatusOK || len(plan.Rules) != 0 || len(plan.Departments) != 0 || plan.Unchanged != 2 {
+		t.Errorf("Expected export to apply without changes, got %d %+v", code, plan)
+	}
+
+	code, _ = apply("", "rules: []")
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected empty ruleset to be rejected, got %d", code)
+	}
+}
@@ -0,0 +1,48 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/schedule"
+)
+
+// validateSchedule checks the optional schedule of a rule
+func validateSchedule(s *schedule.Schedule) error {
+	if s == nil {
+		return nil
+	}
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	return nil
+}
+
+// scheduleJSON encodes a rule schedule for the nullable JSONB column
+func scheduleJSON(s *schedule.Schedule) []byte {
+	if s == nil {
+		return nil
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// scheduleOf decodes the stored schedule of a rule, nil for rules without one
+func scheduleOf(rule *db.QualityRule) *schedule.Schedule {
+	if len(rule.Schedule) == 0 {
+		return nil
+	}
+	var s schedule.Schedule
+	if err := json.Unmarshal(rule.Schedule, &s); err != nil {
+		return nil
+	}
+	return &s
+}
+
+// InSchedule reports whether a rule is evaluated at t. Rules without a schedule always are.
+func InSchedule(rule *db.QualityRule, t time.Time) bool {
+	s := scheduleOf(rule)
+	return s == nil || s.Contains(t)
+}
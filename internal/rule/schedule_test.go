@@ -0,0 +1,37 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestInSchedule(t *testing.T) {
+	// 2026-03-16 is a Monday
+	at := time.Date(2026, 3, 16, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		want     bool
+	}{
+		{"no schedule", "", true},
+		{"business hours", `{"windows": [{"weekdays": ["MON", "TUE", "WED", "THU", "FRI"], "start": "09:00", "end": "18:00"}]}`, false},
+		{"peak viewing", `{"windows": [{"weekdays": ["MON"], "start": "19:00", "end": "01:00"}]}`, true},
+		{"peak viewing in timezone", `{"windows": [{"weekdays": ["MON"], "start": "19:00", "end": "23:00"}], "timezone": "Europe/Istanbul"}`, false},
+		{"cron", `{"cron": "* 18-23 * * MON-FRI"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &db.QualityRule{}
+			if tt.schedule != "" {
+				rule.Schedule = []byte(tt.schedule)
+			}
+			if got := InSchedule(rule, at); got != tt.want {
+				t.Errorf("InSchedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
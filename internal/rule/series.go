@@ -0,0 +1,183 @@
+package rule
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+const (
+	// SeriesRetention is how far back the worker keeps samples for windowed rules.
+	// Rule windows cannot be longer than this.
+	SeriesRetention = 6 * time.Hour
+	// MaxSeriesSamples caps the number of samples kept per series.
+	MaxSeriesSamples = 10000
+	// seriesSweepInterval is how often, in sample time, the store drops the series of
+	// services that stopped reporting
+	seriesSweepInterval = 10 * time.Minute
+)
+
+// Sample is a single metric value of a series
+type Sample struct {
+	MetricID   uuid.UUID
+	Value      float64
+	RecordedAt time.Time
+}
+
+// SeriesKey identifies a service/metric type series
+type SeriesKey struct {
+	ServiceID  string
+	MetricType db.MetricType
+}
+
+// HistoryLoader loads the newest samples of a series recorded between since and until,
+// ordered oldest first. It is used to rebuild window state after a restart.
+type HistoryLoader func(ctx context.Context, key SeriesKey, since, until time.Time, limit int32) ([]Sample, error)
+
+// SeriesStore keeps recent samples per series for windowed rule evaluation. Series
+// whose samples all fell out of the retention window are dropped.
+type SeriesStore struct {
+	mu     sync.Mutex
+	series map[SeriesKey][]Sample
+	load   HistoryLoader
+	// latest is the newest sample time observed in any series; sweptAt is when the store
+	// was last swept, in the same sample time
+	latest  time.Time
+	sweptAt time.Time
+}
+
+// NewSeriesStore creates a store. load may be nil, in which case series start empty.
+func NewSeriesStore(load HistoryLoader) *SeriesStore {
+	return &SeriesStore{
+		series: make(map[SeriesKey][]Sample),
+		load:   load,
+	}
+}
+
+// Observe records a sample and returns the series up to and including it, oldest first.
+// The first time a series is seen its history is loaded so that windows survive restarts.
+func (s *SeriesStore) Observe(ctx context.Context, key SeriesKey, sample Sample) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples, ok := s.series[key]
+	if !ok && s.load != nil {
+		history, err := s.load(ctx, key, sample.RecordedAt.Add(-SeriesRetention), sample.RecordedAt, MaxSeriesSamples)
+		if err != nil {
+			return nil, err
+		}
+		samples = history
+	}
+
+	samples = insertSample(samples, sample)
+	samples = trimSamples(samples)
+	s.series[key] = samples
+	s.sweep(sample.RecordedAt)
+
+	// Return a copy of the samples recorded up to this one
+	end := sort.Search(len(samples), func(i int) bool {
+		return samples[i].RecordedAt.After(sample.RecordedAt)
+	})
+	result := make([]Sample, end)
+	copy(result, samples[:end])
+	return result, nil
+}
+
+// Latest returns the newest sample of a series recorded at or before at.
+// Like Observe, it loads the history of a series the first time it is seen.
+func (s *SeriesStore) Latest(ctx context.Context, key SeriesKey, at time.Time) (Sample, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples, ok := s.series[key]
+	if !ok && s.load != nil {
+		history, err := s.load(ctx, key, at.Add(-SeriesRetention), at, MaxSeriesSamples)
+		if err != nil {
+			return Sample{}, false, err
+		}
+		samples = trimSamples(history)
+		s.series[key] = samples
+	}
+
+	end := sort.Search(len(samples), func(i int) bool {
+		return samples[i].RecordedAt.After(at)
+	})
+	if end == 0 {
+		return Sample{}, false, nil
+	}
+	return samples[end-1], true, nil
+}
+
+// Forget drops the series of a service; they are loaded again when next seen
+func (s *SeriesStore) Forget(serviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.series {
+		if key.ServiceID == serviceID {
+			delete(s.series, key)
+		}
+	}
+}
+
+// sweep drops samples outside the retention window of the newest sample observed in
+// any series, and the series left empty, once every seriesSweepInterval. s.mu must be held.
+func (s *SeriesStore) sweep(at time.Time) {
+	if at.After(s.latest) {
+		s.latest = at
+	}
+	if s.latest.Sub(s.sweptAt) < seriesSweepInterval {
+		return
+	}
+	s.sweptAt = s.latest
+
+	cutoff := s.latest.Add(-SeriesRetention)
+	for key, samples := range s.series {
+		start := sort.Search(len(samples), func(i int) bool {
+			return !samples[i].RecordedAt.Before(cutoff)
+		})
+		if start == len(samples) {
+			delete(s.series, key)
+			continue
+		}
+		s.series[key] = samples[start:]
+	}
+}
+
+// insertSample adds a sample keeping the slice ordered by RecordedAt.
+// Samples that are already present (for example loaded from history) are not duplicated.
+func insertSample(samples []Sample, sample Sample) []Sample {
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].RecordedAt.After(sample.RecordedAt)
+	})
+	for j := i - 1; j >= 0 && samples[j].RecordedAt.Equal(sample.RecordedAt); j-- {
+		if samples[j].MetricID == sample.MetricID {
+			return samples
+		}
+	}
+
+	samples = append(samples, Sample{})
+	copy(samples[i+1:], samples[i:])
+	samples[i] = sample
+	return samples
+}
+
+// trimSamples drops samples outside the retention window and above the size cap
+func trimSamples(samples []Sample) []Sample {
+	if len(samples) == 0 {
+		return samples
+	}
+
+	cutoff := samples[len(samples)-1].RecordedAt.Add(-SeriesRetention)
+	start := sort.Search(len(samples), func(i int) bool {
+		return !samples[i].RecordedAt.Before(cutoff)
+	})
+	if len(samples)-start > MaxSeriesSamples {
+		start = len(samples) - MaxSeriesSamples
+	}
+	return samples[start:]
+}
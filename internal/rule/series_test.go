@@ -0,0 +1,152 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestSeriesStore_ObserveOrdersSamples(t *testing.T) {
+	store := NewSeriesStore(nil)
+	ctx := context.Background()
+	key := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 1, RecordedAt: start})
+	store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 3, RecordedAt: start.Add(2 * time.Minute)})
+
+	// A late sample is placed by its recorded time and only sees older samples
+	samples, err := store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 2, RecordedAt: start.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Observe() returned error: %v", err)
+	}
+	if len(samples) != 2 || samples[0].Value != 1 || samples[1].Value != 2 {
+		t.Errorf("Expected samples [1 2], got %+v", samples)
+	}
+
+	samples, _ = store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 4, RecordedAt: start.Add(3 * time.Minute)})
+	if len(samples) != 4 {
+		t.Fatalf("Expected 4 samples, got %d", len(samples))
+	}
+	for i, s := range samples {
+		if s.Value != float64(i+1) {
+			t.Errorf("Expected value %d at index %d, got %v", i+1, i, s.Value)
+		}
+	}
+}
+
+func TestSeriesStore_LoadsHistoryOnce(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	current := Sample{MetricID: uuid.New(), Value: 3, RecordedAt: start.Add(2 * time.Minute)}
+
+	calls := 0
+	store := NewSeriesStore(func(ctx context.Context, key SeriesKey, since, until time.Time, limit int32) ([]Sample, error) {
+		calls++
+		if !until.Equal(current.RecordedAt) {
+			t.Errorf("Expected history up to %v, got %v", current.RecordedAt, until)
+		}
+		// History already contains the metric being processed
+		return []Sample{
+			{MetricID: uuid.New(), Value: 1, RecordedAt: start},
+			{MetricID: uuid.New(), Value: 2, RecordedAt: start.Add(time.Minute)},
+			current,
+		}, nil
+	})
+	ctx := context.Background()
+	key := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}
+
+	samples, err := store.Observe(ctx, key, current)
+	if err != nil {
+		t.Fatalf("Observe() returned error: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Errorf("Expected 3 samples without duplicates, got %d", len(samples))
+	}
+
+	store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 4, RecordedAt: start.Add(3 * time.Minute)})
+	if calls != 1 {
+		t.Errorf("Expected history to be loaded once, got %d calls", calls)
+	}
+}
+
+func TestSeriesStore_LoadError(t *testing.T) {
+	store := NewSeriesStore(func(ctx context.Context, key SeriesKey, since, until time.Time, limit int32) ([]Sample, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	_, err := store.Observe(context.Background(), SeriesKey{ServiceID: "S1"}, Sample{RecordedAt: time.Now()})
+	if err == nil {
+		t.Errorf("Expected error when history cannot be loaded")
+	}
+}
+
+func TestSeriesStore_TrimsRetention(t *testing.T) {
+	store := NewSeriesStore(nil)
+	ctx := context.Background()
+	key := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 1, RecordedAt: start})
+	samples, _ := store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 2, RecordedAt: start.Add(SeriesRetention + time.Minute)})
+
+	if len(samples) != 1 || samples[0].Value != 2 {
+		t.Errorf("Expected samples older than the retention to be dropped, got %+v", samples)
+	}
+}
+
+func TestSeriesStore_EvictsStaleSeries(t *testing.T) {
+	store := NewSeriesStore(nil)
+	ctx := context.Background()
+	stale := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}
+	active := SeriesKey{ServiceID: "S2", MetricType: db.MetricTypeLATENCYMS}
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+	store.Observe(ctx, stale, Sample{MetricID: uuid.New(), Value: 1, RecordedAt: start})
+	for at := start; !at.After(start.Add(SeriesRetention + seriesSweepInterval)); at = at.Add(time.Minute) {
+		store.Observe(ctx, active, Sample{MetricID: uuid.New(), Value: 2, RecordedAt: at})
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.series[stale]; ok {
+		t.Error("Expected the series of a service that stopped reporting to be dropped")
+	}
+	if samples := store.series[active]; len(samples) == 0 || len(samples) > int(SeriesRetention/time.Minute)+1 {
+		t.Errorf("Expected the active series to keep its retention window, got %d samples", len(samples))
+	}
+}
+
+func TestSeriesStore_Latest(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	store := NewSeriesStore(func(ctx context.Context, key SeriesKey, since, until time.Time, limit int32) ([]Sample, error) {
+		return []Sample{
+			{MetricID: uuid.New(), Value: 1, RecordedAt: start},
+			{MetricID: uuid.New(), Value: 2, RecordedAt: start.Add(time.Minute)},
+		}, nil
+	})
+	ctx := context.Background()
+	key := SeriesKey{ServiceID: "S1", MetricType: db.MetricTypePACKETLOSS}
+
+	sample, ok, err := store.Latest(ctx, key, start.Add(90*time.Second))
+	if err != nil {
+		t.Fatalf("Latest() returned error: %v", err)
+	}
+	if !ok || sample.Value != 2 {
+		t.Errorf("Expected latest value 2, got %+v (ok=%v)", sample, ok)
+	}
+
+	// Samples recorded after the reference time are not visible
+	store.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 3, RecordedAt: start.Add(2 * time.Minute)})
+	sample, _, _ = store.Latest(ctx, key, start.Add(90*time.Second))
+	if sample.Value != 2 {
+		t.Errorf("Expected latest value 2 before the new sample, got %v", sample.Value)
+	}
+
+	if _, ok, _ := store.Latest(ctx, key, start.Add(-time.Minute)); ok {
+		t.Errorf("Expected no sample before the series starts")
+	}
+}
@@ -0,0 +1,121 @@
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/incident"
+)
+
+// Tier is one level of a multi-tier rule: values breaching threshold open or escalate
+// an incident with severity
+type Tier struct {
+	Threshold float64 `json:"threshold"`
+	Severity  string  `json:"severity"`
+}
+
+// tiersJSON encodes the tiers of a rule for the nullable JSONB column
+func tiersJSON(tiers []Tier) []byte {
+	if len(tiers) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(tiers)
+	return b
+}
+
+// tiersOf decodes the stored tiers of a rule, nil for single-threshold rules
+func tiersOf(rule *db.QualityRule) []Tier {
+	if len(rule.Tiers) == 0 {
+		return nil
+	}
+	var tiers []Tier
+	if err := json.Unmarshal(rule.Tiers, &tiers); err != nil {
+		return nil
+	}
+	return tiers
+}
+
+// baseTier returns the threshold and severity stored on a rule: those of the first tier
+// for multi-tier rules, otherwise the ones given
+func baseTier(tiers []Tier, threshold float64, severity string) (float64, string) {
+	if len(tiers) == 0 {
+		return threshold, severity
+	}
+	return tiers[0].Threshold, tiers[0].Severity
+}
+
+// tieredThreshold returns the threshold a rule first fires at
+func tieredThreshold(tiers []Tier, threshold float64) float64 {
+	threshold, _ = baseTier(tiers, threshold, "")
+	return threshold
+}
+
+// validateTiers checks the tiers of a rule. Tiers replace threshold and severity, must
+// be increasingly breached in operator order and increasingly severe, and only apply to
+// rules that compare a value with a threshold.
+func validateTiers(d *RuleDefinition) error {
+	tiers := d.Tiers
+	if len(tiers) == 0 {
+		return nil
+	}
+	switch {
+	case d.Threshold != 0 || d.Severity != "":
+		return errors.New("threshold and severity cannot be combined with tiers")
+	case d.Condition != nil || d.Expression != nil || d.AnomalyDeviations != nil || d.AbsenceSeconds > 0 || d.ChangeMode != "":
+		return errors.New("tiers are only supported for threshold rules")
+	}
+
+	op := db.RuleOperator(d.Operator)
+	ascending := op == db.RuleOperatorValue0 || op == db.RuleOperatorValue1
+	if !ascending && op != db.RuleOperatorValue2 && op != db.RuleOperatorValue3 {
+		return errors.New("tiers require one of the operators >, >=, < and <=")
+	}
+
+	for i, tier := range tiers {
+		if incident.SeverityRank(db.IncidentSeverity(tier.Severity)) == 0 {
+			return fmt.Errorf("invalid tiers[%d].severity: %s", i, tier.Severity)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := tiers[i-1]
+		if incident.SeverityRank(db.IncidentSeverity(tier.Severity)) <= incident.SeverityRank(db.IncidentSeverity(prev.Severity)) {
+			return errors.New("tiers must be ordered by increasing severity")
+		}
+		if (ascending && tier.Threshold <= prev.Threshold) || (!ascending && tier.Threshold >= prev.Threshold) {
+			return fmt.Errorf("tiers must be ordered so each threshold is breached after the previous one (operator %s)", d.Operator)
+		}
+	}
+	return nil
+}
+
+// tierFor returns the most severe tier a value breaches
+func tierFor(rule *db.QualityRule, tiers []Tier, value float64) (Tier, bool) {
+	for i := len(tiers) - 1; i >= 0; i-- {
+		if compare(rule.Operator, value, tiers[i].Threshold) {
+			return tiers[i], true
+		}
+	}
+	return Tier{}, false
+}
+
+// applyTier sets the severity of a violation of a multi-tier rule from the tier its
+// value breaches
+func applyTier(rule *db.QualityRule, tiers []Tier, v *Violation) {
+	tier, ok := tierFor(rule, tiers, v.Value)
+	if !ok {
+		return
+	}
+	v.Severity = db.IncidentSeverity(tier.Severity)
+	v.Message += fmt.Sprintf(" [%s tier: %s %.2f]", tier.Severity, rule.Operator, tier.Threshold)
+}
+
+// severityOf returns the severity of a violation, the rule's own unless a tier set it
+func severityOf(rule *db.QualityRule, v *Violation) db.IncidentSeverity {
+	if v.Severity != "" {
+		return v.Severity
+	}
+	return rule.Severity
+}
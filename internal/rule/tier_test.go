@@ -0,0 +1,164 @@
+package rule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
+)
+
+func latencyTiers() []Tier {
+	return []Tier{
+		{Threshold: 150, Severity: "MEDIUM"},
+		{Threshold: 300, Severity: "HIGH"},
+		{Threshold: 600, Severity: "CRITICAL"},
+	}
+}
+
+func TestValidateTiers(t *testing.T) {
+	expression := "value > 1"
+	deviations := 3.0
+
+	tests := []struct {
+		name  string
+		req   RuleDefinition
+		valid bool
+	}{
+		{"ascending", RuleDefinition{Operator: ">", Tiers: latencyTiers()}, true},
+		{"descending", RuleDefinition{Operator: "<", Tiers: []Tier{{Threshold: 0.8, Severity: "LOW"}, {Threshold: 0.5, Severity: "HIGH"}}}, true},
+		{"with threshold", RuleDefinition{Operator: ">", Threshold: 150, Tiers: latencyTiers()}, false},
+		{"with severity", RuleDefinition{Operator: ">", Severity: "HIGH", Tiers: latencyTiers()}, false},
+		{"unordered thresholds", RuleDefinition{Operator: ">", Tiers: []Tier{{Threshold: 300, Severity: "MEDIUM"}, {Threshold: 150, Severity: "HIGH"}}}, false},
+		{"descending for <", RuleDefinition{Operator: "<", Tiers: []Tier{{Threshold: 0.5, Severity: "LOW"}, {Threshold: 0.8, Severity: "HIGH"}}}, false},
+		{"unordered severities", RuleDefinition{Operator: ">", Tiers: []Tier{{Threshold: 150, Severity: "HIGH"}, {Threshold: 300, Severity: "MEDIUM"}}}, false},
+		{"repeated severity", RuleDefinition{Operator: ">", Tiers: []Tier{{Threshold: 150, Severity: "HIGH"}, {Threshold: 300, Severity: "HIGH"}}}, false},
+		{"unknown severity", RuleDefinition{Operator: ">", Tiers: []Tier{{Threshold: 150, Severity: "URGENT"}}}, false},
+		{"equality operator", RuleDefinition{Operator: "==", Tiers: latencyTiers()}, false},
+		{"expression", RuleDefinition{Expression: &expression, Tiers: latencyTiers()}, false},
+		{"anomaly", RuleDefinition{AnomalyDeviations: &deviations, Tiers: latencyTiers()}, false},
+		{"change", RuleDefinition{Operator: ">", ChangeMode: "PERCENT", Tiers: latencyTiers()}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTiers(&tt.req)
+			if (err == nil) != tt.valid {
+				t.Errorf("validateTiers() = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCheck_Tiers(t *testing.T) {
+	rule, err := ruleFromDefinition("latency", &RuleDefinition{
+		MetricType: "LATENCY_MS",
+		Operator:   ">",
+		Action:     "OPEN_INCIDENT",
+		Tiers:      latencyTiers(),
+	})
+	if err != nil {
+		t.Fatalf("ruleFromDefinition() error = %v", err)
+	}
+	if pgutil.NumericToFloat64(rule.Threshold) != 150 || rule.Severity != db.IncidentSeverityMEDIUM {
+		t.Fatalf("Expected the first tier to be stored as threshold and severity, got %v %s", pgutil.NumericToFloat64(rule.Threshold), rule.Severity)
+	}
+
+	tests := []struct {
+		value    float64
+		severity db.IncidentSeverity
+	}{
+		{100, ""},
+		{200, db.IncidentSeverityMEDIUM},
+		{300, db.IncidentSeverityMEDIUM},
+		{350, db.IncidentSeverityHIGH},
+		{700, db.IncidentSeverityCRITICAL},
+	}
+
+	for _, tt := range tests {
+		v := Check(rule, []Sample{{Value: tt.value, RecordedAt: time.Now()}})
+		switch {
+		case tt.severity == "" && v != nil:
+			t.Errorf("Check(%v) expected no violation, got %+v", tt.value, v)
+		case tt.severity != "" && (v == nil || v.Severity != tt.severity):
+			t.Errorf("Check(%v) expected %s violation, got %+v", tt.value, tt.severity, v)
+		}
+	}
+}
+
+func TestReplay_Tiers(t *testing.T) {
+	rule, err := ruleFromDefinition("latency", &RuleDefinition{
+		MetricType: "LATENCY_MS",
+		Operator:   ">",
+		Action:     "OPEN_INCIDENT",
+		Tiers:      latencyTiers(),
+	})
+	if err != nil {
+		t.Fatalf("ruleFromDefinition() error = %v", err)
+	}
+
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	results, err := Replay(context.Background(), rule, db.ActionBehaviorOPENINCIDENT, ReplayEnv{}, backtestMetrics("S1", start, 200, 700, 350), nil)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %+v", results)
+	}
+	if got := results[0].Incidents[0].Severity; got != "CRITICAL" {
+		t.Errorf("Expected the incident to be escalated to CRITICAL, got %s", got)
+	}
+}
+
+func TestWorker_TierEscalation(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := wt.worker.ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency",
+		RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS",
+			Operator:   ">",
+			Action:     "OPEN_INCIDENT",
+			IsActive:   true,
+			Tiers:      latencyTiers(),
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency")
+
+	wt.record(t, 200)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 || incidents[0].Severity != db.IncidentSeverityMEDIUM {
+		t.Fatalf("Expected 1 MEDIUM incident, got %+v", incidents)
+	}
+
+	// A worse tier escalates the open incident; a milder one does not lower it
+	wt.record(t, 350, 200)
+	incidents = wt.incidents(t)
+	if len(incidents) != 1 || incidents[0].Severity != db.IncidentSeverityHIGH {
+		t.Fatalf("Expected the incident to be escalated to HIGH, got %+v", incidents)
+	}
+
+	events, err := wt.queries.ListIncidentEvents(ctx, incidents[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list incident events: %v", err)
+	}
+	var changes int
+	for _, e := range events {
+		if e.EventType != db.IncidentEventTypeSEVERITYCHANGED {
+			continue
+		}
+		changes++
+		if *e.OldValue != "MEDIUM" || *e.NewValue != "HIGH" {
+			t.Errorf("Expected severity change MEDIUM -> HIGH, got %s -> %s", *e.OldValue, *e.NewValue)
+		}
+	}
+	if changes != 1 {
+		t.Errorf("Expected 1 SEVERITY_CHANGED event, got %d", changes)
+	}
+}
@@ -5,40 +5,84 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/unitythemaker/tracely/internal/db"
 	"github.com/unitythemaker/tracely/internal/incident"
+	"github.com/unitythemaker/tracely/internal/notification"
 	"github.com/unitythemaker/tracely/internal/outbox"
+	"github.com/unitythemaker/tracely/internal/silence"
+	"github.com/unitythemaker/tracely/internal/webhook"
+	"github.com/unitythemaker/tracely/pkg/httputil"
+	"github.com/unitythemaker/tracely/pkg/pgutil"
 )
 
 const ProcessorName = "rule_worker"
 
+// DefaultWebhookCooldown is how long a WEBHOOK rule without cooldown_seconds waits
+// between deliveries for the same service
+const DefaultWebhookCooldown = 5 * time.Minute
+
+// serviceLease is how long the worker owns the services of the metrics it claims. A batch
+// is evaluated well within it; the leases of a stopped worker are released.
+const serviceLease = time.Minute
+
 type Worker struct {
-	outboxRepo   *outbox.Repository
-	ruleRepo     *Repository
-	incidentRepo *incident.Repository
-	interval     time.Duration
+	outboxRepo       *outbox.Repository
+	ruleRepo         *Repository
+	rules            *RuleCache
+	incidentRepo     *incident.Repository
+	webhookRepo      *webhook.Repository
+	notificationRepo *notification.Repository
+	silenceRepo      *silence.Repository
+	series           *SeriesStore
+	baselines        *BaselineStore
+	evaluator        *evaluator
+	interval         time.Duration
+
+	// owner identifies the worker in the service leases it holds. generations holds the
+	// lease generation each service's in-memory state was built under.
+	owner       string
+	generations map[string]int64
+
+	// evaluationRetention is how long evaluations are kept; zero disables recording
+	evaluationRetention time.Duration
+	evaluationsPrunedAt time.Time
 }
 
-func NewWorker(outboxRepo *outbox.Repository, ruleRepo *Repository, incidentRepo *incident.Repository, interval time.Duration) *Worker {
+func NewWorker(outboxRepo *outbox.Repository, ruleRepo *Repository, incidentRepo *incident.Repository, webhookRepo *webhook.Repository, notificationRepo *notification.Repository, silenceRepo *silence.Repository, interval time.Duration) *Worker {
+	series := NewSeriesStore(ruleRepo.LoadSeriesHistory)
+	baselines := NewBaselineStore(ruleRepo.LoadBaselines, ruleRepo.SaveBaselines)
 	return &Worker{
-		outboxRepo:   outboxRepo,
-		ruleRepo:     ruleRepo,
-		incidentRepo: incidentRepo,
-		interval:     interval,
+		outboxRepo:       outboxRepo,
+		ruleRepo:         ruleRepo,
+		rules:            NewRuleCache(ruleRepo),
+		incidentRepo:     incidentRepo,
+		webhookRepo:      webhookRepo,
+		notificationRepo: notificationRepo,
+		silenceRepo:      silenceRepo,
+		series:           series,
+		baselines:        baselines,
+		evaluator:        newEvaluator(series, baselines),
+		interval:         interval,
+		owner:            uuid.NewString(),
+		generations:      make(map[string]int64),
 	}
 }
 
 func (w *Worker) Run(ctx context.Context) {
 	slog.Info("RuleWorker started", "interval", w.interval)
+	go w.rules.Listen(ctx)
+
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
+			w.releaseServices()
 			slog.Info("RuleWorker stopped")
 			return
 		case <-ticker.C:
@@ -47,15 +91,30 @@ func (w *Worker) Run(ctx context.Context) {
 	}
 }
 
+// RuleCacheStats returns the hit, miss and reload counts of the worker's rule cache
+func (w *Worker) RuleCacheStats() CacheStats {
+	return w.rules.Stats()
+}
+
+// ServeCacheStats responds with the counters of the worker's rule cache
+func (w *Worker) ServeCacheStats(rw http.ResponseWriter, r *http.Request) {
+	httputil.Success(rw, w.RuleCacheStats())
+}
+
 func (w *Worker) processEvents(ctx context.Context) {
-	events, err := w.outboxRepo.GetUnprocessedMetricEvents(ctx, ProcessorName, 100)
+	w.pruneEvaluations(ctx)
+
+	// Metrics are claimed with a lease on their service, so only this worker evaluates
+	// the service while its series and baselines are held in memory
+	events, err := w.outboxRepo.ClaimMetricEventsByService(ctx, ProcessorName, w.owner, 100, serviceLease)
 	if err != nil {
 		slog.Error("RuleWorker: failed to get events", "error", err)
 		return
 	}
 
 	for _, event := range events {
-		if err := w.processEvent(ctx, event); err != nil {
+		w.ownService(event.ServiceID, event.Generation)
+		if err := w.processEvent(ctx, event.Outbox); err != nil {
 			slog.Error("RuleWorker: failed to process event", "event_id", event.ID, "error", err)
 			continue
 		}
@@ -66,12 +125,34 @@ func (w *Worker) processEvents(ctx context.Context) {
 	}
 }
 
+// releaseServices gives up the worker's service leases so other instances can take the
+// services over right away
+func (w *Worker) releaseServices() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.outboxRepo.ReleaseServiceLeases(ctx, w.owner); err != nil {
+		slog.Error("RuleWorker: failed to release service leases", "error", err)
+	}
+}
+
+// ownService drops the in-memory state of a service whose lease changed owner since the
+// state was built: another instance may have evaluated metrics of the service meanwhile,
+// so the state is rebuilt from the database
+func (w *Worker) ownService(serviceID string, generation int64) {
+	if w.generations[serviceID] == generation {
+		return
+	}
+	w.series.Forget(serviceID)
+	w.baselines.Forget(serviceID)
+	w.generations[serviceID] = generation
+}
+
 type MetricPayload struct {
-	ID         string  `json:"id"`
-	ServiceID  string  `json:"service_id"`
-	MetricType string  `json:"metric_type"`
-	Value      float64 `json:"value"`
-	RecordedAt string  `json:"recorded_at"`
+	ID         string    `json:"id"`
+	ServiceID  string    `json:"service_id"`
+	MetricType string    `json:"metric_type"`
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `json:"recorded_at"`
 }
 
 func (w *Worker) processEvent(ctx context.Context, event db.Outbox) error {
@@ -80,47 +161,365 @@ func (w *Worker) processEvent(ctx context.Context, event db.Outbox) error {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
-	// Get active rules for this metric type
-	rules, err := w.ruleRepo.ListActiveByMetricType(ctx, db.MetricType(payload.MetricType))
+	// Get active rules for this metric type that are scoped to the service
+	rules, err := w.rules.ListActiveForService(ctx, db.MetricType(payload.MetricType), payload.ServiceID)
 	if err != nil {
 		return fmt.Errorf("failed to get rules: %w", err)
 	}
+	policy, err := w.rules.EvaluationPolicy(ctx, db.MetricType(payload.MetricType))
+	if err != nil {
+		return fmt.Errorf("failed to get evaluation policy: %w", err)
+	}
 
 	metricID, err := uuid.Parse(payload.ID)
 	if err != nil {
 		return fmt.Errorf("failed to parse metric id: %w", err)
 	}
+	if payload.RecordedAt.IsZero() {
+		payload.RecordedAt = time.Now()
+	}
 
-	// Evaluate each rule
-	for _, rule := range rules {
-		if !Evaluate(&rule, payload.Value) {
+	// Record the sample so windowed rules see the whole series
+	samples, err := w.series.Observe(ctx, SeriesKey{
+		ServiceID:  payload.ServiceID,
+		MetricType: db.MetricType(payload.MetricType),
+	}, Sample{
+		MetricID:   metricID,
+		Value:      payload.Value,
+		RecordedAt: payload.RecordedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load series history: %w", err)
+	}
+
+	// Decide the outcome of each rule in priority order, then apply it
+	decisions := decide(ctx, w.evaluator, rules, policy, payload.ServiceID, samples, payload.RecordedAt, w.matchSilence)
+	for _, d := range decisions {
+		rule, violation := &d.rule.QualityRule, d.violation
+		switch d.outcome {
+		case outcomeOutsideSchedule:
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSKIPPED, "outside schedule", payload.Value, nil)
+			continue
+		case outcomeFailed:
+			slog.Error("RuleWorker: failed to evaluate rule", "rule_id", rule.ID, "error", d.err)
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSKIPPED, "evaluation failed: "+d.err.Error(), payload.Value, violation)
+			continue
+		case outcomeNotMatched:
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeNOTMATCHED, "", payload.Value, nil)
+			if rule.AutoResolve {
+				w.trackRecovery(ctx, rule, payload.ServiceID, samples)
+			}
+			continue
+		case outcomeDraft:
+			if err := recordDraft(ctx, w.ruleRepo, rule, payload.ServiceID, &metricID, violation); err != nil {
+				slog.Error("RuleWorker: failed to record draft violation", "rule_id", rule.ID, "error", err)
+			}
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSUPPRESSED, fmt.Sprintf("%s rule in shadow mode", rule.Status), payload.Value, violation)
+			continue
+		case outcomeShadowed:
+			w.shadow(ctx, rule, d.shadowedBy, payload.ServiceID, metricID, payload.Value, violation)
+			continue
+		case outcomeSilenced:
+			if err := recordSilenced(ctx, w.silenceRepo, w.ruleRepo, d.silence, rule, payload.ServiceID, &metricID, violation); err != nil {
+				slog.Error("RuleWorker: failed to record silenced violation", "rule_id", rule.ID, "error", err)
+			}
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSUPPRESSED, "silenced", payload.Value, violation)
 			continue
 		}
 
-		// Rule violated - check action
-		if rule.Action != db.RuleActionOPENINCIDENT {
-			slog.Debug("RuleWorker: rule action not OPEN_INCIDENT, skipping", "rule_id", rule.ID, "action", rule.Action)
+		action, err := w.rules.Action(ctx, rule.Action)
+		if err != nil {
+			slog.Error("RuleWorker: failed to get rule action", "rule_id", rule.ID, "action", rule.Action, "error", err)
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSKIPPED, "failed to get action "+rule.Action, payload.Value, violation)
 			continue
 		}
+		event := eventOf(rule, payload, violation)
+		event.Message = messageOf(action, event)
+		departmentID := routeOf(rule, action)
 
-		// Create incident
-		message := fmt.Sprintf("%s threshold exceeded: %.2f (threshold: %.2f, operator: %s)",
-			payload.MetricType, payload.Value, float64(rule.Threshold.Int.Int64())/100, rule.Operator)
+		// Rule violated - apply the behaviour of its action
+		switch action.Behavior {
+		case db.ActionBehaviorOPENINCIDENT:
+		case db.ActionBehaviorTHROTTLE:
+			throttled, err := w.throttled(ctx, rule, payload.ServiceID)
+			if err != nil {
+				slog.Error("RuleWorker: failed to check cooldown", "rule_id", rule.ID, "error", err)
+				continue
+			}
+			if throttled {
+				w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSUPPRESSED, "throttled", payload.Value, violation)
+				continue
+			}
+		case db.ActionBehaviorWEBHOOK:
+			reason := "webhook requested"
+			requested, err := w.requestWebhook(ctx, rule, event)
+			if err != nil {
+				slog.Error("RuleWorker: failed to request webhook", "rule_id", rule.ID, "error", err)
+				reason = "webhook request failed: " + err.Error()
+			} else if !requested {
+				if err := w.ruleRepo.RecordSuppression(ctx, rule.ID, payload.ServiceID, db.SuppressionReasonTHROTTLED); err != nil {
+					slog.Error("RuleWorker: failed to record suppression", "rule_id", rule.ID, "error", err)
+				}
+				w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSUPPRESSED, "throttled", payload.Value, violation)
+				continue
+			}
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeMATCHED, reason, payload.Value, violation)
+			continue
+		case db.ActionBehaviorNOTIFYONLY:
+			reason := "notification requested"
+			if err := w.requestNotification(ctx, event, departmentID); err != nil {
+				slog.Error("RuleWorker: failed to request notification", "rule_id", rule.ID, "error", err)
+				reason = "notification request failed: " + err.Error()
+			}
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeMATCHED, reason, payload.Value, violation)
+			continue
+		default:
+			slog.Debug("RuleWorker: rule action does not open incidents, skipping", "rule_id", rule.ID, "action", rule.Action)
+			w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSKIPPED, fmt.Sprintf("action %s does not open incidents", rule.Action), payload.Value, violation)
+			continue
+		}
+		evaluationID := w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeMATCHED, "", payload.Value, violation)
 
-		_, err := w.incidentRepo.CreateWithOutbox(ctx, payload.ServiceID, rule.ID, metricID, rule.Severity, message, rule.DepartmentID)
+		// Open an incident, or attach the violation to the one already open
+		inc, created, err := w.incidentRepo.RecordViolation(ctx, incident.CreateParams{
+			ServiceID:             payload.ServiceID,
+			RuleID:                rule.ID,
+			MetricID:              metricID,
+			Severity:              severityOf(rule, violation),
+			Message:               event.Message,
+			DepartmentID:          departmentID,
+			RuleRevision:          rule.Revision,
+			EvaluationID:          evaluationID,
+			ContributingMetricIDs: violation.MetricIDs,
+		})
 		if err != nil {
-			slog.Error("RuleWorker: failed to create incident", "rule_id", rule.ID, "error", err)
+			slog.Error("RuleWorker: failed to record violation", "rule_id", rule.ID, "error", err)
+			continue
+		}
+
+		if !created {
+			slog.Debug("RuleWorker: violation attached to open incident",
+				"incident_id", inc.ID,
+				"rule_id", rule.ID,
+				"service_id", payload.ServiceID,
+				"occurrence_count", inc.OccurrenceCount,
+			)
 			continue
 		}
 
 		slog.Info("RuleWorker: incident created",
+			"incident_id", inc.ID,
 			"rule_id", rule.ID,
 			"service_id", payload.ServiceID,
 			"metric_type", payload.MetricType,
 			"value", payload.Value,
-			"department_id", rule.DepartmentID,
+			"department_id", departmentID,
 		)
 	}
 
+	// Learn from the sample only after the rules compared it with the previous baseline
+	if err := w.baselines.Learn(ctx, SeriesKey{
+		ServiceID:  payload.ServiceID,
+		MetricType: db.MetricType(payload.MetricType),
+	}, samples[len(samples)-1]); err != nil {
+		slog.Error("RuleWorker: failed to update baseline", "service_id", payload.ServiceID, "metric_type", payload.MetricType, "error", err)
+	}
+
+	return nil
+}
+
+// matchSilence returns the silence in effect now that suppresses a violation, or nil
+func (w *Worker) matchSilence(ctx context.Context, rule *db.QualityRule, serviceID string, violation *Violation) (*db.Silence, error) {
+	return w.silenceRepo.Match(ctx, silenceTarget(rule, serviceID, violation), time.Now())
+}
+
+// recordIfSilenced reports whether an active silence matches a violation. Silenced
+// violations are recorded with recordSilenced.
+func recordIfSilenced(ctx context.Context, silences *silence.Repository, ruleRepo *Repository, rule *db.QualityRule, serviceID string, metricID *uuid.UUID, violation *Violation) (bool, error) {
+	s, err := silences.Match(ctx, silenceTarget(rule, serviceID, violation), time.Now())
+	if err != nil || s == nil {
+		return false, err
+	}
+	if err := recordSilenced(ctx, silences, ruleRepo, s, rule, serviceID, metricID, violation); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordSilenced stores a violation a silence suppressed on the silence for auditing
+// and counts it on the rule
+func recordSilenced(ctx context.Context, silences *silence.Repository, ruleRepo *Repository, s *db.Silence, rule *db.QualityRule, serviceID string, metricID *uuid.UUID, violation *Violation) error {
+	if err := silences.RecordSuppressed(ctx, db.CreateSilencedViolationParams{
+		SilenceID:  &s.ID,
+		RuleID:     rule.ID,
+		ServiceID:  serviceID,
+		MetricID:   metricID,
+		MetricType: rule.MetricType,
+		Severity:   severityOf(rule, violation),
+		Message:    violation.Message,
+	}); err != nil {
+		return err
+	}
+	if err := ruleRepo.RecordSuppression(ctx, rule.ID, serviceID, db.SuppressionReasonSILENCED); err != nil {
+		return err
+	}
+	slog.Debug("violation silenced",
+		"silence_id", s.ID,
+		"rule_id", rule.ID,
+		"service_id", serviceID,
+	)
+	return nil
+}
+
+// shadow records a violation of a rule that a higher-priority rule already matched
+// under the FIRST_MATCH policy. Shadowed violations are counted on the rule.
+func (w *Worker) shadow(ctx context.Context, rule *db.QualityRule, matchedRuleID, serviceID string, metricID uuid.UUID, value float64, violation *Violation) {
+	if err := w.ruleRepo.RecordSuppression(ctx, rule.ID, serviceID, db.SuppressionReasonSHADOWED); err != nil {
+		slog.Error("RuleWorker: failed to record shadowed violation", "rule_id", rule.ID, "error", err)
+	}
+	w.recordEvaluation(ctx, rule, metricID, db.EvaluationOutcomeSUPPRESSED, fmt.Sprintf("shadowed by rule %s", matchedRuleID), value, violation)
+	slog.Debug("RuleWorker: violation shadowed",
+		"rule_id", rule.ID,
+		"matched_rule_id", matchedRuleID,
+		"service_id", serviceID,
+	)
+}
+
+// throttled reports whether a THROTTLE rule opened an incident for the service within
+// its cooldown. Suppressed violations are counted so they stay visible on the rule.
+func (w *Worker) throttled(ctx context.Context, rule *db.QualityRule, serviceID string) (bool, error) {
+	openedAt, ok, err := w.incidentRepo.LastOpenedAt(ctx, rule.ID, serviceID)
+	if err != nil {
+		return false, err
+	}
+	cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+	if !ok || time.Since(openedAt) >= cooldown {
+		return false, nil
+	}
+
+	if err := w.ruleRepo.RecordSuppression(ctx, rule.ID, serviceID, db.SuppressionReasonTHROTTLED); err != nil {
+		return false, err
+	}
+	slog.Debug("RuleWorker: violation throttled",
+		"rule_id", rule.ID,
+		"service_id", serviceID,
+		"cooldown_seconds", rule.CooldownSeconds,
+	)
+	return true, nil
+}
+
+// eventOf describes a violation with the fields webhook bodies and action message
+// templates are rendered with
+func eventOf(rule *db.QualityRule, payload MetricPayload, violation *Violation) webhook.Event {
+	return webhook.Event{
+		RuleID:     rule.ID,
+		ServiceID:  payload.ServiceID,
+		MetricID:   payload.ID,
+		MetricType: payload.MetricType,
+		Value:      violation.Value,
+		Threshold:  pgutil.NumericToFloat64(rule.Threshold),
+		Severity:   string(severityOf(rule, violation)),
+		Message:    violation.Message,
+		RecordedAt: payload.RecordedAt,
+	}
+}
+
+// requestWebhook renders the body of a WEBHOOK rule and queues its delivery. It reports
+// false when the rule is still cooling down for the service.
+func (w *Worker) requestWebhook(ctx context.Context, rule *db.QualityRule, event webhook.Event) (bool, error) {
+	if rule.WebhookUrl == nil {
+		return false, fmt.Errorf("rule has no webhook_url")
+	}
+
+	body, err := webhook.RenderBody(rule.WebhookBodyTemplate, event)
+	if err != nil {
+		return false, fmt.Errorf("failed to render webhook body: %w", err)
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal(rule.WebhookHeaders, &headers); err != nil {
+		return false, fmt.Errorf("invalid webhook headers: %w", err)
+	}
+
+	requested, err := w.webhookRepo.Enqueue(ctx, webhook.Request{
+		RuleID:    rule.ID,
+		ServiceID: event.ServiceID,
+		URL:       *rule.WebhookUrl,
+		Headers:   headers,
+		Body:      body,
+	}, webhookCooldown(rule))
+	if err != nil || !requested {
+		return false, err
+	}
+	slog.Info("RuleWorker: webhook requested", "rule_id", rule.ID, "service_id", event.ServiceID)
+	return true, nil
+}
+
+// webhookCooldown is how long a WEBHOOK rule waits after requesting a delivery for a
+// service before it requests another: cooldown_seconds, or DefaultWebhookCooldown
+func webhookCooldown(rule *db.QualityRule) time.Duration {
+	if rule.CooldownSeconds > 0 {
+		return time.Duration(rule.CooldownSeconds) * time.Second
+	}
+	return DefaultWebhookCooldown
+}
+
+// requestNotification queues the notification of a rule whose action notifies without
+// opening an incident
+func (w *Worker) requestNotification(ctx context.Context, event webhook.Event, departmentID *string) error {
+	if err := w.notificationRepo.Enqueue(ctx, notification.Request{
+		RuleID:       event.RuleID,
+		ServiceID:    event.ServiceID,
+		Severity:     event.Severity,
+		Message:      event.Message,
+		DepartmentID: departmentID,
+	}); err != nil {
+		return err
+	}
+	slog.Info("RuleWorker: notification requested", "rule_id", event.RuleID, "service_id", event.ServiceID)
 	return nil
 }
+
+// trackRecovery closes the open incidents of an auto-resolving rule and service once the
+// rule has been healthy for recovery_samples consecutive samples since the last
+// violation attached to them
+func (w *Worker) trackRecovery(ctx context.Context, rule *db.QualityRule, serviceID string, samples []Sample) {
+	value, healthy := Recovered(rule, samples)
+	if !healthy {
+		return
+	}
+	lastViolation, open, err := w.incidentRepo.LastUnresolvedViolation(ctx, rule.ID, serviceID)
+	if err != nil {
+		slog.Error("RuleWorker: failed to get last violation", "rule_id", rule.ID, "error", err)
+		return
+	}
+	if !open || recoveryStreak(rule, samples, lastViolation) < rule.RecoverySamples {
+		return
+	}
+
+	incidents, err := w.incidentRepo.ListUnresolvedForRule(ctx, rule.ID, serviceID)
+	if err != nil {
+		slog.Error("RuleWorker: failed to list unresolved incidents", "rule_id", rule.ID, "error", err)
+		return
+	}
+
+	action, err := w.rules.Action(ctx, rule.Action)
+	if err != nil {
+		slog.Error("RuleWorker: failed to get rule action", "rule_id", rule.ID, "action", rule.Action, "error", err)
+		return
+	}
+
+	message := recoveryMessage(rule, value)
+	for _, inc := range incidents {
+		if _, err := w.incidentRepo.Resolve(ctx, inc.ID, message, routeOf(rule, action)); err != nil {
+			slog.Error("RuleWorker: failed to resolve incident", "incident_id", inc.ID, "error", err)
+			return
+		}
+		slog.Info("RuleWorker: incident auto-resolved",
+			"incident_id", inc.ID,
+			"rule_id", rule.ID,
+			"service_id", serviceID,
+			"value", value,
+		)
+	}
+}
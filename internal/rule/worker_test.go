@@ -0,0 +1,575 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/incident"
+	"github.com/unitythemaker/tracely/internal/metric"
+	"github.com/unitythemaker/tracely/internal/notification"
+	"github.com/unitythemaker/tracely/internal/outbox"
+	"github.com/unitythemaker/tracely/internal/silence"
+	"github.com/unitythemaker/tracely/internal/testutil"
+	"github.com/unitythemaker/tracely/internal/webhook"
+	"github.com/unitythemaker/tracely/pkg/schedule"
+)
+
+type workerTest struct {
+	worker       *Worker
+	queries      *db.Queries
+	metricRepo   *metric.Repository
+	incidentRepo *incident.Repository
+	start        time.Time
+}
+
+func setupWorkerTest(t *testing.T) (*workerTest, func()) {
+	t.Helper()
+
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+
+	testutil.CleanupTestData(t, pool)
+	testutil.TestService(t, q, "S1", "Service 1")
+
+	incidentRepo := incident.NewRepository(pool, q)
+	wt := &workerTest{
+		worker:       NewWorker(outbox.NewRepository(q), NewRepository(pool, q), incidentRepo, webhook.NewRepository(q), notification.NewRepository(q), silence.NewRepository(q), time.Second),
+		queries:      q,
+		metricRepo:   metric.NewRepository(pool, q),
+		incidentRepo: incidentRepo,
+		start:        time.Now().Add(-time.Hour).Truncate(time.Second),
+	}
+
+	cleanup := func() {
+		testutil.CleanupTestData(t, pool)
+	}
+	return wt, cleanup
+}
+
+// record stores LATENCY_MS samples for S1 one minute apart and runs the worker over them
+func (wt *workerTest) record(t *testing.T, values ...float64) {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, v := range values {
+		_, err := wt.metricRepo.CreateWithOutbox(ctx, metric.CreateMetricRequest{
+			ServiceID:  "S1",
+			MetricType: string(db.MetricTypeLATENCYMS),
+			Value:      v,
+			RecordedAt: wt.start,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create metric: %v", err)
+		}
+		wt.start = wt.start.Add(time.Minute)
+	}
+	wt.worker.processEvents(ctx)
+}
+
+func (wt *workerTest) incidents(t *testing.T) []db.Incident {
+	t.Helper()
+
+	incidents, err := wt.incidentRepo.List(context.Background(), 100, 0)
+	if err != nil {
+		t.Fatalf("Failed to list incidents: %v", err)
+	}
+	return incidents
+}
+
+func TestWorker_AutoResolve(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := wt.worker.ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency",
+		RuleDefinition: RuleDefinition{
+			MetricType:        "LATENCY_MS",
+			Threshold:         150,
+			Operator:          ">",
+			Action:            "OPEN_INCIDENT",
+			Severity:          "HIGH",
+			IsActive:          true,
+			AutoResolve:       true,
+			RecoveryThreshold: threshold(120),
+			RecoverySamples:   2,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency")
+
+	wt.record(t, 200)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	id := incidents[0].ID
+
+	// Below the threshold but above the recovery threshold: still open
+	wt.record(t, 140, 130)
+	if inc, _ := wt.incidentRepo.Get(ctx, id); inc.Status == db.IncidentStatusCLOSED {
+		t.Fatalf("Expected incident to stay open above the recovery threshold")
+	}
+
+	// One healthy sample is not enough
+	wt.record(t, 100)
+	if inc, _ := wt.incidentRepo.Get(ctx, id); inc.Status == db.IncidentStatusCLOSED {
+		t.Fatalf("Expected incident to stay open after a single healthy sample")
+	}
+
+	wt.record(t, 110)
+	inc, _ := wt.incidentRepo.Get(ctx, id)
+	if inc.Status != db.IncidentStatusCLOSED {
+		t.Fatalf("Expected incident to be auto-resolved, got %s", inc.Status)
+	}
+
+	events, _ := wt.incidentRepo.ListEvents(ctx, id)
+	found := false
+	for _, e := range events {
+		if e.EventType == db.IncidentEventTypeSTATUSCHANGED && e.Actor != nil && *e.Actor == "system" &&
+			e.NewValue != nil && *e.NewValue == string(db.IncidentStatusCLOSED) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected STATUS_CHANGED event by system")
+	}
+
+	updates, err := wt.queries.GetUnprocessedEvents(ctx, db.GetUnprocessedEventsParams{
+		Processor: "test",
+		EventType: db.EventTypeINCIDENTUPDATED,
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get outbox events: %v", err)
+	}
+	if len(updates) != 1 {
+		t.Errorf("Expected 1 INCIDENT_UPDATED outbox event, got %d", len(updates))
+	}
+}
+
+func TestWorker_NoAutoResolveByDefault(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{
+		ID:        "latency",
+		Threshold: 150,
+		IsActive:  true,
+	})
+
+	wt.record(t, 200, 100, 100)
+
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 || incidents[0].Status == db.IncidentStatusCLOSED {
+		t.Errorf("Expected a single open incident, got %+v", incidents)
+	}
+}
+
+func TestWorker_DeduplicatesIncidents(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{
+		ID:        "latency",
+		Threshold: 150,
+		IsActive:  true,
+	})
+
+	wt.record(t, 200, 210, 220, 230)
+
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if incidents[0].OccurrenceCount != 4 {
+		t.Errorf("Expected occurrence count 4, got %d", incidents[0].OccurrenceCount)
+	}
+}
+
+func TestWorker_Throttle(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	testutil.TestRule(t, wt.queries, testutil.TestRuleParams{
+		ID:              "latency",
+		Threshold:       150,
+		Action:          ActionThrottle,
+		IsActive:        true,
+		CooldownSeconds: 600,
+	})
+
+	wt.record(t, 200)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if _, err := wt.incidentRepo.Resolve(ctx, incidents[0].ID, "resolved", nil); err != nil {
+		t.Fatalf("Failed to resolve incident: %v", err)
+	}
+
+	// Within the cooldown violations are suppressed even after the incident was closed
+	wt.record(t, 210, 220)
+	if incidents := wt.incidents(t); len(incidents) != 1 {
+		t.Fatalf("Expected violations within the cooldown to be throttled, got %d incidents", len(incidents))
+	}
+
+	suppressions, err := wt.worker.ruleRepo.ListSuppressions(ctx, "latency")
+	if err != nil {
+		t.Fatalf("Failed to list suppressions: %v", err)
+	}
+	if len(suppressions) != 1 {
+		t.Fatalf("Expected 1 suppression row, got %d", len(suppressions))
+	}
+	if suppressions[0].ServiceID != "S1" || suppressions[0].Reason != db.SuppressionReasonTHROTTLED {
+		t.Errorf("Unexpected suppression: %+v", suppressions[0])
+	}
+	if suppressions[0].SuppressedCount != 2 {
+		t.Errorf("Expected suppressed count 2, got %d", suppressions[0].SuppressedCount)
+	}
+}
+
+func TestWorker_Webhook(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	url := "https://example.com/hook"
+	tmpl := `{"text": {{json .Message}}, "value": {{.Value}}}`
+	_, err := wt.worker.ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency-webhook",
+		RuleDefinition: RuleDefinition{
+			MetricType:          "LATENCY_MS",
+			Threshold:           150,
+			Operator:            ">",
+			Action:              "WEBHOOK",
+			Severity:            "HIGH",
+			IsActive:            true,
+			WebhookURL:          &url,
+			WebhookHeaders:      map[string]string{"Authorization": "Bearer token"},
+			WebhookBodyTemplate: &tmpl,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency-webhook")
+
+	// The second violation falls within the default cooldown
+	wt.record(t, 100, 200, 210)
+
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Errorf("Expected WEBHOOK rules not to open incidents, got %d", len(incidents))
+	}
+
+	events, err := outbox.NewRepository(wt.queries).GetUnprocessedWebhookEvents(ctx, "test", 10)
+	if err != nil {
+		t.Fatalf("Failed to get outbox events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 WEBHOOK_REQUESTED event, got %d", len(events))
+	}
+
+	var req webhook.Request
+	if err := json.Unmarshal(events[0].Payload, &req); err != nil {
+		t.Fatalf("Failed to unmarshal payload: %v", err)
+	}
+	if req.URL != url || req.ServiceID != "S1" || req.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("Unexpected webhook request: %+v", req)
+	}
+	if !strings.HasPrefix(req.Body, `{"text": "LATENCY_MS`) || !strings.HasSuffix(req.Body, `"value": 200}`) {
+		t.Errorf("Expected rendered body, got %s", req.Body)
+	}
+
+	suppressions, err := wt.worker.ruleRepo.ListSuppressions(ctx, "latency-webhook")
+	if err != nil {
+		t.Fatalf("Failed to list suppressions: %v", err)
+	}
+	if len(suppressions) != 1 || suppressions[0].Reason != db.SuppressionReasonTHROTTLED || suppressions[0].SuppressedCount != 1 {
+		t.Errorf("Expected 1 THROTTLED suppression, got %+v", suppressions)
+	}
+}
+
+func TestWorker_Expression(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	expression := "between(value, 150, 300) && value > 1.2 * avg_over(600)"
+	_, err := wt.worker.ruleRepo.Create(context.Background(), CreateRuleRequest{
+		ID: "latency-spike",
+		RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS",
+			Expression: &expression,
+			Action:     "OPEN_INCIDENT",
+			Severity:   "HIGH",
+			IsActive:   true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency-spike")
+
+	// 400 is above the range and 160 is not far enough above the baseline
+	wt.record(t, 140, 150, 400, 160)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected no incidents, got %d", len(incidents))
+	}
+
+	wt.record(t, 120, 120, 120, 250)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if incidents[0].Message == nil || !strings.Contains(*incidents[0].Message, expression) {
+		t.Errorf("Expected message to contain the expression, got %v", incidents[0].Message)
+	}
+}
+
+func TestWorker_Anomaly(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	deviations := 4.0
+	_, err := wt.worker.ruleRepo.Create(context.Background(), CreateRuleRequest{
+		ID: "latency-anomaly",
+		RuleDefinition: RuleDefinition{
+			MetricType:        "LATENCY_MS",
+			AnomalyDeviations: &deviations,
+			Action:            "OPEN_INCIDENT",
+			Severity:          "HIGH",
+			IsActive:          true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency-anomaly")
+
+	// Learn a baseline around 100 +/- 10
+	var values []float64
+	for i := 0; i < 40; i++ {
+		values = append(values, 90+float64(i%2)*20)
+	}
+	wt.record(t, values...)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected no incidents while learning, got %d", len(incidents))
+	}
+
+	// A restarted worker continues from the stored baseline
+	wt.worker.releaseServices()
+	wt.worker = NewWorker(wt.worker.outboxRepo, wt.worker.ruleRepo, wt.incidentRepo, wt.worker.webhookRepo, wt.worker.notificationRepo, wt.worker.silenceRepo, time.Second)
+	baseline, ok, err := wt.worker.baselines.Get(context.Background(), SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}, OverallBucket)
+	if err != nil || !ok || baseline.Count != int64(len(values)) {
+		t.Fatalf("Expected the stored baseline to be loaded, got %+v %v %v", baseline, ok, err)
+	}
+
+	wt.record(t, 250)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if incidents[0].Message == nil || !strings.Contains(*incidents[0].Message, "LATENCY_MS anomaly") {
+		t.Errorf("Unexpected message: %v", incidents[0].Message)
+	}
+}
+
+func TestWorker_RateOfChange(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	_, err := wt.worker.ruleRepo.Create(context.Background(), CreateRuleRequest{
+		ID: "latency-doubled",
+		RuleDefinition: RuleDefinition{
+			MetricType:    "LATENCY_MS",
+			Threshold:     100,
+			Operator:      ">=",
+			ChangeMode:    "PERCENT",
+			ChangeSeconds: 120,
+			Action:        "OPEN_INCIDENT",
+			Severity:      "HIGH",
+			IsActive:      true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency-doubled")
+
+	// 300 is high but the series was already high two minutes earlier
+	wt.record(t, 280, 290, 300, 100, 110)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected no incidents, got %d", len(incidents))
+	}
+
+	wt.record(t, 230)
+	incidents := wt.incidents(t)
+	if len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(incidents))
+	}
+	if incidents[0].Message == nil || !strings.Contains(*incidents[0].Message, "100.00 -> 230.00") {
+		t.Errorf("Expected message to show both values, got %v", incidents[0].Message)
+	}
+}
+
+func TestWorker_Schedule(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	// A window two days away never contains the samples
+	day := strings.ToUpper(wt.start.UTC().AddDate(0, 0, 2).Weekday().String()[:3])
+	rule := RuleDefinition{
+		MetricType: "LATENCY_MS",
+		Threshold:  150,
+		Operator:   ">",
+		Action:     "OPEN_INCIDENT",
+		Severity:   "HIGH",
+		IsActive:   true,
+		Schedule: &schedule.Schedule{
+			Windows: []schedule.Window{{Weekdays: []string{day}, Start: "00:00", End: "23:59"}},
+		},
+	}
+	ctx := context.Background()
+	_, err := wt.worker.ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency",
+		RuleDefinition: RuleDefinition{
+			MetricType: rule.MetricType,
+			Threshold:  rule.Threshold,
+			Operator:   rule.Operator,
+			Action:     rule.Action,
+			Severity:   rule.Severity,
+			IsActive:   rule.IsActive,
+			Schedule:   rule.Schedule,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency")
+
+	wt.record(t, 200, 210)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected no incidents outside the schedule, got %d", len(incidents))
+	}
+
+	rule.Schedule = nil
+	_, change, err := wt.worker.ruleRepo.Update(ctx, "latency", rule, "test")
+	if err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	if _, err := wt.worker.ruleRepo.ApproveChange(ctx, change.ID, "reviewer", nil); err != nil {
+		t.Fatalf("Failed to approve the update: %v", err)
+	}
+	wt.record(t, 220)
+	if incidents := wt.incidents(t); len(incidents) != 1 {
+		t.Errorf("Expected 1 incident once the schedule is removed, got %d", len(incidents))
+	}
+}
+
+func TestWorker_Silenced(t *testing.T) {
+	wt, cleanup := setupWorkerTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := wt.worker.ruleRepo.Create(ctx, CreateRuleRequest{
+		ID: "latency",
+		RuleDefinition: RuleDefinition{
+			MetricType: "LATENCY_MS",
+			Threshold:  150,
+			Operator:   ">",
+			Action:     "OPEN_INCIDENT",
+			Severity:   "HIGH",
+			IsActive:   true,
+		},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	publish(t, wt.worker.ruleRepo, "latency")
+
+	service := "S1"
+	start := time.Now().Add(-time.Minute)
+	end := start.Add(time.Hour)
+	s, err := wt.worker.silenceRepo.Create(ctx, silence.CreateSilenceRequest{
+		ServiceID: &service,
+		StartsAt:  start,
+		EndsAt:    &end,
+		Author:    "noc",
+		Reason:    "maintenance",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+
+	wt.record(t, 200, 210)
+	if incidents := wt.incidents(t); len(incidents) != 0 {
+		t.Fatalf("Expected silenced violations to open no incidents, got %d", len(incidents))
+	}
+
+	suppressed, total, err := wt.worker.silenceRepo.ListSuppressed(ctx, s.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list suppressed violations: %v", err)
+	}
+	if total != 2 || suppressed[0].RuleID != "latency" {
+		t.Errorf("Expected 2 suppressed violations, got %d", total)
+	}
+
+	suppressions, _ := wt.queries.ListRuleSuppressions(ctx, "latency")
+	if len(suppressions) != 1 || suppressions[0].Reason != db.SuppressionReasonSILENCED || suppressions[0].SuppressedCount != 2 {
+		t.Errorf("Expected 2 SILENCED suppressions on the rule, got %+v", suppressions)
+	}
+
+	// Once the silence has ended violations open incidents again
+	if err := wt.worker.silenceRepo.Delete(ctx, s.ID); err != nil {
+		t.Fatalf("Failed to delete silence: %v", err)
+	}
+	wt.record(t, 220)
+	if incidents := wt.incidents(t); len(incidents) != 1 {
+		t.Fatalf("Expected 1 incident after the silence, got %d", len(incidents))
+	}
+}
+
+func TestWorker_OwnService(t *testing.T) {
+	series := NewSeriesStore(nil)
+	baselines := NewBaselineStore(nil, nil)
+	w := &Worker{
+		series:      series,
+		baselines:   baselines,
+		generations: make(map[string]int64),
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	for _, serviceID := range []string{"S1", "S2"} {
+		w.ownService(serviceID, 1)
+		key := SeriesKey{ServiceID: serviceID, MetricType: db.MetricTypeLATENCYMS}
+		series.Observe(ctx, key, Sample{MetricID: uuid.New(), Value: 100, RecordedAt: now})
+		baselines.Learn(ctx, key, Sample{Value: 100, RecordedAt: now})
+	}
+
+	// Keeping the lease keeps the state
+	w.ownService("S1", 1)
+	if _, ok, _ := series.Latest(ctx, SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}, now); !ok {
+		t.Fatal("Expected S1 to keep its series")
+	}
+
+	// A lease that changed owner drops the state of that service only
+	w.ownService("S1", 2)
+	if _, ok, _ := series.Latest(ctx, SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}, now); ok {
+		t.Error("Expected the S1 series to be dropped")
+	}
+	if _, ok, _ := baselines.Get(ctx, SeriesKey{ServiceID: "S1", MetricType: db.MetricTypeLATENCYMS}, OverallBucket); ok {
+		t.Error("Expected the S1 baselines to be dropped")
+	}
+	if _, ok, _ := series.Latest(ctx, SeriesKey{ServiceID: "S2", MetricType: db.MetricTypeLATENCYMS}, now); !ok {
+		t.Error("Expected S2 to keep its series")
+	}
+}
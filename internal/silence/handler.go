@@ -0,0 +1,227 @@
+package silence
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/httputil"
+	"github.com/unitythemaker/tracely/pkg/pgerror"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/silences", h.List)
+	mux.HandleFunc("GET /api/silences/{id}", h.Get)
+	mux.HandleFunc("POST /api/silences", h.Create)
+	mux.HandleFunc("PUT /api/silences/{id}", h.Update)
+	mux.HandleFunc("DELETE /api/silences/{id}", h.Delete)
+	mux.HandleFunc("GET /api/silences/{id}/suppressed", h.ListSuppressed)
+}
+
+// pagination reads limit and offset from the query string
+func pagination(r *http.Request) (int, int) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pagination(r)
+
+	silences, total, err := h.repo.List(r.Context(), int32(limit), int32(offset))
+	if err != nil {
+		slog.Error("failed to list silences", "error", err)
+		httputil.InternalError(w, "failed to list silences")
+		return
+	}
+	httputil.SuccessPaginated(w, ToResponseList(silences, time.Now()), total, limit, offset)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.BadRequest(w, "invalid silence id")
+		return
+	}
+
+	s, err := h.repo.Get(r.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "silence not found")
+			return
+		}
+		slog.Error("failed to get silence", "error", err)
+		httputil.InternalError(w, "failed to get silence")
+		return
+	}
+	httputil.Success(w, ToResponse(s, time.Now()))
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateSilenceRequest
+	if err := httputil.Decode(r, &req); err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if err := validate(&req); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	s, err := h.repo.Create(r.Context(), req)
+	if err != nil {
+		if pgerror.IsForeignKeyViolation(err) {
+			httputil.BadRequest(w, "service or rule not found")
+			return
+		}
+		slog.Error("failed to create silence", "error", err)
+		httputil.InternalError(w, "failed to create silence")
+		return
+	}
+	httputil.Created(w, ToResponse(s, time.Now()))
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.BadRequest(w, "invalid silence id")
+		return
+	}
+
+	var req UpdateSilenceRequest
+	if err := httputil.Decode(r, &req); err != nil {
+		httputil.BadRequest(w, "invalid request body")
+		return
+	}
+	if err := validate(&req); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
+	s, err := h.repo.Update(r.Context(), id, req)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "silence not found")
+			return
+		}
+		if pgerror.IsForeignKeyViolation(err) {
+			httputil.BadRequest(w, "service or rule not found")
+			return
+		}
+		slog.Error("failed to update silence", "error", err)
+		httputil.InternalError(w, "failed to update silence")
+		return
+	}
+	httputil.Success(w, ToResponse(s, time.Now()))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.BadRequest(w, "invalid silence id")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "silence not found")
+			return
+		}
+		slog.Error("failed to delete silence", "error", err)
+		httputil.InternalError(w, "failed to delete silence")
+		return
+	}
+	httputil.NoContent(w)
+}
+
+// ListSuppressed returns the violations a silence suppressed, newest first
+func (h *Handler) ListSuppressed(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputil.BadRequest(w, "invalid silence id")
+		return
+	}
+	limit, offset := pagination(r)
+
+	if _, err := h.repo.Get(r.Context(), id); err != nil {
+		if err == pgx.ErrNoRows {
+			httputil.NotFound(w, "silence not found")
+			return
+		}
+		slog.Error("failed to get silence", "error", err)
+		httputil.InternalError(w, "failed to list suppressed violations")
+		return
+	}
+
+	rows, total, err := h.repo.ListSuppressed(r.Context(), id, int32(limit), int32(offset))
+	if err != nil {
+		slog.Error("failed to list suppressed violations", "error", err)
+		httputil.InternalError(w, "failed to list suppressed violations")
+		return
+	}
+	httputil.SuccessPaginated(w, ToSuppressedViolationResponseList(rows), total, limit, offset)
+}
+
+// validate checks a silence definition
+func validate(req *CreateSilenceRequest) error {
+	switch {
+	case req.Author == "":
+		return errors.New("author is required")
+	case req.Reason == "":
+		return errors.New("reason is required")
+	case req.ServiceID == nil && req.MetricType == nil && req.RuleID == nil && req.Severity == nil:
+		return errors.New("at least one of service_id, metric_type, rule_id and severity is required")
+	case req.MetricType != nil && !isValidMetricType(db.MetricType(*req.MetricType)):
+		return errors.New("invalid metric_type: " + *req.MetricType)
+	case req.Severity != nil && !isValidSeverity(db.IncidentSeverity(*req.Severity)):
+		return errors.New("invalid severity: " + *req.Severity)
+	case req.StartsAt.IsZero():
+		return errors.New("starts_at is required")
+	case req.EndsAt == nil && req.Recurrence == nil:
+		return errors.New("ends_at is required for silences without a recurrence")
+	case req.EndsAt != nil && !req.EndsAt.After(req.StartsAt):
+		return errors.New("ends_at must be after starts_at")
+	}
+	if req.Recurrence != nil {
+		return req.Recurrence.Validate()
+	}
+	return nil
+}
+
+func isValidMetricType(t db.MetricType) bool {
+	switch t {
+	case db.MetricTypeLATENCYMS, db.MetricTypePACKETLOSS, db.MetricTypeERRORRATE, db.MetricTypeBUFFERRATIO:
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidSeverity(s db.IncidentSeverity) bool {
+	switch s {
+	case db.IncidentSeverityLOW, db.IncidentSeverityMEDIUM, db.IncidentSeverityHIGH, db.IncidentSeverityCRITICAL:
+		return true
+	default:
+		return false
+	}
+}
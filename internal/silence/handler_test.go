@@ -0,0 +1,275 @@
+package silence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+)
+
+func setupSilenceTest(t *testing.T) (*Handler, *Repository, *db.Queries, func()) {
+	t.Helper()
+
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+
+	testutil.CleanupTestData(t, pool)
+	testutil.TestService(t, q, "S1", "Service 1")
+
+	repo := NewRepository(q)
+	cleanup := func() {
+		testutil.CleanupTestData(t, pool)
+	}
+	return NewHandler(repo), repo, q, cleanup
+}
+
+func TestSilenceHandler_CRUD(t *testing.T) {
+	handler, _, _, cleanup := setupSilenceTest(t)
+	defer cleanup()
+
+	start := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	body, _ := json.Marshal(map[string]any{
+		"service_id": "S1",
+		"starts_at":  start,
+		"recurrence": map[string]any{"weekdays": []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}, "start": "00:00", "end": "00:00"},
+		"author":     "noc",
+		"reason":     "maintenance",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/silences", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.Create(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected empty recurrence window to be rejected, got %d", rr.Code)
+	}
+
+	body, _ = json.Marshal(map[string]any{
+		"service_id": "S1",
+		"starts_at":  start,
+		"ends_at":    start.Add(2 * time.Hour),
+		"author":     "noc",
+		"reason":     "Superonline maintenance",
+	})
+	req = httptest.NewRequest(http.MethodPost, "/api/silences", bytes.NewReader(body))
+	rr = httptest.NewRecorder()
+	handler.Create(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Data SilenceResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if !created.Data.Active || created.Data.ServiceID == nil || *created.Data.ServiceID != "S1" {
+		t.Fatalf("Unexpected silence: %+v", created.Data)
+	}
+	id := created.Data.ID.String()
+
+	body, _ = json.Marshal(map[string]any{
+		"service_id": "S1",
+		"starts_at":  start,
+		"ends_at":    start.Add(30 * time.Minute),
+		"author":     "noc",
+		"reason":     "Superonline maintenance, ended early",
+	})
+	req = httptest.NewRequest(http.MethodPut, "/api/silences/"+id, bytes.NewReader(body))
+	req.SetPathValue("id", id)
+	rr = httptest.NewRecorder()
+	handler.Update(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/silences/"+id, nil)
+	req.SetPathValue("id", id)
+	rr = httptest.NewRecorder()
+	handler.Get(rr, req)
+	var got struct {
+		Data SilenceResponse `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Data.Active {
+		t.Errorf("Expected the shortened silence to have ended")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/silences/"+id, nil)
+	req.SetPathValue("id", id)
+	rr = httptest.NewRecorder()
+	handler.Delete(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.Delete(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a deleted silence, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestSilenceHandler_Create_Invalid(t *testing.T) {
+	handler, _, _, cleanup := setupSilenceTest(t)
+	defer cleanup()
+
+	start := time.Now().UTC().Truncate(time.Second)
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"no matcher", map[string]any{"service_id": nil}},
+		{"missing author", map[string]any{"author": ""}},
+		{"missing reason", map[string]any{"reason": ""}},
+		{"unknown metric type", map[string]any{"metric_type": "JITTER"}},
+		{"unknown severity", map[string]any{"severity": "URGENT"}},
+		{"end before start", map[string]any{"ends_at": start.Add(-time.Hour)}},
+		{"no end without recurrence", map[string]any{"ends_at": nil}},
+		{"invalid recurrence", map[string]any{"recurrence": map[string]any{"weekdays": []string{"SUN"}, "start": "25:00", "end": "04:00"}}},
+		{"unknown service", map[string]any{"service_id": "missing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{
+				"service_id": "S1",
+				"starts_at":  start,
+				"ends_at":    start.Add(time.Hour),
+				"author":     "noc",
+				"reason":     "maintenance",
+			}
+			for k, v := range tt.body {
+				if v == nil {
+					delete(body, k)
+					continue
+				}
+				body[k] = v
+			}
+
+			bodyBytes, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/api/silences", bytes.NewReader(bodyBytes))
+			rr := httptest.NewRecorder()
+			handler.Create(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSilenceHandler_ListSuppressed(t *testing.T) {
+	handler, repo, q, cleanup := setupSilenceTest(t)
+	defer cleanup()
+
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "latency-rule", MetricType: db.MetricTypeLATENCYMS})
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	end := start.Add(2 * time.Hour)
+	service := "S1"
+	s, err := repo.Create(ctx, CreateSilenceRequest{
+		ServiceID: &service,
+		StartsAt:  start,
+		EndsAt:    &end,
+		Author:    "noc",
+		Reason:    "maintenance",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+
+	metricID := uuid.New()
+	err = repo.RecordSuppressed(ctx, db.CreateSilencedViolationParams{
+		SilenceID:  &s.ID,
+		RuleID:     "latency-rule",
+		ServiceID:  "S1",
+		MetricID:   &metricID,
+		MetricType: db.MetricTypeLATENCYMS,
+		Severity:   db.IncidentSeverityHIGH,
+		Message:    "LATENCY_MS value 300.00 exceeded threshold",
+	})
+	if err != nil {
+		t.Fatalf("Failed to record suppressed violation: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/silences/"+s.ID.String()+"/suppressed", nil)
+	req.SetPathValue("id", s.ID.String())
+	rr := httptest.NewRecorder()
+	handler.ListSuppressed(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var response struct {
+		Data []SuppressedViolationResponse `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Meta.Total != 1 || len(response.Data) != 1 || response.Data[0].RuleID != "latency-rule" {
+		t.Errorf("Unexpected suppressed violations: %+v", response)
+	}
+}
+
+func TestSilenceRepository_DeleteKeepsSuppressed(t *testing.T) {
+	_, repo, q, cleanup := setupSilenceTest(t)
+	defer cleanup()
+	pool := testutil.GetTestPool(t)
+
+	testutil.TestRule(t, q, testutil.TestRuleParams{ID: "latency-rule", MetricType: db.MetricTypeLATENCYMS})
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	end := start.Add(2 * time.Hour)
+	service := "S1"
+	s, err := repo.Create(ctx, CreateSilenceRequest{
+		ServiceID: &service,
+		StartsAt:  start,
+		EndsAt:    &end,
+		Author:    "noc",
+		Reason:    "maintenance",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+	metricID := uuid.New()
+	err = repo.RecordSuppressed(ctx, db.CreateSilencedViolationParams{
+		SilenceID:  &s.ID,
+		RuleID:     "latency-rule",
+		ServiceID:  "S1",
+		MetricID:   &metricID,
+		MetricType: db.MetricTypeLATENCYMS,
+		Severity:   db.IncidentSeverityHIGH,
+		Message:    "LATENCY_MS value 300.00 exceeded threshold",
+	})
+	if err != nil {
+		t.Fatalf("Failed to record suppressed violation: %v", err)
+	}
+
+	// Neither deleting the silence nor the rule and service erases the audit trail
+	if err := repo.Delete(ctx, s.ID); err != nil {
+		t.Fatalf("Failed to delete silence: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "DELETE FROM quality_rules WHERE id = 'latency-rule'"); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "DELETE FROM services WHERE id = 'S1'"); err != nil {
+		t.Fatalf("Failed to delete service: %v", err)
+	}
+
+	var kept int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM silenced_violations WHERE silence_id IS NULL AND rule_id = 'latency-rule' AND service_id = 'S1'").Scan(&kept)
+	if err != nil {
+		t.Fatalf("Failed to count suppressed violations: %v", err)
+	}
+	if kept != 1 {
+		t.Errorf("Expected the suppressed violation to be kept, got %d", kept)
+	}
+}
@@ -0,0 +1,104 @@
+package silence
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// CreateSilenceRequest defines a silence. At least one of service_id, metric_type,
+// rule_id and severity must be set; ends_at may only be left out for recurring silences.
+type CreateSilenceRequest struct {
+	ServiceID  *string     `json:"service_id,omitempty"`
+	MetricType *string     `json:"metric_type,omitempty"`
+	RuleID     *string     `json:"rule_id,omitempty"`
+	Severity   *string     `json:"severity,omitempty"`
+	StartsAt   time.Time   `json:"starts_at"`
+	EndsAt     *time.Time  `json:"ends_at,omitempty"`
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	Author     string      `json:"author"`
+	Reason     string      `json:"reason"`
+}
+
+type UpdateSilenceRequest = CreateSilenceRequest
+
+type SilenceResponse struct {
+	ID         uuid.UUID   `json:"id"`
+	ServiceID  *string     `json:"service_id,omitempty"`
+	MetricType *string     `json:"metric_type,omitempty"`
+	RuleID     *string     `json:"rule_id,omitempty"`
+	Severity   *string     `json:"severity,omitempty"`
+	StartsAt   time.Time   `json:"starts_at"`
+	EndsAt     *time.Time  `json:"ends_at,omitempty"`
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	Author     string      `json:"author"`
+	Reason     string      `json:"reason"`
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+func ToResponse(s *db.Silence, now time.Time) SilenceResponse {
+	resp := SilenceResponse{
+		ID:        s.ID,
+		ServiceID: s.ServiceID,
+		RuleID:    s.RuleID,
+		StartsAt:  s.StartsAt,
+		Author:    s.Author,
+		Reason:    s.Reason,
+		Active:    Active(s, now),
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+	if s.MetricType.Valid {
+		metricType := string(s.MetricType.MetricType)
+		resp.MetricType = &metricType
+	}
+	if s.Severity.Valid {
+		severity := string(s.Severity.IncidentSeverity)
+		resp.Severity = &severity
+	}
+	if s.EndsAt.Valid {
+		resp.EndsAt = &s.EndsAt.Time
+	}
+	resp.Recurrence, _ = RecurrenceOf(s)
+	return resp
+}
+
+func ToResponseList(silences []db.Silence, now time.Time) []SilenceResponse {
+	result := make([]SilenceResponse, len(silences))
+	for i, s := range silences {
+		result[i] = ToResponse(&s, now)
+	}
+	return result
+}
+
+// SuppressedViolationResponse is a violation a silence kept from opening an incident
+type SuppressedViolationResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	RuleID       string     `json:"rule_id"`
+	ServiceID    string     `json:"service_id"`
+	MetricID     *uuid.UUID `json:"metric_id"`
+	MetricType   string     `json:"metric_type"`
+	Severity     string     `json:"severity"`
+	Message      string     `json:"message"`
+	SuppressedAt time.Time  `json:"suppressed_at"`
+}
+
+func ToSuppressedViolationResponseList(rows []db.SilencedViolation) []SuppressedViolationResponse {
+	result := make([]SuppressedViolationResponse, len(rows))
+	for i, v := range rows {
+		result[i] = SuppressedViolationResponse{
+			ID:           v.ID,
+			RuleID:       v.RuleID,
+			ServiceID:    v.ServiceID,
+			MetricID:     v.MetricID,
+			MetricType:   string(v.MetricType),
+			Severity:     string(v.Severity),
+			Message:      v.Message,
+			SuppressedAt: v.SuppressedAt,
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package silence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+type Repository struct {
+	q *db.Queries
+}
+
+func NewRepository(q *db.Queries) *Repository {
+	return &Repository{q: q}
+}
+
+// params converts a request into the stored columns of a silence
+func params(req CreateSilenceRequest) (db.CreateSilenceParams, error) {
+	p := db.CreateSilenceParams{
+		ServiceID: req.ServiceID,
+		RuleID:    req.RuleID,
+		StartsAt:  req.StartsAt,
+		Author:    req.Author,
+		Reason:    req.Reason,
+	}
+	if req.MetricType != nil {
+		p.MetricType = db.NullMetricType{MetricType: db.MetricType(*req.MetricType), Valid: true}
+	}
+	if req.Severity != nil {
+		p.Severity = db.NullIncidentSeverity{IncidentSeverity: db.IncidentSeverity(*req.Severity), Valid: true}
+	}
+	if req.EndsAt != nil {
+		p.EndsAt = pgtype.Timestamptz{Time: *req.EndsAt, Valid: true}
+	}
+	if req.Recurrence != nil {
+		recurrence, err := json.Marshal(req.Recurrence)
+		if err != nil {
+			return p, fmt.Errorf("failed to marshal recurrence: %w", err)
+		}
+		p.Recurrence = recurrence
+	}
+	return p, nil
+}
+
+func (r *Repository) Create(ctx context.Context, req CreateSilenceRequest) (*db.Silence, error) {
+	p, err := params(req)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.q.CreateSilence(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*db.Silence, error) {
+	s, err := r.q.GetSilence(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *Repository) List(ctx context.Context, limit, offset int32) ([]db.Silence, int, error) {
+	silences, err := r.q.ListSilences(ctx, db.ListSilencesParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.q.CountSilences(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return silences, int(total), nil
+}
+
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, req UpdateSilenceRequest) (*db.Silence, error) {
+	p, err := params(req)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.q.UpdateSilence(ctx, db.UpdateSilenceParams{
+		ID:         id,
+		ServiceID:  p.ServiceID,
+		MetricType: p.MetricType,
+		RuleID:     p.RuleID,
+		Severity:   p.Severity,
+		StartsAt:   p.StartsAt,
+		EndsAt:     p.EndsAt,
+		Recurrence: p.Recurrence,
+		Author:     p.Author,
+		Reason:     p.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Delete removes a silence. The violations it suppressed are kept for auditing without
+// it. It returns pgx.ErrNoRows when the silence does not exist.
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	n, err := r.q.DeleteSilence(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Match returns the first silence in effect at the given time that matches the
+// violation, or nil when the violation is not silenced
+func (r *Repository) Match(ctx context.Context, target Target, at time.Time) (*db.Silence, error) {
+	silences, err := r.q.ListCurrentSilences(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	for i := range silences {
+		if Matches(&silences[i], target) && Active(&silences[i], at) {
+			return &silences[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// RecordSuppressed stores a violation a silence suppressed so it can be audited later
+func (r *Repository) RecordSuppressed(ctx context.Context, params db.CreateSilencedViolationParams) error {
+	return r.q.CreateSilencedViolation(ctx, params)
+}
+
+func (r *Repository) ListSuppressed(ctx context.Context, id uuid.UUID, limit, offset int32) ([]db.SilencedViolation, int, error) {
+	rows, err := r.q.ListSilencedViolations(ctx, db.ListSilencedViolationsParams{
+		SilenceID: &id,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.q.CountSilencedViolations(ctx, &id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, int(total), nil
+}
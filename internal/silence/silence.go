@@ -0,0 +1,94 @@
+package silence
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/pkg/schedule"
+)
+
+// Recurrence limits a silence to a weekly window, e.g. every Sunday 02:00-04:00. A
+// window whose end is not after its start runs past midnight into the next day.
+type Recurrence struct {
+	Weekdays []string `json:"weekdays"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+func (r *Recurrence) window() schedule.Window {
+	return schedule.Window{Weekdays: r.Weekdays, Start: r.Start, End: r.End}
+}
+
+// Validate checks the recurrence
+func (r *Recurrence) Validate() error {
+	if err := r.window().Validate(); err != nil {
+		return fmt.Errorf("invalid recurrence: %w", err)
+	}
+	if _, err := schedule.LoadLocation(r.Timezone); err != nil {
+		return fmt.Errorf("invalid recurrence: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside one of the weekly windows
+func (r *Recurrence) Contains(t time.Time) bool {
+	loc, err := schedule.LoadLocation(r.Timezone)
+	if err != nil {
+		return false
+	}
+	return r.window().Contains(t.In(loc))
+}
+
+// RecurrenceOf decodes the stored recurrence of a silence, nil for one-off silences
+func RecurrenceOf(s *db.Silence) (*Recurrence, error) {
+	if len(s.Recurrence) == 0 {
+		return nil, nil
+	}
+	var r Recurrence
+	if err := json.Unmarshal(s.Recurrence, &r); err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+	return &r, nil
+}
+
+// Active reports whether a silence is in effect at t: between starts_at and ends_at and,
+// for recurring silences, inside a weekly window
+func Active(s *db.Silence, t time.Time) bool {
+	if t.Before(s.StartsAt) || (s.EndsAt.Valid && !t.Before(s.EndsAt.Time)) {
+		return false
+	}
+	r, err := RecurrenceOf(s)
+	if err != nil {
+		return false
+	}
+	return r == nil || r.Contains(t)
+}
+
+// Target describes a violation a silence may suppress. MetricTypes are the metric types
+// the violating rule evaluates; a composite rule has several.
+type Target struct {
+	ServiceID   string
+	MetricTypes []db.MetricType
+	RuleID      string
+	Severity    db.IncidentSeverity
+}
+
+// Matches reports whether every matcher of a silence accepts the violation. Matchers
+// that are not set accept any value; metric_type accepts any of the target's metric types.
+func Matches(s *db.Silence, t Target) bool {
+	switch {
+	case s.ServiceID != nil && *s.ServiceID != t.ServiceID:
+		return false
+	case s.MetricType.Valid && !slices.Contains(t.MetricTypes, s.MetricType.MetricType):
+		return false
+	case s.RuleID != nil && *s.RuleID != t.RuleID:
+		return false
+	case s.Severity.Valid && s.Severity.IncidentSeverity != t.Severity:
+		return false
+	}
+	return true
+}
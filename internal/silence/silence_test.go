@@ -0,0 +1,135 @@
+package silence
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+func TestRecurrence_Contains(t *testing.T) {
+	// 2026-03-15 is a Sunday
+	sunday := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 15, hour, minute, 0, 0, time.UTC)
+	}
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		at         time.Time
+		expected   bool
+	}{
+		{"inside window", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00"}, sunday(3, 0), true},
+		{"at start", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00"}, sunday(2, 0), true},
+		{"at end", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00"}, sunday(4, 0), false},
+		{"other weekday", Recurrence{Weekdays: []string{"MON"}, Start: "02:00", End: "04:00"}, sunday(3, 0), false},
+		{"lowercase weekday", Recurrence{Weekdays: []string{"sun"}, Start: "02:00", End: "04:00"}, sunday(3, 0), true},
+		{"overnight before midnight", Recurrence{Weekdays: []string{"SUN"}, Start: "23:00", End: "01:00"}, sunday(23, 30), true},
+		{"overnight after midnight", Recurrence{Weekdays: []string{"SAT"}, Start: "23:00", End: "01:00"}, sunday(0, 30), true},
+		{"overnight of other day", Recurrence{Weekdays: []string{"FRI"}, Start: "23:00", End: "01:00"}, sunday(0, 30), false},
+		{"timezone", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00", Timezone: "Europe/Istanbul"}, time.Date(2026, 3, 15, 3, 0, 0, 0, istanbul), true},
+		{"timezone shifts window", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00", Timezone: "Europe/Istanbul"}, sunday(3, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.recurrence.Contains(tt.at); got != tt.expected {
+				t.Errorf("Contains() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecurrence_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		valid      bool
+	}{
+		{"valid", Recurrence{Weekdays: []string{"SUN", "WED"}, Start: "02:00", End: "04:00"}, true},
+		{"no weekdays", Recurrence{Start: "02:00", End: "04:00"}, false},
+		{"unknown weekday", Recurrence{Weekdays: []string{"SUNDAY"}, Start: "02:00", End: "04:00"}, false},
+		{"invalid start", Recurrence{Weekdays: []string{"SUN"}, Start: "2am", End: "04:00"}, false},
+		{"empty window", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "02:00"}, false},
+		{"unknown timezone", Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00", Timezone: "Mars/Olympus"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.recurrence.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := pgtype.Timestamptz{Time: start.Add(30 * 24 * time.Hour), Valid: true}
+	recurrence, _ := json.Marshal(Recurrence{Weekdays: []string{"SUN"}, Start: "02:00", End: "04:00"})
+
+	oneOff := &db.Silence{StartsAt: start, EndsAt: end}
+	recurring := &db.Silence{StartsAt: start, Recurrence: recurrence}
+
+	tests := []struct {
+		name     string
+		silence  *db.Silence
+		at       time.Time
+		expected bool
+	}{
+		{"before start", oneOff, start.Add(-time.Minute), false},
+		{"in range", oneOff, start.Add(time.Hour), true},
+		{"at end", oneOff, end.Time, false},
+		{"recurring inside window", recurring, time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC), true},
+		{"recurring outside window", recurring, time.Date(2026, 3, 16, 3, 0, 0, 0, time.UTC), false},
+		{"recurring without end", recurring, time.Date(2027, 3, 14, 3, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Active(tt.silence, tt.at); got != tt.expected {
+				t.Errorf("Active() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	service := "S1"
+	rule := "latency-rule"
+	target := Target{
+		ServiceID:   "S1",
+		MetricTypes: []db.MetricType{db.MetricTypeLATENCYMS},
+		RuleID:      "latency-rule",
+		Severity:    db.IncidentSeverityHIGH,
+	}
+	composite := target
+	composite.MetricTypes = []db.MetricType{db.MetricTypeLATENCYMS, db.MetricTypePACKETLOSS}
+
+	packetLoss := db.NullMetricType{MetricType: db.MetricTypePACKETLOSS, Valid: true}
+
+	tests := []struct {
+		name     string
+		silence  db.Silence
+		target   Target
+		expected bool
+	}{
+		{"service", db.Silence{ServiceID: &service}, target, true},
+		{"service and rule", db.Silence{ServiceID: &service, RuleID: &rule}, target, true},
+		{"other metric type", db.Silence{ServiceID: &service, MetricType: packetLoss}, target, false},
+		{"other metric type of a composite rule", db.Silence{ServiceID: &service, MetricType: packetLoss}, composite, true},
+		{"severity", db.Silence{Severity: db.NullIncidentSeverity{IncidentSeverity: db.IncidentSeverityHIGH, Valid: true}}, target, true},
+		{"other severity", db.Silence{Severity: db.NullIncidentSeverity{IncidentSeverity: db.IncidentSeverityLOW, Valid: true}}, target, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(&tt.silence, tt.target); got != tt.expected {
+				t.Errorf("Matches() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
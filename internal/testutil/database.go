@@ -65,14 +65,31 @@ func CleanupTestData(t *testing.T, pool *pgxpool.Pool) {
 	// Use TRUNCATE CASCADE to handle all foreign key dependencies
 	_, err := pool.Exec(ctx, `
 		TRUNCATE TABLE
+			outbox_claims,
 			outbox_processing,
 			outbox,
 			notifications,
 			incident_events,
 			incident_comments,
+			incident_metrics,
 			incidents,
 			metrics,
+			rule_suppressions,
+			webhook_delivery_attempts,
+			webhook_deliveries,
+			webhook_cooldowns,
+			rule_series_leases,
+			series_baselines,
+			rule_revisions,
+			rule_status_changes,
+			draft_violations,
+			change_requests,
+			rule_evaluations,
+			metric_evaluation_policies,
+			silenced_violations,
+			silences,
 			quality_rules,
+			rule_actions,
 			services,
 			departments
 		CASCADE
@@ -81,6 +98,17 @@ func CleanupTestData(t *testing.T, pool *pgxpool.Pool) {
 		t.Logf("Warning: failed to truncate tables: %v", err)
 	}
 
+	// Restore the built-in rule actions registered by the migrations
+	_, err = pool.Exec(ctx, `
+		INSERT INTO rule_actions (id, name, behavior) VALUES
+			('OPEN_INCIDENT', 'Open incident', 'OPEN_INCIDENT'),
+			('THROTTLE', 'Open incident at most once per cooldown', 'THROTTLE'),
+			('WEBHOOK', 'Call webhook', 'WEBHOOK')
+	`)
+	if err != nil {
+		t.Logf("Warning: failed to restore built-in rule actions: %v", err)
+	}
+
 	// Reset sequences
 	sequences := []string{
 		"incident_id_seq",
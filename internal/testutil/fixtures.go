@@ -27,6 +27,23 @@ func TestService(t *testing.T, q *db.Queries, id, name string) db.Service {
 	return svc
 }
 
+// TestDepartment creates a test department
+func TestDepartment(t *testing.T, q *db.Queries, id, name string) db.Department {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d, err := q.CreateDepartment(ctx, db.CreateDepartmentParams{
+		ID:   id,
+		Name: name,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test department: %v", err)
+	}
+	return d
+}
+
 // TestRule creates a test quality rule
 func TestRule(t *testing.T, q *db.Queries, params TestRuleParams) db.QualityRule {
 	t.Helper()
@@ -45,25 +62,46 @@ func TestRule(t *testing.T, q *db.Queries, params TestRuleParams) db.QualityRule
 		params.Operator = db.RuleOperatorValue0 // >
 	}
 	if params.Action == "" {
-		params.Action = db.RuleActionOPENINCIDENT
+		params.Action = "OPEN_INCIDENT"
 	}
 	if params.Severity == "" {
 		params.Severity = db.IncidentSeverityMEDIUM
 	}
+	if params.ServiceIDs == nil {
+		params.ServiceIDs = []string{}
+	}
+	if params.Aggregation == "" {
+		params.Aggregation = db.RuleAggregationNONE
+	}
 
 	rule, err := q.CreateRule(ctx, db.CreateRuleParams{
-		ID:         params.ID,
-		MetricType: params.MetricType,
-		Threshold:  pgutil.Float64ToNumeric(params.Threshold),
-		Operator:   params.Operator,
-		Action:     params.Action,
-		Priority:   params.Priority,
-		Severity:   params.Severity,
-		IsActive:   params.IsActive,
+		ID:               params.ID,
+		MetricType:       params.MetricType,
+		Threshold:        pgutil.Float64ToNumeric(params.Threshold),
+		Operator:         params.Operator,
+		Action:           params.Action,
+		Priority:         params.Priority,
+		Severity:         params.Severity,
+		IsActive:         params.IsActive,
+		ServiceIds:       params.ServiceIDs,
+		Aggregation:      params.Aggregation,
+		WindowSeconds:    params.WindowSeconds,
+		MetricTypes:      []string{string(params.MetricType)},
+		CooldownSeconds:  params.CooldownSeconds,
+		WebhookHeaders:   []byte("{}"),
+		AnomalyDirection: db.AnomalyDirectionBOTH,
+		ChangeMode:       db.RuleChangeModeNONE,
+		ForecastMethod:   db.RuleForecastMethodNONE,
 	})
 	if err != nil {
 		t.Fatalf("Failed to create test rule: %v", err)
 	}
+	if params.Status != "" && params.Status != rule.Status {
+		rule, err = q.SetRuleStatus(ctx, db.SetRuleStatusParams{ID: rule.ID, Status: params.Status})
+		if err != nil {
+			t.Fatalf("Failed to set test rule status: %v", err)
+		}
+	}
 	return rule
 }
 
@@ -73,10 +111,19 @@ type TestRuleParams struct {
 	MetricType db.MetricType
 	Threshold  float64
 	Operator   db.RuleOperator
-	Action     db.RuleAction
+	Action     string
 	Priority   int32
 	Severity   db.IncidentSeverity
 	IsActive   bool
+	ServiceIDs []string
+
+	Aggregation   db.RuleAggregation
+	WindowSeconds int32
+
+	CooldownSeconds int32
+
+	// Status defaults to PUBLISHED, so the rule opens incidents
+	Status db.RuleStatus
 }
 
 // TestMetric creates a test metric
@@ -166,7 +213,7 @@ func TestIncident(t *testing.T, q *db.Queries, params TestIncidentParams) db.Inc
 		ID:        id,
 		ServiceID: params.ServiceID,
 		RuleID:    params.RuleID,
-		MetricID:  params.MetricID,
+		MetricID:  &params.MetricID,
 		Severity:  params.Severity,
 		Status:    params.Status,
 		Message:   params.Message,
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/pkg/httputil"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/rules/{id}/webhook-deliveries", h.ListForRule)
+}
+
+// ListForRule returns the webhook deliveries of a rule with their attempts, newest first
+func (h *Handler) ListForRule(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		httputil.BadRequest(w, "missing rule id")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, total, err := h.repo.ListForRule(r.Context(), id, int32(limit), int32(offset))
+	if err != nil {
+		slog.Error("failed to list webhook deliveries", "error", err)
+		httputil.InternalError(w, "failed to list webhook deliveries")
+		return
+	}
+
+	ids := make([]uuid.UUID, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.ID
+	}
+	attempts, err := h.repo.ListAttempts(r.Context(), ids)
+	if err != nil {
+		slog.Error("failed to list webhook delivery attempts", "error", err)
+		httputil.InternalError(w, "failed to list webhook deliveries")
+		return
+	}
+
+	httputil.SuccessPaginated(w, ToDeliveryResponseList(deliveries, attempts), total, limit, offset)
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/testutil"
+)
+
+func TestWebhookHandler_ListForRule(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:     "webhook-rule",
+		Action: "WEBHOOK",
+	})
+
+	ctx := context.Background()
+	repo := NewRepository(q)
+	err := repo.CreateDelivery(ctx, uuid.New(), Request{
+		RuleID:    "webhook-rule",
+		ServiceID: "S1",
+		URL:       "http://example.com/hook",
+		Body:      "{}",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create delivery: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rules/webhook-rule/webhook-deliveries", nil)
+	req.SetPathValue("id", "webhook-rule")
+	rr := httptest.NewRecorder()
+
+	NewHandler(repo).ListForRule(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Data []DeliveryResponse `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Meta.Total != 1 || len(response.Data) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(response.Data))
+	}
+	if response.Data[0].Status != "PENDING" || response.Data[0].URL != "http://example.com/hook" {
+		t.Errorf("Unexpected delivery: %+v", response.Data[0])
+	}
+	if response.Data[0].Attempts == nil {
+		t.Errorf("Expected attempts to be an empty list")
+	}
+}
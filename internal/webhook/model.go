@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+// Request is the payload of a WEBHOOK_REQUESTED outbox event
+type Request struct {
+	RuleID    string            `json:"rule_id"`
+	ServiceID string            `json:"service_id"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      string            `json:"body"`
+}
+
+type DeliveryResponse struct {
+	ID            string            `json:"id"`
+	RuleID        string            `json:"rule_id"`
+	ServiceID     string            `json:"service_id"`
+	URL           string            `json:"url"`
+	Body          string            `json:"body"`
+	Status        string            `json:"status"`
+	AttemptCount  int32             `json:"attempt_count"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"`
+	LastError     *string           `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Attempts      []AttemptResponse `json:"attempts"`
+}
+
+type AttemptResponse struct {
+	AttemptNumber int32     `json:"attempt_number"`
+	StatusCode    *int32    `json:"status_code,omitempty"`
+	Error         *string   `json:"error,omitempty"`
+	DurationMs    int32     `json:"duration_ms"`
+	AttemptedAt   time.Time `json:"attempted_at"`
+}
+
+func ToDeliveryResponse(d *db.WebhookDelivery, attempts []db.WebhookDeliveryAttempt) DeliveryResponse {
+	var nextAttemptAt *time.Time
+	if d.Status == db.WebhookDeliveryStatusPENDING {
+		nextAttemptAt = &d.NextAttemptAt
+	}
+	var deliveredAt *time.Time
+	if d.DeliveredAt.Valid {
+		deliveredAt = &d.DeliveredAt.Time
+	}
+
+	resp := DeliveryResponse{
+		ID:            d.ID.String(),
+		RuleID:        d.RuleID,
+		ServiceID:     d.ServiceID,
+		URL:           d.Url,
+		Body:          d.Body,
+		Status:        string(d.Status),
+		AttemptCount:  d.AttemptCount,
+		NextAttemptAt: nextAttemptAt,
+		LastError:     d.LastError,
+		DeliveredAt:   deliveredAt,
+		CreatedAt:     d.CreatedAt,
+		Attempts:      make([]AttemptResponse, len(attempts)),
+	}
+	for i, a := range attempts {
+		resp.Attempts[i] = AttemptResponse{
+			AttemptNumber: a.AttemptNumber,
+			StatusCode:    a.StatusCode,
+			Error:         a.Error,
+			DurationMs:    a.DurationMs,
+			AttemptedAt:   a.AttemptedAt,
+		}
+	}
+	return resp
+}
+
+// ToDeliveryResponseList groups attempts under their deliveries
+func ToDeliveryResponseList(deliveries []db.WebhookDelivery, attempts []db.WebhookDeliveryAttempt) []DeliveryResponse {
+	byDelivery := make(map[uuid.UUID][]db.WebhookDeliveryAttempt)
+	for _, a := range attempts {
+		byDelivery[a.DeliveryID] = append(byDelivery[a.DeliveryID], a)
+	}
+
+	result := make([]DeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = ToDeliveryResponse(&d, byDelivery[d.ID])
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+)
+
+type Repository struct {
+	q *db.Queries
+}
+
+func NewRepository(q *db.Queries) *Repository {
+	return &Repository{q: q}
+}
+
+// Enqueue writes a WEBHOOK_REQUESTED outbox event for the webhook worker unless the
+// rule already requested a delivery for the service within the cooldown. It reports
+// whether the event was written.
+func (r *Repository) Enqueue(ctx context.Context, req Request, cooldown time.Duration) (bool, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal webhook request: %w", err)
+	}
+
+	n, err := r.q.EnqueueWebhookOutsideCooldown(ctx, db.EnqueueWebhookOutsideCooldownParams{
+		Payload:         payload,
+		RuleID:          req.RuleID,
+		ServiceID:       req.ServiceID,
+		CooldownSeconds: int32(cooldown.Seconds()),
+	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// CreateDelivery stores the delivery of an outbox event. Repeated calls for the same event are ignored.
+func (r *Repository) CreateDelivery(ctx context.Context, outboxID uuid.UUID, req Request) error {
+	headers, err := json.Marshal(req.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook headers: %w", err)
+	}
+	if req.Headers == nil {
+		headers = []byte("{}")
+	}
+
+	return r.q.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		OutboxID:  outboxID,
+		RuleID:    req.RuleID,
+		ServiceID: req.ServiceID,
+		Url:       req.URL,
+		Headers:   headers,
+		Body:      req.Body,
+	})
+}
+
+// ClaimDue returns pending deliveries whose next attempt is due and leases them to the caller
+func (r *Repository) ClaimDue(ctx context.Context, limit int32, lease time.Duration) ([]db.WebhookDelivery, error) {
+	return r.q.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		LeaseSeconds: int32(lease.Seconds()),
+		LimitVal:     limit,
+	})
+}
+
+func (r *Repository) RecordAttempt(ctx context.Context, params db.CreateWebhookDeliveryAttemptParams) (*db.WebhookDeliveryAttempt, error) {
+	attempt, err := r.q.CreateWebhookDeliveryAttempt(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *Repository) MarkDelivered(ctx context.Context, id uuid.UUID, attemptCount int32) (*db.WebhookDelivery, error) {
+	d, err := r.q.MarkWebhookDelivered(ctx, db.MarkWebhookDeliveredParams{
+		ID:           id,
+		AttemptCount: attemptCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *Repository) ScheduleRetry(ctx context.Context, id uuid.UUID, attemptCount int32, lastError string, nextAttemptAt time.Time) (*db.WebhookDelivery, error) {
+	d, err := r.q.ScheduleWebhookRetry(ctx, db.ScheduleWebhookRetryParams{
+		ID:            id,
+		AttemptCount:  attemptCount,
+		LastError:     &lastError,
+		NextAttemptAt: nextAttemptAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *Repository) MarkFailed(ctx context.Context, id uuid.UUID, attemptCount int32, lastError string) (*db.WebhookDelivery, error) {
+	d, err := r.q.MarkWebhookFailed(ctx, db.MarkWebhookFailedParams{
+		ID:           id,
+		AttemptCount: attemptCount,
+		LastError:    &lastError,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListForRule returns a page of the rule's deliveries, newest first, with the total count
+func (r *Repository) ListForRule(ctx context.Context, ruleID string, limit, offset int32) ([]db.WebhookDelivery, int, error) {
+	deliveries, err := r.q.ListWebhookDeliveriesForRule(ctx, db.ListWebhookDeliveriesForRuleParams{
+		RuleID: ruleID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.q.CountWebhookDeliveriesForRule(ctx, ruleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, int(total), nil
+}
+
+// ListAttempts returns the attempts of the given deliveries
+func (r *Repository) ListAttempts(ctx context.Context, deliveryIDs []uuid.UUID) ([]db.WebhookDeliveryAttempt, error) {
+	return r.q.ListWebhookDeliveryAttempts(ctx, deliveryIDs)
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Event is the data a webhook body template is rendered with
+type Event struct {
+	RuleID     string    `json:"rule_id"`
+	ServiceID  string    `json:"service_id"`
+	MetricID   string    `json:"metric_id"`
+	MetricType string    `json:"metric_type"`
+	Value      float64   `json:"value"`
+	Threshold  float64   `json:"threshold"`
+	Severity   string    `json:"severity"`
+	Message    string    `json:"message"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value so it can be embedded in a JSON body, e.g. {"text": {{json .Message}}}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(templateFuncs).Parse(text)
+}
+
+// ValidateTemplate parses a body template and renders it once so references to
+// unknown fields are reported when the rule is saved rather than when it fires
+func ValidateTemplate(text string) error {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(&strings.Builder{}, Event{})
+}
+
+// RenderBody renders the request body of a webhook. Without a template the event is sent as JSON.
+func RenderBody(text *string, event Event) (string, error) {
+	if text == nil || *text == "" {
+		b, err := json.Marshal(event)
+		return string(b), err
+	}
+
+	tmpl, err := parseTemplate(*text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, event); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,264 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/outbox"
+	"github.com/unitythemaker/tracely/pkg/pgerror"
+)
+
+const (
+	ProcessorName = "webhook_worker"
+
+	SignatureHeader = "X-Tracely-Signature"
+	TimestampHeader = "X-Tracely-Timestamp"
+	DeliveryHeader  = "X-Tracely-Delivery"
+
+	// DefaultMaxAttempts is how many requests are made before a delivery is marked FAILED
+	DefaultMaxAttempts = 6
+	// DefaultBaseDelay is the delay before the first retry; it doubles with every attempt
+	DefaultBaseDelay = 30 * time.Second
+	// maxDelay caps the retry delay
+	maxDelay = time.Hour
+	// claimLease is how long a claimed delivery is hidden from other workers
+	claimLease = 5 * time.Minute
+	// requestTimeout bounds a single delivery request
+	requestTimeout = 10 * time.Second
+	// deliveryBatch is how many due deliveries are claimed per poll, and
+	// deliveryConcurrency how many of them are sent at once. A batch takes at most
+	// deliveryBatch/deliveryConcurrency request timeouts, well within claimLease, so
+	// no delivery is claimed again while it is being sent.
+	deliveryBatch       = 50
+	deliveryConcurrency = 10
+)
+
+// deliveryStore is what the worker claims deliveries from and records their attempts
+// in; Repository implements it
+type deliveryStore interface {
+	CreateDelivery(ctx context.Context, outboxID uuid.UUID, req Request) error
+	ClaimDue(ctx context.Context, limit int32, lease time.Duration) ([]db.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, params db.CreateWebhookDeliveryAttemptParams) (*db.WebhookDeliveryAttempt, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, attemptCount int32) (*db.WebhookDelivery, error)
+	ScheduleRetry(ctx context.Context, id uuid.UUID, attemptCount int32, lastError string, nextAttemptAt time.Time) (*db.WebhookDelivery, error)
+	MarkFailed(ctx context.Context, id uuid.UUID, attemptCount int32, lastError string) (*db.WebhookDelivery, error)
+}
+
+type Worker struct {
+	outboxRepo  *outbox.Repository
+	repo        deliveryStore
+	client      *http.Client
+	secret      []byte
+	maxAttempts int32
+	baseDelay   time.Duration
+	interval    time.Duration
+}
+
+func NewWorker(outboxRepo *outbox.Repository, repo *Repository, secret string, interval time.Duration) *Worker {
+	return &Worker{
+		outboxRepo:  outboxRepo,
+		repo:        repo,
+		client:      newClient(requestTimeout),
+		secret:      []byte(secret),
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultBaseDelay,
+		interval:    interval,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	slog.Info("WebhookWorker started", "interval", w.interval)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("WebhookWorker stopped")
+			return
+		case <-ticker.C:
+			w.processEvents(ctx)
+			w.deliverDue(ctx)
+		}
+	}
+}
+
+// processEvents turns WEBHOOK_REQUESTED outbox events into pending deliveries. Events
+// that can never become a delivery, because the payload does not decode or the rule or
+// service no longer exists, are dead-lettered instead of retried.
+func (w *Worker) processEvents(ctx context.Context) {
+	events, err := w.outboxRepo.ClaimWebhookEvents(ctx, ProcessorName, 100)
+	if err != nil {
+		slog.Error("WebhookWorker: failed to get events", "error", err)
+		return
+	}
+
+	for _, event := range events {
+		var req Request
+		if err := json.Unmarshal(event.Payload, &req); err != nil {
+			w.deadLetter(ctx, event.ID, fmt.Errorf("invalid payload: %w", err))
+			continue
+		}
+		if err := w.repo.CreateDelivery(ctx, event.ID, req); err != nil {
+			if pgerror.IsForeignKeyViolation(err) {
+				w.deadLetter(ctx, event.ID, err)
+				continue
+			}
+			slog.Error("WebhookWorker: failed to create delivery", "event_id", event.ID, "error", err)
+			continue
+		}
+
+		if err := w.outboxRepo.MarkProcessed(ctx, event.ID, ProcessorName); err != nil {
+			slog.Error("WebhookWorker: failed to mark event processed", "event_id", event.ID, "error", err)
+		}
+	}
+}
+
+// deadLetter marks an event that cannot be delivered as processed with the reason
+func (w *Worker) deadLetter(ctx context.Context, eventID uuid.UUID, reason error) {
+	slog.Error("WebhookWorker: dead-lettering event", "event_id", eventID, "error", reason)
+	if err := w.outboxRepo.MarkFailed(ctx, eventID, ProcessorName, reason.Error()); err != nil {
+		slog.Error("WebhookWorker: failed to dead-letter event", "event_id", eventID, "error", err)
+	}
+}
+
+// deliverDue attempts the pending deliveries whose next attempt is due, at most
+// deliveryConcurrency at a time
+func (w *Worker) deliverDue(ctx context.Context) {
+	deliveries, err := w.repo.ClaimDue(ctx, deliveryBatch, claimLease)
+	if err != nil {
+		slog.Error("WebhookWorker: failed to claim deliveries", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, deliveryConcurrency)
+	for i := range deliveries {
+		d := &deliveries[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := w.attempt(ctx, d); err != nil {
+				slog.Error("WebhookWorker: failed to record attempt", "delivery_id", d.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt sends a delivery once, records the attempt and schedules a retry on failure
+func (w *Worker) attempt(ctx context.Context, d *db.WebhookDelivery) error {
+	attemptNumber := d.AttemptCount + 1
+
+	start := time.Now()
+	statusCode, sendErr := w.send(ctx, d)
+	params := db.CreateWebhookDeliveryAttemptParams{
+		DeliveryID:    d.ID,
+		AttemptNumber: attemptNumber,
+		DurationMs:    int32(time.Since(start).Milliseconds()),
+	}
+	if statusCode != 0 {
+		params.StatusCode = &statusCode
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		params.Error = &msg
+	}
+	if _, err := w.repo.RecordAttempt(ctx, params); err != nil {
+		return err
+	}
+
+	if sendErr == nil {
+		_, err := w.repo.MarkDelivered(ctx, d.ID, attemptNumber)
+		if err == nil {
+			slog.Info("WebhookWorker: delivered", "delivery_id", d.ID, "rule_id", d.RuleID, "attempt", attemptNumber)
+		}
+		return err
+	}
+
+	if attemptNumber >= w.maxAttempts {
+		slog.Warn("WebhookWorker: delivery failed", "delivery_id", d.ID, "rule_id", d.RuleID, "attempts", attemptNumber, "error", sendErr)
+		_, err := w.repo.MarkFailed(ctx, d.ID, attemptNumber, sendErr.Error())
+		return err
+	}
+
+	delay := Backoff(w.baseDelay, attemptNumber)
+	slog.Debug("WebhookWorker: delivery will be retried", "delivery_id", d.ID, "attempt", attemptNumber, "delay", delay, "error", sendErr)
+	_, err := w.repo.ScheduleRetry(ctx, d.ID, attemptNumber, sendErr.Error(), time.Now().Add(delay))
+	return err
+}
+
+// send posts the delivery body and returns the response status. Non-2xx responses are errors.
+func (w *Worker) send(ctx context.Context, d *db.WebhookDelivery) (int32, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader([]byte(d.Body)))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	var headers map[string]string
+	if err := json.Unmarshal(d.Headers, &headers); err != nil {
+		return 0, fmt.Errorf("invalid headers: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	// Signature headers are set last so custom headers cannot override them
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(DeliveryHeader, d.ID.String())
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, timestamp, []byte(d.Body)))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	statusCode := int32(resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return statusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers recompute it with
+// the shared secret and the X-Tracely-Timestamp header to verify a delivery.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay after the given failed attempt: base, 2*base, 4*base, ... capped at an hour
+func Backoff(base time.Duration, attempt int32) time.Duration {
+	delay := base
+	for i := int32(1); i < attempt; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,542 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitythemaker/tracely/internal/db"
+	"github.com/unitythemaker/tracely/internal/outbox"
+	"github.com/unitythemaker/tracely/internal/testutil"
+)
+
+func TestSign(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"rule_id":"latency"}`)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("1700000000." + string(body)))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign(secret, "1700000000", body); got != expected {
+		t.Errorf("Sign() = %s, want %s", got, expected)
+	}
+	if Sign([]byte("other"), "1700000000", body) == expected {
+		t.Errorf("Expected a different signature for a different secret")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int32
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{10, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(DefaultBaseDelay, tt.attempt); got != tt.expected {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.expected)
+		}
+	}
+}
+
+func TestDeliveryBatchFitsClaimLease(t *testing.T) {
+	// Every request of a batch must finish before its claim expires, or another worker
+	// could claim and send the same delivery again
+	rounds := (deliveryBatch + deliveryConcurrency - 1) / deliveryConcurrency
+	if worst := time.Duration(rounds) * requestTimeout; worst >= claimLease {
+		t.Errorf("A batch may take %s, longer than the %s claim lease", worst, claimLease)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://203.0.113.10:8080/hook", true},
+		{"/hook", false},
+		{"ftp://example.com/hook", false},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateTarget(tt.url); (err == nil) != tt.valid {
+			t.Errorf("ValidateTarget(%q) = %v, want valid=%v", tt.url, err, tt.valid)
+		}
+	}
+}
+
+func TestWorker_RefusesPrivateTargets(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// A host name passes validation but the connection is refused once it resolves
+	// to loopback
+	w := NewWorker(nil, nil, "secret", time.Second)
+	_, err := w.send(context.Background(), &db.WebhookDelivery{
+		ID:      uuid.New(),
+		Url:     strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		Headers: []byte(`{}`),
+		Body:    `{}`,
+	})
+	if !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("Expected ErrPrivateTarget, got %v", err)
+	}
+	if called {
+		t.Error("Expected the request not to reach the server")
+	}
+}
+
+func TestRenderBody(t *testing.T) {
+	event := Event{
+		RuleID:    "latency",
+		ServiceID: "S1",
+		Value:     180,
+		Message:   `LATENCY_MS "high"`,
+	}
+
+	body, err := RenderBody(nil, event)
+	if err != nil {
+		t.Fatalf("RenderBody() error: %v", err)
+	}
+	var decoded Event
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil || decoded.RuleID != "latency" {
+		t.Errorf("Expected the event as JSON by default, got %s", body)
+	}
+
+	tmpl := `{"text": {{json .Message}}, "service": "{{.ServiceID}}", "value": {{.Value}}}`
+	body, err = RenderBody(&tmpl, event)
+	if err != nil {
+		t.Fatalf("RenderBody() error: %v", err)
+	}
+	expected := `{"text": "LATENCY_MS \"high\"", "service": "S1", "value": 180}`
+	if body != expected {
+		t.Errorf("RenderBody() = %s, want %s", body, expected)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ValidateTemplate(`{"rule": {{json .RuleID}}}`); err != nil {
+		t.Errorf("Expected valid template, got %v", err)
+	}
+	if err := ValidateTemplate(`{{.RuleID`); err == nil {
+		t.Errorf("Expected error for unterminated action")
+	}
+	if err := ValidateTemplate(`{{.Unknown}}`); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+}
+
+func TestWorker_Send(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		if r.Header.Get("X-Fail") == "true" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewWorker(nil, nil, "secret", time.Second)
+	// The test server listens on loopback, which the default client refuses
+	w.client = server.Client()
+	d := &db.WebhookDelivery{
+		ID:      uuid.New(),
+		Url:     server.URL,
+		Headers: []byte(`{"Authorization": "Bearer token", "X-Tracely-Signature": "forged"}`),
+		Body:    `{"rule_id":"latency"}`,
+	}
+
+	status, err := w.send(context.Background(), d)
+	if err != nil {
+		t.Fatalf("send() error: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", status)
+	}
+	if string(receivedBody) != d.Body {
+		t.Errorf("Expected body %s, got %s", d.Body, receivedBody)
+	}
+	if received.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected custom header to be sent")
+	}
+	if received.Header.Get(DeliveryHeader) != d.ID.String() {
+		t.Errorf("Expected delivery id header %s, got %s", d.ID, received.Header.Get(DeliveryHeader))
+	}
+
+	expected := "sha256=" + Sign([]byte("secret"), received.Header.Get(TimestampHeader), receivedBody)
+	if received.Header.Get(SignatureHeader) != expected {
+		t.Errorf("Expected signature %s, got %s", expected, received.Header.Get(SignatureHeader))
+	}
+
+	d.Headers = []byte(`{"X-Fail": "true"}`)
+	status, err = w.send(context.Background(), d)
+	if err == nil {
+		t.Errorf("Expected error for a 502 response")
+	}
+	if status != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", status)
+	}
+}
+
+func TestWorker_RetriesUntilDelivered(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:       "webhook-rule",
+		Action:   "WEBHOOK",
+		IsActive: true,
+	})
+
+	// The receiver fails twice before accepting the delivery
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := NewRepository(q)
+	w := NewWorker(outbox.NewRepository(q), repo, "secret", time.Second)
+	w.client = server.Client()
+	w.baseDelay = 0
+
+	request := Request{
+		RuleID:    "webhook-rule",
+		ServiceID: "S1",
+		URL:       server.URL,
+		Body:      `{"ok":true}`,
+	}
+	if enqueued, err := repo.Enqueue(ctx, request, time.Hour); err != nil || !enqueued {
+		t.Fatalf("Failed to enqueue webhook: %v", err)
+	}
+	// A second violation within the cooldown requests no delivery
+	if enqueued, err := repo.Enqueue(ctx, request, time.Hour); err != nil || enqueued {
+		t.Fatalf("Expected the cooldown to skip the second webhook, got %v (err: %v)", enqueued, err)
+	}
+
+	// Outbox events are turned into deliveries only once
+	w.processEvents(ctx)
+	w.processEvents(ctx)
+	for i := 0; i < 4; i++ {
+		w.deliverDue(ctx)
+	}
+
+	deliveries, total, err := repo.ListForRule(ctx, "webhook-rule", 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if total != 1 || len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", total)
+	}
+	d := deliveries[0]
+	if d.Status != db.WebhookDeliveryStatusDELIVERED {
+		t.Errorf("Expected DELIVERED, got %s", d.Status)
+	}
+	if d.AttemptCount != 3 {
+		t.Errorf("Expected 3 attempts, got %d", d.AttemptCount)
+	}
+
+	attempts, err := repo.ListAttempts(ctx, []uuid.UUID{d.ID})
+	if err != nil {
+		t.Fatalf("Failed to list attempts: %v", err)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("Expected 3 attempt records, got %d", len(attempts))
+	}
+	if attempts[0].StatusCode == nil || *attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected first attempt to record status 503")
+	}
+	if attempts[0].Error == nil || !strings.Contains(*attempts[0].Error, "503") {
+		t.Errorf("Expected first attempt to record the error")
+	}
+	if attempts[2].Error != nil {
+		t.Errorf("Expected last attempt to succeed, got error %s", *attempts[2].Error)
+	}
+}
+
+func TestWorker_MarksFailedAfterMaxAttempts(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	testutil.TestService(t, q, "S1", "Service 1")
+	testutil.TestRule(t, q, testutil.TestRuleParams{
+		ID:       "webhook-rule",
+		Action:   "WEBHOOK",
+		IsActive: true,
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := NewRepository(q)
+	w := NewWorker(outbox.NewRepository(q), repo, "secret", time.Second)
+	w.client = server.Client()
+	w.baseDelay = 0
+	w.maxAttempts = 2
+
+	if _, err := repo.Enqueue(ctx, Request{RuleID: "webhook-rule", ServiceID: "S1", URL: server.URL, Body: "{}"}, 0); err != nil {
+		t.Fatalf("Failed to enqueue webhook: %v", err)
+	}
+	w.processEvents(ctx)
+	for i := 0; i < 4; i++ {
+		w.deliverDue(ctx)
+	}
+
+	deliveries, _, err := repo.ListForRule(ctx, "webhook-rule", 10, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d (err: %v)", len(deliveries), err)
+	}
+	if deliveries[0].Status != db.WebhookDeliveryStatusFAILED {
+		t.Errorf("Expected FAILED, got %s", deliveries[0].Status)
+	}
+	if deliveries[0].AttemptCount != 2 {
+		t.Errorf("Expected 2 attempts, got %d", deliveries[0].AttemptCount)
+	}
+}
+
+func TestWorker_DeadLettersUndeliverableEvents(t *testing.T) {
+	pool := testutil.GetTestPool(t)
+	q := db.New(pool)
+
+	testutil.CleanupTestData(t, pool)
+	defer testutil.CleanupTestData(t, pool)
+
+	ctx := context.Background()
+	outboxRepo := outbox.NewRepository(q)
+	w := NewWorker(outboxRepo, NewRepository(q), "secret", time.Second)
+
+	payloads := map[string]string{
+		"invalid payload": `"not a request"`,
+		"missing rule":    `{"rule_id": "deleted", "service_id": "S1", "url": "https://example.com/hook", "body": "{}"}`,
+	}
+	for name, payload := range payloads {
+		if _, err := q.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+			EventType:     db.EventTypeWEBHOOKREQUESTED,
+			AggregateType: "rule",
+			AggregateID:   name,
+			Payload:       []byte(payload),
+		}); err != nil {
+			t.Fatalf("Failed to create outbox event: %v", err)
+		}
+	}
+
+	w.processEvents(ctx)
+
+	events, err := outboxRepo.GetUnprocessedWebhookEvents(ctx, ProcessorName, 10)
+	if err != nil {
+		t.Fatalf("Failed to get outbox events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected undeliverable events not to be retried, got %d", len(events))
+	}
+
+	var failed int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM outbox_processing WHERE processor = $1 AND error IS NOT NULL`, ProcessorName).Scan(&failed); err != nil {
+		t.Fatalf("Failed to count dead letters: %v", err)
+	}
+	if failed != len(payloads) {
+		t.Errorf("Expected %d dead letters, got %d", len(payloads), failed)
+	}
+}
+
+// fakeDeliveries is an in-memory deliveryStore. Claimed deliveries are hidden until
+// they are retried, like the lease of ClaimDue.
+type fakeDeliveries struct {
+	mu        sync.Mutex
+	due       []db.WebhookDelivery
+	limit     int32
+	lease     time.Duration
+	attempts  []db.CreateWebhookDeliveryAttemptParams
+	delivered map[uuid.UUID]int32
+	retries   map[uuid.UUID]time.Time
+	failed    map[uuid.UUID]int32
+}
+
+func newFakeDeliveries(due ...db.WebhookDelivery) *fakeDeliveries {
+	return &fakeDeliveries{
+		due:       due,
+		delivered: make(map[uuid.UUID]int32),
+		retries:   make(map[uuid.UUID]time.Time),
+		failed:    make(map[uuid.UUID]int32),
+	}
+}
+
+func (f *fakeDeliveries) CreateDelivery(context.Context, uuid.UUID, Request) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeDeliveries) ClaimDue(_ context.Context, limit int32, lease time.Duration) ([]db.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.limit, f.lease = limit, lease
+	n := min(int(limit), len(f.due))
+	claimed := f.due[:n]
+	f.due = f.due[n:]
+	return claimed, nil
+}
+
+func (f *fakeDeliveries) RecordAttempt(_ context.Context, params db.CreateWebhookDeliveryAttemptParams) (*db.WebhookDeliveryAttempt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts = append(f.attempts, params)
+	return &db.WebhookDeliveryAttempt{DeliveryID: params.DeliveryID, AttemptNumber: params.AttemptNumber}, nil
+}
+
+func (f *fakeDeliveries) MarkDelivered(_ context.Context, id uuid.UUID, attemptCount int32) (*db.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivered[id] = attemptCount
+	return &db.WebhookDelivery{ID: id, AttemptCount: attemptCount}, nil
+}
+
+func (f *fakeDeliveries) ScheduleRetry(_ context.Context, id uuid.UUID, attemptCount int32, _ string, nextAttemptAt time.Time) (*db.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retries[id] = nextAttemptAt
+	return &db.WebhookDelivery{ID: id, AttemptCount: attemptCount}, nil
+}
+
+func (f *fakeDeliveries) MarkFailed(_ context.Context, id uuid.UUID, attemptCount int32, _ string) (*db.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed[id] = attemptCount
+	return &db.WebhookDelivery{ID: id, AttemptCount: attemptCount}, nil
+}
+
+func TestWorker_DeliverDueWithinLease(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// More deliveries are due than fit in one batch
+	due := make([]db.WebhookDelivery, deliveryBatch+5)
+	for i := range due {
+		due[i] = db.WebhookDelivery{ID: uuid.New(), Url: server.URL, Headers: []byte(`{}`), Body: "{}"}
+	}
+	store := newFakeDeliveries(due...)
+	w := NewWorker(nil, nil, "secret", time.Second)
+	w.repo = store
+	w.client = server.Client()
+
+	w.deliverDue(context.Background())
+
+	if store.limit != deliveryBatch || store.lease != claimLease {
+		t.Errorf("Expected to claim %d deliveries for %s, got %d for %s", deliveryBatch, claimLease, store.limit, store.lease)
+	}
+	if len(store.delivered) != deliveryBatch {
+		t.Errorf("Expected the %d claimed deliveries to be delivered, got %d", deliveryBatch, len(store.delivered))
+	}
+	if len(store.due) != 5 {
+		t.Errorf("Expected 5 deliveries left for the next poll, got %d", len(store.due))
+	}
+	// The batch is sent concurrently so it finishes well within the lease
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInFlight > deliveryConcurrency || maxInFlight < 2 {
+		t.Errorf("Expected between 2 and %d requests in flight, got %d", deliveryConcurrency, maxInFlight)
+	}
+}
+
+func TestWorker_AttemptSchedulesRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	first := db.WebhookDelivery{ID: uuid.New(), Url: server.URL, Headers: []byte(`{}`), Body: "{}"}
+	last := db.WebhookDelivery{ID: uuid.New(), Url: server.URL, Headers: []byte(`{}`), Body: "{}", AttemptCount: DefaultMaxAttempts - 1}
+	store := newFakeDeliveries(first, last)
+	w := NewWorker(nil, nil, "secret", time.Second)
+	w.repo = store
+	w.client = server.Client()
+
+	before := time.Now()
+	w.deliverDue(context.Background())
+
+	// A failed attempt is retried after the backoff and the last one fails the delivery
+	retryAt, ok := store.retries[first.ID]
+	if !ok {
+		t.Fatalf("Expected the first attempt to be retried")
+	}
+	if wait := retryAt.Sub(before); wait < DefaultBaseDelay || wait > DefaultBaseDelay+time.Minute {
+		t.Errorf("Expected the retry after about %s, got %s", DefaultBaseDelay, wait)
+	}
+	if attempts, ok := store.failed[last.ID]; !ok || attempts != DefaultMaxAttempts {
+		t.Errorf("Expected the last attempt to mark the delivery failed after %d attempts, got %d", DefaultMaxAttempts, attempts)
+	}
+	if len(store.attempts) != 2 {
+		t.Fatalf("Expected 2 attempt records, got %d", len(store.attempts))
+	}
+	for _, a := range store.attempts {
+		if a.StatusCode == nil || *a.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected attempt to record status 503, got %v", a.StatusCode)
+		}
+	}
+}
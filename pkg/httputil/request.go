@@ -3,8 +3,23 @@ package httputil
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
+// ActorHeader names the user making a change, e.g. in rule revisions
+const ActorHeader = "X-Actor"
+
+// DefaultActor is used when a request does not name its actor
+const DefaultActor = "anonymous"
+
 func Decode(r *http.Request, v any) error {
 	return json.NewDecoder(r.Body).Decode(v)
 }
+
+// Actor returns the user named in the X-Actor header, or "anonymous"
+func Actor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get(ActorHeader)); actor != "" {
+		return actor
+	}
+	return DefaultActor
+}
@@ -195,3 +195,15 @@ func TestDecode_TypeMismatch(t *testing.T) {
 		t.Errorf("Expected error for type mismatch, got nil")
 	}
 }
+
+func TestActor(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	if actor := Actor(req); actor != DefaultActor {
+		t.Errorf("Expected %q without header, got %q", DefaultActor, actor)
+	}
+
+	req.Header.Set(ActorHeader, " alice ")
+	if actor := Actor(req); actor != "alice" {
+		t.Errorf("Expected alice, got %q", actor)
+	}
+}
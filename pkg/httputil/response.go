@@ -60,6 +60,12 @@ func Created(w http.ResponseWriter, data any) {
 	JSON(w, http.StatusCreated, SuccessResponse{Data: data})
 }
 
+// Accepted responds to a request whose change takes effect later, such as once it is
+// reviewed
+func Accepted(w http.ResponseWriter, data any) {
+	JSON(w, http.StatusAccepted, SuccessResponse{Data: data})
+}
+
 func NoContent(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -79,3 +85,7 @@ func InternalError(w http.ResponseWriter, message string) {
 func Conflict(w http.ResponseWriter, message string) {
 	Error(w, http.StatusConflict, "conflict", message)
 }
+
+func Forbidden(w http.ResponseWriter, message string) {
+	Error(w, http.StatusForbidden, "forbidden", message)
+}
@@ -189,6 +189,23 @@ func TestCreated(t *testing.T) {
 	}
 }
 
+func TestAccepted(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Accepted(rr, map[string]int{"id": 1})
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+
+	var response SuccessResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if dataMap, ok := response.Data.(map[string]any); !ok || dataMap["id"] != float64(1) {
+		t.Errorf("Expected id=1, got %v", response.Data)
+	}
+}
+
 func TestNoContent(t *testing.T) {
 	rr := httptest.NewRecorder()
 	NoContent(rr)
@@ -278,6 +295,24 @@ func TestConflict(t *testing.T) {
 	}
 }
 
+func TestForbidden(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Forbidden(rr, "not allowed")
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	var response ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if response.Error != "forbidden" {
+		t.Errorf("Expected error forbidden, got %s", response.Error)
+	}
+}
+
 func TestSuccessResponse_EmptyMessage(t *testing.T) {
 	rr := httptest.NewRecorder()
 	JSON(rr, http.StatusOK, SuccessResponse{Data: "test"})
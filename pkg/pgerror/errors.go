@@ -8,7 +8,8 @@ import (
 
 const (
 	// PostgreSQL error codes
-	UniqueViolation = "23505"
+	UniqueViolation     = "23505"
+	ForeignKeyViolation = "23503"
 )
 
 // IsUniqueViolation checks if the error is a PostgreSQL unique constraint violation
@@ -19,3 +20,12 @@ func IsUniqueViolation(err error) bool {
 	}
 	return false
 }
+
+// IsForeignKeyViolation checks if the error is a PostgreSQL foreign key constraint violation
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == ForeignKeyViolation
+	}
+	return false
+}
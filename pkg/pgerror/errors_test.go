@@ -77,6 +77,28 @@ func TestUniqueViolationConstant(t *testing.T) {
 	}
 }
 
+func TestIsForeignKeyViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"foreign key violation", &pgconn.PgError{Code: ForeignKeyViolation}, true},
+		{"wrapped foreign key violation", fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: ForeignKeyViolation}), true},
+		{"unique violation", &pgconn.PgError{Code: UniqueViolation}, false},
+		{"nil error", nil, false},
+		{"non-postgres error", errors.New("some error"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsForeignKeyViolation(tt.err); result != tt.expected {
+				t.Errorf("IsForeignKeyViolation() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestIsUniqueViolation_WithMessage(t *testing.T) {
 	err := &pgconn.PgError{
 		Code:           UniqueViolation,
@@ -23,3 +23,20 @@ func Float64ToNumeric(f float64) pgtype.Numeric {
 	n.Valid = true
 	return n
 }
+
+// NumericToFloat64Ptr converts a nullable pgtype.Numeric to *float64
+func NumericToFloat64Ptr(n pgtype.Numeric) *float64 {
+	if !n.Valid {
+		return nil
+	}
+	f := NumericToFloat64(n)
+	return &f
+}
+
+// Float64PtrToNumeric converts *float64 to a nullable pgtype.Numeric
+func Float64PtrToNumeric(f *float64) pgtype.Numeric {
+	if f == nil {
+		return pgtype.Numeric{}
+	}
+	return Float64ToNumeric(*f)
+}
@@ -151,6 +151,21 @@ func TestNumericToFloat64_EdgeCases(t *testing.T) {
 	}
 }
 
+func TestNullableNumeric(t *testing.T) {
+	if NumericToFloat64Ptr(pgtype.Numeric{}) != nil {
+		t.Errorf("Expected nil for invalid Numeric")
+	}
+	if Float64PtrToNumeric(nil).Valid {
+		t.Errorf("Expected invalid Numeric for nil")
+	}
+
+	v := 120.5
+	result := NumericToFloat64Ptr(Float64PtrToNumeric(&v))
+	if result == nil || math.Abs(*result-v) > 0.001 {
+		t.Errorf("Round trip failed: expected %f, got %v", v, result)
+	}
+}
+
 func BenchmarkFloat64ToNumeric(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		Float64ToNumeric(99.99)
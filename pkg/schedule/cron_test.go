@@ -0,0 +1,70 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"* 17-9 * * *",
+		"* * * * MON-XYZ",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("ParseCron(%q) expected error", expr)
+			}
+		})
+	}
+}
+
+func TestCron_Matches(t *testing.T) {
+	// 2026-03-16 is a Monday
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 16, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"every minute", "* * * * *", monday(3, 17), true},
+		{"business hours", "* 9-17 * * MON-FRI", monday(9, 0), true},
+		{"business hours last minute", "* 9-17 * * MON-FRI", monday(17, 59), true},
+		{"business hours after", "* 9-17 * * MON-FRI", monday(18, 0), false},
+		{"business hours weekend", "* 9-17 * * MON-FRI", monday(10, 0).AddDate(0, 0, -1), false},
+		{"step", "*/15 * * * *", monday(10, 45), true},
+		{"step miss", "*/15 * * * *", monday(10, 46), false},
+		{"start with step", "5/20 * * * *", monday(10, 25), true},
+		{"list", "0 8,12,18 * * *", monday(12, 0), true},
+		{"sunday as 7", "* * * * 7", monday(10, 0).AddDate(0, 0, -1), true},
+		{"month name", "* * * MAR *", monday(10, 0), true},
+		{"other month", "* * * APR *", monday(10, 0), false},
+		{"day of month or weekday", "* * 1 * MON", monday(10, 0), true},
+		{"day of month and any weekday", "* * 1 * *", monday(10, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := c.Matches(tt.at); got != tt.want {
+				t.Errorf("Matches(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
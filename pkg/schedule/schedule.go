@@ -0,0 +1,62 @@
+// Package schedule decides whether a point in time falls inside a weekly or cron-based
+// schedule in a given time zone.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schedule limits something to either the minutes a cron expression matches or a set of
+// weekly windows, evaluated in Timezone (UTC when empty)
+type Schedule struct {
+	Cron     string   `json:"cron,omitempty"`
+	Windows  []Window `json:"windows,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+// Validate checks the schedule
+func (s *Schedule) Validate() error {
+	switch {
+	case s.Cron == "" && len(s.Windows) == 0:
+		return errors.New("either cron or windows is required")
+	case s.Cron != "" && len(s.Windows) > 0:
+		return errors.New("cron and windows cannot be combined")
+	}
+	if s.Cron != "" {
+		if _, err := ParseCron(s.Cron); err != nil {
+			return fmt.Errorf("invalid cron: %w", err)
+		}
+	}
+	for i, w := range s.Windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("invalid windows[%d]: %w", i, err)
+		}
+	}
+	if _, err := LoadLocation(s.Timezone); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the schedule. An invalid schedule contains
+// no time.
+func (s *Schedule) Contains(t time.Time) bool {
+	loc, err := LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+
+	if s.Cron != "" {
+		c, err := ParseCron(s.Cron)
+		return err == nil && c.Matches(t)
+	}
+	for _, w := range s.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
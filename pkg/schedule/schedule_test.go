@@ -0,0 +1,62 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Validate(t *testing.T) {
+	businessHours := []Window{{Weekdays: []string{"MON", "FRI"}, Start: "09:00", End: "18:00"}}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		valid    bool
+	}{
+		{"cron", Schedule{Cron: "* 9-17 * * MON-FRI"}, true},
+		{"windows", Schedule{Windows: businessHours, Timezone: "Europe/Istanbul"}, true},
+		{"empty", Schedule{}, false},
+		{"cron and windows", Schedule{Cron: "* * * * *", Windows: businessHours}, false},
+		{"invalid cron", Schedule{Cron: "* * *"}, false},
+		{"invalid window", Schedule{Windows: []Window{{Weekdays: []string{"MON"}, Start: "9am", End: "18:00"}}}, false},
+		{"unknown timezone", Schedule{Cron: "* * * * *", Timezone: "Mars/Olympus"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSchedule_Contains(t *testing.T) {
+	// 2026-03-16 09:30 UTC is 12:30 on a Monday in Istanbul
+	at := time.Date(2026, 3, 16, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		want     bool
+	}{
+		{"cron in UTC", Schedule{Cron: "* 9 * * MON"}, true},
+		{"cron in timezone", Schedule{Cron: "* 9 * * MON", Timezone: "Europe/Istanbul"}, false},
+		{"cron shifted by timezone", Schedule{Cron: "* 12 * * MON", Timezone: "Europe/Istanbul"}, true},
+		{"window", Schedule{Windows: []Window{{Weekdays: []string{"MON"}, Start: "09:00", End: "10:00"}}}, true},
+		{"second window", Schedule{Windows: []Window{
+			{Weekdays: []string{"SAT"}, Start: "09:00", End: "10:00"},
+			{Weekdays: []string{"MON"}, Start: "12:00", End: "13:00"},
+		}, Timezone: "Europe/Istanbul"}, true},
+		{"outside windows", Schedule{Windows: []Window{{Weekdays: []string{"TUE"}, Start: "09:00", End: "10:00"}}}, false},
+		{"invalid", Schedule{Cron: "bad"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Contains(at); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdays maps the day names windows use to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"SUN": time.Sunday,
+	"MON": time.Monday,
+	"TUE": time.Tuesday,
+	"WED": time.Wednesday,
+	"THU": time.Thursday,
+	"FRI": time.Friday,
+	"SAT": time.Saturday,
+}
+
+// Window is a weekly time range, e.g. MON-FRI 09:00-18:00. A window whose end is not
+// after its start runs past midnight into the next day.
+type Window struct {
+	Weekdays []string `json:"weekdays"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+}
+
+// window is a parsed Window
+type window struct {
+	days       [7]bool
+	start, end time.Duration
+}
+
+func (w Window) parse() (window, error) {
+	var p window
+	if len(w.Weekdays) == 0 {
+		return p, errors.New("weekdays is required")
+	}
+	for _, name := range w.Weekdays {
+		day, ok := weekdays[strings.ToUpper(name)]
+		if !ok {
+			return p, fmt.Errorf("invalid weekday: %s", name)
+		}
+		p.days[day] = true
+	}
+
+	var err error
+	if p.start, err = parseClock(w.Start); err != nil {
+		return p, fmt.Errorf("invalid start: %w", err)
+	}
+	if p.end, err = parseClock(w.End); err != nil {
+		return p, fmt.Errorf("invalid end: %w", err)
+	}
+	if p.start == p.end {
+		return p, errors.New("start and end must differ")
+	}
+	return p, nil
+}
+
+// parseClock parses a time of day in HH:MM form
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("expected HH:MM")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate checks the window
+func (w Window) Validate() error {
+	_, err := w.parse()
+	return err
+}
+
+// Contains reports whether t falls inside the window, using the weekday and time of day
+// of t in its own location
+func (w Window) Contains(t time.Time) bool {
+	p, err := w.parse()
+	if err != nil {
+		return false
+	}
+
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	overnight := p.end < p.start
+
+	// A window that starts today
+	if p.days[t.Weekday()] && clock >= p.start && (overnight || clock < p.end) {
+		return true
+	}
+	// The part of yesterday's window that runs past midnight
+	yesterday := (t.Weekday() + 6) % 7
+	return overnight && p.days[yesterday] && clock < p.end
+}
+
+// LoadLocation returns the named time zone, UTC when name is empty
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone: %s", name)
+	}
+	return loc, nil
+}
@@ -465,7 +465,7 @@ export default function IncidentDetailPage() {
             </CardHeader>
             <CardContent className="pt-0">
               <p className="text-xs font-mono text-muted-foreground break-all">
-                {incident.metric_id}
+                {incident.metric_id ?? '-'}
               </p>
             </CardContent>
           </Card>
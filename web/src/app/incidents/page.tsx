@@ -565,7 +565,7 @@ export default function IncidentsPage() {
                         )}
                         {isColumnVisible('metric_id') && (
                           <TableCell className="font-mono text-xs text-muted-foreground">
-                            {incident.metric_id}
+                            {incident.metric_id ?? '-'}
                           </TableCell>
                         )}
                         {isColumnVisible('opened_at') && (
@@ -565,13 +565,17 @@ export default function NotificationsPage() {
                       )}
                       {isColumnVisible('incident_id') && (
                         <TableCell>
-                          <Link
-                            href={`/incidents/${notification.incident_id}`}
-                            className="font-mono text-sm text-[#00d9ff] hover:text-[#33e1ff] hover:underline flex items-center gap-1"
-                          >
-                            {notification.incident_id}
-                            <ExternalLink className="w-3 h-3" />
-                          </Link>
+                          {notification.incident_id ? (
+                            <Link
+                              href={`/incidents/${notification.incident_id}`}
+                              className="font-mono text-sm text-[#00d9ff] hover:text-[#33e1ff] hover:underline flex items-center gap-1"
+                            >
+                              {notification.incident_id}
+                              <ExternalLink className="w-3 h-3" />
+                            </Link>
+                          ) : (
+                            <span className="font-mono text-sm text-soft">{notification.rule_id}</span>
+                          )}
                         </TableCell>
                       )}
                       {isColumnVisible('sent_at') && (
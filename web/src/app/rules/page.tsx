@@ -71,6 +71,12 @@ const severityColors: Record<string, { color: string; priority: number }> = {
   LOW: { color: '#7d8a9d', priority: 1 },
 };
 
+const reviewLabels: Record<string, string> = {
+  DRAFT: 'Taslak',
+  PENDING_REVIEW: 'İncelemede',
+  PUBLISHED: 'Yayında',
+};
+
 const defaultRule: CreateRuleInput = {
   id: '',
   metric_type: 'LATENCY_MS',
@@ -122,6 +128,7 @@ export default function RulesPage() {
   const [metricFilter, setMetricFilter] = useState<string>('all');
   const [severityFilter, setSeverityFilter] = useState<string>('all');
   const [statusFilter, setStatusFilter] = useState<string>('all');
+  const [reviewFilter, setReviewFilter] = useState<string>('all');
 
   // Sorting
   const [sortField, setSortField] = useState<SortField>('trigger_count');
@@ -152,10 +159,10 @@ export default function RulesPage() {
   }, [searchQuery]);
 
   // Refs for polling
-  const filtersRef = useRef({ limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, debouncedSearch });
+  const filtersRef = useRef({ limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, reviewFilter, debouncedSearch });
   useEffect(() => {
-    filtersRef.current = { limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, debouncedSearch };
-  }, [limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, debouncedSearch]);
+    filtersRef.current = { limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, reviewFilter, debouncedSearch };
+  }, [limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, reviewFilter, debouncedSearch]);
 
   // Silent fetch for polling
   const fetchStatsSilent = useCallback(async () => {
@@ -185,6 +192,9 @@ export default function RulesPage() {
     if (f.statusFilter !== 'all') {
       params.is_active = f.statusFilter === 'active';
     }
+    if (f.reviewFilter !== 'all') {
+      params.status = f.reviewFilter;
+    }
     if (f.debouncedSearch) {
       params.search = f.debouncedSearch;
     }
@@ -228,7 +238,7 @@ export default function RulesPage() {
     if (!loading) {
       fetchRulesSilent();
     }
-  }, [limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, debouncedSearch, loading, fetchRulesSilent]);
+  }, [limit, offset, sortField, sortDirection, metricFilter, severityFilter, statusFilter, reviewFilter, debouncedSearch, loading, fetchRulesSilent]);
 
   function handleSort(field: SortField) {
     if (sortField === field) {
@@ -255,11 +265,12 @@ export default function RulesPage() {
     setMetricFilter('all');
     setSeverityFilter('all');
     setStatusFilter('all');
+    setReviewFilter('all');
     setOffset(0);
   }
 
   const hasActiveFilters =
-    searchQuery || metricFilter !== 'all' || severityFilter !== 'all' || statusFilter !== 'all';
+    searchQuery || metricFilter !== 'all' || severityFilter !== 'all' || statusFilter !== 'all' || reviewFilter !== 'all';
 
   function openCreateDialog() {
     setEditingRule(null);
@@ -294,7 +305,10 @@ export default function RulesPage() {
     setSaving(true);
     try {
       if (editingRule) {
-        await api.updateRule(editingRule.id, formData);
+        const change = await api.updateRule(editingRule.id, formData);
+        if (change) {
+          alert('Yayındaki kuralın değişikliği incelemeye gönderildi.');
+        }
       } else {
         await api.createRule(formData);
       }
@@ -312,7 +326,10 @@ export default function RulesPage() {
     if (!ruleToDelete) return;
     setSaving(true);
     try {
-      await api.deleteRule(ruleToDelete.id);
+      const change = await api.deleteRule(ruleToDelete.id);
+      if (change) {
+        alert('Yayındaki kuralın silinmesi incelemeye gönderildi.');
+      }
       setDeleteDialogOpen(false);
       setRuleToDelete(null);
       await fetchAllData();
@@ -334,15 +351,15 @@ export default function RulesPage() {
     setToggling(rule.id);
 
     try {
-      await api.updateRule(rule.id, {
-        metric_type: rule.metric_type,
-        threshold: rule.threshold,
-        operator: rule.operator,
-        action: rule.action,
-        priority: rule.priority,
-        severity: rule.severity,
-        is_active: newIsActive,
-      });
+      const change = await api.setRuleActive(rule.id, newIsActive);
+      if (change) {
+        // The rule stays as it is until the change is approved
+        setRules((prev) =>
+          prev.map((r) => (r.id === rule.id ? { ...r, is_active: !newIsActive } : r))
+        );
+        alert('Yayındaki kuralın durum değişikliği incelemeye gönderildi.');
+        return;
+      }
       // Update stats
       setActiveCount((prev) => (newIsActive ? prev + 1 : prev - 1));
     } catch (error) {
@@ -513,6 +530,25 @@ export default function RulesPage() {
               </div>
             </div>
 
+            {/* Review Filter */}
+            <div className="flex items-center gap-2">
+              <span className="text-sm text-muted-foreground">İnceleme:</span>
+              <div className="flex gap-1">
+                {['all', 'DRAFT', 'PENDING_REVIEW', 'PUBLISHED'].map((status) => (
+                  <button
+                    key={status}
+                    onClick={() => {
+                      setReviewFilter(status);
+                      setOffset(0);
+                    }}
+                    className={`filter-chip ${reviewFilter === status ? 'active' : ''}`}
+                  >
+                    {status === 'all' ? 'Tümü' : reviewLabels[status]}
+                  </button>
+                ))}
+              </div>
+            </div>
+
             {/* Clear Filters */}
             {hasActiveFilters && (
               <Button
@@ -675,7 +711,14 @@ export default function RulesPage() {
                   {rules.map((rule) => (
                     <TableRow key={rule.id} className="border-border hover:bg-muted/30">
                       {isColumnVisible('id') && (
-                        <TableCell className="font-mono text-sm text-[#00d9ff]">{rule.id}</TableCell>
+                        <TableCell className="font-mono text-sm text-[#00d9ff]">
+                          {rule.id}
+                          {rule.status !== 'PUBLISHED' && (
+                            <Badge variant="outline" className="ml-2 text-xs text-[#ffb800] border-[#ffb800]/40">
+                              {reviewLabels[rule.status]}
+                            </Badge>
+                          )}
+                        </TableCell>
                       )}
                       {isColumnVisible('metric_type') && (
                         <TableCell>
@@ -111,11 +111,41 @@ export interface Rule {
   severity: string;
   is_active: boolean;
   department_id?: string;
+  status: RuleStatus;
+  owner?: string;
+  author?: string;
   created_at: string;
   updated_at: string;
   trigger_count: number;
 }
 
+export type RuleStatus = 'DRAFT' | 'PENDING_REVIEW' | 'PUBLISHED';
+
+export interface RuleStatusChange {
+  id: number;
+  rule_id: string;
+  from_status: RuleStatus | null;
+  to_status: RuleStatus;
+  actor: string;
+  comment?: string;
+  created_at: string;
+}
+
+export interface ChangeRequest {
+  id: number;
+  target: 'RULE' | 'ACTION';
+  target_id: string;
+  kind: 'ACTIVATE' | 'DEACTIVATE' | 'DELETE' | 'UPDATE';
+  payload?: unknown;
+  requested_by: string;
+  comment?: string;
+  status: 'PENDING' | 'APPROVED' | 'REJECTED';
+  reviewed_by?: string;
+  review_comment?: string;
+  created_at: string;
+  reviewed_at?: string;
+}
+
 export interface CreateRuleInput {
   id: string;
   metric_type: string;
@@ -137,7 +167,7 @@ export interface Incident {
   id: string;
   service_id: string;
   rule_id: string;
-  metric_id: string;
+  metric_id: string | null;
   severity: string;
   status: string;
   message: string;
@@ -169,7 +199,8 @@ export interface IncidentEvent {
 
 export interface Notification {
   id: string;
-  incident_id: string;
+  incident_id: string | null;
+  rule_id?: string;
   target: string;
   message: string;
   department_id?: string;
@@ -339,7 +370,8 @@ export const api = {
     }
     return res.json();
   },
-  updateRule: async (id: string, data: Partial<CreateRuleInput>) => {
+  // Updating a published rule returns the change request waiting for review
+  updateRule: async (id: string, data: Partial<CreateRuleInput>): Promise<ChangeRequest | null> => {
     const res = await fetch(`${API_BASE}/api/rules/${id}`, {
       method: 'PATCH',
       headers: { 'Content-Type': 'application/json' },
@@ -349,9 +381,27 @@ export const api = {
       const errorData = await res.json().catch(() => ({}));
       throw new Error(errorData.message || `API error: ${res.status}`);
     }
-    return res.json();
+    if (res.status === 202) {
+      return (await res.json()).data;
+    }
+    return null;
+  },
+  // Activating or deactivating a published rule returns the change request waiting for review
+  setRuleActive: async (id: string, active: boolean): Promise<ChangeRequest | null> => {
+    const res = await fetch(`${API_BASE}/api/rules/${id}/${active ? 'activate' : 'deactivate'}`, {
+      method: 'POST',
+    });
+    if (!res.ok) {
+      const errorData = await res.json().catch(() => ({}));
+      throw new Error(errorData.message || `API error: ${res.status}`);
+    }
+    if (res.status === 202) {
+      return (await res.json()).data;
+    }
+    return null;
   },
-  deleteRule: async (id: string) => {
+  // Deleting a published rule returns the change request waiting for review
+  deleteRule: async (id: string): Promise<ChangeRequest | null> => {
     const res = await fetch(`${API_BASE}/api/rules/${id}`, {
       method: 'DELETE',
     });
@@ -359,7 +409,38 @@ export const api = {
       const errorData = await res.json().catch(() => ({}));
       throw new Error(errorData.message || `API error: ${res.status}`);
     }
-    return true;
+    if (res.status === 202) {
+      return (await res.json()).data;
+    }
+    return null;
+  },
+  reviewRule: async (id: string, step: 'submit' | 'approve' | 'reject', actor: string, comment?: string) => {
+    const res = await fetch(`${API_BASE}/api/rules/${id}/${step}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', 'X-Actor': actor },
+      body: JSON.stringify(comment ? { comment } : {}),
+    });
+    if (!res.ok) {
+      const errorData = await res.json().catch(() => ({}));
+      throw new Error(errorData.message || `API error: ${res.status}`);
+    }
+    return res.json();
+  },
+  getRuleStatusChanges: (id: string, params: ListParams = {}) =>
+    fetchPaginatedAPI<RuleStatusChange>(`/api/rules/${id}/status-changes`, params),
+  getChangeRequests: (params: ListParams = {}) =>
+    fetchPaginatedAPI<ChangeRequest>('/api/change-requests', params),
+  reviewChangeRequest: async (id: number, step: 'approve' | 'reject', actor: string, comment?: string) => {
+    const res = await fetch(`${API_BASE}/api/change-requests/${id}/${step}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', 'X-Actor': actor },
+      body: JSON.stringify(comment ? { comment } : {}),
+    });
+    if (!res.ok) {
+      const errorData = await res.json().catch(() => ({}));
+      throw new Error(errorData.message || `API error: ${res.status}`);
+    }
+    return res.json();
   },
   getTopTriggeredRules: (limit: number = 10) => fetchAPI<TopTriggeredRule[]>(`/api/rules/stats/top-triggered?limit=${limit}`),
 